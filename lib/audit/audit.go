@@ -0,0 +1,292 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/config"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration fields for auditing dropped messages.
+type Config struct {
+	Enabled bool          `json:"enabled" yaml:"enabled"`
+	Mode    string        `json:"mode" yaml:"mode"`
+	Fields  []string      `json:"fields" yaml:"fields"`
+	Output  output.Config `json:"output" yaml:"output"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled: false,
+		Mode:    "record",
+		Fields:  []string{},
+		Output:  output.NewConfig(),
+	}
+}
+
+// SanitiseConfig returns a sanitised version of the Config, meaning sections
+// that aren't relevant to behaviour are removed.
+func SanitiseConfig(conf Config) (interface{}, error) {
+	outConf, err := output.SanitiseConfig(conf.Output)
+	if err != nil {
+		return nil, err
+	}
+	return config.Sanitised{
+		"enabled": conf.Enabled,
+		"mode":    conf.Mode,
+		"fields":  conf.Fields,
+		"output":  outConf,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// recordBufferSize is the number of audit messages that can be pending whilst
+// the output is busy before further audits are skipped.
+const recordBufferSize = 1000
+
+// outputManager wraps the manager given to the audit output, which gives
+// components of the output their own drop hooks. This prevents messages that
+// are dropped by the output itself from being audited again.
+type outputManager struct {
+	types.Manager
+}
+
+//------------------------------------------------------------------------------
+
+// Type receives messages reported as dropped by components and sends either a
+// copy of them or a compact record describing them to an output.
+type Type struct {
+	running    int32
+	removeHook func()
+
+	copyMode bool
+	fields   []string
+
+	log   log.Modular
+	stats metrics.Type
+
+	out          types.Output
+	records      chan types.Message
+	transactions chan types.Transaction
+
+	mDropped metrics.StatCounter
+	mSkipped metrics.StatCounter
+	mSent    metrics.StatCounter
+	mErr     metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// New creates a new audit sink from a config and registers it as a drop hook of
+// the manager.
+func New(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (*Type, error) {
+	t := &Type{
+		running:      1,
+		fields:       conf.Fields,
+		log:          log,
+		stats:        stats,
+		records:      make(chan types.Message, recordBufferSize),
+		transactions: make(chan types.Transaction),
+		mDropped:     stats.GetCounter("dropped"),
+		mSkipped:     stats.GetCounter("skipped"),
+		mSent:        stats.GetCounter("sent"),
+		mErr:         stats.GetCounter("error"),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+
+	switch conf.Mode {
+	case "record":
+	case "copy":
+		t.copyMode = true
+	default:
+		return nil, fmt.Errorf("drop audit mode not recognised: %v", conf.Mode)
+	}
+
+	var err error
+	if t.out, err = output.New(
+		conf.Output, &outputManager{Manager: mgr},
+		log.NewModule(".output"), metrics.Namespaced(stats, "output"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create drop audit output: %v", err)
+	}
+	if err = t.out.Consume(t.transactions); err != nil {
+		t.out.CloseAsync()
+		return nil, err
+	}
+
+	go t.loop()
+	t.removeHook = drop.AddHook(mgr, t.report)
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+type dropRecord struct {
+	Reason    string                 `json:"reason"`
+	Timestamp string                 `json:"timestamp"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// record creates a message containing a record for each dropped message part.
+func (t *Type) record(reason string, msg types.Message) types.Message {
+	ts := time.Now().Format(time.RFC3339Nano)
+	recMsg := message.New(nil)
+	msg.Iter(func(i int, p types.Part) error {
+		rec := dropRecord{
+			Reason:    reason,
+			Timestamp: ts,
+		}
+		if len(t.fields) > 0 {
+			if jObj, err := p.JSON(); err == nil {
+				gObj, _ := gabs.Consume(jObj)
+				rec.Fields = map[string]interface{}{}
+				for _, path := range t.fields {
+					if gObj.ExistsP(path) {
+						rec.Fields[path] = gObj.Path(path).Data()
+					}
+				}
+			}
+		}
+		recBytes, err := json.Marshal(rec)
+		if err != nil {
+			t.log.Errorf("Failed to serialise drop record: %v\n", err)
+			return nil
+		}
+		recMsg.Append(message.NewPart(recBytes))
+		return nil
+	})
+	return recMsg
+}
+
+// report is the drop hook, which buffers an audit message for the loop. Audits
+// are skipped rather than blocking the component dropping messages when the
+// buffer is full.
+func (t *Type) report(reason string, msg types.Message) {
+	t.mDropped.Incr(int64(msg.Len()))
+
+	var auditMsg types.Message
+	if t.copyMode {
+		auditMsg = msg.Copy()
+	} else {
+		auditMsg = t.record(reason, msg)
+	}
+	auditMsg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set("drop_reason", reason)
+		return nil
+	})
+
+	select {
+	case <-t.closeChan:
+		t.log.Warnf("Failed to audit %v messages dropped by %v due to shut down\n", msg.Len(), reason)
+		return
+	default:
+	}
+	select {
+	case t.records <- auditMsg:
+	default:
+		t.mSkipped.Incr(int64(msg.Len()))
+		t.log.Debugf("Skipped audit of %v messages dropped by %v due to a full buffer\n", msg.Len(), reason)
+	}
+}
+
+func (t *Type) loop() {
+	defer func() {
+		close(t.transactions)
+		close(t.closedChan)
+	}()
+
+	throt := throttle.New(throttle.OptCloseChan(t.closeChan))
+	resChan := make(chan types.Response)
+
+	for {
+		var msg types.Message
+		select {
+		case msg = <-t.records:
+		case <-t.closeChan:
+			return
+		}
+
+		for {
+			select {
+			case t.transactions <- types.NewTransaction(msg, resChan):
+			case <-t.closeChan:
+				return
+			}
+			var res types.Response
+			select {
+			case res = <-resChan:
+			case <-t.closeChan:
+				return
+			}
+			if err := res.Error(); err != nil {
+				t.log.Errorf("Failed to send drop audit message: %v\n", err)
+				t.mErr.Incr(1)
+				if !throt.Retry() {
+					return
+				}
+				continue
+			}
+			t.mSent.Incr(int64(msg.Len()))
+			throt.Reset()
+			break
+		}
+	}
+}
+
+// CloseAsync unregisters the drop hook and shuts down the audit output.
+func (t *Type) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&t.running, 1, 0) {
+		t.removeHook()
+		close(t.closeChan)
+		t.out.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the audit sink and its output have closed down.
+func (t *Type) WaitForClose(timeout time.Duration) error {
+	tStarted := time.Now()
+	select {
+	case <-t.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return t.out.WaitForClose(timeout - time.Since(tStarted))
+}
+
+//------------------------------------------------------------------------------
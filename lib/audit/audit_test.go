@@ -0,0 +1,233 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newAuditTest(t *testing.T, conf Config, stats metrics.Type) (*Type, types.Manager, <-chan types.Transaction) {
+	mgr, err := manager.New(manager.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Enabled = true
+	conf.Output.Type = "inproc"
+	conf.Output.Inproc = "drop_audit_test"
+
+	a, err := New(conf, mgr, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pipe <-chan types.Transaction
+	for i := 0; i < 100 && pipe == nil; i++ {
+		if pipe, err = mgr.GetPipe("drop_audit_test"); err != nil {
+			<-time.After(time.Millisecond * 10)
+		}
+	}
+	if pipe == nil {
+		t.Fatal(err)
+	}
+	return a, mgr, pipe
+}
+
+func readAudit(t *testing.T, pipe <-chan types.Transaction) types.Message {
+	var tran types.Transaction
+	select {
+	case tran = <-pipe:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return tran.Payload
+}
+
+func TestAuditRecord(t *testing.T) {
+	conf := NewConfig()
+	conf.Fields = []string{"id", "user.name", "missing"}
+
+	a, mgr, pipe := newAuditTest(t, conf, metrics.Noop())
+	defer func() {
+		a.CloseAsync()
+		if err := a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	go drop.Report(mgr, "filter", message.New([][]byte{
+		[]byte(`{"id":"foo","user":{"name":"bar"},"content":"baz"}`),
+		[]byte(`not json`),
+	}))
+
+	msg := readAudit(t, pipe)
+	if exp, act := 2, msg.Len(); exp != act {
+		t.Fatalf("Wrong count of records: %v != %v", act, exp)
+	}
+
+	var rec dropRecord
+	if err := json.Unmarshal(msg.Get(0).Get(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "filter", rec.Reason; exp != act {
+		t.Errorf("Wrong reason: %v != %v", act, exp)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, rec.Timestamp); err != nil {
+		t.Errorf("Bad timestamp: %v", err)
+	}
+	if exp, act := 2, len(rec.Fields); exp != act {
+		t.Errorf("Wrong count of fields: %v != %v", act, exp)
+	}
+	if exp, act := "foo", rec.Fields["id"]; exp != act {
+		t.Errorf("Wrong field: %v != %v", act, exp)
+	}
+	if exp, act := "bar", rec.Fields["user.name"]; exp != act {
+		t.Errorf("Wrong field: %v != %v", act, exp)
+	}
+	if exp, act := "filter", msg.Get(1).Metadata().Get("drop_reason"); exp != act {
+		t.Errorf("Wrong drop_reason metadata: %v != %v", act, exp)
+	}
+}
+
+func TestAuditCopy(t *testing.T) {
+	conf := NewConfig()
+	conf.Mode = "copy"
+
+	a, mgr, pipe := newAuditTest(t, conf, metrics.Noop())
+
+	dropped := message.New([][]byte{[]byte("hello world")})
+	dropped.Get(0).Metadata().Set("foo", "bar")
+	go drop.Report(mgr, "dedupe", dropped)
+
+	msg := readAudit(t, pipe)
+	if exp, act := "hello world", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong content: %v != %v", act, exp)
+	}
+	if exp, act := "bar", msg.Get(0).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "dedupe", msg.Get(0).Metadata().Get("drop_reason"); exp != act {
+		t.Errorf("Wrong drop_reason metadata: %v != %v", act, exp)
+	}
+	if dropped.Get(0).Metadata().Get("drop_reason") != "" {
+		t.Error("Original message was modified")
+	}
+
+	a.CloseAsync()
+	if err := a.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	// Reporting after close must not block.
+	drop.Report(mgr, "dedupe", dropped)
+}
+
+func TestAuditFullBuffer(t *testing.T) {
+	stats := metrics.NewLocal()
+	a, mgr, _ := newAuditTest(t, NewConfig(), stats)
+	defer func() {
+		a.CloseAsync()
+		if err := a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	doneChan := make(chan struct{})
+	go func() {
+		for i := 0; i < recordBufferSize+10; i++ {
+			drop.Report(mgr, "filter", message.New([][]byte{[]byte("foo")}))
+		}
+		close(doneChan)
+	}()
+	select {
+	case <-doneChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Reports blocked on a full buffer")
+	}
+
+	if act := stats.GetCounters()["skipped"]; act == 0 {
+		t.Error("Expected skipped audits")
+	}
+}
+
+func TestAuditIgnoresOwnDrops(t *testing.T) {
+	mgr, err := manager.New(manager.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Mode = "copy"
+	conf.Output.Type = "drop"
+
+	stats := metrics.NewLocal()
+	a, err := New(conf, mgr, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		a.CloseAsync()
+		if err := a.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	drop.Report(mgr, "filter", message.New([][]byte{[]byte("foo")}))
+	for i := 0; i < 100 && stats.GetCounters()["sent"] == 0; i++ {
+		<-time.After(time.Millisecond * 10)
+	}
+	<-time.After(time.Millisecond * 50)
+
+	counters := stats.GetCounters()
+	if exp, act := int64(1), counters["sent"]; exp != act {
+		t.Errorf("Wrong count of sent audits: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), counters["dropped"]; exp != act {
+		t.Errorf("Wrong count of audited drops: %v != %v", act, exp)
+	}
+}
+
+func TestAuditBadMode(t *testing.T) {
+	conf := NewConfig()
+	conf.Mode = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+}
+
+//------------------------------------------------------------------------------
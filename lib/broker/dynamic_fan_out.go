@@ -295,6 +295,7 @@ func (d *DynamicFanOut) loop() {
 		mMsgsRcd.Incr(1)
 
 		// If we received a message attempt to send it to each output.
+		var skipAck bool
 		remainingTargets := make(map[string]outputWithResChan, len(d.outputs))
 		for k, v := range d.outputs {
 			remainingTargets[k] = v
@@ -327,6 +328,9 @@ func (d *DynamicFanOut) loop() {
 					} else {
 						d.throt.Reset()
 						mMsgsSnt.Incr(1)
+						if res.SkipAck() {
+							skipAck = true
+						}
 						delete(remainingTargets, k)
 					}
 				case <-d.closeChan:
@@ -334,8 +338,12 @@ func (d *DynamicFanOut) loop() {
 				}
 			}
 		}
+		var res types.Response = response.NewAck()
+		if skipAck {
+			res = response.NewUnack()
+		}
 		select {
-		case ts.ResponseChan <- response.NewAck():
+		case ts.ResponseChan <- res:
 		case <-d.closeChan:
 			return
 		}
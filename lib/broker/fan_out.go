@@ -138,6 +138,7 @@ func (o *FanOut) loop() {
 		}
 		mMsgsRcvd.Incr(1)
 
+		var skipAck bool
 		outputTargets := o.outputNs
 		for len(outputTargets) > 0 {
 			for _, i := range outputTargets {
@@ -162,6 +163,9 @@ func (o *FanOut) loop() {
 					} else {
 						o.throt.Reset()
 						mMsgsSnt.Incr(1)
+						if res.SkipAck() {
+							skipAck = true
+						}
 					}
 				case <-o.closeChan:
 					return
@@ -169,8 +173,14 @@ func (o *FanOut) loop() {
 			}
 			outputTargets = newTargets
 		}
+		var res types.Response = response.NewAck()
+		if skipAck {
+			// At least one output is holding on to the message, e.g. within a
+			// batching policy, and will acknowledge it later.
+			res = response.NewUnack()
+		}
 		select {
-		case ts.ResponseChan <- response.NewAck():
+		case ts.ResponseChan <- res:
 		case <-o.closeChan:
 			return
 		}
@@ -133,6 +133,7 @@ func (o *FanOutSequential) loop() {
 			return
 		}
 
+		var skipAck bool
 		for _, i := range o.outputNs {
 		retryLoop:
 			for {
@@ -151,6 +152,9 @@ func (o *FanOutSequential) loop() {
 						}
 					} else {
 						o.throt.Reset()
+						if res.SkipAck() {
+							skipAck = true
+						}
 						break retryLoop
 					}
 				case <-o.closeChan:
@@ -159,8 +163,12 @@ func (o *FanOutSequential) loop() {
 			}
 		}
 
+		var res types.Response = response.NewAck()
+		if skipAck {
+			res = response.NewUnack()
+		}
 		select {
-		case ts.ResponseChan <- response.NewAck():
+		case ts.ResponseChan <- res:
 		case <-o.closeChan:
 			return
 		}
@@ -126,6 +126,73 @@ func TestBasicFanOut(t *testing.T) {
 	}
 }
 
+func TestFanOutUnackPropagation(t *testing.T) {
+	mockOne, mockTwo := MockOutputType{}, MockOutputType{}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewFanOut(
+		[]types.Output{&mockOne, &mockTwo}, log.Noop(), metrics.Noop(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		oTM.CloseAsync()
+		if err := oTM.WaitForClose(time.Second * 5); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	for _, holdAck := range []bool{true, false} {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte("hello")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		var ts1, ts2 types.Transaction
+		select {
+		case ts1 = <-mockOne.TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for mockOne")
+		}
+		select {
+		case ts2 = <-mockTwo.TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for mockTwo")
+		}
+		select {
+		case ts1.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		var res types.Response = response.NewAck()
+		if holdAck {
+			res = response.NewUnack()
+		}
+		select {
+		case ts2.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Errorf("Received unexpected errors from broker: %v", res.Error())
+			}
+			if exp, act := holdAck, res.SkipAck(); exp != act {
+				t.Errorf("Wrong skip ack value: %v != %v", act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+	}
+}
+
 func TestFanOutAtLeastOnce(t *testing.T) {
 	mockOne := MockOutputType{}
 	mockTwo := MockOutputType{}
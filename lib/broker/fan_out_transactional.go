@@ -0,0 +1,276 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// FanOutTransactional is a broker that implements types.Consumer and
+// broadcasts each message out to an array of outputs using a two-phase commit,
+// where a message is first staged by every output and is then either committed
+// by all outputs or aborted by all outputs.
+type FanOutTransactional struct {
+	running int32
+
+	logger log.Modular
+	stats  metrics.Type
+
+	throt *throttle.Type
+
+	transactions <-chan types.Transaction
+
+	outputTsChans  []chan types.Transaction
+	outputResChans []chan types.Response
+	outputs        []types.Output
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewFanOutTransactional creates a new FanOutTransactional type by providing
+// outputs. Each output must be able to stage writes, otherwise they would be
+// written to during the first phase and could not be rolled back.
+func NewFanOutTransactional(
+	outputs []types.Output, logger log.Modular, stats metrics.Type,
+) (*FanOutTransactional, error) {
+	for i, out := range outputs {
+		if s, ok := out.(types.Stager); !ok || !s.CanStage() {
+			return nil, fmt.Errorf("output '%v' does not support staging writes", i)
+		}
+	}
+
+	o := &FanOutTransactional{
+		running:      1,
+		stats:        stats,
+		logger:       logger,
+		transactions: nil,
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		closeChan:    make(chan struct{}),
+	}
+	o.throt = throttle.New(throttle.OptCloseChan(o.closeChan))
+
+	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
+	o.outputResChans = make([]chan types.Response, len(o.outputs))
+	for i := range o.outputTsChans {
+		o.outputTsChans[i] = make(chan types.Transaction)
+		o.outputResChans[i] = make(chan types.Response)
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the broker to read.
+func (o *FanOutTransactional) Consume(transactions <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = transactions
+
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *FanOutTransactional) Connected() bool {
+	for _, out := range o.outputs {
+		if !out.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// prepare sends a message to all outputs and returns the writes staged by each
+// output along with whether all outputs succeeded. If any output failed then
+// all staged writes are aborted. An output that acknowledges without staging,
+// which happens when a batch is empty, has a nil entry in the returned slice.
+// The final return value is false if the broker was closed before the message
+// could be dispatched.
+func (o *FanOutTransactional) prepare(msg types.Message) ([]types.PreparedWrite, bool, bool) {
+	for i := range o.outputTsChans {
+		select {
+		case o.outputTsChans[i] <- types.NewPrepareTransaction(msg.Copy(), o.outputResChans[i]):
+		case <-o.closeChan:
+			return nil, false, false
+		}
+	}
+
+	var failed bool
+	prepared := make([]types.PreparedWrite, len(o.outputTsChans))
+	for i := range o.outputResChans {
+		select {
+		case res := <-o.outputResChans[i]:
+			if err := res.Error(); err != nil {
+				o.logger.Errorf("Failed to prepare fan out message: %v\n", err)
+				failed = true
+			} else if p, ok := res.(types.PreparedWrite); ok {
+				prepared[i] = p
+			}
+		case <-o.closeChan:
+			failed = true
+		}
+	}
+
+	if failed {
+		for _, p := range prepared {
+			if p == nil {
+				continue
+			}
+			if err := p.Abort(); err != nil {
+				o.logger.Errorf("Failed to abort fan out message: %v\n", err)
+			}
+		}
+		return nil, false, true
+	}
+	return prepared, true, true
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *FanOutTransactional) loop() {
+	defer func() {
+		for _, c := range o.outputTsChans {
+			close(c)
+		}
+		close(o.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd     = o.stats.GetCounter("messages.received")
+		mOutputErr    = o.stats.GetCounter("error")
+		mMsgsSnt      = o.stats.GetCounter("messages.sent")
+		mAborted      = o.stats.GetCounter("transaction.aborted")
+		mCommitted    = o.stats.GetCounter("transaction.committed")
+		mCommitFailed = o.stats.GetCounter("transaction.commit.error")
+		mInconsistent = o.stats.GetCounter("transaction.inconsistent")
+	)
+
+	for atomic.LoadInt32(&o.running) == 1 {
+		var ts types.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-o.transactions:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mMsgsRcvd.Incr(1)
+
+		var prepared []types.PreparedWrite
+		for {
+			var ok, running bool
+			if prepared, ok, running = o.prepare(ts.Payload); !running {
+				return
+			}
+			if ok {
+				o.throt.Reset()
+				break
+			}
+			mOutputErr.Incr(1)
+			mAborted.Incr(1)
+			if !o.throt.Retry() {
+				return
+			}
+		}
+
+		// A commit that fails cannot be retried, as the staged write is
+		// finished either way, and the outputs that have already committed
+		// cannot be rolled back. Therefore the remaining writes are aborted and
+		// the message is nacked, leaving the outputs inconsistent.
+		var commitErr error
+		for i, p := range prepared {
+			if p == nil {
+				continue
+			}
+			if commitErr != nil {
+				if err := p.Abort(); err != nil {
+					o.logger.Errorf("Failed to abort fan out message: %v\n", err)
+				}
+				continue
+			}
+			if err := p.Commit(); err != nil {
+				o.logger.Errorf(
+					"Failed to commit fan out message to output '%v', outputs committed before it are now inconsistent: %v\n", i, err,
+				)
+				mCommitFailed.Incr(1)
+				mInconsistent.Incr(1)
+				commitErr = fmt.Errorf("failed to commit message to output '%v': %v", i, err)
+			}
+		}
+
+		var res types.Response
+		if commitErr != nil {
+			mOutputErr.Incr(1)
+			res = response.NewError(commitErr)
+		} else {
+			mCommitted.Incr(1)
+			mMsgsSnt.Incr(1)
+			res = response.NewAck()
+		}
+
+		select {
+		case ts.ResponseChan <- res:
+		case <-o.closeChan:
+			return
+		}
+	}
+}
+
+// CloseAsync shuts down the FanOutTransactional broker and stops processing
+// requests.
+func (o *FanOutTransactional) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the FanOutTransactional broker has closed down.
+func (o *FanOutTransactional) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
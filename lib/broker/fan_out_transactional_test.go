@@ -0,0 +1,267 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockPreparedWrite struct {
+	committed int
+	aborted   int
+	commitErr error
+}
+
+func (m *mockPreparedWrite) Commit() error {
+	m.committed++
+	return m.commitErr
+}
+
+func (m *mockPreparedWrite) Abort() error {
+	m.aborted++
+	return nil
+}
+
+type mockStagingOutput struct {
+	*MockOutputType
+}
+
+func (m mockStagingOutput) CanStage() bool {
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+func TestFanOutTransactionalInterfaces(t *testing.T) {
+	f := &FanOutTransactional{}
+	if types.Consumer(f) == nil {
+		t.Errorf("FanOutTransactional: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("FanOutTransactional: nil types.Closable")
+	}
+}
+
+func TestFanOutTransactionalCommitAndAbort(t *testing.T) {
+	outputs := []types.Output{}
+	mockOutputs := []*MockOutputType{}
+	for i := 0; i < 2; i++ {
+		mockOutputs = append(mockOutputs, &MockOutputType{})
+		outputs = append(outputs, mockStagingOutput{mockOutputs[i]})
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewFanOutTransactional(
+		outputs, log.New(os.Stdout, logConfig), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		oTM.CloseAsync()
+		if err := oTM.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker send")
+	}
+
+	receive := func() []types.Transaction {
+		trans := []types.Transaction{}
+		for _, out := range mockOutputs {
+			select {
+			case ts := <-out.TChan:
+				if !ts.Prepare {
+					t.Error("Expected prepare transaction")
+				}
+				if exp, act := "foo", string(ts.Payload.Get(0).Get()); exp != act {
+					t.Errorf("Wrong content returned %s != %s", act, exp)
+				}
+				trans = append(trans, ts)
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for broker propagate")
+			}
+		}
+		return trans
+	}
+
+	// First attempt, second output fails to stage the message.
+	firstAttempt := &mockPreparedWrite{}
+	trans := receive()
+	select {
+	case trans[0].ResponseChan <- response.NewPrepared(firstAttempt):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+	select {
+	case trans[1].ResponseChan <- response.NewError(errors.New("nope")):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+
+	// Second attempt, both outputs succeed.
+	secondAttempt := &mockPreparedWrite{}
+	thirdAttempt := &mockPreparedWrite{}
+	trans = receive()
+	select {
+	case trans[0].ResponseChan <- response.NewPrepared(secondAttempt):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+	select {
+	case trans[1].ResponseChan <- response.NewPrepared(thirdAttempt):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Errorf("Received unexpected errors from broker: %v", res.Error())
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for broker response")
+	}
+
+	if exp, act := 1, firstAttempt.aborted; exp != act {
+		t.Errorf("Wrong count of aborts: %v != %v", act, exp)
+	}
+	if exp, act := 0, firstAttempt.committed; exp != act {
+		t.Errorf("Wrong count of commits: %v != %v", act, exp)
+	}
+	if exp, act := 0, secondAttempt.aborted; exp != act {
+		t.Errorf("Wrong count of aborts: %v != %v", act, exp)
+	}
+	if exp, act := 1, secondAttempt.committed; exp != act {
+		t.Errorf("Wrong count of commits: %v != %v", act, exp)
+	}
+	if exp, act := 1, thirdAttempt.committed; exp != act {
+		t.Errorf("Wrong count of commits: %v != %v", act, exp)
+	}
+}
+
+func TestFanOutTransactionalCommitFailure(t *testing.T) {
+	outputs := []types.Output{}
+	mockOutputs := []*MockOutputType{}
+	for i := 0; i < 3; i++ {
+		mockOutputs = append(mockOutputs, &MockOutputType{})
+		outputs = append(outputs, mockStagingOutput{mockOutputs[i]})
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewFanOutTransactional(
+		outputs, log.New(os.Stdout, logConfig), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		oTM.CloseAsync()
+		if err := oTM.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker send")
+	}
+
+	writes := []*mockPreparedWrite{
+		{},
+		{commitErr: errors.New("nope")},
+		{},
+	}
+	trans := []types.Transaction{}
+	for _, out := range mockOutputs {
+		select {
+		case ts := <-out.TChan:
+			trans = append(trans, ts)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+	}
+	for i, ts := range trans {
+		select {
+		case ts.ResponseChan <- response.NewPrepared(writes[i]):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() == nil {
+			t.Error("Expected error from failed commit")
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for broker response")
+	}
+
+	for i, exp := range [][2]int{{1, 0}, {1, 0}, {0, 1}} {
+		if act := writes[i].committed; act != exp[0] {
+			t.Errorf("Wrong count of commits for output %v: %v != %v", i, act, exp[0])
+		}
+		if act := writes[i].aborted; act != exp[1] {
+			t.Errorf("Wrong count of aborts for output %v: %v != %v", i, act, exp[1])
+		}
+	}
+}
+
+func TestFanOutTransactionalNotStaging(t *testing.T) {
+	outputs := []types.Output{
+		mockStagingOutput{&MockOutputType{}},
+		&MockOutputType{},
+	}
+	if _, err := NewFanOutTransactional(
+		outputs, log.New(os.Stdout, logConfig), metrics.DudType{},
+	); err == nil {
+		t.Error("Expected error from output that cannot stage writes")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -50,28 +50,31 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each buffer type.
 const (
-	TypeMemory = "memory"
-	TypeMMAP   = "mmap_file"
-	TypeNone   = "none"
+	TypeDeferUntil = "defer_until"
+	TypeMemory     = "memory"
+	TypeMMAP       = "mmap_file"
+	TypeNone       = "none"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all buffer types.
 type Config struct {
-	Type   string              `json:"type" yaml:"type"`
-	Memory single.MemoryConfig `json:"memory" yaml:"memory"`
-	Mmap   MmapBufferConfig    `json:"mmap_file,omitempty" yaml:"mmap_file,omitempty"`
-	None   struct{}            `json:"none" yaml:"none"`
+	Type       string              `json:"type" yaml:"type"`
+	DeferUntil DeferUntilConfig    `json:"defer_until" yaml:"defer_until"`
+	Memory     single.MemoryConfig `json:"memory" yaml:"memory"`
+	Mmap       MmapBufferConfig    `json:"mmap_file,omitempty" yaml:"mmap_file,omitempty"`
+	None       struct{}            `json:"none" yaml:"none"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:   "none",
-		Memory: single.NewMemoryConfig(),
-		Mmap:   NewMmapBufferConfig(),
-		None:   struct{}{},
+		Type:       "none",
+		DeferUntil: NewDeferUntilConfig(),
+		Memory:     single.NewMemoryConfig(),
+		Mmap:       NewMmapBufferConfig(),
+		None:       struct{}{},
 	}
 }
 
@@ -153,17 +156,21 @@ different options and their qualities:
 
 #### Performance
 
-| Type      | Throughput | Consumers | Capacity |
-| --------- | ---------- | --------- | -------- |
-| Memory    | Highest    | Parallel  | RAM      |
-| Mmap File | High       | Single    | Disk     |
+| Type        | Throughput | Consumers | Capacity |
+| ----------- | ---------- | --------- | -------- |
+| Defer Until | High       | Parallel  | RAM      |
+| Memory      | Highest    | Parallel  | RAM      |
+| Mmap File   | High       | Single    | Disk     |
 
 #### Delivery Guarantees
 
-| Type      | On Restart | On Crash  | On Disk Corruption |
-| --------- | ---------- | --------- | ------------------ |
-| Memory    | Lost       | Lost      | Lost               |
-| Mmap File | Persisted  | Lost      | Lost               |`
+| Type        | On Restart | On Crash  | On Disk Corruption |
+| ----------- | ---------- | --------- | ------------------ |
+| Defer Until | Persisted* | Persisted* | Lost              |
+| Memory      | Lost       | Lost      | Lost               |
+| Mmap File   | Persisted  | Lost      | Lost               |
+
+\* When a ` + "`directory`" + ` is configured.`
 
 // Descriptions returns a formatted string of collated descriptions of each type.
 func Descriptions() string {
@@ -0,0 +1,122 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package buffer
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/buffer/parallel"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDeferUntil] = TypeSpec{
+		constructor: NewDeferUntil,
+		description: `
+The defer_until buffer holds each message until a timestamp extracted from it
+has been reached, allowing scheduled or delayed delivery of messages. The field
+` + "`timestamp`" + ` supports
+[interpolation functions](../config_interpolation.md#functions) and is resolved
+against the message when it enters the buffer. It should result in either a
+unix timestamp in seconds, which may contain a fractional component, or an
+RFC 3339 formatted timestamp. Messages where the timestamp is empty, has
+already passed or cannot be parsed are made available immediately.
+
+Messages are released in the order of their timestamps rather than the order
+in which they were received.
+
+If a ` + "`directory`" + ` is specified then each message is written to a file
+within it until it has been delivered, and any messages found within the
+directory are loaded when the buffer starts. This allows deferred messages to
+survive a restart of the service, in which case messages that were in flight
+at the time may be delivered more than once. Without a directory messages are
+held in memory only and are lost if the service is stopped.
+
+The ` + "`limit`" + ` field sets the maximum total size in bytes of deferred
+messages, once reached the buffer applies back pressure until messages are
+delivered.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DeferUntilConfig contains configuration fields for the defer_until buffer.
+type DeferUntilConfig struct {
+	Timestamp string `json:"timestamp" yaml:"timestamp"`
+	Directory string `json:"directory" yaml:"directory"`
+	Limit     int    `json:"limit" yaml:"limit"`
+}
+
+// NewDeferUntilConfig creates a DeferUntilConfig with default values.
+func NewDeferUntilConfig() DeferUntilConfig {
+	return DeferUntilConfig{
+		Timestamp: "${!metadata:defer_until}",
+		Directory: "",
+		Limit:     524288000,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// parseDeferTimestamp parses either an RFC 3339 formatted timestamp or a unix
+// timestamp in seconds, which may contain a fractional component.
+func parseDeferTimestamp(str string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(str, 64); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, str)
+}
+
+// NewDeferUntil creates a buffer that holds messages until a timestamp
+// extracted from each message is reached.
+func NewDeferUntil(config Config, log log.Modular, stats metrics.Type) (Type, error) {
+	timestamp := text.NewInterpolatedString(config.DeferUntil.Timestamp)
+	mParseErr := stats.GetCounter("defer_until.timestamp.error")
+
+	dueFn := func(msg types.Message) time.Time {
+		tsStr := timestamp.Get(msg)
+		if len(tsStr) == 0 {
+			return time.Time{}
+		}
+		ts, err := parseDeferTimestamp(tsStr)
+		if err != nil {
+			mParseErr.Incr(1)
+			log.Errorf("Failed to parse timestamp '%v', delivering immediately: %v\n", tsStr, err)
+			return time.Time{}
+		}
+		return ts
+	}
+
+	b, err := parallel.NewDeferred(config.DeferUntil.Limit, config.DeferUntil.Directory, dueFn)
+	if err != nil {
+		return nil, err
+	}
+	return NewParallelWrapper(config, b, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,115 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package buffer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestParseDeferTimestamp(t *testing.T) {
+	tests := map[string]time.Time{
+		"1500000000":                   time.Unix(1500000000, 0),
+		"1500000000.5":                 time.Unix(1500000000, 500000000),
+		"2017-07-14T02:40:00Z":         time.Unix(1500000000, 0),
+		"2017-07-14T02:40:00.25+00:00": time.Unix(1500000000, 250000000),
+	}
+	for input, exp := range tests {
+		act, err := parseDeferTimestamp(input)
+		if err != nil {
+			t.Errorf("Failed to parse '%v': %v", input, err)
+		} else if !act.Equal(exp) {
+			t.Errorf("Wrong result for '%v': %v != %v", input, act, exp)
+		}
+	}
+	if _, err := parseDeferTimestamp("not a timestamp"); err == nil {
+		t.Error("Expected error from bad timestamp")
+	}
+}
+
+func TestDeferUntilBuffer(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "defer_until"
+
+	buf, err := New(conf, log.New(os.Stdout, logConfig), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = buf.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	due := time.Now().Add(100 * time.Millisecond)
+
+	later := message.New([][]byte{[]byte(`later`)})
+	later.Get(0).Metadata().Set("defer_until", due.Format(time.RFC3339Nano))
+	now := message.New([][]byte{[]byte(`now`)})
+
+	for _, msg := range []types.Message{later, now} {
+		select {
+		case tChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	for _, exp := range []string{`now`, `later`} {
+		var outTr types.Transaction
+		select {
+		case outTr = <-buf.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		if act := string(outTr.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		if exp == `later` && time.Now().Before(due) {
+			t.Error("Message delivered before due time")
+		}
+		select {
+		case outTr.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	buf.CloseAsync()
+	if err := buf.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parallel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+const deferredFileExt = ".deferred"
+
+// DueFunc returns the time at which a message should become available for
+// consumption. A zero time indicates that the message is available
+// immediately.
+type DueFunc func(msg types.Message) time.Time
+
+type deferredPart struct {
+	Content  []byte            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type deferredRecord struct {
+	Due   int64          `json:"due"`
+	Parts []deferredPart `json:"parts"`
+}
+
+type deferredEntry struct {
+	seq  uint64
+	due  time.Time
+	size int
+	msg  types.Message
+}
+
+//------------------------------------------------------------------------------
+
+// Deferred is a parallel buffer implementation that holds each message until a
+// due time, extracted from the message when it is pushed, has been reached.
+// Messages are consumed in order of their due times rather than the order in
+// which they were pushed.
+//
+// When a directory is provided each message is also written to a file within
+// it until acknowledged, and any files found within the directory are loaded
+// when the buffer is created. This allows deferred messages to survive a
+// restart.
+type Deferred struct {
+	dir   string
+	dueFn DueFunc
+
+	pending  []*deferredEntry
+	inFlight int
+	bytes    int
+	nextSeq  uint64
+
+	cap  int
+	cond *sync.Cond
+
+	closed bool
+}
+
+// NewDeferred creates a parallel buffer that defers messages until their due
+// time. If dir is non-empty then messages are persisted to and restored from
+// files within it.
+func NewDeferred(cap int, dir string, dueFn DueFunc) (*Deferred, error) {
+	d := &Deferred{
+		dir:   dir,
+		dueFn: dueFn,
+		cap:   cap,
+		cond:  sync.NewCond(&sync.Mutex{}),
+	}
+	if len(dir) > 0 {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		if err := d.load(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+func messageSize(msg types.Message) int {
+	size := 0
+	msg.Iter(func(i int, b types.Part) error {
+		size += len(b.Get())
+		return nil
+	})
+	return size
+}
+
+func (d *Deferred) entryPath(seq uint64) string {
+	return filepath.Join(d.dir, fmt.Sprintf("%020d%v", seq, deferredFileExt))
+}
+
+// load reads all persisted messages from the buffer directory.
+func (d *Deferred) load() error {
+	infos, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, deferredFileExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, deferredFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		recBytes, err := ioutil.ReadFile(filepath.Join(d.dir, name))
+		if err != nil {
+			return err
+		}
+		var rec deferredRecord
+		if err = json.Unmarshal(recBytes, &rec); err != nil {
+			return fmt.Errorf("failed to decode deferred message '%v': %v", name, err)
+		}
+
+		msg := message.New(nil)
+		for _, p := range rec.Parts {
+			part := message.NewPart(p.Content)
+			for k, v := range p.Metadata {
+				part.Metadata().Set(k, v)
+			}
+			msg.Append(part)
+		}
+
+		entry := &deferredEntry{
+			seq:  seq,
+			due:  time.Unix(0, rec.Due),
+			size: messageSize(msg),
+			msg:  msg,
+		}
+		if rec.Due == 0 {
+			entry.due = time.Time{}
+		}
+		d.insert(entry)
+		d.bytes += entry.size
+		if seq >= d.nextSeq {
+			d.nextSeq = seq + 1
+		}
+	}
+	return nil
+}
+
+// persist writes an entry to the buffer directory, the file is written under
+// a temporary name and then renamed so that a partial write is never loaded.
+func (d *Deferred) persist(e *deferredEntry) error {
+	rec := deferredRecord{}
+	if !e.due.IsZero() {
+		rec.Due = e.due.UnixNano()
+	}
+	e.msg.Iter(func(i int, p types.Part) error {
+		dp := deferredPart{
+			Content: p.Get(),
+		}
+		p.Metadata().Iter(func(k, v string) error {
+			if dp.Metadata == nil {
+				dp.Metadata = map[string]string{}
+			}
+			dp.Metadata[k] = v
+			return nil
+		})
+		rec.Parts = append(rec.Parts, dp)
+		return nil
+	})
+
+	recBytes, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	path := d.entryPath(e.seq)
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, recBytes, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// insert adds an entry to the pending list, which is sorted by due time and
+// then by sequence.
+func (d *Deferred) insert(e *deferredEntry) {
+	i := sort.Search(len(d.pending), func(i int) bool {
+		p := d.pending[i]
+		if p.due.Equal(e.due) {
+			return p.seq > e.seq
+		}
+		return p.due.After(e.due)
+	})
+	d.pending = append(d.pending, nil)
+	copy(d.pending[i+1:], d.pending[i:])
+	d.pending[i] = e
+}
+
+//------------------------------------------------------------------------------
+
+// NextMessage reads the message with the earliest due time, blocking until
+// that time is reached. The message is preserved until the returned AckFunc
+// is called.
+func (d *Deferred) NextMessage() (types.Message, AckFunc, error) {
+	d.cond.L.Lock()
+	for {
+		if d.closed {
+			d.cond.L.Unlock()
+			return nil, nil, types.ErrTypeClosed
+		}
+		if len(d.pending) == 0 {
+			d.cond.Wait()
+			continue
+		}
+		wait := time.Until(d.pending[0].due)
+		if wait <= 0 {
+			break
+		}
+		timer := time.AfterFunc(wait, func() {
+			d.cond.L.Lock()
+			d.cond.Broadcast()
+			d.cond.L.Unlock()
+		})
+		d.cond.Wait()
+		timer.Stop()
+	}
+
+	entry := d.pending[0]
+	d.pending[0] = nil
+	d.pending = d.pending[1:]
+	d.inFlight++
+
+	d.cond.L.Unlock()
+
+	return entry.msg, func(ack bool) (int, error) {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+
+		if ack {
+			if len(d.dir) > 0 {
+				if err := os.Remove(d.entryPath(entry.seq)); err != nil && !os.IsNotExist(err) {
+					return 0, err
+				}
+			}
+			d.bytes -= entry.size
+		} else {
+			if d.closed {
+				return 0, types.ErrTypeClosed
+			}
+			d.insert(entry)
+		}
+		d.inFlight--
+		d.cond.Broadcast()
+
+		return d.bytes, nil
+	}, nil
+}
+
+// PushMessage adds a new message to the buffer. Returns the backlog in bytes.
+func (d *Deferred) PushMessage(msg types.Message) (int, error) {
+	extraBytes := messageSize(msg)
+	if extraBytes > d.cap {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	entry := &deferredEntry{
+		due:  d.dueFn(msg),
+		size: extraBytes,
+		msg:  msg.DeepCopy(),
+	}
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	if d.closed {
+		return 0, types.ErrTypeClosed
+	}
+	for (d.bytes + extraBytes) > d.cap {
+		d.cond.Wait()
+		if d.closed {
+			return 0, types.ErrTypeClosed
+		}
+	}
+
+	entry.seq = d.nextSeq
+	d.nextSeq++
+	if len(d.dir) > 0 {
+		if err := d.persist(entry); err != nil {
+			return 0, err
+		}
+	}
+
+	d.insert(entry)
+	d.bytes += extraBytes
+	d.cond.Broadcast()
+
+	return d.bytes, nil
+}
+
+// CloseOnceEmpty closes the Buffer once the buffer has been emptied. When
+// messages are persisted to a directory only in flight messages are waited
+// for, as pending messages will be restored when the buffer is next created.
+// This call blocks until the close is completed.
+func (d *Deferred) CloseOnceEmpty() {
+	d.cond.L.Lock()
+	for !d.closed {
+		if len(d.dir) > 0 && d.inFlight == 0 {
+			break
+		}
+		if len(d.dir) == 0 && d.bytes == 0 {
+			break
+		}
+		d.cond.Wait()
+	}
+	if !d.closed {
+		d.closed = true
+		d.cond.Broadcast()
+	}
+	d.cond.L.Unlock()
+}
+
+// Close closes the Buffer so that blocked readers or writers become
+// unblocked.
+func (d *Deferred) Close() {
+	d.cond.L.Lock()
+	d.closed = true
+	d.cond.Broadcast()
+	d.cond.L.Unlock()
+}
+
+//------------------------------------------------------------------------------
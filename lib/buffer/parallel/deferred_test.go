@@ -0,0 +1,192 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parallel
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func deferredDueFromMeta(msg types.Message) time.Time {
+	str := msg.Get(0).Metadata().Get("due")
+	if len(str) == 0 {
+		return time.Time{}
+	}
+	d, _ := time.ParseDuration(str)
+	return time.Now().Add(d)
+}
+
+func newDeferredMsg(content, due string) types.Message {
+	msg := message.New([][]byte{[]byte(content)})
+	if len(due) > 0 {
+		msg.Get(0).Metadata().Set("due", due)
+	}
+	return msg
+}
+
+func TestDeferredOrdering(t *testing.T) {
+	block, err := NewDeferred(100000, "", deferredDueFromMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	for _, m := range []types.Message{
+		newDeferredMsg("third", "60ms"),
+		newDeferredMsg("first", ""),
+		newDeferredMsg("second", "30ms"),
+	} {
+		if _, err = block.PushMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	for _, exp := range []string{"first", "second", "third"} {
+		m, ackFn, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(m.Get(0).Get()); act != exp {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		if _, err = ackFn(true); err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Messages delivered too early: %v", elapsed)
+	}
+}
+
+func TestDeferredNack(t *testing.T) {
+	block, err := NewDeferred(100000, "", deferredDueFromMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	if _, err = block.PushMessage(newDeferredMsg("foo", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ackFn, err := block.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ackFn(false); err != nil {
+		t.Fatal(err)
+	}
+
+	m, ackFn, err := block.NextMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := string(m.Get(0).Get()); act != "foo" {
+		t.Errorf("Wrong message: %v", act)
+	}
+	backlog, err := ackFn(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backlog != 0 {
+		t.Errorf("Wrong backlog: %v", backlog)
+	}
+}
+
+func TestDeferredClose(t *testing.T) {
+	block, err := NewDeferred(100000, "", deferredDueFromMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = block.PushMessage(newDeferredMsg("foo", "1h")); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(10 * time.Millisecond)
+		block.Close()
+	}()
+
+	if _, _, err = block.NextMessage(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestDeferredPersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_deferred_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	block, err := NewDeferred(100000, dir, deferredDueFromMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []types.Message{
+		newDeferredMsg("second", "20ms"),
+		newDeferredMsg("first", ""),
+	} {
+		if _, err = block.PushMessage(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block.CloseOnceEmpty()
+
+	if block, err = NewDeferred(100000, dir, deferredDueFromMeta); err != nil {
+		t.Fatal(err)
+	}
+	defer block.Close()
+
+	for _, exp := range []string{"first", "second"} {
+		m, ackFn, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(m.Get(0).Get()); act != exp {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		if exp == "second" {
+			if act := m.Get(0).Metadata().Get("due"); act != "20ms" {
+				t.Errorf("Metadata not restored: %v", act)
+			}
+		}
+		if _, err = ackFn(true); err != nil {
+			t.Error(err)
+		}
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) > 0 {
+		t.Errorf("Expected acknowledged messages to be removed, found %v files", len(infos))
+	}
+}
@@ -21,11 +21,15 @@
 package cache
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 )
@@ -39,7 +43,8 @@ func init() {
 The memory cache simply stores key/value pairs in a map held in memory. This
 cache is therefore reset every time the service restarts. Each item in the cache
 has a TTL set from the moment it was last edited, after which it will be removed
-during the next compaction.
+during the next compaction. The TTL of individual items can be overridden by
+the ` + "[`cache` processor](../processors/README.md#cache)" + `.
 
 A compaction only occurs during a write where the time since the last compaction
 is above the compaction interval. It is therefore possible to obtain values of
@@ -65,9 +70,14 @@ func NewMemoryConfig() MemoryConfig {
 
 //------------------------------------------------------------------------------
 
+// evictionReason is the reason given when reporting items removed due to their
+// TTL expiring.
+const evictionReason = "memory_cache_ttl"
+
 type item struct {
 	value []byte
 	ts    time.Time
+	ttl   time.Duration
 }
 
 // Memory is a memory based cache implementation.
@@ -77,6 +87,7 @@ type Memory struct {
 	compInterval   time.Duration
 	lastCompaction time.Time
 
+	mgr          types.Manager
 	stats        metrics.Type
 	mCompactions metrics.StatCounter
 	mKeys        metrics.StatGauge
@@ -98,6 +109,7 @@ func NewMemory(conf Config, mgr types.Manager, log log.Modular, stats metrics.Ty
 		ttl:            time.Second * time.Duration(conf.Memory.TTL),
 		compInterval:   interval,
 		lastCompaction: time.Now(),
+		mgr:            mgr,
 		stats:          stats,
 		mCompactions:   stats.GetCounter("compaction"),
 		mKeys:          stats.GetGauge("keys"),
@@ -106,17 +118,32 @@ func NewMemory(conf Config, mgr types.Manager, log log.Modular, stats metrics.Ty
 
 //------------------------------------------------------------------------------
 
-func (m *Memory) compaction() {
+// compaction removes expired items and returns them as a message, where each
+// part has the metadata field cache_key set, so that they can be reported as
+// dropped once the lock is released.
+func (m *Memory) compaction() types.Message {
 	if time.Since(m.lastCompaction) < m.compInterval {
-		return
+		return nil
 	}
 	m.mCompactions.Incr(1)
+	var evicted types.Message
 	for k, v := range m.items {
-		if time.Since(v.ts) >= m.ttl {
+		ttl := m.ttl
+		if v.ttl > 0 {
+			ttl = v.ttl
+		}
+		if time.Since(v.ts) >= ttl {
+			if evicted == nil {
+				evicted = message.New(nil)
+			}
+			part := message.NewPart(v.value)
+			part.Metadata().Set("cache_key", k)
+			evicted.Append(part)
 			delete(m.items, k)
 		}
 	}
 	m.lastCompaction = time.Now()
+	return evicted
 }
 
 // Get attempts to locate and return a cached value by its key, returns an error
@@ -133,11 +160,18 @@ func (m *Memory) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (m *Memory) Set(key string, value []byte) error {
+	return m.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL attempts to set the value of a key with an expiration that
+// overrides the default TTL of the cache. A ttl of zero uses the default.
+func (m *Memory) SetWithTTL(key string, value []byte, ttl time.Duration) error {
 	m.Lock()
-	m.compaction()
-	m.items[key] = item{value: value, ts: time.Now()}
+	evicted := m.compaction()
+	m.items[key] = item{value: value, ts: time.Now(), ttl: ttl}
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
+	drop.Report(m.mgr, evictionReason, evicted)
 	return nil
 }
 
@@ -145,37 +179,94 @@ func (m *Memory) Set(key string, value []byte) error {
 // keys fail.
 func (m *Memory) SetMulti(items map[string][]byte) error {
 	m.Lock()
-	m.compaction()
+	evicted := m.compaction()
 	for k, v := range items {
 		m.items[k] = item{value: v, ts: time.Now()}
 	}
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
+	drop.Report(m.mgr, evictionReason, evicted)
 	return nil
 }
 
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (m *Memory) Add(key string, value []byte) error {
+	return m.AddWithTTL(key, value, 0)
+}
+
+// AddWithTTL attempts to set the value of a key only if the key does not
+// already exist, with an expiration that overrides the default TTL of the
+// cache. A ttl of zero uses the default.
+func (m *Memory) AddWithTTL(key string, value []byte, ttl time.Duration) error {
 	m.Lock()
 	if _, exists := m.items[key]; exists {
 		m.Unlock()
 		return types.ErrKeyAlreadyExists
 	}
-	m.compaction()
-	m.items[key] = item{value: value, ts: time.Now()}
+	evicted := m.compaction()
+	m.items[key] = item{value: value, ts: time.Now(), ttl: ttl}
+	m.mKeys.Set(int64(len(m.items)))
+	m.Unlock()
+	drop.Report(m.mgr, evictionReason, evicted)
+	return nil
+}
+
+// Incr atomically adds delta to the integer value of a key and returns the
+// result, a key that does not exist is treated as zero.
+func (m *Memory) Incr(key string, delta int64) (int64, error) {
+	m.Lock()
+	current, exists := m.items[key]
+	var value int64
+	if exists {
+		var err error
+		if value, err = strconv.ParseInt(string(current.value), 10, 64); err != nil {
+			m.Unlock()
+			return 0, fmt.Errorf("value is not an integer: %v", err)
+		}
+	}
+	value += delta
+	evicted := m.compaction()
+	m.items[key] = item{
+		value: []byte(strconv.FormatInt(value, 10)),
+		ts:    time.Now(),
+		ttl:   current.ttl,
+	}
+	m.mKeys.Set(int64(len(m.items)))
+	m.Unlock()
+	drop.Report(m.mgr, evictionReason, evicted)
+	return value, nil
+}
+
+// CompareAndSwap sets the value of a key only if its current value is equal to
+// old. A ttl of zero uses the default TTL of the cache.
+func (m *Memory) CompareAndSwap(key string, old, value []byte, ttl time.Duration) error {
+	m.Lock()
+	current, exists := m.items[key]
+	if !exists {
+		m.Unlock()
+		return types.ErrKeyNotFound
+	}
+	if !bytes.Equal(current.value, old) {
+		m.Unlock()
+		return types.ErrValueMismatch
+	}
+	evicted := m.compaction()
+	m.items[key] = item{value: value, ts: time.Now(), ttl: ttl}
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
+	drop.Report(m.mgr, evictionReason, evicted)
 	return nil
 }
 
 // Delete attempts to remove a key.
 func (m *Memory) Delete(key string) error {
 	m.Lock()
-	m.compaction()
+	evicted := m.compaction()
 	delete(m.items, key)
 	m.mKeys.Set(int64(len(m.items)))
 	m.Unlock()
+	drop.Report(m.mgr, evictionReason, evicted)
 	return nil
 }
 
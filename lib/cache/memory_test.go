@@ -23,6 +23,7 @@ package cache
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -141,4 +142,123 @@ func TestMemoryCacheCompaction(t *testing.T) {
 	}
 }
 
+func TestMemoryCacheIncr(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Type = "memory"
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter, ok := c.(types.CacheCounter)
+	if !ok {
+		t.Fatal("Memory cache does not implement CacheCounter")
+	}
+
+	for _, test := range []struct {
+		delta int64
+		exp   int64
+	}{
+		{1, 1}, {5, 6}, {-10, -4},
+	} {
+		act, err := counter.Incr("foo", test.delta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act != test.exp {
+			t.Errorf("Wrong result: %v != %v", act, test.exp)
+		}
+	}
+
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if exp := "-4"; string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+
+	if err = c.Set("bar", []byte("not a number")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = counter.Incr("bar", 1); err == nil {
+		t.Error("Expected error from non-integer value")
+	}
+}
+
+func TestMemoryCacheCompareAndSwap(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Type = "memory"
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cas, ok := c.(types.CacheCompareAndSwapper)
+	if !ok {
+		t.Fatal("Memory cache does not implement CacheCompareAndSwapper")
+	}
+
+	if err = cas.CompareAndSwap("foo", []byte("1"), []byte("2"), 0); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err = cas.CompareAndSwap("foo", []byte("2"), []byte("3"), 0); err != types.ErrValueMismatch {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrValueMismatch)
+	}
+	if err = cas.CompareAndSwap("foo", []byte("1"), []byte("3"), 0); err != nil {
+		t.Error(err)
+	}
+
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if exp := "3"; string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+}
+
+func TestMemoryCacheTTLOverride(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.CompactionInterval = ""
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttlCache, ok := c.(types.CacheWithTTL)
+	if !ok {
+		t.Fatal("Memory cache does not implement CacheWithTTL")
+	}
+
+	if err = ttlCache.SetWithTTL("foo", []byte("1"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	if err = ttlCache.AddWithTTL("bar", []byte("2"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(time.Millisecond)
+
+	// This should trigger compaction.
+	if err = c.Set("baz", []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Get("foo"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if act, err := c.Get("bar"); err != nil {
+		t.Error(err)
+	} else if exp := "2"; string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -38,7 +38,11 @@ func init() {
 		constructor: NewRedis,
 		description: `
 Use a Redis instance as a cache. The expiration can be set to zero or an empty
-string in order to set no expiration.`,
+string in order to set no expiration.
+
+Atomic increments, such as those of the ` + "`incr`" + ` and ` + "`decr`" + `
+operators of the [` + "`cache`" + ` processor](../processors/README.md#cache), are
+not retried as they are not idempotent, and refresh the expiration of the key.`,
 	}
 }
 
@@ -97,6 +101,17 @@ type Redis struct {
 	mDelNotFound   metrics.StatCounter
 	mDelSuccess    metrics.StatCounter
 	mDelLatency    metrics.StatTimer
+	mIncrCount     metrics.StatCounter
+	mIncrFailedErr metrics.StatCounter
+	mIncrSuccess   metrics.StatCounter
+	mIncrLatency   metrics.StatTimer
+	mCASCount      metrics.StatCounter
+	mCASRetry      metrics.StatCounter
+	mCASFailedErr  metrics.StatCounter
+	mCASMismatch   metrics.StatCounter
+	mCASNotFound   metrics.StatCounter
+	mCASSuccess    metrics.StatCounter
+	mCASLatency    metrics.StatTimer
 
 	client      *redis.Client
 	ttl         time.Duration
@@ -169,6 +184,17 @@ func NewRedis(
 		mDelNotFound:   stats.GetCounter("delete.failed.not_found"),
 		mDelSuccess:    stats.GetCounter("delete.success"),
 		mDelLatency:    stats.GetTimer("delete.latency"),
+		mIncrCount:     stats.GetCounter("incr.count"),
+		mIncrFailedErr: stats.GetCounter("incr.failed.error"),
+		mIncrSuccess:   stats.GetCounter("incr.success"),
+		mIncrLatency:   stats.GetTimer("incr.latency"),
+		mCASCount:      stats.GetCounter("cas.count"),
+		mCASRetry:      stats.GetCounter("cas.retry"),
+		mCASFailedErr:  stats.GetCounter("cas.failed.error"),
+		mCASMismatch:   stats.GetCounter("cas.failed.mismatch"),
+		mCASNotFound:   stats.GetCounter("cas.failed.not_found"),
+		mCASSuccess:    stats.GetCounter("cas.success"),
+		mCASLatency:    stats.GetTimer("cas.latency"),
 
 		retryPeriod: retryPeriod,
 		ttl:         ttl,
@@ -219,17 +245,23 @@ func (r *Redis) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (r *Redis) Set(key string, value []byte) error {
+	return r.SetWithTTL(key, value, r.ttl)
+}
+
+// SetWithTTL attempts to set the value of a key with an expiration that
+// overrides the default expiration of the cache.
+func (r *Redis) SetWithTTL(key string, value []byte, ttl time.Duration) error {
 	r.mSetCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
 
-	err := r.client.Set(key, value, r.ttl).Err()
+	err := r.client.Set(key, value, ttl).Err()
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Set command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mSetRetry.Incr(1)
-		err = r.client.Set(key, value, r.ttl).Err()
+		err = r.client.Set(key, value, ttl).Err()
 	}
 	if err != nil {
 		r.mSetFailed.Incr(1)
@@ -259,12 +291,19 @@ func (r *Redis) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists or if the operation fails.
 func (r *Redis) Add(key string, value []byte) error {
+	return r.AddWithTTL(key, value, r.ttl)
+}
+
+// AddWithTTL attempts to set the value of a key only if the key does not
+// already exist, with an expiration that overrides the default expiration of
+// the cache.
+func (r *Redis) AddWithTTL(key string, value []byte, ttl time.Duration) error {
 	r.mAddCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
 
-	set, err := r.client.SetNX(key, value, r.ttl).Result()
+	set, err := r.client.SetNX(key, value, ttl).Result()
 	if err == nil && !set {
 		r.mAddFailedDupe.Incr(1)
 
@@ -278,7 +317,7 @@ func (r *Redis) Add(key string, value []byte) error {
 		r.log.Errorf("Add command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mAddRetry.Incr(1)
-		if set, err = r.client.SetNX(key, value, r.ttl).Result(); err == nil && !set {
+		if set, err = r.client.SetNX(key, value, ttl).Result(); err == nil && !set {
 			r.mAddFailedDupe.Incr(1)
 
 			latency := int64(time.Since(tStarted))
@@ -336,6 +375,103 @@ func (r *Redis) Delete(key string) error {
 	return err
 }
 
+// redisIncrScript atomically adds to the integer value of a key and refreshes
+// its expiration when a TTL in milliseconds greater than zero is given.
+var redisIncrScript = redis.NewScript(`
+local result = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return result
+`)
+
+// Incr atomically adds delta to the integer value of a key and returns the
+// result, a key that does not exist is treated as zero. The key expires
+// according to the expiration of the cache, measured from the last increment.
+//
+// Increments are not idempotent and are therefore never retried, since a
+// failed response does not guarantee that the increment was not applied.
+func (r *Redis) Incr(key string, delta int64) (int64, error) {
+	r.mIncrCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+	ttlMillis := int64(r.ttl / time.Millisecond)
+
+	res, err := redisIncrScript.Run(r.client, []string{key}, delta, ttlMillis).Int64()
+	if err != nil {
+		r.log.Errorf("Incr command failed: %v\n", err)
+		r.mIncrFailedErr.Incr(1)
+	} else {
+		r.mIncrSuccess.Incr(1)
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mIncrLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	return res, err
+}
+
+// redisCASScript atomically replaces the value of a key when it matches an
+// expected value, returning -1 if the key does not exist, 0 if the value does
+// not match and 1 if the value was replaced.
+var redisCASScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current == false then
+	return -1
+end
+if current ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// CompareAndSwap sets the value of a key only if its current value is equal to
+// old. A ttl of zero uses the default expiration of the cache.
+func (r *Redis) CompareAndSwap(key string, old, value []byte, ttl time.Duration) error {
+	r.mCASCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+	if ttl == 0 {
+		ttl = r.ttl
+	}
+	ttlMillis := int64(ttl / time.Millisecond)
+
+	res, err := redisCASScript.Run(r.client, []string{key}, old, value, ttlMillis).Int64()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("CompareAndSwap command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mCASRetry.Incr(1)
+		res, err = redisCASScript.Run(r.client, []string{key}, old, value, ttlMillis).Int64()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mCASLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mCASFailedErr.Incr(1)
+		return err
+	}
+	switch res {
+	case -1:
+		r.mCASNotFound.Incr(1)
+		return types.ErrKeyNotFound
+	case 0:
+		r.mCASMismatch.Incr(1)
+		return types.ErrValueMismatch
+	}
+	r.mCASSuccess.Incr(1)
+	return nil
+}
+
 // CloseAsync shuts down the cache.
 func (r *Redis) CloseAsync() {
 }
@@ -23,6 +23,7 @@ package cache
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -73,6 +74,58 @@ func TestRedisIntegration(t *testing.T) {
 	t.Run("TestRedisGetAndSet", func(te *testing.T) {
 		testRedisGetAndSet(url, te)
 	})
+	t.Run("TestRedisIncrAndCompareAndSwap", func(te *testing.T) {
+		testRedisIncrAndCompareAndSwap(url, te)
+	})
+}
+
+func testRedisIncrAndCompareAndSwap(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+
+	c, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Delete("benthos_test_counter"); err != nil {
+		t.Fatal(err)
+	}
+	counter := c.(types.CacheCounter)
+	for _, exp := range []int64{2, 4, 6} {
+		act, err := counter.Incr("benthos_test_counter", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act != exp {
+			t.Errorf("Wrong counter value: %v != %v", act, exp)
+		}
+	}
+	if ttl := c.(*Redis).client.PTTL("benthos_test_counter").Val(); ttl <= 0 {
+		t.Errorf("Counter has no expiration: %v", ttl)
+	}
+
+	cas := c.(types.CacheCompareAndSwapper)
+	if err = c.Delete("benthos_test_cas"); err != nil {
+		t.Fatal(err)
+	}
+	if err = cas.CompareAndSwap("benthos_test_cas", []byte("foo"), []byte("bar"), 0); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrKeyNotFound)
+	}
+	if err = c.Set("benthos_test_cas", []byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err = cas.CompareAndSwap("benthos_test_cas", []byte("nope"), []byte("bar"), 0); err != types.ErrValueMismatch {
+		t.Errorf("Wrong error returned: %v != %v", err, types.ErrValueMismatch)
+	}
+	if err = cas.CompareAndSwap("benthos_test_cas", []byte("foo"), []byte("bar"), time.Minute); err != nil {
+		t.Error(err)
+	}
+	if act, err := c.Get("benthos_test_cas"); err != nil {
+		t.Error(err)
+	} else if exp := "bar"; string(act) != exp {
+		t.Errorf("Wrong value returned: %v != %v", string(act), exp)
+	}
 }
 
 func testRedisAddDuplicate(url string, t *testing.T) {
@@ -62,13 +62,16 @@ var (
 	TypeCheckField         = "check_field"
 	TypeCheckInterpolation = "check_interpolation"
 	TypeCount              = "count"
+	TypeExpression         = "expression"
 	TypeJMESPath           = "jmespath"
 	TypeNot                = "not"
 	TypeNumber             = "number"
 	TypeMetadata           = "metadata"
 	TypeOr                 = "or"
+	TypeProcessorError     = "processor_error"
 	TypeProcessorFailed    = "processor_failed"
 	TypeResource           = "resource"
+	TypeRollout            = "rollout"
 	TypeStatic             = "static"
 	TypeText               = "text"
 	TypeXor                = "xor"
@@ -86,14 +89,17 @@ type Config struct {
 	CheckField         CheckFieldConfig         `json:"check_field" yaml:"check_field"`
 	CheckInterpolation CheckInterpolationConfig `json:"check_interpolation" yaml:"check_interpolation"`
 	Count              CountConfig              `json:"count" yaml:"count"`
+	Expression         ExpressionConfig         `json:"expression" yaml:"expression"`
 	JMESPath           JMESPathConfig           `json:"jmespath" yaml:"jmespath"`
 	Not                NotConfig                `json:"not" yaml:"not"`
 	Number             NumberConfig             `json:"number" yaml:"number"`
 	Metadata           MetadataConfig           `json:"metadata" yaml:"metadata"`
 	Or                 OrConfig                 `json:"or" yaml:"or"`
 	Plugin             interface{}              `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	ProcessorError     ProcessorErrorConfig     `json:"processor_error" yaml:"processor_error"`
 	ProcessorFailed    ProcessorFailedConfig    `json:"processor_failed" yaml:"processor_failed"`
 	Resource           string                   `json:"resource" yaml:"resource"`
+	Rollout            RolloutConfig            `json:"rollout" yaml:"rollout"`
 	Static             bool                     `json:"static" yaml:"static"`
 	Text               TextConfig               `json:"text" yaml:"text"`
 	Xor                XorConfig                `json:"xor" yaml:"xor"`
@@ -108,14 +114,17 @@ func NewConfig() Config {
 		CheckField:         NewCheckFieldConfig(),
 		CheckInterpolation: NewCheckInterpolationConfig(),
 		Count:              NewCountConfig(),
+		Expression:         NewExpressionConfig(),
 		JMESPath:           NewJMESPathConfig(),
 		Not:                NewNotConfig(),
 		Number:             NewNumberConfig(),
 		Metadata:           NewMetadataConfig(),
 		Or:                 NewOrConfig(),
 		Plugin:             nil,
+		ProcessorError:     NewProcessorErrorConfig(),
 		ProcessorFailed:    NewProcessorFailedConfig(),
 		Resource:           "",
+		Rollout:            NewRolloutConfig(),
 		Static:             true,
 		Text:               NewTextConfig(),
 		Xor:                NewXorConfig(),
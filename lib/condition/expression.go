@@ -0,0 +1,554 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeExpression] = TypeSpec{
+		constructor: NewExpression,
+		description: `
+Expression is a condition that evaluates a boolean expression against a message
+part, allowing checks across multiple fields and metadata values to be written
+without deeply nested ` + "`and`, `or` and `not`" + ` conditions:
+
+` + "``` yaml" + `
+expression:
+  part: 0
+  expression: 'meta("env") == "prod" && (json("amount") > 100 || json("user.vip") == true)'
+` + "```" + `
+
+The expression is compiled when the condition is created, and therefore syntax
+errors are reported at startup.
+
+### Values
+
+The following functions are available for extracting values from the message
+part:
+
+- ` + "`meta(\"key\")`" + ` returns the value of a metadata key, or an empty
+  string if it does not exist.
+- ` + "`json(\"path\")`" + ` returns the value at a dot separated path of the
+  part parsed as a JSON document, or ` + "`null`" + ` if it does not exist.
+- ` + "`content()`" + ` returns the raw contents of the part as a string.
+
+Literal values can be strings wrapped in double or single quotes, numbers,
+` + "`true`, `false` and `null`" + `.
+
+### Operators
+
+Values can be compared with ` + "`==`, `!=`, `>`, `>=`, `<` and `<=`" + `.
+When either side of a comparison is a number and the other is a string that
+contains a number (as is always the case with metadata values) the two are
+compared numerically.
+
+Comparisons can be combined with ` + "`&&`, `||`" + ` and negated with
+` + "`!`" + `, where ` + "`&&`" + ` binds tighter than ` + "`||`" + `, and
+parentheses can be used for grouping. Values used directly as operands of these
+operators are only considered true when they are the boolean ` + "`true`" + `.
+
+If the part cannot be parsed as JSON then calls to ` + "`json`" + ` return
+` + "`null`" + ` and the error is logged at debug level.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ExpressionConfig contains configuration fields for the Expression condition.
+type ExpressionConfig struct {
+	Part       int    `json:"part" yaml:"part"`
+	Expression string `json:"expression" yaml:"expression"`
+}
+
+// NewExpressionConfig returns a ExpressionConfig with default values.
+func NewExpressionConfig() ExpressionConfig {
+	return ExpressionConfig{
+		Part:       0,
+		Expression: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// exprContext holds the state of a single evaluation of an expression, and
+// lazily parses the JSON contents of the part so that it is only done once.
+type exprContext struct {
+	part    types.Part
+	log     log.Modular
+	mErr    metrics.StatCounter
+	parsed  bool
+	jsonDoc *gabs.Container
+}
+
+func (e *exprContext) json() *gabs.Container {
+	if e.parsed {
+		return e.jsonDoc
+	}
+	e.parsed = true
+	jpart, err := e.part.JSON()
+	if err == nil {
+		e.jsonDoc, err = gabs.Consume(jpart)
+	}
+	if err != nil {
+		e.log.Debugf("Failed to parse message as JSON: %v\n", err)
+		e.mErr.Incr(1)
+	}
+	return e.jsonDoc
+}
+
+type exprNode func(ctx *exprContext) interface{}
+
+func exprTruthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+func exprToNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// exprNumeric returns both values as numbers when at least one side is a
+// number and the other can be converted to one.
+func exprNumeric(l, r interface{}) (float64, float64, bool) {
+	_, lNum := l.(float64)
+	_, rNum := r.(float64)
+	if !lNum && !rNum {
+		return 0, 0, false
+	}
+	lf, lok := exprToNumber(l)
+	rf, rok := exprToNumber(r)
+	return lf, rf, lok && rok
+}
+
+func exprCompare(op string, lhs, rhs exprNode) exprNode {
+	return func(ctx *exprContext) interface{} {
+		l, r := lhs(ctx), rhs(ctx)
+		switch op {
+		case "==", "!=":
+			var equal bool
+			if lf, rf, ok := exprNumeric(l, r); ok {
+				equal = lf == rf
+			} else {
+				equal = reflect.DeepEqual(l, r)
+			}
+			return equal == (op == "==")
+		}
+		var cmp int
+		if lf, rf, ok := exprNumeric(l, r); ok {
+			switch {
+			case lf < rf:
+				cmp = -1
+			case lf > rf:
+				cmp = 1
+			}
+		} else {
+			ls, lok := l.(string)
+			rs, rok := r.(string)
+			if !lok || !rok {
+				return false
+			}
+			cmp = strings.Compare(ls, rs)
+		}
+		switch op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		}
+		return cmp >= 0
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type exprToken struct {
+	kind  string
+	value string
+	pos   int
+}
+
+func exprLex(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, exprToken{kind: string(c), pos: i})
+			i++
+		case strings.HasPrefix(expr[i:], "&&"),
+			strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "=="),
+			strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "<="),
+			strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, exprToken{kind: "op", value: expr[i : i+2], pos: i})
+			i += 2
+		case c == '!' || c == '<' || c == '>':
+			tokens = append(tokens, exprToken{kind: "op", value: string(c), pos: i})
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("char %v: unterminated string", i)
+			}
+			raw := expr[i+1 : j]
+			if c == '\'' {
+				raw = strings.Replace(raw, `\'`, `'`, -1)
+				raw = strings.Replace(raw, `"`, `\"`, -1)
+			}
+			str, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return nil, fmt.Errorf("char %v: invalid string: %v", i, err)
+			}
+			tokens = append(tokens, exprToken{kind: "string", value: str, pos: i})
+			i = j + 1
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for ; j < len(expr); j++ {
+				if d := expr[j]; d != '.' && d != 'e' && d != 'E' && (d < '0' || d > '9') {
+					break
+				}
+			}
+			tokens = append(tokens, exprToken{kind: "number", value: expr[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(rune(c)) || c == '_':
+			j := i + 1
+			for ; j < len(expr); j++ {
+				if d := rune(expr[j]); !unicode.IsLetter(d) && !unicode.IsDigit(d) && d != '_' {
+					break
+				}
+			}
+			tokens = append(tokens, exprToken{kind: "ident", value: expr[i:j], pos: i})
+			i = j
+		default:
+			return nil, fmt.Errorf("char %v: unexpected character '%c'", i, c)
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	i      int
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.i < len(p.tokens) {
+		return &p.tokens[p.i]
+	}
+	return nil
+}
+
+func (p *exprParser) peekOp(ops ...string) string {
+	if t := p.peek(); t != nil && t.kind == "op" {
+		for _, op := range ops {
+			if t.value == op {
+				return op
+			}
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) expect(kind string) (*exprToken, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("expected '%v' but reached end of expression", kind)
+	}
+	if t.kind != kind {
+		return nil, fmt.Errorf("char %v: expected '%v'", t.pos, kind)
+	}
+	p.i++
+	return t, nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("||") != "" {
+		p.i++
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := lhs
+		lhs = func(ctx *exprContext) interface{} {
+			return exprTruthy(l(ctx)) || exprTruthy(rhs(ctx))
+		}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("&&") != "" {
+		p.i++
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := lhs
+		lhs = func(ctx *exprContext) interface{} {
+			return exprTruthy(l(ctx)) && exprTruthy(rhs(ctx))
+		}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peekOp("!") != "" {
+		p.i++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *exprContext) interface{} {
+			return !exprTruthy(child(ctx))
+		}, nil
+	}
+	lhs, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.peekOp("==", "!=", "<", "<=", ">", ">="); op != "" {
+		p.i++
+		rhs, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return exprCompare(op, lhs, rhs), nil
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) parseValue() (exprNode, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, errors.New("unexpected end of expression")
+	}
+	p.i++
+	switch t.kind {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "string":
+		str := t.value
+		return func(ctx *exprContext) interface{} { return str }, nil
+	case "number":
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("char %v: invalid number '%v'", t.pos, t.value)
+		}
+		return func(ctx *exprContext) interface{} { return f }, nil
+	case "ident":
+		switch t.value {
+		case "true", "false":
+			b := t.value == "true"
+			return func(ctx *exprContext) interface{} { return b }, nil
+		case "null":
+			return func(ctx *exprContext) interface{} { return nil }, nil
+		}
+		return p.parseFunction(t)
+	}
+	tok := t.value
+	if len(tok) == 0 {
+		tok = t.kind
+	}
+	return nil, fmt.Errorf("char %v: unexpected token '%v'", t.pos, tok)
+}
+
+func (p *exprParser) parseFunction(name *exprToken) (exprNode, error) {
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []string
+	for {
+		if t := p.peek(); t != nil && t.kind == ")" {
+			p.i++
+			break
+		}
+		if len(args) > 0 {
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expect("string")
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg.value)
+	}
+
+	switch name.value {
+	case "meta":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("char %v: meta expects one argument", name.pos)
+		}
+		key := args[0]
+		return func(ctx *exprContext) interface{} {
+			return ctx.part.Metadata().Get(key)
+		}, nil
+	case "json":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("char %v: json expects one argument", name.pos)
+		}
+		path := args[0]
+		return func(ctx *exprContext) interface{} {
+			doc := ctx.json()
+			if doc == nil {
+				return nil
+			}
+			if len(path) > 0 {
+				doc = doc.Path(path)
+			}
+			return doc.Data()
+		}, nil
+	case "content":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("char %v: content expects no arguments", name.pos)
+		}
+		return func(ctx *exprContext) interface{} {
+			return string(ctx.part.Get())
+		}, nil
+	}
+	return nil, fmt.Errorf("char %v: unknown function '%v'", name.pos, name.value)
+}
+
+func compileExpression(expr string) (exprNode, error) {
+	tokens, err := exprLex(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("expression is empty")
+	}
+	p := exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		return nil, fmt.Errorf("char %v: unexpected token after expression", t.pos)
+	}
+	return node, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Expression is a condition that checks a message part against a compiled
+// boolean expression.
+type Expression struct {
+	log  log.Modular
+	part int
+	expr exprNode
+
+	mCount   metrics.StatCounter
+	mTrue    metrics.StatCounter
+	mFalse   metrics.StatCounter
+	mErrJSON metrics.StatCounter
+}
+
+// NewExpression returns an Expression condition.
+func NewExpression(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	expr, err := compileExpression(conf.Expression.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile expression: %v", err)
+	}
+	return &Expression{
+		log:  log,
+		part: conf.Expression.Part,
+		expr: expr,
+
+		mCount:   stats.GetCounter("count"),
+		mTrue:    stats.GetCounter("true"),
+		mFalse:   stats.GetCounter("false"),
+		mErrJSON: stats.GetCounter("error_json_parse"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Check attempts to check a message part against a configured condition.
+func (c *Expression) Check(msg types.Message) bool {
+	c.mCount.Incr(1)
+	if msg.Len() == 0 {
+		c.mFalse.Incr(1)
+		return false
+	}
+
+	ctx := exprContext{
+		part: msg.Get(c.part),
+		log:  c.log,
+		mErr: c.mErrJSON,
+	}
+	res := exprTruthy(c.expr(&ctx))
+	if res {
+		c.mTrue.Incr(1)
+	} else {
+		c.mFalse.Incr(1)
+	}
+	return res
+}
+
+//------------------------------------------------------------------------------
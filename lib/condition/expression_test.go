@@ -0,0 +1,151 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestExpressionCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		content string
+		meta    map[string]string
+		want    bool
+	}{
+		{
+			name:    "meta and json",
+			expr:    `meta("env") == "prod" && json("amount") > 100`,
+			content: `{"amount":150}`,
+			meta:    map[string]string{"env": "prod"},
+			want:    true,
+		},
+		{
+			name:    "meta and json false",
+			expr:    `meta("env") == "prod" && json("amount") > 100`,
+			content: `{"amount":50}`,
+			meta:    map[string]string{"env": "prod"},
+			want:    false,
+		},
+		{
+			name:    "or precedence",
+			expr:    `meta("env") == "dev" && json("amount") > 100 || json("user.vip") == true`,
+			content: `{"amount":50,"user":{"vip":true}}`,
+			meta:    map[string]string{"env": "prod"},
+			want:    true,
+		},
+		{
+			name:    "parentheses",
+			expr:    `meta("env") == "dev" && (json("amount") > 100 || json("user.vip"))`,
+			content: `{"amount":150,"user":{"vip":true}}`,
+			meta:    map[string]string{"env": "prod"},
+			want:    false,
+		},
+		{
+			name:    "numeric metadata",
+			expr:    `meta("count") >= 10 && meta("count") < 20.5`,
+			content: `{}`,
+			meta:    map[string]string{"count": "20"},
+			want:    true,
+		},
+		{
+			name:    "not",
+			expr:    `!(json("deleted") == true) && json("name") != 'foo'`,
+			content: `{"name":"bar"}`,
+			want:    true,
+		},
+		{
+			name:    "null missing field",
+			expr:    `json("missing") == null && meta("missing") == ""`,
+			content: `{"name":"bar"}`,
+			want:    true,
+		},
+		{
+			name:    "string comparison",
+			expr:    `json("name") < "baz" && json("name") >= "bar"`,
+			content: `{"name":"bar"}`,
+			want:    true,
+		},
+		{
+			name:    "not json",
+			expr:    `json("name") == "bar" || content() == "not json"`,
+			content: `not json`,
+			want:    true,
+		},
+		{
+			name:    "non boolean operand",
+			expr:    `json("name") || json("count")`,
+			content: `{"name":"bar","count":1}`,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		conf := NewConfig()
+		conf.Type = TypeExpression
+		conf.Expression.Expression = tt.expr
+
+		c, err := NewExpression(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+
+		msg := message.New([][]byte{[]byte(tt.content)})
+		for k, v := range tt.meta {
+			msg.Get(0).Metadata().Set(k, v)
+		}
+		if got := c.Check(msg); got != tt.want {
+			t.Errorf("%v: Check() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExpressionBadExpressions(t *testing.T) {
+	tests := []string{
+		``,
+		`meta("foo") ==`,
+		`meta("foo") == "bar" &&`,
+		`(meta("foo") == "bar"`,
+		`meta("foo") == "bar")`,
+		`meta("foo", "bar") == "baz"`,
+		`content("foo") == "bar"`,
+		`nope("foo") == "bar"`,
+		`json(foo) == "bar"`,
+		`json("foo") == "bar`,
+		`json("foo") = "bar"`,
+		`json("foo") == 1.2.3`,
+	}
+
+	for _, expr := range tests {
+		conf := NewConfig()
+		conf.Type = TypeExpression
+		conf.Expression.Expression = expr
+
+		if _, err := NewExpression(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error from expression: %v", expr)
+		}
+	}
+}
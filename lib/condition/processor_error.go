@@ -0,0 +1,126 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeProcessorError] = TypeSpec{
+		constructor: NewProcessorError,
+		description: `
+Checks the error message of a message that has failed a processing stage against
+a logical operator and an argument. Messages that have not failed a processing
+stage always resolve to false.
+
+Available operators are the same as those of the ` + "[`text`](#text)" + `
+condition. For example, the following would resolve to true for messages that
+failed due to a timeout:
+
+` + "``` yaml" + `
+processor_error:
+  operator: contains
+  arg: timed out
+` + "```" + `
+
+The error message of a failed message can also be obtained with the
+` + "`${!error}`" + ` [function interpolation](../config_interpolation.md#error),
+which is useful for including the reason for a failure in messages routed to a
+dead letter queue.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessorErrorConfig is a configuration struct containing fields for the
+// processor_error condition.
+type ProcessorErrorConfig struct {
+	Operator string      `json:"operator" yaml:"operator"`
+	Part     int         `json:"part" yaml:"part"`
+	Arg      interface{} `json:"arg" yaml:"arg"`
+}
+
+// NewProcessorErrorConfig returns a ProcessorErrorConfig with default values.
+func NewProcessorErrorConfig() ProcessorErrorConfig {
+	return ProcessorErrorConfig{
+		Operator: "contains",
+		Part:     0,
+		Arg:      "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessorError is a condition that checks the error message of a message
+// that has failed a processing step.
+type ProcessorError struct {
+	operator textOperator
+	part     int
+
+	mCount metrics.StatCounter
+	mTrue  metrics.StatCounter
+	mFalse metrics.StatCounter
+}
+
+// NewProcessorError returns a ProcessorError condition.
+func NewProcessorError(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	op, err := strToTextOperator(conf.ProcessorError.Operator, conf.ProcessorError.Arg)
+	if err != nil {
+		return nil, fmt.Errorf("operator '%v': %v", conf.ProcessorError.Operator, err)
+	}
+	return &ProcessorError{
+		operator: op,
+		part:     conf.ProcessorError.Part,
+
+		mCount: stats.GetCounter("count"),
+		mTrue:  stats.GetCounter("true"),
+		mFalse: stats.GetCounter("false"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Check attempts to check a message part against a configured condition.
+func (p *ProcessorError) Check(msg types.Message) bool {
+	p.mCount.Incr(1)
+	if msg.Len() == 0 {
+		p.mFalse.Incr(1)
+		return false
+	}
+	errStr := msg.Get(p.part).Metadata().Get("benthos_processing_failed")
+	if len(errStr) == 0 || !p.operator([]byte(errStr)) {
+		p.mFalse.Incr(1)
+		return false
+	}
+	p.mTrue.Incr(1)
+	return true
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestProcessorError(t *testing.T) {
+	type testCase struct {
+		name     string
+		operator string
+		arg      interface{}
+		errStr   string
+		expected bool
+	}
+
+	tests := []testCase{
+		{
+			name:     "not failed",
+			operator: "contains",
+			arg:      "",
+			errStr:   "",
+			expected: false,
+		},
+		{
+			name:     "any failure",
+			operator: "contains",
+			arg:      "",
+			errStr:   "true",
+			expected: true,
+		},
+		{
+			name:     "contains match",
+			operator: "contains",
+			arg:      "timed out",
+			errStr:   "request timed out after 5s",
+			expected: true,
+		},
+		{
+			name:     "contains no match",
+			operator: "contains",
+			arg:      "timed out",
+			errStr:   "connection refused",
+			expected: false,
+		},
+		{
+			name:     "prefix match",
+			operator: "prefix",
+			arg:      "failed to",
+			errStr:   "failed to execute query",
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeProcessorError
+			conf.ProcessorError.Operator = test.operator
+			conf.ProcessorError.Arg = test.arg
+
+			c, err := New(conf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				tt.Fatal(err)
+			}
+
+			msg := message.New([][]byte{[]byte("foo")})
+			if len(test.errStr) > 0 {
+				msg.Get(0).Metadata().Set("benthos_processing_failed", test.errStr)
+			}
+			if act := c.Check(msg); act != test.expected {
+				tt.Errorf("Unexpected result: %v != %v", act, test.expected)
+			}
+		})
+	}
+}
+
+func TestProcessorErrorBadOperator(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeProcessorError
+	conf.ProcessorError.Operator = "nope"
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRollout] = TypeSpec{
+		constructor: NewRollout,
+		description: `
+Resolves to true for a percentage of messages and/or within a window of time,
+allowing new processing logic to be rolled out gradually within a single config
+by using this condition within a ` + "[`switch`](../processors/README.md#switch)" + `
+case or ` + "[`conditional`](../processors/README.md#conditional)" + ` processor.
+
+Messages are selected by hashing the result of the ` + "`key`" + ` field, which
+supports [function interpolations](../config_interpolation.md#functions), and
+therefore messages that share a key consistently resolve to the same result.
+When rolling out several features at once you can prefix each key with a unique
+string in order to select a different portion of messages for each feature.
+The key of a batch is resolved from its first message, and therefore in order
+to select messages of a batch individually the processors should be wrapped
+within a ` + "[`for_each`](../processors/README.md#for_each)" + ` processor.
+
+The fields ` + "`start` and `end`" + ` are optional RFC3339 timestamps that
+define a window outside of which the condition always resolves to false. When
+` + "`ramp`" + ` is set to a duration the percentage of selected messages grows
+linearly from zero at ` + "`start`" + ` up to ` + "`percentage`" + ` once the
+duration has elapsed.
+
+` + "``` yaml" + `
+pipeline:
+  processors:
+  - switch:
+    - condition:
+        rollout:
+          key: new-enrichment-${!json_field:user.id}
+          percentage: 25
+          start: 2019-06-01T09:00:00Z
+          ramp: 24h
+      processors:
+      - jmespath:
+          query: '{ user: user, new: true }'
+      fallthrough: false
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RolloutConfig is a configuration struct containing fields for the Rollout
+// condition.
+type RolloutConfig struct {
+	Key        string  `json:"key" yaml:"key"`
+	Percentage float64 `json:"percentage" yaml:"percentage"`
+	Start      string  `json:"start" yaml:"start"`
+	End        string  `json:"end" yaml:"end"`
+	Ramp       string  `json:"ramp" yaml:"ramp"`
+}
+
+// NewRolloutConfig returns a RolloutConfig with default values.
+func NewRolloutConfig() RolloutConfig {
+	return RolloutConfig{
+		Key:        "${!content}",
+		Percentage: 100,
+		Start:      "",
+		End:        "",
+		Ramp:       "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// rolloutBuckets is the number of buckets keys are hashed into, allowing
+// percentages to be specified with a precision of two decimal places.
+const rolloutBuckets = 10000
+
+// Rollout is a condition that resolves to true for a stable portion of
+// messages, optionally within a window of time.
+type Rollout struct {
+	key        *text.InterpolatedBytes
+	percentage float64
+	start      time.Time
+	end        time.Time
+	ramp       time.Duration
+
+	now func() time.Time
+
+	mCount metrics.StatCounter
+	mTrue  metrics.StatCounter
+	mFalse metrics.StatCounter
+}
+
+// NewRollout returns a Rollout condition.
+func NewRollout(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.Rollout.Percentage < 0 || conf.Rollout.Percentage > 100 {
+		return nil, fmt.Errorf("percentage must be between 0 and 100, got %v", conf.Rollout.Percentage)
+	}
+
+	r := &Rollout{
+		key:        text.NewInterpolatedBytes([]byte(conf.Rollout.Key)),
+		percentage: conf.Rollout.Percentage,
+		now:        time.Now,
+
+		mCount: stats.GetCounter("count"),
+		mTrue:  stats.GetCounter("true"),
+		mFalse: stats.GetCounter("false"),
+	}
+
+	var err error
+	if len(conf.Rollout.Start) > 0 {
+		if r.start, err = time.Parse(time.RFC3339, conf.Rollout.Start); err != nil {
+			return nil, fmt.Errorf("failed to parse start: %v", err)
+		}
+	}
+	if len(conf.Rollout.End) > 0 {
+		if r.end, err = time.Parse(time.RFC3339, conf.Rollout.End); err != nil {
+			return nil, fmt.Errorf("failed to parse end: %v", err)
+		}
+		if !r.start.IsZero() && !r.end.After(r.start) {
+			return nil, errors.New("end must be after start")
+		}
+	}
+	if len(conf.Rollout.Ramp) > 0 {
+		if r.start.IsZero() {
+			return nil, errors.New("a ramp requires a start time")
+		}
+		if r.ramp, err = time.ParseDuration(conf.Rollout.Ramp); err != nil {
+			return nil, fmt.Errorf("failed to parse ramp: %v", err)
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// threshold returns the number of buckets that are currently enabled.
+func (r *Rollout) threshold() float64 {
+	now := r.now()
+	if !r.start.IsZero() && now.Before(r.start) {
+		return 0
+	}
+	if !r.end.IsZero() && !now.Before(r.end) {
+		return 0
+	}
+	percentage := r.percentage
+	if elapsed := now.Sub(r.start); r.ramp > 0 && elapsed < r.ramp {
+		percentage *= float64(elapsed) / float64(r.ramp)
+	}
+	return percentage / 100 * rolloutBuckets
+}
+
+// Check attempts to check a message part against a configured condition.
+func (r *Rollout) Check(msg types.Message) bool {
+	r.mCount.Incr(1)
+
+	res := false
+	if threshold := r.threshold(); threshold >= rolloutBuckets {
+		res = true
+	} else if threshold > 0 {
+		h := fnv.New32a()
+		h.Write(r.key.Get(msg))
+		res = float64(h.Sum32()%rolloutBuckets) < threshold
+	}
+
+	if res {
+		r.mTrue.Incr(1)
+	} else {
+		r.mFalse.Incr(1)
+	}
+	return res
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package condition
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestRolloutPercentage(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "rollout"
+	conf.Rollout.Percentage = 25
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("user-%v", i))})
+		res := c.Check(msg)
+		if res {
+			enabled++
+		}
+		if c.Check(msg) != res {
+			t.Fatalf("Result for key %v was not stable", i)
+		}
+	}
+	if enabled < 2300 || enabled > 2700 {
+		t.Errorf("Expected roughly 25%% of messages to be enabled, got %v", enabled)
+	}
+}
+
+func TestRolloutBounds(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo")})
+
+	conf := NewConfig()
+	conf.Type = "rollout"
+
+	conf.Rollout.Percentage = 0
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Check(msg) {
+		t.Error("Expected false with zero percentage")
+	}
+
+	conf.Rollout.Percentage = 100
+	if c, err = New(conf, nil, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Check(msg) {
+		t.Error("Expected true with full percentage")
+	}
+}
+
+func TestRolloutWindow(t *testing.T) {
+	conf := NewConfig()
+	conf.Rollout.Start = "2019-06-01T09:00:00Z"
+	conf.Rollout.End = "2019-06-02T09:00:00Z"
+
+	c, err := NewRollout(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := c.(*Rollout)
+	msg := message.New([][]byte{[]byte("foo")})
+
+	tests := map[string]bool{
+		"2019-06-01T08:59:59Z": false,
+		"2019-06-01T09:00:00Z": true,
+		"2019-06-01T20:00:00Z": true,
+		"2019-06-02T09:00:00Z": false,
+		"2019-06-03T00:00:00Z": false,
+	}
+	for ts, exp := range tests {
+		now, _ := time.Parse(time.RFC3339, ts)
+		r.now = func() time.Time { return now }
+		if act := r.Check(msg); exp != act {
+			t.Errorf("Wrong result at %v: %v != %v", ts, act, exp)
+		}
+	}
+}
+
+func TestRolloutRamp(t *testing.T) {
+	conf := NewConfig()
+	conf.Rollout.Percentage = 50
+	conf.Rollout.Start = "2019-06-01T00:00:00Z"
+	conf.Rollout.Ramp = "10h"
+
+	c, err := NewRollout(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := c.(*Rollout)
+	start, _ := time.Parse(time.RFC3339, conf.Rollout.Start)
+
+	tests := []struct {
+		elapsed time.Duration
+		min     int
+		max     int
+	}{
+		{0, 0, 0},
+		{time.Hour * 5, 2200, 2800},
+		{time.Hour * 10, 4700, 5300},
+		{time.Hour * 100, 4700, 5300},
+	}
+	for _, test := range tests {
+		now := start.Add(test.elapsed)
+		r.now = func() time.Time { return now }
+
+		enabled := 0
+		for i := 0; i < 10000; i++ {
+			if r.Check(message.New([][]byte{[]byte(fmt.Sprintf("user-%v", i))})) {
+				enabled++
+			}
+		}
+		if enabled < test.min || enabled > test.max {
+			t.Errorf("Wrong enabled count after %v: %v not within %v-%v", test.elapsed, enabled, test.min, test.max)
+		}
+	}
+}
+
+func TestRolloutBadConfig(t *testing.T) {
+	tests := map[string]func(c *RolloutConfig){
+		"negative percentage": func(c *RolloutConfig) { c.Percentage = -1 },
+		"large percentage":    func(c *RolloutConfig) { c.Percentage = 101 },
+		"bad start":           func(c *RolloutConfig) { c.Start = "nope" },
+		"bad end":             func(c *RolloutConfig) { c.End = "nope" },
+		"end before start": func(c *RolloutConfig) {
+			c.Start = "2019-06-02T00:00:00Z"
+			c.End = "2019-06-01T00:00:00Z"
+		},
+		"ramp without start": func(c *RolloutConfig) { c.Ramp = "1h" },
+		"bad ramp": func(c *RolloutConfig) {
+			c.Start = "2019-06-01T00:00:00Z"
+			c.Ramp = "nope"
+		},
+	}
+	for name, fn := range tests {
+		conf := NewConfig()
+		fn(&conf.Rollout)
+		if _, err := NewRollout(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
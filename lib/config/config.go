@@ -22,6 +22,7 @@ package config
 
 import (
 	"github.com/Jeffail/benthos/lib/api"
+	"github.com/Jeffail/benthos/lib/audit"
 	"github.com/Jeffail/benthos/lib/buffer"
 	"github.com/Jeffail/benthos/lib/condition"
 	"github.com/Jeffail/benthos/lib/input"
@@ -46,6 +47,7 @@ type Type struct {
 	Logger             log.Config     `json:"logger" yaml:"logger"`
 	Metrics            metrics.Config `json:"metrics" yaml:"metrics"`
 	Tracer             tracer.Config  `json:"tracer" yaml:"tracer"`
+	DropAudit          audit.Config   `json:"drop_audit" yaml:"drop_audit"`
 	SystemCloseTimeout string         `json:"shutdown_timeout" yaml:"shutdown_timeout"`
 }
 
@@ -61,6 +63,7 @@ func New() Type {
 		Logger:             log.NewConfig(),
 		Metrics:            metricsConf,
 		Tracer:             tracer.NewConfig(),
+		DropAudit:          audit.NewConfig(),
 		SystemCloseTimeout: "20s",
 	}
 }
@@ -77,6 +80,7 @@ type SanitisedConfig struct {
 	Logger             interface{} `json:"logger" yaml:"logger"`
 	Metrics            interface{} `json:"metrics" yaml:"metrics"`
 	Tracer             interface{} `json:"tracer" yaml:"tracer"`
+	DropAudit          interface{} `json:"drop_audit" yaml:"drop_audit"`
 	SystemCloseTimeout interface{} `json:"shutdown_timeout" yaml:"shutdown_timeout"`
 }
 
@@ -125,6 +129,12 @@ func (c Type) Sanitised() (*SanitisedConfig, error) {
 		return nil, err
 	}
 
+	var auditConf interface{}
+	auditConf, err = audit.SanitiseConfig(c.DropAudit)
+	if err != nil {
+		return nil, err
+	}
+
 	return &SanitisedConfig{
 		HTTP:               c.HTTP,
 		Input:              inConf,
@@ -135,6 +145,7 @@ func (c Type) Sanitised() (*SanitisedConfig, error) {
 		Logger:             c.Logger,
 		Metrics:            metConf,
 		Tracer:             tracConf,
+		DropAudit:          auditConf,
 		SystemCloseTimeout: c.SystemCloseTimeout,
 	}, nil
 }
@@ -210,4 +221,10 @@ func Read(path string, replaceEnvs bool, config *Type) ([]string, error) {
 	return Lint(configBytes, *config)
 }
 
+// ReadRaw reads a configuration file path with any JSON references expanded,
+// but without replacing environment variables.
+func ReadRaw(path string) ([]byte, error) {
+	return readWithJSONRefs(path, false)
+}
+
 //------------------------------------------------------------------------------
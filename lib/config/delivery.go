@@ -0,0 +1,232 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Jeffail/benthos/lib/buffer"
+	"github.com/Jeffail/benthos/lib/input"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/stream"
+)
+
+//------------------------------------------------------------------------------
+
+// LintDelivery reports combinations of components within a config that break
+// the propagation of acknowledgements from outputs back to inputs, where
+// messages can be lost without an error being returned to the source. An empty
+// result means that a message is only acknowledged at its source once it has
+// been delivered by the output.
+//
+// Resources only contain caches, conditions, rate limits and plugins, none of
+// which acknowledge messages, and therefore only the stream is checked.
+func LintDelivery(conf Type) []string {
+	return LintStreamDelivery(conf.Config)
+}
+
+// LintStreamDelivery reports combinations of components within a stream config
+// that break the propagation of acknowledgements, as described by
+// LintDelivery, which is used for each stream in streams mode.
+func LintStreamDelivery(conf stream.Config) []string {
+	lints := []string{}
+
+	switch conf.Buffer.Type {
+	case buffer.TypeNone:
+	case buffer.TypeMemory:
+		lints = append(lints, "path 'buffer': Type 'memory' acknowledges messages at the input as soon as they are buffered, and any messages within it are lost if Benthos stops")
+	default:
+		lints = append(lints, fmt.Sprintf("path 'buffer': Type '%v' acknowledges messages at the input as soon as they are buffered, and any messages within it are lost if the buffer cannot be recovered", conf.Buffer.Type))
+	}
+
+	// A batch processor holds back acknowledgements for the messages it has
+	// buffered, which only works when messages reach it one at a time in the
+	// order they were read. Otherwise a flushed batch is acknowledged at the
+	// input along with earlier messages that are still buffered elsewhere.
+	inFlightPath := lintDeliveryInFlight("input", conf.Input)
+	lints = append(lints, lintDeliveryInput("input", conf.Input, inFlightPath)...)
+	pipelineReason := inFlightReason(inFlightPath)
+	if len(pipelineReason) == 0 && conf.Pipeline.Threads > 1 {
+		pipelineReason = "acknowledges messages at the input before they are flushed by other processing threads, set 'pipeline.threads' to 1 or use a batching policy on the input or output instead"
+	}
+	lints = append(lints, lintDeliveryProcs("pipeline.processors", conf.Pipeline.Processors, pipelineReason)...)
+	lints = append(lints, lintDeliveryOutput("output", conf.Output, inFlightPath)...)
+	return lints
+}
+
+// inputChildren returns the paths and configs of the inputs nested within an
+// input.
+func inputChildren(path string, conf input.Config) ([]string, []input.Config) {
+	var paths []string
+	var children []input.Config
+	switch conf.Type {
+	case input.TypeBroker:
+		for i, c := range conf.Broker.Inputs {
+			paths = append(paths, fmt.Sprintf("%v.broker.inputs[%v]", path, i))
+			children = append(children, c)
+		}
+	case input.TypeDynamic:
+		keys := make([]string, 0, len(conf.Dynamic.Inputs))
+		for k := range conf.Dynamic.Inputs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			paths = append(paths, fmt.Sprintf("%v.dynamic.inputs.%v", path, k))
+			children = append(children, conf.Dynamic.Inputs[k])
+		}
+	case input.TypeReadUntil:
+		if conf.ReadUntil.Input != nil {
+			paths = append(paths, path+".read_until.input")
+			children = append(children, *conf.ReadUntil.Input)
+		}
+	case input.TypeTee:
+		if conf.Tee.Input != nil {
+			paths = append(paths, path+".tee.input")
+			children = append(children, *conf.Tee.Input)
+		}
+	case input.TypeContract:
+		if conf.Contract.Input != nil {
+			paths = append(paths, path+".contract.input")
+			children = append(children, *conf.Contract.Input)
+		}
+	}
+	return paths, children
+}
+
+// lintDeliveryInFlight returns the path of the field that allows an input, or
+// any input nested within it, to have multiple batches in flight, or an empty
+// string if they have at most one.
+func lintDeliveryInFlight(path string, conf input.Config) string {
+	switch conf.Type {
+	case input.TypeKafka:
+		if conf.Kafka.MaxInFlight > 1 {
+			return path + ".kafka.max_in_flight"
+		}
+	case input.TypeKafkaBalanced:
+		if conf.KafkaBalanced.MaxInFlight > 1 {
+			return path + ".kafka_balanced.max_in_flight"
+		}
+	}
+	paths, children := inputChildren(path, conf)
+	for i, c := range children {
+		if inFlightPath := lintDeliveryInFlight(paths[i], c); len(inFlightPath) > 0 {
+			return inFlightPath
+		}
+	}
+	return ""
+}
+
+func lintDeliveryInput(path string, conf input.Config, inFlightPath string) []string {
+	lints := lintDeliveryProcs(path+".processors", conf.Processors, inFlightReason(inFlightPath))
+
+	paths, children := inputChildren(path, conf)
+	for i, c := range children {
+		lints = append(lints, lintDeliveryInput(paths[i], c, inFlightPath)...)
+	}
+	return lints
+}
+
+// inFlightReason describes why batch processors lose messages when an input
+// has multiple batches in flight, or is empty if it has at most one.
+func inFlightReason(inFlightPath string) string {
+	if len(inFlightPath) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("acknowledges messages at the input before the batch is flushed when '%v' is greater than 1", inFlightPath)
+}
+
+// lintDeliveryProcs reports processors that remove messages, which are
+// acknowledged at the input without being delivered, and when a reason is
+// given also batch processors.
+func lintDeliveryProcs(path string, procs []processor.Config, batchReason string) []string {
+	lints := []string{}
+	for i, proc := range procs {
+		switch proc.Type {
+		case processor.TypeBatch:
+			if len(batchReason) > 0 {
+				lints = append(lints, fmt.Sprintf("path '%v[%v]': Type 'batch' %v", path, i, batchReason))
+			}
+		case processor.TypeFilter:
+			lints = append(lints, fmt.Sprintf("path '%v[%v]': Type 'filter' acknowledges the messages it removes at the input", path, i))
+		case processor.TypeFilterParts, processor.TypeSelectParts:
+			lints = append(lints, fmt.Sprintf("path '%v[%v]': Type '%v' acknowledges the message parts it removes at the input", path, i, proc.Type))
+		}
+	}
+	return lints
+}
+
+func lintDeliveryOutput(path string, conf output.Config, inFlightPath string) []string {
+	lints := lintDeliveryProcs(path+".processors", conf.Processors, inFlightReason(inFlightPath))
+
+	type child struct {
+		path string
+		conf output.Config
+	}
+	children := []child{}
+	switch conf.Type {
+	case output.TypeDropOnError:
+		lints = append(lints, fmt.Sprintf("path '%v': Type 'drop_on_error' acknowledges messages that failed to be delivered", path))
+		if conf.DropOnError.Config != nil {
+			children = append(children, child{path + ".drop_on_error", *conf.DropOnError.Config})
+		}
+	case output.TypeNATS:
+		lints = append(lints, fmt.Sprintf("path '%v': Type 'nats' is at-most-once and does not confirm that messages were received", path))
+	case output.TypeRedisPubSub:
+		lints = append(lints, fmt.Sprintf("path '%v': Type 'redis_pubsub' does not confirm that messages were received", path))
+	case output.TypeMQTT:
+		if conf.MQTT.QoS == 0 {
+			lints = append(lints, fmt.Sprintf("path '%v.mqtt.qos': A QoS of 0 is at-most-once and does not confirm that messages were received", path))
+		}
+	case output.TypeBroker:
+		for i, c := range conf.Broker.Outputs {
+			children = append(children, child{fmt.Sprintf("%v.broker.outputs[%v]", path, i), c})
+		}
+	case output.TypeSharded:
+		for i, c := range conf.Sharded.Outputs {
+			children = append(children, child{fmt.Sprintf("%v.sharded.outputs[%v]", path, i), c})
+		}
+	case output.TypeSwitch:
+		for i, c := range conf.Switch.Outputs {
+			children = append(children, child{fmt.Sprintf("%v.switch.outputs[%v].output", path, i), c.Output})
+		}
+	case output.TypeRetry:
+		if conf.Retry.Output != nil {
+			children = append(children, child{path + ".retry.output", *conf.Retry.Output})
+		}
+	case output.TypeDeadLetter:
+		if conf.DeadLetter.Output != nil {
+			children = append(children, child{path + ".dead_letter.output", *conf.DeadLetter.Output})
+		}
+		if conf.DeadLetter.DeadLetter != nil {
+			children = append(children, child{path + ".dead_letter.dead_letter", *conf.DeadLetter.DeadLetter})
+		}
+	}
+
+	for _, c := range children {
+		lints = append(lints, lintDeliveryOutput(c.path, c.conf, inFlightPath)...)
+	}
+	return lints
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+func TestLintDelivery(t *testing.T) {
+	type testObj struct {
+		name  string
+		conf  string
+		lints []string
+	}
+
+	tests := []testObj{
+		{
+			name: "no problems",
+			conf: `input:
+  kafka: {}
+pipeline:
+  processors:
+  - jmespath: {}
+output:
+  kafka: {}`,
+			lints: []string{},
+		},
+		{
+			name: "memory buffer",
+			conf: `input:
+  kafka: {}
+buffer:
+  memory: {}
+output:
+  kafka: {}`,
+			lints: []string{
+				"path 'buffer': Type 'memory' acknowledges messages at the input as soon as they are buffered, and any messages within it are lost if Benthos stops",
+			},
+		},
+		{
+			name: "batch processors",
+			conf: `input:
+  kafka: {}
+  processors:
+  - batch: {}
+pipeline:
+  processors:
+  - jmespath: {}
+  - batch: {}
+output:
+  kafka: {}
+  processors:
+  - batch: {}`,
+			lints: []string{},
+		},
+		{
+			name: "batch processors multiple threads",
+			conf: `input:
+  kafka: {}
+  processors:
+  - batch: {}
+pipeline:
+  threads: 2
+  processors:
+  - jmespath: {}
+  - batch: {}
+output:
+  kafka: {}
+  processors:
+  - batch: {}`,
+			lints: []string{
+				"path 'pipeline.processors[1]': Type 'batch' acknowledges messages at the input before they are flushed by other processing threads, set 'pipeline.threads' to 1 or use a batching policy on the input or output instead",
+			},
+		},
+		{
+			name: "batch processors multiple in flight",
+			conf: `input:
+  kafka:
+    max_in_flight: 2
+  processors:
+  - batch: {}
+pipeline:
+  processors:
+  - jmespath: {}
+  - batch: {}
+output:
+  broker:
+    outputs:
+    - kafka: {}
+      processors:
+      - batch: {}`,
+			lints: []string{
+				"path 'input.processors[0]': Type 'batch' acknowledges messages at the input before the batch is flushed when 'input.kafka.max_in_flight' is greater than 1",
+				"path 'pipeline.processors[1]': Type 'batch' acknowledges messages at the input before the batch is flushed when 'input.kafka.max_in_flight' is greater than 1",
+				"path 'output.broker.outputs[0].processors[0]': Type 'batch' acknowledges messages at the input before the batch is flushed when 'input.kafka.max_in_flight' is greater than 1",
+			},
+		},
+		{
+			name: "nested outputs",
+			conf: `input:
+  kafka: {}
+output:
+  broker:
+    pattern: fan_out
+    outputs:
+    - kafka: {}
+    - nats: {}
+    - retry:
+        output:
+          drop_on_error:
+            redis_pubsub: {}
+    - mqtt:
+        qos: 0`,
+			lints: []string{
+				"path 'output.broker.outputs[1]': Type 'nats' is at-most-once and does not confirm that messages were received",
+				"path 'output.broker.outputs[2].retry.output': Type 'drop_on_error' acknowledges messages that failed to be delivered",
+				"path 'output.broker.outputs[2].retry.output.drop_on_error': Type 'redis_pubsub' does not confirm that messages were received",
+				"path 'output.broker.outputs[3].mqtt.qos': A QoS of 0 is at-most-once and does not confirm that messages were received",
+			},
+		},
+		{
+			name: "nested inputs",
+			conf: `input:
+  broker:
+    inputs:
+    - amqp: {}
+      processors:
+      - batch: {}
+    - read_until:
+        input:
+          kafka_balanced:
+            max_in_flight: 2
+pipeline:
+  processors:
+  - batch: {}
+output:
+  kafka: {}`,
+			lints: []string{
+				"path 'input.broker.inputs[0].processors[0]': Type 'batch' acknowledges messages at the input before the batch is flushed when 'input.broker.inputs[1].read_until.input.kafka_balanced.max_in_flight' is greater than 1",
+				"path 'pipeline.processors[0]': Type 'batch' acknowledges messages at the input before the batch is flushed when 'input.broker.inputs[1].read_until.input.kafka_balanced.max_in_flight' is greater than 1",
+			},
+		},
+		{
+			name: "removing processors",
+			conf: `input:
+  broker:
+    inputs:
+    - kafka: {}
+      processors:
+      - filter_parts: {}
+pipeline:
+  processors:
+  - jmespath: {}
+  - filter: {}
+output:
+  kafka: {}
+  processors:
+  - select_parts: {}`,
+			lints: []string{
+				"path 'input.broker.inputs[0].processors[0]': Type 'filter_parts' acknowledges the message parts it removes at the input",
+				"path 'pipeline.processors[1]': Type 'filter' acknowledges the messages it removes at the input",
+				"path 'output.processors[0]': Type 'select_parts' acknowledges the message parts it removes at the input",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			config := New()
+			if err := yaml.Unmarshal([]byte(test.conf), &config); err != nil {
+				tt.Fatal(err)
+			}
+			if exp, act := test.lints, LintDelivery(config); !reflect.DeepEqual(exp, act) {
+				tt.Errorf("Wrong lint results: %v != %v", act, exp)
+			}
+		})
+	}
+}
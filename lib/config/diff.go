@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	uconfig "github.com/Jeffail/benthos/lib/util/config"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// DiffResponse is the body returned by a DiffHandler, containing the sanitised
+// running config and, when a candidate config was posted, the sanitised
+// candidate, any lint errors found within it and the changes between them.
+type DiffResponse struct {
+	Running   *SanitisedConfig `json:"running"`
+	Candidate *SanitisedConfig `json:"candidate,omitempty"`
+	Lints     []string         `json:"lints,omitempty"`
+	Changes   []uconfig.Change `json:"changes,omitempty"`
+}
+
+// Diff parses a candidate config and returns a DiffResponse describing how it
+// differs from c. Environment variables within the candidate are replaced.
+func (c Type) Diff(candidateBytes []byte) (*DiffResponse, error) {
+	running, err := c.Sanitised()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sanitise running config: %v", err)
+	}
+	res := &DiffResponse{Running: running}
+	if len(candidateBytes) == 0 {
+		return res, nil
+	}
+
+	candidateBytes = text.ReplaceEnvVariables(candidateBytes)
+
+	candidate := New()
+	if err = yaml.Unmarshal(candidateBytes, &candidate); err != nil {
+		return nil, fmt.Errorf("failed to parse candidate config: %v", err)
+	}
+	if res.Lints, err = Lint(candidateBytes, candidate); err != nil {
+		return nil, fmt.Errorf("failed to lint candidate config: %v", err)
+	}
+	if res.Candidate, err = candidate.Sanitised(); err != nil {
+		return nil, fmt.Errorf("failed to sanitise candidate config: %v", err)
+	}
+	if res.Changes, err = uconfig.Diff(res.Running, res.Candidate); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DiffHandler returns an HTTP handler that responds with the sanitised config
+// c, and when a candidate config is posted in the request body the changes
+// required to reach it.
+func (c Type) DiffHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var candidateBytes []byte
+		if r.Method == "POST" {
+			var err error
+			if candidateBytes, err = ioutil.ReadAll(r.Body); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
+				return
+			}
+		} else if r.Method != "GET" {
+			http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		res, err := c.Diff(candidateBytes)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		resBytes, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiffHandler(t *testing.T) {
+	running := New()
+	running.Input.Type = "kafka"
+	running.Input.Kafka.Topic = "foo"
+
+	candidate := []byte(`
+input:
+  type: kafka
+  kafka:
+    topic: bar
+`)
+
+	h := running.DiffHandler()
+
+	req := httptest.NewRequest("POST", "/debug/config/diff", bytes.NewReader(candidate))
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Fatalf("Wrong status code: %v != %v: %s", act, exp, rec.Body.Bytes())
+	}
+
+	var res struct {
+		Running   map[string]interface{} `json:"running"`
+		Candidate map[string]interface{} `json:"candidate"`
+		Changes   []struct {
+			Path string      `json:"path"`
+			Op   string      `json:"op"`
+			From interface{} `json:"from"`
+			To   interface{} `json:"to"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Running == nil || res.Candidate == nil {
+		t.Fatalf("Missing configs: %s", rec.Body.Bytes())
+	}
+	if len(res.Changes) != 1 {
+		t.Fatalf("Wrong count of changes: %s", rec.Body.Bytes())
+	}
+	if exp, act := "input.kafka.topic", res.Changes[0].Path; exp != act {
+		t.Errorf("Wrong path: %v != %v", act, exp)
+	}
+	if exp, act := "foo", res.Changes[0].From; exp != act {
+		t.Errorf("Wrong from: %v != %v", act, exp)
+	}
+	if exp, act := "bar", res.Changes[0].To; exp != act {
+		t.Errorf("Wrong to: %v != %v", act, exp)
+	}
+
+	req = httptest.NewRequest("GET", "/debug/config/diff", nil)
+	rec = httptest.NewRecorder()
+	h(rec, req)
+
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Fatalf("Wrong status code: %v != %v", act, exp)
+	}
+
+	req = httptest.NewRequest("POST", "/debug/config/diff", bytes.NewReader([]byte(`{ not valid`)))
+	rec = httptest.NewRecorder()
+	h(rec, req)
+
+	if exp, act := http.StatusBadRequest, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+}
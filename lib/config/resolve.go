@@ -0,0 +1,248 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	uconfig "github.com/Jeffail/benthos/lib/util/config"
+	"github.com/Jeffail/benthos/lib/util/text"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+const maskedValue = "********"
+
+var (
+	secretKeyWords = []string{"password", "secret", "token", "passphrase"}
+	urlCredsRegex  = regexp.MustCompile(`://([^:/@\s]+):([^@/\s]+)@`)
+)
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, w := range secretKeyWords {
+		if strings.Contains(key, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskScalar masks the value of a scalar node when its key suggests that it
+// is a secret, or otherwise masks any password within a URL.
+func maskScalar(key string, n *yaml.Node) {
+	if len(n.Value) == 0 {
+		return
+	}
+	if isSecretKey(key) {
+		n.Tag, n.Value = "!!str", maskedValue
+		return
+	}
+	n.Value = urlCredsRegex.ReplaceAllString(n.Value, "://$1:"+maskedValue+"@")
+}
+
+//------------------------------------------------------------------------------
+
+// envSources walks a config parsed prior to environment variable replacement
+// and returns a map of dot separated field paths to descriptions of how the
+// environment variables within each field were resolved.
+func envSources(rawConfig []byte) (map[string]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(rawConfig, &root); err != nil {
+		return nil, err
+	}
+	sources := map[string]string{}
+	var walk func(path string, n *yaml.Node)
+	walk = func(path string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(path, c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i < len(n.Content)-1; i += 2 {
+				walk(joinPath(path, n.Content[i].Value), n.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(joinPath(path, strconv.Itoa(i)), c)
+			}
+		case yaml.ScalarNode:
+			if srcs := text.EnvVariableSources([]byte(n.Value)); len(srcs) > 0 {
+				sources[path] = strings.Join(srcs, "; ")
+			}
+		}
+	}
+	walk("", &root)
+	return sources, nil
+}
+
+// envReferences walks a config parsed prior to environment variable
+// replacement and returns a map of dot separated field paths to the raw values
+// of fields that contain environment variable references.
+func envReferences(rawConfig []byte) (map[string]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(rawConfig, &root); err != nil {
+		return nil, err
+	}
+	refs := map[string]string{}
+	var walk func(path string, n *yaml.Node)
+	walk = func(path string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(path, c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i < len(n.Content)-1; i += 2 {
+				walk(joinPath(path, n.Content[i].Value), n.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(joinPath(path, strconv.Itoa(i)), c)
+			}
+		case yaml.ScalarNode:
+			if len(text.EnvVariableSources([]byte(n.Value))) > 0 {
+				refs[path] = n.Value
+			}
+		}
+	}
+	walk("", &root)
+	return refs, nil
+}
+
+func joinPath(path, seg string) string {
+	if len(path) == 0 {
+		return seg
+	}
+	return path + "." + seg
+}
+
+// MarshalResolvedYAML marshals a config structure as YAML with the values of
+// secret fields masked. Fields that contained environment variable references
+// within rawConfig, the config file prior to their replacement, are annotated
+// with a comment describing where their values were resolved from.
+func MarshalResolvedYAML(conf interface{}, rawConfig []byte) ([]byte, error) {
+	sources, err := envSources(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	confBytes, err := yaml.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(confBytes, &root); err != nil {
+		return nil, err
+	}
+
+	var walk func(path string, key *yaml.Node, n *yaml.Node)
+	walk = func(path string, key *yaml.Node, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(path, nil, c)
+			}
+			return
+		case yaml.MappingNode:
+			for i := 0; i < len(n.Content)-1; i += 2 {
+				k := n.Content[i]
+				walk(joinPath(path, k.Value), k, n.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(joinPath(path, strconv.Itoa(i)), nil, c)
+			}
+		case yaml.ScalarNode:
+			keyStr := ""
+			if key != nil {
+				keyStr = key.Value
+			}
+			maskScalar(keyStr, n)
+		}
+		if src, exists := sources[path]; exists {
+			if n.Kind == yaml.ScalarNode {
+				n.LineComment = "# " + src
+			} else if key != nil {
+				key.LineComment = "# " + src
+			}
+		}
+	}
+	walk("", nil, &root)
+
+	return uconfig.MarshalYAML(&root)
+}
+
+//------------------------------------------------------------------------------
+
+// MarshalUnresolvedYAML marshals a config structure as YAML where the values of
+// fields that were resolved from environment variable references within
+// rawConfig, the config file prior to their replacement, are replaced with
+// those references again. This prevents resolved values, which are often
+// secrets, from being written out. Fields that have been modified since they
+// were resolved are left as they are.
+func MarshalUnresolvedYAML(conf interface{}, rawConfig []byte) ([]byte, error) {
+	refs, err := envReferences(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	confBytes, err := yaml.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(confBytes, &root); err != nil {
+		return nil, err
+	}
+
+	var walk func(path string, n *yaml.Node)
+	walk = func(path string, n *yaml.Node) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(path, c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i < len(n.Content)-1; i += 2 {
+				walk(joinPath(path, n.Content[i].Value), n.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(joinPath(path, strconv.Itoa(i)), c)
+			}
+		case yaml.ScalarNode:
+			ref, exists := refs[path]
+			if exists && n.Value == string(text.ReplaceEnvVariables([]byte(ref))) {
+				n.Tag, n.Style, n.Value = "!!str", 0, ref
+			}
+		}
+	}
+	walk("", &root)
+
+	return uconfig.MarshalYAML(&root)
+}
+
+//------------------------------------------------------------------------------
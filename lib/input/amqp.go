@@ -40,6 +40,16 @@ The field ` + "`max_batch_count`" + ` specifies the maximum number of prefetched
 messages to be batched together. When more than one message is batched they can
 be split into individual messages with the ` + "`split`" + ` processor.
 
+Prefetching is controlled with the ` + "`prefetch_count`" + ` and
+` + "`prefetch_size`" + ` fields, which set the QoS of the channel. Since a
+batch can only be formed from prefetched messages ` + "`prefetch_count`" + `
+should be at least as large as ` + "`max_batch_count`" + `.
+
+The gauge ` + "`prefetch.unacked`" + ` reports the number of messages that have
+been delivered but are awaiting acknowledgement, and
+` + "`batch.utilisation`" + ` reports the size of each batch as a percentage of
+` + "`max_batch_count`" + `.
+
 It's possible for this input type to declare the target queue by setting
 ` + "`queue_declare.enabled` to `true`" + `, if the queue already exists then
 the declaration passively verifies that they match the target fields.
@@ -96,7 +106,12 @@ func NewAMQP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if err != nil {
 		return nil, err
 	}
-	return NewReader("amqp", a, log, stats)
+	r, err := NewReader("amqp", a, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	registerPausable("amqp", r, mgr, stats)
+	return r, nil
 }
 
 //------------------------------------------------------------------------------
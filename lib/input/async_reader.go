@@ -0,0 +1,361 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// AsyncReader is an input implementation that reads messages from a
+// reader.Async and allows a configured number of them to be in flight at a
+// time. Messages that fail to propagate are resent until they succeed, and each
+// message is acknowledged once with the final outcome of its delivery.
+type AsyncReader struct {
+	running   int32
+	connected int32
+
+	typeStr     string
+	maxInFlight int
+	reader      reader.Async
+
+	stats metrics.Type
+	log   log.Modular
+
+	connThrot *throttle.Type
+
+	pauseMut   sync.Mutex
+	resumeChan chan struct{}
+	mPaused    metrics.StatGauge
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewAsyncReader creates a new AsyncReader input type.
+func NewAsyncReader(
+	typeStr string,
+	maxInFlight int,
+	r reader.Async,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	rdr := &AsyncReader{
+		running:      1,
+		typeStr:      typeStr,
+		maxInFlight:  maxInFlight,
+		reader:       r,
+		log:          log,
+		stats:        stats,
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+		mPaused:      stats.GetGauge("paused"),
+	}
+
+	rdr.connThrot = throttle.New(throttle.OptCloseChan(rdr.closeChan))
+
+	go rdr.loop()
+	return rdr, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *AsyncReader) loop() {
+	// Metrics paths
+	var (
+		mRunning     = r.stats.GetGauge("running")
+		mInFlight    = r.stats.GetGauge("in_flight")
+		mCount       = r.stats.GetCounter("count")
+		mPartsCount  = r.stats.GetCounter("parts.count")
+		mRcvd        = r.stats.GetCounter("batch.received")
+		mPartsRcvd   = r.stats.GetCounter("received")
+		mReadSuccess = r.stats.GetCounter("read.success")
+		mReadError   = r.stats.GetCounter("read.error")
+		mSendSuccess = r.stats.GetCounter("send.success")
+		mSendError   = r.stats.GetCounter("send.error")
+		mAckSuccess  = r.stats.GetCounter("ack.success")
+		mAckError    = r.stats.GetCounter("ack.error")
+		mConn        = r.stats.GetCounter("connection.up")
+		mFailedConn  = r.stats.GetCounter("connection.failed")
+		mLostConn    = r.stats.GetCounter("connection.lost")
+		mLatency     = r.stats.GetTimer("latency")
+	)
+
+	var inFlight int64
+	pendingAcks := sync.WaitGroup{}
+	inFlightChan := make(chan struct{}, r.maxInFlight)
+
+	defer func() {
+		pendingAcks.Wait()
+
+		err := r.reader.WaitForClose(time.Second)
+		for ; err != nil; err = r.reader.WaitForClose(time.Second) {
+		}
+		mRunning.Decr(1)
+		atomic.StoreInt32(&r.connected, 0)
+
+		close(r.transactions)
+		close(r.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	for {
+		if err := r.reader.Connect(); err != nil {
+			if err == types.ErrTypeClosed {
+				return
+			}
+			r.log.Errorf("Failed to connect to %v: %v\n", r.typeStr, err)
+			mFailedConn.Incr(1)
+			if !r.connThrot.Retry() {
+				return
+			}
+		} else {
+			r.connThrot.Reset()
+			break
+		}
+	}
+	mConn.Incr(1)
+	atomic.StoreInt32(&r.connected, 1)
+
+	// propagate sends a message down the pipeline until it is successfully
+	// delivered, and then calls its acknowledgement function exactly once with
+	// the final outcome. Messages that are held without acknowledgement by
+	// processors are never acknowledged here, the same as the Reader input.
+	propagate := func(msg types.Message, ackFn reader.AsyncAckFn) {
+		defer func() {
+			mInFlight.Set(atomic.AddInt64(&inFlight, -1))
+			<-inFlightChan
+			pendingAcks.Done()
+		}()
+
+		throt := throttle.New(
+			throttle.OptCloseChan(r.closeChan),
+			throttle.OptThrottlePeriod(time.Second),
+		)
+		resChan := make(chan types.Response)
+		for {
+			select {
+			case r.transactions <- types.NewTransaction(msg, resChan):
+			case <-r.closeChan:
+				ackFn(types.ErrTypeClosed)
+				return
+			}
+
+			var res types.Response
+			select {
+			case res = <-resChan:
+			case <-r.closeChan:
+				// The pipeline is terminating but we still want to attempt to
+				// propagate an acknowledgement from in-transit messages.
+				select {
+				case res = <-resChan:
+				case <-time.After(time.Second):
+					ackFn(types.ErrTypeClosed)
+					return
+				}
+			}
+
+			if err := res.Error(); err != nil {
+				mSendError.Incr(1)
+				if !throt.Retry() {
+					ackFn(err)
+					return
+				}
+				continue
+			}
+
+			mSendSuccess.Incr(1)
+			if !res.SkipAck() {
+				if err := ackFn(nil); err != nil {
+					mAckError.Incr(1)
+				} else {
+					tTaken := time.Since(msg.CreatedAt()).Nanoseconds()
+					mLatency.Timing(tTaken)
+					mAckSuccess.Incr(1)
+				}
+			}
+			tracing.FinishSpans(msg)
+			return
+		}
+	}
+
+	for atomic.LoadInt32(&r.running) == 1 {
+		if resumeChan := r.pausedChan(); resumeChan != nil {
+			select {
+			case <-resumeChan:
+			case <-r.closeChan:
+				return
+			}
+		}
+
+		select {
+		case inFlightChan <- struct{}{}:
+		case <-r.closeChan:
+			return
+		}
+
+		msg, ackFn, err := r.reader.ReadWithAck()
+
+		// If our reader says it is not connected.
+		if err == types.ErrNotConnected {
+			mLostConn.Incr(1)
+			atomic.StoreInt32(&r.connected, 0)
+
+			// Continue to try to reconnect while still active.
+			for atomic.LoadInt32(&r.running) == 1 {
+				if err = r.reader.Connect(); err != nil {
+					// Close immediately if our reader is closed.
+					if err == types.ErrTypeClosed {
+						return
+					}
+
+					r.log.Errorf("Failed to reconnect to %v: %v\n", r.typeStr, err)
+					mFailedConn.Incr(1)
+				} else if msg, ackFn, err = r.reader.ReadWithAck(); err != types.ErrNotConnected {
+					mConn.Incr(1)
+					atomic.StoreInt32(&r.connected, 1)
+					r.connThrot.Reset()
+					break
+				}
+				if !r.connThrot.Retry() {
+					return
+				}
+			}
+		}
+
+		// Close immediately if our reader is closed.
+		if err == types.ErrTypeClosed {
+			return
+		}
+
+		if err != nil || msg == nil {
+			<-inFlightChan
+			if err != types.ErrTimeout && err != types.ErrNotConnected {
+				mReadError.Incr(1)
+				r.log.Errorf("Failed to read message: %v\n", err)
+			}
+			if !r.connThrot.Retry() {
+				return
+			}
+			continue
+		} else {
+			r.connThrot.Reset()
+			mCount.Incr(1)
+			mPartsCount.Incr(int64(msg.Len()))
+			mReadSuccess.Incr(1)
+			mPartsRcvd.Incr(int64(msg.Len()))
+			mRcvd.Incr(1)
+		}
+
+		tracing.InitSpans("input_"+r.typeStr, msg)
+
+		mInFlight.Set(atomic.AddInt64(&inFlight, 1))
+		pendingAcks.Add(1)
+		go propagate(msg, ackFn)
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (r *AsyncReader) TransactionChan() <-chan types.Transaction {
+	return r.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (r *AsyncReader) Connected() bool {
+	return atomic.LoadInt32(&r.connected) == 1
+}
+
+// pausedChan returns a channel that is closed once the input is resumed, or nil
+// if the input is not paused.
+func (r *AsyncReader) pausedChan() <-chan struct{} {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	if r.resumeChan == nil {
+		return nil
+	}
+	return r.resumeChan
+}
+
+// Pause stops the AsyncReader from reading new messages until Resume is called.
+// Messages that are already in flight are still delivered and acknowledged.
+func (r *AsyncReader) Pause() {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	if r.resumeChan == nil {
+		r.resumeChan = make(chan struct{})
+		r.mPaused.Set(1)
+		r.log.Infof("Pausing %v input\n", r.typeStr)
+	}
+}
+
+// Resume continues reading messages after a call to Pause.
+func (r *AsyncReader) Resume() {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	if r.resumeChan != nil {
+		close(r.resumeChan)
+		r.resumeChan = nil
+		r.mPaused.Set(0)
+		r.log.Infof("Resuming %v input\n", r.typeStr)
+	}
+}
+
+// Paused returns true if the AsyncReader is currently paused.
+func (r *AsyncReader) Paused() bool {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	return r.resumeChan != nil
+}
+
+// CloseAsync shuts down the AsyncReader input and stops processing requests.
+func (r *AsyncReader) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
+		r.reader.CloseAsync()
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the AsyncReader input has closed down.
+func (r *AsyncReader) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
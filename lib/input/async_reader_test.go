@@ -0,0 +1,243 @@
+// Copyright (c) 2018 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockAsyncReader struct {
+	mut   sync.Mutex
+	count int
+	acks  map[string][]error
+}
+
+func newMockAsyncReader() *mockAsyncReader {
+	return &mockAsyncReader{
+		acks: map[string][]error{},
+	}
+}
+
+func (r *mockAsyncReader) Connect() error {
+	return nil
+}
+func (r *mockAsyncReader) ReadWithAck() (types.Message, reader.AsyncAckFn, error) {
+	r.mut.Lock()
+	r.count++
+	content := fmt.Sprintf("foo%v", r.count)
+	r.mut.Unlock()
+
+	return message.New([][]byte{[]byte(content)}), func(err error) error {
+		r.mut.Lock()
+		r.acks[content] = append(r.acks[content], err)
+		r.mut.Unlock()
+		return nil
+	}, nil
+}
+func (r *mockAsyncReader) CloseAsync() {}
+func (r *mockAsyncReader) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestAsyncReaderMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	readerImpl := newMockAsyncReader()
+	r, err := NewAsyncReader("foo", 3, readerImpl, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var trans []types.Transaction
+	for i := 0; i < 3; i++ {
+		select {
+		case tran := <-r.TransactionChan():
+			trans = append(trans, tran)
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case <-r.TransactionChan():
+		t.Fatal("Received transaction beyond max in flight")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	// Acknowledge out of order to free up a single slot.
+	select {
+	case trans[1].ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if exp, act := "foo4", string(tran.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+
+	select {
+	case <-r.TransactionChan():
+		t.Fatal("Received transaction beyond max in flight")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	r.CloseAsync()
+	for _, tran := range append([]types.Transaction{trans[0], trans[2]}, tran) {
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	readerImpl.mut.Lock()
+	defer readerImpl.mut.Unlock()
+	for _, k := range []string{"foo1", "foo2", "foo3", "foo4"} {
+		if exp, act := []error{nil}, readerImpl.acks[k]; len(act) != 1 || act[0] != exp[0] {
+			t.Errorf("Wrong acks for %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestAsyncReaderResend(t *testing.T) {
+	t.Parallel()
+
+	readerImpl := newMockAsyncReader()
+	r, err := NewAsyncReader("foo", 1, readerImpl, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errTest := errors.New("test err")
+
+	var tran types.Transaction
+	select {
+	case tran = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- response.NewError(errTest):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case tran = <-r.TransactionChan():
+	case <-time.After(time.Second * 2):
+		t.Fatal("timed out")
+	}
+	if exp, act := "foo1", string(tran.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+
+	r.CloseAsync()
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	readerImpl.mut.Lock()
+	defer readerImpl.mut.Unlock()
+	if acks := readerImpl.acks["foo1"]; len(acks) != 1 || acks[0] != nil {
+		t.Errorf("Wrong acks: %v", acks)
+	}
+}
+
+func TestAsyncReaderSkipAck(t *testing.T) {
+	t.Parallel()
+
+	readerImpl := newMockAsyncReader()
+	r, err := NewAsyncReader("foo", 1, readerImpl, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran.ResponseChan <- response.NewUnack():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case tran = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if exp, act := "foo2", string(tran.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+
+	r.CloseAsync()
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	readerImpl.mut.Lock()
+	defer readerImpl.mut.Unlock()
+	if acks, exists := readerImpl.acks["foo1"]; exists {
+		t.Errorf("Held message was acknowledged: %v", acks)
+	}
+	if acks := readerImpl.acks["foo2"]; len(acks) != 1 || acks[0] != nil {
+		t.Errorf("Wrong acks: %v", acks)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -63,6 +63,7 @@ var Constructors = map[string]TypeSpec{}
 const (
 	TypeAMQP          = "amqp"
 	TypeBroker        = "broker"
+	TypeContract      = "contract"
 	TypeDynamic       = "dynamic"
 	TypeFile          = "file"
 	TypeFiles         = "files"
@@ -83,9 +84,13 @@ const (
 	TypeRedisList     = "redis_list"
 	TypeRedisPubSub   = "redis_pubsub"
 	TypeRedisStreams  = "redis_streams"
+	TypeRSS           = "rss"
 	TypeS3            = "s3"
+	TypeSQLite        = "sqlite"
 	TypeSQS           = "sqs"
 	TypeSTDIN         = "stdin"
+	TypeTCPServer     = "tcp_server"
+	TypeTee           = "tee"
 	TypeWebsocket     = "websocket"
 	TypeZMQ4          = "zmq4"
 )
@@ -97,6 +102,7 @@ type Config struct {
 	Type          string                     `json:"type" yaml:"type"`
 	AMQP          reader.AMQPConfig          `json:"amqp" yaml:"amqp"`
 	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
+	Contract      ContractConfig             `json:"contract" yaml:"contract"`
 	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
 	File          FileConfig                 `json:"file" yaml:"file"`
 	Files         reader.FilesConfig         `json:"files" yaml:"files"`
@@ -118,9 +124,13 @@ type Config struct {
 	RedisList     reader.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub   reader.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
 	RedisStreams  reader.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
+	RSS           reader.RSSConfig           `json:"rss" yaml:"rss"`
 	S3            reader.AmazonS3Config      `json:"s3" yaml:"s3"`
+	SQLite        *reader.SQLiteConfig       `json:"sqlite,omitempty" yaml:"sqlite,omitempty"`
 	SQS           reader.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
 	STDIN         STDINConfig                `json:"stdin" yaml:"stdin"`
+	TCPServer     TCPServerConfig            `json:"tcp_server" yaml:"tcp_server"`
+	Tee           TeeConfig                  `json:"tee" yaml:"tee"`
 	Websocket     reader.WebsocketConfig     `json:"websocket" yaml:"websocket"`
 	ZMQ4          *reader.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
 	Processors    []processor.Config         `json:"processors" yaml:"processors"`
@@ -132,6 +142,7 @@ func NewConfig() Config {
 		Type:          "stdin",
 		AMQP:          reader.NewAMQPConfig(),
 		Broker:        NewBrokerConfig(),
+		Contract:      NewContractConfig(),
 		Dynamic:       NewDynamicConfig(),
 		File:          NewFileConfig(),
 		Files:         reader.NewFilesConfig(),
@@ -153,9 +164,13 @@ func NewConfig() Config {
 		RedisList:     reader.NewRedisListConfig(),
 		RedisPubSub:   reader.NewRedisPubSubConfig(),
 		RedisStreams:  reader.NewRedisStreamsConfig(),
+		RSS:           reader.NewRSSConfig(),
 		S3:            reader.NewAmazonS3Config(),
+		SQLite:        reader.NewSQLiteConfig(),
 		SQS:           reader.NewAmazonSQSConfig(),
 		STDIN:         NewSTDINConfig(),
+		TCPServer:     NewTCPServerConfig(),
+		Tee:           NewTeeConfig(),
 		Websocket:     reader.NewWebsocketConfig(),
 		ZMQ4:          reader.NewZMQ4Config(),
 		Processors:    []processor.Config{},
@@ -0,0 +1,448 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/linkedin/goavro/v2"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeContract] = TypeSpec{
+		constructor: NewContract,
+		description: `
+Reads from a child input and validates each message against a schema before it
+enters the pipeline. Supported schema types are ` + "`json_schema`" + ` and
+` + "`avro`" + `, and the schema can either be provided inline with the field
+` + "`schema`" + ` or loaded from a file with ` + "`schema_path`" + `.
+
+` + "``` yaml" + `
+input:
+  contract:
+    name: orders
+    schema_type: json_schema
+    schema_path: ./schemas/order.json
+    input:
+      http_server:
+        path: /orders
+` + "```" + `
+
+When the schema type is ` + "`avro`" + ` messages are validated by decoding
+them with the encoding set by ` + "`avro_encoding`" + `, which can be one of
+` + "`textual`, `binary` or `single`" + `.
+
+### Rejecting Invalid Messages
+
+Where the protocol of the child input supports it invalid messages are rejected
+back to the source. When any message of a batch is invalid the whole batch is
+rejected. Currently the following inputs support rejection:
+
+- ` + "`http_server`" + `: requests are responded to with a 400 status code,
+  and websocket messages are not retried.
+- ` + "`amqp`" + `: messages are nacked without being requeued, which allows
+  them to be routed to a dead letter exchange.
+
+For all other inputs invalid messages are logged and removed from their batch,
+and the remaining valid messages continue through the pipeline. Invalid
+messages are therefore acknowledged at the source, e.g. for Kafka the offset is
+committed past them.
+
+### Metrics
+
+Metrics are namespaced by the field ` + "`name`" + ` as
+` + "`contract.<name>`" + `, which allows you to track violations per
+contract.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var inputSanit interface{} = struct{}{}
+			if conf.Contract.Input != nil {
+				var err error
+				if inputSanit, err = SanitiseConfig(*conf.Contract.Input); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"name":          conf.Contract.Name,
+				"schema_type":   conf.Contract.SchemaType,
+				"schema":        conf.Contract.Schema,
+				"schema_path":   conf.Contract.SchemaPath,
+				"avro_encoding": conf.Contract.AvroEncoding,
+				"input":         inputSanit,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ContractConfig contains configuration values for the Contract input type.
+type ContractConfig struct {
+	Input        *Config `json:"input" yaml:"input"`
+	Name         string  `json:"name" yaml:"name"`
+	SchemaType   string  `json:"schema_type" yaml:"schema_type"`
+	Schema       string  `json:"schema" yaml:"schema"`
+	SchemaPath   string  `json:"schema_path" yaml:"schema_path"`
+	AvroEncoding string  `json:"avro_encoding" yaml:"avro_encoding"`
+}
+
+// NewContractConfig creates a new ContractConfig with default values.
+func NewContractConfig() ContractConfig {
+	return ContractConfig{
+		Input:        nil,
+		Name:         "",
+		SchemaType:   "json_schema",
+		Schema:       "",
+		SchemaPath:   "",
+		AvroEncoding: "textual",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyContractConfig struct {
+	Input        interface{} `json:"input" yaml:"input"`
+	Name         string      `json:"name" yaml:"name"`
+	SchemaType   string      `json:"schema_type" yaml:"schema_type"`
+	Schema       string      `json:"schema" yaml:"schema"`
+	SchemaPath   string      `json:"schema_path" yaml:"schema_path"`
+	AvroEncoding string      `json:"avro_encoding" yaml:"avro_encoding"`
+}
+
+func (c ContractConfig) dummy() dummyContractConfig {
+	dummy := dummyContractConfig{
+		Input:        c.Input,
+		Name:         c.Name,
+		SchemaType:   c.SchemaType,
+		Schema:       c.Schema,
+		SchemaPath:   c.SchemaPath,
+		AvroEncoding: c.AvroEncoding,
+	}
+	if c.Input == nil {
+		dummy.Input = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (c ContractConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (c ContractConfig) MarshalYAML() (interface{}, error) {
+	return c.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// contractRejectInputs is the set of input types that are able to reject
+// invalid messages back to their source.
+var contractRejectInputs = map[string]struct{}{
+	TypeAMQP:       {},
+	TypeHTTPServer: {},
+}
+
+type contractValidator func(part types.Part) error
+
+func newJSONSchemaValidator(schema string) (contractValidator, error) {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema: %v", err)
+	}
+	return func(part types.Part) error {
+		jObj, err := part.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to parse message as JSON: %v", err)
+		}
+		result, err := s.Validate(gojsonschema.NewGoLoader(jObj))
+		if err != nil {
+			return err
+		}
+		if !result.Valid() {
+			var errs []string
+			for _, desc := range result.Errors() {
+				errs = append(errs, desc.String())
+			}
+			return errors.New(strings.Join(errs, ", "))
+		}
+		return nil
+	}, nil
+}
+
+func newAvroValidator(schema, encoding string) (contractValidator, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Avro schema: %v", err)
+	}
+	var decode func([]byte) (interface{}, []byte, error)
+	switch encoding {
+	case "textual":
+		decode = codec.NativeFromTextual
+	case "binary":
+		decode = codec.NativeFromBinary
+	case "single":
+		decode = codec.NativeFromSingle
+	default:
+		return nil, fmt.Errorf("avro encoding '%v' not recognised", encoding)
+	}
+	return func(part types.Part) error {
+		_, remaining, err := decode(part.Get())
+		if err != nil {
+			return err
+		}
+		if len(strings.TrimSpace(string(remaining))) > 0 {
+			return errors.New("unexpected trailing data after Avro document")
+		}
+		return nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Contract is an input type that validates messages from a child input against
+// a schema, rejecting or dropping messages that are invalid.
+type Contract struct {
+	running int32
+
+	wrapped  Type
+	validate contractValidator
+	reject   bool
+
+	log log.Modular
+
+	mCount    metrics.StatCounter
+	mValid    metrics.StatCounter
+	mInvalid  metrics.StatCounter
+	mRejected metrics.StatCounter
+	mDropped  metrics.StatCounter
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewContract creates a new Contract input type.
+func NewContract(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.Contract.Input == nil {
+		return nil, errors.New("cannot create contract input without a child")
+	}
+
+	schema := conf.Contract.Schema
+	if len(conf.Contract.SchemaPath) > 0 {
+		if len(schema) > 0 {
+			return nil, errors.New("cannot set both schema and schema_path")
+		}
+		schemaBytes, err := ioutil.ReadFile(conf.Contract.SchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema: %v", err)
+		}
+		schema = string(schemaBytes)
+	}
+	if len(schema) == 0 {
+		return nil, errors.New("a schema must be provided")
+	}
+
+	var validate contractValidator
+	var err error
+	switch conf.Contract.SchemaType {
+	case "json_schema":
+		validate, err = newJSONSchemaValidator(schema)
+	case "avro":
+		validate, err = newAvroValidator(schema, conf.Contract.AvroEncoding)
+	default:
+		err = fmt.Errorf("schema type '%v' not recognised", conf.Contract.SchemaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := New(*conf.Contract.Input, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input '%v': %v", conf.Contract.Input.Type, err)
+	}
+
+	ns := "contract"
+	if len(conf.Contract.Name) > 0 {
+		ns = ns + "." + conf.Contract.Name
+	}
+	cStats := metrics.Namespaced(stats, ns)
+	_, reject := contractRejectInputs[conf.Contract.Input.Type]
+
+	c := &Contract{
+		running:  1,
+		wrapped:  wrapped,
+		validate: validate,
+		reject:   reject,
+
+		log: log.NewModule("." + ns),
+
+		mCount:    cStats.GetCounter("count"),
+		mValid:    cStats.GetCounter("valid"),
+		mInvalid:  cStats.GetCounter("invalid"),
+		mRejected: cStats.GetCounter("rejected"),
+		mDropped:  cStats.GetCounter("dropped"),
+
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+
+	go c.loop()
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Contract) loop() {
+	defer func() {
+		c.wrapped.CloseAsync()
+		err := c.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = c.wrapped.WaitForClose(time.Second) {
+		}
+		close(c.transactions)
+		close(c.closedChan)
+	}()
+
+	for atomic.LoadInt32(&c.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-c.wrapped.TransactionChan():
+			if !open {
+				return
+			}
+		case <-c.closeChan:
+			return
+		}
+
+		var firstErr error
+		valid := make([]bool, tran.Payload.Len())
+		tran.Payload.Iter(func(i int, p types.Part) error {
+			c.mCount.Incr(1)
+			if err := c.validate(p); err != nil {
+				c.mInvalid.Incr(1)
+				c.log.Warnf("Message part %v violates contract: %v\n", i, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+				return nil
+			}
+			c.mValid.Incr(1)
+			valid[i] = true
+			return nil
+		})
+
+		if firstErr == nil {
+			select {
+			case c.transactions <- tran:
+			case <-c.closeChan:
+				return
+			}
+			continue
+		}
+
+		if c.reject {
+			c.mRejected.Incr(int64(tran.Payload.Len()))
+			select {
+			case tran.ResponseChan <- response.NewError(types.ErrRejected{Err: firstErr}):
+			case <-c.closeChan:
+				return
+			}
+			continue
+		}
+
+		newMsg := message.New(nil)
+		tran.Payload.Iter(func(i int, p types.Part) error {
+			if valid[i] {
+				newMsg.Append(p)
+			} else {
+				c.mDropped.Incr(1)
+			}
+			return nil
+		})
+
+		if newMsg.Len() == 0 {
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-c.closeChan:
+				return
+			}
+			continue
+		}
+
+		select {
+		case c.transactions <- types.NewTransaction(newMsg, tran.ResponseChan):
+		case <-c.closeChan:
+			return
+		}
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (c *Contract) TransactionChan() <-chan types.Transaction {
+	return c.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (c *Contract) Connected() bool {
+	return c.wrapped.Connected()
+}
+
+// CloseAsync shuts down the Contract input and stops processing requests.
+func (c *Contract) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&c.running, 1, 0) {
+		close(c.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Contract input has closed down.
+func (c *Contract) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-c.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,259 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+var contractTestSchema = `{
+	"type": "object",
+	"properties": {
+		"id": { "type": "number" }
+	},
+	"required": [ "id" ]
+}`
+
+type mockContractInput struct {
+	ts        chan types.Transaction
+	closeOnce sync.Once
+}
+
+func (m *mockContractInput) TransactionChan() <-chan types.Transaction {
+	return m.ts
+}
+
+func (m *mockContractInput) Connected() bool {
+	return true
+}
+
+func (m *mockContractInput) CloseAsync() {
+	m.closeOnce.Do(func() {
+		close(m.ts)
+	})
+}
+
+func (m *mockContractInput) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestContractBadConfig(t *testing.T) {
+	inConf := NewConfig()
+	inConf.Type = TypeSTDIN
+
+	tests := map[string]func(c *ContractConfig){
+		"no child": func(c *ContractConfig) {
+			c.Input = nil
+		},
+		"no schema": func(c *ContractConfig) {
+			c.Schema = ""
+		},
+		"both schemas": func(c *ContractConfig) {
+			c.SchemaPath = "/does/not/exist.json"
+		},
+		"bad schema type": func(c *ContractConfig) {
+			c.SchemaType = "nope"
+		},
+		"bad json schema": func(c *ContractConfig) {
+			c.Schema = `{"type":`
+		},
+		"bad avro encoding": func(c *ContractConfig) {
+			c.SchemaType = "avro"
+			c.Schema = `"string"`
+			c.AvroEncoding = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeContract
+		conf.Contract.Input = &inConf
+		conf.Contract.Schema = contractTestSchema
+		fn(&conf.Contract)
+		if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
+
+func TestContractDropInvalid(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "benthos_contract_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err = tmpfile.Write([]byte(`{"id":1}
+{"id":"nope"}
+not json
+{"id":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = tmpfile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	inConf := NewConfig()
+	inConf.Type = TypeFile
+	inConf.File.Path = tmpfile.Name()
+
+	conf := NewConfig()
+	conf.Type = TypeContract
+	conf.Contract.Input = &inConf
+	conf.Contract.Schema = contractTestSchema
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{`{"id":1}`, `{"id":2}`} {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-in.TransactionChan():
+			if !open {
+				t.Fatal("transaction chan closed")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		if act := string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	in.CloseAsync()
+	if err = in.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestContractReject(t *testing.T) {
+	validate, err := newJSONSchemaValidator(contractTestSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockIn := &mockContractInput{ts: make(chan types.Transaction)}
+	stats := metrics.Noop()
+	c := &Contract{
+		running:  1,
+		wrapped:  mockIn,
+		validate: validate,
+		reject:   true,
+
+		log: log.Noop(),
+
+		mCount:    stats.GetCounter("count"),
+		mValid:    stats.GetCounter("valid"),
+		mInvalid:  stats.GetCounter("invalid"),
+		mRejected: stats.GetCounter("rejected"),
+		mDropped:  stats.GetCounter("dropped"),
+
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+	go c.loop()
+
+	resChan := make(chan types.Response)
+	select {
+	case mockIn.ts <- types.NewTransaction(message.New([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":"nope"}`),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if _, ok := res.Error().(types.ErrRejected); !ok {
+			t.Errorf("Expected rejection, received: %v", res.Error())
+		}
+	case <-c.TransactionChan():
+		t.Fatal("Invalid message was propagated")
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case mockIn.ts <- types.NewTransaction(message.New([][]byte{
+		[]byte(`{"id":1}`),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case tran := <-c.TransactionChan():
+		if exp, act := `{"id":1}`, string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	c.CloseAsync()
+	if err = c.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestContractAvro(t *testing.T) {
+	validate, err := newAvroValidator(`{
+	"type": "record",
+	"name": "order",
+	"fields": [ { "name": "id", "type": "long" } ]
+}`, "textual")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = validate(message.NewPart([]byte(`{"id":5}`))); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err = validate(message.NewPart([]byte(`{"id":"nope"}`))); err == nil {
+		t.Error("Expected error from invalid document")
+	}
+}
+
+//------------------------------------------------------------------------------
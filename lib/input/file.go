@@ -40,7 +40,24 @@ The file type reads input from a file. If multipart is set to false each line
 is read as a separate message. If multipart is set to true each line is read as
 a message part, and an empty line indicates the end of a message.
 
-If the delimiter field is left empty then line feed (\n) is used.`,
+If the delimiter field is left empty then line feed (\n) is used.
+
+The path can also be a glob pattern (e.g. ` + "`/data/*.log`" + `), or a
+directory, in which case each matched file is read in turn, sorted either by
+path (` + "`order: lexical`" + `) or by modification time
+(` + "`order: mtime`" + `).
+
+### Checkpointing
+
+When a [cache resource](../caches/README.md) is specified with the field
+` + "`cache`" + ` the byte offset of each file is stored within it as lines are
+acknowledged, and when restarted the input resumes from those offsets, skipping
+files that have already been consumed. The cache must persist across restarts
+for this to be useful, such as the ` + "`file`" + ` or ` + "`redis`" + ` caches.
+
+Files can be cleaned up once every line has been acknowledged by either setting
+` + "`delete_on_ack` to `true`" + `, or by setting ` + "`move_on_ack`" + ` to
+a directory that consumed files are moved to.`,
 	}
 }
 
@@ -48,19 +65,27 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 
 // FileConfig contains configuration values for the File input type.
 type FileConfig struct {
-	Path      string `json:"path" yaml:"path"`
-	Multipart bool   `json:"multipart" yaml:"multipart"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
-	Delim     string `json:"delimiter" yaml:"delimiter"`
+	Path        string `json:"path" yaml:"path"`
+	Multipart   bool   `json:"multipart" yaml:"multipart"`
+	MaxBuffer   int    `json:"max_buffer" yaml:"max_buffer"`
+	Delim       string `json:"delimiter" yaml:"delimiter"`
+	Order       string `json:"order" yaml:"order"`
+	Cache       string `json:"cache" yaml:"cache"`
+	DeleteOnAck bool   `json:"delete_on_ack" yaml:"delete_on_ack"`
+	MoveOnAck   string `json:"move_on_ack" yaml:"move_on_ack"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
 func NewFileConfig() FileConfig {
 	return FileConfig{
-		Path:      "",
-		Multipart: false,
-		MaxBuffer: 1000000,
-		Delim:     "",
+		Path:        "",
+		Multipart:   false,
+		MaxBuffer:   1000000,
+		Delim:       "",
+		Order:       "lexical",
+		Cache:       "",
+		DeleteOnAck: false,
+		MoveOnAck:   "",
 	}
 }
 
@@ -68,30 +93,81 @@ func NewFileConfig() FileConfig {
 
 // NewFile creates a new File input type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	file, err := os.Open(conf.File.Path)
+	paths, err := reader.GlobFiles(conf.File.Path, conf.File.Order)
 	if err != nil {
 		return nil, err
 	}
+	tracker, err := reader.NewFileTracker(
+		conf.File.Cache, conf.File.DeleteOnAck, conf.File.MoveOnAck, mgr, log,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file currently being read and the offset it was resumed from.
+	var current string
+	var baseOffset int64
+
+	openNext := func() (io.Reader, error) {
+		// By the time the next file is requested all lines of the current file
+		// have been acknowledged.
+		if len(current) > 0 {
+			if err := tracker.Finish(current); err != nil {
+				log.Errorf("Failed to clean up file: %v\n", err)
+			}
+			current = ""
+		}
+		for len(paths) > 0 {
+			path := paths[0]
+			paths = paths[1:]
+
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			offset := tracker.Offset(path)
+			if offset > 0 {
+				info, err := file.Stat()
+				if err != nil {
+					file.Close()
+					return nil, err
+				}
+				if offset >= info.Size() {
+					file.Close()
+					if err = tracker.Finish(path); err != nil {
+						log.Errorf("Failed to clean up file: %v\n", err)
+					}
+					continue
+				}
+				if _, err = file.Seek(offset, io.SeekStart); err != nil {
+					file.Close()
+					return nil, err
+				}
+				log.Infof("Resuming file '%v' from offset %v\n", path, offset)
+			}
+			current, baseOffset = path, offset
+			return file, nil
+		}
+		return nil, io.EOF
+	}
+
 	delim := conf.File.Delim
 	if len(delim) == 0 {
 		delim = "\n"
 	}
 
 	rdr, err := reader.NewLines(
-		func() (io.Reader, error) {
-			// Swap so this only works once since we don't want to read the file
-			// multiple times.
-			if file == nil {
-				return nil, io.EOF
-			}
-			sendFile := file
-			file = nil
-			return sendFile, nil
-		},
+		openNext,
 		func() {},
 		reader.OptLinesSetDelimiter(delim),
 		reader.OptLinesSetMaxBuffer(conf.File.MaxBuffer),
 		reader.OptLinesSetMultipart(conf.File.Multipart),
+		reader.OptLinesSetOnAcknowledge(func(offset int64) error {
+			if len(current) == 0 {
+				return nil
+			}
+			return tracker.Commit(current, baseOffset+offset)
+		}),
 	)
 	if err != nil {
 		return nil, err
@@ -23,10 +23,13 @@ package input
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/lib/cache"
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -180,3 +183,86 @@ func TestFileMultiPart(t *testing.T) {
 		t.Error("Timed out waiting for channel close")
 	}
 }
+
+func TestFileGlobCheckpoint(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for name, content := range map[string]string{
+		"a.log": "1\n2\n",
+		"b.log": "3\n4",
+		"c.txt": "nope\n",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["foo"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.File.Path = filepath.Join(tmpDir, "*.log")
+	conf.File.Cache = "foo"
+
+	readN := func(exp []string, closes bool) {
+		f, err := NewFile(conf, mgr, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			f.CloseAsync()
+			if err := f.WaitForClose(time.Second); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		for _, msg := range exp {
+			var ts types.Transaction
+			select {
+			case ts = <-f.TransactionChan():
+				if res := string(ts.Payload.Get(0).Get()); res != msg {
+					t.Errorf("Wrong result, %v != %v", res, msg)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for message")
+			}
+			select {
+			case ts.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for response")
+			}
+		}
+		if !closes {
+			return
+		}
+		select {
+		case _, open := <-f.TransactionChan():
+			if open {
+				t.Error("Channel not closed at end of messages")
+			}
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for channel close")
+		}
+	}
+
+	readN([]string{"1", "2", "3"}, false)
+	readN([]string{"4"}, true)
+
+	conf.File.DeleteOnAck = true
+	readN(nil, true)
+
+	if _, err = os.Stat(filepath.Join(tmpDir, "a.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be deleted: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "c.txt")); err != nil {
+		t.Errorf("Expected file to remain: %v", err)
+	}
+}
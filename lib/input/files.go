@@ -36,7 +36,23 @@ func init() {
 Reads files from a path, where each discrete file will be consumed as a single
 message payload. The path can either point to a single file (resulting in only a
 single message) or a directory, in which case the directory will be walked and
-each file found will become a message.
+each file found will become a message. The path can also be a glob pattern such
+as ` + "`/data/*.json`" + `.
+
+Files are consumed in the order specified by ` + "`order`" + `, which can be
+either ` + "`lexical`" + ` (sorted by path) or ` + "`mtime`" + ` (sorted by
+modification time, oldest first).
+
+### Checkpointing
+
+When a [cache resource](../caches/README.md) is specified with the field
+` + "`cache`" + ` each file is recorded within it once acknowledged, and when
+restarted the input skips files that have already been consumed, unless they
+have since grown in size.
+
+Files can be cleaned up once acknowledged by either setting
+` + "`delete_on_ack` to `true`" + `, or by setting ` + "`move_on_ack`" + ` to
+a directory that consumed files are moved to.
 
 ### Metadata
 
@@ -55,7 +71,7 @@ You can access these metadata fields using
 
 // NewFiles creates a new Files input type.
 func NewFiles(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	f, err := reader.NewFiles(conf.Files)
+	f, err := reader.NewFiles(conf.Files, mgr, log)
 	if err != nil {
 		return nil, err
 	}
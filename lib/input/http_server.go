@@ -22,6 +22,8 @@ package input
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"fmt"
 	"io"
@@ -30,6 +32,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -44,6 +47,7 @@ import (
 	httputil "github.com/Jeffail/benthos/lib/util/http"
 	"github.com/Jeffail/benthos/lib/util/throttle"
 	"github.com/gorilla/websocket"
+	"github.com/klauspost/compress/zstd"
 	"github.com/opentracing/opentracing-go"
 )
 
@@ -65,6 +69,14 @@ EXPERIMENTAL: It's possible to return a response for each message received using
 [synchronous responses](../sync_responses.md). This feature is considered
 experimental and therefore subject to change outside of major version releases.
 
+Synchronous responses are compressed with either zstd or gzip when the request
+includes an ` + "`Accept-Encoding`" + ` header that allows it.
+
+### Compression
+
+Request bodies with a ` + "`Content-Encoding`" + ` header of ` + "`gzip`" + `,
+` + "`deflate` or `zstd`" + ` are decompressed before being consumed.
+
 ### Endpoints
 
 The following fields specify endpoints that are registered for sending messages:
@@ -207,11 +219,10 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		mAsyncSucc:  stats.GetCounter("send.async_success"),
 	}
 
-	postHdlr := httputil.GzipHandler(h.postHandler)
 	wsHdlr := httputil.GzipHandler(h.wsHandler)
 	if mux != nil {
 		if len(h.conf.HTTPServer.Path) > 0 {
-			mux.HandleFunc(h.conf.HTTPServer.Path, postHdlr)
+			mux.HandleFunc(h.conf.HTTPServer.Path, h.postHandler)
 		}
 		if len(h.conf.HTTPServer.WSPath) > 0 {
 			mux.HandleFunc(h.conf.HTTPServer.WSPath, wsHdlr)
@@ -219,7 +230,7 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 	} else {
 		if len(h.conf.HTTPServer.Path) > 0 {
 			mgr.RegisterEndpoint(
-				h.conf.HTTPServer.Path, "Post a message into Benthos.", postHdlr,
+				h.conf.HTTPServer.Path, "Post a message into Benthos.", h.postHandler,
 			)
 		}
 		if len(h.conf.HTTPServer.WSPath) > 0 {
@@ -235,6 +246,74 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 
 //------------------------------------------------------------------------------
 
+// responseEncoding returns the content coding that a response should be
+// compressed with according to the Accept-Encoding header of a request, or an
+// empty string if neither zstd or gzip are accepted. When both are accepted the
+// one with the highest quality value is chosen, preferring zstd for ties.
+func responseEncoding(r *http.Request) string {
+	qualities := map[string]float64{}
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(enc, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name != "zstd" && name != "gzip" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qualities[name] = q
+	}
+
+	var chosen string
+	var chosenQ float64
+	for _, name := range []string{"zstd", "gzip"} {
+		if q, exists := qualities[name]; exists && q > chosenQ {
+			chosen, chosenQ = name, q
+		}
+	}
+	return chosen
+}
+
+// zstdBody is a zstd encoded request body that releases its decoder when
+// closed.
+type zstdBody struct {
+	*zstd.Decoder
+}
+
+// Close releases the resources of the decoder.
+func (z zstdBody) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// requestBody returns a reader of the body of a request, decompressing it
+// according to its Content-Encoding header. The reader must be closed once
+// consumed.
+func requestBody(r *http.Request) (io.ReadCloser, error) {
+	switch enc := strings.ToLower(r.Header.Get("Content-Encoding")); enc {
+	case "", "identity":
+		return ioutil.NopCloser(r.Body), nil
+	case "gzip":
+		return gzip.NewReader(r.Body)
+	case "deflate":
+		return zlib.NewReader(r.Body)
+	case "zstd":
+		d, err := zstd.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return zstdBody{d}, nil
+	default:
+		return nil, fmt.Errorf("content encoding not supported: %v", enc)
+	}
+}
+
 func extractMessageFromRequest(r *http.Request) (types.Message, error) {
 	msg := message.New(nil)
 
@@ -243,8 +322,14 @@ func extractMessageFromRequest(r *http.Request) (types.Message, error) {
 		return nil, err
 	}
 
+	body, err := requestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
 	if strings.HasPrefix(mediaType, "multipart/") {
-		mr := multipart.NewReader(r.Body, params["boundary"])
+		mr := multipart.NewReader(body, params["boundary"])
 		for {
 			var p *multipart.Part
 			if p, err = mr.NextPart(); err != nil {
@@ -262,7 +347,7 @@ func extractMessageFromRequest(r *http.Request) (types.Message, error) {
 		}
 	} else {
 		var msgBytes []byte
-		if msgBytes, err = ioutil.ReadAll(r.Body); err != nil {
+		if msgBytes, err = ioutil.ReadAll(body); err != nil {
 			return nil, err
 		}
 		msg.Append(message.NewPart(msgBytes))
@@ -347,6 +432,10 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		} else if res.Error() != nil {
 			h.mErr.Incr(1)
+			if _, rejected := res.Error().(types.ErrRejected); rejected {
+				http.Error(w, res.Error().Error(), http.StatusBadRequest)
+				return
+			}
 			http.Error(w, res.Error().Error(), http.StatusBadGateway)
 			return
 		}
@@ -375,12 +464,34 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 			return nil
 		})
 	}
+	var out io.Writer = w
+	if len(parts) > 0 {
+		var zw io.WriteCloser
+		enc := responseEncoding(r)
+		switch enc {
+		case "zstd":
+			var zerr error
+			if zw, zerr = zstd.NewWriter(w); zerr != nil {
+				h.log.Errorf("Failed to create zstd encoder: %v\n", zerr)
+				zw = nil
+			}
+		case "gzip":
+			zw = gzip.NewWriter(w)
+		}
+		if zw != nil {
+			w.Header().Set("Content-Encoding", enc)
+			w.Header().Add("Vary", "Accept-Encoding")
+			defer zw.Close()
+			out = zw
+		}
+	}
+
 	if plen := len(parts); plen == 1 {
 		payload := parts[0].Get()
 		w.Header().Set("Content-Type", http.DetectContentType(payload))
-		w.Write(payload)
+		out.Write(payload)
 	} else if plen > 1 {
-		writer := multipart.NewWriter(w)
+		writer := multipart.NewWriter(out)
 		var merr error
 		for i := 0; i < plen && merr == nil; i++ {
 			payload := parts[i].Get()
@@ -461,7 +572,12 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 			if res.Error() != nil {
 				h.mWSErr.Incr(1)
 				h.mErr.Incr(1)
-				throt.Retry()
+				if _, rejected := res.Error().(types.ErrRejected); rejected {
+					// Rejected messages must not be retried.
+					msgBytes = nil
+				} else {
+					throt.Retry()
+				}
 			} else {
 				h.mWSSucc.Incr(1)
 				h.mSucc.Incr(1)
@@ -22,6 +22,7 @@ package input
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -33,6 +34,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/klauspost/compress/zstd"
 )
 
 func TestHTTPBasic(t *testing.T) {
@@ -210,3 +212,184 @@ func TestHTTPTimeout(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHTTPCompression(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1234"
+	conf.HTTPServer.Path = "/testpost"
+
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-time.After(time.Millisecond * 1000)
+
+	var reqBody bytes.Buffer
+	zw := gzip.NewWriter(&reqBody)
+	zw.Write([]byte("hello world"))
+	zw.Close()
+
+	go func() {
+		var ts types.Transaction
+		select {
+		case ts = <-h.TransactionChan():
+			if exp, act := "hello world", string(ts.Payload.Get(0).Get()); exp != act {
+				t.Errorf("Wrong result, %v != %v", act, exp)
+			}
+			ts.Payload.Get(0).Set([]byte("hello response"))
+			roundtrip.SetAsResponse(ts.Payload)
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for message")
+			return
+		}
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for response")
+		}
+	}()
+
+	req, err := http.NewRequest("POST", "http://localhost:1234/testpost", &reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if exp, act := http.StatusOK, res.StatusCode; exp != act {
+		t.Fatalf("Unexpected status code: %v != %v", act, exp)
+	}
+	if exp, act := "gzip", res.Header.Get("Content-Encoding"); exp != act {
+		t.Errorf("Wrong content encoding: %v != %v", act, exp)
+	}
+
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resBytes, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello response", string(resBytes); exp != act {
+		t.Errorf("Wrong sync response: %v != %v", act, exp)
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPCompressionZstd(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1235"
+	conf.HTTPServer.Path = "/testpost"
+
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-time.After(time.Millisecond * 1000)
+
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqBody := zw.EncodeAll([]byte("hello world"), nil)
+
+	go func() {
+		var ts types.Transaction
+		select {
+		case ts = <-h.TransactionChan():
+			if exp, act := "hello world", string(ts.Payload.Get(0).Get()); exp != act {
+				t.Errorf("Wrong result, %v != %v", act, exp)
+			}
+			ts.Payload.Get(0).Set([]byte("hello response"))
+			roundtrip.SetAsResponse(ts.Payload)
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for message")
+			return
+		}
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Error("Timed out waiting for response")
+		}
+	}()
+
+	req, err := http.NewRequest("POST", "http://localhost:1235/testpost", bytes.NewReader(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "zstd")
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if exp, act := http.StatusOK, res.StatusCode; exp != act {
+		t.Fatalf("Unexpected status code: %v != %v", act, exp)
+	}
+	if exp, act := "zstd", res.Header.Get("Content-Encoding"); exp != act {
+		t.Errorf("Wrong content encoding: %v != %v", act, exp)
+	}
+
+	zr, err := zstd.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	resBytes, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello response", string(resBytes); exp != act {
+		t.Errorf("Wrong sync response: %v != %v", act, exp)
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPResponseEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"deflate":                "",
+		"gzip":                   "gzip",
+		"deflate, gzip;q=0.8":    "gzip",
+		"gzip, zstd":             "zstd",
+		"gzip;q=1.0, zstd;q=0.5": "gzip",
+		"zstd;q=0, gzip;q=0.1":   "gzip",
+		"zstd;q=0, gzip;q=0":     "",
+		"ZSTD":                   "zstd",
+	}
+
+	for header, exp := range tests {
+		req, err := http.NewRequest("POST", "http://localhost/testpost", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Encoding", header)
+		if act := responseEncoding(req); act != exp {
+			t.Errorf("Wrong encoding for '%v': %v != %v", header, act, exp)
+		}
+	}
+}
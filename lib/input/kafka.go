@@ -43,6 +43,27 @@ The field ` + "`max_batch_count`" + ` specifies the maximum number of prefetched
 messages to be batched together. When more than one message is batched they can
 be split into individual messages with the ` + "`split`" + ` processor.
 
+The field ` + "`max_in_flight`" + ` sets the maximum number of batches that can
+be processed at the same time, which is useful when there are multiple
+processing pipeline threads. Offsets are only committed once all prior batches
+have been delivered, and failed batches are resent until they succeed. The gauge
+` + "`in_flight`" + ` reports the number of batches currently being processed.
+Batches that are held without acknowledgement by processors such as
+` + "`batch`" + ` block offset commits from then on, so this field must be left at 1
+when using them.
+
+Prefetching is controlled with ` + "`fetch_buffer_cap`" + `, the number of
+messages buffered per partition, and ` + "`fetch_bytes`" + `, the number of bytes
+requested per partition in each fetch. The field ` + "`fetch_max_bytes`" + `
+caps the size of a single fetch response, where zero means no limit. Lower
+values reduce memory usage at the cost of throughput.
+
+The gauge ` + "`prefetch.buffered`" + ` reports the number of messages waiting
+to be read after each batch, and ` + "`batch.utilisation`" + ` reports the
+size of each batch as a percentage of ` + "`max_batch_count`" + `. A buffer that
+is consistently full suggests the pipeline is the bottleneck, whereas low batch
+utilisation with an empty buffer suggests that prefetching should be increased.
+
 The field ` + "`max_processing_period`" + ` should be set above the maximum
 estimated time taken to process a message.
 
@@ -84,7 +105,17 @@ func NewKafka(conf Config, mgr types.Manager, log log.Modular, stats metrics.Typ
 	if err != nil {
 		return nil, err
 	}
-	return NewReader("kafka", reader.NewPreserver(k), log, stats)
+	var r Type
+	if conf.Kafka.MaxInFlight > 1 {
+		r, err = NewAsyncReader("kafka", conf.Kafka.MaxInFlight, k, log, stats)
+	} else {
+		r, err = NewReader("kafka", reader.NewPreserver(k), log, stats)
+	}
+	if err != nil {
+		return nil, err
+	}
+	registerPausable("kafka", r, mgr, stats)
+	return r, nil
 }
 
 //------------------------------------------------------------------------------
@@ -0,0 +1,159 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Pausable is an optional interface implemented by inputs that are able to
+// temporarily halt the consumption of data without closing their connections.
+type Pausable interface {
+	// Pause stops the input from consuming new data until Resume is called.
+	Pause()
+
+	// Resume continues consuming data after a call to Pause.
+	Resume()
+
+	// Paused returns true if the input is currently paused.
+	Paused() bool
+}
+
+//------------------------------------------------------------------------------
+
+// pauseGroup is the set of pausable inputs at a config path that share a
+// manager, and therefore also share pause and resume endpoints. Inputs only
+// share a path when they are copies of the same config.
+type pauseGroup struct {
+	mut    sync.Mutex
+	inputs map[Pausable]struct{}
+}
+
+type pauseGroupKey struct {
+	mgr  types.Manager
+	path string
+}
+
+var (
+	pauseGroupsMut sync.Mutex
+	pauseGroups    = map[pauseGroupKey]*pauseGroup{}
+)
+
+func (g *pauseGroup) handler(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		g.mut.Lock()
+		paused := len(g.inputs) > 0
+		for p := range g.inputs {
+			if r.Method == "POST" {
+				if pause {
+					p.Pause()
+				} else {
+					p.Resume()
+				}
+			}
+			paused = paused && p.Paused()
+		}
+		g.mut.Unlock()
+
+		resBytes, err := json.Marshal(map[string]bool{
+			"paused": paused,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+}
+
+// registerPausable adds an input to the pause group of its path and manager,
+// registering the endpoints of the group, and removes it from the group once
+// the input is closed. The path of an input is the namespace of its metrics,
+// e.g. input.broker.inputs.0, and inputs without a namespace fall back to a
+// path of their type.
+func registerPausable(typeStr string, input Type, mgr types.Manager, stats metrics.Type) {
+	p, ok := input.(Pausable)
+	if !ok || mgr == nil {
+		return
+	}
+
+	confPath := metrics.Namespace(stats)
+	if len(confPath) == 0 {
+		confPath = "input." + typeStr
+	}
+	key := pauseGroupKey{mgr: mgr, path: confPath}
+
+	pauseGroupsMut.Lock()
+	g, exists := pauseGroups[key]
+	if !exists {
+		g = &pauseGroup{inputs: map[Pausable]struct{}{}}
+		pauseGroups[key] = g
+	}
+	g.mut.Lock()
+	g.inputs[p] = struct{}{}
+	g.mut.Unlock()
+	pauseGroupsMut.Unlock()
+
+	endpoint := "/" + strings.Replace(confPath, ".", "/", -1)
+	mgr.RegisterEndpoint(
+		path.Join(endpoint, "pause"),
+		"POST to pause the consumption of the "+typeStr+" input at "+confPath+
+			", or GET to check whether it is paused.",
+		g.handler(true),
+	)
+	mgr.RegisterEndpoint(
+		path.Join(endpoint, "resume"),
+		"POST to resume the consumption of the "+typeStr+" input at "+confPath+
+			", or GET to check whether it is paused.",
+		g.handler(false),
+	)
+
+	go func() {
+		for input.WaitForClose(time.Second) != nil {
+		}
+		pauseGroupsMut.Lock()
+		g.mut.Lock()
+		delete(g.inputs, p)
+		if len(g.inputs) == 0 {
+			delete(pauseGroups, key)
+		}
+		g.mut.Unlock()
+		pauseGroupsMut.Unlock()
+	}()
+}
+
+//------------------------------------------------------------------------------
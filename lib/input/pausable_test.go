@@ -0,0 +1,273 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type pauseTestMgr struct {
+	types.DudMgr
+	handlers map[string]http.HandlerFunc
+}
+
+func (m *pauseTestMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	m.handlers[path] = h
+}
+
+//------------------------------------------------------------------------------
+
+func TestReaderPause(t *testing.T) {
+	t.Parallel()
+
+	readerImpl := newMockReader()
+
+	r, err := NewReader(
+		"foo", readerImpl,
+		log.Noop(), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		r.CloseAsync()
+		if err = r.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	select {
+	case readerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case readerImpl.readChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var ts types.Transaction
+	select {
+	case ts = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	// Pausing with a message in flight should still allow it to be
+	// acknowledged.
+	pausable := r.(Pausable)
+	pausable.Pause()
+	if !pausable.Paused() {
+		t.Error("Expected reader to be paused")
+	}
+
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case readerImpl.ackChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case readerImpl.readChan <- nil:
+		t.Fatal("Read while paused")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	pausable.Resume()
+	if pausable.Paused() {
+		t.Error("Expected reader to be resumed")
+	}
+
+	select {
+	case readerImpl.readChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case ts = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case readerImpl.ackChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+}
+
+func TestReaderPauseClose(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewReader(
+		"foo", readerCantConnect{},
+		log.Noop(), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.(Pausable).Pause()
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPausableEndpoints(t *testing.T) {
+	t.Parallel()
+
+	mgr := &pauseTestMgr{handlers: map[string]http.HandlerFunc{}}
+
+	var inputs []Type
+	for i := 0; i < 2; i++ {
+		r, err := NewReader(
+			"foo", readerCantConnect{},
+			log.Noop(), metrics.DudType{},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		registerPausable("foo", r, mgr, metrics.DudType{})
+		inputs = append(inputs, r)
+	}
+
+	call := func(method, path string) string {
+		t.Helper()
+		h, exists := mgr.handlers[path]
+		if !exists {
+			t.Fatalf("Endpoint %v not registered", path)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(method, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Wrong status code from %v %v: %v", method, path, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if exp, act := `{"paused":false}`, call("GET", "/input/foo/pause"); exp != act {
+		t.Errorf("Wrong response: %v != %v", act, exp)
+	}
+	if exp, act := `{"paused":true}`, call("POST", "/input/foo/pause"); exp != act {
+		t.Errorf("Wrong response: %v != %v", act, exp)
+	}
+	for i, in := range inputs {
+		if !in.(Pausable).Paused() {
+			t.Errorf("Input %v not paused", i)
+		}
+	}
+	if exp, act := `{"paused":true}`, call("GET", "/input/foo/resume"); exp != act {
+		t.Errorf("Wrong response: %v != %v", act, exp)
+	}
+	if exp, act := `{"paused":false}`, call("POST", "/input/foo/resume"); exp != act {
+		t.Errorf("Wrong response: %v != %v", act, exp)
+	}
+	for i, in := range inputs {
+		if in.(Pausable).Paused() {
+			t.Errorf("Input %v still paused", i)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mgr.handlers["/input/foo/pause"](rec, httptest.NewRequest("DELETE", "/input/foo/pause", nil))
+	if exp, act := http.StatusMethodNotAllowed, rec.Code; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+
+	for _, in := range inputs {
+		in.CloseAsync()
+		if err := in.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestPausableEndpointsByPath(t *testing.T) {
+	t.Parallel()
+
+	mgr := &pauseTestMgr{handlers: map[string]http.HandlerFunc{}}
+	stats := metrics.Namespaced(metrics.DudType{}, "input")
+
+	var inputs []Type
+	for i := 0; i < 2; i++ {
+		r, err := NewReader(
+			"foo", readerCantConnect{},
+			log.Noop(), metrics.DudType{},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ns := fmt.Sprintf("broker.inputs.%v", i)
+		registerPausable("foo", r, mgr, metrics.Combine(stats, metrics.Namespaced(stats, ns)))
+		inputs = append(inputs, r)
+	}
+
+	h, exists := mgr.handlers["/input/broker/inputs/1/pause"]
+	if !exists {
+		t.Fatal("Endpoint not registered")
+	}
+	if _, exists = mgr.handlers["/input/broker/inputs/0/pause"]; !exists {
+		t.Fatal("Endpoint not registered")
+	}
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("POST", "/input/broker/inputs/1/pause", nil))
+	if exp, act := `{"paused":true}`, rec.Body.String(); exp != act {
+		t.Errorf("Wrong response: %v != %v", act, exp)
+	}
+	if inputs[0].(Pausable).Paused() {
+		t.Error("Input 0 paused")
+	}
+	if !inputs[1].(Pausable).Paused() {
+		t.Error("Input 1 not paused")
+	}
+
+	for _, in := range inputs {
+		in.CloseAsync()
+		if err := in.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,6 +21,7 @@
 package input
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -47,6 +48,10 @@ type Reader struct {
 
 	connThrot *throttle.Type
 
+	pauseMut   sync.Mutex
+	resumeChan chan struct{}
+	mPaused    metrics.StatGauge
+
 	transactions chan types.Transaction
 	responses    chan types.Response
 
@@ -71,6 +76,7 @@ func NewReader(
 		responses:    make(chan types.Response),
 		closeChan:    make(chan struct{}),
 		closedChan:   make(chan struct{}),
+		mPaused:      stats.GetGauge("paused"),
 	}
 
 	rdr.connThrot = throttle.New(throttle.OptCloseChan(rdr.closeChan))
@@ -132,6 +138,14 @@ func (r *Reader) loop() {
 	atomic.StoreInt32(&r.connected, 1)
 
 	for atomic.LoadInt32(&r.running) == 1 {
+		if resumeChan := r.pausedChan(); resumeChan != nil {
+			select {
+			case <-resumeChan:
+			case <-r.closeChan:
+				return
+			}
+		}
+
 		msg, err := r.reader.Read()
 
 		// If our reader says it is not connected.
@@ -240,6 +254,48 @@ func (r *Reader) Connected() bool {
 	return atomic.LoadInt32(&r.connected) == 1
 }
 
+// pausedChan returns a channel that is closed once the input is resumed, or nil
+// if the input is not paused.
+func (r *Reader) pausedChan() <-chan struct{} {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	if r.resumeChan == nil {
+		return nil
+	}
+	return r.resumeChan
+}
+
+// Pause stops the Reader from reading new messages until Resume is called.
+// Messages that are already in flight are still delivered and acknowledged.
+func (r *Reader) Pause() {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	if r.resumeChan == nil {
+		r.resumeChan = make(chan struct{})
+		r.mPaused.Set(1)
+		r.log.Infof("Pausing %v input\n", r.typeStr)
+	}
+}
+
+// Resume continues reading messages after a call to Pause.
+func (r *Reader) Resume() {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	if r.resumeChan != nil {
+		close(r.resumeChan)
+		r.resumeChan = nil
+		r.mPaused.Set(0)
+		r.log.Infof("Resuming %v input\n", r.typeStr)
+	}
+}
+
+// Paused returns true if the Reader is currently paused.
+func (r *Reader) Paused() bool {
+	r.pauseMut.Lock()
+	defer r.pauseMut.Unlock()
+	return r.resumeChan != nil
+}
+
 // CloseAsync shuts down the Reader input and stops processing requests.
 func (r *Reader) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
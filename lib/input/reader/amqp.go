@@ -22,6 +22,7 @@ package reader
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -91,8 +92,13 @@ type AMQP struct {
 	amqpChan     *amqp.Channel
 	consumerChan <-chan amqp.Delivery
 
-	ackTag  uint64
-	tlsConf *tls.Config
+	ackTag    uint64
+	batchTags []uint64
+	unacked   int
+	tlsConf   *tls.Config
+
+	mUnacked      metrics.StatGauge
+	mBatchUtilPct metrics.StatGauge
 
 	conf  AMQPConfig
 	stats metrics.Type
@@ -104,9 +110,20 @@ type AMQP struct {
 // NewAMQP creates a new AMQP input type.
 func NewAMQP(conf AMQPConfig, log log.Modular, stats metrics.Type) (Type, error) {
 	a := AMQP{
-		conf:  conf,
-		stats: stats,
-		log:   log,
+		conf:          conf,
+		stats:         stats,
+		log:           log,
+		mUnacked:      stats.GetGauge("prefetch.unacked"),
+		mBatchUtilPct: stats.GetGauge("batch.utilisation"),
+	}
+	if conf.MaxBatchCount < 1 {
+		return nil, errors.New("max_batch_count must be greater than or equal to 1")
+	}
+	if conf.PrefetchCount > 0 && conf.PrefetchCount < conf.MaxBatchCount {
+		log.Warnf(
+			"Prefetch count (%v) is lower than max batch count (%v), batches will never exceed the prefetch count\n",
+			conf.PrefetchCount, conf.MaxBatchCount,
+		)
 	}
 	if conf.TLS.Enabled {
 		var err error
@@ -195,6 +212,8 @@ func (a *AMQP) Connect() (err error) {
 	a.conn = conn
 	a.amqpChan = amqpChan
 	a.consumerChan = consumerChan
+	a.batchTags = nil
+	a.unacked = 0
 
 	a.log.Infof("Receiving AMQP messages from queue: %v\n", a.conf.Queue)
 	return
@@ -287,10 +306,14 @@ func (a *AMQP) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
+	a.batchTags = a.batchTags[:0]
+
 	msg := message.New(nil)
 	addPart := func(data amqp.Delivery) {
 		// Only store the latest delivery tag, but always Ack multiple.
 		a.ackTag = data.DeliveryTag
+		a.batchTags = append(a.batchTags, data.DeliveryTag)
+		a.unacked++
 
 		part := message.NewPart(data.Body)
 
@@ -351,6 +374,9 @@ batchLoop:
 	if msg.Len() == 0 {
 		return nil, types.ErrTimeout
 	}
+
+	a.mUnacked.Set(int64(a.unacked))
+	a.mBatchUtilPct.Set(int64(msg.Len() * 100 / a.conf.MaxBatchCount))
 	return msg, nil
 }
 
@@ -363,9 +389,25 @@ func (a *AMQP) Acknowledge(err error) error {
 		return types.ErrNotConnected
 	}
 	if err != nil {
-		return a.amqpChan.Reject(a.ackTag, true)
+		// Each delivery of the batch is rejected individually so that earlier
+		// deliveries are unaffected. Rejected messages are not requeued, which
+		// allows them to be routed to a dead letter exchange.
+		_, rejected := err.(types.ErrRejected)
+		for _, tag := range a.batchTags {
+			if err = a.amqpChan.Reject(tag, !rejected); err != nil {
+				return err
+			}
+			a.unacked--
+		}
+		a.batchTags = a.batchTags[:0]
+		a.mUnacked.Set(int64(a.unacked))
+		return nil
+	}
+	if err = a.amqpChan.Ack(a.ackTag, true); err == nil {
+		a.unacked = 0
+		a.mUnacked.Set(0)
 	}
-	return a.amqpChan.Ack(a.ackTag, true)
+	return err
 }
 
 // CloseAsync shuts down the AMQP input and stops processing requests.
@@ -0,0 +1,205 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// GlobFiles resolves a path, which can be a glob pattern, into a list of files
+// sorted according to an order, which is either "lexical" or "mtime". Any
+// directories matched by the path are walked and their files included.
+func GlobFiles(pattern, order string) ([]string, error) {
+	var lessFn func(a, b fileTarget) bool
+	switch order {
+	case "lexical", "":
+		lessFn = func(a, b fileTarget) bool {
+			return a.path < b.path
+		}
+	case "mtime":
+		lessFn = func(a, b fileTarget) bool {
+			if a.modTime == b.modTime {
+				return a.path < b.path
+			}
+			return a.modTime < b.modTime
+		}
+	default:
+		return nil, fmt.Errorf("file order not recognised: %v", order)
+	}
+
+	matches := []string{pattern}
+	if strings.ContainsAny(pattern, "*?[") {
+		var err error
+		if matches, err = filepath.Glob(pattern); err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files matched the pattern: %v", pattern)
+		}
+	}
+
+	seen := map[string]struct{}{}
+	var targets []fileTarget
+	for _, match := range matches {
+		if err := filepath.Walk(match, func(path string, info os.FileInfo, werr error) error {
+			if werr != nil {
+				return werr
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if _, exists := seen[path]; !exists {
+				seen[path] = struct{}{}
+				targets = append(targets, fileTarget{
+					path:    path,
+					modTime: info.ModTime().UnixNano(),
+				})
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return lessFn(targets[i], targets[j])
+	})
+
+	paths := make([]string, len(targets))
+	for i, t := range targets {
+		paths[i] = t.path
+	}
+	return paths, nil
+}
+
+type fileTarget struct {
+	path    string
+	modTime int64
+}
+
+//------------------------------------------------------------------------------
+
+// FileTracker persists the read progress of files as byte offsets within a
+// cache, allowing readers to resume from where they left off after a restart,
+// and also performs clean up actions on files once they have been consumed.
+type FileTracker struct {
+	cache       types.Cache
+	deleteOnAck bool
+	moveOnAck   string
+	log         log.Modular
+}
+
+// NewFileTracker creates a new FileTracker. The cache name can be empty, in
+// which case progress is not persisted.
+func NewFileTracker(
+	cacheName string,
+	deleteOnAck bool,
+	moveOnAck string,
+	mgr types.Manager,
+	log log.Modular,
+) (*FileTracker, error) {
+	if deleteOnAck && len(moveOnAck) > 0 {
+		return nil, errors.New("cannot both delete and move files on acknowledgement")
+	}
+	t := &FileTracker{
+		deleteOnAck: deleteOnAck,
+		moveOnAck:   moveOnAck,
+		log:         log,
+	}
+	if len(cacheName) > 0 {
+		var err error
+		if t.cache, err = mgr.GetCache(cacheName); err != nil {
+			return nil, fmt.Errorf("failed to obtain cache '%v': %v", cacheName, err)
+		}
+	}
+	return t, nil
+}
+
+func (t *FileTracker) key(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// Offset returns the last committed byte offset of a file, or zero if no
+// progress has been committed.
+func (t *FileTracker) Offset(path string) int64 {
+	if t.cache == nil {
+		return 0
+	}
+	v, err := t.cache.Get(t.key(path))
+	if err != nil {
+		return 0
+	}
+	offset, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		t.log.Warnf("Ignoring invalid offset '%s' of file '%v'\n", v, path)
+		return 0
+	}
+	return offset
+}
+
+// Commit stores the byte offset that a file has been consumed up to.
+func (t *FileTracker) Commit(path string, offset int64) error {
+	if t.cache == nil {
+		return nil
+	}
+	return t.cache.Set(t.key(path), []byte(strconv.FormatInt(offset, 10)))
+}
+
+// Finish performs any configured clean up actions on a file that has been
+// fully consumed and acknowledged. When a file is deleted or moved its progress
+// is also removed from the cache.
+func (t *FileTracker) Finish(path string) error {
+	if !t.deleteOnAck && len(t.moveOnAck) == 0 {
+		return nil
+	}
+	if t.deleteOnAck {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file '%v': %v", path, err)
+		}
+	} else {
+		if err := os.MkdirAll(t.moveOnAck, 0755); err != nil {
+			return fmt.Errorf("failed to create directory '%v': %v", t.moveOnAck, err)
+		}
+		if err := os.Rename(path, filepath.Join(t.moveOnAck, filepath.Base(path))); err != nil {
+			return fmt.Errorf("failed to move file '%v': %v", path, err)
+		}
+	}
+	if t.cache != nil {
+		return t.cache.Delete(t.key(path))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -24,9 +24,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 	"time"
 
+	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/types"
 )
@@ -35,46 +35,53 @@ import (
 
 // FilesConfig contains configuration for the Files input type.
 type FilesConfig struct {
-	Path string `json:"path" yaml:"path"`
+	Path        string `json:"path" yaml:"path"`
+	Order       string `json:"order" yaml:"order"`
+	Cache       string `json:"cache" yaml:"cache"`
+	DeleteOnAck bool   `json:"delete_on_ack" yaml:"delete_on_ack"`
+	MoveOnAck   string `json:"move_on_ack" yaml:"move_on_ack"`
 }
 
 // NewFilesConfig creates a new FilesConfig with default values.
 func NewFilesConfig() FilesConfig {
 	return FilesConfig{
-		Path: "",
+		Path:        "",
+		Order:       "lexical",
+		Cache:       "",
+		DeleteOnAck: false,
+		MoveOnAck:   "",
 	}
 }
 
 //------------------------------------------------------------------------------
 
+type readFile struct {
+	path string
+	size int64
+}
+
 // Files is an input type that reads file contents at a path as messages.
 type Files struct {
 	targets []string
+	unacked []readFile
+	tracker *FileTracker
+	log     log.Modular
 }
 
 // NewFiles creates a new Files input type.
-func NewFiles(conf FilesConfig) (Type, error) {
-	f := Files{}
-
-	if info, err := os.Stat(conf.Path); err != nil {
+func NewFiles(conf FilesConfig, mgr types.Manager, log log.Modular) (Type, error) {
+	tracker, err := NewFileTracker(conf.Cache, conf.DeleteOnAck, conf.MoveOnAck, mgr, log)
+	if err != nil {
 		return nil, err
-	} else if !info.IsDir() {
-		f.targets = append(f.targets, conf.Path)
-		return &f, nil
 	}
-
-	err := filepath.Walk(conf.Path, func(path string, info os.FileInfo, werr error) error {
-		if werr != nil {
-			return werr
-		}
-		if info.IsDir() {
-			return nil
-		}
-		f.targets = append(f.targets, path)
-		return nil
-	})
-
-	return &f, err
+	f := Files{
+		tracker: tracker,
+		log:     log,
+	}
+	if f.targets, err = GlobFiles(conf.Path, conf.Order); err != nil {
+		return nil, err
+	}
+	return &f, nil
 }
 
 //------------------------------------------------------------------------------
@@ -88,12 +95,25 @@ func (f *Files) Connect() (err error) {
 
 // Read a new Files message.
 func (f *Files) Read() (types.Message, error) {
-	if len(f.targets) == 0 {
-		return nil, types.ErrTypeClosed
-	}
+	var path string
+	var info os.FileInfo
+	for {
+		if len(f.targets) == 0 {
+			return nil, types.ErrTypeClosed
+		}
+		path = f.targets[0]
+		f.targets = f.targets[1:]
 
-	path := f.targets[0]
-	f.targets = f.targets[1:]
+		var err error
+		if info, err = os.Stat(path); err != nil {
+			return nil, fmt.Errorf("failed to read file '%v': %v", path, err)
+		}
+		if offset := f.tracker.Offset(path); offset > 0 && offset >= info.Size() {
+			f.log.Debugf("Skipping previously consumed file: %v\n", path)
+			continue
+		}
+		break
+	}
 
 	file, openerr := os.Open(path)
 	if openerr != nil {
@@ -105,6 +125,10 @@ func (f *Files) Read() (types.Message, error) {
 	if readerr != nil {
 		return nil, readerr
 	}
+	f.unacked = append(f.unacked, readFile{
+		path: path,
+		size: int64(len(msgBytes)),
+	})
 
 	msg := message.New([][]byte{msgBytes})
 	msg.Get(0).Metadata().Set("path", path)
@@ -112,8 +136,28 @@ func (f *Files) Read() (types.Message, error) {
 }
 
 // Acknowledge instructs whether unacknowledged messages have been successfully
-// propagated.
+// propagated. Files that failed to propagate are queued to be read again and
+// are therefore never committed, deleted or moved.
 func (f *Files) Acknowledge(err error) error {
+	if err != nil {
+		paths := make([]string, 0, len(f.unacked)+len(f.targets))
+		for _, rf := range f.unacked {
+			paths = append(paths, rf.path)
+		}
+		f.targets = append(paths, f.targets...)
+		f.unacked = nil
+		return nil
+	}
+	for len(f.unacked) > 0 {
+		rf := f.unacked[0]
+		if err = f.tracker.Commit(rf.path, rf.size); err != nil {
+			return fmt.Errorf("failed to commit progress of file '%v': %v", rf.path, err)
+		}
+		if err = f.tracker.Finish(rf.path); err != nil {
+			return err
+		}
+		f.unacked = f.unacked[1:]
+	}
 	return nil
 }
 
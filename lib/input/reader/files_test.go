@@ -21,16 +21,48 @@
 package reader
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
 //------------------------------------------------------------------------------
 
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readAllFiles(t *testing.T, f Type) []string {
+	var res []string
+	for {
+		msg, err := f.Read()
+		if err == types.ErrTypeClosed {
+			return res
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, string(msg.Get(0).Get()))
+		if err = f.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 func TestFilesDirectory(t *testing.T) {
 	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
 	if err != nil {
@@ -74,7 +106,7 @@ func TestFilesDirectory(t *testing.T) {
 	conf.Path = tmpDir
 
 	var f Type
-	if f, err = NewFiles(conf); err != nil {
+	if f, err = NewFiles(conf, types.NoopMgr(), log.Noop()); err != nil {
 		t.Fatal(err)
 	}
 
@@ -132,7 +164,7 @@ func TestFilesFile(t *testing.T) {
 	conf.Path = tmpFile.Name()
 
 	var f Type
-	if f, err = NewFiles(conf); err != nil {
+	if f, err = NewFiles(conf, types.NoopMgr(), log.Noop()); err != nil {
 		t.Fatal(err)
 	}
 
@@ -159,11 +191,210 @@ func TestFilesFile(t *testing.T) {
 	}
 }
 
+func TestFilesGlobOrder(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"a.txt":  "a",
+		"b.txt":  "b",
+		"c.txt":  "c",
+		"d.json": "d",
+	})
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"a.txt": 1, "b.txt": 3, "c.txt": 2} {
+		mtime := now.Add(-time.Hour * age)
+		if err = os.Chtimes(filepath.Join(tmpDir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := NewFilesConfig()
+	conf.Path = filepath.Join(tmpDir, "*.txt")
+
+	f, err := NewFiles(conf, types.NoopMgr(), log.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"a", "b", "c"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	conf.Order = "mtime"
+	if f, err = NewFiles(conf, types.NoopMgr(), log.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"b", "c", "a"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	conf.Path = filepath.Join(tmpDir, "*.nope")
+	if _, err = NewFiles(conf, types.NoopMgr(), log.Noop()); err == nil {
+		t.Error("Expected error from pattern without matches")
+	}
+}
+
+func TestFilesCheckpoint(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+	})
+
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["foo"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewFilesConfig()
+	conf.Path = tmpDir
+	conf.Cache = "foo"
+
+	f, err := NewFiles(conf, mgr, log.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read a file without acknowledging it, simulating a restart.
+	if _, err = f.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, err = NewFiles(conf, mgr, log.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"b"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	// Files that have grown since being consumed are read again.
+	writeTestFiles(t, tmpDir, map[string]string{
+		"a.txt": "aa",
+	})
+	if f, err = NewFiles(conf, mgr, log.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"aa"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestFilesMoveAndDeleteOnAck(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	inDir, doneDir := filepath.Join(tmpDir, "in"), filepath.Join(tmpDir, "done")
+	if err = os.Mkdir(inDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, inDir, map[string]string{
+		"a.txt": "a",
+	})
+
+	conf := NewFilesConfig()
+	conf.Path = inDir
+	conf.MoveOnAck = doneDir
+
+	f, err := NewFiles(conf, types.NoopMgr(), log.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"a"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if _, err = os.Stat(filepath.Join(inDir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be moved: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(doneDir, "a.txt")); err != nil {
+		t.Errorf("Expected moved file: %v", err)
+	}
+
+	conf.Path = doneDir
+	conf.MoveOnAck = ""
+	conf.DeleteOnAck = true
+	if f, err = NewFiles(conf, types.NoopMgr(), log.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"a"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if _, err = os.Stat(filepath.Join(doneDir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be deleted: %v", err)
+	}
+
+	conf.MoveOnAck = doneDir
+	if _, err = NewFiles(conf, types.NoopMgr(), log.Noop()); err == nil {
+		t.Error("Expected error from both delete and move")
+	}
+}
+
+func TestFilesNackNotDeleted(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_file_input_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeTestFiles(t, tmpDir, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+	})
+
+	conf := NewFilesConfig()
+	conf.Path = tmpDir
+	conf.DeleteOnAck = true
+
+	f, err := NewFiles(conf, types.NoopMgr(), log.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := f.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "a", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if err = f.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The nacked file must be read again rather than deleted by the next ack.
+	if exp, act := []string{"a", "b"}, readAllFiles(t, f); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err = os.Stat(filepath.Join(tmpDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected file %v to be deleted: %v", name, err)
+		}
+	}
+}
+
 func TestFilesBadPath(t *testing.T) {
 	conf := NewFilesConfig()
 	conf.Path = "fdgdfkte34%#@$%#$%KL@#K$@:L#$23k;32l;23"
 
-	if _, err := NewFiles(conf); err == nil {
+	if _, err := NewFiles(conf, types.NoopMgr(), log.Noop()); err == nil {
 		t.Error("Expected error from bad path")
 	}
 }
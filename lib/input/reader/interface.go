@@ -47,3 +47,28 @@ type Type interface {
 
 	types.Closable
 }
+
+// AsyncAckFn is a function used to acknowledge the result of propagating a
+// single message read from an Async reader. It is called at most once per
+// message with the final outcome, where a non-nil error means the message was
+// never successfully propagated. Messages that are held without acknowledgement
+// by processors are not acknowledged at all.
+type AsyncAckFn func(err error) error
+
+// Async is a type that reads Benthos messages from an external source and
+// allows each message to be acknowledged independently, which means more than
+// one message can be in flight at a time.
+type Async interface {
+	// Connect attempts to establish a connection to the source, if unsuccessful
+	// returns an error. If the attempt is successful (or not necessary) returns
+	// nil.
+	Connect() error
+
+	// ReadWithAck attempts to read a new message from the source, returning it
+	// along with a function for acknowledging it. Messages may be acknowledged
+	// in any order, and it is the responsibility of the implementation to only
+	// commit a message once all prior messages have also been acknowledged.
+	ReadWithAck() (types.Message, AsyncAckFn, error)
+
+	types.Closable
+}
@@ -22,6 +22,7 @@ package reader
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -46,11 +47,14 @@ type KafkaConfig struct {
 	CommitPeriod        string      `json:"commit_period" yaml:"commit_period"`
 	MaxProcessingPeriod string      `json:"max_processing_period" yaml:"max_processing_period"`
 	FetchBufferCap      int         `json:"fetch_buffer_cap" yaml:"fetch_buffer_cap"`
+	FetchBytes          int32       `json:"fetch_bytes" yaml:"fetch_bytes"`
+	FetchMaxBytes       int32       `json:"fetch_max_bytes" yaml:"fetch_max_bytes"`
 	Topic               string      `json:"topic" yaml:"topic"`
 	Partition           int32       `json:"partition" yaml:"partition"`
 	StartFromOldest     bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
 	TargetVersion       string      `json:"target_version" yaml:"target_version"`
 	MaxBatchCount       int         `json:"max_batch_count" yaml:"max_batch_count"`
+	MaxInFlight         int         `json:"max_in_flight" yaml:"max_in_flight"`
 	TLS                 btls.Config `json:"tls" yaml:"tls"`
 	SASL                SASLConfig  `json:"sasl" yaml:"sasl"`
 }
@@ -64,17 +68,27 @@ func NewKafkaConfig() KafkaConfig {
 		CommitPeriod:        "1s",
 		MaxProcessingPeriod: "100ms",
 		FetchBufferCap:      256,
+		FetchBytes:          1048576,
+		FetchMaxBytes:       0,
 		Topic:               "benthos_stream",
 		Partition:           0,
 		StartFromOldest:     true,
 		TargetVersion:       sarama.V1_0_0_0.String(),
 		MaxBatchCount:       1,
+		MaxInFlight:         1,
 		TLS:                 btls.NewConfig(),
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// kafkaPendingBatch tracks the offset following a batch that has been read but
+// not yet acknowledged.
+type kafkaPendingBatch struct {
+	offset int64
+	done   bool
+}
+
 // Kafka is an input type that reads from a Kafka instance.
 type Kafka struct {
 	client       sarama.Client
@@ -85,12 +99,17 @@ type Kafka struct {
 	tlsConf *tls.Config
 
 	sMut sync.Mutex
+	cMut sync.Mutex
+
+	pending []*kafkaPendingBatch
 
 	offsetLastCommitted time.Time
 	commitPeriod        time.Duration
 	maxProcPeriod       time.Duration
 
-	mRcvErr metrics.StatCounter
+	mRcvErr       metrics.StatCounter
+	mBuffered     metrics.StatGauge
+	mBatchUtilPct metrics.StatGauge
 
 	offsetCommitted int64
 	offsetCommit    int64
@@ -107,11 +126,23 @@ func NewKafka(
 	conf KafkaConfig, log log.Modular, stats metrics.Type,
 ) (*Kafka, error) {
 	k := Kafka{
-		offset:  0,
-		conf:    conf,
-		stats:   stats,
-		mRcvErr: stats.GetCounter("recv.error"),
-		log:     log,
+		offset:        0,
+		conf:          conf,
+		stats:         stats,
+		mRcvErr:       stats.GetCounter("recv.error"),
+		mBuffered:     stats.GetGauge("prefetch.buffered"),
+		mBatchUtilPct: stats.GetGauge("batch.utilisation"),
+		log:           log,
+	}
+
+	if conf.MaxBatchCount < 1 {
+		return nil, errors.New("max_batch_count must be greater than or equal to 1")
+	}
+	if conf.MaxInFlight < 1 {
+		return nil, errors.New("max_in_flight must be greater than or equal to 1")
+	}
+	if conf.FetchBytes < 1 {
+		return nil, errors.New("fetch_bytes must be greater than or equal to 1")
 	}
 
 	if conf.TLS.Enabled {
@@ -155,7 +186,9 @@ func NewKafka(
 // closeClients closes the kafka clients, this interrupts loop() out of the read
 // block.
 func (k *Kafka) closeClients() {
+	k.cMut.Lock()
 	k.commit()
+	k.cMut.Unlock()
 
 	k.sMut.Lock()
 	defer k.sMut.Unlock()
@@ -208,6 +241,8 @@ func (k *Kafka) Connect() error {
 	config.Consumer.Return.Errors = true
 	config.Consumer.MaxProcessingTime = k.maxProcPeriod
 	config.ChannelBufferSize = k.conf.FetchBufferCap
+	config.Consumer.Fetch.Default = k.conf.FetchBytes
+	config.Consumer.Fetch.Max = k.conf.FetchMaxBytes
 	config.Net.TLS.Enable = k.conf.TLS.Enabled
 	if k.conf.TLS.Enabled {
 		config.Net.TLS.Config = k.tlsConf
@@ -353,11 +388,17 @@ batchLoop:
 	if msg.Len() == 0 {
 		return nil, types.ErrTimeout
 	}
+
+	k.mBuffered.Set(int64(len(partConsumer.Messages())))
+	k.mBatchUtilPct.Set(int64(msg.Len() * 100 / k.conf.MaxBatchCount))
 	return msg, nil
 }
 
 // Acknowledge instructs whether the current offset should be committed.
 func (k *Kafka) Acknowledge(err error) error {
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+
 	if err == nil {
 		k.offsetCommit = k.offset
 	}
@@ -369,6 +410,45 @@ func (k *Kafka) Acknowledge(err error) error {
 	return k.commit()
 }
 
+// ReadWithAck attempts to read a message from a Kafka topic and returns it
+// along with a function that acknowledges it. Offsets are only committed up to
+// the earliest batch that has not yet been successfully acknowledged.
+func (k *Kafka) ReadWithAck() (types.Message, AsyncAckFn, error) {
+	msg, err := k.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batch := &kafkaPendingBatch{offset: k.offset}
+
+	k.cMut.Lock()
+	k.pending = append(k.pending, batch)
+	k.cMut.Unlock()
+
+	return msg, func(err error) error {
+		if err != nil {
+			return nil
+		}
+
+		k.cMut.Lock()
+		defer k.cMut.Unlock()
+
+		batch.done = true
+		for len(k.pending) > 0 && k.pending[0].done {
+			k.offsetCommit = k.pending[0].offset
+			k.pending[0] = nil
+			k.pending = k.pending[1:]
+		}
+
+		if time.Since(k.offsetLastCommitted) < k.commitPeriod {
+			return nil
+		}
+		return k.commit()
+	}, nil
+}
+
+// commit sends the latest acknowledged offset to the coordinator, cMut must be
+// held by the caller.
 func (k *Kafka) commit() error {
 	if k.offsetCommit == k.offsetCommitted {
 		return nil
@@ -386,7 +466,7 @@ func (k *Kafka) commit() error {
 
 	commitReq := sarama.OffsetCommitRequest{}
 	commitReq.ConsumerGroup = k.conf.ConsumerGroup
-	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, "")
+	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offsetCommit, 0, "")
 
 	commitRes, err := coordinator.CommitOffset(&commitReq)
 	if err == nil {
@@ -67,10 +67,13 @@ type KafkaBalancedConfig struct {
 	CommitPeriod        string                   `json:"commit_period" yaml:"commit_period"`
 	MaxProcessingPeriod string                   `json:"max_processing_period" yaml:"max_processing_period"`
 	FetchBufferCap      int                      `json:"fetch_buffer_cap" yaml:"fetch_buffer_cap"`
+	FetchBytes          int32                    `json:"fetch_bytes" yaml:"fetch_bytes"`
+	FetchMaxBytes       int32                    `json:"fetch_max_bytes" yaml:"fetch_max_bytes"`
 	Topics              []string                 `json:"topics" yaml:"topics"`
 	StartFromOldest     bool                     `json:"start_from_oldest" yaml:"start_from_oldest"`
 	TargetVersion       string                   `json:"target_version" yaml:"target_version"`
 	MaxBatchCount       int                      `json:"max_batch_count" yaml:"max_batch_count"`
+	MaxInFlight         int                      `json:"max_in_flight" yaml:"max_in_flight"`
 	TLS                 btls.Config              `json:"tls" yaml:"tls"`
 	SASL                SASLConfig               `json:"sasl" yaml:"sasl"`
 }
@@ -92,10 +95,13 @@ func NewKafkaBalancedConfig() KafkaBalancedConfig {
 		CommitPeriod:        "1s",
 		MaxProcessingPeriod: "100ms",
 		FetchBufferCap:      256,
+		FetchBytes:          1048576,
+		FetchMaxBytes:       0,
 		Topics:              []string{"benthos_stream"},
 		StartFromOldest:     true,
 		TargetVersion:       sarama.V1_0_0_0.String(),
 		MaxBatchCount:       1,
+		MaxInFlight:         1,
 		TLS:                 btls.NewConfig(),
 	}
 }
@@ -107,6 +113,13 @@ type consumerMessage struct {
 	highWaterMark int64
 }
 
+// kafkaBalancedPendingBatch tracks the offsets of a batch that has been read
+// but not yet acknowledged.
+type kafkaBalancedPendingBatch struct {
+	offsets map[string]map[int32]int64
+	done    bool
+}
+
 // KafkaBalanced is an input type that reads from a Kafka cluster by balancing
 // partitions across other consumers of the same consumer group.
 type KafkaBalanced struct {
@@ -127,8 +140,11 @@ type KafkaBalanced struct {
 	msgChan       chan consumerMessage
 
 	offsets map[string]map[int32]int64
+	pending []*kafkaBalancedPendingBatch
 
-	mRebalanced metrics.StatCounter
+	mRebalanced   metrics.StatCounter
+	mBuffered     metrics.StatGauge
+	mBatchUtilPct metrics.StatGauge
 
 	conf  KafkaBalancedConfig
 	stats metrics.Type
@@ -146,10 +162,18 @@ func NewKafkaBalanced(
 		log:           log,
 		offsets:       map[string]map[int32]int64{},
 		mRebalanced:   stats.GetCounter("rebalanced"),
+		mBuffered:     stats.GetGauge("prefetch.buffered"),
+		mBatchUtilPct: stats.GetGauge("batch.utilisation"),
 	}
 	if conf.MaxBatchCount < 1 {
 		return nil, errors.New("max_batch_count must be greater than or equal to 1")
 	}
+	if conf.MaxInFlight < 1 {
+		return nil, errors.New("max_in_flight must be greater than or equal to 1")
+	}
+	if conf.FetchBytes < 1 {
+		return nil, errors.New("fetch_bytes must be greater than or equal to 1")
+	}
 	if conf.TLS.Enabled {
 		var err error
 		if k.tlsConf, err = conf.TLS.Get(); err != nil {
@@ -252,16 +276,29 @@ func (k *KafkaBalanced) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sar
 
 //------------------------------------------------------------------------------
 
-func (k *KafkaBalanced) setOffset(topic string, partition int32, offset int64) {
+func setKafkaOffset(offsets map[string]map[int32]int64, topic string, partition int32, offset int64) {
 	var topicMap map[int32]int64
 	var exists bool
-	if topicMap, exists = k.offsets[topic]; !exists {
+	if topicMap, exists = offsets[topic]; !exists {
 		topicMap = map[int32]int64{}
-		k.offsets[topic] = topicMap
+		offsets[topic] = topicMap
 	}
 	topicMap[partition] = offset
 }
 
+// markOffsets marks a map of consumed offsets on the current session, cMut
+// must be held by the caller.
+func (k *KafkaBalanced) markOffsets(offsets map[string]map[int32]int64) {
+	if k.session == nil {
+		return
+	}
+	for topic, v := range offsets {
+		for part, offset := range v {
+			k.session.MarkOffset(topic, part, offset+1, "")
+		}
+	}
+}
+
 func (k *KafkaBalanced) closeGroup() {
 	k.cMut.Lock()
 	cancelFn := k.groupCancelFn
@@ -293,6 +330,8 @@ func (k *KafkaBalanced) Connect() error {
 	config.Consumer.Group.Heartbeat.Interval = k.heartbeatInterval
 	config.Consumer.Group.Rebalance.Timeout = k.rebalanceTimeout
 	config.ChannelBufferSize = k.conf.FetchBufferCap
+	config.Consumer.Fetch.Default = k.conf.FetchBytes
+	config.Consumer.Fetch.Max = k.conf.FetchMaxBytes
 
 	if config.Net.ReadTimeout <= k.sessionTimeout {
 		config.Net.ReadTimeout = k.sessionTimeout * 2
@@ -379,6 +418,7 @@ func (k *KafkaBalanced) Connect() error {
 
 	k.msgChan = make(chan consumerMessage, k.conf.MaxBatchCount)
 	k.offsets = map[string]map[int32]int64{}
+	k.pending = nil
 
 	k.log.Infof("Receiving KafkaBalanced messages from addresses: %s\n", k.addresses)
 	return nil
@@ -386,6 +426,12 @@ func (k *KafkaBalanced) Connect() error {
 
 // Read attempts to read a message from a KafkaBalanced topic.
 func (k *KafkaBalanced) Read() (types.Message, error) {
+	return k.readBatch(k.offsets)
+}
+
+// readBatch reads a batch of messages and records the latest offset of each
+// topic and partition consumed within the provided map.
+func (k *KafkaBalanced) readBatch(offsets map[string]map[int32]int64) (types.Message, error) {
 	k.cMut.Lock()
 	msgChan := k.msgChan
 	k.cMut.Unlock()
@@ -417,7 +463,7 @@ func (k *KafkaBalanced) Read() (types.Message, error) {
 
 		msg.Append(part)
 
-		k.setOffset(data.Topic, data.Partition, data.Offset)
+		setKafkaOffset(offsets, data.Topic, data.Partition, data.Offset)
 	}
 
 	data, open := <-msgChan
@@ -443,6 +489,9 @@ batchLoop:
 	if msg.Len() == 0 {
 		return nil, types.ErrTimeout
 	}
+
+	k.mBuffered.Set(int64(len(msgChan)))
+	k.mBatchUtilPct.Set(int64(msg.Len() * 100 / k.conf.MaxBatchCount))
 	return msg, nil
 }
 
@@ -450,18 +499,46 @@ batchLoop:
 func (k *KafkaBalanced) Acknowledge(err error) error {
 	if err == nil {
 		k.cMut.Lock()
-		if k.session != nil {
-			for topic, v := range k.offsets {
-				for part, offset := range v {
-					k.session.MarkOffset(topic, part, offset+1, "")
-				}
-			}
-		}
+		k.markOffsets(k.offsets)
 		k.cMut.Unlock()
 	}
 	return nil
 }
 
+// ReadWithAck attempts to read a message from a KafkaBalanced topic and returns
+// it along with a function that acknowledges it. Offsets are only marked up to
+// the earliest batch that has not yet been successfully acknowledged.
+func (k *KafkaBalanced) ReadWithAck() (types.Message, AsyncAckFn, error) {
+	batch := &kafkaBalancedPendingBatch{
+		offsets: map[string]map[int32]int64{},
+	}
+	msg, err := k.readBatch(batch.offsets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k.cMut.Lock()
+	k.pending = append(k.pending, batch)
+	k.cMut.Unlock()
+
+	return msg, func(err error) error {
+		if err != nil {
+			return nil
+		}
+
+		k.cMut.Lock()
+		defer k.cMut.Unlock()
+
+		batch.done = true
+		for len(k.pending) > 0 && k.pending[0].done {
+			k.markOffsets(k.pending[0].offsets)
+			k.pending[0] = nil
+			k.pending = k.pending[1:]
+		}
+		return nil
+	}, nil
+}
+
 // CloseAsync shuts down the KafkaBalanced input and stops processing requests.
 func (k *KafkaBalanced) CloseAsync() {
 	go k.closeGroup()
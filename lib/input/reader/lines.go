@@ -37,6 +37,7 @@ import (
 type Lines struct {
 	handleCtor func() (io.Reader, error)
 	onClose    func()
+	onAck      func(offset int64) error
 
 	handle  io.Reader
 	scanner *bufio.Scanner
@@ -44,6 +45,11 @@ type Lines struct {
 	messageBuffer      *bytes.Buffer
 	messageBufferIndex int
 
+	// The number of bytes of the current handle consumed by the scanner, and
+	// the number consumed as of the last message read.
+	consumed   int64
+	readOffset int64
+
 	maxBuffer int
 	multipart bool
 	delimiter []byte
@@ -106,6 +112,16 @@ func OptLinesSetDelimiter(delimiter string) func(r *Lines) {
 	}
 }
 
+// OptLinesSetOnAcknowledge is a option func that sets a function to be called
+// each time messages are successfully acknowledged, with the byte offset of
+// the current reader that has been consumed up to and including the last
+// message read. Offsets are reset each time a new reader is created.
+func OptLinesSetOnAcknowledge(fn func(offset int64) error) func(r *Lines) {
+	return func(r *Lines) {
+		r.onAck = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func (r *Lines) closeHandle() {
@@ -134,6 +150,7 @@ func (r *Lines) Connect() error {
 		return err
 	}
 
+	r.consumed, r.readOffset = 0, 0
 	r.scanner = bufio.NewScanner(r.handle)
 	if r.maxBuffer != bufio.MaxScanTokenSize {
 		r.scanner.Buffer([]byte{}, r.maxBuffer)
@@ -146,11 +163,13 @@ func (r *Lines) Connect() error {
 
 		if i := bytes.Index(data, r.delimiter); i >= 0 {
 			// We have a full terminated line.
+			r.consumed += int64(i + len(r.delimiter))
 			return i + len(r.delimiter), data[0:i], nil
 		}
 
 		// If we're at EOF, we have a final, non-terminated line. Return it.
 		if atEOF {
+			r.consumed += int64(len(data))
 			return len(data), data, nil
 		}
 
@@ -180,11 +199,13 @@ func (r *Lines) Read() (types.Message, error) {
 		if partSize > 0 {
 			msg.Append(message.NewPart(r.messageBuffer.Bytes()[rIndex : rIndex+partSize]))
 			if !r.multipart {
+				r.readOffset = r.consumed
 				return msg, nil
 			}
 		} else if r.multipart && msg.Len() > 0 {
 			// Empty line means we're finished reading parts for this
 			// message.
+			r.readOffset = r.consumed
 			return msg, nil
 		}
 	}
@@ -197,6 +218,7 @@ func (r *Lines) Read() (types.Message, error) {
 	r.closeHandle()
 
 	if msg.Len() > 0 {
+		r.readOffset = r.consumed
 		return msg, nil
 	}
 	return nil, types.ErrNotConnected
@@ -209,6 +231,9 @@ func (r *Lines) Acknowledge(err error) error {
 		r.messageBuffer.Reset()
 		r.messageBufferIndex = 0
 	}
+	if err == nil && r.onAck != nil {
+		return r.onAck(r.readOffset)
+	}
 	return nil
 }
 
@@ -0,0 +1,450 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// RSSConfig contains configuration fields for the RSS input type.
+type RSSConfig struct {
+	URLs     []string `json:"urls" yaml:"urls"`
+	Interval string   `json:"interval" yaml:"interval"`
+	Cache    string   `json:"cache" yaml:"cache"`
+	Timeout  string   `json:"timeout" yaml:"timeout"`
+}
+
+// NewRSSConfig creates a new RSSConfig with default values.
+func NewRSSConfig() RSSConfig {
+	return RSSConfig{
+		URLs:     []string{},
+		Interval: "5m",
+		Cache:    "",
+		Timeout:  "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// rssItem covers the items of both RSS 2.0 and RSS 1.0 (RDF) feeds.
+type rssItem struct {
+	GUID        string   `xml:"guid"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"encoded"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"`
+	Categories  []string `xml:"category"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+// rssFeed is able to decode RSS 2.0, RSS 1.0 and Atom documents.
+type rssFeed struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// feedEntry is the structured form of an entry emitted as a message.
+type feedEntry struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Link        string   `json:"link"`
+	Description string   `json:"description"`
+	Content     string   `json:"content"`
+	Published   string   `json:"published"`
+	Updated     string   `json:"updated"`
+	Authors     []string `json:"authors"`
+	Categories  []string `json:"categories"`
+	FeedTitle   string   `json:"feed_title"`
+	FeedURL     string   `json:"feed_url"`
+}
+
+var feedTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// normaliseFeedTime attempts to convert a feed timestamp into RFC 3339, and
+// returns the original string if it cannot be parsed.
+func normaliseFeedTime(s string) string {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return s
+}
+
+// feedCharsetReader supports the single byte charsets most commonly declared
+// by feeds that aren't UTF-8.
+func feedCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "windows-1252":
+		b, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 0, len(b))
+		for _, c := range b {
+			buf = append(buf, string(rune(c))...)
+		}
+		return bytes.NewReader(buf), nil
+	}
+	return nil, fmt.Errorf("charset not supported: %v", charset)
+}
+
+func parseFeed(feedURL string, body io.Reader) ([]feedEntry, error) {
+	dec := xml.NewDecoder(body)
+	dec.CharsetReader = feedCharsetReader
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var feed rssFeed
+	if err := dec.Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var entries []feedEntry
+	switch strings.ToLower(feed.XMLName.Local) {
+	case "rss", "rdf":
+		title := feed.Channel.Title
+		items := append(feed.Channel.Items, feed.Items...)
+		for _, item := range items {
+			e := feedEntry{
+				ID:          strings.TrimSpace(item.GUID),
+				Title:       strings.TrimSpace(item.Title),
+				Link:        strings.TrimSpace(item.Link),
+				Description: item.Description,
+				Content:     item.Content,
+				Published:   normaliseFeedTime(item.PubDate),
+				Updated:     normaliseFeedTime(item.Date),
+				Authors:     []string{},
+				Categories:  item.Categories,
+				FeedTitle:   strings.TrimSpace(title),
+				FeedURL:     feedURL,
+			}
+			if len(e.Published) == 0 {
+				e.Published = e.Updated
+			}
+			for _, a := range []string{item.Author, item.Creator} {
+				if a = strings.TrimSpace(a); len(a) > 0 {
+					e.Authors = append(e.Authors, a)
+				}
+			}
+			entries = append(entries, e)
+		}
+	case "feed":
+		for _, entry := range feed.Entries {
+			e := feedEntry{
+				ID:          strings.TrimSpace(entry.ID),
+				Title:       strings.TrimSpace(entry.Title),
+				Description: entry.Summary,
+				Content:     entry.Content,
+				Published:   normaliseFeedTime(entry.Published),
+				Updated:     normaliseFeedTime(entry.Updated),
+				Authors:     []string{},
+				Categories:  []string{},
+				FeedTitle:   strings.TrimSpace(feed.Title),
+				FeedURL:     feedURL,
+			}
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					e.Link = l.Href
+					break
+				}
+			}
+			if len(e.Published) == 0 {
+				e.Published = e.Updated
+			}
+			for _, a := range entry.Authors {
+				if name := strings.TrimSpace(a.Name); len(name) > 0 {
+					e.Authors = append(e.Authors, name)
+				}
+			}
+			for _, c := range entry.Categories {
+				e.Categories = append(e.Categories, c.Term)
+			}
+			entries = append(entries, e)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised feed document type: %v", feed.XMLName.Local)
+	}
+
+	for i, e := range entries {
+		if e.Categories == nil {
+			entries[i].Categories = []string{}
+		}
+		if len(e.ID) == 0 {
+			if len(e.Link) > 0 {
+				entries[i].ID = e.Link
+			} else {
+				entries[i].ID = e.Title + e.Published
+			}
+		}
+	}
+	return entries, nil
+}
+
+//------------------------------------------------------------------------------
+
+// RSS is a benthos reader.Type implementation that polls RSS and Atom feeds and
+// emits each newly seen entry as a message.
+type RSS struct {
+	conf  RSSConfig
+	cache types.Cache
+
+	interval time.Duration
+	client   http.Client
+	lastPoll time.Time
+
+	pending   []types.Message
+	pendingID []string
+	unacked   []string
+	inFlight  map[string]struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mPolls   metrics.StatCounter
+	mPollErr metrics.StatCounter
+	mSkipped metrics.StatCounter
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewRSS creates a new RSS reader.Type.
+func NewRSS(
+	conf RSSConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (*RSS, error) {
+	if len(conf.URLs) == 0 {
+		return nil, errors.New("at least one feed url must be specified")
+	}
+	if len(conf.Cache) == 0 {
+		return nil, errors.New("a cache must be specified")
+	}
+	cache, err := mgr.GetCache(conf.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Cache, err)
+	}
+
+	r := &RSS{
+		conf:      conf,
+		cache:     cache,
+		inFlight:  map[string]struct{}{},
+		log:       log,
+		stats:     stats,
+		mPolls:    stats.GetCounter("poll.count"),
+		mPollErr:  stats.GetCounter("poll.error"),
+		mSkipped:  stats.GetCounter("entries.skipped"),
+		closeChan: make(chan struct{}),
+	}
+	if r.interval, err = time.ParseDuration(conf.Interval); err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		if r.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *RSS) fetch(feedURL string) ([]feedEntry, error) {
+	res, err := r.client.Get(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, types.ErrUnexpectedHTTPRes{Code: res.StatusCode, S: res.Status}
+	}
+	return parseFeed(feedURL, res.Body)
+}
+
+// poll fetches each feed and queues any entries that have not yet been seen.
+func (r *RSS) poll() {
+	r.mPolls.Incr(1)
+	for _, feedURL := range r.conf.URLs {
+		entries, err := r.fetch(feedURL)
+		if err != nil {
+			r.mPollErr.Incr(1)
+			r.log.Errorf("Failed to poll feed '%v': %v\n", feedURL, err)
+			continue
+		}
+
+		// Feeds are typically ordered newest first.
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if _, exists := r.inFlight[e.ID]; exists {
+				continue
+			}
+			if _, err = r.cache.Get(e.ID); err == nil {
+				r.mSkipped.Incr(1)
+				continue
+			}
+
+			jBytes, err := json.Marshal(e)
+			if err != nil {
+				r.log.Errorf("Failed to serialise feed entry: %v\n", err)
+				continue
+			}
+			msg := message.New([][]byte{jBytes})
+			meta := msg.Get(0).Metadata()
+			meta.Set("rss_feed_url", feedURL)
+			meta.Set("rss_feed_title", e.FeedTitle)
+			meta.Set("rss_entry_id", e.ID)
+
+			r.inFlight[e.ID] = struct{}{}
+			r.pending = append(r.pending, msg)
+			r.pendingID = append(r.pendingID, e.ID)
+		}
+	}
+	r.lastPoll = time.Now()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect does nothing as each poll is a standalone HTTP request.
+func (r *RSS) Connect() error {
+	r.log.Infof("Polling feeds every %v: %v\n", r.interval, strings.Join(r.conf.URLs, ", "))
+	return nil
+}
+
+// Read attempts to read a new feed entry, polling the feeds when no entries are
+// queued and the poll interval has passed.
+func (r *RSS) Read() (types.Message, error) {
+	if len(r.pending) == 0 {
+		if !r.lastPoll.IsZero() {
+			select {
+			case <-time.After(time.Until(r.lastPoll.Add(r.interval))):
+			case <-r.closeChan:
+				return nil, types.ErrTypeClosed
+			}
+		}
+		r.poll()
+		if len(r.pending) == 0 {
+			return nil, types.ErrTimeout
+		}
+	}
+
+	msg := r.pending[0]
+	r.unacked = append(r.unacked, r.pendingID[0])
+	r.pending, r.pendingID = r.pending[1:], r.pendingID[1:]
+	return msg, nil
+}
+
+// Acknowledge records all entries read since the last acknowledgement as seen
+// within the cache. Entries that failed to propagate are forgotten so that they
+// are emitted again by the next poll.
+func (r *RSS) Acknowledge(err error) error {
+	if err != nil {
+		for _, id := range r.unacked {
+			delete(r.inFlight, id)
+		}
+		r.unacked = nil
+		return nil
+	}
+	seenAt := []byte(time.Now().Format(time.RFC3339))
+	for _, id := range r.unacked {
+		if serr := r.cache.Set(id, seenAt); serr != nil {
+			return fmt.Errorf("failed to store seen entry '%v': %v", id, serr)
+		}
+		delete(r.inFlight, id)
+	}
+	r.unacked = nil
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (r *RSS) CloseAsync() {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (r *RSS) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,238 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestRSSParseRSS2(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+  <title>Foo Feed</title>
+  <item>
+    <title>Second</title>
+    <link>http://example.com/2</link>
+    <guid>id2</guid>
+    <description>second &amp; last</description>
+    <content:encoded><![CDATA[<p>second</p>]]></content:encoded>
+    <pubDate>Mon, 01 Jul 2019 16:53:20 +0000</pubDate>
+    <category>a</category>
+    <category>b</category>
+  </item>
+  <item>
+    <title>First</title>
+    <link>http://example.com/1</link>
+    <author>foo@example.com</author>
+  </item>
+</channel>
+</rss>`
+
+	entries, err := parseFeed("http://example.com/feed", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []feedEntry{
+		{
+			ID:          "id2",
+			Title:       "Second",
+			Link:        "http://example.com/2",
+			Description: "second & last",
+			Content:     "<p>second</p>",
+			Published:   "2019-07-01T16:53:20Z",
+			Authors:     []string{},
+			Categories:  []string{"a", "b"},
+			FeedTitle:   "Foo Feed",
+			FeedURL:     "http://example.com/feed",
+		},
+		{
+			ID:         "http://example.com/1",
+			Title:      "First",
+			Link:       "http://example.com/1",
+			Authors:    []string{"foo@example.com"},
+			Categories: []string{},
+			FeedTitle:  "Foo Feed",
+			FeedURL:    "http://example.com/feed",
+		},
+	}
+	if !reflect.DeepEqual(exp, entries) {
+		t.Errorf("Wrong entries: %+v != %+v", entries, exp)
+	}
+}
+
+func TestRSSParseAtom(t *testing.T) {
+	input := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Bar Feed</title>
+  <entry>
+    <title>Hello</title>
+    <link rel="self" href="http://example.com/self"/>
+    <link href="http://example.com/hello"/>
+    <id>urn:uuid:1225c695</id>
+    <updated>2019-07-01T16:53:20Z</updated>
+    <summary>Some text.</summary>
+    <author><name>Ash</name></author>
+    <category term="tech"/>
+  </entry>
+</feed>`
+
+	entries, err := parseFeed("http://example.com/atom", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []feedEntry{
+		{
+			ID:          "urn:uuid:1225c695",
+			Title:       "Hello",
+			Link:        "http://example.com/hello",
+			Description: "Some text.",
+			Published:   "2019-07-01T16:53:20Z",
+			Updated:     "2019-07-01T16:53:20Z",
+			Authors:     []string{"Ash"},
+			Categories:  []string{"tech"},
+			FeedTitle:   "Bar Feed",
+			FeedURL:     "http://example.com/atom",
+		},
+	}
+	if !reflect.DeepEqual(exp, entries) {
+		t.Errorf("Wrong entries: %+v != %+v", entries, exp)
+	}
+}
+
+func TestRSSParseErrors(t *testing.T) {
+	for _, input := range []string{
+		`not xml`,
+		`<html><body>nope</body></html>`,
+	} {
+		if _, err := parseFeed("foo", strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error from input: %v", input)
+		}
+	}
+}
+
+func TestRSSDedupe(t *testing.T) {
+	var itemsMut sync.Mutex
+	items := []string{"a", "b"}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		itemsMut.Lock()
+		defer itemsMut.Unlock()
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>foo</title>`)
+		for i := len(items) - 1; i >= 0; i-- {
+			fmt.Fprintf(w, `<item><guid>%v</guid><title>%v</title></item>`, items[i], items[i])
+		}
+		fmt.Fprint(w, `</channel></rss>`)
+	}))
+	defer ts.Close()
+
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["foo"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewRSSConfig()
+	conf.URLs = []string{ts.URL}
+	conf.Cache = "foo"
+	conf.Interval = "1ms"
+
+	r, err := NewRSS(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.CloseAsync()
+
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	readIDs := func(n int) []string {
+		var ids []string
+		for i := 0; i < n; i++ {
+			msg, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, msg.Get(0).Metadata().Get("rss_entry_id"))
+		}
+		if err := r.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+
+	if exp, act := []string{"a", "b"}, readIDs(2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong entries: %v != %v", act, exp)
+	}
+
+	if _, err = r.Read(); err != types.ErrTimeout {
+		t.Errorf("Expected timeout error, received: %v", err)
+	}
+
+	itemsMut.Lock()
+	items = append(items, "c")
+	itemsMut.Unlock()
+
+	if exp, act := []string{"c"}, readIDs(1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong entries: %v != %v", act, exp)
+	}
+
+	itemsMut.Lock()
+	items = append(items, "d", "e")
+	itemsMut.Unlock()
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "d", msg.Get(0).Metadata().Get("rss_entry_id"); exp != act {
+		t.Errorf("Wrong entry: %v != %v", act, exp)
+	}
+	if err = r.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The nacked entry must not be recorded as seen by the next ack.
+	if exp, act := []string{"e", "d"}, readIDs(2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong entries: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
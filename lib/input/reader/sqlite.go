@@ -0,0 +1,250 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build SQLITE
+
+package reader
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+
+	// SQL Drivers
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLiteConfig contains configuration fields for the SQLite input type.
+type SQLiteConfig struct {
+	Path          string `json:"path" yaml:"path"`
+	Table         string `json:"table" yaml:"table"`
+	MaxBatchCount int    `json:"max_batch_count" yaml:"max_batch_count"`
+	PollInterval  string `json:"poll_interval" yaml:"poll_interval"`
+}
+
+// NewSQLiteConfig creates a new SQLiteConfig with default values.
+func NewSQLiteConfig() *SQLiteConfig {
+	return &SQLiteConfig{
+		Path:          "",
+		Table:         "benthos_queue",
+		MaxBatchCount: 1,
+		PollInterval:  "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQLite is an input type that consumes messages from a queue table within a
+// local SQLite database file, deleting them once they are acknowledged.
+type SQLite struct {
+	conf         SQLiteConfig
+	pollInterval time.Duration
+
+	db      *sql.DB
+	sel     *sql.Stmt
+	connMut sync.Mutex
+
+	// The ID of the last row read, and the IDs of rows read but not yet
+	// acknowledged.
+	lastID  int64
+	pending []int64
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRowsDeleted metrics.StatCounter
+}
+
+// NewSQLite creates a new SQLite input type.
+func NewSQLite(
+	conf *SQLiteConfig, log log.Modular, stats metrics.Type,
+) (*SQLite, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a path must be specified")
+	}
+	if conf.MaxBatchCount < 1 {
+		return nil, errors.New("max_batch_count must be greater than zero")
+	}
+	s := &SQLite{
+		conf:         *conf,
+		closeChan:    make(chan struct{}),
+		log:          log,
+		stats:        stats,
+		mRowsDeleted: stats.GetCounter("rows.deleted"),
+	}
+	if len(conf.PollInterval) > 0 {
+		var err error
+		if s.pollInterval, err = time.ParseDuration(conf.PollInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse poll interval string: %v", err)
+		}
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect opens the database file, creating the queue table if necessary.
+func (s *SQLite) Connect() error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := bsql.OpenQueue(s.conf.Path, s.conf.Table)
+	if err != nil {
+		return err
+	}
+	sel, err := db.Prepare(fmt.Sprintf(
+		"SELECT id, content, metadata FROM %v WHERE id > ? ORDER BY id LIMIT ?",
+		s.conf.Table,
+	))
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to prepare select: %v", err)
+	}
+
+	s.db, s.sel = db, sel
+	s.log.Infof("Consuming messages from SQLite queue: %v\n", s.conf.Path)
+	return nil
+}
+
+// Read attempts to read a batch of messages from the queue table, returning
+// types.ErrTimeout after the poll interval if the table is empty.
+func (s *SQLite) Read() (types.Message, error) {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.db == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	rows, err := s.sel.Query(s.lastID, s.conf.MaxBatchCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msg := message.New(nil)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var content []byte
+		var meta string
+		if err = rows.Scan(&id, &content, &meta); err != nil {
+			return nil, err
+		}
+		part, perr := bsql.DecodeQueuePart(content, meta)
+		if perr != nil {
+			s.log.Errorf("Row %v of SQLite queue is malformed: %v\n", id, perr)
+			part = message.NewPart(content)
+		}
+		msg.Append(part)
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		select {
+		case <-time.After(s.pollInterval):
+		case <-s.closeChan:
+			return nil, types.ErrTypeClosed
+		}
+		return nil, types.ErrTimeout
+	}
+
+	s.lastID = ids[len(ids)-1]
+	s.pending = append(s.pending, ids...)
+	return msg, nil
+}
+
+// Acknowledge deletes all rows read since the last acknowledgement when err is
+// nil, otherwise those rows are read again.
+func (s *SQLite) Acknowledge(err error) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if err != nil {
+		s.lastID = s.pending[0] - 1
+		s.pending = nil
+		return nil
+	}
+	if s.db == nil {
+		return types.ErrNotConnected
+	}
+
+	placeholders := strings.Repeat(",?", len(s.pending))[1:]
+	args := make([]interface{}, len(s.pending))
+	for i, id := range s.pending {
+		args[i] = id
+	}
+	if _, err = s.db.Exec(fmt.Sprintf(
+		"DELETE FROM %v WHERE id IN (%v)", s.conf.Table, placeholders,
+	), args...); err != nil {
+		return err
+	}
+	s.mRowsDeleted.Incr(int64(len(s.pending)))
+	s.pending = nil
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (s *SQLite) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+	})
+	go func() {
+		s.connMut.Lock()
+		if s.db != nil {
+			s.sel.Close()
+			s.db.Close()
+			s.db, s.sel = nil, nil
+		}
+		s.connMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (s *SQLite) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
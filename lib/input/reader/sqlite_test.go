@@ -0,0 +1,135 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build SQLITE
+
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+)
+
+func TestSQLiteQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_sqlite_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "queue.db")
+	db, err := bsql.OpenQueue(path, "foo")
+	if err != nil {
+		t.Skipf("SQLite driver unavailable: %v", err)
+	}
+	defer db.Close()
+
+	for _, c := range []string{"first", "second", "third"} {
+		p := message.NewPart([]byte(c))
+		p.Metadata().Set("content", c)
+		meta, err := bsql.EncodeQueueMetadata(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = db.Exec("INSERT INTO foo (content, metadata) VALUES (?, ?)", p.Get(), meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := NewSQLiteConfig()
+	conf.Path = path
+	conf.Table = "foo"
+	conf.MaxBatchCount = 2
+	conf.PollInterval = "10ms"
+
+	r, err := NewSQLite(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		r.CloseAsync()
+		if err := r.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRead := func(exp ...string) {
+		t.Helper()
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var act []string
+		msg.Iter(func(i int, p types.Part) error {
+			act = append(act, string(p.Get()))
+			if c := p.Metadata().Get("content"); c != string(p.Get()) {
+				t.Errorf("Wrong metadata: %v != %s", c, p.Get())
+			}
+			return nil
+		})
+		if len(act) != len(exp) {
+			t.Fatalf("Wrong messages: %v != %v", act, exp)
+		}
+		for i := range exp {
+			if exp[i] != act[i] {
+				t.Errorf("Wrong messages: %v != %v", act, exp)
+			}
+		}
+	}
+
+	expectRead("first", "second")
+
+	// Failed messages are read again.
+	if err = r.Acknowledge(types.ErrTimeout); err != nil {
+		t.Fatal(err)
+	}
+	expectRead("first", "second")
+	if err = r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRead("third")
+	if _, err = r.Read(); err != types.ErrTimeout {
+		t.Errorf("Expected timeout, received: %v", err)
+	}
+	if err = r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err = db.QueryRow("SELECT COUNT(*) FROM foo").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected acknowledged rows to be deleted, %v remain", count)
+	}
+}
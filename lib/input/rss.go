@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRSS] = TypeSpec{
+		constructor: NewRSS,
+		description: `
+Polls a list of RSS (1.0 and 2.0) or Atom feeds on an interval and emits each
+entry not seen before as a JSON message of the form:
+
+` + "``` json" + `
+{
+  "id": "entry id, or link when absent",
+  "title": "",
+  "link": "",
+  "description": "",
+  "content": "",
+  "published": "RFC 3339 when parseable",
+  "updated": "",
+  "authors": [],
+  "categories": [],
+  "feed_title": "",
+  "feed_url": ""
+}
+` + "```" + `
+
+The IDs of entries are stored in the [cache resource](../caches/README.md)
+specified by ` + "`cache`" + ` once the entry has been successfully delivered,
+and entries whose IDs already exist within the cache are skipped. Since the
+first poll with an empty cache emits every entry currently within the feeds, it
+is recommended to use a persisted cache, and to make sure that the TTL of the
+cache exceeds the time that entries remain within the feeds.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- rss_feed_url
+- rss_feed_title
+- rss_entry_id
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRSS creates a new RSS input type.
+func NewRSS(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := reader.NewRSS(conf.RSS, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"rss",
+		reader.NewPreserver(r),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
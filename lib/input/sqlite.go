@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build SQLITE
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSQLite] = TypeSpec{
+		constructor: NewSQLite,
+		description: `
+Consumes messages from a queue table within a local SQLite database file, such
+as one written to by the ` + "[`sqlite`](../outputs/README.md#sqlite)" + `
+output. Messages are read in the order they were written, up to
+` + "`max_batch_count`" + ` at a time, and their rows are only deleted once they
+have been acknowledged. Unacknowledged messages are therefore read again after
+a failure or a restart, giving at-least-once delivery.
+
+When the table is empty it is polled again after ` + "`poll_interval`" + `.
+
+` + bsql.QueueDocumentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQLite creates a new SQLite input type.
+func NewSQLite(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSQLite(conf.SQLite, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("sqlite", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -46,7 +46,12 @@ func NewAmazonSQS(conf Config, mgr types.Manager, log log.Modular, stats metrics
 	if err != nil {
 		return nil, err
 	}
-	return NewReader("sqs", s, log, stats)
+	r, err := NewReader("sqs", s, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	registerPausable("sqs", r, mgr, stats)
+	return r, nil
 }
 
 //------------------------------------------------------------------------------
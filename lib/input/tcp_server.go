@@ -0,0 +1,394 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeTCPServer] = TypeSpec{
+		constructor: NewTCPServer,
+		description: `
+Creates a server that receives messages over TCP. Each connection is read as a
+stream of delimited messages, where by default each line is a separate message.
+If multipart is set to true then each line is read as a message part, and an
+empty line indicates the end of a message. If the delimiter field is left empty
+then line feed (\n) is used.
+
+TLS is enabled when both ` + "`cert_file` and `key_file`" + ` are specified.
+
+The number of concurrent connections can be capped with the field
+` + "`max_connections`" + `, where connections beyond the limit are closed
+immediately. When ` + "`idle_timeout`" + ` is set connections that have not
+sent any data within the period are closed.
+
+Messages from a connection are acknowledged in order, and a connection is not
+read from whilst its messages are being processed.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- tcp_remote_address
+- tcp_remote_port
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TCPServerConfig contains configuration for the TCPServer input type.
+type TCPServerConfig struct {
+	Address        string `json:"address" yaml:"address"`
+	CertFile       string `json:"cert_file" yaml:"cert_file"`
+	KeyFile        string `json:"key_file" yaml:"key_file"`
+	MaxConnections int    `json:"max_connections" yaml:"max_connections"`
+	IdleTimeout    string `json:"idle_timeout" yaml:"idle_timeout"`
+	Multipart      bool   `json:"multipart" yaml:"multipart"`
+	MaxBuffer      int    `json:"max_buffer" yaml:"max_buffer"`
+	Delim          string `json:"delimiter" yaml:"delimiter"`
+}
+
+// NewTCPServerConfig creates a new TCPServerConfig with default values.
+func NewTCPServerConfig() TCPServerConfig {
+	return TCPServerConfig{
+		Address:        "0.0.0.0:4196",
+		CertFile:       "",
+		KeyFile:        "",
+		MaxConnections: 0,
+		IdleTimeout:    "",
+		Multipart:      false,
+		MaxBuffer:      1000000,
+		Delim:          "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// idleConn wraps a connection with a deadline that is extended with each read,
+// and treats an expired deadline as the end of the stream.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	n, err := c.Conn.Read(p)
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// connMetaReader adds metadata describing the remote end of a connection to
+// each message read.
+type connMetaReader struct {
+	reader.Type
+	address string
+	port    string
+}
+
+func (r *connMetaReader) Read() (types.Message, error) {
+	msg, err := r.Type.Read()
+	if err != nil {
+		return nil, err
+	}
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		meta.Set("tcp_remote_address", r.address)
+		meta.Set("tcp_remote_port", r.port)
+		return nil
+	})
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
+
+// TCPServer is an input type that binds to an address and consumes messages
+// from any number of TCP connections.
+type TCPServer struct {
+	running int32
+
+	conf  TCPServerConfig
+	stats metrics.Type
+	log   log.Modular
+
+	listener    net.Listener
+	idleTimeout time.Duration
+	delim       string
+
+	conns    map[Type]struct{}
+	connsMut sync.Mutex
+	connsWG  sync.WaitGroup
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	mConnOpened   metrics.StatCounter
+	mConnClosed   metrics.StatCounter
+	mConnRejected metrics.StatCounter
+	mConnActive   metrics.StatGauge
+}
+
+// NewTCPServer creates a new TCPServer input type.
+func NewTCPServer(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	tConf := conf.TCPServer
+	if len(tConf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+
+	t := TCPServer{
+		running:      1,
+		conf:         tConf,
+		stats:        stats,
+		log:          log,
+		delim:        tConf.Delim,
+		conns:        map[Type]struct{}{},
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+
+		mConnOpened:   stats.GetCounter("connection.opened"),
+		mConnClosed:   stats.GetCounter("connection.closed"),
+		mConnRejected: stats.GetCounter("connection.rejected"),
+		mConnActive:   stats.GetGauge("connection.active"),
+	}
+	if len(t.delim) == 0 {
+		t.delim = "\n"
+	}
+	if tout := tConf.IdleTimeout; len(tout) > 0 {
+		var err error
+		if t.idleTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse idle timeout string: %v", err)
+		}
+	}
+
+	var err error
+	if len(tConf.CertFile) > 0 || len(tConf.KeyFile) > 0 {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(tConf.CertFile, tConf.KeyFile); err != nil {
+			return nil, fmt.Errorf("failed to load TLS key pair: %v", err)
+		}
+		t.listener, err = tls.Listen("tcp", tConf.Address, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		})
+	} else {
+		t.listener, err = net.Listen("tcp", tConf.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go t.loop()
+	return &t, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (t *TCPServer) addConn(conn net.Conn) error {
+	address, port, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		address = conn.RemoteAddr().String()
+	}
+
+	var handle io.Reader = &idleConn{Conn: conn, timeout: t.idleTimeout}
+	rdr, err := reader.NewLines(
+		func() (io.Reader, error) {
+			// Swap so this only works once since a connection can only be
+			// consumed once.
+			if handle == nil {
+				return nil, io.EOF
+			}
+			sendHandle := handle
+			handle = nil
+			return sendHandle, nil
+		},
+		func() {
+			conn.Close()
+		},
+		reader.OptLinesSetDelimiter(t.delim),
+		reader.OptLinesSetMaxBuffer(t.conf.MaxBuffer),
+		reader.OptLinesSetMultipart(t.conf.Multipart),
+	)
+	if err != nil {
+		return err
+	}
+
+	input, err := NewReader("tcp_server", reader.NewPreserver(&connMetaReader{
+		Type:    rdr,
+		address: address,
+		port:    port,
+	}), t.log, t.stats)
+	if err != nil {
+		return err
+	}
+
+	t.connsMut.Lock()
+	t.conns[input] = struct{}{}
+	t.connsMut.Unlock()
+
+	t.mConnOpened.Incr(1)
+	t.mConnActive.Incr(1)
+	t.connsWG.Add(1)
+
+	go func() {
+		defer func() {
+			conn.Close()
+			t.connsMut.Lock()
+			delete(t.conns, input)
+			t.connsMut.Unlock()
+
+			t.mConnClosed.Incr(1)
+			t.mConnActive.Decr(1)
+			t.connsWG.Done()
+		}()
+		for tran := range input.TransactionChan() {
+			select {
+			case t.transactions <- tran:
+			case <-t.closeChan:
+				input.CloseAsync()
+				for range input.TransactionChan() {
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (t *TCPServer) loop() {
+	mRunning := t.stats.GetGauge("running")
+
+	defer func() {
+		atomic.StoreInt32(&t.running, 0)
+
+		t.connsMut.Lock()
+		for c := range t.conns {
+			c.CloseAsync()
+		}
+		t.connsMut.Unlock()
+		t.connsWG.Wait()
+
+		mRunning.Decr(1)
+
+		close(t.transactions)
+		close(t.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	go func() {
+		<-t.closeChan
+		t.listener.Close()
+	}()
+
+	t.log.Infof("Receiving TCP messages at: %v\n", t.listener.Addr())
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			select {
+			case <-t.closeChan:
+				return
+			default:
+			}
+			t.log.Errorf("Failed to accept connection: %v\n", err)
+			select {
+			case <-time.After(time.Second):
+			case <-t.closeChan:
+				return
+			}
+			continue
+		}
+
+		t.connsMut.Lock()
+		active := len(t.conns)
+		t.connsMut.Unlock()
+
+		if t.conf.MaxConnections > 0 && active >= t.conf.MaxConnections {
+			t.log.Warnf(
+				"Rejecting connection from %v as the maximum of %v connections has been reached\n",
+				conn.RemoteAddr(), t.conf.MaxConnections,
+			)
+			t.mConnRejected.Incr(1)
+			conn.Close()
+			continue
+		}
+
+		if err = t.addConn(conn); err != nil {
+			t.log.Errorf("Failed to consume connection: %v\n", err)
+			conn.Close()
+		}
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input.
+func (t *TCPServer) TransactionChan() <-chan types.Transaction {
+	return t.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (t *TCPServer) Connected() bool {
+	return true
+}
+
+// CloseAsync shuts down the TCPServer input and stops processing requests.
+func (t *TCPServer) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&t.running, 1, 0) {
+		close(t.closeChan)
+	}
+}
+
+// WaitForClose blocks until the TCPServer input has closed down.
+func (t *TCPServer) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-t.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
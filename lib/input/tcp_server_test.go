@@ -0,0 +1,186 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestTCPServerBasic(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.TCPServer.Address = "localhost:1251"
+
+	rdr, err := NewTCPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		rdr.CloseAsync()
+		if err := rdr.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:1251")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("foo\nbar\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"foo", "bar"} {
+		var tran types.Transaction
+		select {
+		case tran = <-rdr.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		if act := string(tran.Payload.Get(0).Get()); act != exp {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		meta := tran.Payload.Get(0).Metadata()
+		if act := meta.Get("tcp_remote_address"); act != "127.0.0.1" {
+			t.Errorf("Wrong remote address: %v", act)
+		}
+		if act, exp := meta.Get("tcp_remote_port"), conn.LocalAddr().(*net.TCPAddr).Port; act == "" || act != strconv.Itoa(exp) {
+			t.Errorf("Wrong remote port: %v != %v", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestTCPServerMaxConnections(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.TCPServer.Address = "localhost:1252"
+	conf.TCPServer.MaxConnections = 1
+
+	rdr, err := NewTCPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		rdr.CloseAsync()
+		if err := rdr.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	connA, err := net.Dial("tcp", "localhost:1252")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connA.Close()
+
+	if _, err = connA.Write([]byte("foo\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-rdr.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if act := string(tran.Payload.Get(0).Get()); act != "foo" {
+		t.Errorf("Wrong message: %v", act)
+	}
+
+	connB, err := net.Dial("tcp", "localhost:1252")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connB.Close()
+
+	connB.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = connB.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected rejected connection to be closed")
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestTCPServerIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.TCPServer.Address = "localhost:1253"
+	conf.TCPServer.IdleTimeout = "100ms"
+
+	rdr, err := NewTCPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		rdr.CloseAsync()
+		if err := rdr.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:1253")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = conn.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected idle connection to be closed")
+	} else if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		t.Error("Expected idle connection to be closed before deadline")
+	}
+}
+
+func TestTCPServerBadTLS(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.TCPServer.Address = "localhost:1254"
+	conf.TCPServer.CertFile = "/does/not/exist.pem"
+	conf.TCPServer.KeyFile = "/does/not/exist.key"
+
+	if _, err := NewTCPServer(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing key pair")
+	}
+}
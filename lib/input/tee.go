@@ -0,0 +1,343 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeTee] = TypeSpec{
+		constructor: NewTee,
+		description: `
+Reads from a child input and mirrors a copy of each message batch to a
+secondary output, before the batch continues through the pipeline as normal.
+This can be used in order to send production traffic to a staging pipeline or a
+data lake without affecting the main path.
+
+Mirroring is fire-and-forget: batches are copied into a buffer of up to
+` + "`buffer_size`" + ` batches, and when the buffer is full (e.g. when the
+secondary output is slow or disconnected) copies are dropped rather than
+applying back pressure. Errors from the secondary output are also ignored, and
+acknowledgements to the child input are determined only by the main path.
+
+` + "``` yaml" + `
+input:
+  tee:
+    buffer_size: 100
+    input:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: events
+    output:
+      http_client:
+        url: http://staging:4195/post
+` + "```" + ``,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			var inputSanit interface{} = struct{}{}
+			if conf.Tee.Input != nil {
+				if inputSanit, err = SanitiseConfig(*conf.Tee.Input); err != nil {
+					return nil, err
+				}
+			}
+			var outputSanit interface{} = struct{}{}
+			if conf.Tee.Output != nil {
+				if outputSanit, err = output.SanitiseConfig(*conf.Tee.Output); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"buffer_size": conf.Tee.BufferSize,
+				"input":       inputSanit,
+				"output":      outputSanit,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TeeConfig contains configuration values for the Tee input type.
+type TeeConfig struct {
+	BufferSize int            `json:"buffer_size" yaml:"buffer_size"`
+	Input      *Config        `json:"input" yaml:"input"`
+	Output     *output.Config `json:"output" yaml:"output"`
+}
+
+// NewTeeConfig creates a new TeeConfig with default values.
+func NewTeeConfig() TeeConfig {
+	return TeeConfig{
+		BufferSize: 100,
+		Input:      nil,
+		Output:     nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyTeeConfig struct {
+	BufferSize int         `json:"buffer_size" yaml:"buffer_size"`
+	Input      interface{} `json:"input" yaml:"input"`
+	Output     interface{} `json:"output" yaml:"output"`
+}
+
+func (t TeeConfig) dummy() dummyTeeConfig {
+	dummy := dummyTeeConfig{
+		BufferSize: t.BufferSize,
+		Input:      t.Input,
+		Output:     t.Output,
+	}
+	if t.Input == nil {
+		dummy.Input = struct{}{}
+	}
+	if t.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints empty objects instead of nil.
+func (t TeeConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.dummy())
+}
+
+// MarshalYAML prints empty objects instead of nil.
+func (t TeeConfig) MarshalYAML() (interface{}, error) {
+	return t.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// Tee is an input type that reads from a child input and mirrors a copy of
+// each message to a secondary output.
+type Tee struct {
+	running int32
+
+	wrapped Type
+	mirror  output.Type
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactions chan types.Transaction
+	mirrorBuffer chan types.Message
+	mirrorTrans  chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewTee creates a new Tee input type.
+func NewTee(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.Tee.Input == nil {
+		return nil, errors.New("cannot create tee input without a child input")
+	}
+	if conf.Tee.Output == nil {
+		return nil, errors.New("cannot create tee input without a mirror output")
+	}
+	if conf.Tee.BufferSize < 1 {
+		return nil, errors.New("buffer size must be greater than zero")
+	}
+
+	wrapped, err := New(*conf.Tee.Input, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input '%v': %v", conf.Tee.Input.Type, err)
+	}
+
+	var mirror output.Type
+	if mirror, err = output.New(
+		*conf.Tee.Output, mgr,
+		log.NewModule(".tee.output"), metrics.Namespaced(stats, "tee.output"),
+	); err != nil {
+		wrapped.CloseAsync()
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.Tee.Output.Type, err)
+	}
+
+	t := &Tee{
+		running: 1,
+
+		wrapped: wrapped,
+		mirror:  mirror,
+
+		log:   log.NewModule(".tee"),
+		stats: metrics.Namespaced(stats, "tee"),
+
+		transactions: make(chan types.Transaction),
+		mirrorBuffer: make(chan types.Message, conf.Tee.BufferSize),
+		mirrorTrans:  make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	if err = mirror.Consume(t.mirrorTrans); err != nil {
+		wrapped.CloseAsync()
+		return nil, err
+	}
+
+	mirrorDone := make(chan struct{})
+	go t.mirrorLoop(mirrorDone)
+	go t.loop(mirrorDone)
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+// mirrorLoop writes buffered copies of messages to the mirror output until
+// either the buffer is closed and drained or the input is closed.
+func (t *Tee) mirrorLoop(done chan<- struct{}) {
+	var (
+		mSent = t.stats.GetCounter("mirror.sent")
+		mErr  = t.stats.GetCounter("mirror.error")
+	)
+
+	defer close(done)
+
+	resChan := make(chan types.Response)
+	for {
+		var msg types.Message
+		var open bool
+		select {
+		case msg, open = <-t.mirrorBuffer:
+			if !open {
+				return
+			}
+		case <-t.closeChan:
+			return
+		}
+
+		select {
+		case t.mirrorTrans <- types.NewTransaction(msg, resChan):
+		case <-t.closeChan:
+			return
+		}
+
+		select {
+		case res := <-resChan:
+			if err := res.Error(); err != nil {
+				mErr.Incr(1)
+				t.log.Debugf("Failed to mirror message: %v\n", err)
+			} else {
+				mSent.Incr(1)
+			}
+		case <-t.closeChan:
+			return
+		}
+	}
+}
+
+func (t *Tee) loop(mirrorDone <-chan struct{}) {
+	var (
+		mCount   = t.stats.GetCounter("count")
+		mDropped = t.stats.GetCounter("mirror.dropped")
+	)
+
+	defer func() {
+		t.wrapped.CloseAsync()
+		for err := t.wrapped.WaitForClose(time.Second); err != nil; err = t.wrapped.WaitForClose(time.Second) {
+		}
+
+		// Allow the mirror to drain its buffer when our child input closes
+		// naturally.
+		close(t.mirrorBuffer)
+		<-mirrorDone
+		close(t.mirrorTrans)
+		t.mirror.CloseAsync()
+		for err := t.mirror.WaitForClose(time.Second); err != nil; err = t.mirror.WaitForClose(time.Second) {
+		}
+
+		close(t.transactions)
+		close(t.closedChan)
+	}()
+
+	for atomic.LoadInt32(&t.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-t.wrapped.TransactionChan():
+			if !open {
+				return
+			}
+		case <-t.closeChan:
+			return
+		}
+		mCount.Incr(1)
+
+		select {
+		case t.mirrorBuffer <- tran.Payload.DeepCopy():
+		default:
+			mDropped.Incr(1)
+		}
+
+		select {
+		case t.transactions <- tran:
+		case <-t.closeChan:
+			return
+		}
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (t *Tee) TransactionChan() <-chan types.Transaction {
+	return t.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (t *Tee) Connected() bool {
+	return t.wrapped.Connected()
+}
+
+// CloseAsync shuts down the Tee input and stops processing requests.
+func (t *Tee) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&t.running, 1, 0) {
+		close(t.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Tee input has closed down.
+func (t *Tee) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-t.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,130 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestTeeInput(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_tee_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	inPath := filepath.Join(tmpDir, "in.txt")
+	mirrorPath := filepath.Join(tmpDir, "mirror.txt")
+	if err = ioutil.WriteFile(inPath, []byte("foo\nbar\nbaz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inConf := NewConfig()
+	inConf.Type = TypeFile
+	inConf.File.Path = inPath
+
+	outConf := output.NewConfig()
+	outConf.Type = output.TypeFile
+	outConf.File.Path = mirrorPath
+
+	conf := NewConfig()
+	conf.Type = TypeTee
+	conf.Tee.Input = &inConf
+	conf.Tee.Output = &outConf
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"foo", "bar", "baz"} {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-in.TransactionChan():
+			if !open {
+				t.Fatal("transaction chan closed")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		if act := string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case _, open := <-in.TransactionChan():
+		if open {
+			t.Error("expected transaction chan to close")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	if err = in.WaitForClose(time.Second * 5); err != nil {
+		t.Fatal(err)
+	}
+
+	mirrored, err := ioutil.ReadFile(mirrorPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\nbaz\n", string(mirrored); exp != act {
+		t.Errorf("Wrong mirrored contents: %q != %q", act, exp)
+	}
+}
+
+func TestTeeInputBadConfig(t *testing.T) {
+	inConf := NewConfig()
+	outConf := output.NewConfig()
+
+	conf := NewConfig()
+	conf.Type = TypeTee
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from missing input")
+	}
+
+	conf.Tee.Input = &inConf
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from missing output")
+	}
+
+	conf.Tee.Output = &outConf
+	conf.Tee.BufferSize = 0
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from zero buffer size")
+	}
+}
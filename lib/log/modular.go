@@ -28,6 +28,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	yaml "gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -95,11 +97,13 @@ func logLevelToInt(level string) int {
 
 // Config holds configuration options for a logger object.
 type Config struct {
+	Type         string            `json:"type,omitempty" yaml:"type,omitempty"`
 	Prefix       string            `json:"prefix" yaml:"prefix"`
 	LogLevel     string            `json:"level" yaml:"level"`
 	AddTimeStamp bool              `json:"add_timestamp" yaml:"add_timestamp"`
 	JSONFormat   bool              `json:"json_format" yaml:"json_format"`
 	StaticFields map[string]string `json:"static_fields" yaml:"static_fields"`
+	Plugin       interface{}       `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 }
 
 // NewConfig returns a config struct with the default values for each field.
@@ -133,6 +137,11 @@ func (l *Config) UnmarshalJSON(bytes []byte) error {
 		aliased.StaticFields = defaultFields
 	}
 
+	var err error
+	if aliased.Plugin, err = pluginConfig(aliased.Type, aliased.Plugin, json.Marshal, json.Unmarshal); err != nil {
+		return err
+	}
+
 	*l = Config(aliased)
 	return nil
 }
@@ -154,6 +163,11 @@ func (l *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		aliased.StaticFields = defaultFields
 	}
 
+	var err error
+	if aliased.Plugin, err = pluginConfig(aliased.Type, aliased.Plugin, yaml.Marshal, yaml.Unmarshal); err != nil {
+		return err
+	}
+
 	*l = Config(aliased)
 	return nil
 }
@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+type mockPluginConf struct {
+	Foo string `json:"foo" yaml:"foo"`
+	Bar string `json:"bar" yaml:"bar"`
+}
+
+func newMockPluginConf() interface{} {
+	return &mockPluginConf{
+		Foo: "default",
+		Bar: "change this",
+	}
+}
+
+func TestYAMLPlugin(t *testing.T) {
+	RegisterPlugin("foo", newMockPluginConf,
+		func(conf interface{}, common Config) (Modular, error) {
+			mConf, ok := conf.(*mockPluginConf)
+			if !ok {
+				t.Fatalf("failed to cast config: %T", conf)
+			}
+			if exp, act := "default", mConf.Foo; exp != act {
+				t.Errorf("Wrong config value: %v != %v", act, exp)
+			}
+			if exp, act := "custom", mConf.Bar; exp != act {
+				t.Errorf("Wrong config value: %v != %v", act, exp)
+			}
+			if exp, act := "DEBUG", common.LogLevel; exp != act {
+				t.Errorf("Wrong log level: %v != %v", act, exp)
+			}
+			return Noop(), nil
+		})
+
+	confStr := `type: foo
+level: DEBUG
+plugin:
+  bar: custom`
+
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(confStr), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFromConfig(nil, conf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONPluginError(t *testing.T) {
+	errTest := errors.New("test err")
+
+	RegisterPlugin("foo", newMockPluginConf,
+		func(conf interface{}, common Config) (Modular, error) {
+			return nil, errTest
+		})
+
+	conf := NewConfig()
+	if err := json.Unmarshal([]byte(`{"type":"foo","plugin":{"bar":"custom"}}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "custom", conf.Plugin.(*mockPluginConf).Bar; exp != act {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+
+	_, err := NewFromConfig(nil, conf)
+	if err == nil || !strings.Contains(err.Error(), "test err") {
+		t.Errorf("Wrong error returned: %v != %v", err, errTest)
+	}
+}
+
+func TestNewFromConfigDefault(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	conf := NewConfig()
+	conf.JSONFormat = false
+	conf.AddTimeStamp = false
+
+	logger, err := NewFromConfig(buf, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Infoln("hello world")
+	if exp, act := "INFO | benthos | hello world\n", buf.String(); exp != act {
+		t.Errorf("Wrong log output: %v != %v", act, exp)
+	}
+
+	conf.Type = "does_not_exist"
+	if _, err = NewFromConfig(buf, conf); err == nil {
+		t.Error("Expected error from unknown logger type")
+	}
+}
+
+func TestPluginDescriptions(t *testing.T) {
+	RegisterPlugin("foo", newMockPluginConf, nil)
+	DocumentPlugin("foo", "This is a foo plugin.", nil)
+
+	exp := `Logger Plugins
+==============
+
+This document was generated with ` + "`benthos --list-logger-plugins`" + `.
+
+This document lists any logger plugins that this flavour of Benthos offers as
+alternatives to the standard logger.
+
+### Contents
+
+1. [` + "`foo`" + `](#foo)
+
+## ` + "`foo`" + `
+
+` + "``` yaml" + `
+type: foo
+plugin:
+  bar: change this
+  foo: default
+` + "```" + `
+
+This is a foo plugin.
+`
+
+	act := PluginDescriptions()
+	if exp != act {
+		t.Logf("Expected:\n%v\n", exp)
+		t.Logf("Actual:\n%v\n", act)
+		t.Error("Wrong descriptions")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package drop
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Hook is a function called with a message that has been intentionally dropped
+// by a component, along with the reason for the drop, which is usually the
+// type of the component responsible.
+//
+// The message must not be modified by the hook and should be copied if it is
+// retained after the hook returns.
+type Hook func(reason string, msg types.Message)
+
+// hookEntry wraps a hook so that it can be identified for removal, since
+// functions are not comparable.
+type hookEntry struct {
+	h Hook
+}
+
+var (
+	hooks   = map[types.Manager][]*hookEntry{}
+	hookMut sync.RWMutex
+)
+
+// hookKey returns the manager that hooks of a given manager are registered
+// under, which is the underlying manager of wrappers such as the namespaced
+// managers of streams. Managers that cannot be used as a key have no hooks.
+func hookKey(mgr types.Manager) (types.Manager, bool) {
+	for mgr != nil {
+		u, ok := mgr.(interface {
+			GetUnderlying() types.Manager
+		})
+		if !ok {
+			break
+		}
+		mgr = u.GetUnderlying()
+	}
+	if mgr == nil || !reflect.TypeOf(mgr).Comparable() {
+		return nil, false
+	}
+	return mgr, true
+}
+
+// AddHook registers a hook to be called for each message dropped by components
+// of a manager and returns a function that removes it again.
+func AddHook(mgr types.Manager, h Hook) func() {
+	key, ok := hookKey(mgr)
+	if !ok {
+		return func() {}
+	}
+	e := &hookEntry{h: h}
+
+	hookMut.Lock()
+	hooks[key] = append(hooks[key], e)
+	hookMut.Unlock()
+
+	return func() {
+		hookMut.Lock()
+		defer hookMut.Unlock()
+		for i, v := range hooks[key] {
+			if v == e {
+				if remaining := append(hooks[key][:i:i], hooks[key][i+1:]...); len(remaining) > 0 {
+					hooks[key] = remaining
+				} else {
+					delete(hooks, key)
+				}
+				return
+			}
+		}
+	}
+}
+
+// Report notifies each hook registered with the manager of a component that a
+// message has been dropped by it. The hooks are called after releasing the lock
+// on the hook list.
+func Report(mgr types.Manager, reason string, msg types.Message) {
+	if msg == nil || msg.Len() == 0 {
+		return
+	}
+	key, ok := hookKey(mgr)
+	if !ok {
+		return
+	}
+
+	hookMut.RLock()
+	current := make([]*hookEntry, len(hooks[key]))
+	copy(current, hooks[key])
+	hookMut.RUnlock()
+
+	for _, e := range current {
+		e.h(reason, msg)
+	}
+}
+
+//------------------------------------------------------------------------------
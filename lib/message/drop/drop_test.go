@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package drop
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestReport(t *testing.T) {
+	mgr := types.DudMgr{ID: 1}
+
+	// Reporting without a hook does nothing.
+	Report(mgr, "foo", message.New([][]byte{[]byte("foo")}))
+
+	var reasons []string
+	remove := AddHook(mgr, func(reason string, msg types.Message) {
+		reasons = append(reasons, reason+":"+string(msg.Get(0).Get()))
+	})
+
+	Report(mgr, "foo", message.New([][]byte{[]byte("bar")}))
+	Report(mgr, "baz", message.New(nil))
+	Report(mgr, "baz", nil)
+	Report(types.DudMgr{ID: 2}, "quz", message.New([][]byte{[]byte("quz")}))
+
+	if exp, act := []string{"foo:bar"}, reasons; len(act) != 1 || act[0] != exp[0] {
+		t.Errorf("Wrong reports: %v != %v", act, exp)
+	}
+
+	remove()
+	Report(mgr, "foo", message.New([][]byte{[]byte("qux")}))
+	if exp, act := 1, len(reasons); exp != act {
+		t.Errorf("Wrong count of reports after removal: %v != %v", act, exp)
+	}
+}
+
+func TestReportHookCallsReport(t *testing.T) {
+	// A hook that reports a drop itself must not deadlock on the hook list.
+	mgr := types.DudMgr{ID: 3}
+
+	var calls int
+	var remove func()
+	remove = AddHook(mgr, func(reason string, msg types.Message) {
+		if calls++; calls == 1 {
+			remove()
+			Report(mgr, "nested", msg)
+		}
+	})
+	defer remove()
+
+	Report(mgr, "foo", message.New([][]byte{[]byte("bar")}))
+	if exp, act := 1, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+type wrappedMgr struct {
+	types.Manager
+}
+
+func (w wrappedMgr) GetUnderlying() types.Manager {
+	return w.Manager
+}
+
+func TestReportWrappedManager(t *testing.T) {
+	mgr := types.DudMgr{ID: 4}
+
+	var calls int
+	remove := AddHook(mgr, func(reason string, msg types.Message) {
+		calls++
+	})
+	defer remove()
+
+	Report(wrappedMgr{Manager: mgr}, "foo", message.New([][]byte{[]byte("bar")}))
+	Report(nil, "foo", message.New([][]byte{[]byte("bar")}))
+	if exp, act := 1, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
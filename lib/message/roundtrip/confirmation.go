@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package roundtrip
+
+import (
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Confirmation is a set of identifiers assigned to a message by the sink it
+// was written to, such as an offset or an object key.
+type Confirmation map[string]string
+
+// SetConfirmationsAsResponse stores a response message derived from a batch
+// that has been written to a sink. Each message of the response is a JSON
+// object of the confirmation at the same index, and the fields of the
+// confirmation are also added to its metadata. Messages without a
+// corresponding confirmation use the last one provided, which allows a single
+// confirmation to be shared by a whole batch.
+//
+// This action fails if the message does not contain a valid ResultStore within
+// its context.
+func SetConfirmationsAsResponse(msg types.Message, confs []Confirmation) error {
+	if msg.Len() == 0 || len(confs) == 0 {
+		return nil
+	}
+	resMsg := msg.Copy()
+	if err := resMsg.Iter(func(i int, p types.Part) error {
+		conf := confs[len(confs)-1]
+		if i < len(confs) {
+			conf = confs[i]
+		}
+		jObj := make(map[string]interface{}, len(conf))
+		for k, v := range conf {
+			jObj[k] = v
+			p.Metadata().Set(k, v)
+		}
+		return p.SetJSON(jObj)
+	}); err != nil {
+		return err
+	}
+	return SetAsResponse(resMsg)
+}
+
+//------------------------------------------------------------------------------
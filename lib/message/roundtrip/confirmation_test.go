@@ -0,0 +1,79 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package roundtrip
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestSetConfirmationsAsResponse(t *testing.T) {
+	impl := &resultStoreImpl{}
+	ctx := context.WithValue(context.Background(), ResultStoreKey, impl)
+
+	msg := message.New(nil)
+	var p types.Part = message.NewPart([]byte("foo"))
+	p = message.WithContext(ctx, p)
+	msg.Append(p)
+	msg.Append(message.NewPart([]byte("bar")))
+	msg.Append(message.NewPart([]byte("baz")))
+
+	if err := SetConfirmationsAsResponse(msg, []Confirmation{
+		{"offset": "1"},
+		{"offset": "2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	results := impl.Get()
+	if len(results) != 1 {
+		t.Fatalf("Wrong count of result batches: %v", len(results))
+	}
+	if results[0].Len() != 3 {
+		t.Fatalf("Wrong count of messages: %v", results[0].Len())
+	}
+	for i, exp := range []string{"1", "2", "2"} {
+		if act := results[0].Get(i).Metadata().Get("offset"); exp != act {
+			t.Errorf("Wrong metadata of message %v: %v != %v", i, act, exp)
+		}
+		if exp, act := `{"offset":"`+exp+`"}`, string(results[0].Get(i).Get()); exp != act {
+			t.Errorf("Wrong contents of message %v: %v != %v", i, act, exp)
+		}
+	}
+	if exp, act := "foo", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Original message was modified: %v != %v", act, exp)
+	}
+	if act := msg.Get(0).Metadata().Get("offset"); len(act) > 0 {
+		t.Errorf("Original metadata was modified: %v", act)
+	}
+}
+
+func TestSetConfirmationsAsResponseNoStore(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo")})
+	if err := SetConfirmationsAsResponse(msg, []Confirmation{
+		{"offset": "1"},
+	}); err != ErrNoStore {
+		t.Errorf("Wrong error: %v != %v", err, ErrNoStore)
+	}
+}
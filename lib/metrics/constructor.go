@@ -30,6 +30,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/util/config"
+	yaml "gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -79,6 +80,7 @@ type Config struct {
 	Rename     RenameConfig     `json:"rename" yaml:"rename"`
 	Statsd     StatsdConfig     `json:"statsd" yaml:"statsd"`
 	Whitelist  WhitelistConfig  `json:"whitelist" yaml:"whitelist"`
+	Plugin     interface{}      `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -92,6 +94,7 @@ func NewConfig() Config {
 		Rename:     NewRenameConfig(),
 		Statsd:     NewStatsdConfig(),
 		Whitelist:  NewWhitelistConfig(),
+		Plugin:     nil,
 	}
 }
 
@@ -116,9 +119,20 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 		if outputMap[t], err = sfunc(conf); err != nil {
 			return nil, err
 		}
-	} else {
+	} else if _, exists := hashMap[t]; exists {
 		outputMap[t] = hashMap[t]
 	}
+	if spec, exists := pluginSpecs[t]; exists {
+		var plugSanit interface{}
+		if spec.confSanitiser != nil {
+			plugSanit = spec.confSanitiser(conf.Plugin)
+		} else {
+			plugSanit = hashMap["plugin"]
+		}
+		if plugSanit != nil {
+			outputMap["plugin"] = plugSanit
+		}
+	}
 	outputMap["prefix"] = hashMap["prefix"]
 
 	return outputMap, nil
@@ -136,6 +150,21 @@ func (c *Config) UnmarshalJSON(bytes []byte) error {
 		return err
 	}
 
+	if spec, exists := pluginSpecs[aliased.Type]; exists && spec.confConstructor != nil {
+		confBytes, err := json.Marshal(aliased.Plugin)
+		if err != nil {
+			return err
+		}
+
+		conf := spec.confConstructor()
+		if err = json.Unmarshal(confBytes, conf); err != nil {
+			return err
+		}
+		aliased.Plugin = conf
+	} else {
+		aliased.Plugin = nil
+	}
+
 	*c = Config(aliased)
 	return nil
 }
@@ -170,6 +199,21 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		aliased.Type = inferredType
 	}
 
+	if spec, exists := pluginSpecs[aliased.Type]; exists && spec.confConstructor != nil {
+		confBytes, err := yaml.Marshal(aliased.Plugin)
+		if err != nil {
+			return err
+		}
+
+		conf := spec.confConstructor()
+		if err = yaml.Unmarshal(confBytes, conf); err != nil {
+			return err
+		}
+		aliased.Plugin = conf
+	} else {
+		aliased.Plugin = nil
+	}
+
 	*c = Config(aliased)
 	return nil
 }
@@ -256,6 +300,16 @@ func New(conf Config, opts ...func(Type)) (Type, error) {
 	if c, ok := Constructors[conf.Type]; ok {
 		return c.constructor(conf, opts...)
 	}
+	if c, ok := pluginSpecs[conf.Type]; ok {
+		t, err := c.constructor(conf.Plugin, conf.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metrics plugin '%v': %v", conf.Type, err)
+		}
+		for _, opt := range opts {
+			opt(t)
+		}
+		return t, nil
+	}
 	return nil, ErrInvalidMetricOutputType
 }
 
@@ -42,6 +42,27 @@ func Namespaced(t Type, ns string) Type {
 	}
 }
 
+// Namespace returns the full namespace that an aggregator created with
+// Namespaced writes metrics under, or an empty string if the aggregator is not
+// namespaced. When aggregators are combined the longest namespace of the two is
+// used, since brokers combine a parent aggregator with a namespaced child.
+func Namespace(t Type) string {
+	switch v := t.(type) {
+	case namespacedWrapper:
+		if parent := Namespace(v.t); len(parent) > 0 {
+			return parent + "." + v.ns
+		}
+		return v.ns
+	case *combinedWrapper:
+		ns1, ns2 := Namespace(v.t1), Namespace(v.t2)
+		if len(ns2) > len(ns1) {
+			return ns2
+		}
+		return ns1
+	}
+	return ""
+}
+
 //------------------------------------------------------------------------------
 
 func (d namespacedWrapper) GetCounter(path string) StatCounter {
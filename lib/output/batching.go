@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/condition"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/pipeline"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// batchingEnabled returns true if a batching policy would result in messages
+// being accumulated before reaching an output.
+func batchingEnabled(conf processor.BatchConfig) bool {
+	return conf.Count > 1 ||
+		conf.ByteSize > 0 ||
+		len(conf.Period) > 0 ||
+		conf.Condition.Type != condition.TypeStatic ||
+		conf.Condition.Static
+}
+
+// sanitiseBatching returns a sanitised version of a batching policy.
+func sanitiseBatching(conf processor.BatchConfig) (interface{}, error) {
+	condSanit, err := condition.SanitiseConfig(conf.Condition)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"byte_size": conf.ByteSize,
+		"count":     conf.Count,
+		"condition": condSanit,
+		"period":    conf.Period,
+	}, nil
+}
+
+// newBatchingPipeline returns a pipeline constructor that accumulates messages
+// according to a batching policy before they reach an output.
+func newBatchingPipeline(
+	conf processor.BatchConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) types.PipelineConstructorFunc {
+	return func(i *int) (types.Pipeline, error) {
+		batchConf := processor.NewConfig()
+		batchConf.Type = processor.TypeBatch
+		batchConf.Batch = conf
+
+		batchLog := log.NewModule(".batching")
+		batchStats := metrics.Namespaced(stats, "batching")
+		batcher, err := processor.New(batchConf, mgr, batchLog, batchStats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create batching policy: %v", err)
+		}
+		return pipeline.NewProcessor(batchLog, batchStats, batcher), nil
+	}
+}
+
+//------------------------------------------------------------------------------
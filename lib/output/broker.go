@@ -85,6 +85,28 @@ Similar to the fan out pattern except outputs are written to sequentially,
 meaning an output is only written to once the preceding output has confirmed
 receipt of the same message.
 
+#### ` + "`fan_out_transactional`" + `
+
+Similar to the fan out pattern except each message is written using a two-phase
+commit. Every output is first asked to stage the message, and only once all
+outputs have staged it successfully is it committed to each of them. If any
+output fails to stage the message then it is aborted by all outputs and the
+whole attempt is retried.
+
+A commit can still fail after the message was staged by all outputs, for
+example if a database connection is lost. Since a failed commit cannot be
+retried, the writes staged by the remaining outputs are aborted and the message
+is reattempted in full, which means the outputs that committed before the
+failure receive it twice. These events are logged and counted by the metric
+` + "`transaction.inconsistent`" + `.
+
+This pattern is intended for pipelines with strict consistency requirements
+across multiple sinks. Every output of the broker must support staging messages,
+otherwise the broker fails to start. Currently the ` + "`sql`" + ` output is the
+only output that supports staging messages, and it can be wrapped within a
+` + "`retry`" + ` output. Outputs with processors or batching policies are not
+able to stage messages as their batches might be split or merged.
+
 #### ` + "`round_robin`" + `
 
 With the round robin pattern each message will be assigned a single output
@@ -105,13 +127,36 @@ outputs.
 
 The try pattern attempts to send each message to only one output, starting from
 the first output on the list. If an output attempt fails then the broker
-attempts to send to the next output in the list and so on.
+attempts to send to the next output in the list and so on. The pattern
+` + "`fallback`" + ` is an alias of ` + "`try`" + `.
 
 This pattern is useful for triggering events in the case where certain output
 targets have broken. For example, if you had an output type ` + "`http_client`" + `
 but wished to reroute messages whenever the endpoint becomes unreachable you
 could use a try broker.
 
+By default each output is attempted once before moving on to the next. The
+field ` + "`retries`" + ` can be used in order to set a number of retries per
+output, where the value at each index applies to the output at the same index.
+Retries back off exponentially from 100ms up to a maximum of one second between
+attempts:
+
+` + "``` yaml" + `
+output:
+  broker:
+    pattern: fallback
+    retries: [ 3, 1 ]
+    outputs:
+    - http_client:
+        url: http://primary:4195/post
+    - http_client:
+        url: http://secondary:4195/post
+    - file:
+        path: /tmp/dead_letters.txt
+` + "```" + `
+
+The field ` + "`retries`" + ` is ignored by all other patterns.
+
 ### Utilising More Outputs
 
 When using brokered outputs with patterns such as round robin or greedy it is
@@ -139,6 +184,7 @@ nodes processors.`,
 			return map[string]interface{}{
 				"copies":  conf.Broker.Copies,
 				"pattern": conf.Broker.Pattern,
+				"retries": conf.Broker.Retries,
 				"outputs": outSlice,
 			}, nil
 		},
@@ -151,6 +197,7 @@ nodes processors.`,
 type BrokerConfig struct {
 	Copies  int              `json:"copies" yaml:"copies"`
 	Pattern string           `json:"pattern" yaml:"pattern"`
+	Retries []int            `json:"retries" yaml:"retries"`
 	Outputs brokerOutputList `json:"outputs" yaml:"outputs"`
 }
 
@@ -159,6 +206,7 @@ func NewBrokerConfig() BrokerConfig {
 	return BrokerConfig{
 		Copies:  1,
 		Pattern: "fan_out",
+		Retries: []int{},
 		Outputs: brokerOutputList{},
 	}
 }
@@ -176,6 +224,14 @@ func NewBroker(
 ) (Type, error) {
 	outputConfs := conf.Broker.Outputs
 
+	switch conf.Broker.Pattern {
+	case "try", "fallback":
+		var err error
+		if outputConfs, err = withTryRetries(outputConfs, conf.Broker.Retries); err != nil {
+			return nil, err
+		}
+	}
+
 	lOutputs := len(outputConfs) * conf.Broker.Copies
 
 	if lOutputs <= 0 {
@@ -217,11 +273,13 @@ func NewBroker(
 		b, err = broker.NewFanOut(outputs, log, stats)
 	case "fan_out_sequential":
 		b, err = broker.NewFanOutSequential(outputs, log, stats)
+	case "fan_out_transactional":
+		b, err = broker.NewFanOutTransactional(outputs, log, stats)
 	case "round_robin":
 		b, err = broker.NewRoundRobin(outputs, stats)
 	case "greedy":
 		b, err = broker.NewGreedy(outputs)
-	case "try":
+	case "try", "fallback":
 		b, err = broker.NewTry(outputs, stats)
 	default:
 		return nil, fmt.Errorf("broker pattern was not recognised: %v", conf.Broker.Pattern)
@@ -233,3 +291,36 @@ func NewBroker(
 }
 
 //------------------------------------------------------------------------------
+
+// withTryRetries wraps each output config within a retry output when a retry
+// limit has been specified for its index.
+func withTryRetries(confs []Config, limits []int) ([]Config, error) {
+	if len(limits) > len(confs) {
+		return nil, fmt.Errorf("number of retry limits (%v) exceeds the number of outputs (%v)", len(limits), len(confs))
+	}
+	wrapped := make([]Config, len(confs))
+	copy(wrapped, confs)
+	for i, limit := range limits {
+		if limit < 0 {
+			return nil, fmt.Errorf("retry limit of output '%v' must not be negative: %v", i, limit)
+		}
+		if limit == 0 {
+			continue
+		}
+		child := confs[i]
+
+		rConf := NewConfig()
+		rConf.Type = TypeRetry
+		rConf.Processors = child.Processors
+		rConf.Retry.Output = &child
+		rConf.Retry.Output.Processors = nil
+		rConf.Retry.MaxRetries = uint64(limit)
+		rConf.Retry.Backoff.InitialInterval = "100ms"
+		rConf.Retry.Backoff.MaxInterval = "1s"
+		rConf.Retry.Backoff.MaxElapsedTime = "0s"
+		wrapped[i] = rConf
+	}
+	return wrapped, nil
+}
+
+//------------------------------------------------------------------------------
@@ -331,6 +331,89 @@ func TestFanOutBroker(t *testing.T) {
 	}
 }
 
+func TestFanOutBrokerBatching(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_fan_out_broker_batching_tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outOne, outTwo := NewConfig(), NewConfig()
+	outOne.Type, outTwo.Type = TypeFiles, TypeFiles
+	outOne.Files.Path = filepath.Join(dir, "one", "${!content}.txt")
+	outTwo.Files.Path = filepath.Join(dir, "two", "${!content}.txt")
+	outTwo.Batching.Count = 2
+
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "fan_out"
+	conf.Broker.Outputs = append(conf.Broker.Outputs, outOne)
+	conf.Broker.Outputs = append(conf.Broker.Outputs, outTwo)
+
+	s, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(sendChan); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	send := func(content string) types.Response {
+		t.Helper()
+		select {
+		case sendChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+			return res
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		return nil
+	}
+
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(dir, path))
+		return err == nil
+	}
+
+	if res := send("first"); !res.SkipAck() {
+		t.Error("Expected first message to be held by batching policy")
+	}
+	if !exists("one/first.txt") {
+		t.Error("Expected unbatched output to write first message")
+	}
+	if exists("two/first.txt") {
+		t.Error("Expected batched output to hold first message")
+	}
+
+	if res := send("second"); res.SkipAck() {
+		t.Error("Expected second message to flush batch")
+	}
+	for _, path := range []string{
+		"one/second.txt", "two/first.txt", "two/second.txt",
+	} {
+		if !exists(path) {
+			t.Errorf("Expected file '%v' to exist", path)
+		}
+	}
+}
+
 func TestRoundRobinBroker(t *testing.T) {
 	dir, err := ioutil.TempDir("", "benthos_round_robin_broker_tests")
 	if err != nil {
@@ -606,3 +689,110 @@ func TestTryBroker(t *testing.T) {
 		}
 	}
 }
+
+func TestFallbackBrokerRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_fallback_broker_tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outOne, outTwo := NewConfig(), NewConfig()
+	outOne.Type, outTwo.Type = TypeHTTPClient, TypeFiles
+	outOne.HTTPClient.URL = "http://localhost:11111111/badurl"
+	outOne.HTTPClient.NumRetries = 1
+	outOne.HTTPClient.Retry = "1ms"
+	outTwo.Files.Path = filepath.Join(dir, "two", "bar-${!count:ffoo}-${!count:fbar}.txt")
+
+	procOne, procTwo := processor.NewConfig(), processor.NewConfig()
+	procOne.Type, procTwo.Type = processor.TypeText, processor.TypeText
+	procOne.Text.Operator = "prepend"
+	procOne.Text.Value = "this-should-never-appear ${!count:ffoo}"
+	procTwo.Text.Operator = "prepend"
+	procTwo.Text.Value = "two-"
+
+	outOne.Processors = append(outOne.Processors, procOne)
+	outTwo.Processors = append(outTwo.Processors, procTwo)
+
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "fallback"
+	conf.Broker.Retries = []int{2}
+	conf.Broker.Outputs = append(conf.Broker.Outputs, outOne)
+	conf.Broker.Outputs = append(conf.Broker.Outputs, outTwo)
+
+	s, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(sendChan); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	inputs := []string{
+		"first", "second",
+	}
+
+	// Processors of a retried output are only applied once per message.
+	expFiles := map[string]string{
+		"./two/bar-2-1.txt": "two-first",
+		"./two/bar-4-2.txt": "two-second",
+	}
+
+	for _, input := range inputs {
+		testMsg := message.New([][]byte{[]byte(input)})
+		select {
+		case sendChan <- types.NewTransaction(testMsg, resChan):
+		case <-time.After(time.Second * 2):
+			t.Fatal("Action timed out")
+		}
+
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	for k, exp := range expFiles {
+		k = filepath.Join(dir, k)
+		fileBytes, err := ioutil.ReadFile(k)
+		if err != nil {
+			t.Errorf("Expected file '%v' could not be read: %v", k, err)
+			continue
+		}
+		if act := string(fileBytes); exp != act {
+			t.Errorf("Wrong contents for file '%v': %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestTryBrokerBadRetries(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "try"
+	conf.Broker.Outputs = append(conf.Broker.Outputs, NewConfig(), NewConfig())
+
+	conf.Broker.Retries = []int{1, 2, 3}
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from too many retry limits")
+	}
+
+	conf.Broker.Retries = []int{-1}
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from negative retry limit")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -63,6 +64,7 @@ const (
 	TypeAMQP          = "amqp"
 	TypeBroker        = "broker"
 	TypeCache         = "cache"
+	TypeDeadLetter    = "dead_letter"
 	TypeDrop          = "drop"
 	TypeDropOnError   = "drop_on_error"
 	TypeDynamic       = "dynamic"
@@ -70,10 +72,14 @@ const (
 	TypeElasticsearch = "elasticsearch"
 	TypeFile          = "file"
 	TypeFiles         = "files"
+	TypeGCPBigQuery   = "gcp_bigquery"
 	TypeGCPPubSub     = "gcp_pubsub"
+	TypeGoogleSheets  = "google_sheets"
+	TypeGraphite      = "graphite"
 	TypeHDFS          = "hdfs"
 	TypeHTTPClient    = "http_client"
 	TypeHTTPServer    = "http_server"
+	TypeInfluxDB      = "influxdb"
 	TypeInproc        = "inproc"
 	TypeKafka         = "kafka"
 	TypeKinesis       = "kinesis"
@@ -85,8 +91,14 @@ const (
 	TypeRedisList     = "redis_list"
 	TypeRedisPubSub   = "redis_pubsub"
 	TypeRedisStreams  = "redis_streams"
+	TypeReject        = "reject"
 	TypeRetry         = "retry"
 	TypeS3            = "s3"
+	TypeSharded       = "sharded"
+	TypeSnowflake     = "snowflake"
+	TypeSocket        = "socket"
+	TypeSQL           = "sql"
+	TypeSQLite        = "sqlite"
 	TypeSQS           = "sqs"
 	TypeSTDOUT        = "stdout"
 	TypeSwitch        = "switch"
@@ -103,6 +115,7 @@ type Config struct {
 	AMQP          writer.AMQPConfig          `json:"amqp" yaml:"amqp"`
 	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
 	Cache         writer.CacheConfig         `json:"cache" yaml:"cache"`
+	DeadLetter    DeadLetterConfig           `json:"dead_letter" yaml:"dead_letter"`
 	Drop          writer.DropConfig          `json:"drop" yaml:"drop"`
 	DropOnError   DropOnErrorConfig          `json:"drop_on_error" yaml:"drop_on_error"`
 	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
@@ -110,10 +123,14 @@ type Config struct {
 	Elasticsearch writer.ElasticsearchConfig `json:"elasticsearch" yaml:"elasticsearch"`
 	File          FileConfig                 `json:"file" yaml:"file"`
 	Files         writer.FilesConfig         `json:"files" yaml:"files"`
+	GCPBigQuery   writer.GCPBigQueryConfig   `json:"gcp_bigquery" yaml:"gcp_bigquery"`
 	GCPPubSub     writer.GCPPubSubConfig     `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	GoogleSheets  writer.GoogleSheetsConfig  `json:"google_sheets" yaml:"google_sheets"`
+	Graphite      writer.GraphiteConfig      `json:"graphite" yaml:"graphite"`
 	HDFS          writer.HDFSConfig          `json:"hdfs" yaml:"hdfs"`
 	HTTPClient    writer.HTTPClientConfig    `json:"http_client" yaml:"http_client"`
 	HTTPServer    HTTPServerConfig           `json:"http_server" yaml:"http_server"`
+	InfluxDB      writer.InfluxDBConfig      `json:"influxdb" yaml:"influxdb"`
 	Inproc        InprocConfig               `json:"inproc" yaml:"inproc"`
 	Kafka         writer.KafkaConfig         `json:"kafka" yaml:"kafka"`
 	Kinesis       writer.KinesisConfig       `json:"kinesis" yaml:"kinesis"`
@@ -126,14 +143,22 @@ type Config struct {
 	RedisList     writer.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub   writer.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
 	RedisStreams  writer.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
+	Reject        RejectConfig               `json:"reject" yaml:"reject"`
 	Retry         RetryConfig                `json:"retry" yaml:"retry"`
 	S3            writer.AmazonS3Config      `json:"s3" yaml:"s3"`
+	Sharded       ShardedConfig              `json:"sharded" yaml:"sharded"`
+	Snowflake     writer.SnowflakeConfig     `json:"snowflake" yaml:"snowflake"`
+	Socket        writer.SocketConfig        `json:"socket" yaml:"socket"`
+	SQL           writer.SQLConfig           `json:"sql" yaml:"sql"`
+	SQLite        *writer.SQLiteConfig       `json:"sqlite,omitempty" yaml:"sqlite,omitempty"`
 	SQS           writer.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
 	STDOUT        STDOUTConfig               `json:"stdout" yaml:"stdout"`
 	Switch        SwitchConfig               `json:"switch" yaml:"switch"`
 	SyncResponse  struct{}                   `json:"sync_response" yaml:"sync_response"`
 	Websocket     writer.WebsocketConfig     `json:"websocket" yaml:"websocket"`
 	ZMQ4          *writer.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
+	MaxInFlight   int                        `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching      processor.BatchConfig      `json:"batching" yaml:"batching"`
 	Processors    []processor.Config         `json:"processors" yaml:"processors"`
 }
 
@@ -144,6 +169,7 @@ func NewConfig() Config {
 		AMQP:          writer.NewAMQPConfig(),
 		Broker:        NewBrokerConfig(),
 		Cache:         writer.NewCacheConfig(),
+		DeadLetter:    NewDeadLetterConfig(),
 		Drop:          writer.NewDropConfig(),
 		DropOnError:   NewDropOnErrorConfig(),
 		Dynamic:       NewDynamicConfig(),
@@ -151,10 +177,14 @@ func NewConfig() Config {
 		Elasticsearch: writer.NewElasticsearchConfig(),
 		File:          NewFileConfig(),
 		Files:         writer.NewFilesConfig(),
+		GCPBigQuery:   writer.NewGCPBigQueryConfig(),
 		GCPPubSub:     writer.NewGCPPubSubConfig(),
+		GoogleSheets:  writer.NewGoogleSheetsConfig(),
+		Graphite:      writer.NewGraphiteConfig(),
 		HDFS:          writer.NewHDFSConfig(),
 		HTTPClient:    writer.NewHTTPClientConfig(),
 		HTTPServer:    NewHTTPServerConfig(),
+		InfluxDB:      writer.NewInfluxDBConfig(),
 		Inproc:        NewInprocConfig(),
 		Kafka:         writer.NewKafkaConfig(),
 		Kinesis:       writer.NewKinesisConfig(),
@@ -167,14 +197,22 @@ func NewConfig() Config {
 		RedisList:     writer.NewRedisListConfig(),
 		RedisPubSub:   writer.NewRedisPubSubConfig(),
 		RedisStreams:  writer.NewRedisStreamsConfig(),
+		Reject:        NewRejectConfig(),
 		Retry:         NewRetryConfig(),
 		S3:            writer.NewAmazonS3Config(),
+		Sharded:       NewShardedConfig(),
+		Snowflake:     writer.NewSnowflakeConfig(),
+		Socket:        writer.NewSocketConfig(),
+		SQL:           writer.NewSQLConfig(),
+		SQLite:        writer.NewSQLiteConfig(),
 		SQS:           writer.NewAmazonSQSConfig(),
 		STDOUT:        NewSTDOUTConfig(),
 		Switch:        NewSwitchConfig(),
 		SyncResponse:  struct{}{},
 		Websocket:     writer.NewWebsocketConfig(),
 		ZMQ4:          writer.NewZMQ4Config(),
+		MaxInFlight:   1,
+		Batching:      processor.NewBatchConfig(),
 		Processors:    []processor.Config{},
 	}
 }
@@ -219,6 +257,15 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 		}
 	}
 
+	if conf.MaxInFlight != 1 {
+		outputMap["max_in_flight"] = conf.MaxInFlight
+	}
+	if batchingEnabled(conf.Batching) {
+		if outputMap["batching"], err = sanitiseBatching(conf.Batching); err != nil {
+			return nil, err
+		}
+	}
+
 	if len(conf.Processors) == 0 {
 		return outputMap, nil
 	}
@@ -326,6 +373,68 @@ with a [` + "`retry`" + `](#retry) output. Some other outputs, such as the
 [` + "`broker`" + `](#broker), might also retry indefinitely depending on their
 configuration.
 
+### Batching
+
+Any output, including each child of a ` + "[`broker`](#broker)" + `, can accumulate
+messages into batches with a ` + "`batching`" + ` policy before they are written.
+The fields of the policy match those of the
+` + "[`batch`](../processors/README.md#batch)" + ` processor:
+
+` + "``` yaml" + `
+output:
+  broker:
+    pattern: fan_out
+    outputs:
+    - kafka:
+        addresses: [ localhost:9092 ]
+        topic: foo
+    - s3:
+        bucket: bar
+        path: ${!count:files}-${!timestamp_unix_nano}.tar.gz
+      batching:
+        byte_size: 20_000_000
+        period: 1m
+      processors:
+      - archive:
+          format: tar
+      - compress:
+          algorithm: gzip
+` + "```" + `
+
+Messages held within a batching policy are acknowledged once the batch is
+written, therefore a broker will not acknowledge a message until every child
+that holds it has flushed its batch. Processors of an output are applied to
+each batch once it is flushed, which makes it possible to archive a batch into a
+single message as shown above.
+
+### Max In Flight
+
+By default an output writes one batch at a time, waiting for each write to be
+acknowledged before starting the next. Outputs that write to network services,
+such as ` + "`http_client`" + ` and ` + "`kafka`" + `, can instead be configured with
+` + "`max_in_flight`" + ` in order to dispatch that many batches in parallel over
+the same client:
+
+` + "``` yaml" + `
+output:
+  http_client:
+    url: http://localhost:8080/post
+  max_in_flight: 16
+` + "```" + `
+
+Each batch is acknowledged individually once its own write completes, and
+therefore messages may be delivered out of order. Only outputs whose clients are
+safe for concurrent use support this field, which are currently
+` + "`cache`" + `, ` + "`drop`" + `, ` + "`gcp_pubsub`" + `, ` + "`http_client`" + ` and ` + "`kafka`" + `. Other
+outputs, such as ` + "`socket`" + ` and ` + "`websocket`" + `, will fail to start when
+` + "`max_in_flight`" + ` is greater than one. Brokers do not support this field
+and should instead set it on each of their children.
+
+Setting ` + "`max_in_flight`" + ` to ` + "`-1`" + ` caps the number of parallel writes to
+the value of ` + "`GOMAXPROCS`" + ` for outputs that support it, and leaves other
+outputs writing one batch at a time. The resolved value is exposed as the gauge
+metric ` + "`output.max_in_flight`" + `.
+
 ### Multiplexing Outputs
 
 It is possible to perform content based multiplexing of messages to specific
@@ -342,7 +451,26 @@ conditions please [read the docs here](../conditions/README.md).
 ### Dead Letter Queues
 
 It's possible to create fallback outputs for when an output target fails using
-a ` + "[`broker`](#broker)" + ` output with the 'try' pattern.`
+a ` + "[`broker`](#broker)" + ` output with the 'try' pattern.
+
+### Endpoint Failover
+
+The ` + "`http_client`, `kinesis`, `s3` and `sqs`" + ` outputs can fail over
+to secondary endpoints, such as a replica in another region, by listing them
+under ` + "`failover.endpoints`" + `. Messages are written to the first
+healthy endpoint in the order primary, then each secondary as listed.
+
+An endpoint is considered unhealthy when it fails to connect, or when
+` + "`failover.failure_threshold`" + ` consecutive writes to it have failed,
+after which it is skipped for the duration of
+` + "`failover.recovery_interval`" + `. Once that interval has passed the
+endpoint is attempted again, which means traffic returns to the primary after an
+outage without any reconfiguration. If every endpoint is unhealthy then the one
+due to recover soonest is used. Endpoints that lose their connection are
+connected again before their next write. The health of an endpoint is only
+determined from connection attempts and writes, no separate health check
+requests are made.
+`
 
 // Descriptions returns a formatted string of collated descriptions of each
 // type.
@@ -393,6 +521,29 @@ func Descriptions() string {
 	return buf.String()
 }
 
+// setMaxInFlight configures the maximum number of parallel writes of an output
+// when it is greater than one. Only outputs that are backed by a writer which
+// is safe for concurrent use are able to write in parallel. A value of -1 sets
+// the maximum to GOMAXPROCS for outputs that are able to write in parallel, and
+// is otherwise ignored.
+func setMaxInFlight(conf Config, output Type) error {
+	w, isWriter := output.(*Writer)
+	n := conf.MaxInFlight
+	if n == -1 {
+		if !isWriter || !w.concurrent() {
+			return nil
+		}
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n <= 1 {
+		return nil
+	}
+	if !isWriter {
+		return fmt.Errorf("output '%v' does not support max_in_flight", conf.Type)
+	}
+	return w.setMaxInFlight(n)
+}
+
 // New creates an output type based on an output configuration.
 func New(
 	conf Config,
@@ -401,6 +552,9 @@ func New(
 	stats metrics.Type,
 	pipelines ...types.PipelineConstructorFunc,
 ) (Type, error) {
+	if batchingEnabled(conf.Batching) {
+		pipelines = append(pipelines, newBatchingPipeline(conf.Batching, mgr, log, stats))
+	}
 	if len(conf.Processors) > 0 {
 		pipelines = append(pipelines, []types.PipelineConstructorFunc{func(i *int) (types.Pipeline, error) {
 			if i == nil {
@@ -422,12 +576,18 @@ func New(
 	}
 	if c, ok := Constructors[conf.Type]; ok {
 		if c.brokerConstructor != nil {
+			if conf.MaxInFlight > 1 {
+				return nil, fmt.Errorf("output '%v' does not support max_in_flight, set it on its child outputs instead", conf.Type)
+			}
 			return c.brokerConstructor(conf, mgr, log, stats, pipelines...)
 		}
 		output, err := c.constructor(conf, mgr, log, stats)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create output '%v': %v", conf.Type, err)
 		}
+		if err = setMaxInFlight(conf, output); err != nil {
+			return nil, err
+		}
 		return WrapWithPipelines(output, pipelines...)
 	}
 	if c, ok := pluginSpecs[conf.Type]; ok {
@@ -435,6 +595,9 @@ func New(
 		if err != nil {
 			return nil, err
 		}
+		if err = setMaxInFlight(conf, output); err != nil {
+			return nil, err
+		}
 		return WrapWithPipelines(output, pipelines...)
 	}
 	return nil, types.ErrInvalidOutputType
@@ -0,0 +1,395 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDeadLetter] = TypeSpec{
+		constructor: NewDeadLetter,
+		description: `
+Attempts to write messages to a child output, and if the write fails for
+` + "`max_attempts`" + ` consecutive attempts then the message is instead
+written to a secondary ` + "`dead_letter`" + ` output. Attempts are made with an
+exponential backoff between them.
+
+Messages written to the dead letter output have the following metadata fields
+added describing the failure:
+
+- ` + "`dead_letter_error`" + `: The error returned by the final attempt.
+- ` + "`dead_letter_attempts`" + `: The number of attempts made.
+- ` + "`dead_letter_failed_at`" + `: The time of the final attempt in RFC3339
+  format.
+
+If the write to the dead letter output also fails then the error is returned to
+the input, and the message is eventually attempted again from the beginning.
+
+` + "``` yaml" + `
+output:
+  dead_letter:
+    max_attempts: 3
+    output:
+      type: foo
+    dead_letter:
+      type: bar
+` + "```" + ``,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DeadLetter)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DeadLetter.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DeadLetter.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+
+			var dlSanit interface{} = struct{}{}
+			if conf.DeadLetter.DeadLetter != nil {
+				if dlSanit, err = SanitiseConfig(*conf.DeadLetter.DeadLetter); err != nil {
+					return nil, err
+				}
+			}
+			confMap["dead_letter"] = dlSanit
+			return confMap, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DeadLetterConfig contains configuration values for the DeadLetter output
+// type.
+type DeadLetterConfig struct {
+	MaxAttempts int             `json:"max_attempts" yaml:"max_attempts"`
+	Backoff     retries.Backoff `json:"backoff" yaml:"backoff"`
+	Output      *Config         `json:"output" yaml:"output"`
+	DeadLetter  *Config         `json:"dead_letter" yaml:"dead_letter"`
+}
+
+// NewDeadLetterConfig creates a new DeadLetterConfig with default values.
+func NewDeadLetterConfig() DeadLetterConfig {
+	return DeadLetterConfig{
+		MaxAttempts: 3,
+		Backoff: retries.Backoff{
+			InitialInterval: "100ms",
+			MaxInterval:     "1s",
+			MaxElapsedTime:  "0s",
+		},
+		Output:     nil,
+		DeadLetter: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDeadLetterConfig struct {
+	MaxAttempts int             `json:"max_attempts" yaml:"max_attempts"`
+	Backoff     retries.Backoff `json:"backoff" yaml:"backoff"`
+	Output      interface{}     `json:"output" yaml:"output"`
+	DeadLetter  interface{}     `json:"dead_letter" yaml:"dead_letter"`
+}
+
+func (d DeadLetterConfig) dummy() dummyDeadLetterConfig {
+	dummy := dummyDeadLetterConfig{
+		MaxAttempts: d.MaxAttempts,
+		Backoff:     d.Backoff,
+		Output:      d.Output,
+		DeadLetter:  d.DeadLetter,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if d.DeadLetter == nil {
+		dummy.DeadLetter = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints empty objects instead of nil.
+func (d DeadLetterConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints empty objects instead of nil.
+func (d DeadLetterConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// DeadLetter is an output type that writes messages to a child output, and
+// writes messages that repeatedly fail to a secondary dead letter output.
+type DeadLetter struct {
+	running int32
+
+	maxAttempts int
+	output      Type
+	deadLetter  Type
+	backoff     backoff.BackOff
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn <-chan types.Transaction
+	outputTsChan   chan types.Transaction
+	deadLetterChan chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDeadLetter creates a new DeadLetter output type.
+func NewDeadLetter(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.DeadLetter.Output == nil {
+		return nil, errors.New("cannot create dead_letter output without a child output")
+	}
+	if conf.DeadLetter.DeadLetter == nil {
+		return nil, errors.New("cannot create dead_letter output without a dead letter output")
+	}
+	if conf.DeadLetter.MaxAttempts < 1 {
+		return nil, errors.New("max attempts must be greater than zero")
+	}
+
+	output, err := New(
+		*conf.DeadLetter.Output, mgr,
+		log.NewModule(".output"), metrics.Namespaced(stats, "output"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.DeadLetter.Output.Type, err)
+	}
+
+	var deadLetter Type
+	if deadLetter, err = New(
+		*conf.DeadLetter.DeadLetter, mgr,
+		log.NewModule(".dead_letter"), metrics.Namespaced(stats, "dead_letter"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter output '%v': %v", conf.DeadLetter.DeadLetter.Type, err)
+	}
+
+	rConf := retries.NewConfig()
+	rConf.Backoff = conf.DeadLetter.Backoff
+	var boff backoff.BackOff
+	if boff, err = rConf.Get(); err != nil {
+		return nil, err
+	}
+
+	return &DeadLetter{
+		running: 1,
+
+		maxAttempts: conf.DeadLetter.MaxAttempts,
+		output:      output,
+		deadLetter:  deadLetter,
+		backoff:     boff,
+
+		log:   log,
+		stats: stats,
+
+		outputTsChan:   make(chan types.Transaction),
+		deadLetterChan: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (d *DeadLetter) loop() {
+	// Metrics paths
+	var (
+		mCount        = d.stats.GetCounter("dead_letter.count")
+		mSuccess      = d.stats.GetCounter("dead_letter.send.success")
+		mError        = d.stats.GetCounter("dead_letter.send.error")
+		mRouted       = d.stats.GetCounter("dead_letter.routed")
+		mPartsRouted  = d.stats.GetCounter("dead_letter.parts.routed")
+		mRoutedFailed = d.stats.GetCounter("dead_letter.routed.error")
+	)
+
+	defer func() {
+		close(d.outputTsChan)
+		close(d.deadLetterChan)
+		d.output.CloseAsync()
+		d.deadLetter.CloseAsync()
+		for err := d.output.WaitForClose(time.Second); err != nil; err = d.output.WaitForClose(time.Second) {
+		}
+		for err := d.deadLetter.WaitForClose(time.Second); err != nil; err = d.deadLetter.WaitForClose(time.Second) {
+		}
+		close(d.closedChan)
+	}()
+
+	resChan := make(chan types.Response)
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+			mCount.Incr(1)
+		case <-d.closeChan:
+			return
+		}
+
+		var res types.Response
+		attempts := 0
+		for attempts < d.maxAttempts {
+			if attempts > 0 {
+				nextBackoff := d.backoff.NextBackOff()
+				if nextBackoff == backoff.Stop {
+					break
+				}
+				select {
+				case <-time.After(nextBackoff):
+				case <-d.closeChan:
+					return
+				}
+			}
+			attempts++
+
+			select {
+			case d.outputTsChan <- types.NewTransaction(ts.Payload, resChan):
+			case <-d.closeChan:
+				return
+			}
+			select {
+			case res = <-resChan:
+			case <-d.closeChan:
+				return
+			}
+			if res.Error() == nil {
+				break
+			}
+			mError.Incr(1)
+			d.log.Errorf("Failed to send message (attempt %v): %v\n", attempts, res.Error())
+		}
+		d.backoff.Reset()
+
+		if res.Error() == nil {
+			mSuccess.Incr(1)
+		} else {
+			failedAt := time.Now().Format(time.RFC3339)
+			msg := ts.Payload.Copy()
+			msg.Iter(func(i int, p types.Part) error {
+				meta := p.Metadata()
+				meta.Set("dead_letter_error", res.Error().Error())
+				meta.Set("dead_letter_attempts", strconv.Itoa(attempts))
+				meta.Set("dead_letter_failed_at", failedAt)
+				return nil
+			})
+
+			select {
+			case d.deadLetterChan <- types.NewTransaction(msg, resChan):
+			case <-d.closeChan:
+				return
+			}
+			select {
+			case res = <-resChan:
+			case <-d.closeChan:
+				return
+			}
+			if res.Error() != nil {
+				mRoutedFailed.Incr(1)
+				d.log.Errorf("Failed to send message to dead letter output: %v\n", res.Error())
+			} else {
+				mRouted.Incr(1)
+				mPartsRouted.Incr(int64(msg.Len()))
+			}
+		}
+
+		select {
+		case ts.ResponseChan <- res:
+		case <-d.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *DeadLetter) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.output.Consume(d.outputTsChan); err != nil {
+		return err
+	}
+	if err := d.deadLetter.Consume(d.deadLetterChan); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (d *DeadLetter) Connected() bool {
+	return d.output.Connected()
+}
+
+// CloseAsync shuts down the DeadLetter output and stops processing requests.
+func (d *DeadLetter) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the DeadLetter output has closed down.
+func (d *DeadLetter) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
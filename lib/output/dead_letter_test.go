@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func newDeadLetterWithMocks(t *testing.T, maxAttempts int) (*DeadLetter, *mockOutput, *mockOutput) {
+	t.Helper()
+
+	conf := NewConfig()
+	outConf, dlConf := NewConfig(), NewConfig()
+	conf.DeadLetter.Output = &outConf
+	conf.DeadLetter.DeadLetter = &dlConf
+	conf.DeadLetter.MaxAttempts = maxAttempts
+	conf.DeadLetter.Backoff.InitialInterval = "10us"
+	conf.DeadLetter.Backoff.MaxInterval = "10us"
+
+	output, err := NewDeadLetter(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl, ok := output.(*DeadLetter)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut, mDL := &mockOutput{}, &mockOutput{}
+	dl.output, dl.deadLetter = mOut, mDL
+	return dl, mOut, mDL
+}
+
+func TestDeadLetterConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDeadLetter
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing outputs")
+	}
+
+	oConf := NewConfig()
+	conf.DeadLetter.Output = &oConf
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing dead letter output")
+	}
+
+	dlConf := NewConfig()
+	conf.DeadLetter.DeadLetter = &dlConf
+	conf.DeadLetter.MaxAttempts = 0
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero max attempts")
+	}
+
+	conf.DeadLetter.MaxAttempts = 1
+	conf.DeadLetter.Backoff.InitialInterval = "not a time period"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad initial period")
+	}
+}
+
+func TestDeadLetterHappyPath(t *testing.T) {
+	dl, mOut, _ := newDeadLetterWithMocks(t, 3)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err := dl.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{[]byte("foo")})
+	select {
+	case tChan <- types.NewTransaction(testMsg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if tran.Payload != testMsg {
+		t.Error("Wrong payload returned")
+	}
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err := res.Error(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	dl.CloseAsync()
+	if err := dl.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeadLetterRouted(t *testing.T) {
+	dl, mOut, mDL := newDeadLetterWithMocks(t, 2)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err := dl.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	select {
+	case tChan <- types.NewTransaction(testMsg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	for i := 0; i < 2; i++ {
+		var tran types.Transaction
+		select {
+		case tran = <-mOut.ts:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case tran.ResponseChan <- response.NewError(errors.New("nope")):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-mDL.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if exp, act := 2, tran.Payload.Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+	tran.Payload.Iter(func(i int, p types.Part) error {
+		if exp, act := "nope", p.Metadata().Get("dead_letter_error"); exp != act {
+			t.Errorf("Wrong error metadata: %v != %v", act, exp)
+		}
+		if exp, act := "2", p.Metadata().Get("dead_letter_attempts"); exp != act {
+			t.Errorf("Wrong attempts metadata: %v != %v", act, exp)
+		}
+		if _, err := time.Parse(time.RFC3339, p.Metadata().Get("dead_letter_failed_at")); err != nil {
+			t.Errorf("Bad failed at metadata: %v", err)
+		}
+		return nil
+	})
+	if act := testMsg.Get(0).Metadata().Get("dead_letter_error"); len(act) > 0 {
+		t.Errorf("Original message was modified: %v", act)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err := res.Error(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	dl.CloseAsync()
+	if err := dl.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -42,7 +42,7 @@ Drops all messages.`,
 // NewDrop creates a new Drop output type.
 func NewDrop(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
 	return NewWriter(
-		TypeDrop, writer.NewDrop(conf.Drop, log, stats), log, stats,
+		TypeDrop, writer.NewDrop(conf.Drop, mgr, log, stats), log, stats,
 	)
 }
 
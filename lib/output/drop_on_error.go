@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -129,6 +130,7 @@ type DropOnError struct {
 
 	wrapped Type
 
+	mgr   types.Manager
 	stats metrics.Type
 	log   log.Modular
 
@@ -158,6 +160,7 @@ func NewDropOnError(
 	return &DropOnError{
 		running: 1,
 
+		mgr:             mgr,
 		log:             log,
 		stats:           stats,
 		wrapped:         wrapped,
@@ -217,6 +220,7 @@ func (d *DropOnError) loop() {
 			mDropped.Incr(int64(ts.Payload.Len()))
 			mDroppedBatch.Incr(1)
 			d.log.Warnf("Message dropped due to: %v\n", res.Error())
+			drop.Report(d.mgr, TypeDropOnError, ts.Payload)
 		}
 
 		select {
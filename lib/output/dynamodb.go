@@ -69,7 +69,39 @@ json_map_columns:
 
 In which case the top level document fields will be written at the root of the
 item, potentially overwriting previously defined column values. If a path is not
-found within a document the column will not be populated.`,
+found within a document the column will not be populated.
+
+The field ` + "`table`" + ` can also be
+[function interpolated](../config_interpolation.md#functions), in which case
+messages of a batch are grouped by their resolved table.
+
+### Batching
+
+Batches are written with ` + "`BatchWriteItem`" + ` requests of up to 25 items
+each. Any items returned by DynamoDB as unprocessed, which happens when a table
+is throttled, are retried according to the ` + "`backoff`" + ` and
+` + "`max_retries`" + ` fields until either they are written or the retries are
+exhausted, at which point the batch is reattempted in full.
+
+### Conditional Writes
+
+When ` + "`condition_expression`" + ` is set each item is instead written
+individually with a ` + "`PutItem`" + ` request carrying the condition, as
+conditions are not supported by batched writes. Placeholders within the
+condition can be defined with ` + "`expression_attribute_names`" + ` and
+` + "`expression_attribute_values`" + `, where the values are function
+interpolated per message and written as strings:
+
+` + "``` yaml" + `
+condition_expression: "attribute_not_exists(id) OR #updated < :updated"
+expression_attribute_names:
+  "#updated": updated_at
+expression_attribute_values:
+  ":updated": ${!json_field:updated_at}
+` + "```" + `
+
+Items that fail their condition are dropped and counted with the metric
+` + "`write.condition_failed`" + `.`,
 	}
 }
 
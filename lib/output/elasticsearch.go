@@ -36,9 +36,46 @@ func init() {
 Publishes messages into an Elasticsearch index. This output currently does not
 support creating the target index.
 
-Both the ` + "`id` and `index`" + ` fields can be dynamically set using function
-interpolations described [here](../config_interpolation.md#functions). When
-sending batched messages these interpolations are performed per message part.`,
+The fields ` + "`id`, `index`, `action`, `routing` and `pipeline`" + ` can be
+dynamically set using function interpolations described
+[here](../config_interpolation.md#functions). When sending batched messages
+these interpolations are performed per message part.
+
+### Actions
+
+The ` + "`action`" + ` field determines how each document is written and must
+resolve to one of ` + "`index`, `create`, `update` or `delete`" + `. An
+` + "`update`" + ` merges the message as a partial document into an existing
+document, and a ` + "`delete`" + ` removes the document with the resolved
+` + "`id`" + ` without requiring the message to contain JSON. Deleting a
+document that does not exist and creating a document that already exists are
+treated as successful writes. For example, the action can be selected with
+metadata set earlier in the pipeline:
+
+` + "``` yaml" + `
+elasticsearch:
+  action: ${!metadata:operation}
+  id: ${!json_field:doc.id}
+  index: users
+` + "```" + `
+
+### Data Streams and ILM
+
+Data streams only accept the ` + "`create`" + ` action, and therefore in order to
+write to one set ` + "`index`" + ` to the name of the data stream and
+` + "`action`" + ` to ` + "`create`" + `. Documents are required to contain a
+` + "`@timestamp`" + ` field. When targeting Elasticsearch 7 or later
+` + "`type`" + ` should also be set to ` + "`_doc`" + `.
+
+Similarly, indexes managed by an ILM policy can be written to via their rollover
+alias by setting ` + "`index`" + ` to the name of the alias.
+
+When ` + "`propagate_response`" + ` is set to ` + "`true`" + ` each message is
+returned to the input as a [synchronous response](../sync_responses.md) in the
+form of a JSON object containing the fields
+` + "`elasticsearch_index` and `elasticsearch_id`" + ` of the indexed
+document. Messages sent individually also include an
+` + "`elasticsearch_version`" + ` field.`,
 	}
 }
 
@@ -21,11 +21,19 @@
 package output
 
 import (
+	"compress/gzip"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
 )
 
 //------------------------------------------------------------------------------
@@ -42,16 +50,62 @@ would be written as:
 
 foo\n
 bar\n
-baz\n\n`,
+baz\n\n
+
+### Rotation
+
+The output file can be rotated by Benthos itself, which avoids races between
+writes and external tools such as logrotate. A rotation is triggered before a
+write when either the file would exceed ` + "`rotation.max_size`" + ` bytes,
+or the file has been open for longer than ` + "`rotation.max_age`" + `. Since
+rotations are only checked on writes a file can remain open beyond its max age
+when no messages are being written.
+
+When rotated the current file is moved to ` + "`rotation.path`" + `, which
+supports [interpolation functions](../config_interpolation.md#functions) and
+defaults to the output path suffixed with a unix nanosecond timestamp, and a new
+file is opened at the output path. If a file already exists at the rotated path
+then a sequence number suffix (` + "`.1`" + `, ` + "`.2`" + `, etc) is added
+rather than overwriting it. Rotated files are compressed with gzip and
+given a ` + "`.gz`" + ` extension when ` + "`rotation.compress`" + ` is
+` + "`true`" + `.
+
+` + "``` yaml" + `
+file:
+  path: /var/log/benthos/events.log
+  rotation:
+    max_size: 100000000
+    max_age: 24h
+    path: /var/log/benthos/events-${!timestamp:2006-01-02T15-04-05}.log
+    compress: true
+` + "```" + `
+
+### Fsync
+
+The field ` + "`fsync`" + ` determines when file contents are flushed to disk,
+and can be ` + "`none`" + ` (the default) where this is left to the operating
+system, ` + "`write`" + ` where each message is flushed after being written, or
+` + "`rotate`" + ` where files are flushed before being rotated or closed.`,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// FileRotationConfig contains configuration fields for rotating the file of a
+// file based output.
+type FileRotationConfig struct {
+	MaxSize  int64  `json:"max_size" yaml:"max_size"`
+	MaxAge   string `json:"max_age" yaml:"max_age"`
+	Path     string `json:"path" yaml:"path"`
+	Compress bool   `json:"compress" yaml:"compress"`
+}
+
 // FileConfig contains configuration fields for the file based output type.
 type FileConfig struct {
-	Path  string `json:"path" yaml:"path"`
-	Delim string `json:"delimiter" yaml:"delimiter"`
+	Path     string             `json:"path" yaml:"path"`
+	Delim    string             `json:"delimiter" yaml:"delimiter"`
+	Rotation FileRotationConfig `json:"rotation" yaml:"rotation"`
+	Fsync    string             `json:"fsync" yaml:"fsync"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
@@ -59,6 +113,13 @@ func NewFileConfig() FileConfig {
 	return FileConfig{
 		Path:  "",
 		Delim: "",
+		Rotation: FileRotationConfig{
+			MaxSize:  0,
+			MaxAge:   "",
+			Path:     "",
+			Compress: false,
+		},
+		Fsync: "none",
 	}
 }
 
@@ -66,7 +127,15 @@ func NewFileConfig() FileConfig {
 
 // NewFile creates a new File output type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	file, err := os.OpenFile(conf.File.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.FileMode(0666))
+	rotConf := conf.File.Rotation
+	if rotConf.MaxSize <= 0 && len(rotConf.MaxAge) == 0 && (conf.File.Fsync == "none" || conf.File.Fsync == "") {
+		file, err := os.OpenFile(conf.File.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.FileMode(0666))
+		if err != nil {
+			return nil, err
+		}
+		return NewLineWriter(file, true, []byte(conf.File.Delim), "file", log, stats)
+	}
+	file, err := newRotatingFile(conf.File, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -74,3 +143,210 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 }
 
 //------------------------------------------------------------------------------
+
+// rotatingFile is an io.WriteCloser that appends to a file, which is rotated
+// according to a size and age policy.
+type rotatingFile struct {
+	path        string
+	rotatedPath *text.InterpolatedString
+	maxSize     int64
+	maxAge      time.Duration
+	compress    bool
+	syncOnWrite bool
+	syncOnClose bool
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	log           log.Modular
+	mRotated      metrics.StatCounter
+	mRotateErr    metrics.StatCounter
+	mCompressErr  metrics.StatCounter
+	compressGroup sync.WaitGroup
+}
+
+func newRotatingFile(conf FileConfig, log log.Modular, stats metrics.Type) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:         conf.Path,
+		maxSize:      conf.Rotation.MaxSize,
+		compress:     conf.Rotation.Compress,
+		log:          log,
+		mRotated:     stats.GetCounter("rotation.success"),
+		mRotateErr:   stats.GetCounter("rotation.error"),
+		mCompressErr: stats.GetCounter("rotation.compress.error"),
+	}
+
+	rotatedPath := conf.Rotation.Path
+	if len(rotatedPath) == 0 {
+		rotatedPath = conf.Path + ".${!timestamp_unix_nano}"
+	}
+	r.rotatedPath = text.NewInterpolatedString(rotatedPath)
+
+	if len(conf.Rotation.MaxAge) > 0 {
+		var err error
+		if r.maxAge, err = time.ParseDuration(conf.Rotation.MaxAge); err != nil {
+			return nil, fmt.Errorf("failed to parse rotation max age: %v", err)
+		}
+	}
+
+	switch conf.Fsync {
+	case "none", "":
+	case "write":
+		r.syncOnWrite = true
+	case "rotate":
+		r.syncOnClose = true
+	default:
+		return nil, fmt.Errorf("fsync policy not recognised: %v", conf.Fsync)
+	}
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = time.Now()
+	return nil
+}
+
+func (r *rotatingFile) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	if r.syncOnClose {
+		if err := r.file.Sync(); err != nil {
+			return err
+		}
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// rotate closes the current file, moves it to its rotated path and opens a new
+// file in its place.
+func (r *rotatingFile) rotate() error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	target := r.rotatedPath.Get(message.New(nil))
+	if err := os.MkdirAll(filepath.Dir(target), os.FileMode(0777)); err != nil {
+		return err
+	}
+	target = r.freePath(target)
+	if err := os.Rename(r.path, target); err != nil {
+		return err
+	}
+	if r.compress {
+		r.compressGroup.Add(1)
+		go func() {
+			defer r.compressGroup.Done()
+			if err := compressFile(target); err != nil {
+				r.mCompressErr.Incr(1)
+				r.log.Errorf("Failed to compress rotated file '%v': %v\n", target, err)
+			}
+		}()
+	}
+	return r.open()
+}
+
+// freePath returns the given rotation path, or the path suffixed with the first
+// sequence number that is not yet taken when a file (or its compressed copy)
+// already exists there.
+func (r *rotatingFile) freePath(path string) string {
+	taken := func(p string) bool {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			return true
+		}
+		if r.compress {
+			if _, err := os.Stat(p + ".gz"); !os.IsNotExist(err) {
+				return true
+			}
+		}
+		return false
+	}
+	if !taken(path) {
+		return path
+	}
+	for i := 1; ; i++ {
+		if candidate := fmt.Sprintf("%v.%v", path, i); !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// compressFile writes a gzip compressed copy of a file with a .gz extension and
+// removes the original.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Write appends bytes to the current file, rotating it first if the write
+// would breach the rotation policy.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if (r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize) ||
+		(r.maxAge > 0 && time.Since(r.openedAt) >= r.maxAge) {
+		if err := r.rotate(); err != nil {
+			r.mRotateErr.Incr(1)
+			return 0, fmt.Errorf("failed to rotate file: %v", err)
+		}
+		r.mRotated.Incr(1)
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err == nil && r.syncOnWrite {
+		err = r.file.Sync()
+	}
+	return n, err
+}
+
+// Close closes the current file and waits for any pending compression of
+// rotated files.
+func (r *rotatingFile) Close() error {
+	err := r.closeFile()
+	r.compressGroup.Wait()
+	return err
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,189 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestFileRotationBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "out.log")
+	conf.Rotation.MaxSize = 8
+	conf.Rotation.Path = filepath.Join(dir, "rotated", "${!count:file_rotation_test}.log")
+
+	w, err := newRotatingFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"foo\n", "bar\n", "baz\n", "quz\n", "qux\n"} {
+		if _, err = w.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"out.log":       "qux\n",
+		"rotated/1.log": "foo\nbar\n",
+		"rotated/2.log": "baz\nquz\n",
+	}
+	for path, content := range exp {
+		act, err := ioutil.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(act) != content {
+			t.Errorf("Wrong contents for %v: %q != %q", path, act, content)
+		}
+	}
+}
+
+func TestFileRotationCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "out.log")
+	conf.Rotation.MaxSize = 4
+	conf.Rotation.Compress = true
+	conf.Fsync = "rotate"
+
+	w, err := newRotatingFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"foo\n", "bar\n"} {
+		if _, err = w.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "out.log.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(matches)
+	if len(matches) != 1 || filepath.Ext(matches[0]) != ".gz" {
+		t.Fatalf("Unexpected rotated files: %v", matches)
+	}
+
+	f, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "foo\n"; string(act) != exp {
+		t.Errorf("Wrong rotated contents: %q != %q", act, exp)
+	}
+}
+
+func TestFileRotationExistingTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "out.log")
+	conf.Rotation.MaxSize = 4
+	conf.Rotation.Path = filepath.Join(dir, "rotated.log")
+
+	w, err := newRotatingFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"foo\n", "bar\n", "baz\n", "qux\n"} {
+		if _, err = w.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"out.log":       "qux\n",
+		"rotated.log":   "foo\n",
+		"rotated.log.1": "bar\n",
+		"rotated.log.2": "baz\n",
+	}
+	for path, content := range exp {
+		act, err := ioutil.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(act) != content {
+			t.Errorf("Wrong contents for %v: %q != %q", path, act, content)
+		}
+	}
+}
+
+func TestFileRotationBadConfig(t *testing.T) {
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(os.TempDir(), "benthos_file_rotation_bad.log")
+
+	conf.Fsync = "nope"
+	if _, err := newRotatingFile(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad fsync policy")
+	}
+
+	conf.Fsync = "none"
+	conf.Rotation.MaxAge = "not a duration"
+	if _, err := newRotatingFile(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad max age")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGCPBigQuery] = TypeSpec{
+		constructor: NewGCPBigQuery,
+		description: `
+Inserts messages as rows into a GCP BigQuery table. Each message of a batch must
+be a JSON document, and rows are written to the table with either streaming
+inserts (` + "`method: stream`" + `) or a load job per batch
+(` + "`method: load`" + `).
+
+The field ` + "`table`" + ` supports
+[interpolation functions](../config_interpolation.md#functions) resolved per
+message of a batch, which can be used to target time partitions of a table with
+a partition decorator:
+
+` + "``` yaml" + `
+table: events$${!timestamp:20060102}
+` + "```" + `
+
+The field ` + "`fields`" + ` is a map of column names to json paths, where the
+path is extracted from each document and written to the column. Both an empty
+path and the path ` + "`.`" + ` are interpreted as the root of the document. If
+the map is empty the top level fields of each document are used as the row:
+
+` + "``` yaml" + `
+fields:
+  id: document.id
+  user: document.user.name
+  raw: .
+` + "```" + `
+
+When streaming, the field ` + "`insert_id`" + ` can be set in order to provide
+BigQuery with an ID used for best effort deduplication of rows, and also
+supports interpolation functions, e.g. ` + "`${!json_field:document.id}`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGCPBigQuery creates a new GCPBigQuery output type.
+func NewGCPBigQuery(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := writer.NewGCPBigQuery(conf.GCPBigQuery, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(
+		"gcp_bigquery", g, log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
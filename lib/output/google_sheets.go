@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGoogleSheets] = TypeSpec{
+		constructor: NewGoogleSheets,
+		description: `
+Appends messages as rows to a Google Sheets spreadsheet. Each message of a batch
+becomes a row, and all rows of a batch are appended with a single request, it is
+therefore recommended to use a ` + "[`batching`](#batching)" + ` policy in order
+to limit the number of requests made against the API quota.
+
+The field ` + "`columns`" + ` is a list of strings that each become a cell of
+the row, and support
+[interpolation functions](../config_interpolation.md#functions) resolved per
+message of a batch. If the list is empty each message must instead be a JSON
+array, where the elements are written as the cells of the row, and messages
+that are not arrays are dropped:
+
+` + "``` yaml" + `
+output:
+  google_sheets:
+    spreadsheet_id: 1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upms
+    range: Signups
+    columns:
+    - ${!timestamp}
+    - ${!json_field:user.email}
+    - ${!json_field:user.plan}
+  batching:
+    count: 50
+    period: 10s
+` + "```" + `
+
+The field ` + "`range`" + ` determines the sheet (and optionally the columns) to
+append to, using A1 notation such as ` + "`Sheet1` or `Sheet1!A:C`" + `, and
+also supports interpolation functions, in which case messages are grouped by
+their resolved range. With ` + "`value_input_option`" + ` set to
+` + "`USER_ENTERED`" + ` cells are parsed as if typed into the UI, converting
+numbers, dates and formulas, whereas ` + "`RAW`" + ` stores them as strings.
+
+Requests are authenticated with OAuth2 using the service account key file at
+` + "`credentials_file`" + `, or when empty the
+[application default credentials](https://cloud.google.com/docs/authentication/production).
+The spreadsheet must be shared with the service account.
+
+In order to append rows to other REST based services, such as Airtable, use the
+` + "[`http_client`](#http_client)" + ` output with a ` + "`batching`" + `
+policy and processors that construct the request body for the batch.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGoogleSheets creates a new GoogleSheets output type.
+func NewGoogleSheets(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := writer.NewGoogleSheets(conf.GoogleSheets, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(
+		"google_sheets", g, log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
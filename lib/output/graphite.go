@@ -0,0 +1,77 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGraphite] = TypeSpec{
+		constructor: NewGraphite,
+		description: `
+Writes metrics to a Graphite server using the
+[plaintext protocol](https://graphite.readthedocs.io/en/latest/feeding-carbon.html#the-plaintext-protocol),
+where ` + "`network`" + ` can be either ` + "`tcp` or `udp`" + `.
+
+Each message is converted into a metric by resolving the fields
+` + "`path`, `value` and `timestamp`" + `, which support
+[interpolation functions](../config_interpolation.md#functions) and by default
+read the fields of the same name from a JSON document such as:
+
+` + "``` json" + `
+{"path":"servers.foo.cpu","value":0.35,"timestamp":1559830000}
+` + "```" + `
+
+The resolved path is prefixed with ` + "`prefix`" + ` and any whitespace within
+it is replaced with underscores. The value must be a number, and the timestamp
+can be either a unix timestamp in seconds or an RFC3339 formatted date. When the
+timestamp is empty or missing the current time is used. Messages that
+cannot be converted into a metric are dropped and an error is logged.
+
+Over TCP the metrics of a batch are written together, and therefore a
+` + "[`batching`](#batching)" + ` policy can be used in order to reduce the
+number of writes made. Over UDP each metric is sent as its own datagram.
+
+A connection is considered lost when a write fails or exceeds
+` + "`write_timeout`" + `, at which point attempts to reconnect are made with an
+exponential backoff between
+` + "`reconnect.initial_interval` and `reconnect.max_interval`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGraphite creates a new Graphite output type.
+func NewGraphite(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := writer.NewGraphite(conf.Graphite, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("graphite", g, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -42,6 +42,11 @@ The period of time between retries is linear by default. Response codes that are
 within the ` + "`backoff_on`" + ` list will instead apply exponential backoff
 between retry attempts.
 
+Codes listed within ` + "`successful_on`" + ` are treated as successful, even
+when they fall outside the range of 200 -> 299. When the ` + "`retry_on`" + `
+list is not empty only the codes it contains are retried linearly, and any other
+unsuccessful code fails the request immediately.
+
 When the number of retries expires the output will reject the message, the
 behaviour after this will depend on the pipeline but usually this simply means
 the send is attempted again until successful whilst applying back pressure.
@@ -53,6 +58,42 @@ The body of the HTTP request is the raw contents of the message payload. If the
 message has multiple parts the request will be sent according to
 [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html)
 
+### Multipart Uploads
+
+The parts of a multipart request can instead be explicitly described within the
+field ` + "`multipart`" + `, where each item results in a part of the body. The
+fields ` + "`content_type`, `content_disposition` and `body`" + ` of each item
+support [interpolation functions](../config_interpolation.md#functions), which
+are resolved against the message being sent:
+
+` + "``` yaml" + `
+multipart:
+- content_type: application/json
+  content_disposition: form-data; name="meta"
+  body: '{"id":"${!json_field:id}"}'
+- content_type: application/octet-stream
+  content_disposition: form-data; name="file"; filename="${!metadata:name}"
+  body: ${!content}
+` + "```" + `
+
+If the target server caps the size of request bodies the field
+` + "`max_batch_bytes`" + ` can be set, and batches exceeding it are split
+across multiple requests.
+
+Request bodies can be compressed by setting the field ` + "`compression`" + ` to
+` + "`gzip`, `deflate` or `zstd`" + `, in which case the corresponding
+` + "`Content-Encoding`" + ` header is added to each request.
+
+Secondary servers can be listed as URLs within ` + "`failover.endpoints`" + `,
+which are sent requests with the same headers and retry behaviour as the primary
+whilst it is [unhealthy](#endpoint-failover):
+
+` + "``` yaml" + `
+failover:
+  endpoints:
+  - url: https://backup.example.com/post
+` + "```" + `
+
 ### Propagating Responses
 
 EXPERIMENTAL: It's possible to propagate the response from each HTTP request
@@ -60,6 +101,10 @@ back to the input source by setting ` + "`propagate_response` to `true`" + `.
 Only inputs that support [synchronous responses](../sync_responses.md) are able
 to make use of these propagated responses.
 
+Each part of a propagated response retains the metadata of the message that was
+sent, with the status code of the response added under the key
+` + "`http_status_code`" + `.
+
 This feature is considered experimental and is therefore subject to change
 outside of major version releases.`,
 	}
@@ -67,7 +112,7 @@ outside of major version releases.`,
 
 // NewHTTPClient creates a new HTTPClient output type.
 func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	h, err := writer.NewHTTPClient(conf.HTTPClient, mgr, log, stats)
+	h, err := writer.NewHTTPClientFailover(conf.HTTPClient, mgr, log, stats)
 	if err != nil {
 		return nil, err
 	}
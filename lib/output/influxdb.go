@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeInfluxDB] = TypeSpec{
+		constructor: NewInfluxDB,
+		description: `
+Converts JSON messages into points and writes them to InfluxDB using the
+[line protocol](https://docs.influxdata.com/influxdb/latest/reference/syntax/line-protocol/),
+where each message batch is written with a single request.
+
+### API Versions
+
+When ` + "`api_version`" + ` is ` + "`v2`" + ` points are written to the
+` + "`bucket`" + ` of the ` + "`org`" + ` specified, authenticated with
+` + "`token`" + `. When ` + "`api_version`" + ` is ` + "`v1`" + ` points are
+written to the database ` + "`db`" + ` and the optional
+` + "`retention_policy`" + `, authenticated with either ` + "`token`" + ` or
+basic authentication with ` + "`username` and `password`" + `.
+
+### Mapping
+
+The fields ` + "`measurement`" + ` and ` + "`tags`" + ` support
+[interpolation functions](../config_interpolation.md#functions) resolved per
+message, and tags that resolve to an empty string are omitted from the point:
+
+` + "``` yaml" + `
+measurement: ${!metadata:kafka_topic}
+tags:
+  host: ${!json_field:host}
+` + "```" + `
+
+The field ` + "`fields`" + ` is a map of point field names to json paths, where
+each path is extracted from the document. If the map is empty the top level
+string, number and boolean values of each document are used as the fields.
+Numbers are always written as floats.
+
+The field ` + "`timestamp`" + ` is an optional json path to the timestamp of a
+point, which can either be a number representing a unix timestamp in units of
+the configured ` + "`precision`" + `, or an RFC 3339 formatted string. If the
+path is empty or not found the time of the write is used instead.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewInfluxDB creates a new InfluxDB output type.
+func NewInfluxDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	i, err := writer.NewInfluxDB(conf.InfluxDB, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(
+		"influxdb", i, log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
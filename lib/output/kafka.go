@@ -55,6 +55,42 @@ value. If the key is empty then a partition is chosen at random. You can
 alternatively force the partitioner to round-robin partitions with the field
 ` + "`round_robin_partitions`" + `.
 
+Batches that are larger than ` + "`max_batch_bytes`" + ` (when greater than zero)
+are split into smaller batches that are sent one after the other, which can be
+used in order to avoid exceeding broker limits when compression is enabled. If
+an individual message exceeds this limit the batch is rejected.
+
+Setting ` + "`idempotent_write` to `true`" + ` enables the idempotent producer,
+which prevents retried sends from writing duplicate messages to a partition and
+preserves their ordering. This implies ` + "`ack_replicas`" + `, requires a
+` + "`target_version`" + ` of at least 0.11.0.0 and the
+` + "`IDEMPOTENT_WRITE`" + ` permission on the cluster.
+
+### Headers
+
+Metadata fields of each message are written as record headers, which requires
+a ` + "`target_version`" + ` of at least 0.11.0.0. The fields written can be
+restricted with lists of regular expressions in
+` + "`metadata.include_patterns` and `metadata.exclude_patterns`" + `, where a
+field is written if it matches any include pattern (or the list is empty) and
+no exclude patterns.
+
+### Timestamp
+
+The field ` + "`timestamp`" + ` can be used to set the timestamp of each record
+and supports
+[function interpolations](../config_interpolation.md#functions). It must
+resolve to either an RFC 3339 formatted date or a unix timestamp in seconds. If
+left empty the timestamp is set by the producer.
+
+### Responses
+
+When ` + "`propagate_response`" + ` is set to ` + "`true`" + ` the location of
+each record is returned to the input as a
+[synchronous response](../sync_responses.md), where each message is replaced
+with a JSON object containing the fields ` + "`kafka_topic`" + `,
+` + "`kafka_partition` and `kafka_offset`" + ` assigned by the broker.
+
 ` + tls.Documentation + ``,
 	}
 }
@@ -38,7 +38,28 @@ Sends messages to a Kinesis stream.
 Both the ` + "`partition_key`" + `(required) and ` + "`hash_key`" + ` (optional)
 fields can be dynamically set using function interpolations described
 [here](../config_interpolation.md#functions). When sending batched messages the
-interpolations are performed per message part.`,
+interpolations are performed per message part.
+
+Records can be redirected to a stream in another region during an outage of the
+primary by adding it to ` + "`failover.endpoints`" + `, where any of the fields
+` + "`region`, `endpoint` and `stream`" + ` left empty are taken from the primary.
+Read more about how endpoints are chosen [here](#endpoint-failover).
+
+### Aggregation
+
+When ` + "`aggregation.enabled`" + ` is ` + "`true`" + ` the parts of each batch
+that share a partition and hash key are packed into
+[KPL aggregated records](https://github.com/awslabs/amazon-kinesis-producer/blob/master/aggregation-format.md),
+which can greatly reduce the number of records (and therefore the cost) of
+writing small messages. Consumers built with the KCL, as well as Lambda
+functions using the KPL de-aggregation modules, will unpack these records
+transparently.
+
+An aggregated record is flushed when adding a part would exceed
+` + "`max_size`" + ` bytes, or when it contains ` + "`max_records`" + ` parts if
+that field is greater than zero. Parts that end up alone are sent without
+aggregation. Aggregation only applies within a batch, and therefore this should
+be combined with [batching](../batching.md) in order to be effective.`,
 	}
 }
 
@@ -46,7 +67,7 @@ interpolations are performed per message part.`,
 
 // NewKinesis creates a new Kinesis output type.
 func NewKinesis(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	kin, err := writer.NewKinesis(conf.Kinesis, log, stats)
+	kin, err := writer.NewKinesisFailover(conf.Kinesis, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +22,9 @@ package output
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"sync/atomic"
@@ -37,6 +40,64 @@ import (
 
 //------------------------------------------------------------------------------
 
+// lineCodec writes a message batch to a writer in a particular format.
+type lineCodec func(w io.Writer, msg types.Message, delim []byte) error
+
+// lineCodecs are the formats a LineWriter is able to write batches with.
+var lineCodecs = map[string]lineCodec{
+	"lines": func(w io.Writer, msg types.Message, delim []byte) (err error) {
+		if msg.Len() == 1 {
+			_, err = fmt.Fprintf(w, "%s%s", msg.Get(0).Get(), delim)
+		} else {
+			_, err = fmt.Fprintf(w, "%s%s%s", bytes.Join(message.GetAllBytes(msg), delim), delim, delim)
+		}
+		return
+	},
+	"json_array": func(w io.Writer, msg types.Message, delim []byte) error {
+		parts := make([]json.RawMessage, msg.Len())
+		if err := msg.Iter(func(i int, p types.Part) error {
+			if !json.Valid(p.Get()) {
+				return fmt.Errorf("message part %v is not valid JSON", i)
+			}
+			parts[i] = json.RawMessage(p.Get())
+			return nil
+		}); err != nil {
+			return err
+		}
+		jBytes, err := json.Marshal(parts)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s%s", jBytes, delim)
+		return err
+	},
+	"length_prefixed": func(w io.Writer, msg types.Message, delim []byte) error {
+		buf := bytes.Buffer{}
+		msg.Iter(func(i int, p types.Part) error {
+			binary.Write(&buf, binary.BigEndian, uint32(len(p.Get())))
+			buf.Write(p.Get())
+			return nil
+		})
+		_, err := w.Write(buf.Bytes())
+		return err
+	},
+	"hex": func(w io.Writer, msg types.Message, delim []byte) error {
+		buf := bytes.Buffer{}
+		msg.Iter(func(i int, p types.Part) error {
+			buf.WriteString(hex.EncodeToString(p.Get()))
+			buf.Write(delim)
+			return nil
+		})
+		if msg.Len() > 1 {
+			buf.Write(delim)
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	},
+}
+
+//------------------------------------------------------------------------------
+
 // LineWriter is an output type that writes messages to an io.WriterCloser type
 // as lines.
 type LineWriter struct {
@@ -47,6 +108,7 @@ type LineWriter struct {
 	stats   metrics.Type
 
 	customDelim []byte
+	codec       lineCodec
 
 	transactions <-chan types.Transaction
 
@@ -66,12 +128,32 @@ func NewLineWriter(
 	log log.Modular,
 	stats metrics.Type,
 ) (Type, error) {
+	return NewLineWriterWithCodec(handle, closeOnExit, customDelimiter, "lines", typeStr, log, stats)
+}
+
+// NewLineWriterWithCodec creates a new LineWriter output type that writes
+// batches in the format of a codec, which can be one of lines, json_array,
+// length_prefixed or hex.
+func NewLineWriterWithCodec(
+	handle io.WriteCloser,
+	closeOnExit bool,
+	customDelimiter []byte,
+	codec string,
+	typeStr string,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	c, exists := lineCodecs[codec]
+	if !exists {
+		return nil, fmt.Errorf("codec not recognised: %v", codec)
+	}
 	return &LineWriter{
 		running:     1,
 		typeStr:     typeStr,
 		log:         log,
 		stats:       stats,
 		customDelim: customDelimiter,
+		codec:       c,
 		handle:      handle,
 		closeOnExit: closeOnExit,
 		closeChan:   make(chan struct{}),
@@ -126,13 +208,9 @@ func (w *LineWriter) loop() {
 
 		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
 
-		var err error
-		if ts.Payload.Len() == 1 {
-			_, err = fmt.Fprintf(w.handle, "%s%s", ts.Payload.Get(0).Get(), delim)
-		} else {
-			_, err = fmt.Fprintf(w.handle, "%s%s%s", bytes.Join(message.GetAllBytes(ts.Payload), delim), delim, delim)
-		}
+		err := w.codec(w.handle, ts.Payload, delim)
 		if err != nil {
+			w.log.Errorf("Failed to write message: %v\n", err)
 			mError.Incr(1)
 		} else {
 			mSuccess.Incr(1)
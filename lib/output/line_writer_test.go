@@ -190,3 +190,93 @@ func TestLineWriterCustomDelim(t *testing.T) {
 		t.Error("Buffer was not closed by writer")
 	}
 }
+
+func TestLineWriterCodecs(t *testing.T) {
+	testCases := []struct {
+		codec          string
+		message        []string
+		expectedOutput string
+		expectedErr    bool
+	}{
+		{
+			codec:          "json_array",
+			message:        []string{`{"foo":"bar"}`},
+			expectedOutput: "[{\"foo\":\"bar\"}]\n",
+		},
+		{
+			codec:          "json_array",
+			message:        []string{`{"foo":"bar"}`, `5`, `"baz"`},
+			expectedOutput: "[{\"foo\":\"bar\"},5,\"baz\"]\n",
+		},
+		{
+			codec:       "json_array",
+			message:     []string{`{"foo":"bar"}`, `not json`},
+			expectedErr: true,
+		},
+		{
+			codec:          "length_prefixed",
+			message:        []string{`foo`, `hello`},
+			expectedOutput: "\x00\x00\x00\x03foo\x00\x00\x00\x05hello",
+		},
+		{
+			codec:          "hex",
+			message:        []string{`foo`},
+			expectedOutput: "666f6f\n",
+		},
+		{
+			codec:          "hex",
+			message:        []string{`foo`, `bar`},
+			expectedOutput: "666f6f\n626172\n\n",
+		},
+	}
+
+	for _, c := range testCases {
+		var buf testBuffer
+
+		msgChan := make(chan types.Transaction)
+		resChan := make(chan types.Response)
+
+		writer, err := NewLineWriterWithCodec(&buf, true, []byte{}, c.codec, "foo", log.New(os.Stdout, logConfig), metrics.DudType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = writer.Consume(msgChan); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := message.New(nil)
+		for _, part := range c.message {
+			msg.Append(message.NewPart([]byte(part)))
+		}
+
+		select {
+		case msgChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out sending message")
+		}
+
+		select {
+		case res := <-resChan:
+			if c.expectedErr && res.Error() == nil {
+				t.Errorf("Expected error from codec %v", c.codec)
+			} else if !c.expectedErr && res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for response")
+		}
+
+		if exp, act := c.expectedOutput, buf.String(); exp != act {
+			t.Errorf("Unexpected output from codec %v: %q != %q", c.codec, act, exp)
+		}
+
+		writer.CloseAsync()
+		if err = writer.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := NewLineWriterWithCodec(&testBuffer{}, true, nil, "nope", "foo", log.Noop(), metrics.DudType{}); err == nil {
+		t.Error("Expected error from unrecognised codec")
+	}
+}
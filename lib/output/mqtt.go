@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
 )
 
 //------------------------------------------------------------------------------
@@ -33,7 +34,28 @@ func init() {
 	Constructors[TypeMQTT] = TypeSpec{
 		constructor: NewMQTT,
 		description: `
-Pushes messages to an MQTT broker.`,
+Pushes messages to an MQTT broker.
+
+The ` + "`topic`" + ` field can be dynamically set using function interpolations
+described [here](../config_interpolation.md#functions). When sending batched
+messages these interpolations are performed per message part.
+
+The field ` + "`qos`" + ` sets the quality of service level of published
+messages, which can be 0 (at most once), 1 (at least once) or 2 (exactly once).
+Messages are published with the retained flag when ` + "`retained`" + ` is
+` + "`true`" + `, in which case the broker keeps the last message of each topic
+and delivers it to new subscribers.
+
+### Sessions
+
+When ` + "`clean_session`" + ` is ` + "`false`" + ` the broker keeps the
+session of the ` + "`client_id`" + ` across reconnects, allowing in-flight
+messages of QoS levels 1 and 2 to be completed after a connection is lost. These
+in-flight messages are held in memory by default, and setting
+` + "`store_directory`" + ` persists them to disk so that they also survive a
+restart of Benthos.
+
+` + tls.Documentation,
 	}
 }
 
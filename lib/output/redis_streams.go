@@ -42,7 +42,29 @@ efficiency.
 Redis stream entries are key/value pairs, as such it is necessary to specify the
 key to be set to the body of the message. All metadata fields of the message
 will also be set as key/value pairs, if there is a key collision between
-a metadata item and the body then the body takes precedence.`,
+a metadata item and the body then the body takes precedence.
+
+The field ` + "`fields`" + ` maps entry keys to dot paths of a JSON message,
+allowing a document to be spread across multiple entry fields:
+
+` + "``` yaml" + `
+redis_streams:
+  stream: events_${!metadata:tenant}
+  body_key: ""
+  fields:
+    user: user.id
+    event: type
+    payload: data
+` + "```" + `
+
+String values are set as they are and all other values are serialised as JSON,
+paths that do not exist within a message are skipped and the path ` + "`.`" + `
+maps the whole document. Mapped fields take precedence over metadata, and the
+body is omitted when ` + "`body_key`" + ` is empty. Messages that result in an entry
+without any fields are dropped, as Redis rejects them.
+
+This output will interpolate functions within the stream field, you can find a
+list of functions [here](../config_interpolation.md#functions).`,
 	}
 }
 
@@ -50,7 +72,7 @@ a metadata item and the body then the body takes precedence.`,
 
 // NewRedisStreams creates a new RedisStreams output type.
 func NewRedisStreams(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	w, err := writer.NewRedisStreams(conf.RedisStreams, log, stats)
+	w, err := writer.NewRedisStreams(conf.RedisStreams, mgr, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeReject] = TypeSpec{
+		constructor: NewReject,
+		description: `
+Rejects all messages by responding to the input with an error. The error
+message is set by the field ` + "`error`" + `, which supports
+[interpolation functions](../config_interpolation.md#functions) resolved
+against each batch.
+
+When ` + "`requeue`" + ` is ` + "`true`" + ` the error is treated by inputs as
+a failed delivery, and inputs that support it (such as ` + "`amqp`" + `) will
+requeue the message to be consumed again. When ` + "`false`" + ` the message is
+rejected instead, which inputs communicate back to the source where possible,
+e.g. ` + "`amqp`" + ` nacks the message without requeuing it, allowing it to be
+routed to a dead letter exchange, and ` + "`http_server`" + ` responds with a
+400 status code.
+
+Inputs that are unable to requeue or reject messages will instead reattempt
+delivery indefinitely, and therefore this output is typically used as a case of
+a ` + "[`switch`](#switch)" + ` output, where ` + "`retry_until_success`" + `
+must be ` + "`false`" + ` in order for the error to reach the input:
+
+` + "``` yaml" + `
+output:
+  switch:
+    retry_until_success: false
+    outputs:
+    - output:
+        reject:
+          error: "failed to process message: ${!metadata:benthos_processing_failed}"
+          requeue: false
+      condition:
+        processor_failed: {}
+    - output:
+        type: foo
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RejectConfig contains configuration fields for the Reject output type.
+type RejectConfig struct {
+	Error   string `json:"error" yaml:"error"`
+	Requeue bool   `json:"requeue" yaml:"requeue"`
+}
+
+// NewRejectConfig creates a new RejectConfig with default values.
+func NewRejectConfig() RejectConfig {
+	return RejectConfig{
+		Error:   "rejected by output",
+		Requeue: true,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Reject is an output type that responds to all messages with an error.
+type Reject struct {
+	running int32
+
+	errStr  *text.InterpolatedString
+	requeue bool
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn <-chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewReject creates a new Reject output type.
+func NewReject(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Reject.Error) == 0 {
+		return nil, errors.New("an error message must be specified")
+	}
+	return &Reject{
+		running: 1,
+
+		errStr:  text.NewInterpolatedString(conf.Reject.Error),
+		requeue: conf.Reject.Requeue,
+
+		log:   log,
+		stats: stats,
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Reject) loop() {
+	// Metrics paths
+	var (
+		mCount     = r.stats.GetCounter("reject.count")
+		mPartCount = r.stats.GetCounter("reject.parts.count")
+	)
+
+	defer close(r.closedChan)
+
+	for atomic.LoadInt32(&r.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-r.transactionsIn:
+			if !open {
+				return
+			}
+		case <-r.closeChan:
+			return
+		}
+		mCount.Incr(1)
+		mPartCount.Incr(int64(ts.Payload.Len()))
+
+		err := errors.New(r.errStr.Get(ts.Payload))
+		if !r.requeue {
+			err = types.ErrRejected{Err: err}
+		}
+		r.log.Debugf("Rejecting message: %v\n", err)
+
+		select {
+		case ts.ResponseChan <- response.NewError(err):
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (r *Reject) Consume(ts <-chan types.Transaction) error {
+	if r.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	r.transactionsIn = ts
+	go r.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (r *Reject) Connected() bool {
+	return true
+}
+
+// CloseAsync shuts down the Reject output and stops processing requests.
+func (r *Reject) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Reject output has closed down.
+func (r *Reject) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestRejectBasic(t *testing.T) {
+	type testCase struct {
+		requeue  bool
+		rejected bool
+	}
+	for _, test := range []testCase{
+		{requeue: true, rejected: false},
+		{requeue: false, rejected: true},
+	} {
+		conf := NewConfig()
+		conf.Type = TypeReject
+		conf.Reject.Error = "bad message: ${!metadata:reason}"
+		conf.Reject.Requeue = test.requeue
+
+		output, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+		if err = output.Consume(tChan); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := message.New([][]byte{[]byte("foo")})
+		msg.Get(0).Metadata().Set("reason", "too foo")
+
+		select {
+		case tChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+
+		select {
+		case res := <-resChan:
+			err = res.Error()
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		if err == nil {
+			t.Fatal("Expected error response")
+		}
+		rErr, isRejected := err.(types.ErrRejected)
+		if isRejected != test.rejected {
+			t.Errorf("Wrong rejected state for requeue %v: %v", test.requeue, err)
+		}
+		if isRejected {
+			err = rErr.Err
+		}
+		if exp, act := "bad message: too foo", err.Error(); exp != act {
+			t.Errorf("Wrong error message: %v != %v", act, exp)
+		}
+
+		output.CloseAsync()
+		if err = output.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRejectNoError(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeReject
+	conf.Reject.Error = ""
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty error message")
+	}
+}
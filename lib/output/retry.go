@@ -55,7 +55,8 @@ we want to avoid reapplying to the same message more than once in the pipeline.
 
 Rather than retrying the same output you may wish to retry the send using a
 different output target (a dead letter queue). In which case you should instead
-use the ` + "[`broker`](#broker)" + ` output type with the pattern 'try'.`,
+use the ` + "[`dead_letter`](#dead_letter)" + ` output type, or the
+` + "[`broker`](#broker)" + ` output type with the pattern 'try'.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			confBytes, err := json.Marshal(conf.Retry)
 			if err != nil {
@@ -229,10 +230,13 @@ func (r *Retry) loop() {
 
 		var resOut types.Response
 
+		outTran := types.NewTransaction(ts.Payload, resChan)
+		outTran.Prepare = ts.Prepare
+
 	retryLoop:
 		for atomic.LoadInt32(&r.running) == 1 {
 			select {
-			case r.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+			case r.transactionsOut <- outTran:
 			case <-r.closeChan:
 				return
 			}
@@ -266,6 +270,9 @@ func (r *Retry) loop() {
 				mPartsSuccess.Incr(int64(ts.Payload.Len()))
 				r.backoff.Reset()
 				resOut = response.NewAck()
+				if _, ok := res.(types.PreparedWrite); ok {
+					resOut = res
+				}
 				break retryLoop
 			}
 		}
@@ -278,6 +285,12 @@ func (r *Retry) loop() {
 	}
 }
 
+// CanStage returns true if the wrapped output is able to stage writes.
+func (r *Retry) CanStage() bool {
+	s, ok := r.wrapped.(types.Stager)
+	return ok && s.CanStage()
+}
+
 // Consume assigns a messages channel for the output to read.
 func (r *Retry) Consume(ts <-chan types.Transaction) error {
 	if r.transactionsIn != nil {
@@ -41,7 +41,56 @@ interpolations described [here](../config_interpolation.md#functions), which are
 calculated per message of a batch.
 
 The fields ` + "`content_type` and `content_encoding`" + ` can also be set
-dynamically using function interpolation.`,
+dynamically using function interpolation.
+
+### Batching
+
+Each message of a batch is written as its own object. In order to accumulate
+messages into larger objects configure a [` + "`batching`" + `](#batching)
+policy on the output and join each batch with an
+` + "[`archive`](../processors/README.md#archive)" + ` processor:
+
+` + "``` yaml" + `
+output:
+  s3:
+    bucket: foo
+    path: ${!timestamp:2006/01/02/15}/${!timestamp_unix_nano}.log
+  batching:
+    byte_size: 5_000_000
+    period: 1m
+  processors:
+  - archive:
+      format: lines
+` + "```" + `
+
+### Compression and Encryption
+
+Setting ` + "`compression` to `gzip`" + ` compresses each object before it is
+uploaded, in which case ` + "`content_encoding`" + ` defaults to
+` + "`gzip`" + `.
+
+Server side encryption is enabled by setting
+` + "`server_side_encryption`" + ` to either ` + "`AES256` or `aws:kms`" + `,
+where a specific KMS key can be chosen with the field ` + "`kms_key_id`" + `.
+
+### Responses
+
+When ` + "`propagate_response`" + ` is set to ` + "`true`" + ` the identifiers of
+each written object are returned to the input as a
+[synchronous response](../sync_responses.md), where each message is replaced
+with a JSON object containing the fields ` + "`s3_bucket`, `s3_key`" + `,
+` + "`s3_location` and `s3_version_id`" + ` (when versioning is enabled on the
+bucket). This can be used to return the key of an uploaded object to the
+client of an ` + "`http_server`" + ` input.
+
+### Failover
+
+Since bucket names are global a secondary bucket, usually a replica within
+another region, must be given both its ` + "`bucket` and `region`" + ` when
+listed within ` + "`failover.endpoints`" + `. Objects are uploaded to it with the
+same path and settings as the primary whilst the primary is
+[unhealthy](#endpoint-failover), and therefore any ` + "`s3_bucket`" + ` values
+within propagated responses reflect the bucket that was actually written to.`,
 	}
 }
 
@@ -49,7 +98,7 @@ dynamically using function interpolation.`,
 
 // NewAmazonS3 creates a new AmazonS3 output type.
 func NewAmazonS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	sthree, err := writer.NewAmazonS3(conf.S3, log, stats)
+	sthree, err := writer.NewAmazonS3Failover(conf.S3, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,463 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+var (
+	// ErrShardedNoOutputs is returned when creating a Sharded type without any
+	// outputs.
+	ErrShardedNoOutputs = errors.New("attempting to create sharded output without any outputs")
+)
+
+func init() {
+	Constructors[TypeSharded] = TypeSpec{
+		constructor: NewSharded,
+		description: `
+Routes each message of a batch to one of N child outputs, known as shards, based
+on a key resolved from the message with
+[interpolation functions](../config_interpolation.md#functions). This is useful
+for writing to sharded databases or distributing data across sets of files.
+
+The field ` + "`strategy`" + ` determines how a shard is chosen from the key:
+
+- ` + "`hash`" + ` (default): The key is hashed and the shard is chosen by
+  the modulus of the number of outputs. Messages that share a key are always
+  sent to the same shard.
+- ` + "`range`" + `: The key is parsed as a number and is sent to the shard
+  with the matching item of ` + "`ranges`" + `, where ` + "`min`" + ` is
+  inclusive and ` + "`max`" + ` is exclusive. There must be exactly one range
+  per output.
+
+` + "``` yaml" + `
+output:
+  sharded:
+    key: ${!json_field:user.id}
+    strategy: range
+    ranges:
+    - min: 0
+      max: 1000000
+    - min: 1000000
+      max: 2000000
+    outputs:
+    - sql:
+        driver: postgres
+        dsn: postgres://foo@shard-0:5432/users
+        query: INSERT INTO users (id, name) VALUES (?, ?);
+        args:
+        - ${!json_field:user.id}
+        - ${!json_field:user.name}
+    - sql:
+        driver: postgres
+        dsn: postgres://foo@shard-1:5432/users
+        query: INSERT INTO users (id, name) VALUES (?, ?);
+        args:
+        - ${!json_field:user.id}
+        - ${!json_field:user.name}
+` + "```" + `
+
+Each message sent to a shard has the index of that shard added to its metadata
+under the key ` + "`shard`" + `. This allows a set of identical outputs to be
+configured with ` + "`ditto`" + ` that write to distinct targets, which in the
+following example results in four shards each writing to their own directory:
+
+` + "``` yaml" + `
+output:
+  sharded:
+    key: ${!metadata:kafka_key}
+    outputs:
+    - files:
+        path: ./shard-${!metadata:shard}/${!count:files}.json
+    - type: ditto_3
+` + "```" + `
+
+If the key of any message within a batch cannot be routed to a shard, due to it
+being non-numeric or outside of every range, then the whole batch is rejected
+with an error.
+
+If an output fails to send a message it will be retried continuously until
+completion or service shut down, and only the shards that failed are retried.
+
+### Metrics
+
+The count of messages sent to each shard is tracked under the metric path
+` + "`sharded.shard.<index>.messages.sent`" + `, which can be used in order to
+monitor how evenly data is distributed across shards and whether a rebalancing
+of ranges is required.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			outSlice := []interface{}{}
+			for _, output := range conf.Sharded.Outputs {
+				sanOutput, err := SanitiseConfig(output)
+				if err != nil {
+					return nil, err
+				}
+				outSlice = append(outSlice, sanOutput)
+			}
+			rangeSlice := []interface{}{}
+			for _, r := range conf.Sharded.Ranges {
+				rangeSlice = append(rangeSlice, map[string]interface{}{
+					"min": r.Min,
+					"max": r.Max,
+				})
+			}
+			return map[string]interface{}{
+				"key":      conf.Sharded.Key,
+				"strategy": conf.Sharded.Strategy,
+				"ranges":   rangeSlice,
+				"outputs":  outSlice,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ShardedConfig contains configuration fields for the Sharded output type.
+type ShardedConfig struct {
+	Key      string               `json:"key" yaml:"key"`
+	Strategy string               `json:"strategy" yaml:"strategy"`
+	Ranges   []ShardedRangeConfig `json:"ranges" yaml:"ranges"`
+	Outputs  brokerOutputList     `json:"outputs" yaml:"outputs"`
+}
+
+// NewShardedConfig creates a new ShardedConfig with default values.
+func NewShardedConfig() ShardedConfig {
+	return ShardedConfig{
+		Key:      "",
+		Strategy: "hash",
+		Ranges:   []ShardedRangeConfig{},
+		Outputs:  brokerOutputList{},
+	}
+}
+
+// ShardedRangeConfig contains the bounds of a key range routed to a shard.
+type ShardedRangeConfig struct {
+	Min float64 `json:"min" yaml:"min"`
+	Max float64 `json:"max" yaml:"max"`
+}
+
+//------------------------------------------------------------------------------
+
+// Sharded is an output type that routes each message of a batch to one of
+// many child outputs based on a key.
+type Sharded struct {
+	running int32
+
+	logger log.Modular
+	stats  metrics.Type
+
+	throt *throttle.Type
+
+	key    *text.InterpolatedString
+	ranges []ShardedRangeConfig
+
+	transactions <-chan types.Transaction
+
+	outputs        []types.Output
+	outputTsChans  []chan types.Transaction
+	outputResChans []chan types.Response
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewSharded creates a new Sharded output type.
+func NewSharded(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	lOutputs := len(conf.Sharded.Outputs)
+	if lOutputs == 0 {
+		return nil, ErrShardedNoOutputs
+	}
+	if len(conf.Sharded.Key) == 0 {
+		return nil, errors.New("a key must be specified")
+	}
+
+	o := &Sharded{
+		running:    1,
+		stats:      stats,
+		logger:     logger,
+		key:        text.NewInterpolatedString(conf.Sharded.Key),
+		outputs:    make([]types.Output, lOutputs),
+		closedChan: make(chan struct{}),
+		closeChan:  make(chan struct{}),
+	}
+
+	switch conf.Sharded.Strategy {
+	case "hash", "":
+	case "range":
+		if len(conf.Sharded.Ranges) != lOutputs {
+			return nil, fmt.Errorf("number of ranges (%v) must match the number of outputs (%v)", len(conf.Sharded.Ranges), lOutputs)
+		}
+		for i, r := range conf.Sharded.Ranges {
+			if r.Min >= r.Max {
+				return nil, fmt.Errorf("range '%v' min (%v) must be less than max (%v)", i, r.Min, r.Max)
+			}
+		}
+		o.ranges = conf.Sharded.Ranges
+	default:
+		return nil, fmt.Errorf("sharding strategy not recognised: %v", conf.Sharded.Strategy)
+	}
+
+	var err error
+	for i, oConf := range conf.Sharded.Outputs {
+		ns := fmt.Sprintf("sharded.%v", i)
+		if o.outputs[i], err = New(
+			oConf, mgr,
+			logger.NewModule("."+ns),
+			metrics.Combine(stats, metrics.Namespaced(stats, ns)),
+		); err != nil {
+			return nil, fmt.Errorf("failed to create output '%v' type '%v': %v", i, oConf.Type, err)
+		}
+	}
+
+	o.throt = throttle.New(throttle.OptCloseChan(o.closeChan))
+
+	o.outputTsChans = make([]chan types.Transaction, lOutputs)
+	o.outputResChans = make([]chan types.Response, lOutputs)
+	for i := range o.outputTsChans {
+		o.outputTsChans[i] = make(chan types.Transaction)
+		o.outputResChans[i] = make(chan types.Response)
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the output to read.
+func (o *Sharded) Consume(transactions <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = transactions
+
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *Sharded) Connected() bool {
+	for _, out := range o.outputs {
+		if !out.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// shardIndex returns the index of the shard that a key should be routed to.
+func (o *Sharded) shardIndex(key string) (int, error) {
+	if o.ranges == nil {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return int(h.Sum32() % uint32(len(o.outputs))), nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(key), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse key '%v' as a number: %v", key, err)
+	}
+	for i, r := range o.ranges {
+		if v >= r.Min && v < r.Max {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("key '%v' does not match any range", key)
+}
+
+// shardMessage splits a message into a batch per shard, where shards that
+// receive no messages are nil.
+func (o *Sharded) shardMessage(msg types.Message) ([]types.Message, error) {
+	shardParts := make([][]types.Part, len(o.outputs))
+	if err := msg.Iter(func(i int, p types.Part) error {
+		index, err := o.shardIndex(o.key.Get(message.Lock(msg, i)))
+		if err != nil {
+			return err
+		}
+		part := p.Copy()
+		part.Metadata().Set("shard", strconv.Itoa(index))
+		shardParts[index] = append(shardParts[index], part)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	shards := make([]types.Message, len(o.outputs))
+	for i, parts := range shardParts {
+		if len(parts) > 0 {
+			shards[i] = message.New(nil)
+			shards[i].SetAll(parts)
+		}
+	}
+	return shards, nil
+}
+
+// loop is an internal loop that routes incoming messages to outputs.
+func (o *Sharded) loop() {
+	var (
+		mMsgRcvd   = o.stats.GetCounter("sharded.messages.received")
+		mMsgRej    = o.stats.GetCounter("sharded.messages.rejected")
+		mMsgSnt    = o.stats.GetCounter("sharded.messages.sent")
+		mOutputErr = o.stats.GetCounter("sharded.output.error")
+		mShardSnt  = make([]metrics.StatCounter, len(o.outputs))
+	)
+	for i := range mShardSnt {
+		mShardSnt[i] = o.stats.GetCounter(fmt.Sprintf("sharded.shard.%v.messages.sent", i))
+	}
+
+	defer func() {
+		for i, output := range o.outputs {
+			output.CloseAsync()
+			close(o.outputTsChans[i])
+		}
+		for _, output := range o.outputs {
+			if err := output.WaitForClose(time.Second); err != nil {
+				for err != nil {
+					err = output.WaitForClose(time.Second)
+				}
+			}
+		}
+		close(o.closedChan)
+	}()
+
+	for atomic.LoadInt32(&o.running) == 1 {
+		var ts types.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-o.transactions:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mMsgRcvd.Incr(1)
+
+		shards, err := o.shardMessage(ts.Payload)
+		if err != nil {
+			o.logger.Errorf("Failed to route message to shard: %v\n", err)
+			select {
+			case ts.ResponseChan <- response.NewError(err):
+				mMsgRej.Incr(1)
+			case <-o.closeChan:
+				return
+			}
+			continue
+		}
+
+		var outputTargets []int
+		for i, shard := range shards {
+			if shard != nil {
+				outputTargets = append(outputTargets, i)
+			}
+		}
+
+		var skipAck bool
+		for len(outputTargets) > 0 {
+			for _, i := range outputTargets {
+				select {
+				case o.outputTsChans[i] <- types.NewTransaction(shards[i].Copy(), o.outputResChans[i]):
+				case <-o.closeChan:
+					return
+				}
+			}
+			newTargets := []int{}
+			for _, i := range outputTargets {
+				select {
+				case res := <-o.outputResChans[i]:
+					if res.Error() != nil {
+						newTargets = append(newTargets, i)
+						o.logger.Errorf("Failed to dispatch sharded message: %v\n", res.Error())
+						mOutputErr.Incr(1)
+						if !o.throt.Retry() {
+							return
+						}
+					} else {
+						o.throt.Reset()
+						mMsgSnt.Incr(1)
+						mShardSnt[i].Incr(int64(shards[i].Len()))
+						if res.SkipAck() {
+							skipAck = true
+						}
+					}
+				case <-o.closeChan:
+					return
+				}
+			}
+			outputTargets = newTargets
+		}
+
+		var res types.Response = response.NewAck()
+		if skipAck {
+			res = response.NewUnack()
+		}
+		select {
+		case ts.ResponseChan <- res:
+		case <-o.closeChan:
+			return
+		}
+	}
+}
+
+// CloseAsync shuts down the Sharded output and stops processing requests.
+func (o *Sharded) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Sharded output has closed down.
+func (o *Sharded) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
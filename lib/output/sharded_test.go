@@ -0,0 +1,249 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newSharded(conf Config, mockOutputs []*MockOutputType) (*Sharded, error) {
+	conf.Type = TypeSharded
+	for range mockOutputs {
+		conf.Sharded.Outputs = append(conf.Sharded.Outputs, NewConfig())
+	}
+	genType, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		return nil, err
+	}
+
+	rType, ok := genType.(*Sharded)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast: %T", genType)
+	}
+
+	for i := 0; i < len(mockOutputs); i++ {
+		close(rType.outputTsChans[i])
+		rType.outputs[i] = mockOutputs[i]
+		rType.outputTsChans[i] = make(chan types.Transaction)
+		mockOutputs[i].Consume(rType.outputTsChans[i])
+	}
+	return rType, nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestShardedRange(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}}
+
+	conf := NewConfig()
+	conf.Sharded.Key = "${!json_field:id}"
+	conf.Sharded.Strategy = "range"
+	conf.Sharded.Ranges = []ShardedRangeConfig{
+		{Min: 0, Max: 10},
+		{Min: 10, Max: 20},
+	}
+
+	s, err := newSharded(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":5}`),
+		[]byte(`{"id":15}`),
+		[]byte(`{"id":7}`),
+	})
+	select {
+	case readChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for output send")
+	}
+
+	expParts := [][]string{
+		{`{"id":5}`, `{"id":7}`},
+		{`{"id":15}`},
+	}
+	var tranChans []chan<- types.Response
+	for i, mock := range mockOutputs {
+		select {
+		case ts := <-mock.TChan:
+			if exp, act := expParts[i], message.GetAllBytes(ts.Payload); len(exp) != len(act) {
+				t.Errorf("Wrong count of parts for shard %v: %v != %v", i, len(act), len(exp))
+			} else {
+				for j := range exp {
+					if exp[j] != string(act[j]) {
+						t.Errorf("Wrong part %v for shard %v: %s != %v", j, i, act[j], exp[j])
+					}
+					if exp, act := fmt.Sprintf("%v", i), ts.Payload.Get(j).Metadata().Get("shard"); exp != act {
+						t.Errorf("Wrong shard metadata: %v != %v", act, exp)
+					}
+				}
+			}
+			tranChans = append(tranChans, ts.ResponseChan)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for shard %v", i)
+		}
+	}
+	for _, rChan := range tranChans {
+		select {
+		case rChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+
+	msg = message.New([][]byte{
+		[]byte(`{"id":5}`),
+		[]byte(`{"id":25}`),
+	})
+	select {
+	case readChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for output send")
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() == nil {
+			t.Error("Expected error from key outside of ranges")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+
+	s.CloseAsync()
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShardedHash(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+
+	conf := NewConfig()
+	conf.Sharded.Key = "${!metadata:key}"
+
+	s, err := newSharded(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	shardForKey := map[string]string{}
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key%v", i%5)
+
+		msg := message.New([][]byte{[]byte("hello world")})
+		msg.Get(0).Metadata().Set("key", key)
+		select {
+		case readChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for output send")
+		}
+
+		var ts types.Transaction
+		select {
+		case ts = <-mockOutputs[0].TChan:
+		case ts = <-mockOutputs[1].TChan:
+		case ts = <-mockOutputs[2].TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for shard")
+		}
+
+		shard := ts.Payload.Get(0).Metadata().Get("shard")
+		if prev, exists := shardForKey[key]; exists && prev != shard {
+			t.Errorf("Key %v routed to shard %v after shard %v", key, shard, prev)
+		}
+		shardForKey[key] = shard
+
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+	}
+
+	s.CloseAsync()
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShardedBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSharded
+	conf.Sharded.Key = "${!metadata:key}"
+	if _, err := NewSharded(conf, nil, log.Noop(), metrics.Noop()); err != ErrShardedNoOutputs {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrShardedNoOutputs)
+	}
+
+	conf.Sharded.Outputs = append(conf.Sharded.Outputs, NewConfig(), NewConfig())
+	conf.Sharded.Strategy = "range"
+	conf.Sharded.Ranges = []ShardedRangeConfig{{Min: 0, Max: 10}}
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from mismatched ranges")
+	}
+
+	conf.Sharded.Strategy = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad strategy")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSnowflake] = TypeSpec{
+		constructor: NewSnowflake,
+		description: `
+Writes each message batch as a file to an S3 bucket configured as an external
+Snowflake stage, and then calls the Snowpipe REST API in order to have the file
+ingested by a pipe. Files are written either as newline delimited JSON
+(` + "`format: ndjson`" + `), in which case each message must be a valid JSON
+document, or as CSV (` + "`format: csv`" + `), where each message is written
+as a line.
+
+Snowpipe requests are authenticated with key pair authentication, where
+` + "`private_key_file`" + ` is a path to an unencrypted PEM encoded RSA
+private key (PKCS#1 or PKCS#8) whose public key has been assigned to the
+configured ` + "`user`" + `.
+
+The fields ` + "`pipe` and `stage.path`" + ` support
+[interpolation functions](../config_interpolation.md#functions) resolved per
+batch, which can be used to target different tables per batch:
+
+` + "``` yaml" + `
+pipe: MYDB.PUBLIC.${!metadata:table}_PIPE
+` + "```" + `
+
+When ` + "`stage.path`" + ` is left empty files are named with a counter and a
+timestamp, and given the extension ` + "`.json` or `.csv`" + ` of the format.
+
+The pipe must be created with a ` + "`COPY INTO`" + ` statement that reads
+from a stage pointing to the configured bucket and ` + "`stage.prefix`" + `, as
+file paths are sent to Snowpipe relative to that prefix.
+
+Messages should be [batched](../batching.md) before reaching this output in
+order to avoid staging a large number of small files.
+
+### Internal Stages
+
+Only external stages backed by S3 are supported. Internal (Snowflake managed)
+stages can only be written to with the ` + "`PUT`" + ` command of a Snowflake
+SQL session, which this output does not open, and so a bucket must always be
+configured with ` + "`stage.bucket`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSnowflake creates a new Snowflake output type.
+func NewSnowflake(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSnowflake(conf.Snowflake, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(
+		"snowflake", s, log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSocket] = TypeSpec{
+		constructor: NewSocket,
+		description: `
+Writes messages to a socket, where ` + "`network`" + ` can be one of ` + "`tcp`" + `,
+` + "`udp` or `unix`" + `. For the ` + "`unix`" + ` network the address is a
+file path.
+
+### Framing
+
+Each message of a batch is framed and written individually according to
+` + "`framing`" + `, which can be one of the following:
+
+- ` + "`delimiter`" + `: Each message is followed by ` + "`delimiter`" + `,
+  which defaults to a newline when left empty.
+- ` + "`length_prefixed`" + `: Each message is preceded by its length as an
+  unsigned 32-bit big-endian integer.
+
+With the ` + "`udp`" + ` network each framed message is sent as a single
+datagram.
+
+### Reconnection
+
+Each message is written with a deadline set by ` + "`write_timeout`" + `, and
+a connection is considered lost when a write fails. Attempts to reconnect are
+made with an exponential backoff between
+` + "`reconnect.initial_interval` and `reconnect.max_interval`" + `. A batch
+that fails to be written is attempted again in full once reconnected, which can
+result in duplicate messages.
+
+TLS is supported for the ` + "`tcp` and `unix`" + ` networks only.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSocket creates a new Socket output type.
+func NewSocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSocket(conf.Socket, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("socket", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build SQLITE
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSQLite] = TypeSpec{
+		constructor: NewSQLite,
+		description: `
+Appends messages to a queue table within a local SQLite database file, which is
+created if it does not already exist. Paired with the
+` + "[`sqlite`](../inputs/README.md#sqlite)" + ` input this provides durable
+store-and-forward for small deployments without needing to run a message
+broker, where one stream writes to the file and another consumes from it:
+
+` + "``` yaml" + `
+output:
+  sqlite:
+    path: /var/lib/benthos/queue.db
+` + "```" + `
+
+All messages of a batch are appended within a single transaction.
+
+` + bsql.QueueDocumentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQLite creates a new SQLite output type.
+func NewSQLite(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSQLite(conf.SQLite, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(
+		"sqlite", s, log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
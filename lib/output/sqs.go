@@ -33,7 +33,22 @@ func init() {
 	Constructors[TypeSQS] = TypeSpec{
 		constructor: NewAmazonSQS,
 		description: `
-Sends messages to an SQS queue.`,
+Sends messages to an SQS queue.
+
+SQS limits the total size of a batch send to 256KiB, and therefore batches that
+exceed ` + "`max_batch_bytes`" + ` are broken down into multiple sends. Setting
+the field to zero disables this behaviour.
+
+A queue in a secondary region can be configured with ` + "`failover.endpoints`" + `,
+each specifying the ` + "`region`" + ` and ` + "`url`" + ` of the queue to send
+to whilst the primary is [unhealthy](#endpoint-failover):
+
+` + "``` yaml" + `
+failover:
+  endpoints:
+  - region: eu-central-1
+    url: https://sqs.eu-central-1.amazonaws.com/123456789012/backup
+` + "```",
 	}
 }
 
@@ -41,7 +56,7 @@ Sends messages to an SQS queue.`,
 
 // NewAmazonSQS creates a new AmazonSQS output type.
 func NewAmazonSQS(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	s, err := writer.NewAmazonSQS(conf.SQS, log, stats)
+	s, err := writer.NewAmazonSQSFailover(conf.SQS, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -21,6 +21,7 @@
 package output
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -42,7 +43,23 @@ as:
 
 foo\n
 bar\n
-baz\n\n`,
+baz\n\n
+
+### Codecs
+
+The format of written batches can be changed with the field ` + "`codec`" + `,
+which can be one of the following:
+
+- ` + "`lines`" + `: The default format described above.
+- ` + "`json_array`" + `: Each batch is written as a JSON array of its parts
+  followed by a delimiter, where each part must be valid JSON. A batch of size
+  one is therefore written as a single element array.
+- ` + "`length_prefixed`" + `: Each part is prefixed with its length in bytes
+  as a 32-bit big endian unsigned integer, and delimiters are not written.
+- ` + "`hex`" + `: Each part is hex encoded and written as with ` + "`lines`" + `.
+
+Messages are written to stdout unless ` + "`target`" + ` is set to
+` + "`stderr`" + `, which keeps stdout free for other uses such as logs.`,
 	}
 }
 
@@ -50,13 +67,17 @@ baz\n\n`,
 
 // STDOUTConfig contains configuration fields for the stdout based output type.
 type STDOUTConfig struct {
-	Delim string `json:"delimiter" yaml:"delimiter"`
+	Codec  string `json:"codec" yaml:"codec"`
+	Delim  string `json:"delimiter" yaml:"delimiter"`
+	Target string `json:"target" yaml:"target"`
 }
 
 // NewSTDOUTConfig creates a new STDOUTConfig with default values.
 func NewSTDOUTConfig() STDOUTConfig {
 	return STDOUTConfig{
-		Delim: "",
+		Codec:  "lines",
+		Delim:  "",
+		Target: "stdout",
 	}
 }
 
@@ -64,7 +85,15 @@ func NewSTDOUTConfig() STDOUTConfig {
 
 // NewSTDOUT creates a new STDOUT output type.
 func NewSTDOUT(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	return NewLineWriter(os.Stdout, false, []byte(conf.STDOUT.Delim), "stdout", log, stats)
+	handle := os.Stdout
+	switch conf.STDOUT.Target {
+	case "stdout":
+	case "stderr":
+		handle = os.Stderr
+	default:
+		return nil, fmt.Errorf("target not recognised: %v", conf.STDOUT.Target)
+	}
+	return NewLineWriterWithCodec(handle, false, []byte(conf.STDOUT.Delim), conf.STDOUT.Codec, "stdout", log, stats)
 }
 
 //------------------------------------------------------------------------------
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/Jeffail/benthos/lib/util/throttle"
 )
 
@@ -77,7 +78,7 @@ output:
         text:
           operator: contains
           arg: foo
-      fallthrough: true
+      continue: true
     - output:
         bar:
           bar_field_1: value2
@@ -86,7 +87,7 @@ output:
         text:
           operator: contains
           arg: bar
-      fallthrough: true
+      continue: true
     - output:
         baz:
           baz_field_1: value4
@@ -98,11 +99,49 @@ output:
 
 The switch output requires a minimum of two outputs. If no condition is defined
 for an output, it behaves like a static ` + "`true`" + ` condition. If
-` + "`fallthrough`" + ` is set to ` + "`true`" + `, the switch output will
-continue evaluating additional outputs after finding a match.
+` + "`continue`" + ` is set to ` + "`true`" + `, the switch output will
+continue evaluating additional outputs after finding a match, and therefore
+messages can be sent to multiple matching outputs. The field
+` + "`fallthrough`" + ` is an older name for ` + "`continue`" + ` and has
+the same effect.
 
-Messages that do not match any outputs will be dropped. If an output applies
-back pressure it will block all subsequent messages.
+### Interpolated Routing
+
+Outputs can also be matched by comparing the result of an
+[interpolated string](../config_interpolation.md#functions) within the field
+` + "`match`" + ` against the field ` + "`equals`" + `, which is often simpler
+than writing a condition:
+
+` + "``` yaml" + `
+output:
+  switch:
+    unmatched: reject
+    outputs:
+    - match: ${!metadata:kafka_topic}
+      equals: orders
+      output:
+        foo:
+          foo_field_1: value1
+    - match: ${!metadata:kafka_topic}
+      equals: payments
+      output:
+        bar:
+          bar_field_1: value2
+` + "```" + `
+
+When both a ` + "`match`" + ` and a condition are set then both must pass for
+the output to be matched.
+
+### Unmatched Messages
+
+The field ` + "`unmatched`" + ` determines what happens to messages that do not
+match any outputs. When set to ` + "`drop`" + ` (the default) they are
+acknowledged and dropped. When set to ` + "`reject`" + ` an error is returned
+for them instead, which results in them being reprocessed or nacked depending
+on the input. Messages can also be routed to a default output by adding it as
+the last output without a condition or match.
+
+If an output applies back pressure it will block all subsequent messages.
 
 If an output fails to send a message it will be retried continuously until
 completion or service shut down. You can change this behaviour so that when an
@@ -124,12 +163,16 @@ duplicate messages aren't introduced during error conditions.`,
 				sanit := map[string]interface{}{
 					"output":      sanOutput,
 					"fallthrough": out.Fallthrough,
+					"continue":    out.Continue,
+					"match":       out.Match,
+					"equals":      out.Equals,
 					"condition":   sanCond,
 				}
 				outSlice = append(outSlice, sanit)
 			}
 			return map[string]interface{}{
 				"retry_until_success": conf.Switch.RetryUntilSuccess,
+				"unmatched":           conf.Switch.Unmatched,
 				"outputs":             outSlice,
 			}, nil
 		},
@@ -141,6 +184,7 @@ duplicate messages aren't introduced during error conditions.`,
 // SwitchConfig contains configuration fields for the Switch output type.
 type SwitchConfig struct {
 	RetryUntilSuccess bool                 `json:"retry_until_success" yaml:"retry_until_success"`
+	Unmatched         string               `json:"unmatched" yaml:"unmatched"`
 	Outputs           []SwitchConfigOutput `json:"outputs" yaml:"outputs"`
 }
 
@@ -148,6 +192,7 @@ type SwitchConfig struct {
 func NewSwitchConfig() SwitchConfig {
 	return SwitchConfig{
 		RetryUntilSuccess: true,
+		Unmatched:         "drop",
 		Outputs:           []SwitchConfigOutput{},
 	}
 }
@@ -155,6 +200,9 @@ func NewSwitchConfig() SwitchConfig {
 // SwitchConfigOutput contains configuration fields per output of a switch type.
 type SwitchConfigOutput struct {
 	Condition   condition.Config `json:"condition" yaml:"condition"`
+	Match       string           `json:"match" yaml:"match"`
+	Equals      string           `json:"equals" yaml:"equals"`
+	Continue    bool             `json:"continue" yaml:"continue"`
 	Fallthrough bool             `json:"fallthrough" yaml:"fallthrough"`
 	Output      Config           `json:"output" yaml:"output"`
 }
@@ -167,6 +215,9 @@ func NewSwitchConfigOutput() SwitchConfigOutput {
 
 	return SwitchConfigOutput{
 		Condition:   cond,
+		Match:       "",
+		Equals:      "",
+		Continue:    false,
 		Fallthrough: false,
 		Output:      NewConfig(),
 	}
@@ -220,8 +271,11 @@ type Switch struct {
 	outputResChans []chan types.Response
 
 	retryUntilSuccess bool
+	rejectUnmatched   bool
 	outputs           []types.Output
 	conditions        []types.Condition
+	matches           []*text.InterpolatedString
+	equals            []string
 	fallthroughs      []bool
 
 	closedChan chan struct{}
@@ -248,12 +302,22 @@ func NewSwitch(
 		transactions:      nil,
 		outputs:           make([]types.Output, lOutputs),
 		conditions:        make([]types.Condition, lOutputs),
+		matches:           make([]*text.InterpolatedString, lOutputs),
+		equals:            make([]string, lOutputs),
 		fallthroughs:      make([]bool, lOutputs),
 		retryUntilSuccess: conf.Switch.RetryUntilSuccess,
 		closedChan:        make(chan struct{}),
 		closeChan:         make(chan struct{}),
 	}
 
+	switch conf.Switch.Unmatched {
+	case "drop", "":
+	case "reject":
+		o.rejectUnmatched = true
+	default:
+		return nil, fmt.Errorf("unmatched behaviour not recognised: %v", conf.Switch.Unmatched)
+	}
+
 	var err error
 	for i, oConf := range conf.Switch.Outputs {
 		ns := fmt.Sprintf("switch.%v", i)
@@ -271,7 +335,11 @@ func NewSwitch(
 		); err != nil {
 			return nil, fmt.Errorf("failed to create output '%v' condition '%v': %v", i, oConf.Condition.Type, err)
 		}
-		o.fallthroughs[i] = oConf.Fallthrough
+		if len(oConf.Match) > 0 {
+			o.matches[i] = text.NewInterpolatedString(oConf.Match)
+		}
+		o.equals[i] = oConf.Equals
+		o.fallthroughs[i] = oConf.Continue || oConf.Fallthrough
 	}
 
 	o.throt = throttle.New(throttle.OptCloseChan(o.closeChan))
@@ -318,6 +386,7 @@ func (o *Switch) Connected() bool {
 func (o *Switch) loop() {
 	var (
 		mMsgDrop   = o.stats.GetCounter("switch.messages.dropped")
+		mMsgRej    = o.stats.GetCounter("switch.messages.rejected")
 		mMsgRcvd   = o.stats.GetCounter("switch.messages.received")
 		mMsgSnt    = o.stats.GetCounter("switch.messages.sent")
 		mOutputErr = o.stats.GetCounter("switch.output.error")
@@ -354,6 +423,9 @@ func (o *Switch) loop() {
 
 		var outputTargets []int
 		for i, oCond := range o.conditions {
+			if o.matches[i] != nil && o.matches[i].Get(ts.Payload) != o.equals[i] {
+				continue
+			}
 			if oCond.Check(ts.Payload) {
 				outputTargets = append(outputTargets, i)
 				if !o.fallthroughs[i] {
@@ -362,9 +434,17 @@ func (o *Switch) loop() {
 			}
 		}
 		if len(outputTargets) == 0 {
+			var res types.Response = response.NewAck()
+			if o.rejectUnmatched {
+				res = response.NewError(ErrSwitchNoConditionMet)
+			}
 			select {
-			case ts.ResponseChan <- response.NewAck():
-				mMsgDrop.Incr(1)
+			case ts.ResponseChan <- res:
+				if o.rejectUnmatched {
+					mMsgRej.Incr(1)
+				} else {
+					mMsgDrop.Incr(1)
+				}
 			case <-o.closeChan:
 				return
 			}
@@ -401,6 +401,138 @@ func TestSwitchNoMatch(t *testing.T) {
 	}
 }
 
+func TestSwitchNoMatchReject(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}}
+
+	conf := NewConfig()
+	conf.Switch.Unmatched = "reject"
+	for i := 0; i < len(mockOutputs); i++ {
+		conf.Switch.Outputs = append(conf.Switch.Outputs, NewSwitchConfigOutput())
+		conf.Switch.Outputs[i].Match = "${!metadata:topic}"
+		conf.Switch.Outputs[i].Equals = fmt.Sprintf("topic%v", i)
+	}
+
+	s, err := newSwitch(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`hello world`)})
+	msg.Get(0).Metadata().Set("topic", "nope")
+	select {
+	case readChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for output send")
+	}
+
+	select {
+	case res := <-resChan:
+		if exp, act := ErrSwitchNoConditionMet, res.Error(); exp != act {
+			t.Errorf("Wrong error returned: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to output")
+	}
+
+	s.CloseAsync()
+
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSwitchInterpolatedMatch(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+
+	conf := NewConfig()
+	for i := 0; i < len(mockOutputs); i++ {
+		conf.Switch.Outputs = append(conf.Switch.Outputs, NewSwitchConfigOutput())
+	}
+	conf.Switch.Outputs[0].Match = "${!metadata:topic}"
+	conf.Switch.Outputs[0].Equals = "foo"
+	conf.Switch.Outputs[0].Continue = true
+	conf.Switch.Outputs[1].Match = "${!metadata:topic}"
+	conf.Switch.Outputs[1].Equals = "bar"
+
+	s, err := newSwitch(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		topic   string
+		outputs []int
+	}{
+		{topic: "foo", outputs: []int{0, 2}},
+		{topic: "bar", outputs: []int{1}},
+		{topic: "baz", outputs: []int{2}},
+	}
+
+	for _, test := range tests {
+		msg := message.New([][]byte{[]byte(test.topic)})
+		msg.Get(0).Metadata().Set("topic", test.topic)
+		select {
+		case readChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for output send")
+		}
+
+		for _, i := range test.outputs {
+			select {
+			case ts := <-mockOutputs[i].TChan:
+				if exp, act := test.topic, string(ts.Payload.Get(0).Get()); exp != act {
+					t.Errorf("Wrong message received by output %v: %v != %v", i, act, exp)
+				}
+				go func() {
+					ts.ResponseChan <- response.NewAck()
+				}()
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for output %v to receive topic %v", i, test.topic)
+			}
+		}
+
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Errorf("Received unexpected errors from output: %v", res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+	}
+
+	s.CloseAsync()
+
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSwitchBadUnmatched(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSwitch
+	conf.Switch.Unmatched = "nope"
+	conf.Switch.Outputs = append(conf.Switch.Outputs, NewSwitchConfigOutput(), NewSwitchConfigOutput())
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad unmatched behaviour")
+	}
+}
+
 func TestSwitchWithConditionsNoFallthrough(t *testing.T) {
 	nMsgs := 100
 
@@ -33,7 +33,17 @@ func init() {
 	Constructors[TypeWebsocket] = TypeSpec{
 		constructor: NewWebsocket,
 		description: `
-Sends messages to an HTTP server via a websocket connection.`,
+Sends messages to an HTTP server via a websocket connection.
+
+Each message is written with a deadline set by ` + "`write_timeout`" + `, and
+a connection is considered lost when a write fails or the server closes it.
+Attempts to reconnect are made with an exponential backoff between
+` + "`reconnect.initial_interval` and `reconnect.max_interval`" + `.
+
+By default a batch that fails to be written due to a lost connection is
+attempted again in full once reconnected, which can result in duplicate
+messages. When ` + "`buffered_retry`" + ` is set to ` + "`true`" + ` only the
+messages of the batch that were not yet written are sent after reconnecting.`,
 	}
 }
 
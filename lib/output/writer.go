@@ -21,6 +21,8 @@
 package output
 
 import (
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -40,8 +42,10 @@ type Writer struct {
 	running     int32
 	isConnected int32
 
-	typeStr string
-	writer  writer.Type
+	typeStr     string
+	writer      writer.Type
+	maxInFlight int
+	reconnMut   sync.Mutex
 
 	log   log.Modular
 	stats metrics.Type
@@ -63,6 +67,7 @@ func NewWriter(
 		running:      1,
 		typeStr:      typeStr,
 		writer:       w,
+		maxInFlight:  1,
 		log:          log,
 		stats:        stats,
 		transactions: nil,
@@ -73,21 +78,38 @@ func NewWriter(
 
 //------------------------------------------------------------------------------
 
+// writerMetrics contains the metrics shared by each write loop of a Writer.
+type writerMetrics struct {
+	count        metrics.StatCounter
+	partsCount   metrics.StatCounter
+	success      metrics.StatCounter
+	partsSuccess metrics.StatCounter
+	err          metrics.StatCounter
+	sent         metrics.StatCounter
+	partsSent    metrics.StatCounter
+	conn         metrics.StatCounter
+	failedConn   metrics.StatCounter
+	lostConn     metrics.StatCounter
+}
+
 // loop is an internal loop that brokers incoming messages to output pipe.
 func (w *Writer) loop() {
 	// Metrics paths
 	var (
-		mRunning      = w.stats.GetGauge("running")
-		mCount        = w.stats.GetCounter("count")
-		mPartsCount   = w.stats.GetCounter("parts.count")
-		mSuccess      = w.stats.GetCounter("send.success")
-		mPartsSuccess = w.stats.GetCounter("parts.send.success")
-		mError        = w.stats.GetCounter("send.error")
-		mSent         = w.stats.GetCounter("batch.sent")
-		mPartsSent    = w.stats.GetCounter("sent")
-		mConn         = w.stats.GetCounter("connection.up")
-		mFailedConn   = w.stats.GetCounter("connection.failed")
-		mLostConn     = w.stats.GetCounter("connection.lost")
+		mRunning     = w.stats.GetGauge("running")
+		mMaxInFlight = w.stats.GetGauge("max_in_flight")
+		m            = writerMetrics{
+			count:        w.stats.GetCounter("count"),
+			partsCount:   w.stats.GetCounter("parts.count"),
+			success:      w.stats.GetCounter("send.success"),
+			partsSuccess: w.stats.GetCounter("parts.send.success"),
+			err:          w.stats.GetCounter("send.error"),
+			sent:         w.stats.GetCounter("batch.sent"),
+			partsSent:    w.stats.GetCounter("sent"),
+			conn:         w.stats.GetCounter("connection.up"),
+			failedConn:   w.stats.GetCounter("connection.failed"),
+			lostConn:     w.stats.GetCounter("connection.lost"),
+		}
 	)
 
 	defer func() {
@@ -99,6 +121,7 @@ func (w *Writer) loop() {
 		close(w.closedChan)
 	}()
 	mRunning.Incr(1)
+	mMaxInFlight.Set(int64(w.maxInFlight))
 
 	throt := throttle.New(throttle.OptCloseChan(w.closeChan))
 
@@ -110,7 +133,7 @@ func (w *Writer) loop() {
 			}
 
 			w.log.Errorf("Failed to connect to %v: %v\n", w.typeStr, err)
-			mFailedConn.Incr(1)
+			m.failedConn.Incr(1)
 			if !throt.Retry() {
 				return
 			}
@@ -118,9 +141,72 @@ func (w *Writer) loop() {
 			break
 		}
 	}
-	mConn.Incr(1)
+	m.conn.Incr(1)
 	atomic.StoreInt32(&w.isConnected, 1)
 
+	if w.maxInFlight <= 1 {
+		w.writeLoop(m, throt)
+		return
+	}
+
+	// Each write loop reads transactions from the shared channel, and since
+	// every transaction carries its own response channel the acknowledgement
+	// of each batch is unaffected by the order in which they complete.
+	wg := sync.WaitGroup{}
+	wg.Add(w.maxInFlight)
+	for i := 0; i < w.maxInFlight; i++ {
+		go func() {
+			defer wg.Done()
+			w.writeLoop(m, throttle.New(throttle.OptCloseChan(w.closeChan)))
+		}()
+	}
+	wg.Wait()
+}
+
+// reconnect attempts to reestablish a lost connection, and then repeats a
+// write. Reconnects are serialised across write loops, and a loop that finds
+// the connection already reestablished by another simply repeats its write.
+func (w *Writer) reconnect(m writerMetrics, throt *throttle.Type, write func() error) error {
+	w.reconnMut.Lock()
+	defer w.reconnMut.Unlock()
+
+	if atomic.LoadInt32(&w.isConnected) == 1 {
+		if err := write(); err != types.ErrNotConnected {
+			return err
+		}
+		m.lostConn.Incr(1)
+		atomic.StoreInt32(&w.isConnected, 0)
+	}
+
+	// Continue to try to reconnect while still active.
+	err := types.ErrNotConnected
+	for atomic.LoadInt32(&w.running) == 1 {
+		if err = w.writer.Connect(); err != nil {
+			// Close immediately if our writer is closed.
+			if err == types.ErrTypeClosed {
+				return err
+			}
+
+			w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
+			m.failedConn.Incr(1)
+			if !throt.Retry() {
+				return types.ErrTypeClosed
+			}
+		} else if err = write(); err != types.ErrNotConnected {
+			atomic.StoreInt32(&w.isConnected, 1)
+			m.conn.Incr(1)
+			break
+		} else if !throt.Retry() {
+			return types.ErrTypeClosed
+		}
+	}
+	return err
+}
+
+// writeLoop reads transactions and writes them until the output is closed.
+func (w *Writer) writeLoop(m writerMetrics, throt *throttle.Type) {
+	transactor, _ := w.writer.(writer.Transactor)
+
 	for atomic.LoadInt32(&w.running) == 1 {
 		var ts types.Transaction
 		var open bool
@@ -129,41 +215,30 @@ func (w *Writer) loop() {
 			if !open {
 				return
 			}
-			mCount.Incr(1)
-			mPartsCount.Incr(int64(ts.Payload.Len()))
+			m.count.Incr(1)
+			m.partsCount.Incr(int64(ts.Payload.Len()))
 		case <-w.closeChan:
 			return
 		}
 
+		var prepared types.PreparedWrite
+		write := func() (err error) {
+			if ts.Prepare && transactor != nil {
+				prepared, err = transactor.WritePrepared(ts.Payload)
+				return
+			}
+			return w.writer.Write(ts.Payload)
+		}
+
 		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
-		err := w.writer.Write(ts.Payload)
+		err := write()
 
 		// If our writer says it is not connected.
 		if err == types.ErrNotConnected {
-			mLostConn.Incr(1)
-			atomic.StoreInt32(&w.isConnected, 0)
-
-			// Continue to try to reconnect while still active.
-			for atomic.LoadInt32(&w.running) == 1 {
-				if err = w.writer.Connect(); err != nil {
-					// Close immediately if our writer is closed.
-					if err == types.ErrTypeClosed {
-						return
-					}
-
-					w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
-					mFailedConn.Incr(1)
-					if !throt.Retry() {
-						return
-					}
-				} else if err = w.writer.Write(ts.Payload); err != types.ErrNotConnected {
-					atomic.StoreInt32(&w.isConnected, 1)
-					mConn.Incr(1)
-					break
-				} else if !throt.Retry() {
-					return
-				}
+			if atomic.CompareAndSwapInt32(&w.isConnected, 1, 0) {
+				m.lostConn.Incr(1)
 			}
+			err = w.reconnect(m, throt, write)
 		}
 
 		// Close immediately if our writer is closed.
@@ -173,15 +248,15 @@ func (w *Writer) loop() {
 
 		if err != nil {
 			w.log.Errorf("Failed to send message to %v: %v\n", w.typeStr, err)
-			mError.Incr(1)
+			m.err.Incr(1)
 			if !throt.Retry() {
 				return
 			}
 		} else {
-			mSuccess.Incr(1)
-			mPartsSuccess.Incr(int64(ts.Payload.Len()))
-			mSent.Incr(1)
-			mPartsSent.Incr(int64(ts.Payload.Len()))
+			m.success.Incr(1)
+			m.partsSuccess.Incr(int64(ts.Payload.Len()))
+			m.sent.Incr(1)
+			m.partsSent.Incr(int64(ts.Payload.Len()))
 			throt.Reset()
 		}
 
@@ -189,8 +264,13 @@ func (w *Writer) loop() {
 			s.Finish()
 		}
 
+		var res types.Response = response.NewError(err)
+		if err == nil && prepared != nil {
+			res = response.NewPrepared(prepared)
+		}
+
 		select {
-		case ts.ResponseChan <- response.NewError(err):
+		case ts.ResponseChan <- res:
 		case <-w.closeChan:
 			// The pipeline is terminating but we still want to attempt to
 			// propagate an acknowledgement from in-transit messages.
@@ -198,14 +278,46 @@ func (w *Writer) loop() {
 			// TODO: Replace this timer with a value linked to our service
 			// shutdown timer.
 			select {
-			case ts.ResponseChan <- response.NewError(err):
+			case ts.ResponseChan <- res:
 			case <-time.After(time.Second):
+				// Nobody is left to resolve a staged write.
+				if prepared != nil {
+					if aerr := prepared.Abort(); aerr != nil {
+						w.log.Errorf("Failed to abort staged message to %v: %v\n", w.typeStr, aerr)
+					}
+				}
 			}
 			return
 		}
 	}
 }
 
+// concurrent returns true if the underlying writer is safe for concurrent use.
+func (w *Writer) concurrent() bool {
+	_, ok := w.writer.(writer.ConcurrentWriter)
+	return ok
+}
+
+// setMaxInFlight sets the maximum number of message batches that may be
+// written in parallel, which must be set before the output starts consuming.
+// Values above one are rejected unless the writer is safe for concurrent use.
+func (w *Writer) setMaxInFlight(n int) error {
+	if w.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	if n > 1 && !w.concurrent() {
+		return fmt.Errorf("output '%v' does not support concurrent writes", w.typeStr)
+	}
+	w.maxInFlight = n
+	return nil
+}
+
+// CanStage returns true if the underlying writer is able to stage writes.
+func (w *Writer) CanStage() bool {
+	_, ok := w.writer.(writer.Transactor)
+	return ok
+}
+
 // Consume assigns a messages channel for the output to read.
 func (w *Writer) Consume(ts <-chan types.Transaction) error {
 	if w.transactions != nil {
@@ -22,12 +22,14 @@ package writer
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	sess "github.com/Jeffail/benthos/lib/util/aws/session"
@@ -41,25 +43,53 @@ import (
 
 // AmazonS3Config contains configuration fields for the AmazonS3 output type.
 type AmazonS3Config struct {
-	sess.Config        `json:",inline" yaml:",inline"`
-	Bucket             string `json:"bucket" yaml:"bucket"`
-	ForcePathStyleURLs bool   `json:"force_path_style_urls" yaml:"force_path_style_urls"`
-	Path               string `json:"path" yaml:"path"`
-	ContentType        string `json:"content_type" yaml:"content_type"`
-	ContentEncoding    string `json:"content_encoding" yaml:"content_encoding"`
-	Timeout            string `json:"timeout" yaml:"timeout"`
+	sess.Config          `json:",inline" yaml:",inline"`
+	Bucket               string                 `json:"bucket" yaml:"bucket"`
+	ForcePathStyleURLs   bool                   `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	Path                 string                 `json:"path" yaml:"path"`
+	ContentType          string                 `json:"content_type" yaml:"content_type"`
+	ContentEncoding      string                 `json:"content_encoding" yaml:"content_encoding"`
+	Compression          string                 `json:"compression" yaml:"compression"`
+	ServerSideEncryption string                 `json:"server_side_encryption" yaml:"server_side_encryption"`
+	KMSKeyID             string                 `json:"kms_key_id" yaml:"kms_key_id"`
+	Timeout              string                 `json:"timeout" yaml:"timeout"`
+	PropagateResponse    bool                   `json:"propagate_response" yaml:"propagate_response"`
+	Failover             AmazonS3FailoverConfig `json:"failover" yaml:"failover"`
+}
+
+// AmazonS3FailoverConfig contains configuration fields for failing over to
+// secondary S3 buckets.
+type AmazonS3FailoverConfig struct {
+	FailoverConfig `json:",inline" yaml:",inline"`
+	Endpoints      []AmazonS3EndpointConfig `json:"endpoints" yaml:"endpoints"`
+}
+
+// AmazonS3EndpointConfig describes a secondary S3 bucket, where empty fields
+// are inherited from the primary.
+type AmazonS3EndpointConfig struct {
+	Region   string `json:"region" yaml:"region"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	Bucket   string `json:"bucket" yaml:"bucket"`
 }
 
 // NewAmazonS3Config creates a new Config with default values.
 func NewAmazonS3Config() AmazonS3Config {
 	return AmazonS3Config{
-		Config:             sess.NewConfig(),
-		Bucket:             "",
-		ForcePathStyleURLs: false,
-		Path:               "${!count:files}-${!timestamp_unix_nano}.txt",
-		ContentType:        "application/octet-stream",
-		ContentEncoding:    "",
-		Timeout:            "5s",
+		Config:               sess.NewConfig(),
+		Bucket:               "",
+		ForcePathStyleURLs:   false,
+		Path:                 "${!count:files}-${!timestamp_unix_nano}.txt",
+		ContentType:          "application/octet-stream",
+		ContentEncoding:      "",
+		Compression:          "none",
+		ServerSideEncryption: "",
+		KMSKeyID:             "",
+		Timeout:              "5s",
+		PropagateResponse:    false,
+		Failover: AmazonS3FailoverConfig{
+			FailoverConfig: NewFailoverConfig(),
+			Endpoints:      []AmazonS3EndpointConfig{},
+		},
 	}
 }
 
@@ -73,6 +103,7 @@ type AmazonS3 struct {
 	path            *text.InterpolatedString
 	contentType     *text.InterpolatedString
 	contentEncoding *text.InterpolatedString
+	gzip            bool
 
 	session  *session.Session
 	uploader *s3manager.Uploader
@@ -95,6 +126,26 @@ func NewAmazonS3(
 			return nil, fmt.Errorf("failed to parse timeout period string: %v", err)
 		}
 	}
+	var useGzip bool
+	switch conf.Compression {
+	case "", "none":
+	case "gzip":
+		useGzip = true
+		if len(conf.ContentEncoding) == 0 {
+			conf.ContentEncoding = "gzip"
+		}
+	default:
+		return nil, fmt.Errorf("compression type not recognised: %v", conf.Compression)
+	}
+	switch conf.ServerSideEncryption {
+	case "", "AES256":
+		if len(conf.KMSKeyID) > 0 {
+			return nil, fmt.Errorf("field kms_key_id requires a server_side_encryption of aws:kms")
+		}
+	case "aws:kms":
+	default:
+		return nil, fmt.Errorf("server side encryption type not recognised: %v", conf.ServerSideEncryption)
+	}
 	return &AmazonS3{
 		conf:            conf,
 		log:             log,
@@ -102,10 +153,47 @@ func NewAmazonS3(
 		path:            text.NewInterpolatedString(conf.Path),
 		contentType:     text.NewInterpolatedString(conf.ContentType),
 		contentEncoding: text.NewInterpolatedString(conf.ContentEncoding),
+		gzip:            useGzip,
 		timeout:         timeout,
 	}, nil
 }
 
+// NewAmazonS3Failover creates a writer.Type that writes to the primary S3
+// bucket of a config and fails over to any secondary buckets configured.
+func NewAmazonS3Failover(
+	conf AmazonS3Config,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Failover.Endpoints) == 0 {
+		return NewAmazonS3(conf, log, stats)
+	}
+	confs := []AmazonS3Config{conf}
+	for _, e := range conf.Failover.Endpoints {
+		eConf := conf
+		if len(e.Region) > 0 {
+			eConf.Region = e.Region
+		}
+		if len(e.Endpoint) > 0 {
+			eConf.Endpoint = e.Endpoint
+		}
+		if len(e.Bucket) > 0 {
+			eConf.Bucket = e.Bucket
+		}
+		confs = append(confs, eConf)
+	}
+	names := make([]string, len(confs))
+	writers := make([]Type, len(confs))
+	for i, c := range confs {
+		w, err := NewAmazonS3(c, log, stats)
+		if err != nil {
+			return nil, err
+		}
+		names[i], writers[i] = c.Region+"/"+c.Bucket, w
+	}
+	return NewFailover(conf.Failover.FailoverConfig, names, writers, log, stats)
+}
+
 // Connect attempts to establish a connection to the target S3 bucket.
 func (a *AmazonS3) Connect() error {
 	if a.session != nil {
@@ -126,6 +214,77 @@ func (a *AmazonS3) Connect() error {
 	return nil
 }
 
+// objectBody returns the contents of an object, which is the contents of a
+// part compressed when configured.
+func (a *AmazonS3) objectBody(p types.Part) ([]byte, error) {
+	if !a.gzip {
+		return p.Get(), nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(p.Get()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// upload writes the part at index i of msg as an object. Returns the
+// identifiers of the written object.
+func (a *AmazonS3) upload(ctx context.Context, msg types.Message, i int) (roundtrip.Confirmation, error) {
+	metadata := map[string]*string{}
+	msg.Get(i).Metadata().Iter(func(k, v string) error {
+		metadata[k] = aws.String(v)
+		return nil
+	})
+
+	lMsg := message.Lock(msg, i)
+
+	var contentEncoding *string
+	if ce := a.contentEncoding.Get(lMsg); len(ce) > 0 {
+		contentEncoding = aws.String(ce)
+	}
+
+	body, err := a.objectBody(msg.Get(i))
+	if err != nil {
+		return nil, err
+	}
+
+	key := a.path.Get(lMsg)
+	input := &s3manager.UploadInput{
+		Bucket:          &a.conf.Bucket,
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(body),
+		ContentType:     aws.String(a.contentType.Get(lMsg)),
+		ContentEncoding: contentEncoding,
+		Metadata:        metadata,
+	}
+	if len(a.conf.ServerSideEncryption) > 0 {
+		input.ServerSideEncryption = aws.String(a.conf.ServerSideEncryption)
+	}
+	if len(a.conf.KMSKeyID) > 0 {
+		input.SSEKMSKeyId = aws.String(a.conf.KMSKeyID)
+	}
+
+	out, err := a.uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := roundtrip.Confirmation{
+		"s3_bucket":   a.conf.Bucket,
+		"s3_key":      key,
+		"s3_location": out.Location,
+	}
+	if out.VersionID != nil {
+		conf["s3_version_id"] = *out.VersionID
+	}
+	return conf, nil
+}
+
 // Write attempts to write message contents to a target S3 bucket as files.
 func (a *AmazonS3) Write(msg types.Message) error {
 	if a.session == nil {
@@ -137,32 +296,21 @@ func (a *AmazonS3) Write(msg types.Message) error {
 	)
 	defer cancel()
 
-	return msg.Iter(func(i int, p types.Part) error {
-		metadata := map[string]*string{}
-		p.Metadata().Iter(func(k, v string) error {
-			metadata[k] = aws.String(v)
-			return nil
-		})
-
-		lMsg := message.Lock(msg, i)
-
-		var contentEncoding *string
-		if ce := a.contentEncoding.Get(lMsg); len(ce) > 0 {
-			contentEncoding = aws.String(ce)
-		}
-
-		if _, err := a.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
-			Bucket:          &a.conf.Bucket,
-			Key:             aws.String(a.path.Get(lMsg)),
-			Body:            bytes.NewReader(p.Get()),
-			ContentType:     aws.String(a.contentType.Get(lMsg)),
-			ContentEncoding: contentEncoding,
-			Metadata:        metadata,
-		}); err != nil {
+	confs := make([]roundtrip.Confirmation, 0, msg.Len())
+	if err := msg.Iter(func(i int, p types.Part) error {
+		conf, err := a.upload(ctx, msg, i)
+		if err != nil {
 			return err
 		}
+		confs = append(confs, conf)
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+	if a.conf.PropagateResponse {
+		roundtrip.SetConfirmationsAsResponse(msg, confs)
+	}
+	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
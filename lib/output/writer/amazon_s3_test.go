@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestAmazonS3ObjectBody(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Compression = "gzip"
+
+	a, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "gzip", a.conf.ContentEncoding; exp != act {
+		t.Errorf("Wrong default content encoding: %v != %v", act, exp)
+	}
+
+	body, err := a.objectBody(message.NewPart([]byte("foo\nbar")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar", string(decoded); exp != act {
+		t.Errorf("Wrong object body: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3BadConfig(t *testing.T) {
+	tests := map[string]func(*AmazonS3Config){
+		"bad compression": func(c *AmazonS3Config) {
+			c.Compression = "nope"
+		},
+		"bad encryption": func(c *AmazonS3Config) {
+			c.ServerSideEncryption = "nope"
+		},
+		"kms key without kms": func(c *AmazonS3Config) {
+			c.ServerSideEncryption = "AES256"
+			c.KMSKeyID = "foo"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewAmazonS3Config()
+		fn(&conf)
+		if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
@@ -40,6 +40,7 @@ import (
 
 const (
 	sqsMaxRecordsCount = 10
+	sqsMaxBatchBytes   = 262144
 )
 
 //------------------------------------------------------------------------------
@@ -47,10 +48,27 @@ const (
 // AmazonSQSConfig contains configuration fields for the output AmazonSQS type.
 type AmazonSQSConfig struct {
 	sessionConfig  `json:",inline" yaml:",inline"`
-	URL            string `json:"url" yaml:"url"`
+	URL            string                  `json:"url" yaml:"url"`
+	MaxBatchBytes  int                     `json:"max_batch_bytes" yaml:"max_batch_bytes"`
+	Failover       AmazonSQSFailoverConfig `json:"failover" yaml:"failover"`
 	retries.Config `json:",inline" yaml:",inline"`
 }
 
+// AmazonSQSFailoverConfig contains configuration fields for failing over to
+// secondary SQS queues.
+type AmazonSQSFailoverConfig struct {
+	FailoverConfig `json:",inline" yaml:",inline"`
+	Endpoints      []AmazonSQSEndpointConfig `json:"endpoints" yaml:"endpoints"`
+}
+
+// AmazonSQSEndpointConfig describes a secondary SQS queue, where empty fields
+// are inherited from the primary.
+type AmazonSQSEndpointConfig struct {
+	Region   string `json:"region" yaml:"region"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	URL      string `json:"url" yaml:"url"`
+}
+
 // NewAmazonSQSConfig creates a new Config with default values.
 func NewAmazonSQSConfig() AmazonSQSConfig {
 	rConf := retries.NewConfig()
@@ -61,7 +79,12 @@ func NewAmazonSQSConfig() AmazonSQSConfig {
 		sessionConfig: sessionConfig{
 			Config: sess.NewConfig(),
 		},
-		URL:    "",
+		URL:           "",
+		MaxBatchBytes: sqsMaxBatchBytes,
+		Failover: AmazonSQSFailoverConfig{
+			FailoverConfig: NewFailoverConfig(),
+			Endpoints:      []AmazonSQSEndpointConfig{},
+		},
 		Config: rConf,
 	}
 }
@@ -79,6 +102,8 @@ type AmazonSQS struct {
 
 	log   log.Modular
 	stats metrics.Type
+
+	mBatchSplit metrics.StatCounter
 }
 
 // NewAmazonSQS creates a new Amazon SQS writer.Type.
@@ -88,9 +113,10 @@ func NewAmazonSQS(
 	stats metrics.Type,
 ) (*AmazonSQS, error) {
 	s := &AmazonSQS{
-		conf:  conf,
-		log:   log,
-		stats: stats,
+		conf:        conf,
+		log:         log,
+		stats:       stats,
+		mBatchSplit: stats.GetCounter("batch.split"),
 	}
 
 	var err error
@@ -100,6 +126,42 @@ func NewAmazonSQS(
 	return s, nil
 }
 
+// NewAmazonSQSFailover creates a writer.Type that writes to the primary SQS
+// queue of a config and fails over to any secondary queues configured.
+func NewAmazonSQSFailover(
+	conf AmazonSQSConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Failover.Endpoints) == 0 {
+		return NewAmazonSQS(conf, log, stats)
+	}
+	confs := []AmazonSQSConfig{conf}
+	for _, e := range conf.Failover.Endpoints {
+		eConf := conf
+		if len(e.Region) > 0 {
+			eConf.Region = e.Region
+		}
+		if len(e.Endpoint) > 0 {
+			eConf.Endpoint = e.Endpoint
+		}
+		if len(e.URL) > 0 {
+			eConf.URL = e.URL
+		}
+		confs = append(confs, eConf)
+	}
+	names := make([]string, len(confs))
+	writers := make([]Type, len(confs))
+	for i, c := range confs {
+		w, err := NewAmazonSQS(c, log, stats)
+		if err != nil {
+			return nil, err
+		}
+		names[i], writers[i] = c.URL, w
+	}
+	return NewFailover(conf.Failover.FailoverConfig, names, writers, log, stats)
+}
+
 // Connect attempts to establish a connection to the target SQS queue.
 func (a *AmazonSQS) Connect() error {
 	if a.session != nil {
@@ -124,6 +186,22 @@ func (a *AmazonSQS) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
+	batches, err := splitBySize(msg, a.conf.MaxBatchBytes)
+	if err != nil {
+		return err
+	}
+	if len(batches) > 1 {
+		a.mBatchSplit.Incr(1)
+	}
+	for _, batch := range batches {
+		if err = a.writeBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AmazonSQS) writeBatch(msg types.Message) error {
 	entries := []*sqs.SendMessageBatchRequestEntry{}
 	msg.Iter(func(i int, p types.Part) error {
 		entries = append(entries, &sqs.SendMessageBatchRequestEntry{
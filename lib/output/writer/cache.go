@@ -87,6 +87,10 @@ func (c *Cache) Connect() error {
 	return nil
 }
 
+// ConcurrentWrites marks Cache as safe for concurrent writes, cache resources
+// are already shared across components.
+func (c *Cache) ConcurrentWrites() {}
+
 // Write attempts to write message contents to a target Cache directory as files.
 func (c *Cache) Write(msg types.Message) error {
 	if msg.Len() == 1 {
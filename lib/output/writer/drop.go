@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 )
@@ -43,16 +44,19 @@ func NewDropConfig() DropConfig {
 // Drop is a benthos writer.Type implementation that writes message parts to no
 // where.
 type Drop struct {
+	mgr types.Manager
 	log log.Modular
 }
 
 // NewDrop creates a new file based writer.Type.
 func NewDrop(
 	conf DropConfig,
+	mgr types.Manager,
 	log log.Modular,
 	stats metrics.Type,
 ) *Drop {
 	return &Drop{
+		mgr: mgr,
 		log: log,
 	}
 }
@@ -63,8 +67,12 @@ func (d *Drop) Connect() error {
 	return nil
 }
 
-// Write does nothing.
+// ConcurrentWrites marks Drop as safe for concurrent writes.
+func (d *Drop) ConcurrentWrites() {}
+
+// Write reports the message as dropped and does nothing else.
 func (d *Drop) Write(msg types.Message) error {
+	drop.Report(d.mgr, "drop", msg)
 	return nil
 }
 
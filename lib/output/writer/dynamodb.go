@@ -36,6 +36,7 @@ import (
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/Jeffail/gabs"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/cenkalti/backoff"
@@ -51,6 +52,9 @@ type DynamoDBConfig struct {
 	JSONMapColumns map[string]string `json:"json_map_columns" yaml:"json_map_columns"`
 	TTL            string            `json:"ttl" yaml:"ttl"`
 	TTLKey         string            `json:"ttl_key" yaml:"ttl_key"`
+	Condition      string            `json:"condition_expression" yaml:"condition_expression"`
+	ExprNames      map[string]string `json:"expression_attribute_names" yaml:"expression_attribute_names"`
+	ExprValues     map[string]string `json:"expression_attribute_values" yaml:"expression_attribute_values"`
 	retries.Config `json:",inline" yaml:",inline"`
 }
 
@@ -70,14 +74,21 @@ func NewDynamoDBConfig() DynamoDBConfig {
 		JSONMapColumns: map[string]string{},
 		TTL:            "",
 		TTLKey:         "",
+		Condition:      "",
+		ExprNames:      map[string]string{},
+		ExprValues:     map[string]string{},
 		Config:         rConf,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// dynamoDBMaxBatchItems is the maximum number of items that a single
+// BatchWriteItem request may contain.
+const dynamoDBMaxBatchItems = 25
+
 // DynamoDB is a benthos writer.Type implementation that writes messages to an
-// Amazon SQS queue.
+// Amazon DynamoDB table.
 type DynamoDB struct {
 	client  dynamodbiface.DynamoDBAPI
 	conf    DynamoDBConfig
@@ -85,13 +96,18 @@ type DynamoDB struct {
 	stats   metrics.Type
 	backoff backoff.BackOff
 
-	table          *string
+	table          *text.InterpolatedString
 	ttl            time.Duration
 	strColumns     map[string]*text.InterpolatedString
 	jsonMapColumns map[string]string
+	exprNames      map[string]*string
+	exprValues     map[string]*text.InterpolatedString
+
+	mUnprocessed     metrics.StatCounter
+	mConditionFailed metrics.StatCounter
 }
 
-// NewDynamoDB creates a new Amazon SQS writer.Type.
+// NewDynamoDB creates a new Amazon DynamoDB writer.Type.
 func NewDynamoDB(
 	conf DynamoDBConfig,
 	log log.Modular,
@@ -105,10 +121,18 @@ func NewDynamoDB(
 		conf:           conf,
 		log:            log,
 		stats:          stats,
-		table:          aws.String(conf.Table),
+		table:          text.NewInterpolatedString(conf.Table),
 		backoff:        boff,
 		strColumns:     map[string]*text.InterpolatedString{},
 		jsonMapColumns: map[string]string{},
+		exprNames:      map[string]*string{},
+		exprValues:     map[string]*text.InterpolatedString{},
+
+		mUnprocessed:     stats.GetCounter("write.unprocessed"),
+		mConditionFailed: stats.GetCounter("write.condition_failed"),
+	}
+	if len(conf.Table) == 0 {
+		return nil, errors.New("a table must be specified")
 	}
 	if len(conf.StringColumns) == 0 && len(conf.JSONMapColumns) == 0 {
 		return nil, errors.New("you must provide at least one column")
@@ -122,6 +146,15 @@ func NewDynamoDB(
 		}
 		db.jsonMapColumns[k] = v
 	}
+	if conf.Condition == "" && (len(conf.ExprNames) > 0 || len(conf.ExprValues) > 0) {
+		return nil, errors.New("expression attributes require a condition_expression")
+	}
+	for k, v := range conf.ExprNames {
+		db.exprNames[k] = aws.String(v)
+	}
+	for k, v := range conf.ExprValues {
+		db.exprValues[k] = text.NewInterpolatedString(v)
+	}
 	if conf.TTL != "" {
 		ttl, err := time.ParseDuration(conf.TTL)
 		if err != nil {
@@ -132,7 +165,7 @@ func NewDynamoDB(
 	return db, nil
 }
 
-// Connect attempts to establish a connection to the target SQS queue.
+// Connect attempts to establish a connection to the target DynamoDB table.
 func (d *DynamoDB) Connect() error {
 	if d.client != nil {
 		return nil
@@ -144,13 +177,18 @@ func (d *DynamoDB) Connect() error {
 	}
 
 	client := dynamodb.New(sess)
-	out, err := client.DescribeTable(&dynamodb.DescribeTableInput{
-		TableName: d.table,
-	})
-	if err != nil {
-		return err
-	} else if out == nil || out.Table == nil || out.Table.TableStatus == nil || *out.Table.TableStatus != dynamodb.TableStatusActive {
-		return fmt.Errorf("dynamodb table '%s' must be active", d.conf.Table)
+
+	// Interpolated table names can only be resolved per message, so we can
+	// only check the status of the table up front when it is static.
+	if !text.ContainsFunctionVariables([]byte(d.conf.Table)) {
+		out, err := client.DescribeTable(&dynamodb.DescribeTableInput{
+			TableName: aws.String(d.conf.Table),
+		})
+		if err != nil {
+			return err
+		} else if out == nil || out.Table == nil || out.Table.TableStatus == nil || *out.Table.TableStatus != dynamodb.TableStatusActive {
+			return fmt.Errorf("dynamodb table '%s' must be active", d.conf.Table)
+		}
 	}
 
 	d.client = client
@@ -217,82 +255,155 @@ func jsonToMap(path string, root interface{}) (*dynamodb.AttributeValue, error)
 	return walkJSON(gObj.Data()), nil
 }
 
-// Write attempts to write message contents to a target SQS.
-func (d *DynamoDB) Write(msg types.Message) error {
-	if d.client == nil {
-		return types.ErrNotConnected
-	}
-
-	writeReqs := []*dynamodb.WriteRequest{}
-	msg.Iter(func(i int, p types.Part) error {
-		items := map[string]*dynamodb.AttributeValue{}
-		if d.ttl != 0 && d.conf.TTLKey != "" {
-			items[d.conf.TTLKey] = &dynamodb.AttributeValue{
-				N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
-			}
+func (d *DynamoDB) itemFor(msg types.Message, i int, p types.Part) map[string]*dynamodb.AttributeValue {
+	items := map[string]*dynamodb.AttributeValue{}
+	if d.ttl != 0 && d.conf.TTLKey != "" {
+		items[d.conf.TTLKey] = &dynamodb.AttributeValue{
+			N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
 		}
-		for k, v := range d.strColumns {
-			s := v.Get(message.Lock(msg, i))
-			items[k] = &dynamodb.AttributeValue{
-				S: &s,
-			}
+	}
+	for k, v := range d.strColumns {
+		s := v.Get(message.Lock(msg, i))
+		items[k] = &dynamodb.AttributeValue{
+			S: &s,
 		}
-		if len(d.jsonMapColumns) > 0 {
-			jRoot, err := p.JSON()
-			if err != nil {
-				d.log.Errorf("Failed to extract JSON maps from document: %v", err)
-			} else {
-				for k, v := range d.jsonMapColumns {
-					if attr, err := jsonToMap(v, jRoot); err == nil {
-						if len(k) == 0 {
-							for ak, av := range attr.M {
-								items[ak] = av
-							}
-						} else {
-							items[k] = attr
+	}
+	if len(d.jsonMapColumns) > 0 {
+		jRoot, err := p.JSON()
+		if err != nil {
+			d.log.Errorf("Failed to extract JSON maps from document: %v", err)
+		} else {
+			for k, v := range d.jsonMapColumns {
+				if attr, err := jsonToMap(v, jRoot); err == nil {
+					if len(k) == 0 {
+						for ak, av := range attr.M {
+							items[ak] = av
 						}
 					} else {
-						d.log.Warnf("Unable to extract JSON map path '%v' from document: %v", v, err)
+						items[k] = attr
 					}
+				} else {
+					d.log.Warnf("Unable to extract JSON map path '%v' from document: %v", v, err)
 				}
 			}
 		}
-		writeReqs = append(writeReqs, &dynamodb.WriteRequest{
-			PutRequest: &dynamodb.PutRequest{
-				Item: items,
-			},
-		})
-		return nil
-	})
+	}
+	return items
+}
 
-	var err error
-	for len(writeReqs) > 0 {
-		wait := d.backoff.NextBackOff()
-		var batchResult *dynamodb.BatchWriteItemOutput
-		batchResult, err = d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
-			RequestItems: map[string][]*dynamodb.WriteRequest{
-				*d.table: writeReqs,
-			},
+// wait blocks for the next backoff period, returning false if retries have
+// been exhausted.
+func (d *DynamoDB) wait() bool {
+	wait := d.backoff.NextBackOff()
+	if wait == backoff.Stop {
+		return false
+	}
+	<-time.After(wait)
+	return true
+}
+
+// writeBatch writes a batch of requests with BatchWriteItem, retrying any
+// unprocessed items until either they are all written or retries are
+// exhausted.
+func (d *DynamoDB) writeBatch(reqs map[string][]*dynamodb.WriteRequest) error {
+	for len(reqs) > 0 {
+		res, err := d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: reqs,
 		})
 		if err != nil {
 			d.log.Errorf("Write multi error: %v\n", err)
-		} else if unproc := batchResult.UnprocessedItems[*d.table]; len(unproc) > 0 {
-			writeReqs = unproc
-			err = fmt.Errorf("failed to set %v items", len(unproc))
+		} else if len(res.UnprocessedItems) > 0 {
+			unproc := 0
+			for _, v := range res.UnprocessedItems {
+				unproc += len(v)
+			}
+			d.mUnprocessed.Incr(int64(unproc))
+			reqs = res.UnprocessedItems
+			err = fmt.Errorf("failed to set %v items", unproc)
 		} else {
-			writeReqs = nil
+			reqs = nil
+		}
+		if err != nil && !d.wait() {
+			return err
 		}
+	}
+	return nil
+}
 
-		if err != nil {
-			if wait == backoff.Stop {
-				break
+// writeConditional writes a single item with PutItem and the configured
+// condition expression. Items that fail their condition are dropped.
+func (d *DynamoDB) writeConditional(msg types.Message, i int, table string, item map[string]*dynamodb.AttributeValue) error {
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(table),
+		Item:                item,
+		ConditionExpression: aws.String(d.conf.Condition),
+	}
+	if len(d.exprNames) > 0 {
+		input.ExpressionAttributeNames = d.exprNames
+	}
+	if len(d.exprValues) > 0 {
+		input.ExpressionAttributeValues = make(map[string]*dynamodb.AttributeValue, len(d.exprValues))
+		for k, v := range d.exprValues {
+			input.ExpressionAttributeValues[k] = &dynamodb.AttributeValue{
+				S: aws.String(v.Get(message.Lock(msg, i))),
 			}
-			time.After(wait)
 		}
 	}
+	for {
+		_, err := d.client.PutItem(input)
+		if err == nil {
+			return nil
+		}
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			d.mConditionFailed.Incr(1)
+			d.log.Debugf("Dropping item as it failed the condition expression: %v\n", err)
+			return nil
+		}
+		d.log.Errorf("Write error: %v\n", err)
+		if !d.wait() {
+			return err
+		}
+	}
+}
+
+// Write attempts to write message contents to a target DynamoDB table.
+func (d *DynamoDB) Write(msg types.Message) error {
+	if d.client == nil {
+		return types.ErrNotConnected
+	}
+	d.backoff.Reset()
 
-	if err == nil {
-		d.backoff.Reset()
+	var err error
+	if len(d.conf.Condition) > 0 {
+		// Condition expressions are not supported by BatchWriteItem and
+		// therefore each item is written individually.
+		msg.Iter(func(i int, p types.Part) error {
+			table := d.table.Get(message.Lock(msg, i))
+			err = d.writeConditional(msg, i, table, d.itemFor(msg, i, p))
+			return err
+		})
+	} else {
+		reqs := map[string][]*dynamodb.WriteRequest{}
+		count := 0
+		msg.Iter(func(i int, p types.Part) error {
+			table := d.table.Get(message.Lock(msg, i))
+			reqs[table] = append(reqs[table], &dynamodb.WriteRequest{
+				PutRequest: &dynamodb.PutRequest{
+					Item: d.itemFor(msg, i, p),
+				},
+			})
+			if count++; count == dynamoDBMaxBatchItems {
+				if err = d.writeBatch(reqs); err != nil {
+					return err
+				}
+				reqs = map[string][]*dynamodb.WriteRequest{}
+				count = 0
+			}
+			return nil
+		})
+		if err == nil && count > 0 {
+			err = d.writeBatch(reqs)
+		}
 	}
 	return err
 }
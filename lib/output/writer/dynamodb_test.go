@@ -0,0 +1,235 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+type mockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	batchFn func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)
+	putFn   func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+}
+
+func (m *mockDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return m.batchFn(input)
+}
+
+func (m *mockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return m.putFn(input)
+}
+
+func testDynamoDBConf() DynamoDBConfig {
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.StringColumns = map[string]string{
+		"id": "${!json_field:id}",
+	}
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	return conf
+}
+
+func TestDynamoDBBatchSplitting(t *testing.T) {
+	db, err := NewDynamoDB(testDynamoDBConf(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batchSizes []int
+	db.client = &mockDynamoDB{
+		batchFn: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+			batchSizes = append(batchSizes, len(input.RequestItems["foo"]))
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+
+	msg := message.New(nil)
+	for i := 0; i < 30; i++ {
+		msg.Append(message.NewPart([]byte(fmt.Sprintf(`{"id":"%v"}`, i))))
+	}
+	if err = db.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []int{25, 5}, batchSizes; fmt.Sprintf("%v", exp) != fmt.Sprintf("%v", act) {
+		t.Errorf("Wrong batch sizes: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBUnprocessedRetry(t *testing.T) {
+	db, err := NewDynamoDB(testDynamoDBConf(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written []string
+	calls := 0
+	db.client = &mockDynamoDB{
+		batchFn: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+			calls++
+			reqs := input.RequestItems["foo"]
+			if calls == 1 {
+				written = append(written, *reqs[0].PutRequest.Item["id"].S)
+				return &dynamodb.BatchWriteItemOutput{
+					UnprocessedItems: map[string][]*dynamodb.WriteRequest{
+						"foo": reqs[1:],
+					},
+				}, nil
+			}
+			for _, r := range reqs {
+				written = append(written, *r.PutRequest.Item["id"].S)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"a"}`),
+		[]byte(`{"id":"b"}`),
+		[]byte(`{"id":"c"}`),
+	})
+	if err = db.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+	if exp, act := "[a b c]", fmt.Sprintf("%v", written); exp != act {
+		t.Errorf("Wrong items written: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBRetriesExhausted(t *testing.T) {
+	conf := testDynamoDBConf()
+	conf.MaxRetries = 2
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	db.client = &mockDynamoDB{
+		batchFn: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+			calls++
+			return nil, errors.New("nope")
+		},
+	}
+
+	if err = db.Write(message.New([][]byte{[]byte(`{"id":"a"}`)})); err == nil {
+		t.Error("Expected error")
+	}
+	if exp, act := 3, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBInterpolatedTable(t *testing.T) {
+	conf := testDynamoDBConf()
+	conf.Table = "${!metadata:table}"
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := map[string]int{}
+	db.client = &mockDynamoDB{
+		batchFn: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+			for k, v := range input.RequestItems {
+				tables[k] += len(v)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"a"}`),
+		[]byte(`{"id":"b"}`),
+		[]byte(`{"id":"c"}`),
+	})
+	msg.Get(0).Metadata().Set("table", "foo")
+	msg.Get(1).Metadata().Set("table", "bar")
+	msg.Get(2).Metadata().Set("table", "foo")
+
+	if err = db.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "map[bar:1 foo:2]", fmt.Sprintf("%v", tables); exp != act {
+		t.Errorf("Wrong table counts: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBConditional(t *testing.T) {
+	conf := testDynamoDBConf()
+	conf.Condition = "attribute_not_exists(id) OR #v < :v"
+	conf.ExprNames = map[string]string{
+		"#v": "version",
+	}
+	conf.ExprValues = map[string]string{
+		":v": "${!json_field:id}",
+	}
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written []string
+	db.client = &mockDynamoDB{
+		putFn: func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			if exp, act := conf.Condition, *input.ConditionExpression; exp != act {
+				t.Errorf("Wrong condition: %v != %v", act, exp)
+			}
+			if exp, act := "version", *input.ExpressionAttributeNames["#v"]; exp != act {
+				t.Errorf("Wrong attribute name: %v != %v", act, exp)
+			}
+			id := *input.Item["id"].S
+			if exp, act := id, *input.ExpressionAttributeValues[":v"].S; exp != act {
+				t.Errorf("Wrong attribute value: %v != %v", act, exp)
+			}
+			if id == "b" {
+				return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "nope", nil)
+			}
+			written = append(written, id)
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"a"}`),
+		[]byte(`{"id":"b"}`),
+		[]byte(`{"id":"c"}`),
+	})
+	if err = db.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "[a c]", fmt.Sprintf("%v", written); exp != act {
+		t.Errorf("Wrong items written: %v != %v", act, exp)
+	}
+}
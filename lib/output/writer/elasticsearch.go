@@ -24,11 +24,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	sess "github.com/Jeffail/benthos/lib/util/aws/session"
@@ -54,16 +56,19 @@ type OptionalAWSConfig struct {
 // ElasticsearchConfig contains configuration fields for the Elasticsearch
 // output type.
 type ElasticsearchConfig struct {
-	URLs           []string             `json:"urls" yaml:"urls"`
-	Sniff          bool                 `json:"sniff" yaml:"sniff"`
-	ID             string               `json:"id" yaml:"id"`
-	Index          string               `json:"index" yaml:"index"`
-	Pipeline       string               `json:"pipeline" yaml:"pipeline"`
-	Type           string               `json:"type" yaml:"type"`
-	Timeout        string               `json:"timeout" yaml:"timeout"`
-	Auth           auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
-	AWS            OptionalAWSConfig    `json:"aws" yaml:"aws"`
-	retries.Config `json:",inline" yaml:",inline"`
+	URLs              []string             `json:"urls" yaml:"urls"`
+	Sniff             bool                 `json:"sniff" yaml:"sniff"`
+	ID                string               `json:"id" yaml:"id"`
+	Action            string               `json:"action" yaml:"action"`
+	Index             string               `json:"index" yaml:"index"`
+	Pipeline          string               `json:"pipeline" yaml:"pipeline"`
+	Routing           string               `json:"routing" yaml:"routing"`
+	Type              string               `json:"type" yaml:"type"`
+	Timeout           string               `json:"timeout" yaml:"timeout"`
+	Auth              auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	AWS               OptionalAWSConfig    `json:"aws" yaml:"aws"`
+	PropagateResponse bool                 `json:"propagate_response" yaml:"propagate_response"`
+	retries.Config    `json:",inline" yaml:",inline"`
 }
 
 // NewElasticsearchConfig creates a new ElasticsearchConfig with default values.
@@ -77,8 +82,10 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 		URLs:     []string{"http://localhost:9200"},
 		Sniff:    true,
 		ID:       "${!count:elastic_ids}-${!timestamp_unix}",
+		Action:   "index",
 		Index:    "benthos_index",
 		Pipeline: "",
+		Routing:  "",
 		Type:     "doc",
 		Timeout:  "5s",
 		Auth:     auth.NewBasicAuthConfig(),
@@ -86,7 +93,8 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 			Enabled: false,
 			Config:  sess.NewConfig(),
 		},
-		Config: rConf,
+		PropagateResponse: false,
+		Config:            rConf,
 	}
 }
 
@@ -105,8 +113,10 @@ type Elasticsearch struct {
 	timeout time.Duration
 
 	idStr             *text.InterpolatedString
+	actionStr         *text.InterpolatedString
 	indexStr          *text.InterpolatedString
 	pipelineStr       *text.InterpolatedString
+	routingStr        *text.InterpolatedString
 	interpolatedIndex bool
 
 	eJSONErr metrics.StatCounter
@@ -122,12 +132,20 @@ func NewElasticsearch(conf ElasticsearchConfig, log log.Modular, stats metrics.T
 		conf:              conf,
 		sniff:             conf.Sniff,
 		idStr:             text.NewInterpolatedString(conf.ID),
+		actionStr:         text.NewInterpolatedString(conf.Action),
 		indexStr:          text.NewInterpolatedString(conf.Index),
 		pipelineStr:       text.NewInterpolatedString(conf.Pipeline),
+		routingStr:        text.NewInterpolatedString(conf.Routing),
 		interpolatedIndex: text.ContainsFunctionVariables([]byte(conf.Index)),
 		eJSONErr:          stats.GetCounter("error.json"),
 	}
 
+	if !text.ContainsFunctionVariables([]byte(conf.Action)) {
+		if err := validateElasticAction(conf.Action); err != nil {
+			return nil, err
+		}
+	}
+
 	for _, u := range conf.URLs {
 		for _, splitURL := range strings.Split(u, ",") {
 			if len(splitURL) > 0 {
@@ -198,13 +216,71 @@ func shouldRetry(s int) bool {
 	return false
 }
 
+// isElasticNoop returns true if a failed action has nonetheless reached the
+// desired state, which is the case when deleting a document that does not
+// exist or creating a document that already exists.
+func isElasticNoop(action string, status int) bool {
+	switch action {
+	case elasticActionDelete:
+		return status == http.StatusNotFound
+	case elasticActionCreate:
+		return status == http.StatusConflict
+	}
+	return false
+}
+
+const (
+	elasticActionIndex  = "index"
+	elasticActionCreate = "create"
+	elasticActionUpdate = "update"
+	elasticActionDelete = "delete"
+)
+
+func validateElasticAction(action string) error {
+	switch action {
+	case elasticActionIndex, elasticActionCreate, elasticActionUpdate, elasticActionDelete:
+		return nil
+	}
+	return fmt.Errorf("action '%v' is not supported, expected one of index, create, update or delete", action)
+}
+
 type pendingBulkIndex struct {
+	Action   string
 	Index    string
 	Pipeline string
+	Routing  string
 	Type     string
 	Doc      interface{}
 }
 
+// request creates a bulk request for the pending document according to its
+// action.
+func (p *pendingBulkIndex) request(id string) elastic.BulkableRequest {
+	switch p.Action {
+	case elasticActionUpdate:
+		return elastic.NewBulkUpdateRequest().
+			Index(p.Index).
+			Routing(p.Routing).
+			Type(p.Type).
+			Id(id).
+			Doc(p.Doc)
+	case elasticActionDelete:
+		return elastic.NewBulkDeleteRequest().
+			Index(p.Index).
+			Routing(p.Routing).
+			Type(p.Type).
+			Id(id)
+	}
+	return elastic.NewBulkIndexRequest().
+		OpType(p.Action).
+		Index(p.Index).
+		Pipeline(p.Pipeline).
+		Routing(p.Routing).
+		Type(p.Type).
+		Id(id).
+		Doc(p.Doc)
+}
+
 // Write will attempt to write a message to Elasticsearch, wait for
 // acknowledgement, and returns an error if applicable.
 func (e *Elasticsearch) Write(msg types.Message) error {
@@ -213,51 +289,57 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 	}
 
 	if msg.Len() == 1 {
-		index := e.indexStr.Get(msg)
-		_, err := e.client.Index().
-			Index(index).
-			Pipeline(e.pipelineStr.Get(msg)).
-			Type(e.conf.Type).
-			Id(e.idStr.Get(msg)).
-			BodyString(string(msg.Get(0).Get())).
-			Do(context.Background())
-		if err == nil {
-			// Flush to make sure the document got written.
-			_, err = e.client.Flush().Index(index).Do(context.Background())
+		action := e.actionStr.Get(msg)
+		if err := validateElasticAction(action); err != nil {
+			return err
+		}
+		if action == elasticActionIndex || action == elasticActionCreate {
+			return e.writeSingle(action, msg)
 		}
-		return err
 	}
 
 	e.backoff.Reset()
 
 	requests := map[string]*pendingBulkIndex{}
-	msg.Iter(func(i int, part types.Part) error {
-		jObj, ierr := part.JSON()
-		if ierr != nil {
-			e.eJSONErr.Incr(1)
-			e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
-			return nil
-		}
+	confs := make([]roundtrip.Confirmation, msg.Len())
+	if err := msg.Iter(func(i int, part types.Part) error {
 		lMsg := message.Lock(msg, i)
-		requests[e.idStr.Get(lMsg)] = &pendingBulkIndex{
+		action := e.actionStr.Get(lMsg)
+		if err := validateElasticAction(action); err != nil {
+			return err
+		}
+
+		var jObj interface{}
+		if action != elasticActionDelete {
+			var ierr error
+			if jObj, ierr = part.JSON(); ierr != nil {
+				e.eJSONErr.Incr(1)
+				e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
+				return nil
+			}
+		}
+		id := e.idStr.Get(lMsg)
+		req := &pendingBulkIndex{
+			Action:   action,
 			Index:    e.indexStr.Get(lMsg),
 			Pipeline: e.pipelineStr.Get(lMsg),
+			Routing:  e.routingStr.Get(lMsg),
 			Type:     e.conf.Type,
 			Doc:      jObj,
 		}
+		requests[id] = req
+		confs[i] = roundtrip.Confirmation{
+			"elasticsearch_index": req.Index,
+			"elasticsearch_id":    id,
+		}
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
 
 	b := e.client.Bulk()
 	for k, v := range requests {
-		b.Add(
-			elastic.NewBulkIndexRequest().
-				Index(v.Index).
-				Pipeline(v.Pipeline).
-				Type(v.Type).
-				Id(k).
-				Doc(v.Doc),
-		)
+		b.Add(v.request(k))
 	}
 
 	for b.NumberOfActions() != 0 {
@@ -266,7 +348,13 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 			return err
 		}
 
-		failed := result.Failed()
+		var failed []*elastic.BulkResponseItem
+		for _, item := range result.Failed() {
+			if isElasticNoop(requests[item.Id].Action, item.Status) {
+				continue
+			}
+			failed = append(failed, item)
+		}
 		if len(failed) == 0 {
 			e.backoff.Reset()
 			continue
@@ -275,20 +363,12 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 		wait := e.backoff.NextBackOff()
 		for i := 0; i < len(failed); i++ {
 			if !shouldRetry(failed[i].Status) {
-				e.log.Errorf("Elasticsearch message '%v' rejected with code [%s]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
+				e.log.Errorf("Elasticsearch message '%v' rejected with code [%v]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
 				return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
 			}
-			e.log.Errorf("Elasticsearch message '%v' failed with code [%s]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
+			e.log.Errorf("Elasticsearch message '%v' failed with code [%v]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
 			id := failed[i].Id
-			req := requests[id]
-			b.Add(
-				elastic.NewBulkIndexRequest().
-					Index(req.Index).
-					Pipeline(req.Pipeline).
-					Type(req.Type).
-					Id(id).
-					Doc(req.Doc),
-			)
+			b.Add(requests[id].request(id))
 		}
 		if wait == backoff.Stop {
 			return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
@@ -296,9 +376,42 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 		time.Sleep(wait)
 	}
 
+	if e.conf.PropagateResponse {
+		roundtrip.SetConfirmationsAsResponse(msg, confs)
+	}
 	return nil
 }
 
+// writeSingle indexes a single message part without using the bulk API.
+func (e *Elasticsearch) writeSingle(action string, msg types.Message) error {
+	index := e.indexStr.Get(msg)
+	res, err := e.client.Index().
+		OpType(action).
+		Index(index).
+		Pipeline(e.pipelineStr.Get(msg)).
+		Routing(e.routingStr.Get(msg)).
+		Type(e.conf.Type).
+		Id(e.idStr.Get(msg)).
+		BodyString(string(msg.Get(0).Get())).
+		Do(context.Background())
+	if action == elasticActionCreate && elastic.IsConflict(err) {
+		// The document already exists and therefore there's nothing to do.
+		return nil
+	}
+	if err == nil {
+		// Flush to make sure the document got written.
+		_, err = e.client.Flush().Index(index).Do(context.Background())
+	}
+	if err == nil && e.conf.PropagateResponse {
+		roundtrip.SetConfirmationsAsResponse(msg, []roundtrip.Confirmation{{
+			"elasticsearch_index":   res.Index,
+			"elasticsearch_id":      res.Id,
+			"elasticsearch_version": strconv.FormatInt(res.Version, 10),
+		}})
+	}
+	return err
+}
+
 // CloseAsync shuts down the Elasticsearch writer and stops processing messages.
 func (e *Elasticsearch) CloseAsync() {
 }
@@ -122,6 +122,44 @@ func TestElasticIntegration(t *testing.T) {
 	t.Run("TestElasticBatch", func(te *testing.T) {
 		testElasticBatch(urls, client, te)
 	})
+
+	t.Run("TestElasticActions", func(te *testing.T) {
+		testElasticActions(urls, client, te)
+	})
+}
+
+func TestElasticBadAction(t *testing.T) {
+	conf := NewElasticsearchConfig()
+	conf.Action = "upsert"
+	if _, err := NewElasticsearch(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad action")
+	}
+
+	conf.Action = "${!metadata:action}"
+	if _, err := NewElasticsearch(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestElasticNoopFailures(t *testing.T) {
+	tests := []struct {
+		action string
+		status int
+		exp    bool
+	}{
+		{action: "delete", status: 404, exp: true},
+		{action: "create", status: 409, exp: true},
+		{action: "index", status: 404, exp: false},
+		{action: "index", status: 409, exp: false},
+		{action: "update", status: 404, exp: false},
+		{action: "delete", status: 500, exp: false},
+		{action: "create", status: 400, exp: false},
+	}
+	for _, test := range tests {
+		if act := isElasticNoop(test.action, test.status); act != test.exp {
+			t.Errorf("Wrong result for %v %v: %v != %v", test.action, test.status, act, test.exp)
+		}
+	}
 }
 
 func testElasticNoIndex(urls []string, client *elastic.Client, t *testing.T) {
@@ -393,3 +431,84 @@ func testElasticBatch(urls []string, client *elastic.Client, t *testing.T) {
 		}
 	}
 }
+
+func testElasticActions(urls []string, client *elastic.Client, t *testing.T) {
+	conf := NewElasticsearchConfig()
+	conf.Index = "test_conn_index"
+	conf.ID = "${!json_field:user}"
+	conf.Action = "${!metadata:action}"
+	conf.URLs = urls
+
+	m, err := NewElasticsearch(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		m.CloseAsync()
+		if cErr := m.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	writeWithActions := func(actions []string, docs ...string) {
+		t.Helper()
+		msg := message.New(nil)
+		for i, doc := range docs {
+			part := message.NewPart([]byte(doc))
+			part.Metadata().Set("action", actions[i])
+			msg.Append(part)
+		}
+		if err := m.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeWithActions(
+		[]string{"create", "create"},
+		`{"user":"action1","message":"hello world"}`,
+		`{"user":"action2","message":"hello world"}`,
+	)
+	writeWithActions(
+		[]string{"update", "delete"},
+		`{"user":"action1","message":"updated"}`,
+		`{"user":"action2"}`,
+	)
+
+	get, err := client.Get().
+		Index("test_conn_index").
+		Type("doc").
+		Id("action1").
+		Do(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get doc 'action1': %v", err)
+	}
+	sourceBytes, err := get.Source.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"user":"action1","message":"updated"}`, string(sourceBytes); exp != act {
+		t.Errorf("Wrong updated document: %v != %v", act, exp)
+	}
+
+	get, err = client.Get().
+		Index("test_conn_index").
+		Type("doc").
+		Id("action2").
+		Do(context.Background())
+	if err == nil && get.Found {
+		t.Error("Expected document 'action2' to be deleted")
+	} else if err != nil && !elastic.IsNotFound(err) {
+		t.Error(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`{"user":"action3"}`)})
+	msg.Get(0).Metadata().Set("action", "upsert")
+	if err = m.Write(msg); err == nil {
+		t.Error("Expected error from bad action")
+	}
+}
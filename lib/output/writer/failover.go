@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// FailoverConfig contains configuration fields that determine when a writer
+// fails over from one endpoint to the next.
+type FailoverConfig struct {
+	FailureThreshold int    `json:"failure_threshold" yaml:"failure_threshold"`
+	RecoveryInterval string `json:"recovery_interval" yaml:"recovery_interval"`
+}
+
+// NewFailoverConfig creates a FailoverConfig populated with default values.
+func NewFailoverConfig() FailoverConfig {
+	return FailoverConfig{
+		FailureThreshold: 3,
+		RecoveryInterval: "1m",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Failover is a writer.Type implementation that writes messages to the first
+// healthy writer of an ordered list. A writer is considered unhealthy once it
+// fails to connect or returns errors from a number of consecutive writes, after
+// which it is skipped until its recovery interval has passed.
+type Failover struct {
+	writers []Type
+	names   []string
+
+	threshold int
+	recovery  time.Duration
+
+	mut            sync.Mutex
+	active         int
+	connected      []bool
+	failures       []int
+	unhealthyUntil []time.Time
+
+	log log.Modular
+
+	mActive    metrics.StatGauge
+	mFailover  metrics.StatCounter
+	mUnhealthy metrics.StatCounter
+}
+
+// NewFailover creates a new Failover writer.Type from an ordered list of
+// writers and names describing their endpoints, where the first writer is the
+// primary.
+func NewFailover(
+	conf FailoverConfig,
+	names []string,
+	writers []Type,
+	log log.Modular,
+	stats metrics.Type,
+) (*Failover, error) {
+	if len(writers) == 0 {
+		return nil, errors.New("at least one endpoint must be specified")
+	}
+	if len(names) != len(writers) {
+		return nil, errors.New("each endpoint must have a name")
+	}
+	if conf.FailureThreshold < 1 {
+		return nil, errors.New("failure_threshold must be greater than zero")
+	}
+	recovery, err := time.ParseDuration(conf.RecoveryInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recovery_interval: %v", err)
+	}
+	return &Failover{
+		writers:        writers,
+		names:          names,
+		threshold:      conf.FailureThreshold,
+		recovery:       recovery,
+		connected:      make([]bool, len(writers)),
+		failures:       make([]int, len(writers)),
+		unhealthyUntil: make([]time.Time, len(writers)),
+		log:            log,
+		mActive:        stats.GetGauge("failover.endpoint"),
+		mFailover:      stats.GetCounter("failover.switched"),
+		mUnhealthy:     stats.GetCounter("failover.unhealthy"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// markUnhealthy must be called whilst holding mut.
+func (f *Failover) markUnhealthy(i int) {
+	f.failures[i] = 0
+	f.unhealthyUntil[i] = time.Now().Add(f.recovery)
+	f.mUnhealthy.Incr(1)
+	f.log.Warnf("Endpoint '%v' marked as unhealthy for %v\n", f.names[i], f.recovery)
+}
+
+// next returns the index of the writer to use for the next write, which is the
+// first healthy writer or, when none are healthy, the writer that is due to
+// recover soonest.
+func (f *Failover) next() int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	now := time.Now()
+	next := -1
+	for i, t := range f.unhealthyUntil {
+		if !now.Before(t) {
+			next = i
+			break
+		}
+	}
+	if next == -1 {
+		next = 0
+		for i, t := range f.unhealthyUntil {
+			if t.Before(f.unhealthyUntil[next]) {
+				next = i
+			}
+		}
+	}
+
+	if next != f.active {
+		if next < f.active {
+			f.log.Infof("Returning to endpoint '%v'\n", f.names[next])
+		} else {
+			f.log.Warnf("Failing over to endpoint '%v'\n", f.names[next])
+		}
+		f.active = next
+		f.mFailover.Incr(1)
+		f.mActive.Set(int64(next))
+	}
+	return next
+}
+
+// result records the outcome of a write to a writer, a writer that has lost its
+// connection is connected again before it is next written to.
+func (f *Failover) result(i int, err error) {
+	f.mut.Lock()
+	if err == types.ErrNotConnected {
+		f.connected[i] = false
+	}
+	if err == nil {
+		f.failures[i] = 0
+	} else if f.failures[i]++; f.failures[i] >= f.threshold {
+		f.markUnhealthy(i)
+	}
+	f.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to connect each writer, and succeeds as long as at least one
+// of them is connected. Writers that fail to connect are marked as unhealthy
+// and are connected again once they have recovered.
+func (f *Failover) Connect() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var firstErr error
+	anyConnected := false
+	for i, w := range f.writers {
+		if f.connected[i] {
+			anyConnected = true
+			continue
+		}
+		if err := w.Connect(); err != nil {
+			f.log.Errorf("Failed to connect to endpoint '%v': %v\n", f.names[i], err)
+			f.markUnhealthy(i)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		f.connected[i] = true
+		anyConnected = true
+	}
+	if !anyConnected {
+		return firstErr
+	}
+	return nil
+}
+
+// Write attempts to write a message to the first healthy writer.
+func (f *Failover) Write(msg types.Message) error {
+	i := f.next()
+
+	f.mut.Lock()
+	connected := f.connected[i]
+	f.mut.Unlock()
+
+	if !connected {
+		if err := f.writers[i].Connect(); err != nil {
+			f.result(i, err)
+			return err
+		}
+		f.mut.Lock()
+		f.connected[i] = true
+		f.mut.Unlock()
+	}
+
+	err := f.writers[i].Write(msg)
+	f.result(i, err)
+	return err
+}
+
+// CloseAsync begins cleaning up resources used by each writer asynchronously.
+func (f *Failover) CloseAsync() {
+	for _, w := range f.writers {
+		w.CloseAsync()
+	}
+}
+
+// WaitForClose will block until either each writer is closed or a specified
+// timeout occurs.
+func (f *Failover) WaitForClose(timeout time.Duration) error {
+	tStarted := time.Now()
+	remaining := timeout
+	for _, w := range f.writers {
+		if err := w.WaitForClose(remaining); err != nil {
+			return err
+		}
+		if remaining = timeout - time.Since(tStarted); remaining <= 0 {
+			return types.ErrTimeout
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
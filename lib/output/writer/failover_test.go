@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+type mockFailoverWriter struct {
+	connectErr error
+	writeErr   error
+	connects   int
+	writes     int
+}
+
+func (m *mockFailoverWriter) Connect() error {
+	m.connects++
+	return m.connectErr
+}
+
+func (m *mockFailoverWriter) Write(msg types.Message) error {
+	m.writes++
+	return m.writeErr
+}
+
+func (m *mockFailoverWriter) CloseAsync() {}
+
+func (m *mockFailoverWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func newTestFailover(t *testing.T, recovery string, writers ...*mockFailoverWriter) *Failover {
+	t.Helper()
+
+	conf := NewFailoverConfig()
+	conf.FailureThreshold = 2
+	conf.RecoveryInterval = recovery
+
+	names := make([]string, len(writers))
+	ws := make([]Type, len(writers))
+	for i, w := range writers {
+		names[i] = string('a' + rune(i))
+		ws[i] = w
+	}
+	f, err := NewFailover(conf, names, ws, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFailoverBadConfig(t *testing.T) {
+	conf := NewFailoverConfig()
+	conf.FailureThreshold = 0
+	if _, err := NewFailover(conf, []string{"a"}, []Type{&mockFailoverWriter{}}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero threshold")
+	}
+
+	conf = NewFailoverConfig()
+	conf.RecoveryInterval = "nope"
+	if _, err := NewFailover(conf, []string{"a"}, []Type{&mockFailoverWriter{}}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad recovery interval")
+	}
+}
+
+func TestFailoverWriteErrors(t *testing.T) {
+	primary, secondary := &mockFailoverWriter{}, &mockFailoverWriter{}
+	f := newTestFailover(t, "1h", primary, secondary)
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	msg := message.New([][]byte{[]byte("foo")})
+
+	primary.writeErr = errors.New("nope")
+	for i := 0; i < 2; i++ {
+		if err := f.Write(msg); err == nil {
+			t.Error("Expected error")
+		}
+	}
+	if err := f.Write(msg); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 2, primary.writes; exp != act {
+		t.Errorf("Wrong count of primary writes: %v != %v", act, exp)
+	}
+	if exp, act := 1, secondary.writes; exp != act {
+		t.Errorf("Wrong count of secondary writes: %v != %v", act, exp)
+	}
+}
+
+func TestFailoverReconnect(t *testing.T) {
+	primary, secondary := &mockFailoverWriter{}, &mockFailoverWriter{}
+	f := newTestFailover(t, "1h", primary, secondary)
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	msg := message.New([][]byte{[]byte("foo")})
+
+	primary.writeErr = types.ErrNotConnected
+	if err := f.Write(msg); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	primary.writeErr = nil
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Write(msg); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 2, primary.connects; exp != act {
+		t.Errorf("Wrong count of primary connects: %v != %v", act, exp)
+	}
+	if exp, act := 1, secondary.connects; exp != act {
+		t.Errorf("Wrong count of secondary connects: %v != %v", act, exp)
+	}
+	if exp, act := 2, primary.writes; exp != act {
+		t.Errorf("Wrong count of primary writes: %v != %v", act, exp)
+	}
+}
+
+func TestFailoverWriteErrorsReset(t *testing.T) {
+	primary, secondary := &mockFailoverWriter{}, &mockFailoverWriter{}
+	f := newTestFailover(t, "1h", primary, secondary)
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	msg := message.New([][]byte{[]byte("foo")})
+
+	for i := 0; i < 3; i++ {
+		primary.writeErr = errors.New("nope")
+		if err := f.Write(msg); err == nil {
+			t.Error("Expected error")
+		}
+		primary.writeErr = nil
+		if err := f.Write(msg); err != nil {
+			t.Error(err)
+		}
+	}
+	if exp, act := 6, primary.writes; exp != act {
+		t.Errorf("Wrong count of primary writes: %v != %v", act, exp)
+	}
+	if exp, act := 0, secondary.writes; exp != act {
+		t.Errorf("Wrong count of secondary writes: %v != %v", act, exp)
+	}
+}
+
+func TestFailoverConnectErrors(t *testing.T) {
+	primary, secondary := &mockFailoverWriter{
+		connectErr: errors.New("nope"),
+	}, &mockFailoverWriter{}
+	f := newTestFailover(t, "1h", primary, secondary)
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 0, primary.writes; exp != act {
+		t.Errorf("Wrong count of primary writes: %v != %v", act, exp)
+	}
+	if exp, act := 1, secondary.writes; exp != act {
+		t.Errorf("Wrong count of secondary writes: %v != %v", act, exp)
+	}
+
+	secondary.connectErr = errors.New("nope")
+	f = newTestFailover(t, "1h", primary, secondary)
+	if err := f.Connect(); err == nil {
+		t.Error("Expected error when no endpoints connect")
+	}
+}
+
+func TestFailoverRecovery(t *testing.T) {
+	primary, secondary := &mockFailoverWriter{
+		connectErr: errors.New("nope"),
+	}, &mockFailoverWriter{}
+	f := newTestFailover(t, "10ms", primary, secondary)
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	msg := message.New([][]byte{[]byte("foo")})
+	if err := f.Write(msg); err != nil {
+		t.Error(err)
+	}
+
+	primary.connectErr = nil
+	<-time.After(time.Millisecond * 20)
+
+	if err := f.Write(msg); err != nil {
+		t.Error(err)
+	}
+	if exp, act := 1, primary.writes; exp != act {
+		t.Errorf("Wrong count of primary writes: %v != %v", act, exp)
+	}
+	if exp, act := 2, primary.connects; exp != act {
+		t.Errorf("Wrong count of primary connects: %v != %v", act, exp)
+	}
+	if exp, act := 1, secondary.writes; exp != act {
+		t.Errorf("Wrong count of secondary writes: %v != %v", act, exp)
+	}
+}
+
+func TestFailoverAllUnhealthy(t *testing.T) {
+	primary, secondary := &mockFailoverWriter{}, &mockFailoverWriter{}
+	f := newTestFailover(t, "1h", primary, secondary)
+
+	if err := f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	msg := message.New([][]byte{[]byte("foo")})
+
+	primary.writeErr = errors.New("nope")
+	secondary.writeErr = errors.New("nope")
+	for i := 0; i < 5; i++ {
+		if err := f.Write(msg); err == nil {
+			t.Error("Expected error")
+		}
+	}
+
+	// Both endpoints are unhealthy and the primary is due to recover first.
+	if exp, act := 3, primary.writes; exp != act {
+		t.Errorf("Wrong count of primary writes: %v != %v", act, exp)
+	}
+	if exp, act := 2, secondary.writes; exp != act {
+		t.Errorf("Wrong count of secondary writes: %v != %v", act, exp)
+	}
+}
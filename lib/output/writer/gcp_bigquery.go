@@ -0,0 +1,323 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+// GCPBigQueryConfig contains configuration fields for the GCPBigQuery output
+// type.
+type GCPBigQueryConfig struct {
+	ProjectID           string            `json:"project" yaml:"project"`
+	Dataset             string            `json:"dataset" yaml:"dataset"`
+	Table               string            `json:"table" yaml:"table"`
+	Method              string            `json:"method" yaml:"method"`
+	InsertID            string            `json:"insert_id" yaml:"insert_id"`
+	Fields              map[string]string `json:"fields" yaml:"fields"`
+	IgnoreUnknownValues bool              `json:"ignore_unknown_values" yaml:"ignore_unknown_values"`
+	SkipInvalidRows     bool              `json:"skip_invalid_rows" yaml:"skip_invalid_rows"`
+	Timeout             string            `json:"timeout" yaml:"timeout"`
+}
+
+// NewGCPBigQueryConfig creates a new Config with default values.
+func NewGCPBigQueryConfig() GCPBigQueryConfig {
+	return GCPBigQueryConfig{
+		ProjectID:           "",
+		Dataset:             "",
+		Table:               "",
+		Method:              "stream",
+		InsertID:            "",
+		Fields:              map[string]string{},
+		IgnoreUnknownValues: false,
+		SkipInvalidRows:     false,
+		Timeout:             "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// bigQueryRow implements bigquery.ValueSaver for a single message part.
+type bigQueryRow struct {
+	row      map[string]bigquery.Value
+	insertID string
+}
+
+// Save returns the row contents and the insert ID used for deduplication.
+func (r *bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	return r.row, r.insertID, nil
+}
+
+//------------------------------------------------------------------------------
+
+// GCPBigQuery is a benthos writer.Type implementation that writes messages to a
+// GCP BigQuery table.
+type GCPBigQuery struct {
+	conf GCPBigQueryConfig
+
+	table    *text.InterpolatedString
+	insertID *text.InterpolatedString
+	fields   map[string]string
+	timeout  time.Duration
+	useLoad  bool
+
+	client    *bigquery.Client
+	clientMut sync.Mutex
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewGCPBigQuery creates a new GCP BigQuery writer.Type.
+func NewGCPBigQuery(
+	conf GCPBigQueryConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*GCPBigQuery, error) {
+	g := &GCPBigQuery{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		table:    text.NewInterpolatedString(conf.Table),
+		insertID: text.NewInterpolatedString(conf.InsertID),
+		fields:   map[string]string{},
+	}
+	switch conf.Method {
+	case "stream":
+	case "load":
+		g.useLoad = true
+	default:
+		return nil, fmt.Errorf("unrecognised method: %v", conf.Method)
+	}
+	if len(conf.Dataset) == 0 {
+		return nil, errors.New("a dataset must be specified")
+	}
+	if len(conf.Table) == 0 {
+		return nil, errors.New("a table must be specified")
+	}
+	for k, v := range conf.Fields {
+		if v == "." {
+			v = ""
+		}
+		g.fields[k] = v
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if g.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout period string: %v", err)
+		}
+	}
+	return g, nil
+}
+
+// Connect attempts to establish a connection to the target GCP BigQuery
+// dataset.
+func (g *GCPBigQuery) Connect() error {
+	g.clientMut.Lock()
+	defer g.clientMut.Unlock()
+	if g.client != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	client, err := bigquery.NewClient(ctx, g.conf.ProjectID)
+	if err != nil {
+		return err
+	}
+	if _, err = client.Dataset(g.conf.Dataset).Metadata(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to access dataset '%v': %v", g.conf.Dataset, err)
+	}
+
+	g.client = client
+	g.log.Infof("Sending GCP BigQuery rows to project '%v' and dataset '%v'\n", g.conf.ProjectID, g.conf.Dataset)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// mapBigQueryRow extracts a row from a JSON document according to a map of
+// column names to paths. If the map is empty the document must be an object
+// and its top level fields are used as the row.
+func mapBigQueryRow(fields map[string]string, root interface{}) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		obj, ok := root.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected JSON object, found %T", root)
+		}
+		return obj, nil
+	}
+	gObj, err := gabs.Consume(root)
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		target := gObj
+		if len(v) > 0 {
+			target = gObj.Path(v)
+		}
+		if target.Data() != nil {
+			row[k] = target.Data()
+		}
+	}
+	return row, nil
+}
+
+// groupByTable returns the part indexes of a message grouped by their resolved
+// table names, and the table names in the order they were first seen.
+func groupByTable(table *text.InterpolatedString, msg types.Message) ([]string, map[string][]int) {
+	var tables []string
+	groups := map[string][]int{}
+	for i := 0; i < msg.Len(); i++ {
+		t := table.Get(message.Lock(msg, i))
+		if _, exists := groups[t]; !exists {
+			tables = append(tables, t)
+		}
+		groups[t] = append(groups[t], i)
+	}
+	return tables, groups
+}
+
+func (g *GCPBigQuery) rowsFor(msg types.Message, indexes []int) ([]*bigQueryRow, error) {
+	rows := make([]*bigQueryRow, 0, len(indexes))
+	for _, i := range indexes {
+		jObj, err := msg.Get(i).JSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message %v as JSON: %v", i, err)
+		}
+		mapped, err := mapBigQueryRow(g.fields, jObj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map message %v: %v", i, err)
+		}
+		row := make(map[string]bigquery.Value, len(mapped))
+		for k, v := range mapped {
+			row[k] = v
+		}
+		rows = append(rows, &bigQueryRow{
+			row:      row,
+			insertID: g.insertID.Get(message.Lock(msg, i)),
+		})
+	}
+	return rows, nil
+}
+
+func (g *GCPBigQuery) stream(ctx context.Context, table *bigquery.Table, rows []*bigQueryRow) error {
+	uploader := table.Uploader()
+	uploader.IgnoreUnknownValues = g.conf.IgnoreUnknownValues
+	uploader.SkipInvalidRows = g.conf.SkipInvalidRows
+	return uploader.Put(ctx, rows)
+}
+
+func (g *GCPBigQuery) load(ctx context.Context, table *bigquery.Table, rows []*bigQueryRow) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range rows {
+		if err := enc.Encode(r.row); err != nil {
+			return err
+		}
+	}
+
+	source := bigquery.NewReaderSource(&buf)
+	source.SourceFormat = bigquery.JSON
+	source.IgnoreUnknownValues = g.conf.IgnoreUnknownValues
+
+	loader := table.LoaderFrom(source)
+	loader.WriteDisposition = bigquery.WriteAppend
+
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// Write attempts to write message contents to a target BigQuery table.
+func (g *GCPBigQuery) Write(msg types.Message) error {
+	g.clientMut.Lock()
+	client := g.client
+	g.clientMut.Unlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	dataset := client.Dataset(g.conf.Dataset)
+	tables, groups := groupByTable(g.table, msg)
+	for _, t := range tables {
+		rows, err := g.rowsFor(msg, groups[t])
+		if err != nil {
+			return err
+		}
+		if g.useLoad {
+			err = g.load(ctx, dataset.Table(t), rows)
+		} else {
+			err = g.stream(ctx, dataset.Table(t), rows)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write rows to table '%v': %v", t, err)
+		}
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (g *GCPBigQuery) CloseAsync() {
+	g.clientMut.Lock()
+	defer g.clientMut.Unlock()
+	if g.client != nil {
+		g.client.Close()
+		g.client = nil
+	}
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (g *GCPBigQuery) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,130 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+func TestGCPBigQueryMapRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		input  string
+		output map[string]interface{}
+		err    bool
+	}{
+		{
+			name:   "no fields",
+			fields: map[string]string{},
+			input:  `{"foo":"bar","baz":{"qux":"quz"}}`,
+			output: map[string]interface{}{
+				"foo": "bar",
+				"baz": map[string]interface{}{"qux": "quz"},
+			},
+		},
+		{
+			name:   "no fields not object",
+			fields: map[string]string{},
+			input:  `["foo","bar"]`,
+			err:    true,
+		},
+		{
+			name: "mapped fields",
+			fields: map[string]string{
+				"id":      "foo",
+				"nested":  "baz.qux",
+				"whole":   "",
+				"missing": "does.not.exist",
+			},
+			input: `{"foo":"bar","baz":{"qux":"quz"}}`,
+			output: map[string]interface{}{
+				"id":     "bar",
+				"nested": "quz",
+				"whole": map[string]interface{}{
+					"foo": "bar",
+					"baz": map[string]interface{}{"qux": "quz"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		jObj, err := message.NewPart([]byte(test.input)).JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := mapBigQueryRow(test.fields, jObj)
+		if test.err {
+			if err == nil {
+				t.Errorf("%v: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.output, res) {
+			t.Errorf("%v: wrong result: %v != %v", test.name, res, test.output)
+		}
+	}
+}
+
+func TestGCPBigQueryGroupByTable(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`{"day":"20190101"}`),
+		[]byte(`{"day":"20190102"}`),
+		[]byte(`{"day":"20190101"}`),
+	})
+
+	tables, groups := groupByTable(text.NewInterpolatedString(`foo$${!json_field:day}`), msg)
+	if exp, act := []string{"foo$20190101", "foo$20190102"}, tables; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong tables: %v != %v", act, exp)
+	}
+	expGroups := map[string][]int{
+		"foo$20190101": {0, 2},
+		"foo$20190102": {1},
+	}
+	if !reflect.DeepEqual(expGroups, groups) {
+		t.Errorf("Wrong groups: %v != %v", groups, expGroups)
+	}
+}
+
+func TestGCPBigQueryBadConfig(t *testing.T) {
+	conf := NewGCPBigQueryConfig()
+	conf.Dataset = "foo"
+	conf.Table = "bar"
+	conf.Method = "nope"
+	if _, err := NewGCPBigQuery(conf, nil, nil); err == nil {
+		t.Error("Expected error from bad method")
+	}
+
+	conf.Method = "load"
+	conf.Dataset = ""
+	if _, err := NewGCPBigQuery(conf, nil, nil); err == nil {
+		t.Error("Expected error from missing dataset")
+	}
+}
@@ -108,13 +108,17 @@ func (c *GCPPubSub) Connect() error {
 	return nil
 }
 
+// ConcurrentWrites marks GCPPubSub as safe for concurrent writes, a topic may be
+// published to from multiple goroutines.
+func (c *GCPPubSub) ConcurrentWrites() {}
+
 // Write attempts to write message contents to a target topic.
 func (c *GCPPubSub) Write(msg types.Message) error {
 	c.topicMut.Lock()
 	topic := c.topic
 	c.topicMut.Unlock()
 
-	if c.topic == nil {
+	if topic == nil {
 		return types.ErrNotConnected
 	}
 
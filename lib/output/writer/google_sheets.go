@@ -0,0 +1,276 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+//------------------------------------------------------------------------------
+
+const googleSheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// GoogleSheetsConfig contains configuration fields for the GoogleSheets output
+// type.
+type GoogleSheetsConfig struct {
+	SpreadsheetID    string   `json:"spreadsheet_id" yaml:"spreadsheet_id"`
+	Range            string   `json:"range" yaml:"range"`
+	Columns          []string `json:"columns" yaml:"columns"`
+	ValueInputOption string   `json:"value_input_option" yaml:"value_input_option"`
+	CredentialsFile  string   `json:"credentials_file" yaml:"credentials_file"`
+	Endpoint         string   `json:"endpoint" yaml:"endpoint"`
+	Timeout          string   `json:"timeout" yaml:"timeout"`
+}
+
+// NewGoogleSheetsConfig creates a new Config with default values.
+func NewGoogleSheetsConfig() GoogleSheetsConfig {
+	return GoogleSheetsConfig{
+		SpreadsheetID:    "",
+		Range:            "Sheet1",
+		Columns:          []string{},
+		ValueInputOption: "USER_ENTERED",
+		CredentialsFile:  "",
+		Endpoint:         "https://sheets.googleapis.com",
+		Timeout:          "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GoogleSheets is a benthos writer.Type implementation that appends messages as
+// rows to a Google Sheets spreadsheet.
+type GoogleSheets struct {
+	conf GoogleSheetsConfig
+
+	sheetRange *text.InterpolatedString
+	columns    []*text.InterpolatedString
+	timeout    time.Duration
+
+	client    *http.Client
+	clientMut sync.Mutex
+
+	mgr   types.Manager
+	log   log.Modular
+	stats metrics.Type
+
+	mSkipped metrics.StatCounter
+}
+
+// NewGoogleSheets creates a new Google Sheets writer.Type.
+func NewGoogleSheets(
+	conf GoogleSheetsConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (*GoogleSheets, error) {
+	if len(conf.SpreadsheetID) == 0 {
+		return nil, errors.New("a spreadsheet_id must be specified")
+	}
+	if len(conf.Range) == 0 {
+		return nil, errors.New("a range must be specified")
+	}
+	switch conf.ValueInputOption {
+	case "RAW", "USER_ENTERED":
+	default:
+		return nil, fmt.Errorf("unrecognised value_input_option: %v", conf.ValueInputOption)
+	}
+	g := &GoogleSheets{
+		conf:       conf,
+		mgr:        mgr,
+		log:        log,
+		stats:      stats,
+		sheetRange: text.NewInterpolatedString(conf.Range),
+		mSkipped:   stats.GetCounter("skipped"),
+	}
+	for _, c := range conf.Columns {
+		g.columns = append(g.columns, text.NewInterpolatedString(c))
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if g.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout period string: %v", err)
+		}
+	}
+	return g, nil
+}
+
+// Connect creates an OAuth2 authenticated client, using the configured
+// credentials file or otherwise the application default credentials.
+func (g *GoogleSheets) Connect() error {
+	g.clientMut.Lock()
+	defer g.clientMut.Unlock()
+	if g.client != nil {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	var client *http.Client
+	if len(g.conf.CredentialsFile) > 0 {
+		credsBytes, err := ioutil.ReadFile(g.conf.CredentialsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read credentials file: %v", err)
+		}
+		creds, err := google.CredentialsFromJSON(ctx, credsBytes, googleSheetsScope)
+		if err != nil {
+			return fmt.Errorf("failed to parse credentials file: %v", err)
+		}
+		client = oauth2.NewClient(ctx, creds.TokenSource)
+	} else {
+		var err error
+		if client, err = google.DefaultClient(ctx, googleSheetsScope); err != nil {
+			return err
+		}
+	}
+	client.Timeout = g.timeout
+
+	g.client = client
+	g.log.Infof("Appending rows to Google Sheets spreadsheet '%v'\n", g.conf.SpreadsheetID)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// rowFor extracts the cells of a row from a message part, either by resolving
+// the configured columns or, when no columns are configured, by parsing the
+// part as a JSON array.
+func (g *GoogleSheets) rowFor(msg types.Message, index int) ([]interface{}, error) {
+	if len(g.columns) == 0 {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message %v as JSON: %v", index, err)
+		}
+		row, ok := jObj.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected message %v to be a JSON array, found %T", index, jObj)
+		}
+		return row, nil
+	}
+	lMsg := message.Lock(msg, index)
+	row := make([]interface{}, len(g.columns))
+	for i, c := range g.columns {
+		row[i] = c.Get(lMsg)
+	}
+	return row, nil
+}
+
+func (g *GoogleSheets) appendRows(client *http.Client, sheetRange string, rows [][]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"values": rows,
+	})
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf(
+		"%v/v4/spreadsheets/%v/values/%v:append?valueInputOption=%v&insertDataOption=INSERT_ROWS",
+		strings.TrimSuffix(g.conf.Endpoint, "/"),
+		url.PathEscape(g.conf.SpreadsheetID),
+		url.PathEscape(sheetRange),
+		g.conf.ValueInputOption,
+	)
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("append request returned unexpected status %v: %s", res.Status, bytes.TrimSpace(resBody))
+	}
+	return nil
+}
+
+// Write attempts to append the parts of a message as rows to the target
+// spreadsheet, with a request per resolved range. Parts that cannot be
+// converted into a row are dropped, as retrying them would never succeed.
+func (g *GoogleSheets) Write(msg types.Message) error {
+	g.clientMut.Lock()
+	client := g.client
+	g.clientMut.Unlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	ranges, groups := groupByTable(g.sheetRange, msg)
+	for _, r := range ranges {
+		rows := make([][]interface{}, 0, len(groups[r]))
+		for _, i := range groups[r] {
+			row, err := g.rowFor(msg, i)
+			if err != nil {
+				g.log.Errorf("Dropping message part %v: %v\n", i, err)
+				g.mSkipped.Incr(1)
+				drop.Report(g.mgr, "google_sheets", message.New([][]byte{msg.Get(i).Get()}))
+				continue
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if err := g.appendRows(client, r, rows); err != nil {
+			return fmt.Errorf("failed to append rows to range '%v': %v", r, err)
+		}
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (g *GoogleSheets) CloseAsync() {
+	g.clientMut.Lock()
+	g.client = nil
+	g.clientMut.Unlock()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (g *GoogleSheets) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,197 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+type sheetsAppend struct {
+	path   string
+	query  string
+	values [][]interface{}
+}
+
+func sheetsTestServer(t *testing.T) (*httptest.Server, func() []sheetsAppend) {
+	t.Helper()
+
+	var appendsMut sync.Mutex
+	var appends []sheetsAppend
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Wrong method: %v", r.Method)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var req struct {
+			Values [][]interface{} `json:"values"`
+		}
+		if err = json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+		}
+		if req.Values[0][0] == "fail" {
+			http.Error(w, "nope", http.StatusBadRequest)
+			return
+		}
+		appendsMut.Lock()
+		appends = append(appends, sheetsAppend{
+			path:   r.URL.EscapedPath(),
+			query:  r.URL.RawQuery,
+			values: req.Values,
+		})
+		appendsMut.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+
+	return ts, func() []sheetsAppend {
+		appendsMut.Lock()
+		defer appendsMut.Unlock()
+		return appends
+	}
+}
+
+func TestGoogleSheetsColumns(t *testing.T) {
+	ts, getAppends := sheetsTestServer(t)
+	defer ts.Close()
+
+	conf := NewGoogleSheetsConfig()
+	conf.SpreadsheetID = "foo"
+	conf.Range = "${!metadata:sheet}"
+	conf.Columns = []string{"${!json_field:name}", "${!metadata:sheet}"}
+	conf.Endpoint = ts.URL
+
+	g, err := NewGoogleSheets(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Write(message.New(nil)); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error, got: %v", err)
+	}
+	g.client = ts.Client()
+
+	msg := message.New([][]byte{
+		[]byte(`{"name":"a"}`),
+		[]byte(`{"name":"b"}`),
+		[]byte(`{"name":"c"}`),
+	})
+	msg.Get(0).Metadata().Set("sheet", "First")
+	msg.Get(1).Metadata().Set("sheet", "Second Sheet")
+	msg.Get(2).Metadata().Set("sheet", "First")
+
+	if err = g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []sheetsAppend{
+		{
+			path:   "/v4/spreadsheets/foo/values/First:append",
+			query:  "valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+			values: [][]interface{}{{"a", "First"}, {"c", "First"}},
+		},
+		{
+			path:   "/v4/spreadsheets/foo/values/Second%20Sheet:append",
+			query:  "valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+			values: [][]interface{}{{"b", "Second Sheet"}},
+		},
+	}
+	if act := getAppends(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong appends: %v != %v", act, exp)
+	}
+}
+
+func TestGoogleSheetsJSONArrays(t *testing.T) {
+	ts, getAppends := sheetsTestServer(t)
+	defer ts.Close()
+
+	conf := NewGoogleSheetsConfig()
+	conf.SpreadsheetID = "foo"
+	conf.ValueInputOption = "RAW"
+	conf.Endpoint = ts.URL
+
+	g, err := NewGoogleSheets(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.client = ts.Client()
+
+	if err = g.Write(message.New([][]byte{
+		[]byte(`["a",1,true]`),
+		[]byte(`["b",2]`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []sheetsAppend{
+		{
+			path:   "/v4/spreadsheets/foo/values/Sheet1:append",
+			query:  "valueInputOption=RAW&insertDataOption=INSERT_ROWS",
+			values: [][]interface{}{{"a", float64(1), true}, {"b", float64(2)}},
+		},
+	}
+	if act := getAppends(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong appends: %v != %v", act, exp)
+	}
+
+	if err = g.Write(message.New([][]byte{
+		[]byte(`{"not":"an array"}`),
+		[]byte(`["c",3]`),
+	})); err != nil {
+		t.Errorf("Unexpected error from non-array message: %v", err)
+	}
+	exp = append(exp, sheetsAppend{
+		path:   "/v4/spreadsheets/foo/values/Sheet1:append",
+		query:  "valueInputOption=RAW&insertDataOption=INSERT_ROWS",
+		values: [][]interface{}{{"c", float64(3)}},
+	})
+	if act := getAppends(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong appends: %v != %v", act, exp)
+	}
+	if err = g.Write(message.New([][]byte{[]byte(`["fail"]`)})); err == nil {
+		t.Error("Expected error from failed request")
+	}
+}
+
+func TestGoogleSheetsBadConfig(t *testing.T) {
+	conf := NewGoogleSheetsConfig()
+	if _, err := NewGoogleSheets(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing spreadsheet_id")
+	}
+
+	conf.SpreadsheetID = "foo"
+	conf.ValueInputOption = "nope"
+	if _, err := NewGoogleSheets(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad value_input_option")
+	}
+}
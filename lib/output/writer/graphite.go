@@ -0,0 +1,223 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// GraphiteConfig contains configuration fields for the Graphite output type.
+type GraphiteConfig struct {
+	Network      string                `json:"network" yaml:"network"`
+	Address      string                `json:"address" yaml:"address"`
+	Prefix       string                `json:"prefix" yaml:"prefix"`
+	Path         string                `json:"path" yaml:"path"`
+	Value        string                `json:"value" yaml:"value"`
+	Timestamp    string                `json:"timestamp" yaml:"timestamp"`
+	WriteTimeout string                `json:"write_timeout" yaml:"write_timeout"`
+	Reconnect    SocketReconnectConfig `json:"reconnect" yaml:"reconnect"`
+}
+
+// NewGraphiteConfig creates a new GraphiteConfig with default values.
+func NewGraphiteConfig() GraphiteConfig {
+	return GraphiteConfig{
+		Network:      "tcp",
+		Address:      "localhost:2003",
+		Prefix:       "",
+		Path:         "${!json_field:path}",
+		Value:        "${!json_field:value}",
+		Timestamp:    "${!json_field:timestamp}",
+		WriteTimeout: "5s",
+		Reconnect: SocketReconnectConfig{
+			InitialInterval: "500ms",
+			MaxInterval:     "30s",
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Graphite is an output type that writes metrics to Graphite using the
+// plaintext protocol.
+type Graphite struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf      GraphiteConfig
+	path      *text.InterpolatedString
+	value     *text.InterpolatedString
+	timestamp *text.InterpolatedString
+
+	socket *Socket
+
+	mInvalid metrics.StatCounter
+}
+
+// NewGraphite creates a new Graphite output type.
+func NewGraphite(
+	conf GraphiteConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Graphite, error) {
+	switch conf.Network {
+	case "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("network not recognised: %v", conf.Network)
+	}
+
+	sConf := NewSocketConfig()
+	sConf.Network = conf.Network
+	sConf.Address = conf.Address
+	sConf.WriteTimeout = conf.WriteTimeout
+	sConf.Reconnect = conf.Reconnect
+
+	socket, err := NewSocket(sConf, log, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Graphite{
+		log:       log,
+		stats:     stats,
+		conf:      conf,
+		path:      text.NewInterpolatedString(conf.Path),
+		value:     text.NewInterpolatedString(conf.Value),
+		timestamp: text.NewInterpolatedString(conf.Timestamp),
+		socket:    socket,
+		mInvalid:  stats.GetCounter("error.invalid_metric"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// graphitePath removes characters from a metric path that would break the
+// plaintext protocol.
+func graphitePath(path string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, path)
+}
+
+// graphiteTimestamp parses a timestamp either as a unix timestamp in seconds or
+// an RFC3339 formatted string, defaulting to now when empty or null.
+func graphiteTimestamp(ts string) (int64, error) {
+	if len(ts) == 0 || ts == "null" {
+		return time.Now().Unix(), nil
+	}
+	if i, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(ts, 64); err == nil {
+		return int64(f), nil
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timestamp '%v'", ts)
+	}
+	return t.Unix(), nil
+}
+
+// line creates a plaintext protocol line from a message part.
+func (g *Graphite) line(msg types.Message, index int) ([]byte, error) {
+	lMsg := message.Lock(msg, index)
+
+	path := g.path.Get(lMsg)
+	if len(path) == 0 || path == "null" {
+		return nil, fmt.Errorf("metric path is empty")
+	}
+	path = graphitePath(g.conf.Prefix + path)
+
+	valueStr := g.value.Get(lMsg)
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value '%v' of metric '%v'", valueStr, path)
+	}
+
+	ts, err := graphiteTimestamp(g.timestamp.Get(lMsg))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(
+		"%v %v %v", path, strconv.FormatFloat(value, 'f', -1, 64), ts,
+	)), nil
+}
+
+// Connect establishes a connection to the Graphite server.
+func (g *Graphite) Connect() error {
+	return g.socket.Connect()
+}
+
+// Write attempts to write the parts of a message as metrics. Parts that cannot
+// be converted into a metric are dropped. Over TCP the metrics of a batch are
+// written together, over UDP each metric is sent as its own datagram.
+func (g *Graphite) Write(msg types.Message) error {
+	var lines [][]byte
+	msg.Iter(func(i int, p types.Part) error {
+		l, err := g.line(msg, i)
+		if err != nil {
+			g.mInvalid.Incr(1)
+			g.log.Errorf("Dropping invalid metric: %v\n", err)
+			return nil
+		}
+		lines = append(lines, l)
+		return nil
+	})
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var metricsMsg types.Message
+	if g.conf.Network == "udp" {
+		metricsMsg = message.New(lines)
+	} else {
+		metricsMsg = message.New([][]byte{bytes.Join(lines, []byte("\n"))})
+	}
+	return g.socket.Write(metricsMsg)
+}
+
+// CloseAsync shuts down the Graphite output and stops processing messages.
+func (g *Graphite) CloseAsync() {
+	g.socket.CloseAsync()
+}
+
+// WaitForClose blocks until the Graphite output has closed down.
+func (g *Graphite) WaitForClose(timeout time.Duration) error {
+	return g.socket.WaitForClose(timeout)
+}
+
+//------------------------------------------------------------------------------
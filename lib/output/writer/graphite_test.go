@@ -0,0 +1,172 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestGraphiteTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	resChan := make(chan []string, 1)
+	go func() {
+		conn, cerr := ln.Accept()
+		if cerr != nil {
+			t.Error(cerr)
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for len(lines) < 4 && scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		resChan <- lines
+	}()
+
+	conf := NewGraphiteConfig()
+	conf.Address = ln.Addr().String()
+	conf.Prefix = "benthos."
+
+	g, err := NewGraphite(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g.CloseAsync()
+		if cerr := g.WaitForClose(time.Second); cerr != nil {
+			t.Error(cerr)
+		}
+	}()
+
+	if err = g.Write(message.New([][]byte{
+		[]byte(`{"path":"foo.cpu","value":0.35,"timestamp":1559830000}`),
+		[]byte(`{"path":"foo.mem","value":"1024","timestamp":"2019-06-06T14:06:40Z"}`),
+		[]byte(`{"path":"foo.bad","value":"nope","timestamp":1559830000}`),
+		[]byte(`{"value":10,"timestamp":1559830000}`),
+		[]byte(`{"path":"foo bar","value":-2,"timestamp":1559830000.5}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().Unix()
+	if err = g.Write(message.New([][]byte{
+		[]byte(`{"path":"foo.now","value":1}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	select {
+	case lines = <-resChan:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	exp := []string{
+		"benthos.foo.cpu 0.35 1559830000",
+		"benthos.foo.mem 1024 1559830000",
+		"benthos.foo_bar -2 1559830000",
+	}
+	if len(lines) != 4 {
+		t.Fatalf("Wrong count of lines: %v", lines)
+	}
+	if !reflect.DeepEqual(exp, lines[:3]) {
+		t.Errorf("Wrong lines: %v != %v", lines[:3], exp)
+	}
+
+	nowFields := strings.Split(lines[3], " ")
+	if len(nowFields) != 3 || nowFields[0] != "benthos.foo.now" || nowFields[1] != "1" {
+		t.Fatalf("Wrong line: %v", lines[3])
+	}
+	if ts, _ := strconv.ParseInt(nowFields[2], 10, 64); ts < before {
+		t.Errorf("Expected current timestamp, got %v", ts)
+	}
+}
+
+func TestGraphiteUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conf := NewGraphiteConfig()
+	conf.Network = "udp"
+	conf.Address = conn.LocalAddr().String()
+	conf.Path = "${!metadata:path}"
+	conf.Value = "${!content}"
+	conf.Timestamp = "1559830000"
+
+	g, err := NewGraphite(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.CloseAsync()
+
+	msg := message.New([][]byte{[]byte("1"), []byte("2")})
+	msg.Get(0).Metadata().Set("path", "foo")
+	msg.Get(1).Metadata().Set("path", "bar")
+	if err = g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"foo 1 1559830000\n", "bar 2 1559830000\n"}
+	buf := make([]byte, 1024)
+	for _, e := range exp {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, rerr := conn.ReadFrom(buf)
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		if act := string(buf[:n]); e != act {
+			t.Errorf("Wrong datagram: %q != %q", act, e)
+		}
+	}
+}
+
+func TestGraphiteBadConfig(t *testing.T) {
+	conf := NewGraphiteConfig()
+	conf.Network = "unix"
+	if _, err := NewGraphite(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unix network")
+	}
+}
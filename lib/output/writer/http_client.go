@@ -21,6 +21,8 @@
 package writer
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -36,7 +38,22 @@ import (
 // type.
 type HTTPClientConfig struct {
 	client.Config     `json:",inline" yaml:",inline"`
-	PropagateResponse bool `json:"propagate_response" yaml:"propagate_response"`
+	PropagateResponse bool                         `json:"propagate_response" yaml:"propagate_response"`
+	MaxBatchBytes     int                          `json:"max_batch_bytes" yaml:"max_batch_bytes"`
+	Multipart         []client.MultipartExpression `json:"multipart" yaml:"multipart"`
+	Failover          HTTPClientFailoverConfig     `json:"failover" yaml:"failover"`
+}
+
+// HTTPClientFailoverConfig contains configuration fields for failing over to
+// secondary HTTP servers.
+type HTTPClientFailoverConfig struct {
+	FailoverConfig `json:",inline" yaml:",inline"`
+	Endpoints      []HTTPClientEndpointConfig `json:"endpoints" yaml:"endpoints"`
+}
+
+// HTTPClientEndpointConfig describes a secondary HTTP server.
+type HTTPClientEndpointConfig struct {
+	URL string `json:"url" yaml:"url"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
@@ -44,6 +61,12 @@ func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
 		Config:            client.NewConfig(),
 		PropagateResponse: false,
+		MaxBatchBytes:     0,
+		Multipart:         []client.MultipartExpression{},
+		Failover: HTTPClientFailoverConfig{
+			FailoverConfig: NewFailoverConfig(),
+			Endpoints:      []HTTPClientEndpointConfig{},
+		},
 	}
 }
 
@@ -59,6 +82,8 @@ type HTTPClient struct {
 
 	conf      HTTPClientConfig
 	closeChan chan struct{}
+
+	mBatchSplit metrics.StatCounter
 }
 
 // NewHTTPClient creates a new HTTPClient writer type.
@@ -73,20 +98,57 @@ func NewHTTPClient(
 		log:       log,
 		conf:      conf,
 		closeChan: make(chan struct{}),
+
+		mBatchSplit: stats.GetCounter("batch.split"),
 	}
-	var err error
-	if h.client, err = client.New(
-		conf.Config,
+	opts := []func(*client.Type){
 		client.OptSetCloseChan(h.closeChan),
 		client.OptSetLogger(h.log),
 		client.OptSetManager(mgr),
 		client.OptSetStats(metrics.Namespaced(h.stats, "output.http_client")),
-	); err != nil {
+	}
+	if len(conf.Multipart) > 0 {
+		opts = append(opts, client.OptSetMultipart(conf.Multipart))
+	}
+	var err error
+	if h.client, err = client.New(conf.Config, opts...); err != nil {
 		return nil, err
 	}
 	return &h, nil
 }
 
+// NewHTTPClientFailover creates a writer.Type that sends messages to the primary
+// URL of a config and fails over to any secondary URLs configured.
+func NewHTTPClientFailover(
+	conf HTTPClientConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Failover.Endpoints) == 0 {
+		return NewHTTPClient(conf, mgr, log, stats)
+	}
+	confs := []HTTPClientConfig{conf}
+	for _, e := range conf.Failover.Endpoints {
+		if len(e.URL) == 0 {
+			return nil, errors.New("failover endpoints must specify a url")
+		}
+		eConf := conf
+		eConf.URL = e.URL
+		confs = append(confs, eConf)
+	}
+	names := make([]string, len(confs))
+	writers := make([]Type, len(confs))
+	for i, c := range confs {
+		w, err := NewHTTPClient(c, mgr, log, stats)
+		if err != nil {
+			return nil, err
+		}
+		names[i], writers[i] = c.URL, w
+	}
+	return NewFailover(conf.Failover.FailoverConfig, names, writers, log, stats)
+}
+
 //------------------------------------------------------------------------------
 
 // Connect does nothing.
@@ -95,11 +157,37 @@ func (h *HTTPClient) Connect() error {
 	return nil
 }
 
+// ConcurrentWrites marks HTTPClient as safe for concurrent writes, requests are
+// independent and share a thread safe client.
+func (h *HTTPClient) ConcurrentWrites() {}
+
 // Write attempts to send a message to an HTTP server, this attempt may include
-// retries, and if all retries fail an error is returned.
+// retries, and if all retries fail an error is returned. If the message exceeds
+// the configured max batch bytes it is split across multiple requests.
 func (h *HTTPClient) Write(msg types.Message) error {
-	resultMsg, err := h.client.Send(msg)
+	batches, err := splitBySize(msg, h.conf.MaxBatchBytes)
+	if err != nil {
+		return err
+	}
+	if len(batches) > 1 {
+		h.mBatchSplit.Incr(1)
+	}
+	for _, batch := range batches {
+		if err = h.writeBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HTTPClient) writeBatch(msg types.Message) error {
+	res, err := h.client.Do(msg)
+	if err != nil {
+		return err
+	}
+	resultMsg, err := h.client.ParseResponse(res)
 	if err == nil && h.conf.PropagateResponse {
+		statusCode := strconv.Itoa(res.StatusCode)
 		msgCopy := msg.Copy()
 		parts := make([]types.Part, resultMsg.Len())
 		resultMsg.Iter(func(i int, p types.Part) error {
@@ -109,6 +197,7 @@ func (h *HTTPClient) Write(msg types.Message) error {
 				parts[i] = msgCopy.Get(0)
 			}
 			parts[i].Set(p.Get())
+			parts[i].Metadata().Set("http_status_code", statusCode)
 			return nil
 		})
 		msgCopy.SetAll(parts)
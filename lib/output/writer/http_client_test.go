@@ -276,3 +276,49 @@ func TestHTTPClientMultipart(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestHTTPClientMaxBatchBytes(t *testing.T) {
+	resultChan := make(chan []byte, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resultChan <- b
+	}))
+	defer ts.Close()
+
+	conf := NewHTTPClientConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.MaxBatchBytes = 6
+
+	h, err := NewHTTPClient(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = h.Write(message.New([][]byte{
+		[]byte("foo"),
+		[]byte("barbaz"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"foo", "barbaz"} {
+		select {
+		case act := <-resultChan:
+			if exp != string(act) {
+				t.Errorf("Wrong result, %v != %v", exp, string(act))
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	if err = h.Write(message.New([][]byte{
+		[]byte("barbazqux"),
+	})); err == nil {
+		t.Error("Expected error from oversized part")
+	}
+}
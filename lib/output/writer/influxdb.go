@@ -0,0 +1,362 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+// InfluxDBConfig contains configuration fields for the InfluxDB output type.
+type InfluxDBConfig struct {
+	URL             string            `json:"url" yaml:"url"`
+	APIVersion      string            `json:"api_version" yaml:"api_version"`
+	Token           string            `json:"token" yaml:"token"`
+	Org             string            `json:"org" yaml:"org"`
+	Bucket          string            `json:"bucket" yaml:"bucket"`
+	DB              string            `json:"db" yaml:"db"`
+	RetentionPolicy string            `json:"retention_policy" yaml:"retention_policy"`
+	Username        string            `json:"username" yaml:"username"`
+	Password        string            `json:"password" yaml:"password"`
+	Measurement     string            `json:"measurement" yaml:"measurement"`
+	Tags            map[string]string `json:"tags" yaml:"tags"`
+	Fields          map[string]string `json:"fields" yaml:"fields"`
+	Timestamp       string            `json:"timestamp" yaml:"timestamp"`
+	Precision       string            `json:"precision" yaml:"precision"`
+	Timeout         string            `json:"timeout" yaml:"timeout"`
+	TLS             tls.Config        `json:"tls" yaml:"tls"`
+}
+
+// NewInfluxDBConfig creates a new InfluxDBConfig with default values.
+func NewInfluxDBConfig() InfluxDBConfig {
+	return InfluxDBConfig{
+		URL:             "http://localhost:8086",
+		APIVersion:      "v2",
+		Token:           "",
+		Org:             "",
+		Bucket:          "",
+		DB:              "",
+		RetentionPolicy: "",
+		Username:        "",
+		Password:        "",
+		Measurement:     "benthos",
+		Tags:            map[string]string{},
+		Fields:          map[string]string{},
+		Timestamp:       "",
+		Precision:       "ns",
+		Timeout:         "5s",
+		TLS:             tls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// InfluxDB is a benthos writer.Type implementation that writes messages as
+// points to InfluxDB using the line protocol.
+type InfluxDB struct {
+	conf InfluxDBConfig
+
+	writeURL    string
+	measurement *text.InterpolatedString
+	tags        map[string]*text.InterpolatedString
+	tagKeys     []string
+	fieldKeys   []string
+	precision   time.Duration
+
+	client http.Client
+
+	log   log.Modular
+	stats metrics.Type
+
+	mPoints metrics.StatCounter
+}
+
+// NewInfluxDB creates a new InfluxDB writer.Type.
+func NewInfluxDB(
+	conf InfluxDBConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*InfluxDB, error) {
+	i := &InfluxDB{
+		conf:        conf,
+		log:         log,
+		stats:       stats,
+		measurement: text.NewInterpolatedString(conf.Measurement),
+		tags:        map[string]*text.InterpolatedString{},
+		mPoints:     stats.GetCounter("points.sent"),
+	}
+
+	var v1Precision string
+	switch conf.Precision {
+	case "ns":
+		i.precision, v1Precision = time.Nanosecond, "n"
+	case "us":
+		i.precision, v1Precision = time.Microsecond, "u"
+	case "ms":
+		i.precision, v1Precision = time.Millisecond, "ms"
+	case "s":
+		i.precision, v1Precision = time.Second, "s"
+	default:
+		return nil, fmt.Errorf("precision not recognised: %v", conf.Precision)
+	}
+
+	query := url.Values{}
+	baseURL := strings.TrimSuffix(conf.URL, "/")
+	switch conf.APIVersion {
+	case "v1":
+		if len(conf.DB) == 0 {
+			return nil, errors.New("a db must be specified")
+		}
+		query.Set("db", conf.DB)
+		if len(conf.RetentionPolicy) > 0 {
+			query.Set("rp", conf.RetentionPolicy)
+		}
+		query.Set("precision", v1Precision)
+		i.writeURL = baseURL + "/write?" + query.Encode()
+	case "v2":
+		if len(conf.Bucket) == 0 {
+			return nil, errors.New("a bucket must be specified")
+		}
+		query.Set("org", conf.Org)
+		query.Set("bucket", conf.Bucket)
+		query.Set("precision", conf.Precision)
+		i.writeURL = baseURL + "/api/v2/write?" + query.Encode()
+	default:
+		return nil, fmt.Errorf("api version not recognised: %v", conf.APIVersion)
+	}
+
+	for k, v := range conf.Tags {
+		i.tags[k] = text.NewInterpolatedString(v)
+		i.tagKeys = append(i.tagKeys, k)
+	}
+	sort.Strings(i.tagKeys)
+	for k := range conf.Fields {
+		i.fieldKeys = append(i.fieldKeys, k)
+	}
+	sort.Strings(i.fieldKeys)
+
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if i.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		i.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return i, nil
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	stringFieldEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+func writeInfluxFieldValue(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case float64:
+		buf.WriteString(strconv.FormatFloat(t, 'f', -1, 64))
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case string:
+		buf.WriteByte('"')
+		buf.WriteString(stringFieldEscaper.Replace(t))
+		buf.WriteByte('"')
+	default:
+		return fmt.Errorf("unsupported field value type: %T", v)
+	}
+	return nil
+}
+
+// parseTimestamp converts a timestamp extracted from a document into a time,
+// where numbers are treated as unix timestamps in the configured precision and
+// strings are parsed as RFC 3339.
+func (i *InfluxDB) parseTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case float64:
+		return time.Unix(0, int64(t)*int64(i.precision)), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp type: %T", v)
+}
+
+// writePoint appends a message part as a line protocol point to a buffer.
+func (i *InfluxDB) writePoint(buf *bytes.Buffer, msg types.Message, index int, now time.Time) error {
+	lMsg := message.Lock(msg, index)
+
+	jObj, err := msg.Get(index).JSON()
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString(measurementEscaper.Replace(i.measurement.Get(lMsg)))
+	for _, k := range i.tagKeys {
+		v := i.tags[k].Get(lMsg)
+		if len(v) == 0 {
+			continue
+		}
+		buf.WriteByte(',')
+		buf.WriteString(tagEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(tagEscaper.Replace(v))
+	}
+
+	fields := map[string]interface{}{}
+	fieldKeys := i.fieldKeys
+	if len(fieldKeys) == 0 {
+		obj, ok := jObj.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected JSON object, found %T", jObj)
+		}
+		for k, v := range obj {
+			switch v.(type) {
+			case float64, bool, string:
+				fields[k] = v
+				fieldKeys = append(fieldKeys, k)
+			}
+		}
+		sort.Strings(fieldKeys)
+	} else {
+		for _, k := range fieldKeys {
+			if v := gObj.Path(i.conf.Fields[k]).Data(); v != nil {
+				fields[k] = v
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return errors.New("point has no fields")
+	}
+
+	sep := byte(' ')
+	for _, k := range fieldKeys {
+		v, exists := fields[k]
+		if !exists {
+			continue
+		}
+		buf.WriteByte(sep)
+		sep = ','
+		buf.WriteString(tagEscaper.Replace(k))
+		buf.WriteByte('=')
+		if err = writeInfluxFieldValue(buf, v); err != nil {
+			return fmt.Errorf("field %v: %v", k, err)
+		}
+	}
+
+	ts := now
+	if len(i.conf.Timestamp) > 0 {
+		if v := gObj.Path(i.conf.Timestamp).Data(); v != nil {
+			if ts, err = i.parseTimestamp(v); err != nil {
+				return fmt.Errorf("failed to parse timestamp: %v", err)
+			}
+		}
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(ts.UnixNano()/int64(i.precision), 10))
+	buf.WriteByte('\n')
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect does nothing as each write is a standalone HTTP request.
+func (i *InfluxDB) Connect() error {
+	i.log.Infof("Writing points to InfluxDB at: %v\n", i.conf.URL)
+	return nil
+}
+
+// Write attempts to write a message batch as points to InfluxDB.
+func (i *InfluxDB) Write(msg types.Message) error {
+	var buf bytes.Buffer
+	now := time.Now()
+	for j := 0; j < msg.Len(); j++ {
+		if err := i.writePoint(&buf, msg, j, now); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("POST", i.writeURL, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if len(i.conf.Token) > 0 {
+		req.Header.Set("Authorization", "Token "+i.conf.Token)
+	} else if len(i.conf.Username) > 0 {
+		req.SetBasicAuth(i.conf.Username, i.conf.Password)
+	}
+
+	res, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("write request returned status %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	i.mPoints.Incr(int64(msg.Len()))
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (i *InfluxDB) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (i *InfluxDB) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,211 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestInfluxDBWritePoint(t *testing.T) {
+	type testCase struct {
+		name   string
+		tags   map[string]string
+		fields map[string]string
+		ts     string
+		input  string
+		output string
+	}
+
+	tests := []testCase{
+		{
+			name:   "all top level fields",
+			input:  `{"a":1.5,"b":"foo \"bar\"","c":true,"d":{"e":1}}`,
+			output: `cpu a=1.5,b="foo \"bar\"",c=true 1000000000`,
+		},
+		{
+			name: "mapped tags and fields",
+			tags: map[string]string{
+				"host":   "${!json_field:host}",
+				"region": "eu west",
+				"empty":  "",
+			},
+			fields: map[string]string{
+				"usage": "stats.usage",
+				"idle":  "stats.idle",
+				"nope":  "stats.nope",
+			},
+			input:  `{"host":"a,b","stats":{"usage":10,"idle":90}}`,
+			output: `cpu,host=a\,b,region=eu\ west idle=90,usage=10 1000000000`,
+		},
+		{
+			name:   "numeric timestamp",
+			fields: map[string]string{"v": "v"},
+			ts:     "ts",
+			input:  `{"v":1,"ts":1562000000000000000}`,
+			output: `cpu v=1 1562000000000000000`,
+		},
+		{
+			name:   "string timestamp",
+			fields: map[string]string{"v": "v"},
+			ts:     "ts",
+			input:  `{"v":1,"ts":"2019-07-01T16:53:20Z"}`,
+			output: `cpu v=1 1562000000000000000`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewInfluxDBConfig()
+		conf.Bucket = "foo"
+		conf.Measurement = "cpu"
+		if test.tags != nil {
+			conf.Tags = test.tags
+		}
+		if test.fields != nil {
+			conf.Fields = test.fields
+		}
+		conf.Timestamp = test.ts
+
+		i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		msg := message.New([][]byte{[]byte(test.input)})
+		if err = i.writePoint(&buf, msg, 0, time.Unix(1, 0)); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if exp, act := test.output+"\n", buf.String(); exp != act {
+			t.Errorf("%v: Wrong result: %s != %s", test.name, act, exp)
+		}
+	}
+}
+
+func TestInfluxDBWritePointErrors(t *testing.T) {
+	conf := NewInfluxDBConfig()
+	conf.Bucket = "foo"
+
+	i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{`not json`, `[1,2]`, `{"a":{"b":1}}`} {
+		var buf bytes.Buffer
+		if err = i.writePoint(&buf, message.New([][]byte{[]byte(input)}), 0, time.Now()); err == nil {
+			t.Errorf("Expected error from input: %v", input)
+		}
+	}
+}
+
+func TestInfluxDBWriteV2(t *testing.T) {
+	var reqURL, reqAuth, reqBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqURL = r.URL.String()
+		reqAuth = r.Header.Get("Authorization")
+		b, _ := ioutil.ReadAll(r.Body)
+		reqBody = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf := NewInfluxDBConfig()
+	conf.URL = ts.URL
+	conf.Org = "benthos"
+	conf.Bucket = "metrics"
+	conf.Token = "footoken"
+	conf.Precision = "s"
+	conf.Fields = map[string]string{"v": "v"}
+	conf.Timestamp = "ts"
+
+	i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = i.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = i.Write(message.New([][]byte{
+		[]byte(`{"v":1,"ts":10}`),
+		[]byte(`{"v":2,"ts":20}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "/api/v2/write?bucket=metrics&org=benthos&precision=s", reqURL; exp != act {
+		t.Errorf("Wrong URL: %v != %v", act, exp)
+	}
+	if exp, act := "Token footoken", reqAuth; exp != act {
+		t.Errorf("Wrong auth: %v != %v", act, exp)
+	}
+	if exp, act := "benthos v=1 10\nbenthos v=2 20\n", reqBody; exp != act {
+		t.Errorf("Wrong body: %q != %q", act, exp)
+	}
+}
+
+func TestInfluxDBWriteV1(t *testing.T) {
+	var reqURL, reqUser, reqPass string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqURL = r.URL.String()
+		reqUser, reqPass, _ = r.BasicAuth()
+		http.Error(w, `{"error":"nope"}`, http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	conf := NewInfluxDBConfig()
+	conf.URL = ts.URL
+	conf.APIVersion = "v1"
+	conf.DB = "metrics"
+	conf.RetentionPolicy = "autogen"
+	conf.Username = "foo"
+	conf.Password = "bar"
+	conf.Precision = "us"
+
+	i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = i.Write(message.New([][]byte{[]byte(`{"v":1}`)})); err == nil {
+		t.Error("Expected error from bad status")
+	}
+	if exp, act := "/write?db=metrics&precision=u&rp=autogen", reqURL; exp != act {
+		t.Errorf("Wrong URL: %v != %v", act, exp)
+	}
+	if reqUser != "foo" || reqPass != "bar" {
+		t.Errorf("Wrong basic auth: %v:%v", reqUser, reqPass)
+	}
+}
+
+//------------------------------------------------------------------------------
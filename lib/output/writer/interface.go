@@ -40,3 +40,20 @@ type Type interface {
 
 	types.Closable
 }
+
+// Transactor is an optional interface implemented by writers that are able to
+// stage a message and later commit or abort it, allowing them to participate
+// in a two-phase commit across multiple outputs.
+type Transactor interface {
+	// WritePrepared should block until the message is staged within the sink
+	// but not yet visible, returning a handle used to commit or abort it.
+	WritePrepared(msg types.Message) (types.PreparedWrite, error)
+}
+
+// ConcurrentWriter is an optional interface implemented by writers that are
+// safe to call Write on from multiple goroutines at the same time, allowing
+// them to be configured with a max_in_flight greater than one.
+type ConcurrentWriter interface {
+	// ConcurrentWrites is a marker method and does nothing.
+	ConcurrentWrites()
+}
@@ -23,12 +23,15 @@ package writer
 import (
 	"crypto/tls"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
@@ -40,18 +43,23 @@ import (
 
 // KafkaConfig contains configuration fields for the Kafka output type.
 type KafkaConfig struct {
-	Addresses            []string    `json:"addresses" yaml:"addresses"`
-	ClientID             string      `json:"client_id" yaml:"client_id"`
-	Key                  string      `json:"key" yaml:"key"`
-	RoundRobinPartitions bool        `json:"round_robin_partitions" yaml:"round_robin_partitions"`
-	Topic                string      `json:"topic" yaml:"topic"`
-	Compression          string      `json:"compression" yaml:"compression"`
-	MaxMsgBytes          int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	Timeout              string      `json:"timeout" yaml:"timeout"`
-	AckReplicas          bool        `json:"ack_replicas" yaml:"ack_replicas"`
-	TargetVersion        string      `json:"target_version" yaml:"target_version"`
-	TLS                  btls.Config `json:"tls" yaml:"tls"`
-	SASL                 SASLConfig  `json:"sasl" yaml:"sasl"`
+	Addresses            []string            `json:"addresses" yaml:"addresses"`
+	ClientID             string              `json:"client_id" yaml:"client_id"`
+	Key                  string              `json:"key" yaml:"key"`
+	RoundRobinPartitions bool                `json:"round_robin_partitions" yaml:"round_robin_partitions"`
+	Topic                string              `json:"topic" yaml:"topic"`
+	Compression          string              `json:"compression" yaml:"compression"`
+	MaxMsgBytes          int                 `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	MaxBatchBytes        int                 `json:"max_batch_bytes" yaml:"max_batch_bytes"`
+	Timeout              string              `json:"timeout" yaml:"timeout"`
+	AckReplicas          bool                `json:"ack_replicas" yaml:"ack_replicas"`
+	IdempotentWrite      bool                `json:"idempotent_write" yaml:"idempotent_write"`
+	Metadata             KafkaMetadataConfig `json:"metadata" yaml:"metadata"`
+	Timestamp            string              `json:"timestamp" yaml:"timestamp"`
+	PropagateResponse    bool                `json:"propagate_response" yaml:"propagate_response"`
+	TargetVersion        string              `json:"target_version" yaml:"target_version"`
+	TLS                  btls.Config         `json:"tls" yaml:"tls"`
+	SASL                 SASLConfig          `json:"sasl" yaml:"sasl"`
 }
 
 // SASLConfig contains configuration for SASL based authentication.
@@ -61,6 +69,13 @@ type SASLConfig struct {
 	Password string `json:"password" yaml:"password"`
 }
 
+// KafkaMetadataConfig determines which metadata fields of a message are written
+// as record headers.
+type KafkaMetadataConfig struct {
+	IncludePatterns []string `json:"include_patterns" yaml:"include_patterns"`
+	ExcludePatterns []string `json:"exclude_patterns" yaml:"exclude_patterns"`
+}
+
 // NewKafkaConfig creates a new KafkaConfig with default values.
 func NewKafkaConfig() KafkaConfig {
 	return KafkaConfig{
@@ -71,10 +86,18 @@ func NewKafkaConfig() KafkaConfig {
 		Topic:                "benthos_stream",
 		Compression:          "none",
 		MaxMsgBytes:          1000000,
+		MaxBatchBytes:        0,
 		Timeout:              "5s",
 		AckReplicas:          false,
-		TargetVersion:        sarama.V1_0_0_0.String(),
-		TLS:                  btls.NewConfig(),
+		IdempotentWrite:      false,
+		Metadata: KafkaMetadataConfig{
+			IncludePatterns: []string{},
+			ExcludePatterns: []string{},
+		},
+		Timestamp:         "",
+		PropagateResponse: false,
+		TargetVersion:     sarama.V1_0_0_0.String(),
+		TLS:               btls.NewConfig(),
 	}
 }
 
@@ -93,9 +116,14 @@ type Kafka struct {
 	conf      KafkaConfig
 
 	mDroppedMaxBytes metrics.StatCounter
+	mBatchSplit      metrics.StatCounter
 
-	key   *text.InterpolatedBytes
-	topic *text.InterpolatedString
+	key       *text.InterpolatedBytes
+	topic     *text.InterpolatedString
+	timestamp *text.InterpolatedString
+
+	metaInclude []*regexp.Regexp
+	metaExclude []*regexp.Regexp
 
 	producer    sarama.SyncProducer
 	compression sarama.CompressionCodec
@@ -118,6 +146,18 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 		key:         text.NewInterpolatedBytes([]byte(conf.Key)),
 		topic:       text.NewInterpolatedString(conf.Topic),
 		compression: compression,
+
+		mBatchSplit: stats.GetCounter("batch.split"),
+	}
+
+	if len(conf.Timestamp) > 0 {
+		k.timestamp = text.NewInterpolatedString(conf.Timestamp)
+	}
+	if k.metaInclude, err = compilePatterns(conf.Metadata.IncludePatterns); err != nil {
+		return nil, fmt.Errorf("failed to compile metadata include pattern: %v", err)
+	}
+	if k.metaExclude, err = compilePatterns(conf.Metadata.ExcludePatterns); err != nil {
+		return nil, fmt.Errorf("failed to compile metadata exclude pattern: %v", err)
 	}
 
 	if tout := conf.Timeout; len(tout) > 0 {
@@ -137,6 +177,9 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if conf.IdempotentWrite && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf("idempotent writes require a target_version of at least %v", sarama.V0_11_0_0)
+	}
 
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
@@ -167,12 +210,49 @@ func strToCompressionCodec(str string) (sarama.CompressionCodec, error) {
 
 //------------------------------------------------------------------------------
 
-func buildHeaders(part types.Part) []sarama.RecordHeader {
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchesAny(patterns []*regexp.Regexp, str string) bool {
+	for _, re := range patterns {
+		if re.MatchString(str) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKafkaTimestamp parses either an RFC 3339 formatted timestamp or a unix
+// timestamp in seconds, which may contain a fractional component.
+func parseKafkaTimestamp(str string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(str, 64); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, str)
+}
+
+func (k *Kafka) buildHeaders(part types.Part) []sarama.RecordHeader {
 	out := []sarama.RecordHeader{}
 	meta := part.Metadata()
-	meta.Iter(func(k, v string) error {
+	meta.Iter(func(key, v string) error {
+		if len(k.metaInclude) > 0 && !matchesAny(k.metaInclude, key) {
+			return nil
+		}
+		if matchesAny(k.metaExclude, key) {
+			return nil
+		}
 		out = append(out, sarama.RecordHeader{
-			Key:   []byte(k),
+			Key:   []byte(key),
 			Value: []byte(v),
 		})
 		return nil
@@ -183,15 +263,8 @@ func buildHeaders(part types.Part) []sarama.RecordHeader {
 
 //------------------------------------------------------------------------------
 
-// Connect attempts to establish a connection to a Kafka broker.
-func (k *Kafka) Connect() error {
-	k.connMut.Lock()
-	defer k.connMut.Unlock()
-
-	if k.producer != nil {
-		return nil
-	}
-
+// saramaConfig creates the sarama config used by the producer.
+func (k *Kafka) saramaConfig() *sarama.Config {
 	config := sarama.NewConfig()
 	config.ClientID = k.conf.ClientID
 
@@ -222,8 +295,28 @@ func (k *Kafka) Connect() error {
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	if k.conf.IdempotentWrite {
+		// The idempotent producer requires acknowledgements from all in-sync
+		// replicas and only a single in flight request per broker in order to
+		// guarantee ordering.
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+	}
+	return config
+}
+
+// Connect attempts to establish a connection to a Kafka broker.
+func (k *Kafka) Connect() error {
+	k.connMut.Lock()
+	defer k.connMut.Unlock()
+
+	if k.producer != nil {
+		return nil
+	}
+
 	var err error
-	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
+	k.producer, err = sarama.NewSyncProducer(k.addresses, k.saramaConfig())
 
 	if err == nil {
 		k.log.Infof("Sending Kafka messages to addresses: %s\n", k.addresses)
@@ -231,6 +324,10 @@ func (k *Kafka) Connect() error {
 	return err
 }
 
+// ConcurrentWrites marks Kafka as safe for concurrent writes, the sarama sync
+// producer supports sending from multiple goroutines.
+func (k *Kafka) ConcurrentWrites() {}
+
 // Write will attempt to write a message to Kafka, wait for acknowledgement, and
 // returns an error if applicable.
 func (k *Kafka) Write(msg types.Message) error {
@@ -242,31 +339,60 @@ func (k *Kafka) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
-	msgs := []*sarama.ProducerMessage{}
-	msg.Iter(func(i int, p types.Part) error {
-		lMsg := message.Lock(msg, i)
+	batches, err := splitBySize(msg, k.conf.MaxBatchBytes)
+	if err != nil {
+		return err
+	}
+	if len(batches) > 1 {
+		k.mBatchSplit.Incr(1)
+	}
+
+	for _, batch := range batches {
+		msgs := []*sarama.ProducerMessage{}
+		batch.Iter(func(i int, p types.Part) error {
+			lMsg := message.Lock(batch, i)
 
-		key := k.key.Get(lMsg)
-		nextMsg := &sarama.ProducerMessage{
-			Topic:   k.topic.Get(lMsg),
-			Value:   sarama.ByteEncoder(p.Get()),
-			Headers: buildHeaders(p),
-		}
-		if len(key) > 0 {
-			nextMsg.Key = sarama.ByteEncoder(key)
+			key := k.key.Get(lMsg)
+			nextMsg := &sarama.ProducerMessage{
+				Topic:   k.topic.Get(lMsg),
+				Value:   sarama.ByteEncoder(p.Get()),
+				Headers: k.buildHeaders(p),
+			}
+			if len(key) > 0 {
+				nextMsg.Key = sarama.ByteEncoder(key)
+			}
+			if k.timestamp != nil {
+				tsStr := k.timestamp.Get(lMsg)
+				if ts, terr := parseKafkaTimestamp(tsStr); terr != nil {
+					k.log.Errorf("Failed to parse timestamp '%v': %v\n", tsStr, terr)
+				} else {
+					nextMsg.Timestamp = ts
+				}
+			}
+			msgs = append(msgs, nextMsg)
+			return nil
+		})
+
+		if err = producer.SendMessages(msgs); err != nil {
+			if pErr, ok := err.(sarama.ProducerErrors); ok && len(pErr) > 0 {
+				err = fmt.Errorf("failed to send %v parts from message: %v", len(pErr), pErr[0].Err)
+			}
+			return err
 		}
-		msgs = append(msgs, nextMsg)
-		return nil
-	})
 
-	err := producer.SendMessages(msgs)
-	if err != nil {
-		if pErr, ok := err.(sarama.ProducerErrors); ok && len(pErr) > 0 {
-			err = fmt.Errorf("failed to send %v parts from message: %v", len(pErr), pErr[0].Err)
+		if k.conf.PropagateResponse {
+			confs := make([]roundtrip.Confirmation, len(msgs))
+			for i, m := range msgs {
+				confs[i] = roundtrip.Confirmation{
+					"kafka_topic":     m.Topic,
+					"kafka_partition": strconv.Itoa(int(m.Partition)),
+					"kafka_offset":    strconv.FormatInt(m.Offset, 10),
+				}
+			}
+			roundtrip.SetConfirmationsAsResponse(batch, confs)
 		}
 	}
-
-	return err
+	return nil
 }
 
 // CloseAsync shuts down the Kafka writer and stops processing messages.
@@ -0,0 +1,133 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Shopify/sarama"
+)
+
+func TestKafkaHeaders(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Metadata.IncludePatterns = []string{"^foo_"}
+	conf.Metadata.ExcludePatterns = []string{"_secret$"}
+
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := message.NewPart([]byte("hello world"))
+	part.Metadata().
+		Set("foo_a", "a").
+		Set("foo_secret", "b").
+		Set("bar_c", "c")
+
+	headers := k.buildHeaders(part)
+	act := map[string]string{}
+	for _, h := range headers {
+		act[string(h.Key)] = string(h.Value)
+	}
+	if exp := map[string]string{"foo_a": "a"}; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong headers: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaBadMetadataPattern(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Metadata.ExcludePatterns = []string{"("}
+
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad pattern")
+	}
+}
+
+func TestKafkaIdempotentWrite(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.AckReplicas = false
+	conf.IdempotentWrite = true
+	conf.TargetVersion = "0.10.2.0"
+
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from idempotent write with old target version")
+	}
+
+	conf.TargetVersion = "1.0.0"
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := k.saramaConfig()
+	if !config.Producer.Idempotent {
+		t.Error("Expected idempotent producer")
+	}
+	if exp, act := sarama.WaitForAll, config.Producer.RequiredAcks; exp != act {
+		t.Errorf("Wrong required acks: %v != %v", act, exp)
+	}
+	if exp, act := 1, config.Net.MaxOpenRequests; exp != act {
+		t.Errorf("Wrong max open requests: %v != %v", act, exp)
+	}
+	if err = config.Validate(); err != nil {
+		t.Errorf("Invalid producer config: %v", err)
+	}
+
+	conf.IdempotentWrite = false
+	if k, err = NewKafka(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	config = k.saramaConfig()
+	if config.Producer.Idempotent {
+		t.Error("Did not expect idempotent producer")
+	}
+	if exp, act := sarama.WaitForLocal, config.Producer.RequiredAcks; exp != act {
+		t.Errorf("Wrong required acks: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaTimestampParse(t *testing.T) {
+	tests := map[string]time.Time{
+		"1257894000":           time.Unix(1257894000, 0),
+		"1257894000.5":         time.Unix(1257894000, 500000000),
+		"2009-11-10T23:00:00Z": time.Unix(1257894000, 0),
+	}
+
+	for input, exp := range tests {
+		act, err := parseKafkaTimestamp(input)
+		if err != nil {
+			t.Errorf("Failed to parse '%v': %v", input, err)
+			continue
+		}
+		if !act.Equal(exp) {
+			t.Errorf("Wrong timestamp for '%v': %v != %v", input, act, exp)
+		}
+	}
+
+	if _, err := parseKafkaTimestamp("not a timestamp"); err == nil {
+		t.Error("Expected error from bad timestamp")
+	}
+}
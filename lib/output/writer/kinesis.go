@@ -58,12 +58,29 @@ type sessionConfig struct {
 // KinesisConfig contains configuration fields for the Kinesis output type.
 type KinesisConfig struct {
 	sessionConfig  `json:",inline" yaml:",inline"`
-	Stream         string `json:"stream" yaml:"stream"`
-	HashKey        string `json:"hash_key" yaml:"hash_key"`
-	PartitionKey   string `json:"partition_key" yaml:"partition_key"`
+	Stream         string                   `json:"stream" yaml:"stream"`
+	HashKey        string                   `json:"hash_key" yaml:"hash_key"`
+	PartitionKey   string                   `json:"partition_key" yaml:"partition_key"`
+	Aggregation    KinesisAggregationConfig `json:"aggregation" yaml:"aggregation"`
+	Failover       KinesisFailoverConfig    `json:"failover" yaml:"failover"`
 	retries.Config `json:",inline" yaml:",inline"`
 }
 
+// KinesisFailoverConfig contains configuration fields for failing over to
+// secondary Kinesis streams.
+type KinesisFailoverConfig struct {
+	FailoverConfig `json:",inline" yaml:",inline"`
+	Endpoints      []KinesisEndpointConfig `json:"endpoints" yaml:"endpoints"`
+}
+
+// KinesisEndpointConfig describes a secondary Kinesis stream, where empty
+// fields are inherited from the primary.
+type KinesisEndpointConfig struct {
+	Region   string `json:"region" yaml:"region"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	Stream   string `json:"stream" yaml:"stream"`
+}
+
 // NewKinesisConfig creates a new Config with default values.
 func NewKinesisConfig() KinesisConfig {
 	rConf := retries.NewConfig()
@@ -77,7 +94,12 @@ func NewKinesisConfig() KinesisConfig {
 		Stream:       "",
 		HashKey:      "",
 		PartitionKey: "",
-		Config:       rConf,
+		Aggregation:  NewKinesisAggregationConfig(),
+		Failover: KinesisFailoverConfig{
+			FailoverConfig: NewFailoverConfig(),
+			Endpoints:      []KinesisEndpointConfig{},
+		},
+		Config: rConf,
 	}
 }
 
@@ -170,6 +192,42 @@ func (a *Kinesis) toRecords(msg types.Message) ([]*kinesis.PutRecordsRequestEntr
 
 //------------------------------------------------------------------------------
 
+// NewKinesisFailover creates a writer.Type that writes to the primary Kinesis
+// stream of a config and fails over to any secondary streams configured.
+func NewKinesisFailover(
+	conf KinesisConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Failover.Endpoints) == 0 {
+		return NewKinesis(conf, log, stats)
+	}
+	confs := []KinesisConfig{conf}
+	for _, e := range conf.Failover.Endpoints {
+		eConf := conf
+		if len(e.Region) > 0 {
+			eConf.Region = e.Region
+		}
+		if len(e.Endpoint) > 0 {
+			eConf.Endpoint = e.Endpoint
+		}
+		if len(e.Stream) > 0 {
+			eConf.Stream = e.Stream
+		}
+		confs = append(confs, eConf)
+	}
+	names := make([]string, len(confs))
+	writers := make([]Type, len(confs))
+	for i, c := range confs {
+		w, err := NewKinesis(c, log, stats)
+		if err != nil {
+			return nil, err
+		}
+		names[i], writers[i] = c.Region+"/"+c.Stream, w
+	}
+	return NewFailover(conf.Failover.FailoverConfig, names, writers, log, stats)
+}
+
 // Connect creates a new Kinesis client and ensures that the target Kinesis
 // stream exists.
 func (a *Kinesis) Connect() error {
@@ -207,6 +265,9 @@ func (a *Kinesis) Write(msg types.Message) error {
 	if err != nil {
 		return err
 	}
+	if a.conf.Aggregation.Enabled {
+		records = aggregateRecords(a.conf.Aggregation, records)
+	}
 
 	input := &kinesis.PutRecordsInput{
 		Records:    records,
@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+//------------------------------------------------------------------------------
+
+// kplMagic is the prefix of records aggregated with the Kinesis Producer
+// Library format, which consumers such as the KCL use in order to detect that
+// a record must be de-aggregated.
+var kplMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+// kplOverhead is the number of bytes an aggregated record adds on top of its
+// protobuf body.
+const kplOverhead = 4 + md5.Size
+
+// KinesisAggregationConfig contains configuration fields for aggregating
+// multiple messages into a single Kinesis record.
+type KinesisAggregationConfig struct {
+	Enabled    bool `json:"enabled" yaml:"enabled"`
+	MaxRecords int  `json:"max_records" yaml:"max_records"`
+	MaxSize    int  `json:"max_size" yaml:"max_size"`
+}
+
+// NewKinesisAggregationConfig creates a KinesisAggregationConfig with default
+// values.
+func NewKinesisAggregationConfig() KinesisAggregationConfig {
+	return KinesisAggregationConfig{
+		Enabled:    false,
+		MaxRecords: 0,
+		MaxSize:    51200,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func protoVarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+func protoAppendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func protoAppendBytes(b []byte, field uint64, v []byte) []byte {
+	b = protoAppendVarint(b, field<<3|2)
+	b = protoAppendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// kplRecordLen returns the encoded size of a data blob as a record within an
+// aggregated record, including its field header.
+func kplRecordLen(data []byte, withHashKey bool) int {
+	l := 2 + 1 + protoVarintLen(uint64(len(data))) + len(data)
+	if withHashKey {
+		l += 2
+	}
+	return 1 + protoVarintLen(uint64(l)) + l
+}
+
+// kplAggregator collects the data of entries that share a partition and hash
+// key into a single KPL aggregated record.
+type kplAggregator struct {
+	partitionKey string
+	hashKey      string
+	data         [][]byte
+	size         int
+}
+
+func newKPLAggregator(partitionKey, hashKey string) *kplAggregator {
+	size := kplOverhead + 1 + protoVarintLen(uint64(len(partitionKey))) + len(partitionKey)
+	if len(hashKey) > 0 {
+		size += 1 + protoVarintLen(uint64(len(hashKey))) + len(hashKey)
+	}
+	return &kplAggregator{
+		partitionKey: partitionKey,
+		hashKey:      hashKey,
+		size:         size,
+	}
+}
+
+// sizeWith returns the size of the aggregated record if data were added.
+func (k *kplAggregator) sizeWith(data []byte) int {
+	return k.size + kplRecordLen(data, len(k.hashKey) > 0)
+}
+
+func (k *kplAggregator) add(data []byte) {
+	k.size = k.sizeWith(data)
+	k.data = append(k.data, data)
+}
+
+// entry returns a Kinesis entry for the aggregated data. When only a single
+// record was added it is returned as-is, as aggregating would only add
+// overhead.
+func (k *kplAggregator) entry() *kinesis.PutRecordsRequestEntry {
+	entry := &kinesis.PutRecordsRequestEntry{
+		PartitionKey: aws.String(k.partitionKey),
+	}
+	if len(k.hashKey) > 0 {
+		entry.ExplicitHashKey = aws.String(k.hashKey)
+	}
+	if len(k.data) == 1 {
+		entry.Data = k.data[0]
+		return entry
+	}
+
+	body := make([]byte, 0, k.size-kplOverhead)
+	body = protoAppendBytes(body, 1, []byte(k.partitionKey))
+	if len(k.hashKey) > 0 {
+		body = protoAppendBytes(body, 2, []byte(k.hashKey))
+	}
+
+	var record []byte
+	for _, d := range k.data {
+		// Every record refers to the first (and only) entry of the key
+		// tables.
+		record = append(record[:0], 1<<3, 0)
+		if len(k.hashKey) > 0 {
+			record = append(record, 2<<3, 0)
+		}
+		record = protoAppendBytes(record, 3, d)
+		body = protoAppendBytes(body, 3, record)
+	}
+
+	checksum := md5.Sum(body)
+
+	data := make([]byte, 0, len(kplMagic)+len(body)+len(checksum))
+	data = append(data, kplMagic...)
+	data = append(data, body...)
+	entry.Data = append(data, checksum[:]...)
+	return entry
+}
+
+// aggregateRecords packs entries into KPL aggregated records, where entries
+// are grouped by their partition and hash keys. The order of entries that
+// share keys is preserved.
+func aggregateRecords(conf KinesisAggregationConfig, entries []*kinesis.PutRecordsRequestEntry) []*kinesis.PutRecordsRequestEntry {
+	maxSize := conf.MaxSize
+	if maxSize <= 0 || maxSize > mebibyte {
+		maxSize = mebibyte
+	}
+
+	type aggKey struct {
+		partition string
+		hash      string
+	}
+
+	var aggregated []*kinesis.PutRecordsRequestEntry
+	var order []aggKey
+	pending := map[aggKey]*kplAggregator{}
+
+	for _, e := range entries {
+		key := aggKey{partition: aws.StringValue(e.PartitionKey)}
+		if e.ExplicitHashKey != nil {
+			key.hash = *e.ExplicitHashKey
+		}
+
+		agg, exists := pending[key]
+		if exists {
+			full := conf.MaxRecords > 0 && len(agg.data) >= conf.MaxRecords
+			if full || agg.sizeWith(e.Data) > maxSize {
+				aggregated = append(aggregated, agg.entry())
+				exists = false
+			}
+		}
+		if !exists {
+			agg = newKPLAggregator(key.partition, key.hash)
+			pending[key] = agg
+			order = append(order, key)
+		}
+		agg.add(e.Data)
+	}
+
+	for _, key := range order {
+		if agg, exists := pending[key]; exists {
+			aggregated = append(aggregated, agg.entry())
+			delete(pending, key)
+		}
+	}
+	return aggregated
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,214 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+type kplTestRecord struct {
+	partitionKey string
+	hashKey      string
+	data         string
+}
+
+func kplReadField(b []byte) (field, wire uint64, value []byte, rest []byte, err error) {
+	tag, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, 0, nil, nil, errors.New("bad tag")
+	}
+	b = b[n:]
+	field, wire = tag>>3, tag&7
+	switch wire {
+	case 0:
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, 0, nil, nil, errors.New("bad varint")
+		}
+		value = make([]byte, binary.MaxVarintLen64)
+		value = value[:binary.PutUvarint(value, v)]
+		rest = b[n:]
+	case 2:
+		l, n := binary.Uvarint(b)
+		if n <= 0 || len(b[n:]) < int(l) {
+			return 0, 0, nil, nil, errors.New("bad length")
+		}
+		value, rest = b[n:n+int(l)], b[n+int(l):]
+	default:
+		return 0, 0, nil, nil, fmt.Errorf("unexpected wire type %v", wire)
+	}
+	return
+}
+
+// kplDeaggregate decodes an entry using the KPL aggregation format, returning
+// the entry itself when it is not aggregated.
+func kplDeaggregate(e *kinesis.PutRecordsRequestEntry) ([]kplTestRecord, error) {
+	if !bytes.HasPrefix(e.Data, kplMagic) {
+		return []kplTestRecord{{
+			partitionKey: aws.StringValue(e.PartitionKey),
+			hashKey:      aws.StringValue(e.ExplicitHashKey),
+			data:         string(e.Data),
+		}}, nil
+	}
+	body := e.Data[len(kplMagic) : len(e.Data)-md5.Size]
+	if sum := md5.Sum(body); !bytes.Equal(sum[:], e.Data[len(e.Data)-md5.Size:]) {
+		return nil, errors.New("checksum mismatch")
+	}
+
+	var pKeys, hKeys []string
+	var records []kplTestRecord
+	for len(body) > 0 {
+		field, _, value, rest, err := kplReadField(body)
+		if err != nil {
+			return nil, err
+		}
+		body = rest
+		switch field {
+		case 1:
+			pKeys = append(pKeys, string(value))
+		case 2:
+			hKeys = append(hKeys, string(value))
+		case 3:
+			var rec kplTestRecord
+			for len(value) > 0 {
+				rField, _, rValue, rRest, err := kplReadField(value)
+				if err != nil {
+					return nil, err
+				}
+				value = rRest
+				switch rField {
+				case 1:
+					i, _ := binary.Uvarint(rValue)
+					rec.partitionKey = pKeys[i]
+				case 2:
+					i, _ := binary.Uvarint(rValue)
+					rec.hashKey = hKeys[i]
+				case 3:
+					rec.data = string(rValue)
+				}
+			}
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func TestKinesisAggregateRecords(t *testing.T) {
+	entries := []*kinesis.PutRecordsRequestEntry{
+		{PartitionKey: aws.String("a"), Data: []byte("foo")},
+		{PartitionKey: aws.String("b"), Data: []byte("bar")},
+		{PartitionKey: aws.String("a"), Data: []byte("baz")},
+		{PartitionKey: aws.String("a"), ExplicitHashKey: aws.String("123"), Data: []byte("qux")},
+		{PartitionKey: aws.String("a"), ExplicitHashKey: aws.String("123"), Data: []byte("quz")},
+	}
+
+	conf := NewKinesisAggregationConfig()
+	conf.Enabled = true
+
+	aggregated := aggregateRecords(conf, entries)
+	if exp, act := 3, len(aggregated); exp != act {
+		t.Fatalf("Wrong count of aggregated records: %v != %v", act, exp)
+	}
+
+	exp := [][]kplTestRecord{
+		{{"a", "", "foo"}, {"a", "", "baz"}},
+		{{"b", "", "bar"}},
+		{{"a", "123", "qux"}, {"a", "123", "quz"}},
+	}
+	for i, e := range aggregated {
+		act, err := kplDeaggregate(e)
+		if err != nil {
+			t.Fatalf("Record %v: %v", i, err)
+		}
+		if !reflect.DeepEqual(exp[i], act) {
+			t.Errorf("Wrong records at index %v: %v != %v", i, act, exp[i])
+		}
+		if exp, act := exp[i][0].partitionKey, aws.StringValue(e.PartitionKey); exp != act {
+			t.Errorf("Wrong partition key at index %v: %v != %v", i, act, exp)
+		}
+		if exp, act := exp[i][0].hashKey, aws.StringValue(e.ExplicitHashKey); exp != act {
+			t.Errorf("Wrong hash key at index %v: %v != %v", i, act, exp)
+		}
+	}
+	if bytes.HasPrefix(aggregated[1].Data, kplMagic) {
+		t.Error("Expected lone record to be sent without aggregation")
+	}
+}
+
+func TestKinesisAggregateRecordsLimits(t *testing.T) {
+	var entries []*kinesis.PutRecordsRequestEntry
+	for i := 0; i < 10; i++ {
+		entries = append(entries, &kinesis.PutRecordsRequestEntry{
+			PartitionKey: aws.String("a"),
+			Data:         []byte(strings.Repeat("x", 100)),
+		})
+	}
+
+	conf := NewKinesisAggregationConfig()
+	conf.Enabled = true
+	conf.MaxRecords = 3
+
+	aggregated := aggregateRecords(conf, entries)
+	if exp, act := 4, len(aggregated); exp != act {
+		t.Fatalf("Wrong count of aggregated records: %v != %v", act, exp)
+	}
+	for i, exp := range []int{3, 3, 3, 1} {
+		records, err := kplDeaggregate(aggregated[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := len(records); exp != act {
+			t.Errorf("Wrong count of records at index %v: %v != %v", i, act, exp)
+		}
+	}
+
+	conf.MaxRecords = 0
+	conf.MaxSize = 450
+
+	aggregated = aggregateRecords(conf, entries)
+	total := 0
+	for i, e := range aggregated {
+		if len(e.Data) > conf.MaxSize {
+			t.Errorf("Record at index %v exceeds max size: %v", i, len(e.Data))
+		}
+		records, err := kplDeaggregate(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(records)
+	}
+	if exp, act := 10, total; exp != act {
+		t.Errorf("Wrong count of total records: %v != %v", act, exp)
+	}
+	if len(aggregated) < 3 {
+		t.Errorf("Expected records to be split by size, got %v", len(aggregated))
+	}
+}
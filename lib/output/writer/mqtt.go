@@ -21,13 +21,18 @@
 package writer
 
 import (
+	"crypto/tls"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -35,19 +40,27 @@ import (
 
 // MQTTConfig contains configuration fields for the MQTT output type.
 type MQTTConfig struct {
-	URLs     []string `json:"urls" yaml:"urls"`
-	QoS      uint8    `json:"qos" yaml:"qos"`
-	Topic    string   `json:"topic" yaml:"topic"`
-	ClientID string   `json:"client_id" yaml:"client_id"`
+	URLs           []string    `json:"urls" yaml:"urls"`
+	QoS            uint8       `json:"qos" yaml:"qos"`
+	Retained       bool        `json:"retained" yaml:"retained"`
+	Topic          string      `json:"topic" yaml:"topic"`
+	ClientID       string      `json:"client_id" yaml:"client_id"`
+	CleanSession   bool        `json:"clean_session" yaml:"clean_session"`
+	StoreDirectory string      `json:"store_directory" yaml:"store_directory"`
+	TLS            btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewMQTTConfig creates a new MQTTConfig with default values.
 func NewMQTTConfig() MQTTConfig {
 	return MQTTConfig{
-		URLs:     []string{"tcp://localhost:1883"},
-		QoS:      1,
-		Topic:    "benthos_topic",
-		ClientID: "benthos_output",
+		URLs:           []string{"tcp://localhost:1883"},
+		QoS:            1,
+		Retained:       false,
+		Topic:          "benthos_topic",
+		ClientID:       "benthos_output",
+		CleanSession:   true,
+		StoreDirectory: "",
+		TLS:            btls.NewConfig(),
 	}
 }
 
@@ -58,8 +71,10 @@ type MQTT struct {
 	log   log.Modular
 	stats metrics.Type
 
-	urls []string
-	conf MQTTConfig
+	urls    []string
+	conf    MQTTConfig
+	topic   *text.InterpolatedString
+	tlsConf *tls.Config
 
 	client  mqtt.Client
 	connMut sync.RWMutex
@@ -71,10 +86,25 @@ func NewMQTT(
 	log log.Modular,
 	stats metrics.Type,
 ) (*MQTT, error) {
+	if conf.QoS > 2 {
+		return nil, fmt.Errorf("qos must be 0, 1 or 2, received: %v", conf.QoS)
+	}
+	if !conf.CleanSession && len(conf.ClientID) == 0 {
+		return nil, fmt.Errorf("a client_id must be set when clean_session is false")
+	}
+
 	m := &MQTT{
 		log:   log,
 		stats: stats,
 		conf:  conf,
+		topic: text.NewInterpolatedString(conf.Topic),
+	}
+
+	if conf.TLS.Enabled {
+		var err error
+		if m.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
 	}
 
 	for _, u := range conf.URLs {
@@ -103,7 +133,15 @@ func (m *MQTT) Connect() error {
 		SetAutoReconnect(true).
 		SetConnectTimeout(time.Second).
 		SetWriteTimeout(time.Second).
-		SetClientID(m.conf.ClientID)
+		SetClientID(m.conf.ClientID).
+		SetCleanSession(m.conf.CleanSession)
+
+	if m.tlsConf != nil {
+		conf = conf.SetTLSConfig(m.tlsConf)
+	}
+	if len(m.conf.StoreDirectory) > 0 {
+		conf = conf.SetStore(mqtt.NewFileStore(m.conf.StoreDirectory))
+	}
 
 	for _, u := range m.urls {
 		conf = conf.AddBroker(u)
@@ -134,7 +172,8 @@ func (m *MQTT) Write(msg types.Message) error {
 	}
 
 	return msg.Iter(func(i int, p types.Part) error {
-		mtok := client.Publish(m.conf.Topic, byte(m.conf.QoS), false, p.Get())
+		topic := m.topic.Get(message.Lock(msg, i))
+		mtok := client.Publish(topic, byte(m.conf.QoS), m.conf.Retained, p.Get())
 		mtok.Wait()
 		return mtok.Error()
 	})
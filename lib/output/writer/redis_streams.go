@@ -21,13 +21,18 @@
 package writer
 
 import (
+	"encoding/json"
 	"net/url"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
 	"github.com/go-redis/redis"
 )
 
@@ -35,10 +40,11 @@ import (
 
 // RedisStreamsConfig contains configuration fields for the RedisStreams output type.
 type RedisStreamsConfig struct {
-	URL          string `json:"url" yaml:"url"`
-	Stream       string `json:"stream" yaml:"stream"`
-	BodyKey      string `json:"body_key" yaml:"body_key"`
-	MaxLenApprox int64  `json:"max_length" yaml:"max_length"`
+	URL          string            `json:"url" yaml:"url"`
+	Stream       string            `json:"stream" yaml:"stream"`
+	BodyKey      string            `json:"body_key" yaml:"body_key"`
+	Fields       map[string]string `json:"fields" yaml:"fields"`
+	MaxLenApprox int64             `json:"max_length" yaml:"max_length"`
 }
 
 // NewRedisStreamsConfig creates a new RedisStreamsConfig with default values.
@@ -47,6 +53,7 @@ func NewRedisStreamsConfig() RedisStreamsConfig {
 		URL:          "tcp://localhost:6379",
 		Stream:       "benthos_stream",
 		BodyKey:      "body",
+		Fields:       map[string]string{},
 		MaxLenApprox: 0,
 	}
 }
@@ -55,11 +62,14 @@ func NewRedisStreamsConfig() RedisStreamsConfig {
 
 // RedisStreams is an output type that serves RedisStreams messages.
 type RedisStreams struct {
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 
-	url  *url.URL
-	conf RedisStreamsConfig
+	url    *url.URL
+	conf   RedisStreamsConfig
+	stream *text.InterpolatedString
+	fields map[string]string
 
 	client  *redis.Client
 	connMut sync.RWMutex
@@ -68,14 +78,24 @@ type RedisStreams struct {
 // NewRedisStreams creates a new RedisStreams output type.
 func NewRedisStreams(
 	conf RedisStreamsConfig,
+	mgr types.Manager,
 	log log.Modular,
 	stats metrics.Type,
 ) (*RedisStreams, error) {
 
 	r := &RedisStreams{
-		log:   log,
-		stats: stats,
-		conf:  conf,
+		mgr:    mgr,
+		log:    log,
+		stats:  stats,
+		conf:   conf,
+		stream: text.NewInterpolatedString(conf.Stream),
+		fields: map[string]string{},
+	}
+	for k, v := range conf.Fields {
+		if v == "." {
+			v = ""
+		}
+		r.fields[k] = v
 	}
 
 	var err error
@@ -132,10 +152,22 @@ func (r *RedisStreams) Write(msg types.Message) error {
 			values[k] = v
 			return nil
 		})
-		values[r.conf.BodyKey] = p.Get()
+		if len(r.fields) > 0 {
+			r.mapFields(p, values)
+		}
+		if len(r.conf.BodyKey) > 0 {
+			values[r.conf.BodyKey] = p.Get()
+		}
+		if len(values) == 0 {
+			// Redis rejects entries without fields, so retrying would never
+			// succeed.
+			r.log.Errorf("Dropping message part %v as it has no fields to add to the stream\n", i)
+			drop.Report(r.mgr, "redis_streams", message.New([][]byte{p.Get()}))
+			return nil
+		}
 		if err := client.XAdd(&redis.XAddArgs{
 			ID:           "*",
-			Stream:       r.conf.Stream,
+			Stream:       r.stream.Get(message.Lock(msg, i)),
 			MaxLenApprox: r.conf.MaxLenApprox,
 			Values:       values,
 		}).Err(); err != nil {
@@ -147,6 +179,36 @@ func (r *RedisStreams) Write(msg types.Message) error {
 	})
 }
 
+// mapFields sets stream entry fields from the values found at paths of a JSON
+// document, string values are set as they are and all others are serialised
+// as JSON. Paths that do not exist within the document are skipped.
+func (r *RedisStreams) mapFields(p types.Part, values map[string]interface{}) {
+	jRoot, err := p.JSON()
+	if err != nil {
+		r.log.Errorf("Failed to extract JSON fields from document: %v\n", err)
+		return
+	}
+	gObj, err := gabs.Consume(jRoot)
+	if err != nil {
+		r.log.Errorf("Failed to extract JSON fields from document: %v\n", err)
+		return
+	}
+	for k, path := range r.fields {
+		gField := gObj
+		if len(path) > 0 {
+			gField = gObj.Path(path)
+		}
+		switch t := gField.Data().(type) {
+		case nil:
+		case string:
+			values[k] = t
+		default:
+			jBytes, _ := json.Marshal(t)
+			values[k] = string(jBytes)
+		}
+	}
+}
+
 // disconnect safely closes a connection to an RedisStreams server.
 func (r *RedisStreams) disconnect() error {
 	r.connMut.Lock()
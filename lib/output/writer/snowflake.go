@@ -0,0 +1,382 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+// SnowflakeStageConfig contains configuration fields for the S3 stage that
+// batches are written to before ingestion.
+type SnowflakeStageConfig struct {
+	sess.Config `json:",inline" yaml:",inline"`
+	Bucket      string `json:"bucket" yaml:"bucket"`
+	Prefix      string `json:"prefix" yaml:"prefix"`
+	Path        string `json:"path" yaml:"path"`
+}
+
+// SnowflakeConfig contains configuration fields for the Snowflake output type.
+type SnowflakeConfig struct {
+	Account        string               `json:"account" yaml:"account"`
+	User           string               `json:"user" yaml:"user"`
+	PrivateKeyFile string               `json:"private_key_file" yaml:"private_key_file"`
+	Pipe           string               `json:"pipe" yaml:"pipe"`
+	Format         string               `json:"format" yaml:"format"`
+	Stage          SnowflakeStageConfig `json:"stage" yaml:"stage"`
+	Endpoint       string               `json:"endpoint" yaml:"endpoint"`
+	Timeout        string               `json:"timeout" yaml:"timeout"`
+}
+
+// NewSnowflakeConfig creates a new SnowflakeConfig with default values.
+func NewSnowflakeConfig() SnowflakeConfig {
+	return SnowflakeConfig{
+		Account:        "",
+		User:           "",
+		PrivateKeyFile: "",
+		Pipe:           "",
+		Format:         "ndjson",
+		Stage: SnowflakeStageConfig{
+			Config: sess.NewConfig(),
+			Bucket: "",
+			Prefix: "",
+			Path:   "",
+		},
+		Endpoint: "",
+		Timeout:  "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Snowflake is a benthos writer.Type implementation that writes message
+// batches as files to an S3 stage and then triggers their ingestion into a
+// table via the Snowpipe REST API.
+type Snowflake struct {
+	conf SnowflakeConfig
+
+	pipe *text.InterpolatedString
+	path *text.InterpolatedString
+
+	accountName string
+	endpoint    string
+	key         *rsa.PrivateKey
+	fingerprint string
+	timeout     time.Duration
+	client      http.Client
+
+	uploader *s3manager.Uploader
+
+	log   log.Modular
+	stats metrics.Type
+
+	mFilesStaged metrics.StatCounter
+}
+
+// NewSnowflake creates a new Snowflake writer.Type.
+func NewSnowflake(
+	conf SnowflakeConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Snowflake, error) {
+	if len(conf.Account) == 0 {
+		return nil, errors.New("an account must be specified")
+	}
+	if len(conf.User) == 0 {
+		return nil, errors.New("a user must be specified")
+	}
+	if len(conf.Pipe) == 0 {
+		return nil, errors.New("a pipe must be specified")
+	}
+	if len(conf.Stage.Bucket) == 0 {
+		return nil, errors.New("a stage bucket must be specified")
+	}
+	ext := "json"
+	switch conf.Format {
+	case "ndjson":
+	case "csv":
+		ext = "csv"
+	default:
+		return nil, fmt.Errorf("format not recognised: %v", conf.Format)
+	}
+	path := conf.Stage.Path
+	if len(path) == 0 {
+		path = "${!count:files}-${!timestamp_unix_nano}." + ext
+	}
+
+	s := &Snowflake{
+		conf:         conf,
+		log:          log,
+		stats:        stats,
+		pipe:         text.NewInterpolatedString(conf.Pipe),
+		path:         text.NewInterpolatedString(path),
+		accountName:  strings.ToUpper(strings.Split(conf.Account, ".")[0]),
+		endpoint:     conf.Endpoint,
+		mFilesStaged: stats.GetCounter("files.staged"),
+	}
+	if len(s.endpoint) == 0 {
+		s.endpoint = fmt.Sprintf("https://%v.snowflakecomputing.com", conf.Account)
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if s.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout period string: %v", err)
+		}
+	}
+	s.client.Timeout = s.timeout
+
+	keyBytes, err := ioutil.ReadFile(conf.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %v", err)
+	}
+	if s.key, err = parseRSAPrivateKey(keyBytes); err != nil {
+		return nil, err
+	}
+	if s.fingerprint, err = publicKeyFingerprint(&s.key.PublicKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func parseRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode private key PEM block")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func publicKeyFingerprint(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// createJWT returns a signed token used for key pair authentication with the
+// Snowpipe REST API.
+func (s *Snowflake) createJWT(now time.Time) (string, error) {
+	qualifiedUser := s.accountName + "." + strings.ToUpper(s.conf.User)
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": qualifiedUser + "." + s.fingerprint,
+		"sub": qualifiedUser,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour - time.Minute).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	hashed := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// insertFiles notifies Snowpipe of a staged file to be ingested by a pipe.
+func (s *Snowflake) insertFiles(pipe string, files ...string) error {
+	reqID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	token, err := s.createJWT(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to create token: %v", err)
+	}
+
+	type fileEntry struct {
+		Path string `json:"path"`
+	}
+	entries := make([]fileEntry, len(files))
+	for i, f := range files {
+		entries[i].Path = f
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"files": entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	reqURL := fmt.Sprintf(
+		"%v/v1/data/pipes/%v/insertFiles?requestId=%v",
+		s.endpoint, url.PathEscape(pipe), reqID.String(),
+	)
+	req, err := http.NewRequest("POST", reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("insert files request returned status %v: %s", res.StatusCode, resBody)
+	}
+	return nil
+}
+
+// encodeBatch serialises a message batch into the contents of a staged file.
+func (s *Snowflake) encodeBatch(msg types.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	err := msg.Iter(func(i int, p types.Part) error {
+		if s.conf.Format == "ndjson" {
+			jObj, err := p.JSON()
+			if err != nil {
+				return fmt.Errorf("failed to parse message %v as JSON: %v", i, err)
+			}
+			jBytes, err := json.Marshal(jObj)
+			if err != nil {
+				return err
+			}
+			buf.Write(jBytes)
+		} else {
+			buf.Write(bytes.TrimRight(p.Get(), "\n"))
+		}
+		buf.WriteByte('\n')
+		return nil
+	})
+	return buf.Bytes(), err
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the S3 stage.
+func (s *Snowflake) Connect() error {
+	if s.uploader != nil {
+		return nil
+	}
+
+	awsSess, err := s.conf.Stage.GetSession()
+	if err != nil {
+		return err
+	}
+	s.uploader = s3manager.NewUploader(awsSess)
+
+	s.log.Infof("Staging files to S3 bucket '%v' for Snowflake account '%v'\n", s.conf.Stage.Bucket, s.conf.Account)
+	return nil
+}
+
+// Write attempts to write a message batch as a file to the stage and triggers
+// its ingestion.
+func (s *Snowflake) Write(msg types.Message) error {
+	if s.uploader == nil {
+		return types.ErrNotConnected
+	}
+
+	contents, err := s.encodeBatch(msg)
+	if err != nil {
+		return err
+	}
+
+	path := s.path.Get(msg)
+	key := path
+	if len(s.conf.Stage.Prefix) > 0 {
+		key = strings.TrimSuffix(s.conf.Stage.Prefix, "/") + "/" + path
+	}
+
+	ctx, cancel := context.WithTimeout(aws.BackgroundContext(), s.timeout)
+	defer cancel()
+
+	if _, err = s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: &s.conf.Stage.Bucket,
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(contents),
+	}); err != nil {
+		return fmt.Errorf("failed to stage file: %v", err)
+	}
+	s.mFilesStaged.Incr(1)
+
+	return s.insertFiles(s.pipe.Get(msg), path)
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (s *Snowflake) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (s *Snowflake) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,247 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func newTestSnowflake(t *testing.T, conf SnowflakeConfig) (*Snowflake, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile, err := ioutil.TempFile("", "benthos_snowflake_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(keyFile.Name())
+	if err = pem.Encode(keyFile, &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	keyFile.Close()
+
+	conf.PrivateKeyFile = keyFile.Name()
+	s, err := NewSnowflake(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, key
+}
+
+func TestSnowflakeJWT(t *testing.T) {
+	conf := NewSnowflakeConfig()
+	conf.Account = "xy12345.eu-west-1"
+	conf.User = "benthos"
+	conf.Pipe = "db.schema.pipe"
+	conf.Stage.Bucket = "foo"
+
+	s, key := newTestSnowflake(t, conf)
+
+	now := time.Unix(1000, 0)
+	token, err := s.createJWT(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Wrong count of token parts: %v", len(parts))
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], sig); err != nil {
+		t.Errorf("Failed to verify signature: %v", err)
+	}
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]interface{}
+	if err = json.Unmarshal(claimBytes, &claims); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, err := publicKeyFingerprint(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "XY12345.BENTHOS."+fingerprint, claims["iss"]; exp != act {
+		t.Errorf("Wrong issuer: %v != %v", act, exp)
+	}
+	if exp, act := "XY12345.BENTHOS", claims["sub"]; exp != act {
+		t.Errorf("Wrong subject: %v != %v", act, exp)
+	}
+	if exp, act := float64(1000), claims["iat"]; exp != act {
+		t.Errorf("Wrong issued at: %v != %v", act, exp)
+	}
+	if exp, act := float64(1000+3540), claims["exp"]; exp != act {
+		t.Errorf("Wrong expiry: %v != %v", act, exp)
+	}
+}
+
+func TestSnowflakeInsertFiles(t *testing.T) {
+	var reqPath, reqAuth string
+	var reqBody map[string][]map[string]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqPath = r.URL.Path
+		reqAuth = r.Header.Get("Authorization")
+		if len(r.URL.Query().Get("requestId")) == 0 {
+			t.Error("Expected request ID")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"responseCode":"SUCCESS"}`))
+	}))
+	defer ts.Close()
+
+	conf := NewSnowflakeConfig()
+	conf.Account = "xy12345"
+	conf.User = "benthos"
+	conf.Pipe = "db.schema.${!metadata:pipe}"
+	conf.Stage.Bucket = "foo"
+	conf.Endpoint = ts.URL
+
+	s, _ := newTestSnowflake(t, conf)
+
+	msg := message.New([][]byte{[]byte(`{"foo":"bar"}`)})
+	msg.Get(0).Metadata().Set("pipe", "events")
+
+	if err := s.insertFiles(s.pipe.Get(msg), "1-1000.json"); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "/v1/data/pipes/db.schema.events/insertFiles", reqPath; exp != act {
+		t.Errorf("Wrong path: %v != %v", act, exp)
+	}
+	if !strings.HasPrefix(reqAuth, "Bearer ") {
+		t.Errorf("Wrong auth header: %v", reqAuth)
+	}
+	if exp, act := "1-1000.json", reqBody["files"][0]["path"]; exp != act {
+		t.Errorf("Wrong file path: %v != %v", act, exp)
+	}
+}
+
+func TestSnowflakeInsertFilesError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	conf := NewSnowflakeConfig()
+	conf.Account = "xy12345"
+	conf.User = "benthos"
+	conf.Pipe = "db.schema.pipe"
+	conf.Stage.Bucket = "foo"
+	conf.Endpoint = ts.URL
+
+	s, _ := newTestSnowflake(t, conf)
+	if err := s.insertFiles("db.schema.pipe", "foo.json"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestSnowflakeDefaultPath(t *testing.T) {
+	for format, ext := range map[string]string{
+		"ndjson": ".json",
+		"csv":    ".csv",
+	} {
+		conf := NewSnowflakeConfig()
+		conf.Account = "xy12345"
+		conf.User = "benthos"
+		conf.Pipe = "foo"
+		conf.Stage.Bucket = "foo"
+		conf.Format = format
+
+		s, _ := newTestSnowflake(t, conf)
+		if act := s.path.Get(message.New(nil)); !strings.HasSuffix(act, ext) {
+			t.Errorf("Wrong extension for format %v: %v", format, act)
+		}
+	}
+}
+
+func TestSnowflakeEncodeBatch(t *testing.T) {
+	conf := NewSnowflakeConfig()
+	conf.Account = "xy12345"
+	conf.User = "benthos"
+	conf.Pipe = "db.schema.pipe"
+	conf.Stage.Bucket = "foo"
+
+	s, _ := newTestSnowflake(t, conf)
+
+	msg := message.New([][]byte{
+		[]byte(`{"foo": "bar"}`),
+		[]byte(`{"foo": "baz"}`),
+	})
+	res, err := s.encodeBatch(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "{\"foo\":\"bar\"}\n{\"foo\":\"baz\"}\n", string(res); exp != act {
+		t.Errorf("Wrong result: %q != %q", act, exp)
+	}
+
+	if _, err = s.encodeBatch(message.New([][]byte{[]byte(`not json`)})); err == nil {
+		t.Error("Expected error")
+	}
+
+	s.conf.Format = "csv"
+	res, err = s.encodeBatch(message.New([][]byte{
+		[]byte("a,b,c\n"),
+		[]byte("d,e,f"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "a,b,c\nd,e,f\n", string(res); exp != act {
+		t.Errorf("Wrong result: %q != %q", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
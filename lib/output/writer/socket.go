@@ -0,0 +1,283 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+// SocketReconnectConfig contains configuration fields for the exponential
+// backoff applied between reconnection attempts.
+type SocketReconnectConfig struct {
+	InitialInterval string `json:"initial_interval" yaml:"initial_interval"`
+	MaxInterval     string `json:"max_interval" yaml:"max_interval"`
+}
+
+// SocketConfig contains configuration fields for the Socket output type.
+type SocketConfig struct {
+	Network      string                `json:"network" yaml:"network"`
+	Address      string                `json:"address" yaml:"address"`
+	Framing      string                `json:"framing" yaml:"framing"`
+	Delim        string                `json:"delimiter" yaml:"delimiter"`
+	WriteTimeout string                `json:"write_timeout" yaml:"write_timeout"`
+	Reconnect    SocketReconnectConfig `json:"reconnect" yaml:"reconnect"`
+	TLS          btls.Config           `json:"tls" yaml:"tls"`
+}
+
+// NewSocketConfig creates a new SocketConfig with default values.
+func NewSocketConfig() SocketConfig {
+	return SocketConfig{
+		Network:      "tcp",
+		Address:      "localhost:4194",
+		Framing:      "delimiter",
+		Delim:        "",
+		WriteTimeout: "5s",
+		Reconnect: SocketReconnectConfig{
+			InitialInterval: "500ms",
+			MaxInterval:     "30s",
+		},
+		TLS: btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Socket is an output type that writes messages to a TCP, UDP or Unix socket.
+type Socket struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf         SocketConfig
+	frame        func(p []byte) []byte
+	writeTimeout time.Duration
+	tlsConf      *tls.Config
+
+	lock *sync.Mutex
+	conn net.Conn
+
+	// Exponential backoff applied between connection attempts after either a
+	// failed attempt or a lost connection.
+	boff         backoff.BackOff
+	reconnecting bool
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewSocket creates a new Socket output type.
+func NewSocket(
+	conf SocketConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Socket, error) {
+	s := &Socket{
+		log:       log,
+		stats:     stats,
+		conf:      conf,
+		lock:      &sync.Mutex{},
+		closeChan: make(chan struct{}),
+	}
+
+	switch conf.Network {
+	case "tcp", "unix":
+	case "udp":
+		if conf.TLS.Enabled {
+			return nil, errors.New("tls is not supported with the udp network")
+		}
+	default:
+		return nil, fmt.Errorf("network not recognised: %v", conf.Network)
+	}
+
+	switch conf.Framing {
+	case "delimiter", "":
+		delim := []byte(conf.Delim)
+		if len(delim) == 0 {
+			delim = []byte("\n")
+		}
+		s.frame = func(p []byte) []byte {
+			framed := make([]byte, 0, len(p)+len(delim))
+			return append(append(framed, p...), delim...)
+		}
+	case "length_prefixed":
+		s.frame = func(p []byte) []byte {
+			framed := make([]byte, 4, len(p)+4)
+			binary.BigEndian.PutUint32(framed, uint32(len(p)))
+			return append(framed, p...)
+		}
+	default:
+		return nil, fmt.Errorf("framing not recognised: %v", conf.Framing)
+	}
+
+	var err error
+	if tout := conf.WriteTimeout; len(tout) > 0 {
+		if s.writeTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse write timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		if s.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	boff := backoff.NewExponentialBackOff()
+	boff.MaxElapsedTime = 0
+	if boff.InitialInterval, err = time.ParseDuration(conf.Reconnect.InitialInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect initial interval: %v", err)
+	}
+	if boff.MaxInterval, err = time.ParseDuration(conf.Reconnect.MaxInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect max interval: %v", err)
+	}
+	s.boff = boff
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *Socket) getConn() net.Conn {
+	s.lock.Lock()
+	conn := s.conn
+	s.lock.Unlock()
+	return conn
+}
+
+// disconnect closes a connection if it is still the active one, and flags that
+// subsequent connection attempts should be backed off.
+func (s *Socket) disconnect(conn net.Conn) {
+	s.lock.Lock()
+	if s.conn == conn {
+		s.conn.Close()
+		s.conn = nil
+		s.reconnecting = true
+	}
+	s.lock.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a connection to the target socket.
+func (s *Socket) Connect() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn != nil {
+		return nil
+	}
+
+	if s.reconnecting {
+		select {
+		case <-time.After(s.boff.NextBackOff()):
+		case <-s.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+
+	conn, err := net.Dial(s.conf.Network, s.conf.Address)
+	if err != nil {
+		s.reconnecting = true
+		return err
+	}
+	if s.tlsConf != nil {
+		tlsConf := s.tlsConf
+		if len(tlsConf.ServerName) == 0 && s.conf.Network == "tcp" {
+			tlsConf = tlsConf.Clone()
+			if tlsConf.ServerName, _, err = net.SplitHostPort(s.conf.Address); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+		tlsConn := tls.Client(conn, tlsConf)
+		if err = tlsConn.Handshake(); err != nil {
+			tlsConn.Close()
+			s.reconnecting = true
+			return err
+		}
+		conn = tlsConn
+	}
+
+	s.conn = conn
+	s.reconnecting = false
+	s.boff.Reset()
+
+	s.log.Infof("Sending messages to %v socket: %v\n", s.conf.Network, s.conf.Address)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Write attempts to write a message to the socket, where each message part is
+// framed and written individually.
+func (s *Socket) Write(msg types.Message) error {
+	conn := s.getConn()
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	err := msg.Iter(func(i int, p types.Part) error {
+		if s.writeTimeout > 0 {
+			if err := conn.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil {
+				return err
+			}
+		}
+		_, err := conn.Write(s.frame(p.Get()))
+		return err
+	})
+	if err != nil {
+		s.disconnect(conn)
+		s.log.Warnf("Lost %v socket connection: %v\n", s.conf.Network, err)
+		return types.ErrNotConnected
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Socket output and stops processing messages.
+func (s *Socket) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+	})
+	s.lock.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.lock.Unlock()
+}
+
+// WaitForClose blocks until the Socket output has closed down.
+func (s *Socket) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,294 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestSocketTCPDelimiter(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	resChan := make(chan []string, 1)
+	go func() {
+		conn, cerr := ln.Accept()
+		if cerr != nil {
+			t.Error(cerr)
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for len(lines) < 3 && scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		resChan <- lines
+	}()
+
+	conf := NewSocketConfig()
+	conf.Address = ln.Addr().String()
+
+	s, err := NewSocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if cerr := s.WaitForClose(time.Second); cerr != nil {
+			t.Error(cerr)
+		}
+	}()
+
+	if err = s.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Write(message.New([][]byte{[]byte("baz")})); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case lines := <-resChan:
+		if exp, act := []string{"foo", "bar", "baz"}, lines; !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+}
+
+func TestSocketUnixLengthPrefixed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_socket_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ln, err := net.Listen("unix", filepath.Join(dir, "benthos.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	resChan := make(chan []string, 1)
+	go func() {
+		conn, cerr := ln.Accept()
+		if cerr != nil {
+			t.Error(cerr)
+			return
+		}
+		defer conn.Close()
+		var frames []string
+		for len(frames) < 2 {
+			var size uint32
+			if cerr = binary.Read(conn, binary.BigEndian, &size); cerr != nil {
+				t.Error(cerr)
+				break
+			}
+			frame := make([]byte, size)
+			if _, cerr = io.ReadFull(conn, frame); cerr != nil {
+				t.Error(cerr)
+				break
+			}
+			frames = append(frames, string(frame))
+		}
+		resChan <- frames
+	}()
+
+	conf := NewSocketConfig()
+	conf.Network = "unix"
+	conf.Address = filepath.Join(dir, "benthos.sock")
+	conf.Framing = "length_prefixed"
+
+	s, err := NewSocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(message.New([][]byte{[]byte("foo\nbar"), []byte("")})); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case frames := <-resChan:
+		if exp, act := []string{"foo\nbar", ""}, frames; !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result: %q != %q", act, exp)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+}
+
+func TestSocketUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conf := NewSocketConfig()
+	conf.Network = "udp"
+	conf.Address = conn.LocalAddr().String()
+	conf.Delim = "|"
+
+	s, err := NewSocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	buf := make([]byte, 64)
+	for _, exp := range []string{"foo|", "bar|"} {
+		n, _, rerr := conn.ReadFrom(buf)
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		if act := string(buf[:n]); act != exp {
+			t.Errorf("Wrong datagram: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestSocketReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	conf := NewSocketConfig()
+	conf.Address = ln.Addr().String()
+	conf.Reconnect.InitialInterval = "1ms"
+	conf.Reconnect.MaxInterval = "1ms"
+
+	s, err := NewSocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(message.New([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error, received: %v", err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close the first connection from the server side.
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// Writes to a closed connection might succeed until the reset is received.
+	for i := 0; ; i++ {
+		if err = s.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+			break
+		}
+		if i > 100 {
+			t.Fatal("Expected write to fail after connection was closed")
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	if err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error, received: %v", err)
+	}
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if conn, err = ln.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err = s.Write(message.New([][]byte{[]byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "bar\n", line; exp != act {
+		t.Errorf("Wrong result: %q != %q", act, exp)
+	}
+}
+
+func TestSocketBadConfig(t *testing.T) {
+	conf := NewSocketConfig()
+	conf.Network = "nope"
+	if _, err := NewSocket(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad network")
+	}
+
+	conf = NewSocketConfig()
+	conf.Framing = "nope"
+	if _, err := NewSocket(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad framing")
+	}
+
+	conf = NewSocketConfig()
+	conf.Network = "udp"
+	conf.TLS.Enabled = true
+	if _, err := NewSocket(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from tls with udp")
+	}
+
+	conf = NewSocketConfig()
+	conf.Reconnect.MaxInterval = "nope"
+	if _, err := NewSocket(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad reconnect interval")
+	}
+}
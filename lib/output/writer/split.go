@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// splitBySize breaks a message batch down into a slice of smaller batches where
+// the sum of the payload sizes of each batch does not exceed maxBytes. The
+// order of parts is preserved. A maxBytes of zero or less disables splitting,
+// in which case the original batch is returned as is.
+//
+// If any single part exceeds maxBytes an error is returned, as the part could
+// not be sent regardless of how the batch is divided.
+func splitBySize(msg types.Message, maxBytes int) ([]types.Message, error) {
+	if maxBytes <= 0 {
+		return []types.Message{msg}, nil
+	}
+
+	var batches []types.Message
+	var current types.Message
+	currentBytes := 0
+
+	if err := msg.Iter(func(i int, p types.Part) error {
+		size := len(p.Get())
+		if size > maxBytes {
+			return fmt.Errorf("message part %v of size %v bytes exceeds max batch size of %v bytes", i, size, maxBytes)
+		}
+		if current != nil && currentBytes+size > maxBytes {
+			batches = append(batches, current)
+			current = nil
+		}
+		if current == nil {
+			current = message.New(nil)
+			currentBytes = 0
+		}
+		current.Append(p)
+		currentBytes += size
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		batches = append(batches, current)
+	}
+	if len(batches) == 0 {
+		batches = append(batches, msg)
+	}
+	return batches, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+func TestSplitBySize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		maxBytes int
+		output   [][]string
+		err      bool
+	}{
+		{
+			name:     "disabled",
+			input:    []string{"foo", "bar", "baz"},
+			maxBytes: 0,
+			output:   [][]string{{"foo", "bar", "baz"}},
+		},
+		{
+			name:     "fits",
+			input:    []string{"foo", "bar", "baz"},
+			maxBytes: 9,
+			output:   [][]string{{"foo", "bar", "baz"}},
+		},
+		{
+			name:     "split evenly",
+			input:    []string{"foo", "bar", "baz", "qux"},
+			maxBytes: 6,
+			output:   [][]string{{"foo", "bar"}, {"baz", "qux"}},
+		},
+		{
+			name:     "split unevenly",
+			input:    []string{"foo", "barbaz", "q", "ux"},
+			maxBytes: 7,
+			output:   [][]string{{"foo"}, {"barbaz", "q"}, {"ux"}},
+		},
+		{
+			name:     "part too large",
+			input:    []string{"foo", "barbazqux"},
+			maxBytes: 6,
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		parts := make([][]byte, len(test.input))
+		for i, v := range test.input {
+			parts[i] = []byte(v)
+		}
+		msg := message.New(parts)
+		msg.Get(0).Metadata().Set("foo", "bar")
+
+		res, err := splitBySize(msg, test.maxBytes)
+		if test.err {
+			if err == nil {
+				t.Errorf("%v: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		act := [][]string{}
+		for _, m := range res {
+			batch := []string{}
+			for _, b := range message.GetAllBytes(m) {
+				batch = append(batch, string(b))
+			}
+			act = append(act, batch)
+		}
+		if !reflect.DeepEqual(test.output, act) {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, test.output)
+		}
+		if exp, act := "bar", res[0].Get(0).Metadata().Get("foo"); exp != act {
+			t.Errorf("%v: wrong metadata: %v != %v", test.name, act, exp)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+	"github.com/Jeffail/benthos/lib/util/text"
+
+	// SQL Drivers
+	_ "github.com/go-sql-driver/mysql"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLConfig contains configuration fields for the SQL output type.
+type SQLConfig struct {
+	Driver          string          `json:"driver" yaml:"driver"`
+	DSN             string          `json:"dsn" yaml:"dsn"`
+	Query           string          `json:"query" yaml:"query"`
+	Args            []string        `json:"args" yaml:"args"`
+	MaxOpenConns    int             `json:"max_open_connections" yaml:"max_open_connections"`
+	MaxIdleConns    int             `json:"max_idle_connections" yaml:"max_idle_connections"`
+	ConnMaxLifetime string          `json:"connection_max_lifetime" yaml:"connection_max_lifetime"`
+	Init            bsql.InitConfig `json:"init" yaml:"init"`
+}
+
+// NewSQLConfig creates a new SQLConfig with default values.
+func NewSQLConfig() SQLConfig {
+	return SQLConfig{
+		Driver:          "mysql",
+		DSN:             "",
+		Query:           "",
+		Args:            []string{},
+		MaxOpenConns:    0,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: "",
+		Init:            bsql.NewInitConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQL is a benthos writer.Type implementation that executes a prepared
+// statement for each message of a batch within a single transaction.
+type SQL struct {
+	conf SQLConfig
+	args []*text.InterpolatedString
+
+	db      *sql.DB
+	query   *sql.Stmt
+	connMut sync.RWMutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRowsWritten metrics.StatCounter
+	mRollbacks   metrics.StatCounter
+}
+
+// NewSQL creates a new SQL writer.Type.
+func NewSQL(
+	conf SQLConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*SQL, error) {
+	if len(conf.Query) == 0 {
+		return nil, errors.New("a query must be specified")
+	}
+
+	var lifetime time.Duration
+	if len(conf.ConnMaxLifetime) > 0 {
+		var err error
+		if lifetime, err = time.ParseDuration(conf.ConnMaxLifetime); err != nil {
+			return nil, fmt.Errorf("failed to parse connection max lifetime string: %v", err)
+		}
+	}
+
+	db, err := sql.Open(conf.Driver, conf.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(conf.MaxOpenConns)
+	db.SetMaxIdleConns(conf.MaxIdleConns)
+	db.SetConnMaxLifetime(lifetime)
+
+	s := &SQL{
+		conf:         conf,
+		db:           db,
+		log:          log,
+		stats:        stats,
+		mRowsWritten: stats.GetCounter("rows.written"),
+		mRollbacks:   stats.GetCounter("rollback"),
+	}
+	for _, v := range conf.Args {
+		s.args = append(s.args, text.NewInterpolatedString(v))
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect runs any init statements against the database and prepares the
+// query.
+func (s *SQL) Connect() error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.query != nil {
+		return nil
+	}
+	if s.db == nil {
+		return types.ErrTypeClosed
+	}
+
+	if err := s.db.Ping(); err != nil {
+		return err
+	}
+	if err := bsql.RunInit(s.conf.Init, s.db, s.log); err != nil {
+		return err
+	}
+
+	query, err := s.db.Prepare(s.conf.Query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare query: %v", err)
+	}
+	s.query = query
+
+	s.log.Infof("Writing message batches to %v database\n", s.conf.Driver)
+	return nil
+}
+
+// execBatch executes the query for each message of a batch within a new
+// transaction, which is returned without being committed. If any execution
+// fails the transaction is rolled back.
+func (s *SQL) execBatch(msg types.Message) (*sql.Tx, error) {
+	s.connMut.RLock()
+	db, query := s.db, s.query
+	s.connMut.RUnlock()
+
+	if query == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	stmt := tx.Stmt(query)
+
+	if err = msg.Iter(func(i int, p types.Part) error {
+		lMsg := message.Lock(msg, i)
+		args := make([]interface{}, len(s.args))
+		for j, v := range s.args {
+			args[j] = v.Get(lMsg)
+		}
+		if _, eerr := stmt.Exec(args...); eerr != nil {
+			return fmt.Errorf("failed to execute query for message %v: %v", i, eerr)
+		}
+		return nil
+	}); err != nil {
+		s.mRollbacks.Incr(1)
+		if rerr := tx.Rollback(); rerr != nil {
+			s.log.Errorf("Failed to roll back transaction: %v\n", rerr)
+		}
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Write executes the query for each message of a batch and commits them
+// within a single transaction.
+func (s *SQL) Write(msg types.Message) error {
+	tx, err := s.execBatch(msg)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	s.mRowsWritten.Incr(int64(msg.Len()))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type sqlPreparedWrite struct {
+	tx       *sql.Tx
+	rows     int
+	mWritten metrics.StatCounter
+}
+
+func (p *sqlPreparedWrite) Commit() error {
+	if err := p.tx.Commit(); err != nil {
+		return err
+	}
+	p.mWritten.Incr(int64(p.rows))
+	return nil
+}
+
+func (p *sqlPreparedWrite) Abort() error {
+	return p.tx.Rollback()
+}
+
+// WritePrepared executes the query for each message of a batch within a
+// transaction that is left open until the returned handle is committed or
+// aborted.
+func (s *SQL) WritePrepared(msg types.Message) (types.PreparedWrite, error) {
+	tx, err := s.execBatch(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlPreparedWrite{
+		tx:       tx,
+		rows:     msg.Len(),
+		mWritten: s.mRowsWritten,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (s *SQL) CloseAsync() {
+	s.connMut.Lock()
+	if s.query != nil {
+		s.query.Close()
+		s.query = nil
+	}
+	if s.db != nil {
+		s.db.Close()
+		s.db = nil
+	}
+	s.connMut.Unlock()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (s *SQL) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
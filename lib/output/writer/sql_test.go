@@ -0,0 +1,250 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// sqlFakeDriver records the arguments of executed statements, which are only
+// kept once their transaction is committed. Executions with the argument
+// "fail" return an error.
+type sqlFakeDriver struct {
+	mut       sync.Mutex
+	committed [][]string
+}
+
+func (d *sqlFakeDriver) Open(name string) (driver.Conn, error) {
+	return &sqlFakeConn{d: d}, nil
+}
+
+type sqlFakeConn struct {
+	d       *sqlFakeDriver
+	pending [][]string
+}
+
+func (c *sqlFakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &sqlFakeStmt{c: c}, nil
+}
+
+func (c *sqlFakeConn) Close() error { return nil }
+
+func (c *sqlFakeConn) Begin() (driver.Tx, error) {
+	c.pending = nil
+	return &sqlFakeTx{c: c}, nil
+}
+
+type sqlFakeTx struct {
+	c *sqlFakeConn
+}
+
+func (t *sqlFakeTx) Commit() error {
+	t.c.d.mut.Lock()
+	t.c.d.committed = append(t.c.d.committed, t.c.pending...)
+	t.c.d.mut.Unlock()
+	t.c.pending = nil
+	return nil
+}
+
+func (t *sqlFakeTx) Rollback() error {
+	t.c.pending = nil
+	return nil
+}
+
+type sqlFakeStmt struct {
+	c *sqlFakeConn
+}
+
+func (s *sqlFakeStmt) Close() error  { return nil }
+func (s *sqlFakeStmt) NumInput() int { return -1 }
+
+func (s *sqlFakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	strs := make([]string, len(args))
+	for i, a := range args {
+		strs[i] = a.(string)
+		if strs[i] == "fail" {
+			return nil, errors.New("simulated failure")
+		}
+	}
+	s.c.pending = append(s.c.pending, strs)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *sqlFakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, io.EOF
+}
+
+func (d *sqlFakeDriver) rows() [][]string {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.committed
+}
+
+func newSQLFakeDriver(name string) *sqlFakeDriver {
+	d := &sqlFakeDriver{}
+	sql.Register(name, d)
+	return d
+}
+
+//------------------------------------------------------------------------------
+
+func TestSQLWriteBatch(t *testing.T) {
+	d := newSQLFakeDriver("benthos_test_sql_write")
+
+	conf := NewSQLConfig()
+	conf.Driver = "benthos_test_sql_write"
+	conf.Query = "INSERT INTO footable (foo, bar) VALUES (?, ?);"
+	conf.Args = []string{
+		"${!json_field:foo}",
+		"${!metadata:bar}",
+	}
+
+	s, err := NewSQL(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(message.New(nil)); err == nil {
+		t.Error("Expected error before connecting")
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"foo":"foo1"}`),
+		[]byte(`{"foo":"foo2"}`),
+	})
+	msg.Get(0).Metadata().Set("bar", "bar1")
+	msg.Get(1).Metadata().Set("bar", "bar2")
+
+	if err = s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{
+		{"foo1", "bar1"},
+		{"foo2", "bar2"},
+	}
+	if act := d.rows(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rows: %v != %v", act, exp)
+	}
+}
+
+func TestSQLWriteRollback(t *testing.T) {
+	d := newSQLFakeDriver("benthos_test_sql_rollback")
+
+	conf := NewSQLConfig()
+	conf.Driver = "benthos_test_sql_rollback"
+	conf.Query = "INSERT INTO footable (foo) VALUES (?);"
+	conf.Args = []string{"${!content}"}
+
+	s, err := NewSQL(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Write(message.New([][]byte{
+		[]byte(`foo1`),
+		[]byte(`fail`),
+	})); err == nil {
+		t.Error("Expected error from failed batch")
+	}
+	if act := d.rows(); len(act) > 0 {
+		t.Errorf("Expected no rows after rollback: %v", act)
+	}
+}
+
+func TestSQLWritePrepared(t *testing.T) {
+	d := newSQLFakeDriver("benthos_test_sql_prepared")
+
+	conf := NewSQLConfig()
+	conf.Driver = "benthos_test_sql_prepared"
+	conf.Query = "INSERT INTO footable (foo) VALUES (?);"
+	conf.Args = []string{"${!content}"}
+
+	s, err := NewSQL(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	aborted, err := s.WritePrepared(message.New([][]byte{[]byte(`foo1`)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = aborted.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	staged, err := s.WritePrepared(message.New([][]byte{[]byte(`foo2`)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := d.rows(); len(act) > 0 {
+		t.Errorf("Expected no rows before commit: %v", act)
+	}
+	if err = staged.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{{"foo2"}}
+	if act := d.rows(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rows: %v != %v", act, exp)
+	}
+}
+
+func TestSQLBadConfig(t *testing.T) {
+	conf := NewSQLConfig()
+	if _, err := NewSQL(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing query")
+	}
+
+	conf.Query = "INSERT INTO footable (foo) VALUES (?);"
+	conf.ConnMaxLifetime = "not a duration"
+	if _, err := NewSQL(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad lifetime")
+	}
+}
+
+//------------------------------------------------------------------------------
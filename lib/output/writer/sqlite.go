@@ -0,0 +1,169 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build SQLITE
+
+package writer
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+
+	// SQL Drivers
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLiteConfig contains configuration fields for the SQLite output type.
+type SQLiteConfig struct {
+	Path  string `json:"path" yaml:"path"`
+	Table string `json:"table" yaml:"table"`
+}
+
+// NewSQLiteConfig creates a new SQLiteConfig with default values.
+func NewSQLiteConfig() *SQLiteConfig {
+	return &SQLiteConfig{
+		Path:  "",
+		Table: "benthos_queue",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQLite is a benthos writer.Type implementation that appends messages to a
+// queue table within a local SQLite database file.
+type SQLite struct {
+	conf SQLiteConfig
+
+	db      *sql.DB
+	insert  *sql.Stmt
+	connMut sync.RWMutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRowsWritten metrics.StatCounter
+}
+
+// NewSQLite creates a new SQLite writer.Type.
+func NewSQLite(
+	conf *SQLiteConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*SQLite, error) {
+	if len(conf.Path) == 0 {
+		return nil, fmt.Errorf("a path must be specified")
+	}
+	return &SQLite{
+		conf:         *conf,
+		log:          log,
+		stats:        stats,
+		mRowsWritten: stats.GetCounter("rows.written"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect opens the database file, creating the queue table if necessary.
+func (s *SQLite) Connect() error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := bsql.OpenQueue(s.conf.Path, s.conf.Table)
+	if err != nil {
+		return err
+	}
+	insert, err := db.Prepare(fmt.Sprintf(
+		"INSERT INTO %v (content, metadata) VALUES (?, ?)", s.conf.Table,
+	))
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to prepare insert: %v", err)
+	}
+
+	s.db, s.insert = db, insert
+	s.log.Infof("Appending messages to SQLite queue: %v\n", s.conf.Path)
+	return nil
+}
+
+// Write appends each message of a batch to the queue table within a single
+// transaction.
+func (s *SQLite) Write(msg types.Message) error {
+	s.connMut.RLock()
+	db, insert := s.db, s.insert
+	s.connMut.RUnlock()
+
+	if db == nil {
+		return types.ErrNotConnected
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt := tx.Stmt(insert)
+	if err = msg.Iter(func(i int, p types.Part) error {
+		meta, merr := bsql.EncodeQueueMetadata(p)
+		if merr != nil {
+			return merr
+		}
+		_, merr = stmt.Exec(p.Get(), meta)
+		return merr
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.mRowsWritten.Incr(int64(msg.Len()))
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (s *SQLite) CloseAsync() {
+	s.connMut.Lock()
+	if s.db != nil {
+		s.insert.Close()
+		s.db.Close()
+		s.db, s.insert = nil, nil
+	}
+	s.connMut.Unlock()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (s *SQLite) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build SQLITE
+
+package writer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
+)
+
+func TestSQLiteWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_sqlite_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewSQLiteConfig()
+	conf.Path = filepath.Join(dir, "queue.db")
+
+	w, err := NewSQLite(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Skipf("SQLite driver unavailable: %v", err)
+	}
+	defer w.CloseAsync()
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(1).Metadata().Set("baz", "qux")
+	if err = w.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bsql.OpenQueue(conf.Path, conf.Table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT content, metadata FROM benthos_queue ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var act []string
+	for rows.Next() {
+		var content []byte
+		var meta string
+		if err = rows.Scan(&content, &meta); err != nil {
+			t.Fatal(err)
+		}
+		act = append(act, string(content)+" "+meta)
+	}
+	exp := []string{`foo {}`, `bar {"baz":"qux"}`}
+	if len(act) != len(exp) {
+		t.Fatalf("Wrong rows: %v != %v", act, exp)
+	}
+	for i := range exp {
+		if exp[i] != act[i] {
+			t.Errorf("Wrong row: %v != %v", act[i], exp[i])
+		}
+	}
+}
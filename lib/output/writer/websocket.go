@@ -21,6 +21,7 @@
 package writer
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
@@ -30,22 +31,39 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/cenkalti/backoff"
 	"github.com/gorilla/websocket"
 )
 
 //------------------------------------------------------------------------------
 
+// WebsocketReconnectConfig contains configuration fields for the exponential
+// backoff applied between reconnection attempts.
+type WebsocketReconnectConfig struct {
+	InitialInterval string `json:"initial_interval" yaml:"initial_interval"`
+	MaxInterval     string `json:"max_interval" yaml:"max_interval"`
+}
+
 // WebsocketConfig contains configuration fields for the Websocket output type.
 type WebsocketConfig struct {
-	URL         string `json:"url" yaml:"url"`
-	auth.Config `json:",inline" yaml:",inline"`
+	URL           string                   `json:"url" yaml:"url"`
+	WriteTimeout  string                   `json:"write_timeout" yaml:"write_timeout"`
+	Reconnect     WebsocketReconnectConfig `json:"reconnect" yaml:"reconnect"`
+	BufferedRetry bool                     `json:"buffered_retry" yaml:"buffered_retry"`
+	auth.Config   `json:",inline" yaml:",inline"`
 }
 
 // NewWebsocketConfig creates a new WebsocketConfig with default values.
 func NewWebsocketConfig() WebsocketConfig {
 	return WebsocketConfig{
-		URL:    "ws://localhost:4195/post/ws",
-		Config: auth.NewConfig(),
+		URL:          "ws://localhost:4195/post/ws",
+		WriteTimeout: "5s",
+		Reconnect: WebsocketReconnectConfig{
+			InitialInterval: "500ms",
+			MaxInterval:     "30s",
+		},
+		BufferedRetry: false,
+		Config:        auth.NewConfig(),
 	}
 }
 
@@ -58,8 +76,19 @@ type Websocket struct {
 
 	lock *sync.Mutex
 
-	conf   WebsocketConfig
-	client *websocket.Conn
+	conf         WebsocketConfig
+	client       *websocket.Conn
+	writeTimeout time.Duration
+
+	// Exponential backoff applied between connection attempts after either a
+	// failed attempt or a lost connection.
+	boff         backoff.BackOff
+	reconnecting bool
+
+	mBufferedRetry metrics.StatCounter
+
+	closeOnce sync.Once
+	closeChan chan struct{}
 }
 
 // NewWebsocket creates a new Websocket output type.
@@ -73,7 +102,26 @@ func NewWebsocket(
 		stats: stats,
 		lock:  &sync.Mutex{},
 		conf:  conf,
+
+		mBufferedRetry: stats.GetCounter("retry.buffered"),
+
+		closeChan: make(chan struct{}),
 	}
+	var err error
+	if tout := conf.WriteTimeout; len(tout) > 0 {
+		if ws.writeTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse write timeout string: %v", err)
+		}
+	}
+	boff := backoff.NewExponentialBackOff()
+	boff.MaxElapsedTime = 0
+	if boff.InitialInterval, err = time.ParseDuration(conf.Reconnect.InitialInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect initial interval: %v", err)
+	}
+	if boff.MaxInterval, err = time.ParseDuration(conf.Reconnect.MaxInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse reconnect max interval: %v", err)
+	}
+	ws.boff = boff
 	return ws, nil
 }
 
@@ -86,16 +134,42 @@ func (w *Websocket) getWS() *websocket.Conn {
 	return ws
 }
 
+// disconnect closes a client connection if it is still the active one, and
+// flags that subsequent connection attempts should be backed off.
+func (w *Websocket) disconnect(client *websocket.Conn) {
+	w.lock.Lock()
+	if w.client == client {
+		w.client.Close()
+		w.client = nil
+		w.reconnecting = true
+	}
+	w.lock.Unlock()
+}
+
 //------------------------------------------------------------------------------
 
 // Connect establishes a connection to an Websocket server.
 func (w *Websocket) Connect() error {
 	w.lock.Lock()
-	defer w.lock.Unlock()
-
 	if w.client != nil {
+		w.lock.Unlock()
 		return nil
 	}
+	var wait time.Duration
+	if w.reconnecting {
+		wait = w.boff.NextBackOff()
+	}
+	w.lock.Unlock()
+
+	// The backoff and dial happen outside of the lock so that closing the
+	// output or checking the connection are not blocked by them.
+	if wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-w.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
 
 	headers := http.Header{}
 
@@ -113,9 +187,27 @@ func (w *Websocket) Connect() error {
 
 	var client *websocket.Conn
 	if client, _, err = websocket.DefaultDialer.Dial(w.conf.URL, headers); err != nil {
+		w.lock.Lock()
+		w.reconnecting = true
+		w.lock.Unlock()
 		return err
 	}
 
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	select {
+	case <-w.closeChan:
+		client.Close()
+		return types.ErrTypeClosed
+	default:
+	}
+	if w.client != nil {
+		// A concurrent call connected first.
+		client.Close()
+		return nil
+	}
+
 	go func(c *websocket.Conn) {
 		for {
 			if _, _, cerr := c.NextReader(); cerr != nil {
@@ -126,35 +218,73 @@ func (w *Websocket) Connect() error {
 	}(client)
 
 	w.client = client
+	w.reconnecting = false
+	w.boff.Reset()
 	return nil
 }
 
 //------------------------------------------------------------------------------
 
+// writeFrom writes the parts of a message to a client starting from the index
+// pointed to by sent, which is incremented for each part written.
+func (w *Websocket) writeFrom(client *websocket.Conn, msg types.Message, sent *int) error {
+	for ; *sent < msg.Len(); *sent++ {
+		if w.writeTimeout > 0 {
+			if err := client.SetWriteDeadline(time.Now().Add(w.writeTimeout)); err != nil {
+				return err
+			}
+		}
+		if err := client.WriteMessage(websocket.BinaryMessage, msg.Get(*sent).Get()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Write attempts to write a message by pushing it to an Websocket broker.
+//
+// If the connection is lost whilst writing, and buffered retries are enabled,
+// the parts of the message that were not yet written are retained and written
+// once a new connection is established. Otherwise ErrNotConnected is returned
+// and the message is attempted again in full.
 func (w *Websocket) Write(msg types.Message) error {
 	client := w.getWS()
 	if client == nil {
 		return types.ErrNotConnected
 	}
 
-	err := msg.Iter(func(i int, p types.Part) error {
-		return client.WriteMessage(websocket.BinaryMessage, p.Get())
-	})
-	if err != nil {
-		w.lock.Lock()
-		w.client = nil
-		w.lock.Unlock()
-		if err == websocket.ErrCloseSent {
+	sent := 0
+	for {
+		err := w.writeFrom(client, msg, &sent)
+		if err == nil {
+			return nil
+		}
+		w.disconnect(client)
+		w.log.Warnf("Lost websocket connection: %v\n", err)
+		if !w.conf.BufferedRetry {
+			return types.ErrNotConnected
+		}
+		w.mBufferedRetry.Incr(1)
+		for {
+			if err = w.Connect(); err == nil {
+				break
+			}
+			if err == types.ErrTypeClosed {
+				return err
+			}
+			w.log.Errorf("Failed to reconnect websocket: %v\n", err)
+		}
+		if client = w.getWS(); client == nil {
 			return types.ErrNotConnected
 		}
-		return err
 	}
-	return nil
 }
 
 // CloseAsync shuts down the Websocket output and stops processing messages.
 func (w *Websocket) CloseAsync() {
+	w.closeOnce.Do(func() {
+		close(w.closeChan)
+	})
 	w.lock.Lock()
 	if w.client != nil {
 		w.client.Close()
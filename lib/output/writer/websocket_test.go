@@ -136,3 +136,104 @@ func TestWebsocketClose(t *testing.T) {
 	wg.Wait()
 	close(closeChan)
 }
+
+func TestWebsocketBufferedRetry(t *testing.T) {
+	expMsgs := []string{
+		"foo",
+		"bar",
+		"baz",
+	}
+
+	var connMut sync.Mutex
+	var conns int
+	resultChan := make(chan string, len(expMsgs))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		connMut.Lock()
+		conns++
+		first := conns == 1
+		connMut.Unlock()
+
+		// Drop the first connection immediately.
+		if first {
+			return
+		}
+		for range expMsgs {
+			_, actBytes, err := ws.ReadMessage()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resultChan <- string(actBytes)
+		}
+	}))
+	defer server.Close()
+
+	conf := NewWebsocketConfig()
+	conf.BufferedRetry = true
+	conf.Reconnect.InitialInterval = "1ms"
+	conf.Reconnect.MaxInterval = "10ms"
+	if wsURL, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		wsURL.Scheme = "ws"
+		conf.URL = wsURL.String()
+	}
+
+	m, err := NewWebsocket(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the client time to notice that the connection was dropped.
+	<-time.After(time.Millisecond * 100)
+
+	parts := [][]byte{}
+	for _, msg := range expMsgs {
+		parts = append(parts, []byte(msg))
+	}
+	if err = m.Write(message.New(parts)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range expMsgs {
+		select {
+		case act := <-resultChan:
+			if act != exp {
+				t.Errorf("Wrong msg contents: %v != %v", act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for message")
+		}
+	}
+
+	m.CloseAsync()
+	if err = m.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebsocketBadConfig(t *testing.T) {
+	conf := NewWebsocketConfig()
+	conf.WriteTimeout = "nope"
+	if _, err := NewWebsocket(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad write timeout")
+	}
+
+	conf = NewWebsocketConfig()
+	conf.Reconnect.MaxInterval = "nope"
+	if _, err := NewWebsocket(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad reconnect interval")
+	}
+}
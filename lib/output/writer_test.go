@@ -24,6 +24,7 @@ import (
 	"errors"
 	"os"
 	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
@@ -579,3 +580,271 @@ func TestWriterSadPath(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+type mockPreparedWrite struct {
+	committed bool
+}
+
+func (p *mockPreparedWrite) Commit() error {
+	p.committed = true
+	return nil
+}
+func (p *mockPreparedWrite) Abort() error {
+	return nil
+}
+
+type mockTransactionalWriter struct {
+	*mockWriter
+	prepared *mockPreparedWrite
+}
+
+func (w *mockTransactionalWriter) WritePrepared(msg types.Message) (types.PreparedWrite, error) {
+	w.msgRcvd = msg
+	if err := <-w.writeChan; err != nil {
+		return nil, err
+	}
+	return w.prepared, nil
+}
+
+func TestWriterPrepared(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := &mockTransactionalWriter{
+		mockWriter: newMockWriter(),
+		prepared:   &mockPreparedWrite{},
+	}
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.New(os.Stdout, logConfig), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.(*Writer).CanStage() {
+		t.Error("Expected transactional writer to support staging")
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.Consume(msgChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	for _, prepare := range []bool{true, false} {
+		tran := types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan)
+		tran.Prepare = prepare
+
+		select {
+		case msgChan <- tran:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case writerImpl.writeChan <- nil:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			if err := res.Error(); err != nil {
+				t.Error(err)
+			}
+			pw, isPrepared := res.(types.PreparedWrite)
+			if isPrepared != prepare {
+				t.Errorf("Wrong prepared response for prepare %v: %v", prepare, isPrepared)
+			}
+			if pw != nil {
+				if err := pw.Commit(); err != nil {
+					t.Error(err)
+				}
+				if !writerImpl.prepared.committed {
+					t.Error("Expected staged write to be committed")
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+//------------------------------------------------------------------------------
+
+type writerParallel struct {
+	started chan string
+	release map[string]chan error
+}
+
+func (w *writerParallel) Connect() error    { return nil }
+func (w *writerParallel) ConcurrentWrites() {}
+func (w *writerParallel) Write(msg types.Message) error {
+	content := string(msg.Get(0).Get())
+	w.started <- content
+	return <-w.release[content]
+}
+func (w *writerParallel) CloseAsync() {}
+func (w *writerParallel) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestWriterMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := &writerParallel{
+		started: make(chan string),
+		release: map[string]chan error{
+			"foo": make(chan error),
+			"bar": make(chan error),
+			"baz": make(chan error),
+		},
+	}
+
+	w, err := NewWriter(
+		"foo", writerImpl,
+		log.New(os.Stdout, logConfig), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.(*Writer).setMaxInFlight(3); err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	if err = w.Consume(msgChan); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.(*Writer).setMaxInFlight(2); err == nil {
+		t.Error("Expected error from setting max in flight after consuming")
+	}
+
+	resChans := map[string]chan types.Response{}
+	for _, content := range []string{"foo", "bar", "baz"} {
+		resChans[content] = make(chan types.Response)
+		select {
+		case msgChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChans[content]):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	// All three writes must be in flight at the same time.
+	for i := 0; i < 3; i++ {
+		select {
+		case <-writerImpl.started:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for parallel writes")
+		}
+	}
+
+	// Resolve the writes in reverse order, each response must arrive without
+	// waiting for the others.
+	errTest := errors.New("test err")
+	for _, content := range []string{"baz", "bar", "foo"} {
+		var resErr error
+		if content == "bar" {
+			resErr = errTest
+		}
+		select {
+		case writerImpl.release[content] <- resErr:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChans[content]:
+			if exp, act := resErr, res.Error(); exp != act {
+				t.Errorf("Wrong response for %v: %v != %v", content, act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for response of %v", content)
+		}
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOutputMaxInFlightUnsupported(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeInproc
+	conf.MaxInFlight = 2
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from max_in_flight on inproc output")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeBroker
+	conf.MaxInFlight = 2
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from max_in_flight on broker output")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeSocket
+	conf.MaxInFlight = 2
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from max_in_flight on socket output")
+	}
+}
+
+func TestOutputMaxInFlightAuto(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDrop
+	conf.MaxInFlight = -1
+
+	out, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := runtime.GOMAXPROCS(0), out.(*Writer).maxInFlight; exp != act {
+		t.Errorf("Wrong max in flight: %v != %v", act, exp)
+	}
+	out.CloseAsync()
+
+	conf = NewConfig()
+	conf.Type = TypeSocket
+	conf.MaxInFlight = -1
+
+	if out, err = New(conf, nil, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, out.(*Writer).maxInFlight; exp != act {
+		t.Errorf("Wrong max in flight: %v != %v", act, exp)
+	}
+	out.CloseAsync()
+}
+
+func TestWriterMaxInFlightNotConcurrent(t *testing.T) {
+	w, err := NewWriter(
+		"foo", newMockWriter(),
+		log.Noop(), metrics.Noop(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.(*Writer).setMaxInFlight(2); err == nil {
+		t.Error("Expected error from max in flight on non concurrent writer")
+	}
+	if err = w.(*Writer).setMaxInFlight(1); err != nil {
+		t.Error(err)
+	}
+}
@@ -23,6 +23,7 @@ package pipeline
 import (
 	"encoding/json"
 	"fmt"
+	"runtime"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -37,19 +38,32 @@ import (
 // threads specified. Processors are executed on each message in the order that
 // they are written.
 //
+// When threads is set to -1 the number of threads is capped by GOMAXPROCS, and
+// with the greedy routing strategy threads are spawned up to this cap as they
+// are needed.
+//
 // In order to fully utilise each processing thread you must either have a
 // number of parallel inputs that matches or surpasses the number of pipeline
 // threads, or use a memory buffer.
+//
+// The routing field determines how messages are distributed across threads.
+// When an ordering key is specified and routing is left as greedy the hash
+// strategy is used, where messages that resolve to the same key are always
+// processed by the same thread, preserving their order.
 type Config struct {
-	Threads    int                `json:"threads" yaml:"threads"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	Threads     int                `json:"threads" yaml:"threads"`
+	Routing     string             `json:"routing" yaml:"routing"`
+	OrderingKey string             `json:"ordering_key" yaml:"ordering_key"`
+	Processors  []processor.Config `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Threads:    1,
-		Processors: []processor.Config{},
+		Threads:     1,
+		Routing:     RoutingGreedy,
+		OrderingKey: "",
+		Processors:  []processor.Config{},
 	}
 }
 
@@ -111,10 +125,21 @@ func New(
 		}
 		return NewProcessor(log, stats, processors...), nil
 	}
-	if conf.Threads <= 1 {
+	threads := conf.Threads
+	if threads < 0 {
+		threads = runtime.GOMAXPROCS(0)
+	}
+	if threads <= 1 {
 		return procCtor(&procs)
 	}
-	return NewPool(procCtor, conf.Threads, log, stats)
+	routing := conf.Routing
+	if (routing == RoutingGreedy || routing == "") && len(conf.OrderingKey) > 0 {
+		routing = RoutingHash
+	}
+	if conf.Threads < 0 && (routing == RoutingGreedy || routing == "") {
+		return NewAutoPool(procCtor, threads, log, stats)
+	}
+	return NewRoutedPool(procCtor, threads, routing, conf.OrderingKey, log, stats)
 }
 
 //------------------------------------------------------------------------------
@@ -41,7 +41,9 @@ func TestSanitise(t *testing.T) {
 	var err error
 
 	exp := `{` +
+		`"ordering_key":"",` +
 		`"processors":[],` +
+		`"routing":"greedy",` +
 		`"threads":10` +
 		`}`
 
@@ -60,6 +62,7 @@ func TestSanitise(t *testing.T) {
 	}
 
 	exp = `{` +
+		`"ordering_key":"",` +
 		`"processors":[` +
 		`{` +
 		`"type":"log",` +
@@ -77,6 +80,7 @@ func TestSanitise(t *testing.T) {
 		`}` +
 		`}` +
 		`],` +
+		`"routing":"greedy",` +
 		`"threads":10` +
 		`}`
 
@@ -1,12 +1,28 @@
 package pipeline
 
 import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// Routing strategies for distributing transactions across the threads of a
+// pool.
+const (
+	RoutingGreedy     = "greedy"
+	RoutingRoundRobin = "round_robin"
+	RoutingRandom     = "random"
+	RoutingHash       = "hash"
 )
 
 //------------------------------------------------------------------------------
@@ -17,10 +33,24 @@ import (
 type Pool struct {
 	running uint32
 
-	workers []types.Pipeline
+	workers       []types.Pipeline
+	workersMut    sync.Mutex
+	workersClosed bool
+
+	// When set each transaction is routed to the worker chosen by this
+	// function, and each worker reads from its own channel.
+	router      func(msg types.Message) int
+	workerChans []chan types.Transaction
+
+	// When set the pool starts with a single worker and spawns more, up to
+	// maxWorkers, whenever a transaction arrives while all workers are busy.
+	constructor types.PipelineConstructorFunc
+	maxWorkers  int
+	autoChan    chan types.Transaction
 
-	log   log.Modular
-	stats metrics.Type
+	log      log.Modular
+	stats    metrics.Type
+	mThreads metrics.StatGauge
 
 	messagesIn  <-chan types.Transaction
 	messagesOut chan types.Transaction
@@ -41,6 +71,7 @@ func NewPool(
 		workers:     make([]types.Pipeline, threads),
 		log:         log,
 		stats:       stats,
+		mThreads:    stats.GetGauge("threads"),
 		messagesOut: make(chan types.Transaction),
 		closeChan:   make(chan struct{}),
 		closed:      make(chan struct{}),
@@ -53,25 +84,204 @@ func NewPool(
 			return nil, err
 		}
 	}
+	p.mThreads.Set(int64(threads))
+
+	return p, nil
+}
+
+// NewAutoPool returns a new pipeline pool that starts with a single processor
+// thread and scales up to maxThreads according to demand. A new thread is
+// spawned whenever a transaction cannot be consumed immediately because all
+// existing threads are busy. Threads are never scaled back down.
+func NewAutoPool(
+	constructor types.PipelineConstructorFunc,
+	maxThreads int,
+	log log.Modular,
+	stats metrics.Type,
+) (*Pool, error) {
+	p, err := NewPool(constructor, 1, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	p.constructor = constructor
+	p.maxWorkers = maxThreads
+	p.autoChan = make(chan types.Transaction)
+	return p, nil
+}
+
+// NewOrderedPool returns a new pipeline pool that utilises multiple processor
+// threads, where each transaction is routed to a thread chosen by hashing an
+// interpolated key resolved from the message. Transactions that share a key are
+// therefore always processed by the same thread and in the order that they
+// were received.
+func NewOrderedPool(
+	constructor types.PipelineConstructorFunc,
+	threads int,
+	key string,
+	log log.Modular,
+	stats metrics.Type,
+) (*Pool, error) {
+	return NewRoutedPool(constructor, threads, RoutingHash, key, log, stats)
+}
+
+// NewRoutedPool returns a new pipeline pool that utilises multiple processor
+// threads, where each transaction is routed to a thread according to a routing
+// strategy. The key is only used by the hash strategy, where it is resolved as
+// an interpolated string for each message.
+//
+// With the greedy strategy transactions are consumed by whichever thread is
+// free first, which is equivalent to NewPool.
+func NewRoutedPool(
+	constructor types.PipelineConstructorFunc,
+	threads int,
+	routing string,
+	key string,
+	log log.Modular,
+	stats metrics.Type,
+) (*Pool, error) {
+	var router func(msg types.Message) int
+	switch routing {
+	case RoutingGreedy, "":
+	case RoutingRoundRobin:
+		// Only ever called from the dispatch goroutine.
+		next := 0
+		router = func(types.Message) int {
+			i := next
+			next = (next + 1) % threads
+			return i
+		}
+	case RoutingRandom:
+		router = func(types.Message) int {
+			return rand.Intn(threads)
+		}
+	case RoutingHash:
+		if len(key) == 0 {
+			return nil, fmt.Errorf("routing strategy '%v' requires a key", routing)
+		}
+		iKey := text.NewInterpolatedString(key)
+		router = func(msg types.Message) int {
+			h := fnv.New32a()
+			h.Write([]byte(iKey.Get(msg)))
+			return int(h.Sum32() % uint32(threads))
+		}
+	default:
+		return nil, fmt.Errorf("routing strategy not recognised: %v", routing)
+	}
 
+	p, err := NewPool(constructor, threads, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	if router == nil {
+		return p, nil
+	}
+	p.router = router
+	p.workerChans = make([]chan types.Transaction, threads)
+	for i := range p.workerChans {
+		p.workerChans[i] = make(chan types.Transaction)
+	}
 	return p, nil
 }
 
 //------------------------------------------------------------------------------
 
+// dispatchRouted routes transactions from the shared input channel to the
+// channels of individual workers.
+func (p *Pool) dispatchRouted() {
+	defer func() {
+		for _, c := range p.workerChans {
+			close(c)
+		}
+	}()
+	for {
+		var t types.Transaction
+		var open bool
+		select {
+		case t, open = <-p.messagesIn:
+			if !open {
+				return
+			}
+		case <-p.closeChan:
+			return
+		}
+		select {
+		case p.workerChans[p.router(t.Payload)] <- t:
+		case <-p.closeChan:
+			return
+		}
+	}
+}
+
+// dispatchAuto forwards transactions from the shared input channel to the
+// workers, spawning a new worker whenever none are ready to consume.
+func (p *Pool) dispatchAuto(start func(types.Pipeline)) {
+	defer close(p.autoChan)
+	for {
+		var t types.Transaction
+		var open bool
+		select {
+		case t, open = <-p.messagesIn:
+			if !open {
+				return
+			}
+		case <-p.closeChan:
+			return
+		}
+		select {
+		case p.autoChan <- t:
+			continue
+		default:
+		}
+		p.spawnWorker(start)
+		select {
+		case p.autoChan <- t:
+		case <-p.closeChan:
+			return
+		}
+	}
+}
+
+// spawnWorker creates and starts a new worker, unless the pool is already at
+// capacity or all of its workers have closed. The worker is started whilst
+// holding workersMut.
+func (p *Pool) spawnWorker(start func(types.Pipeline)) {
+	p.workersMut.Lock()
+	defer p.workersMut.Unlock()
+	if p.workersClosed || len(p.workers) >= p.maxWorkers {
+		return
+	}
+	procs := 0
+	worker, err := p.constructor(&procs)
+	if err != nil {
+		p.log.Errorf("Failed to create pipeline worker: %v\n", err)
+		// Avoid attempting to construct a failing worker for every message.
+		p.maxWorkers = len(p.workers)
+		return
+	}
+	p.workers = append(p.workers, worker)
+	p.mThreads.Set(int64(len(p.workers)))
+	p.log.Debugf("Scaled processing threads up to %v\n", len(p.workers))
+	start(worker)
+}
+
 // loop is the processing loop of this pipeline.
 func (p *Pool) loop() {
 	defer func() {
 		atomic.StoreUint32(&p.running, 0)
 
+		p.workersMut.Lock()
+		p.workersClosed = true
+		workers := p.workers
+		p.workersMut.Unlock()
+
 		// Signal all workers to close.
-		for _, worker := range p.workers {
+		for _, worker := range workers {
 			worker.CloseAsync()
 		}
 
 		// Wait for all workers to be closed before closing our response and
 		// messages channels as the workers may still have access to them.
-		for _, worker := range p.workers {
+		for _, worker := range workers {
 			err := worker.WaitForClose(time.Second)
 			for err != nil {
 				err = worker.WaitForClose(time.Second)
@@ -85,17 +295,27 @@ func (p *Pool) loop() {
 	internalMessages := make(chan types.Transaction)
 	remainingWorkers := int64(len(p.workers))
 
-	for _, worker := range p.workers {
-		if err := worker.Consume(p.messagesIn); err != nil {
+	// workerDone must be called whilst holding workersMut, once the last
+	// worker is done no more are spawned as internalMessages is closed.
+	workerDone := func() {
+		if atomic.AddInt64(&remainingWorkers, -1) == 0 {
+			p.workersClosed = true
+			close(internalMessages)
+		}
+	}
+
+	// startWorker must be called whilst holding workersMut.
+	startWorker := func(workerIn <-chan types.Transaction, worker types.Pipeline) {
+		if err := worker.Consume(workerIn); err != nil {
 			p.log.Errorf("Failed to start pipeline worker: %v\n", err)
-			atomic.AddInt64(&remainingWorkers, -1)
-			continue
+			workerDone()
+			return
 		}
 		go func(w types.Pipeline) {
 			defer func() {
-				if atomic.AddInt64(&remainingWorkers, -1) == 0 {
-					close(internalMessages)
-				}
+				p.workersMut.Lock()
+				workerDone()
+				p.workersMut.Unlock()
 			}()
 			for {
 				var t types.Transaction
@@ -117,6 +337,27 @@ func (p *Pool) loop() {
 		}(worker)
 	}
 
+	p.workersMut.Lock()
+	for i, worker := range p.workers {
+		workerIn := p.messagesIn
+		if p.workerChans != nil {
+			workerIn = p.workerChans[i]
+		} else if p.autoChan != nil {
+			workerIn = p.autoChan
+		}
+		startWorker(workerIn, worker)
+	}
+	p.workersMut.Unlock()
+	if p.workerChans != nil {
+		go p.dispatchRouted()
+	}
+	if p.autoChan != nil {
+		go p.dispatchAuto(func(w types.Pipeline) {
+			atomic.AddInt64(&remainingWorkers, 1)
+			startWorker(p.autoChan, w)
+		})
+	}
+
 	for atomic.LoadUint32(&p.running) == 1 && atomic.LoadInt64(&remainingWorkers) > 0 {
 		select {
 		case t, open := <-internalMessages:
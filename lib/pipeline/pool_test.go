@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -344,3 +345,264 @@ func TestPoolMultiNaturalClose(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type delayedProc struct{}
+
+func (d delayedProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	// Vary processing time in order to encourage reordering across threads.
+	seq, _ := strconv.Atoi(msg.Get(0).Metadata().Get("seq"))
+	time.Sleep(time.Duration(seq%3) * time.Millisecond)
+	return []types.Message{msg}, nil
+}
+
+func (d delayedProc) CloseAsync() {}
+
+func (d delayedProc) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestPoolOrdered(t *testing.T) {
+	nThreads, nKeys, nMsgs := 4, 5, 200
+
+	constr := func(i *int) (types.Pipeline, error) {
+		return NewProcessor(
+			log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+			metrics.DudType{},
+			delayedProc{},
+		), nil
+	}
+
+	proc, err := NewOrderedPool(
+		constr, nThreads, "${!metadata:key}",
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response, nMsgs)
+	if err := proc.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for i := 0; i < nMsgs; i++ {
+			msg := message.New([][]byte{[]byte("foo")})
+			msg.Get(0).Metadata().
+				Set("key", fmt.Sprintf("key%v", i%nKeys)).
+				Set("seq", strconv.Itoa(i))
+			select {
+			case tChan <- types.NewTransaction(msg, resChan):
+			case <-time.After(time.Second * 5):
+				t.Error("Timed out")
+				return
+			}
+		}
+	}()
+
+	lastSeqs := map[string]int{}
+	for i := 0; i < nMsgs; i++ {
+		select {
+		case procT, open := <-proc.TransactionChan():
+			if !open {
+				t.Fatal("Closed early")
+			}
+			meta := procT.Payload.Get(0).Metadata()
+			key := meta.Get("key")
+			seq, _ := strconv.Atoi(meta.Get("seq"))
+			if last, exists := lastSeqs[key]; exists && last > seq {
+				t.Errorf("Message out of order for key %v: %v received after %v", key, seq, last)
+			}
+			lastSeqs[key] = seq
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+	}
+	if exp, act := nKeys, len(lastSeqs); exp != act {
+		t.Errorf("Wrong count of keys: %v != %v", act, exp)
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+type workerTagProc struct {
+	id int
+}
+
+func (w workerTagProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	msg.Get(0).Metadata().Set("worker", strconv.Itoa(w.id))
+	return []types.Message{msg}, nil
+}
+
+func (w workerTagProc) CloseAsync() {}
+
+func (w workerTagProc) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	nThreads, nMsgs := 3, 9
+
+	workers := 0
+	constr := func(i *int) (types.Pipeline, error) {
+		proc := workerTagProc{id: workers}
+		workers++
+		return NewProcessor(
+			log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+			metrics.DudType{},
+			proc,
+		), nil
+	}
+
+	proc, err := NewRoutedPool(
+		constr, nThreads, RoutingRoundRobin, "",
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response, nMsgs)
+	if err := proc.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < nMsgs; i++ {
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+		select {
+		case procT, open := <-proc.TransactionChan():
+			if !open {
+				t.Fatal("Closed early")
+			}
+			if exp, act := strconv.Itoa(i%nThreads), procT.Payload.Get(0).Metadata().Get("worker"); exp != act {
+				t.Errorf("Wrong worker for message %v: %v != %v", i, act, exp)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+type blockingTagProc struct {
+	id      int
+	started chan<- struct{}
+	release <-chan struct{}
+}
+
+func (b blockingTagProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	msg.Get(0).Metadata().Set("worker", strconv.Itoa(b.id))
+	b.started <- struct{}{}
+	<-b.release
+	return []types.Message{msg}, nil
+}
+
+func (b blockingTagProc) CloseAsync() {}
+
+func (b blockingTagProc) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestPoolAuto(t *testing.T) {
+	nThreads := 3
+	started, release := make(chan struct{}), make(chan struct{})
+
+	workers := 0
+	constr := func(i *int) (types.Pipeline, error) {
+		proc := blockingTagProc{id: workers, started: started, release: release}
+		workers++
+		return NewProcessor(
+			log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+			metrics.DudType{},
+			proc,
+		), nil
+	}
+
+	proc, err := NewAutoPool(
+		constr, nThreads,
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response, nThreads)
+	if err := proc.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each worker blocks on its first message, and therefore every message
+	// requires a new worker to be spawned.
+	for i := 0; i < nThreads; i++ {
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+		select {
+		case <-started:
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+	}
+	close(release)
+
+	seen := map[string]struct{}{}
+	for i := 0; i < nThreads; i++ {
+		select {
+		case procT, open := <-proc.TransactionChan():
+			if !open {
+				t.Fatal("Closed early")
+			}
+			seen[procT.Payload.Get(0).Metadata().Get("worker")] = struct{}{}
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+	}
+	if exp, act := nThreads, len(seen); exp != act {
+		t.Errorf("Wrong count of workers used: %v != %v", act, exp)
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPoolBadRouting(t *testing.T) {
+	constr := func(i *int) (types.Pipeline, error) {
+		return NewProcessor(
+			log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+			metrics.DudType{},
+		), nil
+	}
+
+	if _, err := NewRoutedPool(
+		constr, 2, "nope", "",
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	); err == nil {
+		t.Error("Expected error from unrecognised routing strategy")
+	}
+	if _, err := NewRoutedPool(
+		constr, 2, RoutingHash, "",
+		log.New(os.Stdout, log.Config{LogLevel: "NONE"}),
+		metrics.DudType{},
+	); err == nil {
+		t.Error("Expected error from hash routing without a key")
+	}
+}
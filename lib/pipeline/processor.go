@@ -118,8 +118,10 @@ func (p *Processor) loop() {
 		if len(resultMsgs) > 1 {
 			p.dispatchMessages(resultMsgs, tran.ResponseChan)
 		} else {
+			outTran := types.NewTransaction(resultMsgs[0], tran.ResponseChan)
+			outTran.Prepare = tran.Prepare
 			select {
-			case p.messagesOut <- types.NewTransaction(resultMsgs[0], tran.ResponseChan):
+			case p.messagesOut <- outTran:
 			case <-p.closeChan:
 				return
 			}
@@ -24,7 +24,9 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -35,6 +37,7 @@ import (
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/klauspost/compress/zstd"
 	olog "github.com/opentracing/opentracing-go/log"
 )
 
@@ -46,7 +49,10 @@ func init() {
 		description: `
 Archives all the messages of a batch into a single message according to the
 selected archive format. Supported archive formats are:
-` + "`tar`, `zip`, `binary`, `lines` and `json_array`." + `
+` + "`tar`, `tar.gz`, `tar.zst`, `zip`, `binary`, `lines` and `json_array`." + `
+
+The formats ` + "`tar.gz`" + ` and ` + "`tar.zst`" + ` produce a tar archive
+compressed with gzip and zstd respectively.
 
 Some archive formats (such as tar, zip) treat each archive item (message part)
 as a file with a path. Since message parts only contain raw data a unique path
@@ -55,6 +61,10 @@ interpolations on the 'path' field as described
 [here](../config_interpolation.md#functions). For types that aren't file based
 (such as binary) the file field is ignored.
 
+When archiving messages that were previously extracted with the
+` + "[`unarchive`](#unarchive)" + ` processor the original file names can be
+preserved by setting the path to ` + "`${!metadata:archive_filename}`" + `.
+
 The ` + "`json_array`" + ` format attempts to JSON parse each message and append
 the result to an array, which becomes the contents of the resulting message.
 
@@ -85,9 +95,8 @@ type archiveFunc func(hFunc headerFunc, msg types.Message) (types.Part, error)
 
 type headerFunc func(index int, body types.Part) os.FileInfo
 
-func tarArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
-	buf := &bytes.Buffer{}
-	tw := tar.NewWriter(buf)
+func writeTar(w io.Writer, hFunc headerFunc, msg types.Message) error {
+	tw := tar.NewWriter(w)
 
 	// Iterate through the parts of the message.
 	err := msg.Iter(func(i int, part types.Part) error {
@@ -103,8 +112,47 @@ func tarArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 		}
 		return nil
 	})
-	tw.Close()
+	if cerr := tw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func tarArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
+	buf := &bytes.Buffer{}
+	if err := writeTar(buf, hFunc, msg); err != nil {
+		return nil, err
+	}
+	newPart := msg.Get(0).Copy()
+	newPart.Set(buf.Bytes())
+	return newPart, nil
+}
 
+func tarGzipArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	err := writeTar(gw, hFunc, msg)
+	if cerr := gw.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	newPart := msg.Get(0).Copy()
+	newPart.Set(buf.Bytes())
+	return newPart, nil
+}
+
+func tarZstdArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
+	buf := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	err = writeTar(zw, hFunc, msg)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +236,10 @@ func strToArchiver(str string) (archiveFunc, error) {
 	switch str {
 	case "tar":
 		return tarArchive, nil
+	case "tar.gz":
+		return tarGzipArchive, nil
+	case "tar.zst":
+		return tarZstdArchive, nil
 	case "zip":
 		return zipArchive, nil
 	case "binary":
@@ -310,7 +362,7 @@ func (d *Archive) ProcessMessage(msg types.Message) ([]types.Message, types.Resp
 	newPart, err := d.archive(d.createHeaderFunc(msg), msg)
 	if err != nil {
 		newMsg.Iter(func(i int, p types.Part) error {
-			FlagFail(p)
+			FlagErr(p, err)
 			spans[i].LogFields(
 				olog.String("event", "error"),
 				olog.String("type", err.Error()),
@@ -171,6 +171,65 @@ func TestArchiveZip(t *testing.T) {
 	}
 }
 
+func TestArchiveCompressedTarRoundTrip(t *testing.T) {
+	for _, format := range []string{"tar.gz", "tar.zst"} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			archConf := NewConfig()
+			archConf.Archive.Format = format
+			archConf.Archive.Path = "${!metadata:archive_filename}"
+
+			unarchConf := NewConfig()
+			unarchConf.Unarchive.Format = format
+
+			arch, err := NewArchive(archConf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				t.Fatal(err)
+			}
+			unarch, err := NewUnarchive(unarchConf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exp := [][]byte{
+				[]byte("hello world first part"),
+				[]byte("hello world second part"),
+				[]byte("third part"),
+			}
+			expNames := []string{"foo/a.txt", "foo/b.txt", "bar/c.txt"}
+
+			inMsg := message.New(exp)
+			inMsg.Iter(func(i int, p types.Part) error {
+				p.Metadata().Set("archive_filename", expNames[i])
+				return nil
+			})
+
+			msgs, res := arch.ProcessMessage(inMsg)
+			if len(msgs) != 1 {
+				t.Fatalf("Archive failed: %v", res)
+			}
+			if msgs[0].Len() != 1 {
+				t.Fatal("Archive produced wrong number of parts")
+			}
+			if HasFailed(msgs[0].Get(0)) {
+				t.Fatal("Archive failed")
+			}
+
+			if msgs, res = unarch.ProcessMessage(msgs[0]); len(msgs) != 1 {
+				t.Fatalf("Unarchive failed: %v", res)
+			}
+			if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+				t.Errorf("Unexpected output: %s != %s", act, exp)
+			}
+			for i := 0; i < msgs[0].Len(); i++ {
+				if name := msgs[0].Get(i).Metadata().Get("archive_filename"); name != expNames[i] {
+					t.Errorf("Unexpected name %d: %s != %s", i, name, expNames[i])
+				}
+			}
+		})
+	}
+}
+
 func TestArchiveLines(t *testing.T) {
 	conf := NewConfig()
 	conf.Archive.Format = "lines"
@@ -21,10 +21,12 @@
 package processor
 
 import (
-	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -37,7 +39,35 @@ func init() {
 		constructor: NewBoundsCheck,
 		description: `
 Checks whether each message batch fits within certain boundaries, and drops
-batches that do not.`,
+batches that do not.
+
+The field ` + "`max_batch_size`" + ` sets a limit on the total number of bytes
+within a batch, and is disabled when set to zero.
+
+### Modes
+
+The ` + "`mode`" + ` field determines how batches that violate these
+boundaries are handled, and can be one of the following values:
+
+#### ` + "`drop`" + `
+
+Batches that violate any boundary are dropped entirely.
+
+#### ` + "`truncate`" + `
+
+Parts that exceed ` + "`max_part_size`" + ` are truncated to that size, batches
+that exceed ` + "`max_parts`" + ` have their trailing parts removed, and batches
+that exceed ` + "`max_batch_size`" + ` have their trailing bytes removed, which
+may remove or truncate parts. Boundaries that cannot be satisfied by truncating,
+such as ` + "`min_parts`" + ` and ` + "`min_part_size`" + `, result in the batch
+being dropped.
+
+#### ` + "`fail`" + `
+
+Parts that violate a boundary, or all parts of a batch that violates a batch
+wide boundary, are flagged as having failed and are sent onwards unchanged.
+These messages can then be handled with
+[error handling patterns](../error_handling.md).`,
 	}
 }
 
@@ -46,19 +76,23 @@ batches that do not.`,
 // BoundsCheckConfig contains configuration fields for the BoundsCheck
 // processor.
 type BoundsCheckConfig struct {
-	MaxParts    int `json:"max_parts" yaml:"max_parts"`
-	MinParts    int `json:"min_parts" yaml:"min_parts"`
-	MaxPartSize int `json:"max_part_size" yaml:"max_part_size"`
-	MinPartSize int `json:"min_part_size" yaml:"min_part_size"`
+	MaxParts     int    `json:"max_parts" yaml:"max_parts"`
+	MinParts     int    `json:"min_parts" yaml:"min_parts"`
+	MaxPartSize  int    `json:"max_part_size" yaml:"max_part_size"`
+	MinPartSize  int    `json:"min_part_size" yaml:"min_part_size"`
+	MaxBatchSize int    `json:"max_batch_size" yaml:"max_batch_size"`
+	Mode         string `json:"mode" yaml:"mode"`
 }
 
 // NewBoundsCheckConfig returns a BoundsCheckConfig with default values.
 func NewBoundsCheckConfig() BoundsCheckConfig {
 	return BoundsCheckConfig{
-		MaxParts:    100,
-		MinParts:    1,
-		MaxPartSize: 1 * 1024 * 1024 * 1024, // 1GB
-		MinPartSize: 1,
+		MaxParts:     100,
+		MinParts:     1,
+		MaxPartSize:  1 * 1024 * 1024 * 1024, // 1GB
+		MinPartSize:  1,
+		MaxBatchSize: 0,
+		Mode:         "drop",
 	}
 }
 
@@ -67,90 +101,196 @@ func NewBoundsCheckConfig() BoundsCheckConfig {
 // BoundsCheck is a processor that checks each message against a set of bounds
 // and rejects messages if they aren't within them.
 type BoundsCheck struct {
-	conf  Config
-	log   log.Modular
-	stats metrics.Type
-
-	mCount           metrics.StatCounter
-	mDropped         metrics.StatCounter
-	mDroppedEmpty    metrics.StatCounter
-	mDroppedNumParts metrics.StatCounter
-	mDroppedPartSize metrics.StatCounter
-	mSent            metrics.StatCounter
-	mBatchSent       metrics.StatCounter
+	conf     Config
+	mgr      types.Manager
+	log      log.Modular
+	stats    metrics.Type
+	truncate bool
+	fail     bool
+
+	mCount            metrics.StatCounter
+	mDropped          metrics.StatCounter
+	mDroppedEmpty     metrics.StatCounter
+	mDroppedNumParts  metrics.StatCounter
+	mDroppedPartSize  metrics.StatCounter
+	mDroppedBatchSize metrics.StatCounter
+	mTruncated        metrics.StatCounter
+	mFailed           metrics.StatCounter
+	mSent             metrics.StatCounter
+	mBatchSent        metrics.StatCounter
 }
 
 // NewBoundsCheck returns a BoundsCheck processor.
 func NewBoundsCheck(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	return &BoundsCheck{
+	b := &BoundsCheck{
 		conf:  conf,
+		mgr:   mgr,
 		log:   log,
 		stats: stats,
 
-		mCount:           stats.GetCounter("count"),
-		mDropped:         stats.GetCounter("dropped"),
-		mDroppedEmpty:    stats.GetCounter("dropped_empty"),
-		mDroppedNumParts: stats.GetCounter("dropped_num_parts"),
-		mDroppedPartSize: stats.GetCounter("dropped_part_size"),
-		mSent:            stats.GetCounter("sent"),
-		mBatchSent:       stats.GetCounter("batch.sent"),
-	}, nil
+		mCount:            stats.GetCounter("count"),
+		mDropped:          stats.GetCounter("dropped"),
+		mDroppedEmpty:     stats.GetCounter("dropped_empty"),
+		mDroppedNumParts:  stats.GetCounter("dropped_num_parts"),
+		mDroppedPartSize:  stats.GetCounter("dropped_part_size"),
+		mDroppedBatchSize: stats.GetCounter("dropped_batch_size"),
+		mTruncated:        stats.GetCounter("truncated"),
+		mFailed:           stats.GetCounter("failed"),
+		mSent:             stats.GetCounter("sent"),
+		mBatchSent:        stats.GetCounter("batch.sent"),
+	}
+	switch conf.BoundsCheck.Mode {
+	case "drop":
+	case "truncate":
+		b.truncate = true
+	case "fail":
+		b.fail = true
+	default:
+		return nil, fmt.Errorf("bounds check mode not recognised: %v", conf.BoundsCheck.Mode)
+	}
+	return b, nil
 }
 
 //------------------------------------------------------------------------------
 
+// reject either drops a batch that has violated a boundary or, when in fail
+// mode, flags all parts of the batch as having failed and returns it.
+func (m *BoundsCheck) reject(
+	msg types.Message, dropCounter metrics.StatCounter, err error,
+) ([]types.Message, types.Response) {
+	m.log.Debugf("Rejecting message: %v\n", err)
+	if m.fail {
+		m.mFailed.Incr(int64(msg.Len()))
+		newMsg := msg.Copy()
+		newMsg.Iter(func(i int, p types.Part) error {
+			FlagErr(p, err)
+			return nil
+		})
+		return m.send(newMsg)
+	}
+	m.mDropped.Incr(1)
+	dropCounter.Incr(1)
+	drop.Report(m.mgr, TypeBoundsCheck, msg)
+	return nil, response.NewAck()
+}
+
+func (m *BoundsCheck) send(msg types.Message) ([]types.Message, types.Response) {
+	m.mBatchSent.Incr(1)
+	m.mSent.Incr(int64(msg.Len()))
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (m *BoundsCheck) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	m.mCount.Incr(1)
 
+	bConf := m.conf.BoundsCheck
+
 	lParts := msg.Len()
-	if lParts < m.conf.BoundsCheck.MinParts {
-		m.log.Debugf(
-			"Rejecting message due to message parts below minimum (%v): %v\n",
-			m.conf.BoundsCheck.MinParts, lParts,
-		)
-		m.mDropped.Incr(1)
-		m.mDroppedEmpty.Incr(1)
-		return nil, response.NewAck()
-	} else if lParts > m.conf.BoundsCheck.MaxParts {
-		m.log.Debugf(
-			"Rejecting message due to message parts exceeding limit (%v): %v\n",
-			m.conf.BoundsCheck.MaxParts, lParts,
-		)
-		m.mDropped.Incr(1)
-		m.mDroppedNumParts.Incr(1)
-		return nil, response.NewAck()
+	if lParts < bConf.MinParts {
+		return m.reject(msg, m.mDroppedEmpty, fmt.Errorf(
+			"message parts below minimum (%v): %v", bConf.MinParts, lParts,
+		))
 	}
 
-	var reject bool
+	var truncated bool
+	if lParts > bConf.MaxParts {
+		if !m.truncate {
+			return m.reject(msg, m.mDroppedNumParts, fmt.Errorf(
+				"message parts exceeding limit (%v): %v", bConf.MaxParts, lParts,
+			))
+		}
+		parts := make([]types.Part, 0, bConf.MaxParts)
+		msg.Iter(func(i int, p types.Part) error {
+			if i < bConf.MaxParts {
+				parts = append(parts, p)
+			}
+			return nil
+		})
+		newMsg := message.New(nil)
+		newMsg.SetAll(parts)
+		msg = newMsg
+		truncated = true
+	}
+
+	var sizeErr error
+	var batchSize int
+	var flagged bool
+	parts := make([]types.Part, msg.Len())
 	msg.Iter(func(i int, p types.Part) error {
-		if size := len(p.Get()); size > m.conf.BoundsCheck.MaxPartSize ||
-			size < m.conf.BoundsCheck.MinPartSize {
-			m.log.Debugf(
-				"Rejecting message due to message part size (%v -> %v): %v\n",
-				m.conf.BoundsCheck.MinPartSize,
-				m.conf.BoundsCheck.MaxPartSize,
-				size,
-			)
-			reject = true
-			return errors.New("exit")
+		parts[i] = p
+		size := len(p.Get())
+		if size < bConf.MinPartSize {
+			err := fmt.Errorf("message part size below minimum (%v): %v", bConf.MinPartSize, size)
+			if !m.fail {
+				sizeErr = err
+				return err
+			}
+			m.mFailed.Incr(1)
+			parts[i] = p.Copy()
+			FlagErr(parts[i], err)
+			flagged = true
+		} else if size > bConf.MaxPartSize {
+			err := fmt.Errorf("message part size exceeding limit (%v): %v", bConf.MaxPartSize, size)
+			if m.truncate {
+				parts[i] = p.Copy()
+				parts[i].Set(p.Get()[:bConf.MaxPartSize])
+				size = bConf.MaxPartSize
+				truncated = true
+			} else if m.fail {
+				m.mFailed.Incr(1)
+				parts[i] = p.Copy()
+				FlagErr(parts[i], err)
+				flagged = true
+			} else {
+				sizeErr = err
+				return err
+			}
 		}
+		batchSize += size
 		return nil
 	})
+	if sizeErr != nil {
+		return m.reject(msg, m.mDroppedPartSize, sizeErr)
+	}
 
-	if reject {
-		m.mDropped.Incr(1)
-		m.mDroppedPartSize.Incr(1)
-		return nil, response.NewAck()
+	if bConf.MaxBatchSize > 0 && batchSize > bConf.MaxBatchSize {
+		if !m.truncate {
+			return m.reject(msg, m.mDroppedBatchSize, fmt.Errorf(
+				"message batch size exceeding limit (%v): %v", bConf.MaxBatchSize, batchSize,
+			))
+		}
+		remaining := bConf.MaxBatchSize
+		for i, p := range parts {
+			size := len(p.Get())
+			if size > remaining {
+				if remaining == 0 {
+					parts = parts[:i]
+					break
+				}
+				parts[i] = p.Copy()
+				parts[i].Set(p.Get()[:remaining])
+				parts = parts[:i+1]
+				break
+			}
+			remaining -= size
+		}
+		truncated = true
 	}
 
-	m.mBatchSent.Incr(1)
-	m.mSent.Incr(int64(msg.Len()))
-	msgs := [1]types.Message{msg}
-	return msgs[:], nil
+	if truncated {
+		m.mTruncated.Incr(1)
+	}
+	if truncated || flagged {
+		newMsg := message.New(nil)
+		newMsg.SetAll(parts)
+		msg = newMsg
+	}
+	return m.send(msg)
 }
 
 // CloseAsync shuts down the processor and stops processing requests.
@@ -106,3 +106,190 @@ func TestBoundsCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestBoundsCheckBadMode(t *testing.T) {
+	conf := NewConfig()
+	conf.BoundsCheck.Mode = "nope"
+
+	if _, err := NewBoundsCheck(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+}
+
+func TestBoundsCheckBatchSize(t *testing.T) {
+	conf := NewConfig()
+	conf.BoundsCheck.MaxBatchSize = 10
+
+	proc, err := NewBoundsCheck(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte("hello"),
+		[]byte("world"),
+	})
+	if msgs, _ := proc.ProcessMessage(msg); len(msgs) == 0 {
+		t.Error("Bounds check failed on batch within size")
+	}
+
+	msg = message.New([][]byte{
+		[]byte("hello"),
+		[]byte("world"),
+		[]byte("!"),
+	})
+	if msgs, res := proc.ProcessMessage(msg); len(msgs) > 0 {
+		t.Error("Bounds check didnt fail on batch exceeding size")
+	} else if _, ok := res.(response.Ack); !ok {
+		t.Error("Expected simple response from bad message")
+	}
+}
+
+func TestBoundsCheckTruncate(t *testing.T) {
+	conf := NewConfig()
+	conf.BoundsCheck.Mode = "truncate"
+	conf.BoundsCheck.MaxParts = 3
+	conf.BoundsCheck.MaxPartSize = 5
+	conf.BoundsCheck.MaxBatchSize = 12
+
+	proc, err := NewBoundsCheck(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input  [][]byte
+		output [][]byte
+	}{
+		{
+			input: [][]byte{
+				[]byte("hello"),
+				[]byte("world"),
+			},
+			output: [][]byte{
+				[]byte("hello"),
+				[]byte("world"),
+			},
+		},
+		{
+			input: [][]byte{
+				[]byte("hello world"),
+				[]byte("foo"),
+			},
+			output: [][]byte{
+				[]byte("hello"),
+				[]byte("foo"),
+			},
+		},
+		{
+			input: [][]byte{
+				[]byte("a"),
+				[]byte("b"),
+				[]byte("c"),
+				[]byte("d"),
+			},
+			output: [][]byte{
+				[]byte("a"),
+				[]byte("b"),
+				[]byte("c"),
+			},
+		},
+		{
+			input: [][]byte{
+				[]byte("hello"),
+				[]byte("world"),
+				[]byte("foobar"),
+			},
+			output: [][]byte{
+				[]byte("hello"),
+				[]byte("world"),
+				[]byte("fo"),
+			},
+		},
+	}
+
+	for i, test := range tests {
+		input := message.New(test.input)
+		msgs, res := proc.ProcessMessage(input)
+		if res != nil {
+			t.Fatalf("Test %v: unexpected response: %v", i, res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Test %v: wrong count of messages: %v", i, len(msgs))
+		}
+		if exp, act := test.output, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Test %v: wrong result: %s != %s", i, act, exp)
+		}
+		if exp, act := test.input, message.GetAllBytes(input); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Test %v: input message was modified: %s != %s", i, act, exp)
+		}
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("hello"),
+		[]byte(""),
+	}))
+	if len(msgs) > 0 {
+		t.Error("Bounds check didnt fail on part below minimum size")
+	} else if _, ok := res.(response.Ack); !ok {
+		t.Error("Expected simple response from bad message")
+	}
+}
+
+func TestBoundsCheckFail(t *testing.T) {
+	conf := NewConfig()
+	conf.BoundsCheck.Mode = "fail"
+	conf.BoundsCheck.MaxParts = 3
+	conf.BoundsCheck.MaxPartSize = 5
+
+	proc, err := NewBoundsCheck(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte("hello"),
+		[]byte("hello world"),
+		[]byte(""),
+	})
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	for i, exp := range []bool{false, true, true} {
+		if act := HasFailed(msgs[0].Get(i)); act != exp {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+		if HasFailed(input.Get(i)) {
+			t.Errorf("Input part %v was flagged as failed", i)
+		}
+	}
+
+	input = message.New([][]byte{
+		[]byte("a"),
+		[]byte("b"),
+		[]byte("c"),
+		[]byte("d"),
+	})
+	msgs, res = proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if exp, act := 4, msgs[0].Len(); exp != act {
+		t.Errorf("Wrong count of parts: %v != %v", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if !HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged as failed", i)
+		}
+		if HasFailed(input.Get(i)) {
+			t.Errorf("Input part %v was flagged as failed", i)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeBranch] = TypeSpec{
+		constructor: NewBranch,
+		description: `
+Maps a subset of each message into a new document with ` + "`request_map`" + `,
+executes a list of child processors on the resulting batch, and then maps the
+results back into the original messages with ` + "`result_map`" + `.
+
+Both maps are made of dot path keys and values, where for the
+` + "`request_map`" + ` the keys are paths of the new request document and the
+values are paths of the original message, and for the ` + "`result_map`" + `
+the keys are paths of the original message and the values are paths of the
+result. The path ` + "`.`" + ` refers to the root of a document. Parent paths
+are always mapped before their children.
+
+For example, in order to send the field ` + "`user.id`" + ` to an HTTP service
+and place the ` + "`name`" + ` field of the response at ` + "`user.name`" + `:
+
+` + "``` yaml" + `
+branch:
+  request_map:
+    id: user.id
+  processors:
+  - http:
+      request:
+        url: http://users/lookup
+  result_map:
+    user.name: name
+` + "```" + `
+
+If ` + "`request_map`" + ` is empty the child processors receive a copy of the
+whole message. If ` + "`result_map`" + ` is empty the results of the child
+processors are discarded and the original messages are left unchanged, which is
+useful for processors that are executed only for their side effects. When the
+result is not valid JSON it can still be mapped using the source path
+` + "`.`" + `, in which case it is set as a string.
+
+### Error Handling
+
+Unlike ` + "`process_map`" + ` and ` + "`process_field`" + `, any message that
+cannot be branched is flagged as failed with an error describing the reason and
+left unchanged, where the reason is one of:
+
+- A ` + "`request_map`" + ` value path was not found within the message, or the
+  message was not valid JSON.
+- The child processors flagged the request as failed or returned an error.
+- The child processors resulted in a different number of messages than were
+  sent to them while a ` + "`result_map`" + ` is set.
+- A ` + "`result_map`" + ` value path was not found within the result.
+
+The result is only mapped back into a message once all of its mappings have
+succeeded, therefore messages are never partially modified. Metadata set by the
+child processors is copied into the original message along with the result.
+
+You can read about how to handle failed messages
+[here](../error_handling.md).`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			procConfs := make([]interface{}, len(conf.Branch.Processors))
+			for i, pConf := range conf.Branch.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"parts":       conf.Branch.Parts,
+				"request_map": conf.Branch.RequestMap,
+				"processors":  procConfs,
+				"result_map":  conf.Branch.ResultMap,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// BranchConfig is a config struct containing fields for the Branch processor.
+type BranchConfig struct {
+	Parts      []int             `json:"parts" yaml:"parts"`
+	RequestMap map[string]string `json:"request_map" yaml:"request_map"`
+	Processors []Config          `json:"processors" yaml:"processors"`
+	ResultMap  map[string]string `json:"result_map" yaml:"result_map"`
+}
+
+// NewBranchConfig returns a default BranchConfig.
+func NewBranchConfig() BranchConfig {
+	return BranchConfig{
+		Parts:      []int{},
+		RequestMap: map[string]string{},
+		Processors: []Config{},
+		ResultMap:  map[string]string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// branchMapping is a single dot path mapping from a source document to a
+// destination document.
+type branchMapping struct {
+	dest []string
+	src  []string
+}
+
+func newBranchMappings(m map[string]string) []branchMapping {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Sorting ensures parent paths are mapped before their children.
+	sort.Strings(keys)
+
+	mappings := make([]branchMapping, len(keys))
+	for i, k := range keys {
+		mappings[i] = branchMapping{
+			dest: branchPath(k),
+			src:  branchPath(m[k]),
+		}
+	}
+	return mappings
+}
+
+func branchPath(p string) []string {
+	if p == "." || len(p) == 0 {
+		return nil
+	}
+	return strings.Split(p, ".")
+}
+
+// applyBranchMappings creates a new document from a source document, where the
+// destination is an existing document that is modified.
+func applyBranchMappings(mappings []branchMapping, src, dest interface{}) (interface{}, error) {
+	srcObj, _ := gabs.Consume(src)
+	destObj, _ := gabs.Consume(dest)
+	for _, m := range mappings {
+		var v interface{}
+		if len(m.src) == 0 {
+			v = srcObj.Data()
+		} else if v = srcObj.S(m.src...).Data(); v == nil {
+			return nil, fmt.Errorf("target '%v' not found", strings.Join(m.src, "."))
+		}
+		v, _ = message.CopyJSON(v)
+		if len(m.dest) == 0 {
+			destObj, _ = gabs.Consume(v)
+			continue
+		}
+		if _, err := destObj.Set(v, m.dest...); err != nil {
+			return nil, fmt.Errorf("failed to set '%v': %v", strings.Join(m.dest, "."), err)
+		}
+	}
+	return destObj.Data(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// Branch is a processor that maps a subset of messages into new documents,
+// applies a list of child processors to them, and maps the results back into
+// the original messages.
+type Branch struct {
+	parts      []int
+	requestMap []branchMapping
+	resultMap  []branchMapping
+	children   []types.Processor
+
+	log log.Modular
+
+	mCount      metrics.StatCounter
+	mErr        metrics.StatCounter
+	mErrRequest metrics.StatCounter
+	mErrProc    metrics.StatCounter
+	mErrResult  metrics.StatCounter
+	mSent       metrics.StatCounter
+	mBatchSent  metrics.StatCounter
+}
+
+// NewBranch returns a Branch processor.
+func NewBranch(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var children []types.Processor
+	for i, pconf := range conf.Branch.Processors {
+		prefix := fmt.Sprintf("processor.%v", i)
+		proc, err := New(pconf, mgr, log.NewModule("."+prefix), metrics.Namespaced(stats, prefix))
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+	return &Branch{
+		parts:      conf.Branch.Parts,
+		requestMap: newBranchMappings(conf.Branch.RequestMap),
+		resultMap:  newBranchMappings(conf.Branch.ResultMap),
+		children:   children,
+
+		log: log,
+
+		mCount:      stats.GetCounter("count"),
+		mErr:        stats.GetCounter("error"),
+		mErrRequest: stats.GetCounter("error.request_map"),
+		mErrProc:    stats.GetCounter("error.processors"),
+		mErrResult:  stats.GetCounter("error.result_map"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (b *Branch) createRequest(part types.Part) (types.Part, error) {
+	reqPart := part.Copy()
+	ClearFail(reqPart)
+	if len(b.requestMap) == 0 {
+		return reqPart, nil
+	}
+
+	jObj, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	var reqObj interface{}
+	if reqObj, err = applyBranchMappings(b.requestMap, jObj, map[string]interface{}{}); err != nil {
+		return nil, fmt.Errorf("request map failed: %v", err)
+	}
+	if err = reqPart.SetJSON(reqObj); err != nil {
+		return nil, fmt.Errorf("request map failed: %v", err)
+	}
+	return reqPart, nil
+}
+
+func (b *Branch) overlayResult(part, result types.Part) error {
+	resObj, err := result.JSON()
+	if err != nil {
+		// Results that aren't JSON can only be mapped from the root.
+		for _, m := range b.resultMap {
+			if len(m.src) > 0 {
+				return fmt.Errorf("failed to parse result as JSON: %v", err)
+			}
+		}
+		resObj = string(result.Get())
+	}
+
+	var jObj interface{}
+	if jObj, err = part.JSON(); err == nil {
+		jObj, err = message.CopyJSON(jObj)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	if jObj, err = applyBranchMappings(b.resultMap, resObj, jObj); err != nil {
+		return fmt.Errorf("result map failed: %v", err)
+	}
+	if err = part.SetJSON(jObj); err != nil {
+		return fmt.Errorf("result map failed: %v", err)
+	}
+
+	partMeta := part.Metadata()
+	result.Metadata().Iter(func(k, v string) error {
+		partMeta.Set(k, v)
+		return nil
+	})
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (b *Branch) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	b.mCount.Incr(1)
+	payload := msg.Copy()
+
+	targetParts := b.parts
+	if len(targetParts) == 0 {
+		targetParts = make([]int, payload.Len())
+		for i := range targetParts {
+			targetParts[i] = i
+		}
+	}
+
+	reqMsg := message.New(nil)
+	var indexes []int
+	for _, index := range targetParts {
+		if index < 0 {
+			index = payload.Len() + index
+		}
+		if index < 0 || index >= payload.Len() {
+			continue
+		}
+		reqPart, err := b.createRequest(payload.Get(index))
+		if err != nil {
+			b.mErrRequest.Incr(1)
+			b.mErr.Incr(1)
+			b.log.Debugf("Failed to create branch request for message '%v': %v\n", index, err)
+			FlagErr(payload.Get(index), err)
+			continue
+		}
+		reqMsg.Append(reqPart)
+		indexes = append(indexes, index)
+	}
+
+	if len(indexes) > 0 {
+		b.branch(payload, reqMsg, indexes)
+	}
+
+	b.mBatchSent.Incr(1)
+	b.mSent.Incr(int64(payload.Len()))
+
+	msgs := [1]types.Message{payload}
+	return msgs[:], nil
+}
+
+// branch executes the child processors on a request batch and overlays the
+// results onto the payload parts at the given indexes.
+func (b *Branch) branch(payload, reqMsg types.Message, indexes []int) {
+	propMsg, _ := tracing.WithChildSpans(TypeBranch, reqMsg)
+	resultMsgs, res := ExecuteAll(b.children, propMsg)
+	tracing.FinishSpans(propMsg)
+
+	var resParts []types.Part
+	for _, rMsg := range resultMsgs {
+		rMsg.Iter(func(i int, p types.Part) error {
+			resParts = append(resParts, p)
+			return nil
+		})
+	}
+
+	var err error
+	if res != nil && res.Error() != nil {
+		err = fmt.Errorf("child processors failed: %v", res.Error())
+	} else if exp, act := len(indexes), len(resParts); exp != act {
+		if len(b.resultMap) == 0 {
+			// Results are discarded and can't be aligned with their origins.
+			return
+		}
+		err = fmt.Errorf("child processors resulted in %v messages, expected %v", act, exp)
+	}
+	if err != nil {
+		b.mErrProc.Incr(1)
+		b.mErr.Incr(1)
+		b.log.Errorf("Failed to process branch: %v\n", err)
+		for _, index := range indexes {
+			FlagErr(payload.Get(index), err)
+		}
+		return
+	}
+
+	for i, index := range indexes {
+		if HasFailed(resParts[i]) {
+			b.mErrProc.Incr(1)
+			b.mErr.Incr(1)
+			FlagErr(payload.Get(index), fmt.Errorf(
+				"child processors failed: %v", resParts[i].Metadata().Get(FailFlagKey),
+			))
+			continue
+		}
+		if len(b.resultMap) == 0 {
+			continue
+		}
+		if err = b.overlayResult(payload.Get(index), resParts[i]); err != nil {
+			b.mErrResult.Incr(1)
+			b.mErr.Incr(1)
+			b.log.Debugf("Failed to map branch result for message '%v': %v\n", index, err)
+			FlagErr(payload.Get(index), err)
+		}
+	}
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (b *Branch) CloseAsync() {
+	for _, c := range b.children {
+		c.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (b *Branch) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, c := range b.children {
+		if err := c.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
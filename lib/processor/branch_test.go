@@ -0,0 +1,191 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestBranchBasic(t *testing.T) {
+	procConf := NewConfig()
+	procConf.Type = TypeNumber
+	procConf.Number.Operator = "add"
+	procConf.Number.Value = 1
+
+	conf := NewConfig()
+	conf.Type = TypeBranch
+	conf.Branch.RequestMap["."] = "doc.count"
+	conf.Branch.Processors = []Config{procConf}
+	conf.Branch.ResultMap["doc.count_plus"] = "."
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"doc":{"count":5}}`),
+		[]byte(`{"doc":{"nope":5}}`),
+		[]byte(`{"doc":{"count":"nope"}}`),
+		[]byte(`not json`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte(`{"doc":{"count":5,"count_plus":6}}`),
+		[]byte(`{"doc":{"nope":5}}`),
+		[]byte(`{"doc":{"count":"nope"}}`),
+		[]byte(`not json`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{false, true, true, true} {
+		if act := HasFailed(msgs[0].Get(i)); act != exp {
+			t.Errorf("Wrong failed flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+	if exp, act := "request map failed: target 'doc.count' not found", msgs[0].Get(1).Metadata().Get(FailFlagKey); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+}
+
+func TestBranchWholeMessage(t *testing.T) {
+	procConf := NewConfig()
+	procConf.Type = TypeNoop
+
+	conf := NewConfig()
+	conf.Type = TypeBranch
+	conf.Branch.Processors = []Config{procConf}
+	conf.Branch.ResultMap["copy.foo"] = "foo"
+	conf.Branch.ResultMap["copy"] = "bar"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"foo":"foo value","bar":{"baz":"baz value"}}`),
+		[]byte(`{"foo":"foo value"}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"bar":{"baz":"baz value"},"copy":{"baz":"baz value","foo":"foo value"},"foo":"foo value"}`),
+		[]byte(`{"foo":"foo value"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "bar", msgs[0].Get(0).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Did not expect first part to fail")
+	}
+	if exp, act := "result map failed: target 'bar' not found", msgs[0].Get(1).Metadata().Get(FailFlagKey); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+}
+
+func TestBranchDiscardResult(t *testing.T) {
+	procConf := NewConfig()
+	procConf.Type = TypeNumber
+	procConf.Number.Operator = "add"
+	procConf.Number.Value = 1
+
+	conf := NewConfig()
+	conf.Type = TypeBranch
+	conf.Branch.RequestMap["."] = "count"
+	conf.Branch.Processors = []Config{procConf}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"count":5}`),
+		[]byte(`{"count":"nope"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"count":5}`),
+		[]byte(`{"count":"nope"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Did not expect first part to fail")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected second part to fail")
+	}
+}
+
+func TestBranchParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBranch
+	conf.Branch.Parts = []int{-1}
+	conf.Branch.RequestMap["value"] = "foo"
+	conf.Branch.ResultMap["bar"] = "value"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"first"}`),
+		[]byte(`{"foo":"second"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"foo":"first"}`),
+		[]byte(`{"bar":"second","foo":"second"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
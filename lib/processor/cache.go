@@ -21,7 +21,9 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -41,7 +43,7 @@ func init() {
 Performs operations against a [cache resource](../caches) for each message of a
 batch, allowing you to store or retrieve data within message payloads.
 
-This processor will interpolate functions within the ` + "`key` and `value`" + `
+This processor will interpolate functions within the ` + "`key`, `value` and `expected`" + `
 fields individually for each message of the batch. This allows you to specify
 dynamic keys and values based on the contents of the message payloads and
 metadata. You can find a list of functions
@@ -66,6 +68,35 @@ Retrieve the contents of a cached key and replace the original message payload
 with the result. If the key does not exist the action fails with an error, which
 can be detected with [processor error handling](../error_handling.md).
 
+#### ` + "`incr`" + `
+
+Atomically increment the integer value of a key by the ` + "`value`" + `, or by
+one if the value is empty, and replace the original message payload with the
+result. A key that does not exist is treated as zero. Only supported by caches
+that provide atomic counters, currently ` + "`memory` and `redis`" + `.
+
+#### ` + "`decr`" + `
+
+Atomically decrement the integer value of a key by the ` + "`value`" + `, or by
+one if the value is empty, and replace the original message payload with the
+result. Only supported by caches that provide atomic counters, currently
+` + "`memory` and `redis`" + `.
+
+#### ` + "`cas`" + `
+
+Set a key in the cache to a value only if its current value is equal to the
+` + "`expected`" + ` field. If the key does not exist, or its value has changed,
+the action fails with an error which can be detected with
+[processor error handling](../error_handling.md). Only supported by caches that
+provide compare-and-set, currently ` + "`memory` and `redis`" + `.
+
+### TTL
+
+The field ` + "`ttl`" + ` overrides the expiration of the cache for keys
+written by the ` + "`set`, `add` and `cas`" + ` operators, and when empty the
+default of the cache is used. Only the ` + "`memory` and `redis`" + ` caches
+support overriding the expiration.
+
 ### Examples
 
 The ` + "`cache`" + ` processor can be used in combination with other processors
@@ -103,6 +134,38 @@ using the [` + "`process_dag`" + `](#process_dag) processor:
         key: "${!json_field:message.document_id}"
     postmap:
       message.document: .
+` + "```" + `
+
+#### Sequence Numbers
+
+A sequence number can be added to each message by incrementing a counter and
+mapping the result into the document:
+
+` + "``` yaml" + `
+- process_map:
+    processors:
+    - cache:
+        cache: TODO
+        operator: incr
+        key: "sequence:${!json_field:stream}"
+    postmap:
+      sequence: .
+` + "```" + `
+
+#### Idempotency Tokens
+
+An idempotency token can be claimed with the add operator and a ttl, such that
+duplicates within that window fail and can be removed:
+
+` + "``` yaml" + `
+- cache:
+    cache: TODO
+    operator: add
+    key: "token:${!json_field:request_id}"
+    value: "claimed"
+    ttl: 1h
+- filter_parts:
+    type: processor_failed
 ` + "```" + ``,
 	}
 }
@@ -116,6 +179,8 @@ type CacheConfig struct {
 	Operator string `json:"operator" yaml:"operator"`
 	Key      string `json:"key" yaml:"key"`
 	Value    string `json:"value" yaml:"value"`
+	Expected string `json:"expected" yaml:"expected"`
+	TTL      string `json:"ttl" yaml:"ttl"`
 }
 
 // NewCacheConfig returns a CacheConfig with default values.
@@ -126,6 +191,8 @@ func NewCacheConfig() CacheConfig {
 		Operator: "set",
 		Key:      "",
 		Value:    "",
+		Expected: "",
+		TTL:      "",
 	}
 }
 
@@ -140,8 +207,9 @@ type Cache struct {
 
 	parts []int
 
-	key   *text.InterpolatedString
-	value *text.InterpolatedBytes
+	key      *text.InterpolatedString
+	value    *text.InterpolatedBytes
+	expected *text.InterpolatedBytes
 
 	cache    types.Cache
 	operator cacheOperator
@@ -149,6 +217,7 @@ type Cache struct {
 	mCount            metrics.StatCounter
 	mErr              metrics.StatCounter
 	mKeyAlreadyExists metrics.StatCounter
+	mValueMismatch    metrics.StatCounter
 	mSent             metrics.StatCounter
 	mBatchSent        metrics.StatCounter
 }
@@ -162,9 +231,16 @@ func NewCache(
 		return nil, err
 	}
 
-	op, err := cacheOperatorFromString(conf.Cache.Operator, c)
+	var ttl time.Duration
+	if len(conf.Cache.TTL) > 0 {
+		if ttl, err = time.ParseDuration(conf.Cache.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %v", err)
+		}
+	}
+
+	op, err := cacheOperatorFromString(conf.Cache.Operator, c, ttl)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("cache '%v': %v", conf.Cache.Cache, err)
 	}
 
 	return &Cache{
@@ -174,8 +250,9 @@ func NewCache(
 
 		parts: conf.Cache.Parts,
 
-		key:   text.NewInterpolatedString(conf.Cache.Key),
-		value: text.NewInterpolatedBytes([]byte(conf.Cache.Value)),
+		key:      text.NewInterpolatedString(conf.Cache.Key),
+		value:    text.NewInterpolatedBytes([]byte(conf.Cache.Value)),
+		expected: text.NewInterpolatedBytes([]byte(conf.Cache.Expected)),
 
 		cache:    c,
 		operator: op,
@@ -183,6 +260,7 @@ func NewCache(
 		mCount:            stats.GetCounter("count"),
 		mErr:              stats.GetCounter("error"),
 		mKeyAlreadyExists: stats.GetCounter("key_already_exists"),
+		mValueMismatch:    stats.GetCounter("value_mismatch"),
 		mSent:             stats.GetCounter("sent"),
 		mBatchSent:        stats.GetCounter("batch.sent"),
 	}, nil
@@ -190,37 +268,100 @@ func NewCache(
 
 //------------------------------------------------------------------------------
 
-type cacheOperator func(key string, value []byte) ([]byte, bool, error)
+type cacheOperator func(key string, value, expected []byte) ([]byte, bool, error)
 
-func newCacheSetOperator(cache types.Cache) cacheOperator {
-	return func(key string, value []byte) ([]byte, bool, error) {
-		err := cache.Set(key, value)
-		return nil, false, err
+func newCacheSetOperator(cache types.Cache, ttl time.Duration) (cacheOperator, error) {
+	if ttl == 0 {
+		return func(key string, value, _ []byte) ([]byte, bool, error) {
+			err := cache.Set(key, value)
+			return nil, false, err
+		}, nil
+	}
+	ttlCache, ok := cache.(types.CacheWithTTL)
+	if !ok {
+		return nil, errors.New("cache does not support ttl overrides")
 	}
+	return func(key string, value, _ []byte) ([]byte, bool, error) {
+		err := ttlCache.SetWithTTL(key, value, ttl)
+		return nil, false, err
+	}, nil
 }
 
-func newCacheAddOperator(cache types.Cache) cacheOperator {
-	return func(key string, value []byte) ([]byte, bool, error) {
-		err := cache.Add(key, value)
-		return nil, false, err
+func newCacheAddOperator(cache types.Cache, ttl time.Duration) (cacheOperator, error) {
+	if ttl == 0 {
+		return func(key string, value, _ []byte) ([]byte, bool, error) {
+			err := cache.Add(key, value)
+			return nil, false, err
+		}, nil
+	}
+	ttlCache, ok := cache.(types.CacheWithTTL)
+	if !ok {
+		return nil, errors.New("cache does not support ttl overrides")
 	}
+	return func(key string, value, _ []byte) ([]byte, bool, error) {
+		err := ttlCache.AddWithTTL(key, value, ttl)
+		return nil, false, err
+	}, nil
 }
 
 func newCacheGetOperator(cache types.Cache) cacheOperator {
-	return func(key string, _ []byte) ([]byte, bool, error) {
+	return func(key string, _, _ []byte) ([]byte, bool, error) {
 		result, err := cache.Get(key)
 		return result, true, err
 	}
 }
 
-func cacheOperatorFromString(operator string, cache types.Cache) (cacheOperator, error) {
+func newCacheIncrOperator(cache types.Cache, sign int64) (cacheOperator, error) {
+	counter, ok := cache.(types.CacheCounter)
+	if !ok {
+		return nil, errors.New("cache does not support counters")
+	}
+	return func(key string, value, _ []byte) ([]byte, bool, error) {
+		delta := int64(1)
+		if len(value) > 0 {
+			var err error
+			if delta, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return nil, false, fmt.Errorf("failed to parse value as integer: %v", err)
+			}
+		}
+		result, err := counter.Incr(key, sign*delta)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.FormatInt(result, 10)), true, nil
+	}, nil
+}
+
+func newCacheCASOperator(cache types.Cache, ttl time.Duration) (cacheOperator, error) {
+	cas, ok := cache.(types.CacheCompareAndSwapper)
+	if !ok {
+		return nil, errors.New("cache does not support compare-and-set")
+	}
+	return func(key string, value, expected []byte) ([]byte, bool, error) {
+		err := cas.CompareAndSwap(key, expected, value, ttl)
+		return nil, false, err
+	}, nil
+}
+
+func cacheOperatorFromString(operator string, cache types.Cache, ttl time.Duration) (cacheOperator, error) {
 	switch operator {
 	case "set":
-		return newCacheSetOperator(cache), nil
+		return newCacheSetOperator(cache, ttl)
 	case "add":
-		return newCacheAddOperator(cache), nil
+		return newCacheAddOperator(cache, ttl)
+	case "cas":
+		return newCacheCASOperator(cache, ttl)
+	}
+	if ttl != 0 {
+		return nil, fmt.Errorf("ttl is not supported by operator: %v", operator)
+	}
+	switch operator {
 	case "get":
 		return newCacheGetOperator(cache), nil
+	case "incr":
+		return newCacheIncrOperator(cache, 1)
+	case "decr":
+		return newCacheIncrOperator(cache, -1)
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
@@ -236,15 +377,20 @@ func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 	proc := func(index int, span opentracing.Span, part types.Part) error {
 		key := c.key.Get(message.Lock(newMsg, index))
 		value := c.value.Get(message.Lock(newMsg, index))
+		expected := c.expected.Get(message.Lock(newMsg, index))
 
-		result, useResult, err := c.operator(key, value)
+		result, useResult, err := c.operator(key, value, expected)
 		if err != nil {
-			if err != types.ErrKeyAlreadyExists {
-				c.mErr.Incr(1)
-				c.log.Debugf("Operator failed for key '%s': %v\n", key, err)
-			} else {
+			switch err {
+			case types.ErrKeyAlreadyExists:
 				c.mKeyAlreadyExists.Incr(1)
 				c.log.Debugf("Key already exists: %v\n", key)
+			case types.ErrValueMismatch:
+				c.mValueMismatch.Incr(1)
+				c.log.Debugf("Value of key has changed: %v\n", key)
+			default:
+				c.mErr.Incr(1)
+				c.log.Debugf("Operator failed for key '%s': %v\n", key, err)
 			}
 			return err
 		}
@@ -272,3 +272,166 @@ func TestCacheGet(t *testing.T) {
 		t.Errorf("Wrong fail flag: %v != %v", act, exp)
 	}
 }
+
+func TestCacheIncrDecr(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json_field:key}"
+	conf.Cache.Value = "${!json_field:value}"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "incr"
+	incr, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Cache.Operator = "decr"
+	decr, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := incr.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","value":""}`),
+		[]byte(`{"key":"1","value":"5"}`),
+		[]byte(`{"key":"2","value":"2"}`),
+		[]byte(`{"key":"1","value":"nope"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp := [][]byte{
+		[]byte(`1`),
+		[]byte(`6`),
+		[]byte(`2`),
+		[]byte(`{"key":"1","value":"nope"}`),
+	}
+	if act := message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	if !HasFailed(output[0].Get(3)) {
+		t.Error("Expected bad value to fail")
+	}
+
+	output, res = decr.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","value":"10"}`),
+		[]byte(`{"key":"2","value":""}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp = [][]byte{
+		[]byte(`-4`),
+		[]byte(`1`),
+	}
+	if act := message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+}
+
+func TestCacheCAS(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+	memCache.Set("1", []byte("foo"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json_field:key}"
+	conf.Cache.Value = "${!json_field:value}"
+	conf.Cache.Expected = "${!json_field:expected}"
+	conf.Cache.Cache = "foocache"
+	conf.Cache.Operator = "cas"
+	conf.Cache.TTL = "1h"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1","expected":"foo","value":"bar"}`),
+		[]byte(`{"key":"1","expected":"foo","value":"baz"}`),
+		[]byte(`{"key":"2","expected":"foo","value":"baz"}`),
+	})
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := message.GetAllBytes(input), message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	expFailed := []bool{false, true, true}
+	for i, exp := range expFailed {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag at %v: %v != %v", i, act, exp)
+		}
+	}
+
+	actBytes, err := memCache.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "bar", string(actBytes); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if _, err = memCache.Get("2"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrKeyNotFound)
+	}
+}
+
+type basicCache struct {
+	types.Cache
+}
+
+func TestCacheUnsupportedOperators(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"basic":  basicCache{Cache: memCache},
+			"memory": memCache,
+		},
+	}
+
+	tests := []struct {
+		cache    string
+		operator string
+		ttl      string
+	}{
+		{"basic", "incr", ""},
+		{"basic", "decr", ""},
+		{"basic", "cas", ""},
+		{"basic", "set", "1m"},
+		{"basic", "add", "1m"},
+		{"memory", "get", "1m"},
+		{"memory", "incr", "1m"},
+		{"memory", "set", "nope"},
+		{"memory", "nope", ""},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Cache.Cache = test.cache
+		conf.Cache.Operator = test.operator
+		conf.Cache.TTL = test.ttl
+		if _, err := NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error from %v operator on %v cache with ttl '%v'", test.operator, test.cache, test.ttl)
+		}
+	}
+}
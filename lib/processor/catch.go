@@ -85,8 +85,8 @@ func NewCatchConfig() CatchConfig {
 //------------------------------------------------------------------------------
 
 // Catch is a processor that applies a list of child processors to each message of
-// a batch individually, where processors are skipped for messages that failed a
-// previous processor step.
+// a batch individually, where processors are only applied to messages that
+// failed a previous processor step.
 type Catch struct {
 	children []types.Processor
 
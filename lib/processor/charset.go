@@ -0,0 +1,250 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/opentracing/opentracing-go"
+	"github.com/saintfish/chardet"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCharset] = TypeSpec{
+		constructor: NewCharset,
+		description: `
+Transcodes the contents of messages from a character encoding into UTF-8.
+
+The field ` + "`encoding`" + ` can be set to the name of any encoding known to
+the [IANA](https://www.iana.org/assignments/character-sets/character-sets.xhtml)
+or [WHATWG](https://encoding.spec.whatwg.org/#names-and-labels) registries,
+such as ` + "`UTF-16`, `ISO-8859-1` or `Shift_JIS`" + `.
+
+When set to ` + "`auto`" + ` the encoding of each message is detected. Messages
+that begin with a byte order mark are decoded as the UTF-8 or UTF-16 variant it
+signifies, messages that are already valid UTF-8 are left unchanged, and the
+encoding of all other messages is guessed from their contents. The name of the
+detected encoding is added to each message as the metadata field
+` + "`charset`" + `.
+
+Messages that cannot be decoded, either because their encoding could not be
+detected or because they contain byte sequences that are invalid for the
+encoding, are left unchanged and flagged as having failed, allowing them to be
+handled with [error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CharsetConfig contains configuration fields for the Charset processor.
+type CharsetConfig struct {
+	Encoding string `json:"encoding" yaml:"encoding"`
+	Parts    []int  `json:"parts" yaml:"parts"`
+}
+
+// NewCharsetConfig returns a CharsetConfig with default values.
+func NewCharsetConfig() CharsetConfig {
+	return CharsetConfig{
+		Encoding: "auto",
+		Parts:    []int{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// charsetEncoding returns an encoding by its IANA or WHATWG name.
+func charsetEncoding(name string) (encoding.Encoding, error) {
+	if enc, err := htmlindex.Get(name); err == nil {
+		return enc, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, fmt.Errorf("encoding not supported: %v", name)
+	}
+	return enc, nil
+}
+
+// utf8BOM is UTF-8 where the decoder removes a leading byte order mark.
+type utf8BOM struct {
+	encoding.Encoding
+}
+
+func (u utf8BOM) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: unicode.BOMOverride(u.Encoding.NewDecoder())}
+}
+
+var charsetBOMs = []struct {
+	bom  []byte
+	name string
+	enc  encoding.Encoding
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "UTF-8", utf8BOM{unicode.UTF8}},
+	{[]byte{0xFE, 0xFF}, "UTF-16BE", unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)},
+	{[]byte{0xFF, 0xFE}, "UTF-16LE", unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)},
+}
+
+//------------------------------------------------------------------------------
+
+// Charset is a processor that transcodes messages into UTF-8.
+type Charset struct {
+	conf     CharsetConfig
+	enc      encoding.Encoding
+	detector *chardet.Detector
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewCharset returns a Charset processor.
+func NewCharset(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	c := &Charset{
+		conf:  conf.Charset,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if strings.ToLower(conf.Charset.Encoding) == "auto" {
+		c.detector = chardet.NewTextDetector()
+	} else {
+		var err error
+		if c.enc, err = charsetEncoding(conf.Charset.Encoding); err != nil {
+			return nil, fmt.Errorf("failed to find encoding '%v': %v", conf.Charset.Encoding, err)
+		}
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+// detect returns the name and encoding of a message payload, or a nil encoding
+// if the payload is already valid UTF-8.
+func (c *Charset) detect(payload []byte) (string, encoding.Encoding, error) {
+	for _, b := range charsetBOMs {
+		if bytes.HasPrefix(payload, b.bom) {
+			return b.name, b.enc, nil
+		}
+	}
+	if utf8.Valid(payload) {
+		return "UTF-8", nil, nil
+	}
+	res, err := c.detector.DetectBest(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to detect encoding: %v", err)
+	}
+	enc, err := charsetEncoding(res.Charset)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find detected encoding '%v': %v", res.Charset, err)
+	}
+	return res.Charset, enc, nil
+}
+
+func (c *Charset) transcode(part types.Part) error {
+	payload := part.Get()
+
+	enc := c.enc
+	if c.detector != nil {
+		name, detected, err := c.detect(payload)
+		if err != nil {
+			return err
+		}
+		part.Metadata().Set("charset", name)
+		if detected == nil {
+			return nil
+		}
+		enc = detected
+	}
+
+	result, err := enc.NewDecoder().Bytes(payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode message: %v", err)
+	}
+	if bytes.ContainsRune(result, utf8.RuneError) {
+		return errors.New("failed to decode message: contains invalid byte sequences")
+	}
+	part.Set(result)
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *Charset) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(i int, span opentracing.Span, part types.Part) error {
+		if err := c.transcode(part); err != nil {
+			c.log.Debugf("Failed to transcode message part: %v\n", err)
+			c.mErr.Incr(1)
+			return err
+		}
+		return nil
+	}
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	IteratePartsWithSpan(TypeCharset, c.conf.Parts, newMsg, proc)
+
+	c.mBatchSent.Incr(1)
+	c.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (c *Charset) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (c *Charset) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,175 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestCharsetExplicit(t *testing.T) {
+	tests := []struct {
+		encoding string
+		input    []byte
+		output   string
+	}{
+		{
+			encoding: "ISO-8859-1",
+			input:    []byte{'c', 'a', 'f', 0xE9},
+			output:   "café",
+		},
+		{
+			encoding: "UTF-16BE",
+			input:    []byte{0x00, 'h', 0x00, 'i'},
+			output:   "hi",
+		},
+		{
+			encoding: "Shift_JIS",
+			input:    []byte{0x93, 0xFA, 0x96, 0x7B},
+			output:   "日本",
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Charset.Encoding = test.encoding
+
+		proc, err := NewCharset(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New([][]byte{test.input}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Wrong count of messages: %v", len(msgs))
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result for %v: %v != %v", test.encoding, act, exp)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Unexpected fail flag for %v", test.encoding)
+		}
+	}
+}
+
+func TestCharsetAuto(t *testing.T) {
+	conf := NewConfig()
+
+	proc, err := NewCharset(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte("hello world"),
+		{0xFF, 0xFE, 'h', 0x00, 'i', 0x00},
+		{0xEF, 0xBB, 0xBF, 'h', 'i'},
+	}
+	exp := [][]byte{
+		[]byte("hello world"),
+		[]byte("hi"),
+		[]byte("hi"),
+	}
+	expCharsets := []string{"UTF-8", "UTF-16LE", "UTF-8"}
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range expCharsets {
+		if act := msgs[0].Get(i).Metadata().Get("charset"); exp != act {
+			t.Errorf("Wrong charset for part %v: %v != %v", i, act, exp)
+		}
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Unexpected fail flag for part %v", i)
+		}
+	}
+}
+
+func TestCharsetAutoDetect(t *testing.T) {
+	conf := NewConfig()
+
+	proc, err := NewCharset(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ISO-8859-1 encoded French text.
+	exp := "Le café était très agréable, et nous y sommes allés à plusieurs reprises pendant l'été."
+	input := []byte{}
+	for _, r := range exp {
+		input = append(input, byte(r))
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{input}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Unexpected fail flag")
+	}
+}
+
+func TestCharsetInvalid(t *testing.T) {
+	conf := NewConfig()
+	conf.Charset.Encoding = "UTF-8"
+
+	proc, err := NewCharset(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte{'a', 0xC3, 0x28}
+	msgs, res := proc.ProcessMessage(message.New([][]byte{input}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := input, msgs[0].Get(0).Get(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected fail flag")
+	}
+}
+
+func TestCharsetBadEncoding(t *testing.T) {
+	conf := NewConfig()
+	conf.Charset.Encoding = "not a real encoding"
+
+	if _, err := NewCharset(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad encoding")
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/golang/snappy"
 )
 
 func TestCompressBadAlgo(t *testing.T) {
@@ -187,6 +188,37 @@ func TestCompressFlate(t *testing.T) {
 	}
 }
 
+func TestCompressSnappy(t *testing.T) {
+	conf := NewConfig()
+	conf.Compress.Algorithm = "snappy"
+
+	input := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+	}
+
+	exp := [][]byte{}
+	for i := range input {
+		exp = append(exp, snappy.Encode(nil, input[i]))
+	}
+
+	proc, err := NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if len(msgs) != 1 {
+		t.Error("Compress failed")
+	} else if res != nil {
+		t.Errorf("Expected nil response: %v", res)
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+}
+
 func TestCompressIndexBounds(t *testing.T) {
 	conf := NewConfig()
 
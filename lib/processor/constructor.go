@@ -79,166 +79,226 @@ func Block(typeStr, reason string) {
 
 // String constants representing each processor type.
 const (
-	TypeArchive      = "archive"
-	TypeAvro         = "avro"
-	TypeAWK          = "awk"
-	TypeBatch        = "batch"
-	TypeBoundsCheck  = "bounds_check"
-	TypeCache        = "cache"
-	TypeCatch        = "catch"
-	TypeCompress     = "compress"
-	TypeConditional  = "conditional"
-	TypeDecode       = "decode"
-	TypeDecompress   = "decompress"
-	TypeDedupe       = "dedupe"
-	TypeEncode       = "encode"
-	TypeFilter       = "filter"
-	TypeFilterParts  = "filter_parts"
-	TypeForEach      = "for_each"
-	TypeGrok         = "grok"
-	TypeGroupBy      = "group_by"
-	TypeGroupByValue = "group_by_value"
-	TypeHash         = "hash"
-	TypeHashSample   = "hash_sample"
-	TypeHTTP         = "http"
-	TypeInsertPart   = "insert_part"
-	TypeJMESPath     = "jmespath"
-	TypeJSON         = "json"
-	TypeLambda       = "lambda"
-	TypeLog          = "log"
-	TypeMergeJSON    = "merge_json"
-	TypeMetadata     = "metadata"
-	TypeMetric       = "metric"
-	TypeNoop         = "noop"
-	TypeNumber       = "number"
-	TypeParallel     = "parallel"
-	TypeProcessBatch = "process_batch"
-	TypeProcessDAG   = "process_dag"
-	TypeProcessField = "process_field"
-	TypeProcessMap   = "process_map"
-	TypeSample       = "sample"
-	TypeSelectParts  = "select_parts"
-	TypeSleep        = "sleep"
-	TypeSplit        = "split"
-	TypeSQL          = "sql"
-	TypeSubprocess   = "subprocess"
-	TypeSwitch       = "switch"
-	TypeText         = "text"
-	TypeTry          = "try"
-	TypeThrottle     = "throttle"
-	TypeUnarchive    = "unarchive"
-	TypeWhile        = "while"
+	TypeArchive        = "archive"
+	TypeAvro           = "avro"
+	TypeAWK            = "awk"
+	TypeBatch          = "batch"
+	TypeBoundsCheck    = "bounds_check"
+	TypeBranch         = "branch"
+	TypeCache          = "cache"
+	TypeCatch          = "catch"
+	TypeCharset        = "charset"
+	TypeCompress       = "compress"
+	TypeConditional    = "conditional"
+	TypeDecode         = "decode"
+	TypeDecompress     = "decompress"
+	TypeDecrypt        = "decrypt"
+	TypeDedupe         = "dedupe"
+	TypeDiff           = "diff"
+	TypeEncode         = "encode"
+	TypeEncrypt        = "encrypt"
+	TypeFilter         = "filter"
+	TypeFilterParts    = "filter_parts"
+	TypeForEach        = "for_each"
+	TypeFormatCSV      = "format_csv"
+	TypeGeoIP          = "geoip"
+	TypeGrok           = "grok"
+	TypeGroupBy        = "group_by"
+	TypeGroupByValue   = "group_by_value"
+	TypeHash           = "hash"
+	TypeHashSample     = "hash_sample"
+	TypeHTTP           = "http"
+	TypeInsertPart     = "insert_part"
+	TypeJMESPath       = "jmespath"
+	TypeJSON           = "json"
+	TypeLambda         = "lambda"
+	TypeLog            = "log"
+	TypeMapping        = "mapping"
+	TypeMergeJSON      = "merge_json"
+	TypeMessageStats   = "message_stats"
+	TypeMetadata       = "metadata"
+	TypeMetric         = "metric"
+	TypeNoop           = "noop"
+	TypeNormaliseKeys  = "normalise_keys"
+	TypeNumber         = "number"
+	TypeParallel       = "parallel"
+	TypeParquet        = "parquet"
+	TypeParseCSV       = "parse_csv"
+	TypeProcessBatch   = "process_batch"
+	TypeProcessDAG     = "process_dag"
+	TypeProcessField   = "process_field"
+	TypeProcessMap     = "process_map"
+	TypeRedis          = "redis"
+	TypeRetry          = "retry"
+	TypeSample         = "sample"
+	TypeSchemaRegistry = "schema_registry"
+	TypeSelectParts    = "select_parts"
+	TypeSleep          = "sleep"
+	TypeSplit          = "split"
+	TypeSQL            = "sql"
+	TypeSubprocess     = "subprocess"
+	TypeSwitch         = "switch"
+	TypeText           = "text"
+	TypeTry            = "try"
+	TypeThrottle       = "throttle"
+	TypeTimeout        = "timeout"
+	TypeTokenize       = "tokenize"
+	TypeUnarchive      = "unarchive"
+	TypeUnwrapEnvelope = "unwrap_envelope"
+	TypeWhile          = "while"
+	TypeWorkflow       = "workflow"
+	TypeXSD            = "xsd"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all processor types.
 type Config struct {
-	Type         string             `json:"type" yaml:"type"`
-	Archive      ArchiveConfig      `json:"archive" yaml:"archive"`
-	Avro         AvroConfig         `json:"avro" yaml:"avro"`
-	AWK          AWKConfig          `json:"awk" yaml:"awk"`
-	Batch        BatchConfig        `json:"batch" yaml:"batch"`
-	BoundsCheck  BoundsCheckConfig  `json:"bounds_check" yaml:"bounds_check"`
-	Cache        CacheConfig        `json:"cache" yaml:"cache"`
-	Catch        CatchConfig        `json:"catch" yaml:"catch"`
-	Compress     CompressConfig     `json:"compress" yaml:"compress"`
-	Conditional  ConditionalConfig  `json:"conditional" yaml:"conditional"`
-	Decode       DecodeConfig       `json:"decode" yaml:"decode"`
-	Decompress   DecompressConfig   `json:"decompress" yaml:"decompress"`
-	Dedupe       DedupeConfig       `json:"dedupe" yaml:"dedupe"`
-	Encode       EncodeConfig       `json:"encode" yaml:"encode"`
-	Filter       FilterConfig       `json:"filter" yaml:"filter"`
-	FilterParts  FilterPartsConfig  `json:"filter_parts" yaml:"filter_parts"`
-	ForEach      ForEachConfig      `json:"for_each" yaml:"for_each"`
-	Grok         GrokConfig         `json:"grok" yaml:"grok"`
-	GroupBy      GroupByConfig      `json:"group_by" yaml:"group_by"`
-	GroupByValue GroupByValueConfig `json:"group_by_value" yaml:"group_by_value"`
-	Hash         HashConfig         `json:"hash" yaml:"hash"`
-	HashSample   HashSampleConfig   `json:"hash_sample" yaml:"hash_sample"`
-	HTTP         HTTPConfig         `json:"http" yaml:"http"`
-	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
-	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
-	JSON         JSONConfig         `json:"json" yaml:"json"`
-	Lambda       LambdaConfig       `json:"lambda" yaml:"lambda"`
-	Log          LogConfig          `json:"log" yaml:"log"`
-	MergeJSON    MergeJSONConfig    `json:"merge_json" yaml:"merge_json"`
-	Metadata     MetadataConfig     `json:"metadata" yaml:"metadata"`
-	Metric       MetricConfig       `json:"metric" yaml:"metric"`
-	Number       NumberConfig       `json:"number" yaml:"number"`
-	Plugin       interface{}        `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	Parallel     ParallelConfig     `json:"parallel" yaml:"parallel"`
-	ProcessBatch ForEachConfig      `json:"process_batch" yaml:"process_batch"`
-	ProcessDAG   ProcessDAGConfig   `json:"process_dag" yaml:"process_dag"`
-	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
-	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
-	Sample       SampleConfig       `json:"sample" yaml:"sample"`
-	SelectParts  SelectPartsConfig  `json:"select_parts" yaml:"select_parts"`
-	Sleep        SleepConfig        `json:"sleep" yaml:"sleep"`
-	Split        SplitConfig        `json:"split" yaml:"split"`
-	SQL          SQLConfig          `json:"sql" yaml:"sql"`
-	Subprocess   SubprocessConfig   `json:"subprocess" yaml:"subprocess"`
-	Switch       SwitchConfig       `json:"switch" yaml:"switch"`
-	Text         TextConfig         `json:"text" yaml:"text"`
-	Try          TryConfig          `json:"try" yaml:"try"`
-	Throttle     ThrottleConfig     `json:"throttle" yaml:"throttle"`
-	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
-	While        WhileConfig        `json:"while" yaml:"while"`
+	Type           string               `json:"type" yaml:"type"`
+	Archive        ArchiveConfig        `json:"archive" yaml:"archive"`
+	Avro           AvroConfig           `json:"avro" yaml:"avro"`
+	AWK            AWKConfig            `json:"awk" yaml:"awk"`
+	Batch          BatchConfig          `json:"batch" yaml:"batch"`
+	BoundsCheck    BoundsCheckConfig    `json:"bounds_check" yaml:"bounds_check"`
+	Branch         BranchConfig         `json:"branch" yaml:"branch"`
+	Cache          CacheConfig          `json:"cache" yaml:"cache"`
+	Catch          CatchConfig          `json:"catch" yaml:"catch"`
+	Charset        CharsetConfig        `json:"charset" yaml:"charset"`
+	Compress       CompressConfig       `json:"compress" yaml:"compress"`
+	Conditional    ConditionalConfig    `json:"conditional" yaml:"conditional"`
+	Decode         DecodeConfig         `json:"decode" yaml:"decode"`
+	Decompress     DecompressConfig     `json:"decompress" yaml:"decompress"`
+	Decrypt        DecryptConfig        `json:"decrypt" yaml:"decrypt"`
+	Dedupe         DedupeConfig         `json:"dedupe" yaml:"dedupe"`
+	Diff           DiffConfig           `json:"diff" yaml:"diff"`
+	Encode         EncodeConfig         `json:"encode" yaml:"encode"`
+	Encrypt        EncryptConfig        `json:"encrypt" yaml:"encrypt"`
+	Filter         FilterConfig         `json:"filter" yaml:"filter"`
+	FilterParts    FilterPartsConfig    `json:"filter_parts" yaml:"filter_parts"`
+	ForEach        ForEachConfig        `json:"for_each" yaml:"for_each"`
+	FormatCSV      FormatCSVConfig      `json:"format_csv" yaml:"format_csv"`
+	GeoIP          GeoIPConfig          `json:"geoip" yaml:"geoip"`
+	Grok           GrokConfig           `json:"grok" yaml:"grok"`
+	GroupBy        GroupByConfig        `json:"group_by" yaml:"group_by"`
+	GroupByValue   GroupByValueConfig   `json:"group_by_value" yaml:"group_by_value"`
+	Hash           HashConfig           `json:"hash" yaml:"hash"`
+	HashSample     HashSampleConfig     `json:"hash_sample" yaml:"hash_sample"`
+	HTTP           HTTPConfig           `json:"http" yaml:"http"`
+	InsertPart     InsertPartConfig     `json:"insert_part" yaml:"insert_part"`
+	JMESPath       JMESPathConfig       `json:"jmespath" yaml:"jmespath"`
+	JSON           JSONConfig           `json:"json" yaml:"json"`
+	Lambda         LambdaConfig         `json:"lambda" yaml:"lambda"`
+	Log            LogConfig            `json:"log" yaml:"log"`
+	Mapping        MappingConfig        `json:"mapping" yaml:"mapping"`
+	MergeJSON      MergeJSONConfig      `json:"merge_json" yaml:"merge_json"`
+	MessageStats   MessageStatsConfig   `json:"message_stats" yaml:"message_stats"`
+	Metadata       MetadataConfig       `json:"metadata" yaml:"metadata"`
+	Metric         MetricConfig         `json:"metric" yaml:"metric"`
+	NormaliseKeys  NormaliseKeysConfig  `json:"normalise_keys" yaml:"normalise_keys"`
+	Number         NumberConfig         `json:"number" yaml:"number"`
+	Plugin         interface{}          `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Parallel       ParallelConfig       `json:"parallel" yaml:"parallel"`
+	Parquet        ParquetConfig        `json:"parquet" yaml:"parquet"`
+	ParseCSV       ParseCSVConfig       `json:"parse_csv" yaml:"parse_csv"`
+	ProcessBatch   ForEachConfig        `json:"process_batch" yaml:"process_batch"`
+	ProcessDAG     ProcessDAGConfig     `json:"process_dag" yaml:"process_dag"`
+	ProcessField   ProcessFieldConfig   `json:"process_field" yaml:"process_field"`
+	ProcessMap     ProcessMapConfig     `json:"process_map" yaml:"process_map"`
+	Redis          RedisConfig          `json:"redis" yaml:"redis"`
+	Retry          RetryConfig          `json:"retry" yaml:"retry"`
+	Sample         SampleConfig         `json:"sample" yaml:"sample"`
+	SchemaRegistry SchemaRegistryConfig `json:"schema_registry" yaml:"schema_registry"`
+	SelectParts    SelectPartsConfig    `json:"select_parts" yaml:"select_parts"`
+	Sleep          SleepConfig          `json:"sleep" yaml:"sleep"`
+	Split          SplitConfig          `json:"split" yaml:"split"`
+	SQL            SQLConfig            `json:"sql" yaml:"sql"`
+	Subprocess     SubprocessConfig     `json:"subprocess" yaml:"subprocess"`
+	Switch         SwitchConfig         `json:"switch" yaml:"switch"`
+	Text           TextConfig           `json:"text" yaml:"text"`
+	Try            TryConfig            `json:"try" yaml:"try"`
+	Throttle       ThrottleConfig       `json:"throttle" yaml:"throttle"`
+	Timeout        TimeoutConfig        `json:"timeout" yaml:"timeout"`
+	Tokenize       TokenizeConfig       `json:"tokenize" yaml:"tokenize"`
+	Unarchive      UnarchiveConfig      `json:"unarchive" yaml:"unarchive"`
+	UnwrapEnvelope UnwrapEnvelopeConfig `json:"unwrap_envelope" yaml:"unwrap_envelope"`
+	While          WhileConfig          `json:"while" yaml:"while"`
+	Workflow       WorkflowConfig       `json:"workflow" yaml:"workflow"`
+	XSD            *XSDConfig           `json:"xsd,omitempty" yaml:"xsd,omitempty"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:         "bounds_check",
-		Archive:      NewArchiveConfig(),
-		Avro:         NewAvroConfig(),
-		AWK:          NewAWKConfig(),
-		Batch:        NewBatchConfig(),
-		BoundsCheck:  NewBoundsCheckConfig(),
-		Cache:        NewCacheConfig(),
-		Catch:        NewCatchConfig(),
-		Compress:     NewCompressConfig(),
-		Conditional:  NewConditionalConfig(),
-		Decode:       NewDecodeConfig(),
-		Decompress:   NewDecompressConfig(),
-		Dedupe:       NewDedupeConfig(),
-		Encode:       NewEncodeConfig(),
-		Filter:       NewFilterConfig(),
-		FilterParts:  NewFilterPartsConfig(),
-		ForEach:      NewForEachConfig(),
-		Grok:         NewGrokConfig(),
-		GroupBy:      NewGroupByConfig(),
-		GroupByValue: NewGroupByValueConfig(),
-		Hash:         NewHashConfig(),
-		HashSample:   NewHashSampleConfig(),
-		HTTP:         NewHTTPConfig(),
-		InsertPart:   NewInsertPartConfig(),
-		JMESPath:     NewJMESPathConfig(),
-		JSON:         NewJSONConfig(),
-		Lambda:       NewLambdaConfig(),
-		Log:          NewLogConfig(),
-		MergeJSON:    NewMergeJSONConfig(),
-		Metadata:     NewMetadataConfig(),
-		Metric:       NewMetricConfig(),
-		Number:       NewNumberConfig(),
-		Plugin:       nil,
-		Parallel:     NewParallelConfig(),
-		ProcessBatch: NewForEachConfig(),
-		ProcessDAG:   NewProcessDAGConfig(),
-		ProcessField: NewProcessFieldConfig(),
-		ProcessMap:   NewProcessMapConfig(),
-		Sample:       NewSampleConfig(),
-		SelectParts:  NewSelectPartsConfig(),
-		Sleep:        NewSleepConfig(),
-		Split:        NewSplitConfig(),
-		SQL:          NewSQLConfig(),
-		Subprocess:   NewSubprocessConfig(),
-		Switch:       NewSwitchConfig(),
-		Text:         NewTextConfig(),
-		Try:          NewTryConfig(),
-		Throttle:     NewThrottleConfig(),
-		Unarchive:    NewUnarchiveConfig(),
-		While:        NewWhileConfig(),
+		Type:           "bounds_check",
+		Archive:        NewArchiveConfig(),
+		Avro:           NewAvroConfig(),
+		AWK:            NewAWKConfig(),
+		Batch:          NewBatchConfig(),
+		BoundsCheck:    NewBoundsCheckConfig(),
+		Branch:         NewBranchConfig(),
+		Cache:          NewCacheConfig(),
+		Catch:          NewCatchConfig(),
+		Charset:        NewCharsetConfig(),
+		Compress:       NewCompressConfig(),
+		Conditional:    NewConditionalConfig(),
+		Decode:         NewDecodeConfig(),
+		Decompress:     NewDecompressConfig(),
+		Decrypt:        NewDecryptConfig(),
+		Dedupe:         NewDedupeConfig(),
+		Diff:           NewDiffConfig(),
+		Encode:         NewEncodeConfig(),
+		Encrypt:        NewEncryptConfig(),
+		Filter:         NewFilterConfig(),
+		FilterParts:    NewFilterPartsConfig(),
+		ForEach:        NewForEachConfig(),
+		FormatCSV:      NewFormatCSVConfig(),
+		GeoIP:          NewGeoIPConfig(),
+		Grok:           NewGrokConfig(),
+		GroupBy:        NewGroupByConfig(),
+		GroupByValue:   NewGroupByValueConfig(),
+		Hash:           NewHashConfig(),
+		HashSample:     NewHashSampleConfig(),
+		HTTP:           NewHTTPConfig(),
+		InsertPart:     NewInsertPartConfig(),
+		JMESPath:       NewJMESPathConfig(),
+		JSON:           NewJSONConfig(),
+		Lambda:         NewLambdaConfig(),
+		Log:            NewLogConfig(),
+		Mapping:        NewMappingConfig(),
+		MergeJSON:      NewMergeJSONConfig(),
+		MessageStats:   NewMessageStatsConfig(),
+		Metadata:       NewMetadataConfig(),
+		Metric:         NewMetricConfig(),
+		NormaliseKeys:  NewNormaliseKeysConfig(),
+		Number:         NewNumberConfig(),
+		Plugin:         nil,
+		Parallel:       NewParallelConfig(),
+		Parquet:        NewParquetConfig(),
+		ParseCSV:       NewParseCSVConfig(),
+		ProcessBatch:   NewForEachConfig(),
+		ProcessDAG:     NewProcessDAGConfig(),
+		ProcessField:   NewProcessFieldConfig(),
+		ProcessMap:     NewProcessMapConfig(),
+		Redis:          NewRedisConfig(),
+		Retry:          NewRetryConfig(),
+		Sample:         NewSampleConfig(),
+		SchemaRegistry: NewSchemaRegistryConfig(),
+		SelectParts:    NewSelectPartsConfig(),
+		Sleep:          NewSleepConfig(),
+		Split:          NewSplitConfig(),
+		SQL:            NewSQLConfig(),
+		Subprocess:     NewSubprocessConfig(),
+		Switch:         NewSwitchConfig(),
+		Text:           NewTextConfig(),
+		Try:            NewTryConfig(),
+		Throttle:       NewThrottleConfig(),
+		Timeout:        NewTimeoutConfig(),
+		Tokenize:       NewTokenizeConfig(),
+		Unarchive:      NewUnarchiveConfig(),
+		UnwrapEnvelope: NewUnwrapEnvelopeConfig(),
+		While:          NewWhileConfig(),
+		Workflow:       NewWorkflowConfig(),
+		XSD:            NewXSDConfig(),
 	}
 }
 
@@ -450,13 +510,23 @@ func New(
 	log log.Modular,
 	stats metrics.Type,
 ) (Type, error) {
+	var proc Type
+	var err error
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(conf, mgr, log, stats)
+		proc, err = c.constructor(conf, mgr, log, stats)
+	} else if c, ok := pluginSpecs[conf.Type]; ok {
+		proc, err = c.constructor(conf.Plugin, mgr, log, stats)
+	} else {
+		return nil, types.ErrInvalidProcessorType
+	}
+	if err != nil {
+		return nil, err
 	}
-	if c, ok := pluginSpecs[conf.Type]; ok {
-		return c.constructor(conf.Plugin, mgr, log, stats)
+	// Processors are profiled under the same path as their metrics.
+	if path := metrics.Namespace(stats); len(path) > 0 {
+		proc = newProfiled(proc, path)
 	}
-	return nil, types.ErrInvalidProcessorType
+	return proc, nil
 }
 
 //------------------------------------------------------------------------------
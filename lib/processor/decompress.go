@@ -28,13 +28,17 @@ import (
 	"compress/zlib"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/opentracing/opentracing-go"
+	"github.com/pierrec/lz4"
 )
 
 //------------------------------------------------------------------------------
@@ -44,7 +48,10 @@ func init() {
 		constructor: NewDecompress,
 		description: `
 Decompresses messages according to the selected algorithm. Supported
-decompression types are: gzip, zlib, bzip2, flate.`,
+decompression types are: gzip, zlib, bzip2, flate, zstd, lz4, snappy.
+
+The snappy algorithm expects messages encoded in the snappy block format, which
+matches the output of the ` + "[`compress`](#compress)" + ` processor.`,
 	}
 }
 
@@ -121,6 +128,36 @@ func bzip2Decompress(b []byte) ([]byte, error) {
 	return outBuf.Bytes(), nil
 }
 
+var zstdDecoder *zstd.Decoder
+var zstdDecoderErr error
+var zstdDecoderOnce sync.Once
+
+func zstdDecompress(b []byte) ([]byte, error) {
+	// A decoder is safe to share for DecodeAll calls.
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil)
+	})
+	if zstdDecoderErr != nil {
+		return nil, zstdDecoderErr
+	}
+	return zstdDecoder.DecodeAll(b, nil)
+}
+
+func lz4Decompress(b []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(b)
+	zr := lz4.NewReader(buf)
+
+	outBuf := bytes.Buffer{}
+	if _, err := outBuf.ReadFrom(zr); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return outBuf.Bytes(), nil
+}
+
+func snappyDecompress(b []byte) ([]byte, error) {
+	return snappy.Decode(nil, b)
+}
+
 func strToDecompressor(str string) (decompressFunc, error) {
 	switch str {
 	case "gzip":
@@ -131,6 +168,12 @@ func strToDecompressor(str string) (decompressFunc, error) {
 		return flateDecompress, nil
 	case "bzip2":
 		return bzip2Decompress, nil
+	case "zstd":
+		return zstdDecompress, nil
+	case "lz4":
+		return lz4Decompress, nil
+	case "snappy":
+		return snappyDecompress, nil
 	}
 	return nil, fmt.Errorf("decompression type not recognised: %v", str)
 }
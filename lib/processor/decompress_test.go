@@ -193,6 +193,65 @@ func TestDecompressFlate(t *testing.T) {
 	}
 }
 
+func TestDecompressRoundTrip(t *testing.T) {
+	type test struct {
+		algorithm string
+		level     int
+	}
+	tests := []test{
+		{algorithm: "zstd", level: -1},
+		{algorithm: "zstd", level: 1},
+		{algorithm: "zstd", level: 19},
+		{algorithm: "lz4", level: -1},
+		{algorithm: "lz4", level: 9},
+		{algorithm: "snappy", level: -1},
+	}
+
+	input := [][]byte{
+		[]byte("hello world first part"),
+		bytes.Repeat([]byte("hello world second part"), 1000),
+		[]byte("third part"),
+		[]byte(""),
+	}
+
+	for _, test := range tests {
+		compConf := NewConfig()
+		compConf.Compress.Algorithm = test.algorithm
+		compConf.Compress.Level = test.level
+
+		decompConf := NewConfig()
+		decompConf.Decompress.Algorithm = test.algorithm
+
+		comp, err := NewCompress(compConf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		decomp, err := NewDecompress(decompConf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, _ := comp.ProcessMessage(message.New(input))
+		if len(msgs) != 1 {
+			t.Fatalf("%v: Compress failed", test.algorithm)
+		}
+		if act := message.GetAllBytes(msgs[0]); reflect.DeepEqual(input, act) {
+			t.Errorf("%v: Compressed output matches input", test.algorithm)
+		}
+		if msgs, _ = decomp.ProcessMessage(msgs[0]); len(msgs) != 1 {
+			t.Fatalf("%v: Decompress failed", test.algorithm)
+		}
+		for i := 0; i < msgs[0].Len(); i++ {
+			if HasFailed(msgs[0].Get(i)) {
+				t.Errorf("%v: Part %v failed to decompress", test.algorithm, i)
+			}
+			if exp, act := input[i], msgs[0].Get(i).Get(); !bytes.Equal(exp, act) {
+				t.Errorf("%v: Unexpected output of part %v: %s != %s", test.algorithm, i, act, exp)
+			}
+		}
+	}
+}
+
 func TestDecompressIndexBounds(t *testing.T) {
 	conf := NewConfig()
 
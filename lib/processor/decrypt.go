@@ -0,0 +1,232 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDecrypt] = TypeSpec{
+		constructor: NewDecrypt,
+		description: `
+Decrypts messages that were encrypted by the ` + "[`encrypt`](#encrypt)" + `
+processor. Supported algorithms are ` + "`aes-gcm` and `chacha20-poly1305`" + `,
+and the fields ` + "`key` and `key_encoding`" + ` behave the same as they do for
+the encrypt processor.
+
+Messages are expected to begin with the nonce used to encrypt them, followed by
+the ciphertext. When ` + "`json_paths`" + ` is non-empty each message is parsed
+as a JSON document and the values found at the listed paths are expected to be
+base64 encoded strings, which are decrypted and parsed back into their original
+JSON values. Paths that do not exist within a document are skipped.
+
+Since the algorithms are authenticated any message that has been tampered with,
+or was encrypted with a different key, fails to decrypt. Such messages are
+flagged as having failed and remain unchanged, these failures can be caught
+using [error handling methods](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DecryptConfig contains configuration fields for the Decrypt processor.
+type DecryptConfig struct {
+	Parts       []int    `json:"parts" yaml:"parts"`
+	Algorithm   string   `json:"algorithm" yaml:"algorithm"`
+	Key         string   `json:"key" yaml:"key"`
+	KeyEncoding string   `json:"key_encoding" yaml:"key_encoding"`
+	JSONPaths   []string `json:"json_paths" yaml:"json_paths"`
+}
+
+// NewDecryptConfig returns a DecryptConfig with default values.
+func NewDecryptConfig() DecryptConfig {
+	return DecryptConfig{
+		Parts:       []int{},
+		Algorithm:   "aes-gcm",
+		Key:         "",
+		KeyEncoding: "hex",
+		JSONPaths:   []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Decrypt is a processor that decrypts messages, or fields of JSON documents,
+// that were encrypted with an authenticated encryption algorithm.
+type Decrypt struct {
+	parts     []int
+	provider  *aeadProvider
+	jsonPaths [][]string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrKey    metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewDecrypt returns a Decrypt processor.
+func NewDecrypt(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	provider, err := newAEADProvider(conf.Decrypt.Algorithm, conf.Decrypt.Key, conf.Decrypt.KeyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	var jsonPaths [][]string
+	for _, p := range conf.Decrypt.JSONPaths {
+		if len(p) == 0 {
+			return nil, errors.New("json paths must not be empty")
+		}
+		jsonPaths = append(jsonPaths, strings.Split(p, "."))
+	}
+	return &Decrypt{
+		parts:     conf.Decrypt.Parts,
+		provider:  provider,
+		jsonPaths: jsonPaths,
+
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrKey:    stats.GetCounter("error.key"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErrJSONS:  stats.GetCounter("error.json_set"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (d *Decrypt) decryptFields(aead cipher.AEAD, part types.Part) error {
+	jsonPart, err := part.JSON()
+	if err == nil {
+		jsonPart, err = message.CopyJSON(jsonPart)
+	}
+	if err != nil {
+		d.mErrJSONP.Incr(1)
+		return fmt.Errorf("failed to parse part into json: %v", err)
+	}
+
+	gPart, _ := gabs.Consume(jsonPart)
+	for _, path := range d.jsonPaths {
+		if !gPart.Exists(path...) {
+			continue
+		}
+		pathStr := strings.Join(path, ".")
+		encoded, ok := gPart.S(path...).Data().(string)
+		if !ok {
+			return fmt.Errorf("field '%v' is not a string", pathStr)
+		}
+		var ciphertext, plaintext []byte
+		if ciphertext, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return fmt.Errorf("failed to decode field '%v': %v", pathStr, err)
+		}
+		if plaintext, err = aeadOpen(aead, ciphertext); err != nil {
+			return fmt.Errorf("failed to decrypt field '%v': %v", pathStr, err)
+		}
+		var value interface{}
+		if err = json.Unmarshal(plaintext, &value); err != nil {
+			return fmt.Errorf("failed to parse decrypted field '%v': %v", pathStr, err)
+		}
+		if _, err = gPart.Set(value, path...); err != nil {
+			d.mErrJSONS.Incr(1)
+			return fmt.Errorf("failed to set field '%v': %v", pathStr, err)
+		}
+	}
+	if err = part.SetJSON(gPart.Data()); err != nil {
+		d.mErrJSONS.Incr(1)
+		return fmt.Errorf("failed to convert json into part: %v", err)
+	}
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (d *Decrypt) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	d.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		aead, err := d.provider.get(newMsg, index)
+		if err != nil {
+			d.mErrKey.Incr(1)
+			d.mErr.Incr(1)
+			d.log.Debugf("Failed to resolve key: %v\n", err)
+			return err
+		}
+		if len(d.jsonPaths) > 0 {
+			if err = d.decryptFields(aead, part); err != nil {
+				d.mErr.Incr(1)
+				d.log.Debugf("Failed to decrypt fields: %v\n", err)
+			}
+			return err
+		}
+		plaintext, err := aeadOpen(aead, part.Get())
+		if err != nil {
+			d.mErr.Incr(1)
+			d.log.Debugf("Failed to decrypt message: %v\n", err)
+			return err
+		}
+		part.Set(plaintext)
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeDecrypt, d.parts, newMsg, proc)
+
+	d.mBatchSent.Incr(1)
+	d.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (d *Decrypt) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (d *Decrypt) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestDecryptAESGCM(t *testing.T) {
+	key, err := hex.DecodeString(testKey32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	ciphertext := aead.Seal(nonce, nonce, []byte("hello world"), nil)
+
+	conf := NewConfig()
+	conf.Decrypt.Key = testKey32
+
+	proc, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{ciphertext}))
+	if len(msgs) != 1 {
+		t.Fatal("Decrypt failed")
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Part failed to decrypt")
+	}
+	if exp, act := "hello world", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	conf := NewConfig()
+	conf.Encrypt.Key = testKey32
+	conf.Decrypt.Key = testKey32
+
+	enc, err := NewEncrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, _ := enc.ProcessMessage(message.New([][]byte{[]byte("hello world")}))
+	ciphertext := msgs[0].Get(0).Get()
+
+	tampered := make([]byte, len(ciphertext))
+	copy(tampered, ciphertext)
+	tampered[len(tampered)-1] ^= 0xFF
+
+	dec, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Decrypt.Key = testKey16
+	wrongKeyDec, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Decrypt.Key = testKey32
+	conf.Decrypt.JSONPaths = []string{"foo"}
+	fieldDec, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		proc  Type
+		input []byte
+	}{
+		"tampered":       {proc: dec, input: tampered},
+		"too short":      {proc: dec, input: []byte("foo")},
+		"wrong key":      {proc: wrongKeyDec, input: ciphertext},
+		"not json":       {proc: fieldDec, input: []byte("not json")},
+		"not a string":   {proc: fieldDec, input: []byte(`{"foo":10}`)},
+		"not base64":     {proc: fieldDec, input: []byte(`{"foo":"not base64!"}`)},
+		"bad ciphertext": {proc: fieldDec, input: []byte(`{"foo":"Zm9vYmFyYmF6YnV6cXV4cXV1eA=="}`)},
+	}
+
+	for name, test := range tests {
+		msgs, _ := test.proc.ProcessMessage(message.New([][]byte{test.input}))
+		if len(msgs) != 1 {
+			t.Fatalf("%v: Decrypt failed", name)
+		}
+		if !HasFailed(msgs[0].Get(0)) {
+			t.Errorf("%v: Expected part to be flagged as failed", name)
+		}
+		if exp, act := string(test.input), string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("%v: Failed part was modified: %v != %v", name, act, exp)
+		}
+	}
+}
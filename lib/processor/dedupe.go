@@ -22,11 +22,16 @@ package processor
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
@@ -44,7 +49,7 @@ func init() {
 		description: `
 Dedupes message batches by caching selected (and optionally hashed) messages,
 dropping batches that are already cached. The hash type can be chosen from:
-none or xxhash.
+none, xxhash or sha256.
 
 This processor acts across an entire batch, in order to deduplicate individual
 messages within a batch use this processor with the
@@ -66,6 +71,43 @@ dedupe:
   key: ${!metadata:kafka_key}-${!json_field:id}
 ` + "```" + `
 
+### Composite Keys
+
+The field ` + "`keys`" + ` can be used to build a composite key from a list of
+interpolated values. Each value is resolved independently and the results are
+separated within the hash, which means the values ` + "`a-b`, `c`" + ` and
+` + "`a`, `b-c`" + ` produce distinct keys. When both ` + "`key`" + ` and
+` + "`keys`" + ` are set the ` + "`key`" + ` value is placed first. If every
+value resolves to an empty string the contents of the selected parts are hashed
+instead.
+
+` + "``` yaml" + `
+dedupe:
+  cache: foocache
+  hash: xxhash
+  keys:
+  - ${!metadata:kafka_topic}
+  - ${!json_field:user.id}
+  - ${!json_field:event}
+` + "```" + `
+
+### Windows
+
+By default a key remains cached for as long as the cache itself retains it. The
+field ` + "`window`" + ` sets an explicit duration for each key, and every time
+a duplicate is detected the expiration of its key is reset. This results in a
+sliding window where a message is only considered a duplicate when the same key
+has been seen within the window period. Setting a window requires a cache that
+supports per key TTLs, such as ` + "`memory`" + ` or ` + "`redis`" + `.
+
+### Mode
+
+The field ` + "`mode`" + ` determines what happens to a duplicate batch. The
+default mode ` + "`drop`" + ` removes duplicates from the pipeline entirely.
+The mode ` + "`mark`" + ` instead propagates duplicates with the metadata field
+named by ` + "`mark_key`" + ` set to ` + "`true`" + ` on each message of the
+batch, allowing you to route or filter them further downstream.
+
 Caches should be configured as a resource, for more information check out the
 [documentation here](../caches/README.md).
 
@@ -93,11 +135,15 @@ service restarts increase the chances of duplicates passing undetected.`,
 
 // DedupeConfig contains configuration fields for the Dedupe processor.
 type DedupeConfig struct {
-	Cache          string `json:"cache" yaml:"cache"`
-	HashType       string `json:"hash" yaml:"hash"`
-	Parts          []int  `json:"parts" yaml:"parts"` // message parts to hash
-	Key            string `json:"key" yaml:"key"`
-	DropOnCacheErr bool   `json:"drop_on_err" yaml:"drop_on_err"`
+	Cache          string   `json:"cache" yaml:"cache"`
+	HashType       string   `json:"hash" yaml:"hash"`
+	Parts          []int    `json:"parts" yaml:"parts"` // message parts to hash
+	Key            string   `json:"key" yaml:"key"`
+	Keys           []string `json:"keys" yaml:"keys"`
+	Window         string   `json:"window" yaml:"window"`
+	Mode           string   `json:"mode" yaml:"mode"`
+	MarkKey        string   `json:"mark_key" yaml:"mark_key"`
+	DropOnCacheErr bool     `json:"drop_on_err" yaml:"drop_on_err"`
 }
 
 // NewDedupeConfig returns a DedupeConfig with default values.
@@ -107,6 +153,10 @@ func NewDedupeConfig() DedupeConfig {
 		HashType:       "none",
 		Parts:          []int{0}, // only consider the 1st part
 		Key:            "",
+		Keys:           []string{},
+		Window:         "",
+		Mode:           "drop",
+		MarkKey:        "dedupe_duplicate",
 		DropOnCacheErr: true,
 	}
 }
@@ -136,6 +186,20 @@ func (x *xxhashHasher) Bytes() []byte {
 
 //------------------------------------------------------------------------------
 
+type sha256Hasher struct {
+	h hash.Hash
+}
+
+func (s *sha256Hasher) Write(str []byte) (int, error) {
+	return s.h.Write(str)
+}
+
+func (s *sha256Hasher) Bytes() []byte {
+	return []byte(hex.EncodeToString(s.h.Sum(nil)))
+}
+
+//------------------------------------------------------------------------------
+
 func strToHasher(str string) (hasherFunc, error) {
 	switch str {
 	case "none":
@@ -148,23 +212,54 @@ func strToHasher(str string) (hasherFunc, error) {
 				h: xxhash.New64(),
 			}
 		}, nil
+	case "sha256":
+		return func() hasher {
+			return &sha256Hasher{
+				h: sha256.New(),
+			}
+		}, nil
 	}
 	return nil, fmt.Errorf("hash type not recognised: %v", str)
 }
 
 //------------------------------------------------------------------------------
 
+type dedupeKey struct {
+	value       []byte
+	interpolate bool
+}
+
+func newDedupeKey(k string) *dedupeKey {
+	value := []byte(k)
+	return &dedupeKey{
+		value:       value,
+		interpolate: text.ContainsFunctionVariables(value),
+	}
+}
+
+func (k *dedupeKey) get(msg types.Message) []byte {
+	if k.interpolate {
+		return text.ReplaceFunctionVariables(msg, k.value)
+	}
+	return k.value
+}
+
+//------------------------------------------------------------------------------
+
 // Dedupe is a processor that deduplicates messages either by hashing the full
 // contents of message parts or by hashing the value of an interpolated string.
 type Dedupe struct {
 	conf  Config
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 
-	keyBytes       []byte
-	interpolateKey bool
+	keys []*dedupeKey
 
 	cache      types.Cache
+	ttlCache   types.CacheWithTTL
+	window     time.Duration
+	markDupes  bool
 	hasherFunc hasherFunc
 
 	mCount     metrics.StatCounter
@@ -172,6 +267,7 @@ type Dedupe struct {
 	mErrCache  metrics.StatCounter
 	mErr       metrics.StatCounter
 	mDropped   metrics.StatCounter
+	mMarked    metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
 }
@@ -190,18 +286,49 @@ func NewDedupe(
 		return nil, err
 	}
 
-	keyBytes := []byte(conf.Dedupe.Key)
-	interpolateKey := text.ContainsFunctionVariables(keyBytes)
+	var keys []*dedupeKey
+	for _, k := range append([]string{conf.Dedupe.Key}, conf.Dedupe.Keys...) {
+		if len(k) > 0 {
+			keys = append(keys, newDedupeKey(k))
+		}
+	}
+
+	var markDupes bool
+	switch conf.Dedupe.Mode {
+	case "drop":
+	case "mark":
+		if len(conf.Dedupe.MarkKey) == 0 {
+			return nil, errors.New("mark_key must not be empty when mode is mark")
+		}
+		markDupes = true
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", conf.Dedupe.Mode)
+	}
+
+	var window time.Duration
+	var ttlCache types.CacheWithTTL
+	if len(conf.Dedupe.Window) > 0 {
+		if window, err = time.ParseDuration(conf.Dedupe.Window); err != nil {
+			return nil, fmt.Errorf("failed to parse window: %v", err)
+		}
+		var ok bool
+		if ttlCache, ok = c.(types.CacheWithTTL); !ok {
+			return nil, errors.New("cache does not support ttl overrides required by window")
+		}
+	}
 
 	return &Dedupe{
 		conf:  conf,
+		mgr:   mgr,
 		log:   log,
 		stats: stats,
 
-		keyBytes:       keyBytes,
-		interpolateKey: interpolateKey,
+		keys: keys,
 
 		cache:      c,
+		ttlCache:   ttlCache,
+		window:     window,
+		markDupes:  markDupes,
 		hasherFunc: hFunc,
 
 		mCount:     stats.GetCounter("count"),
@@ -209,6 +336,7 @@ func NewDedupe(
 		mErrCache:  stats.GetCounter("error.cache"),
 		mErr:       stats.GetCounter("error"),
 		mDropped:   stats.GetCounter("dropped"),
+		mMarked:    stats.GetCounter("marked"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}, nil
@@ -231,14 +359,21 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 		}
 	}()
 
-	key := d.keyBytes
-	if len(key) > 0 && d.interpolateKey {
-		key = text.ReplaceFunctionVariables(msg, key)
+	for i, k := range d.keys {
+		if i > 0 {
+			// Separate key values so that distinct combinations of values that
+			// concatenate to the same string produce different hashes.
+			hasher.Write([]byte{0})
+		}
+		if key := k.get(msg); len(key) > 0 {
+			hasher.Write(key)
+			extractedHash = true
+		}
+	}
+	if !extractedHash && len(d.keys) > 0 {
+		hasher = d.hasherFunc()
 	}
-	if len(key) > 0 {
-		hasher.Write(key)
-		extractedHash = true
-	} else {
+	if !extractedHash {
 		for _, index := range d.conf.Dedupe.Parts {
 			// Attempt to add whole part to hash.
 			if partBytes := msg.Get(index).Get(); partBytes != nil {
@@ -257,9 +392,10 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 	if !extractedHash {
 		if d.conf.Dedupe.DropOnCacheErr {
 			d.mDropped.Incr(1)
+			drop.Report(d.mgr, TypeDedupe, msg)
 			return nil, response.NewAck()
 		}
-	} else if err := d.cache.Add(string(hasher.Bytes()), []byte{'t'}); err != nil {
+	} else if err := d.add(string(hasher.Bytes())); err != nil {
 		if err != types.ErrKeyAlreadyExists {
 			d.mErrCache.Incr(1)
 			d.mErr.Incr(1)
@@ -272,8 +408,22 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 			}
 			if d.conf.Dedupe.DropOnCacheErr {
 				d.mDropped.Incr(1)
+				drop.Report(d.mgr, TypeDedupe, msg)
 				return nil, response.NewAck()
 			}
+		} else if d.markDupes {
+			for _, s := range spans {
+				s.LogFields(
+					olog.String("event", "marked"),
+					olog.String("type", "deduplicated"),
+				)
+			}
+			d.mMarked.Incr(1)
+			msg = msg.Copy()
+			msg.Iter(func(i int, p types.Part) error {
+				p.Metadata().Set(d.conf.Dedupe.MarkKey, "true")
+				return nil
+			})
 		} else {
 			for _, s := range spans {
 				s.LogFields(
@@ -282,6 +432,7 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 				)
 			}
 			d.mDropped.Incr(1)
+			drop.Report(d.mgr, TypeDedupe, msg)
 			return nil, response.NewAck()
 		}
 	}
@@ -292,6 +443,22 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 	return msgs[:], nil
 }
 
+// add attempts to add a key to the cache, returning ErrKeyAlreadyExists if the
+// key has already been seen. When a window is configured the expiration of an
+// existing key is reset in order to slide the window forward.
+func (d *Dedupe) add(key string) error {
+	if d.ttlCache == nil {
+		return d.cache.Add(key, []byte{'t'})
+	}
+	err := d.ttlCache.AddWithTTL(key, []byte{'t'}, d.window)
+	if err == types.ErrKeyAlreadyExists {
+		if serr := d.ttlCache.SetWithTTL(key, []byte{'t'}, d.window); serr != nil {
+			d.log.Warnf("Failed to refresh window of key: %v\n", serr)
+		}
+	}
+	return err
+}
+
 // CloseAsync shuts down the processor and stops processing requests.
 func (d *Dedupe) CloseAsync() {
 }
@@ -463,6 +463,185 @@ func TestDedupeNegBoundsCheck(t *testing.T) {
 	}
 }
 
+func TestDedupeCompositeKeys(t *testing.T) {
+	memCache, cacheErr := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if cacheErr != nil {
+		t.Fatal(cacheErr)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.HashType = "sha256"
+	conf.Dedupe.Keys = []string{"${!json_field:a}", "${!json_field:b}"}
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input  string
+		passes bool
+	}{
+		{input: `{"a":"foo-","b":"bar"}`, passes: true},
+		{input: `{"a":"foo","b":"-bar"}`, passes: true},
+		{input: `{"a":"foo-","b":"bar","c":"ignored"}`, passes: false},
+		{input: `{"b":"foo"}`, passes: true},
+		{input: `{"a":"foo"}`, passes: true},
+		{input: `{"a":"foo","b":"-bar"}`, passes: false},
+	}
+
+	for i, test := range tests {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if test.passes {
+			if len(msgs) != 1 {
+				t.Errorf("Message %v was dropped: %v", i, res)
+			}
+		} else if len(msgs) > 0 {
+			t.Errorf("Message %v was not dropped", i)
+		}
+	}
+}
+
+func TestDedupeSHA256(t *testing.T) {
+	memCache, cacheErr := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if cacheErr != nil {
+		t.Fatal(cacheErr)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.HashType = "sha256"
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("hello world")})); len(msgs) != 1 {
+		t.Fatal("First message was dropped")
+	}
+	if msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("hello world")})); len(msgs) != 0 {
+		t.Error("Duplicate message was not dropped")
+	}
+
+	exp := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if _, err := memCache.Get(exp); err != nil {
+		t.Errorf("Expected hex encoded sha256 key in cache: %v", err)
+	}
+}
+
+func TestDedupeMarkMode(t *testing.T) {
+	memCache, cacheErr := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if cacheErr != nil {
+		t.Fatal(cacheErr)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Mode = "mark"
+	conf.Dedupe.MarkKey = "is_dupe"
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	if len(msgs) != 1 {
+		t.Fatal("First message was dropped")
+	}
+	if exp, act := "", msgs[0].Get(0).Metadata().Get("is_dupe"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	input := message.New([][]byte{[]byte("foo"), []byte("baz")})
+	msgs, _ = proc.ProcessMessage(input)
+	if len(msgs) != 1 {
+		t.Fatal("Duplicate message was dropped")
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if exp, act := "true", msgs[0].Get(i).Metadata().Get("is_dupe"); exp != act {
+			t.Errorf("Wrong metadata of part %v: %v != %v", i, act, exp)
+		}
+		if exp, act := "", input.Get(i).Metadata().Get("is_dupe"); exp != act {
+			t.Errorf("Input part %v was marked: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestDedupeWindow(t *testing.T) {
+	memCache, cacheErr := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if cacheErr != nil {
+		t.Fatal(cacheErr)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+			"errcache": errCache{},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Window = "1h"
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foo")})); len(msgs) != 1 {
+		t.Fatal("First message was dropped")
+	}
+	if msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foo")})); len(msgs) != 0 {
+		t.Error("Duplicate message was not dropped")
+	}
+
+	conf.Dedupe.Window = "nope"
+	if _, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad window")
+	}
+
+	conf.Dedupe.Cache = "errcache"
+	conf.Dedupe.Window = "1h"
+	if _, err = NewDedupe(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from cache without ttl support")
+	}
+}
+
+func TestDedupeBadMode(t *testing.T) {
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": errCache{},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Mode = "notexist"
+	if _, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+
+	conf.Dedupe.Mode = "mark"
+	conf.Dedupe.MarkKey = ""
+	if _, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty mark key")
+	}
+}
+
 func randStringRunes(n int) string {
 	b := make([]rune, n)
 	for i := range b {
@@ -0,0 +1,357 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDiff] = TypeSpec{
+		constructor: NewDiff,
+		description: `
+Compares each message against the last seen version of the message with the
+same key, which is stored within a cache resource, in order to detect changes.
+This is useful for only propagating changes from inputs that periodically poll
+the full state of a source.
+
+The field ` + "`key`" + ` is [function interpolated](../config_interpolation.md#functions)
+per message and identifies the document being compared, for example:
+
+` + "``` yaml" + `
+diff:
+  cache: foocache
+  key: ${!json_field:id}
+  drop_unchanged: true
+` + "```" + `
+
+Each message is annotated with the metadata field ` + "`diff_status`" + `,
+which is set to ` + "`new`" + ` when no previous version is cached,
+` + "`changed`" + ` when the contents differ from the previous version and
+` + "`unchanged`" + ` otherwise. When ` + "`drop_unchanged`" + ` is
+` + "`true`" + ` unchanged messages are removed from the batch.
+
+If both the message and its previous version are valid JSON documents then the
+metadata field ` + "`diff_paths`" + ` is also set to a comma separated list of
+the dot separated paths that were added, removed or modified, where array
+elements are referenced by their index, and a change to the type of the root
+value is reported as the path ` + "`.`" + `. Documents are compared structurally, and
+therefore changes to formatting or field ordering are not considered changes.
+
+The latest version of a message is stored within the cache whenever it is new
+or has changed. Caches should be configured as a resource, for more information
+check out the [documentation here](../caches/README.md).
+
+### Delivery Guarantees
+
+Similar to the ` + "[`dedupe`](#dedupe)" + ` processor, the cache is updated
+before a message reaches the output, and therefore a change can be lost if the
+output fails and Benthos is restarted before it succeeds. Wrapping the output
+within a ` + "[`retry`](../outputs/README.md#retry)" + ` block avoids this
+during outages of the output.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DiffConfig contains configuration fields for the Diff processor.
+type DiffConfig struct {
+	Cache         string `json:"cache" yaml:"cache"`
+	Key           string `json:"key" yaml:"key"`
+	DropUnchanged bool   `json:"drop_unchanged" yaml:"drop_unchanged"`
+	Parts         []int  `json:"parts" yaml:"parts"`
+}
+
+// NewDiffConfig returns a DiffConfig with default values.
+func NewDiffConfig() DiffConfig {
+	return DiffConfig{
+		Cache:         "",
+		Key:           "",
+		DropUnchanged: true,
+		Parts:         []int{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Diff is a processor that detects changes to messages by comparing them with
+// the last seen version sharing a key.
+type Diff struct {
+	conf  DiffConfig
+	mgr   types.Manager
+	log   log.Modular
+	stats metrics.Type
+
+	key   *text.InterpolatedString
+	cache types.Cache
+
+	mCount     metrics.StatCounter
+	mErrCache  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mNew       metrics.StatCounter
+	mChanged   metrics.StatCounter
+	mUnchanged metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewDiff returns a Diff processor.
+func NewDiff(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.Diff.Key) == 0 {
+		return nil, errors.New("a key must be specified")
+	}
+	c, err := mgr.GetCache(conf.Diff.Cache)
+	if err != nil {
+		return nil, err
+	}
+	return &Diff{
+		conf:  conf.Diff,
+		mgr:   mgr,
+		log:   log,
+		stats: stats,
+
+		key:   text.NewInterpolatedString(conf.Diff.Key),
+		cache: c,
+
+		mCount:     stats.GetCounter("count"),
+		mErrCache:  stats.GetCounter("error.cache"),
+		mErr:       stats.GetCounter("error"),
+		mNew:       stats.GetCounter("new"),
+		mChanged:   stats.GetCounter("changed"),
+		mUnchanged: stats.GetCounter("unchanged"),
+		mDropped:   stats.GetCounter("dropped"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func joinDiffPath(base, key string) string {
+	if len(base) == 0 {
+		return key
+	}
+	return base + "." + key
+}
+
+// diffJSON appends the paths of all values that differ between two JSON
+// documents to paths.
+func diffJSON(path string, prev, current interface{}, paths []string) []string {
+	switch c := current.(type) {
+	case map[string]interface{}:
+		p, ok := prev.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for k, v := range c {
+			pv, exists := p[k]
+			if !exists {
+				paths = append(paths, joinDiffPath(path, k))
+				continue
+			}
+			paths = diffJSON(joinDiffPath(path, k), pv, v, paths)
+		}
+		for k := range p {
+			if _, exists := c[k]; !exists {
+				paths = append(paths, joinDiffPath(path, k))
+			}
+		}
+		return paths
+	case []interface{}:
+		p, ok := prev.([]interface{})
+		if !ok {
+			break
+		}
+		for i, v := range c {
+			iPath := joinDiffPath(path, strconv.Itoa(i))
+			if i >= len(p) {
+				paths = append(paths, iPath)
+				continue
+			}
+			paths = diffJSON(iPath, p[i], v, paths)
+		}
+		for i := len(c); i < len(p); i++ {
+			paths = append(paths, joinDiffPath(path, strconv.Itoa(i)))
+		}
+		return paths
+	}
+	if !reflect.DeepEqual(prev, current) {
+		if len(path) == 0 {
+			path = "."
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// compare returns whether the contents of a message part have changed from a
+// previous version, and the changed paths when both are JSON documents.
+func (d *Diff) compare(prev []byte, part types.Part) (bool, []string) {
+	var prevJSON interface{}
+	if err := json.Unmarshal(prev, &prevJSON); err == nil {
+		if currentJSON, err := part.JSON(); err == nil {
+			paths := diffJSON("", prevJSON, currentJSON, nil)
+			sort.Strings(paths)
+			return len(paths) > 0, paths
+		}
+	}
+	return !bytes.Equal(prev, part.Get()), nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (d *Diff) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	d.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	droppedMsg := message.New(nil)
+
+	spans := tracing.CreateChildSpans(TypeDiff, msg)
+	defer func() {
+		for _, s := range spans {
+			s.Finish()
+		}
+	}()
+
+	defer drop.Report(d.mgr, TypeDiff, droppedMsg)
+
+	selected := make([]bool, msg.Len())
+	if len(d.conf.Parts) == 0 {
+		for i := range selected {
+			selected[i] = true
+		}
+	} else {
+		for _, index := range d.conf.Parts {
+			if index < 0 {
+				index = len(selected) + index
+			}
+			if index >= 0 && index < len(selected) {
+				selected[index] = true
+			}
+		}
+	}
+
+	for i := 0; i < msg.Len(); i++ {
+		part := msg.Get(i).Copy()
+		if !selected[i] {
+			newMsg.Append(part)
+			continue
+		}
+
+		key := d.key.Get(message.Lock(msg, i))
+		status := "new"
+		prev, err := d.cache.Get(key)
+		if err == nil {
+			changed, paths := d.compare(prev, part)
+			if changed {
+				status = "changed"
+			} else {
+				status = "unchanged"
+			}
+			if len(paths) > 0 {
+				part.Metadata().Set("diff_paths", strings.Join(paths, ","))
+			}
+		} else if err != types.ErrKeyNotFound {
+			d.mErrCache.Incr(1)
+			d.mErr.Incr(1)
+			d.log.Errorf("Cache error: %v\n", err)
+			spans[i].LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+			FlagErr(part, err)
+			newMsg.Append(part)
+			continue
+		}
+
+		part.Metadata().Set("diff_status", status)
+		spans[i].SetTag("result", status)
+
+		switch status {
+		case "unchanged":
+			d.mUnchanged.Incr(1)
+			if d.conf.DropUnchanged {
+				spans[i].LogFields(
+					olog.String("event", "dropped"),
+					olog.String("type", "unchanged"),
+				)
+				d.mDropped.Incr(1)
+				droppedMsg.Append(msg.Get(i))
+				continue
+			}
+		case "changed":
+			d.mChanged.Incr(1)
+		default:
+			d.mNew.Incr(1)
+		}
+
+		if status != "unchanged" {
+			if err = d.cache.Set(key, msg.Get(i).Get()); err != nil {
+				d.mErrCache.Incr(1)
+				d.mErr.Incr(1)
+				d.log.Errorf("Cache error: %v\n", err)
+				FlagErr(part, err)
+			}
+		}
+		newMsg.Append(part)
+	}
+
+	if newMsg.Len() > 0 {
+		d.mBatchSent.Incr(1)
+		d.mSent.Incr(int64(newMsg.Len()))
+		msgs := [1]types.Message{newMsg}
+		return msgs[:], nil
+	}
+	return nil, response.NewAck()
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (d *Diff) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (d *Diff) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,208 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newDiffTestProc(t *testing.T) Type {
+	t.Helper()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Diff.Cache = "foocache"
+	conf.Diff.Key = "${!json_field:id}"
+	proc, err := NewDiff(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proc
+}
+
+func TestDiffBadConfig(t *testing.T) {
+	mgr := &fakeMgr{caches: map[string]types.Cache{}}
+
+	conf := NewConfig()
+	conf.Diff.Cache = "foocache"
+	if _, err := NewDiff(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing key")
+	}
+
+	conf.Diff.Key = "foo"
+	if _, err := NewDiff(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing cache")
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	proc := newDiffTestProc(t)
+
+	type diffResult struct {
+		status string
+		paths  string
+	}
+
+	tests := []struct {
+		input    []string
+		expected []diffResult
+	}{
+		{
+			input: []string{
+				`{"id":"a","foo":{"bar":1,"baz":[1,2]}}`,
+				`{"id":"b","foo":"x"}`,
+			},
+			expected: []diffResult{
+				{status: "new"},
+				{status: "new"},
+			},
+		},
+		{
+			input: []string{
+				`{"foo":{"baz":[1,2],"bar":1},   "id":"a"}`,
+				`{"id":"b","foo":"y"}`,
+			},
+			expected: []diffResult{
+				{status: "changed", paths: "foo"},
+			},
+		},
+		{
+			input: []string{
+				`{"id":"a","foo":{"bar":2,"baz":[1,3,4]},"qux":true}`,
+			},
+			expected: []diffResult{
+				{status: "changed", paths: "foo.bar,foo.baz.1,foo.baz.2,qux"},
+			},
+		},
+		{
+			input: []string{
+				`{"id":"a","foo":{"baz":[1]}}`,
+			},
+			expected: []diffResult{
+				{status: "changed", paths: "foo.bar,foo.baz.1,foo.baz.2,qux"},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		parts := make([][]byte, len(test.input))
+		for j, in := range test.input {
+			parts[j] = []byte(in)
+		}
+		msgs, res := proc.ProcessMessage(message.New(parts))
+		if res != nil {
+			t.Fatalf("Test %v: unexpected response: %v", i, res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Test %v: wrong count of messages: %v", i, len(msgs))
+		}
+		if exp, act := len(test.expected), msgs[0].Len(); exp != act {
+			t.Fatalf("Test %v: wrong count of parts: %v != %v", i, act, exp)
+		}
+		for j, exp := range test.expected {
+			meta := msgs[0].Get(j).Metadata()
+			if act := meta.Get("diff_status"); act != exp.status {
+				t.Errorf("Test %v part %v: wrong status: %v != %v", i, j, act, exp.status)
+			}
+			if act := meta.Get("diff_paths"); act != exp.paths {
+				t.Errorf("Test %v part %v: wrong paths: %v != %v", i, j, act, exp.paths)
+			}
+		}
+	}
+}
+
+func TestDiffRaw(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Diff.Cache = "foocache"
+	conf.Diff.Key = "foo"
+	conf.Diff.DropUnchanged = false
+	proc, err := NewDiff(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, exp := range []string{"new", "unchanged", "changed"} {
+		content := "hello world"
+		if exp == "changed" {
+			content = "hello universe"
+		}
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(content)}))
+		if res != nil {
+			t.Fatalf("Step %v: unexpected response: %v", i, res.Error())
+		}
+		if len(msgs) != 1 || msgs[0].Len() != 1 {
+			t.Fatalf("Step %v: wrong result: %v", i, msgs)
+		}
+		meta := msgs[0].Get(0).Metadata()
+		if act := meta.Get("diff_status"); act != exp {
+			t.Errorf("Step %v: wrong status: %v != %v", i, act, exp)
+		}
+		if act := meta.Get("diff_paths"); act != "" {
+			t.Errorf("Step %v: unexpected paths: %v", i, act)
+		}
+	}
+}
+
+func TestDiffDropAll(t *testing.T) {
+	proc := newDiffTestProc(t)
+
+	input := [][]byte{[]byte(`{"id":"a","v":1}`)}
+	if msgs, res := proc.ProcessMessage(message.New(input)); res != nil || len(msgs) != 1 {
+		t.Fatalf("Unexpected result: %v %v", msgs, res)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages, received: %v", msgs)
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response, received: %v", res)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,358 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeEncrypt] = TypeSpec{
+		constructor: NewEncrypt,
+		description: `
+Encrypts messages using an authenticated encryption algorithm. Supported
+algorithms are ` + "`aes-gcm` and `chacha20-poly1305`" + `.
+
+The key is decoded from the field ` + "`key`" + ` according to
+` + "`key_encoding`" + `, which can be one of ` + "`hex`, `base64` or `raw`" + `.
+AES-GCM requires a key of 16, 24 or 32 bytes, selecting AES-128, AES-192 or
+AES-256 respectively, and ChaCha20-Poly1305 requires a key of 32 bytes. The key
+supports [function interpolations](../config_interpolation.md#functions),
+allowing it to be selected per message, and should usually be supplied with an
+environment variable:
+
+` + "``` yaml" + `
+encrypt:
+  algorithm: aes-gcm
+  key: ${ENCRYPTION_KEY}
+  key_encoding: hex
+` + "```" + `
+
+A random nonce is generated for each encryption and is prepended to the
+resulting ciphertext, and therefore the output of this processor can be given
+directly to the ` + "[`decrypt`](#decrypt)" + ` processor with the same
+configuration.
+
+### Field Encryption
+
+When ` + "`json_paths`" + ` is non-empty each message is parsed as a JSON
+document and only the values found at the listed paths are encrypted. Each
+value is serialised as JSON before encryption and is replaced with a base64
+encoded string of the result. Paths that do not exist within a document are
+skipped.
+
+Messages that fail to encrypt are flagged as having failed and remain
+unchanged, these failures can be caught using
+[error handling methods](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// EncryptConfig contains configuration fields for the Encrypt processor.
+type EncryptConfig struct {
+	Parts       []int    `json:"parts" yaml:"parts"`
+	Algorithm   string   `json:"algorithm" yaml:"algorithm"`
+	Key         string   `json:"key" yaml:"key"`
+	KeyEncoding string   `json:"key_encoding" yaml:"key_encoding"`
+	JSONPaths   []string `json:"json_paths" yaml:"json_paths"`
+}
+
+// NewEncryptConfig returns a EncryptConfig with default values.
+func NewEncryptConfig() EncryptConfig {
+	return EncryptConfig{
+		Parts:       []int{},
+		Algorithm:   "aes-gcm",
+		Key:         "",
+		KeyEncoding: "hex",
+		JSONPaths:   []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type aeadFunc func(key []byte) (cipher.AEAD, error)
+
+func aesGCMAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func strToAEAD(str string) (aeadFunc, error) {
+	switch str {
+	case "aes-gcm":
+		return aesGCMAEAD, nil
+	case "chacha20-poly1305":
+		return chacha20poly1305.New, nil
+	}
+	return nil, fmt.Errorf("encryption algorithm not recognised: %v", str)
+}
+
+type keyDecodeFunc func(key string) ([]byte, error)
+
+func strToKeyDecoder(str string) (keyDecodeFunc, error) {
+	switch str {
+	case "hex":
+		return hex.DecodeString, nil
+	case "base64":
+		return base64.StdEncoding.DecodeString, nil
+	case "raw":
+		return func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("key encoding not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
+// aeadProvider resolves the AEAD cipher of a message part, which is fixed when
+// the key contains no interpolation functions.
+type aeadProvider struct {
+	newAEAD   aeadFunc
+	decodeKey keyDecodeFunc
+
+	key        *text.InterpolatedString
+	staticAEAD cipher.AEAD
+}
+
+func newAEADProvider(algorithm, key, keyEncoding string) (*aeadProvider, error) {
+	newAEAD, err := strToAEAD(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	decodeKey, err := strToKeyDecoder(keyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, errors.New("a key must be specified")
+	}
+	p := &aeadProvider{
+		newAEAD:   newAEAD,
+		decodeKey: decodeKey,
+		key:       text.NewInterpolatedString(key),
+	}
+	if !text.ContainsFunctionVariables([]byte(key)) {
+		if p.staticAEAD, err = p.create(key); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *aeadProvider) create(key string) (cipher.AEAD, error) {
+	keyBytes, err := p.decodeKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %v", err)
+	}
+	aead, err := p.newAEAD(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return aead, nil
+}
+
+func (p *aeadProvider) get(msg types.Message, index int) (cipher.AEAD, error) {
+	if p.staticAEAD != nil {
+		return p.staticAEAD, nil
+	}
+	return p.create(p.key.Get(message.Lock(msg, index)))
+}
+
+func aeadSeal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func aeadOpen(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+//------------------------------------------------------------------------------
+
+// Encrypt is a processor that encrypts messages, or fields of JSON documents,
+// using an authenticated encryption algorithm.
+type Encrypt struct {
+	parts     []int
+	provider  *aeadProvider
+	jsonPaths [][]string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrKey    metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewEncrypt returns a Encrypt processor.
+func NewEncrypt(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	provider, err := newAEADProvider(conf.Encrypt.Algorithm, conf.Encrypt.Key, conf.Encrypt.KeyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	var jsonPaths [][]string
+	for _, p := range conf.Encrypt.JSONPaths {
+		if len(p) == 0 {
+			return nil, errors.New("json paths must not be empty")
+		}
+		jsonPaths = append(jsonPaths, strings.Split(p, "."))
+	}
+	return &Encrypt{
+		parts:     conf.Encrypt.Parts,
+		provider:  provider,
+		jsonPaths: jsonPaths,
+
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrKey:    stats.GetCounter("error.key"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErrJSONS:  stats.GetCounter("error.json_set"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (e *Encrypt) encryptFields(aead cipher.AEAD, part types.Part) error {
+	jsonPart, err := part.JSON()
+	if err == nil {
+		jsonPart, err = message.CopyJSON(jsonPart)
+	}
+	if err != nil {
+		e.mErrJSONP.Incr(1)
+		return fmt.Errorf("failed to parse part into json: %v", err)
+	}
+
+	gPart, _ := gabs.Consume(jsonPart)
+	for _, path := range e.jsonPaths {
+		if !gPart.Exists(path...) {
+			continue
+		}
+		var plaintext, ciphertext []byte
+		if plaintext, err = json.Marshal(gPart.S(path...).Data()); err != nil {
+			return fmt.Errorf("failed to serialise field '%v': %v", strings.Join(path, "."), err)
+		}
+		if ciphertext, err = aeadSeal(aead, plaintext); err != nil {
+			return err
+		}
+		if _, err = gPart.Set(base64.StdEncoding.EncodeToString(ciphertext), path...); err != nil {
+			e.mErrJSONS.Incr(1)
+			return fmt.Errorf("failed to set field '%v': %v", strings.Join(path, "."), err)
+		}
+	}
+	if err = part.SetJSON(gPart.Data()); err != nil {
+		e.mErrJSONS.Incr(1)
+		return fmt.Errorf("failed to convert json into part: %v", err)
+	}
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (e *Encrypt) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	e.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		aead, err := e.provider.get(newMsg, index)
+		if err != nil {
+			e.mErrKey.Incr(1)
+			e.mErr.Incr(1)
+			e.log.Debugf("Failed to resolve key: %v\n", err)
+			return err
+		}
+		if len(e.jsonPaths) > 0 {
+			if err = e.encryptFields(aead, part); err != nil {
+				e.mErr.Incr(1)
+				e.log.Debugf("Failed to encrypt fields: %v\n", err)
+			}
+			return err
+		}
+		ciphertext, err := aeadSeal(aead, part.Get())
+		if err != nil {
+			e.mErr.Incr(1)
+			e.log.Debugf("Failed to encrypt message: %v\n", err)
+			return err
+		}
+		part.Set(ciphertext)
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeEncrypt, e.parts, newMsg, proc)
+
+	e.mBatchSent.Incr(1)
+	e.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (e *Encrypt) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (e *Encrypt) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,268 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+const (
+	testKey16 = "000102030405060708090a0b0c0d0e0f"
+	testKey32 = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
+func TestEncryptBadConfig(t *testing.T) {
+	tests := map[string]EncryptConfig{
+		"bad algorithm": {
+			Algorithm:   "nope",
+			Key:         testKey32,
+			KeyEncoding: "hex",
+		},
+		"bad key encoding": {
+			Algorithm:   "aes-gcm",
+			Key:         testKey32,
+			KeyEncoding: "nope",
+		},
+		"empty key": {
+			Algorithm:   "aes-gcm",
+			KeyEncoding: "hex",
+		},
+		"invalid hex key": {
+			Algorithm:   "aes-gcm",
+			Key:         "not hex",
+			KeyEncoding: "hex",
+		},
+		"wrong aes key size": {
+			Algorithm:   "aes-gcm",
+			Key:         "0001020304",
+			KeyEncoding: "hex",
+		},
+		"wrong chacha key size": {
+			Algorithm:   "chacha20-poly1305",
+			Key:         testKey16,
+			KeyEncoding: "hex",
+		},
+		"empty json path": {
+			Algorithm:   "aes-gcm",
+			Key:         testKey32,
+			KeyEncoding: "hex",
+			JSONPaths:   []string{""},
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Encrypt = test
+		if _, err := NewEncrypt(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: Expected error", name)
+		}
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	type test struct {
+		algorithm   string
+		key         string
+		keyEncoding string
+	}
+	tests := []test{
+		{algorithm: "aes-gcm", key: testKey16, keyEncoding: "hex"},
+		{algorithm: "aes-gcm", key: testKey32, keyEncoding: "hex"},
+		{algorithm: "aes-gcm", key: "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345", keyEncoding: "raw"},
+		{algorithm: "chacha20-poly1305", key: testKey32, keyEncoding: "hex"},
+		{
+			algorithm:   "chacha20-poly1305",
+			key:         base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)),
+			keyEncoding: "base64",
+		},
+	}
+
+	input := [][]byte{
+		[]byte("hello world"),
+		[]byte(""),
+		[]byte(`{"foo":"bar"}`),
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Encrypt.Algorithm = test.algorithm
+		conf.Encrypt.Key = test.key
+		conf.Encrypt.KeyEncoding = test.keyEncoding
+		conf.Decrypt.Algorithm = test.algorithm
+		conf.Decrypt.Key = test.key
+		conf.Decrypt.KeyEncoding = test.keyEncoding
+
+		enc, err := NewEncrypt(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, _ := enc.ProcessMessage(message.New(input))
+		if len(msgs) != 1 {
+			t.Fatalf("%v: Encrypt failed", test.algorithm)
+		}
+		for i := 0; i < msgs[0].Len(); i++ {
+			if HasFailed(msgs[0].Get(i)) {
+				t.Errorf("%v: Part %v failed to encrypt", test.algorithm, i)
+			}
+			if bytes.Contains(msgs[0].Get(i).Get(), input[i]) && len(input[i]) > 0 {
+				t.Errorf("%v: Part %v contains plaintext", test.algorithm, i)
+			}
+		}
+
+		// Encrypting the same input twice must produce different outputs.
+		secondMsgs, _ := enc.ProcessMessage(message.New(input))
+		if bytes.Equal(msgs[0].Get(0).Get(), secondMsgs[0].Get(0).Get()) {
+			t.Errorf("%v: Nonce was reused", test.algorithm)
+		}
+
+		if msgs, _ = dec.ProcessMessage(msgs[0]); len(msgs) != 1 {
+			t.Fatalf("%v: Decrypt failed", test.algorithm)
+		}
+		for i := 0; i < msgs[0].Len(); i++ {
+			if HasFailed(msgs[0].Get(i)) {
+				t.Errorf("%v: Part %v failed to decrypt", test.algorithm, i)
+			}
+			if exp, act := input[i], msgs[0].Get(i).Get(); !bytes.Equal(exp, act) {
+				t.Errorf("%v: Wrong result of part %v: %s != %s", test.algorithm, i, act, exp)
+			}
+		}
+	}
+}
+
+func TestEncryptJSONPaths(t *testing.T) {
+	conf := NewConfig()
+	conf.Encrypt.Key = testKey32
+	conf.Encrypt.JSONPaths = []string{"user.email", "user.age", "tags", "does.not.exist"}
+	conf.Decrypt.Key = testKey32
+	conf.Decrypt.JSONPaths = conf.Encrypt.JSONPaths
+
+	enc, err := NewEncrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"id":"foo","tags":["a","b"],"user":{"age":42,"email":"foo@example.com"}}`
+
+	msgs, _ := enc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+	if len(msgs) != 1 {
+		t.Fatal("Encrypt failed")
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Fatal("Part failed to encrypt")
+	}
+
+	var encrypted map[string]interface{}
+	if err = json.Unmarshal(msgs[0].Get(0).Get(), &encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo", encrypted["id"]; exp != act {
+		t.Errorf("Wrong unencrypted field: %v != %v", act, exp)
+	}
+	if _, ok := encrypted["tags"].(string); !ok {
+		t.Errorf("Expected encrypted string field, got: %v", encrypted["tags"])
+	}
+	user := encrypted["user"].(map[string]interface{})
+	for _, k := range []string{"age", "email"} {
+		str, ok := user[k].(string)
+		if !ok {
+			t.Errorf("Expected encrypted string field %v, got: %v", k, user[k])
+			continue
+		}
+		if _, err = base64.StdEncoding.DecodeString(str); err != nil {
+			t.Errorf("Expected base64 field %v: %v", k, err)
+		}
+	}
+	if _, exists := encrypted["does"]; exists {
+		t.Error("Missing path was created")
+	}
+
+	if msgs, _ = dec.ProcessMessage(msgs[0]); len(msgs) != 1 {
+		t.Fatal("Decrypt failed")
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Fatal("Part failed to decrypt")
+	}
+
+	var exp, act interface{}
+	if err = json.Unmarshal([]byte(input), &exp); err != nil {
+		t.Fatal(err)
+	}
+	if act, err = msgs[0].Get(0).JSON(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestEncryptInterpolatedKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Encrypt.Key = "${!metadata:key}"
+	conf.Decrypt.Key = testKey16
+
+	enc, err := NewEncrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewDecrypt(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inMsg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	inMsg.Get(0).Metadata().Set("key", testKey16)
+
+	msgs, _ := enc.ProcessMessage(inMsg)
+	if len(msgs) != 1 {
+		t.Fatal("Encrypt failed")
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Part with key failed to encrypt")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected part without key to fail")
+	}
+	if exp, act := "bar", string(msgs[0].Get(1).Get()); exp != act {
+		t.Errorf("Failed part was modified: %v != %v", act, exp)
+	}
+
+	msgs, _ = dec.ProcessMessage(message.New([][]byte{msgs[0].Get(0).Get()}))
+	if exp, act := "foo", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/condition"
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
@@ -69,6 +70,7 @@ func NewFilterConfig() FilterConfig {
 // Filter is a processor that checks each message against a condition and
 // rejects the message if a condition returns false.
 type Filter struct {
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 
@@ -92,6 +94,7 @@ func NewFilter(
 		)
 	}
 	return &Filter{
+		mgr:       mgr,
 		log:       log,
 		stats:     stats,
 		condition: cond,
@@ -125,6 +128,7 @@ func (c *Filter) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 	}
 	if !filterRes {
 		c.mDropped.Incr(int64(msg.Len()))
+		drop.Report(c.mgr, TypeFilter, msg)
 		return nil, response.NewAck()
 	}
 
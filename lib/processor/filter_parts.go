@@ -27,6 +27,7 @@ import (
 	"github.com/Jeffail/benthos/lib/condition"
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
@@ -74,6 +75,7 @@ func NewFilterPartsConfig() FilterPartsConfig {
 // FilterParts is a processor that checks each part from a message against a
 // condition and removes the part if the condition returns false.
 type FilterParts struct {
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 
@@ -97,6 +99,7 @@ func NewFilterParts(
 		)
 	}
 	return &FilterParts{
+		mgr:       mgr,
 		log:       log,
 		stats:     stats,
 		condition: cond,
@@ -116,6 +119,7 @@ func (c *FilterParts) ProcessMessage(msg types.Message) ([]types.Message, types.
 	c.mCount.Incr(1)
 
 	newMsg := message.New(nil)
+	droppedMsg := message.New(nil)
 
 	spans := tracing.CreateChildSpans(TypeFilterParts, msg)
 	defer func() {
@@ -124,6 +128,8 @@ func (c *FilterParts) ProcessMessage(msg types.Message) ([]types.Message, types.
 		}
 	}()
 
+	defer drop.Report(c.mgr, TypeFilterParts, droppedMsg)
+
 	for i := 0; i < msg.Len(); i++ {
 		if c.condition.Check(message.Lock(msg, i)) {
 			newMsg.Append(msg.Get(i).Copy())
@@ -135,6 +141,7 @@ func (c *FilterParts) ProcessMessage(msg types.Message) ([]types.Message, types.
 			)
 			spans[i].SetTag("result", false)
 			c.mDropped.Incr(1)
+			droppedMsg.Append(msg.Get(i))
 		}
 	}
 	if newMsg.Len() > 0 {
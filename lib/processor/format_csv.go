@@ -0,0 +1,264 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFormatCSV] = TypeSpec{
+		constructor: NewFormatCSV,
+		description: `
+Serialises messages containing JSON objects into lines of CSV, performing the
+inverse of the ` + "[`parse_csv`](#parse_csv)" + ` processor.
+
+Fields are written in the order of ` + "`columns`" + `, and fields missing
+from an object are written as empty values. When ` + "`columns`" + ` is empty
+the sorted keys of the first object of each batch are used instead. String
+values are written as they are, ` + "`null`" + ` values are written as empty
+values and all other values are written in their JSON form.
+
+When ` + "`header`" + ` is set to ` + "`true`" + ` a message containing the
+header row is added to the start of each batch, adopting the metadata of the
+first message. Combined with the ` + "[`archive`](#archive)" + ` processor
+using the ` + "`lines`" + ` format this allows batches to be written as
+complete CSV files:
+
+` + "``` yaml" + `
+output:
+  s3:
+    bucket: example-bucket
+    path: ${!count:files}-${!timestamp_unix_nano}.csv
+  processors:
+  - batch:
+      count: 1000
+  - format_csv:
+      columns: [ id, name, score ]
+      header: true
+  - archive:
+      format: lines
+` + "```" + `
+
+Messages that are not JSON objects remain unchanged and are flagged as having
+failed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FormatCSVConfig contains configuration fields for the FormatCSV processor.
+type FormatCSVConfig struct {
+	Parts     []int    `json:"parts" yaml:"parts"`
+	Columns   []string `json:"columns" yaml:"columns"`
+	Delimiter string   `json:"delimiter" yaml:"delimiter"`
+	Header    bool     `json:"header" yaml:"header"`
+}
+
+// NewFormatCSVConfig returns a FormatCSVConfig with default values.
+func NewFormatCSVConfig() FormatCSVConfig {
+	return FormatCSVConfig{
+		Parts:     []int{},
+		Columns:   []string{},
+		Delimiter: ",",
+		Header:    false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FormatCSV is a processor that serialises JSON objects into CSV records.
+type FormatCSV struct {
+	conf      FormatCSVConfig
+	delimiter rune
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewFormatCSV returns a FormatCSV processor.
+func NewFormatCSV(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	delim, err := csvDelimiter(conf.FormatCSV.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+	return &FormatCSV{
+		conf:      conf.FormatCSV,
+		delimiter: delim,
+		log:       log,
+		stats:     stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (f *FormatCSV) columns(msg types.Message) []string {
+	if len(f.conf.Columns) > 0 {
+		return f.conf.Columns
+	}
+	var columns []string
+	msg.Iter(func(i int, part types.Part) error {
+		if columns != nil {
+			return nil
+		}
+		jObj, err := part.JSON()
+		if err != nil {
+			return nil
+		}
+		if obj, ok := jObj.(map[string]interface{}); ok {
+			columns = make([]string, 0, len(obj))
+			for k := range obj {
+				columns = append(columns, k)
+			}
+			sort.Strings(columns)
+		}
+		return nil
+	})
+	return columns
+}
+
+func (f *FormatCSV) encode(record []string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = f.delimiter
+	if err := w.Write(record); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func csvValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (f *FormatCSV) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	f.mCount.Incr(1)
+	newMsg := msg.Copy()
+	columns := f.columns(msg)
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		jObj, err := part.JSON()
+		if err != nil {
+			f.mErrJSONP.Incr(1)
+			f.mErr.Incr(1)
+			f.log.Debugf("Failed to parse part into json: %v\n", err)
+			return err
+		}
+		obj, ok := jObj.(map[string]interface{})
+		if !ok {
+			f.mErr.Incr(1)
+			err = fmt.Errorf("expected JSON object, got %T", jObj)
+			f.log.Debugf("Failed to format part as CSV: %v\n", err)
+			return err
+		}
+
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			if record[i], err = csvValue(obj[col]); err != nil {
+				f.mErr.Incr(1)
+				f.log.Debugf("Failed to format field '%v': %v\n", col, err)
+				return err
+			}
+		}
+
+		var line []byte
+		if line, err = f.encode(record); err != nil {
+			f.mErr.Incr(1)
+			f.log.Debugf("Failed to format part as CSV: %v\n", err)
+			return err
+		}
+		part.Set(line)
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeFormatCSV, f.conf.Parts, newMsg, proc)
+
+	if f.conf.Header && newMsg.Len() > 0 && len(columns) > 0 {
+		if line, err := f.encode(columns); err == nil {
+			header := newMsg.Get(0).Copy()
+			header.Set(line)
+
+			parts := make([]types.Part, 0, newMsg.Len()+1)
+			parts = append(parts, header)
+			newMsg.Iter(func(i int, part types.Part) error {
+				parts = append(parts, part)
+				return nil
+			})
+			newMsg.SetAll(parts)
+		} else {
+			f.log.Errorf("Failed to format header row: %v\n", err)
+		}
+	}
+
+	f.mBatchSent.Incr(1)
+	f.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (f *FormatCSV) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (f *FormatCSV) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,146 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestFormatCSVColumns(t *testing.T) {
+	conf := NewConfig()
+	conf.FormatCSV.Columns = []string{"id", "name", "score"}
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewFormatCSV(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"id":"1","name":"foo","score":3.5}`),
+		[]byte(`{"id":2,"name":"bar, \"baz\"","extra":true}`),
+		[]byte(`{"id":3,"name":null,"score":{"a":1}}`),
+		[]byte(`[1,2,3]`),
+		[]byte(`not json`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+
+	exp := [][]byte{
+		[]byte(`1,foo,3.5`),
+		[]byte(`2,"bar, ""baz""",`),
+		[]byte(`3,,"{""a"":1}"`),
+		[]byte(`[1,2,3]`),
+		[]byte(`not json`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	expFailed := []bool{false, false, false, true, true}
+	for i, e := range expFailed {
+		if act := HasFailed(msgs[0].Get(i)); e != act {
+			t.Errorf("Wrong fail flag at %v: %v != %v", i, act, e)
+		}
+	}
+}
+
+func TestFormatCSVHeader(t *testing.T) {
+	conf := NewConfig()
+	conf.FormatCSV.Header = true
+	conf.FormatCSV.Delimiter = "\t"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewFormatCSV(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"name":"foo","id":1}`),
+		[]byte(`{"id":2,"name":"bar"}`),
+	})
+	msgIn.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte("id\tname"),
+		[]byte("1\tfoo"),
+		[]byte("2\tbar"),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "bar", msgs[0].Get(0).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong header metadata: %v != %v", act, exp)
+	}
+}
+
+func TestFormatCSVRoundTrip(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	fConf := NewConfig()
+	fConf.FormatCSV.Header = true
+
+	format, err := NewFormatCSV(fConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse, err := NewParseCSV(NewConfig(), nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := [][]byte{
+		[]byte(`{"a":"foo","b":"bar\nbaz"}`),
+		[]byte(`{"a":"1,2","b":"\"quoted\""}`),
+	}
+	msgs, _ := format.ProcessMessage(message.New(input))
+
+	var joined []byte
+	for i, part := range message.GetAllBytes(msgs[0]) {
+		if i > 0 {
+			joined = append(joined, '\n')
+		}
+		joined = append(joined, part...)
+	}
+
+	msgs, _ = parse.ProcessMessage(message.New([][]byte{joined}))
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(input, act) {
+		t.Errorf("Wrong result: %s != %s", act, input)
+	}
+}
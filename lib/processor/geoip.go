@@ -0,0 +1,459 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
+	"github.com/opentracing/opentracing-go"
+	"github.com/oschwald/geoip2-golang"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGeoIP] = TypeSpec{
+		constructor: NewGeoIP,
+		description: `
+Looks up an IP address found at a JSON path of each message against a
+[MaxMind](https://www.maxmind.com) database file, and merges the resulting
+location fields into the document at ` + "`target_path`" + `. If the target
+path is empty the fields are merged into the root of the document.
+
+The fields added depend on the type of the database, which can be a City,
+Country or ASN database from either the GeoIP2 or GeoLite2 family:
+
+- City: ` + "`city`, `subdivision`, `postal_code`, `country`, `country_code`, `continent_code`, `latitude`, `longitude`, `time_zone`" + `
+- Country: ` + "`country`, `country_code`, `continent_code`" + `
+- ASN: ` + "`asn`, `as_org`" + `
+
+Fields that are empty within the database are omitted. For example, with a
+City database and the default config, a message ` + "`{\"ip\":\"81.2.69.142\"}`" + `
+might become:
+
+` + "``` json" + `
+{
+  "ip": "81.2.69.142",
+  "geoip": {
+    "city": "London",
+    "continent_code": "EU",
+    "country": "United Kingdom",
+    "country_code": "GB",
+    "latitude": 51.5142,
+    "longitude": -0.0931,
+    "subdivision": "England",
+    "time_zone": "Europe/London"
+  }
+}
+` + "```" + `
+
+The database file is checked for changes every ` + "`reload_interval`" + `
+and reloaded when its modification time or size changes, allowing it to be
+updated without restarting the pipeline. Set the interval to an empty string
+in order to disable reloading.
+
+Messages where the IP address is missing, invalid or where the lookup fails are
+left unchanged and flagged as having failed, allowing you to use
+[error handling patterns](../error_handling.md). Addresses that are not found
+within the database are not considered a failure, and leave the document
+unchanged.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIPConfig contains configuration fields for the GeoIP processor.
+type GeoIPConfig struct {
+	Parts          []int  `json:"parts" yaml:"parts"`
+	File           string `json:"file" yaml:"file"`
+	IPPath         string `json:"ip_path" yaml:"ip_path"`
+	TargetPath     string `json:"target_path" yaml:"target_path"`
+	ReloadInterval string `json:"reload_interval" yaml:"reload_interval"`
+}
+
+// NewGeoIPConfig returns a GeoIPConfig with default values.
+func NewGeoIPConfig() GeoIPConfig {
+	return GeoIPConfig{
+		Parts:          []int{},
+		File:           "",
+		IPPath:         "ip",
+		TargetPath:     "geoip",
+		ReloadInterval: "1m",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// geoipDB is a database that resolves IP addresses into location fields.
+type geoipDB interface {
+	lookup(ip net.IP) (map[string]interface{}, error)
+	close() error
+}
+
+// openGeoIPDB opens a database file, and can be replaced in tests.
+var openGeoIPDB = func(path string) (geoipDB, error) {
+	r, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	dbType := r.Metadata().DatabaseType
+	switch {
+	case strings.Contains(dbType, "ASN"):
+		return &maxmindDB{reader: r, lookupFn: maxmindASN}, nil
+	case strings.Contains(dbType, "City"), strings.Contains(dbType, "Enterprise"):
+		return &maxmindDB{reader: r, lookupFn: maxmindCity}, nil
+	case strings.Contains(dbType, "Country"):
+		return &maxmindDB{reader: r, lookupFn: maxmindCountry}, nil
+	}
+	r.Close()
+	return nil, fmt.Errorf("database type not supported: %v", dbType)
+}
+
+type maxmindDB struct {
+	reader   *geoip2.Reader
+	lookupFn func(r *geoip2.Reader, ip net.IP) (map[string]interface{}, error)
+}
+
+func (m *maxmindDB) lookup(ip net.IP) (map[string]interface{}, error) {
+	return m.lookupFn(m.reader, ip)
+}
+
+func (m *maxmindDB) close() error {
+	return m.reader.Close()
+}
+
+func setIfNotEmpty(fields map[string]interface{}, key string, value interface{}) {
+	switch t := value.(type) {
+	case string:
+		if len(t) == 0 {
+			return
+		}
+	case uint:
+		if t == 0 {
+			return
+		}
+	}
+	fields[key] = value
+}
+
+func maxmindCity(r *geoip2.Reader, ip net.IP) (map[string]interface{}, error) {
+	rec, err := r.City(ip)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	setIfNotEmpty(fields, "city", rec.City.Names["en"])
+	if len(rec.Subdivisions) > 0 {
+		setIfNotEmpty(fields, "subdivision", rec.Subdivisions[0].Names["en"])
+	}
+	setIfNotEmpty(fields, "postal_code", rec.Postal.Code)
+	setIfNotEmpty(fields, "country", rec.Country.Names["en"])
+	setIfNotEmpty(fields, "country_code", rec.Country.IsoCode)
+	setIfNotEmpty(fields, "continent_code", rec.Continent.Code)
+	setIfNotEmpty(fields, "time_zone", rec.Location.TimeZone)
+	if rec.Location.Latitude != 0 || rec.Location.Longitude != 0 {
+		fields["latitude"] = rec.Location.Latitude
+		fields["longitude"] = rec.Location.Longitude
+	}
+	return fields, nil
+}
+
+func maxmindCountry(r *geoip2.Reader, ip net.IP) (map[string]interface{}, error) {
+	rec, err := r.Country(ip)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	setIfNotEmpty(fields, "country", rec.Country.Names["en"])
+	setIfNotEmpty(fields, "country_code", rec.Country.IsoCode)
+	setIfNotEmpty(fields, "continent_code", rec.Continent.Code)
+	return fields, nil
+}
+
+func maxmindASN(r *geoip2.Reader, ip net.IP) (map[string]interface{}, error) {
+	rec, err := r.ASN(ip)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	setIfNotEmpty(fields, "asn", rec.AutonomousSystemNumber)
+	setIfNotEmpty(fields, "as_org", rec.AutonomousSystemOrganization)
+	return fields, nil
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIP is a processor that enriches JSON documents with location fields
+// resolved from an IP address.
+type GeoIP struct {
+	parts      []int
+	file       string
+	ipPath     []string
+	targetPath []string
+
+	dbMut  sync.RWMutex
+	db     geoipDB
+	dbMod  time.Time
+	dbSize int64
+
+	closed     int32
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mNotFound  metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrIP     metrics.StatCounter
+	mErrLookup metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mReload    metrics.StatCounter
+	mReloadErr metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewGeoIP returns a GeoIP processor.
+func NewGeoIP(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.GeoIP.File) == 0 {
+		return nil, errors.New("a database file must be specified")
+	}
+	if len(conf.GeoIP.IPPath) == 0 {
+		return nil, errors.New("an ip_path must be specified")
+	}
+
+	var reloadInterval time.Duration
+	if len(conf.GeoIP.ReloadInterval) > 0 {
+		var err error
+		if reloadInterval, err = time.ParseDuration(conf.GeoIP.ReloadInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse reload_interval: %v", err)
+		}
+	}
+
+	g := &GeoIP{
+		parts:      conf.GeoIP.Parts,
+		file:       conf.GeoIP.File,
+		ipPath:     strings.Split(conf.GeoIP.IPPath, "."),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log,
+		stats:      stats,
+
+		mCount:     stats.GetCounter("count"),
+		mNotFound:  stats.GetCounter("not_found"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErrIP:     stats.GetCounter("error.ip_parse"),
+		mErrLookup: stats.GetCounter("error.lookup"),
+		mErrJSONS:  stats.GetCounter("error.json_set"),
+		mErr:       stats.GetCounter("error"),
+		mReload:    stats.GetCounter("reload.success"),
+		mReloadErr: stats.GetCounter("reload.error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if len(conf.GeoIP.TargetPath) > 0 {
+		g.targetPath = strings.Split(conf.GeoIP.TargetPath, ".")
+	}
+
+	if _, err := g.reload(); err != nil {
+		return nil, fmt.Errorf("failed to open database file: %v", err)
+	}
+
+	go g.loop(reloadInterval)
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+// reload opens the database file if it has changed since the last time it was
+// opened, and returns whether a new database was loaded.
+func (g *GeoIP) reload() (bool, error) {
+	info, err := os.Stat(g.file)
+	if err != nil {
+		return false, err
+	}
+	if g.db != nil && info.ModTime().Equal(g.dbMod) && info.Size() == g.dbSize {
+		return false, nil
+	}
+
+	db, err := openGeoIPDB(g.file)
+	if err != nil {
+		return false, err
+	}
+
+	g.dbMut.Lock()
+	prev := g.db
+	g.db = db
+	g.dbMod, g.dbSize = info.ModTime(), info.Size()
+	g.dbMut.Unlock()
+
+	if prev != nil {
+		prev.close()
+	}
+	return true, nil
+}
+
+func (g *GeoIP) loop(reloadInterval time.Duration) {
+	var reloadChan <-chan time.Time
+	if reloadInterval > 0 {
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+		reloadChan = ticker.C
+	}
+	defer func() {
+		g.dbMut.Lock()
+		g.db.close()
+		g.dbMut.Unlock()
+		close(g.closedChan)
+	}()
+
+	for {
+		select {
+		case <-reloadChan:
+			reloaded, err := g.reload()
+			if err != nil {
+				g.mReloadErr.Incr(1)
+				g.log.Errorf("Failed to reload database file: %v\n", err)
+			} else if reloaded {
+				g.mReload.Incr(1)
+				g.log.Infof("Reloaded database file: %v\n", g.file)
+			}
+		case <-g.closeChan:
+			return
+		}
+	}
+}
+
+func (g *GeoIP) lookup(ip net.IP) (map[string]interface{}, error) {
+	g.dbMut.RLock()
+	defer g.dbMut.RUnlock()
+	return g.db.lookup(ip)
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (g *GeoIP) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	g.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		jsonPart, err := part.JSON()
+		if err == nil {
+			jsonPart, err = message.CopyJSON(jsonPart)
+		}
+		if err != nil {
+			g.mErrJSONP.Incr(1)
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to parse part into json: %v\n", err)
+			return err
+		}
+
+		gPart, _ := gabs.Consume(jsonPart)
+		ipStr, ok := gPart.S(g.ipPath...).Data().(string)
+		if !ok {
+			g.mErrIP.Incr(1)
+			g.mErr.Incr(1)
+			err = fmt.Errorf("string not found at path '%v'", strings.Join(g.ipPath, "."))
+			g.log.Debugf("Failed to extract IP address: %v\n", err)
+			return err
+		}
+		ip := net.ParseIP(strings.TrimSpace(ipStr))
+		if ip == nil {
+			g.mErrIP.Incr(1)
+			g.mErr.Incr(1)
+			err = fmt.Errorf("invalid IP address: %v", ipStr)
+			g.log.Debugf("Failed to parse IP address: %v\n", err)
+			return err
+		}
+
+		fields, err := g.lookup(ip)
+		if err != nil {
+			g.mErrLookup.Incr(1)
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to look up IP address: %v\n", err)
+			return err
+		}
+		if len(fields) == 0 {
+			g.mNotFound.Incr(1)
+			return nil
+		}
+
+		for k, v := range fields {
+			if _, err = gPart.Set(v, append(append([]string{}, g.targetPath...), k)...); err != nil {
+				g.mErrJSONS.Incr(1)
+				g.mErr.Incr(1)
+				g.log.Debugf("Failed to set target path: %v\n", err)
+				return err
+			}
+		}
+		if err = part.SetJSON(gPart.Data()); err != nil {
+			g.mErrJSONS.Incr(1)
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to convert json into part: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeGeoIP, g.parts, newMsg, proc)
+
+	g.mBatchSent.Incr(1)
+	g.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (g *GeoIP) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&g.closed, 0, 1) {
+		close(g.closeChan)
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (g *GeoIP) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	case <-g.closedChan:
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
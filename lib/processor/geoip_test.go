@@ -0,0 +1,222 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+type fakeGeoIPDB struct {
+	country string
+	closed  *int32
+}
+
+func (f *fakeGeoIPDB) lookup(ip net.IP) (map[string]interface{}, error) {
+	if ip.Equal(net.ParseIP("10.0.0.1")) {
+		return map[string]interface{}{}, nil
+	}
+	if ip.Equal(net.ParseIP("10.0.0.2")) {
+		return nil, errors.New("lookup failed")
+	}
+	return map[string]interface{}{
+		"country":      f.country,
+		"country_code": "XX",
+	}, nil
+}
+
+func (f *fakeGeoIPDB) close() error {
+	atomic.AddInt32(f.closed, 1)
+	return nil
+}
+
+func withFakeGeoIPDB(t *testing.T) (dbFile string, closed *int32, cleanup func()) {
+	dir, err := ioutil.TempDir("", "benthos_geoip_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbFile = filepath.Join(dir, "test.mmdb")
+	if err = ioutil.WriteFile(dbFile, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	closed = new(int32)
+	prevOpen := openGeoIPDB
+	openGeoIPDB = func(path string) (geoipDB, error) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return &fakeGeoIPDB{country: string(b), closed: closed}, nil
+	}
+	return dbFile, closed, func() {
+		openGeoIPDB = prevOpen
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGeoIPLookup(t *testing.T) {
+	dbFile, closed, cleanup := withFakeGeoIPDB(t)
+	defer cleanup()
+
+	conf := NewConfig()
+	conf.GeoIP.File = dbFile
+	conf.GeoIP.IPPath = "client.ip"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewGeoIP(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"client":{"ip":"81.2.69.142"},"geoip":{"foo":"bar"}}`),
+		[]byte(`{"client":{"ip":"10.0.0.1"}}`),
+		[]byte(`{"client":{"ip":"10.0.0.2"}}`),
+		[]byte(`{"client":{"ip":"not an ip"}}`),
+		[]byte(`{"client":{}}`),
+		[]byte(`not json`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+
+	exp := []string{
+		`{"client":{"ip":"81.2.69.142"},"geoip":{"country":"foo","country_code":"XX","foo":"bar"}}`,
+		`{"client":{"ip":"10.0.0.1"}}`,
+		`{"client":{"ip":"10.0.0.2"}}`,
+		`{"client":{"ip":"not an ip"}}`,
+		`{"client":{}}`,
+		`not json`,
+	}
+	expFailed := []bool{false, false, true, true, true, true}
+	for i, e := range exp {
+		if act := string(msgs[0].Get(i).Get()); e != act {
+			t.Errorf("Wrong result at %v: %v != %v", i, act, e)
+		}
+		if act := HasFailed(msgs[0].Get(i)); expFailed[i] != act {
+			t.Errorf("Wrong fail flag at %v: %v != %v", i, act, expFailed[i])
+		}
+	}
+
+	proc.CloseAsync()
+	if err = proc.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := int32(1), atomic.LoadInt32(closed); exp != act {
+		t.Errorf("Wrong count of closed databases: %v != %v", act, exp)
+	}
+}
+
+func TestGeoIPRootTarget(t *testing.T) {
+	dbFile, _, cleanup := withFakeGeoIPDB(t)
+	defer cleanup()
+
+	conf := NewConfig()
+	conf.GeoIP.File = dbFile
+	conf.GeoIP.TargetPath = ""
+	conf.GeoIP.ReloadInterval = ""
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewGeoIP(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		proc.CloseAsync()
+		proc.WaitForClose(time.Second)
+	}()
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(`{"ip":"81.2.69.142"}`)}))
+	if exp, act := `{"country":"foo","country_code":"XX","ip":"81.2.69.142"}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestGeoIPReload(t *testing.T) {
+	dbFile, closed, cleanup := withFakeGeoIPDB(t)
+	defer cleanup()
+
+	conf := NewConfig()
+	conf.GeoIP.File = dbFile
+	conf.GeoIP.ReloadInterval = "10ms"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewGeoIP(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		proc.CloseAsync()
+		proc.WaitForClose(time.Second)
+	}()
+
+	if err = ioutil.WriteFile(dbFile, []byte("barbaz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `{"geoip":{"country":"barbaz","country_code":"XX"},"ip":"81.2.69.142"}`
+	var act string
+	for i := 0; i < 100; i++ {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(`{"ip":"81.2.69.142"}`)}))
+		if act = string(msgs[0].Get(0).Get()); act == exp {
+			break
+		}
+		<-time.After(10 * time.Millisecond)
+	}
+	if exp != act {
+		t.Errorf("Database was not reloaded: %v != %v", act, exp)
+	}
+	if atomic.LoadInt32(closed) < 1 {
+		t.Error("Expected previous database to be closed")
+	}
+}
+
+func TestGeoIPBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	if _, err := NewGeoIP(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing file")
+	}
+
+	conf.GeoIP.File = "/does/not/exist.mmdb"
+	if _, err := NewGeoIP(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from file that does not exist")
+	}
+}
@@ -21,19 +21,26 @@
 package processor
 
 import (
+	"crypto/hmac"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
 	"github.com/OneOfOne/xxhash"
 	"github.com/opentracing/opentracing-go"
+	"github.com/spaolacci/murmur3"
 )
 
 //------------------------------------------------------------------------------
@@ -43,7 +50,20 @@ func init() {
 		constructor: NewHash,
 		description: `
 Hashes messages according to the selected algorithm. Supported algorithms are:
-sha256, sha512, sha1, xxhash64.
+sha256, sha512, sha1, hmac_sha256, hmac_sha512, xxhash64, murmur3_32.
+
+The HMAC algorithms require the field ` + "`key`" + ` to be set, which should
+usually be provided with an environment variable, e.g.
+` + "`key: ${HASH_KEY}`" + `. The non-cryptographic algorithms xxhash64 and
+murmur3_32 produce the hash as a decimal string, and are considerably faster
+when the hash is only used for things such as partitioning or sampling.
+
+By default the contents of a message are replaced with its hash. Alternatively,
+the hash can be written to a metadata key with the field
+` + "`target_metadata`" + `, or to a field of the message as a JSON document
+with the field ` + "`target_path`" + `, in which cases the original contents of
+the message are preserved. When writing to a metadata key or JSON path the
+cryptographic algorithms produce the hash as a hex encoded string.
 
 This processor is mostly useful when combined with the
 ` + "[`process_field`](#process_field)" + ` processor as it allows you to hash a
@@ -56,6 +76,15 @@ process_field:
   processors:
   - hash:
       algorithm: sha256
+` + "```" + `
+
+Or, to add a signature to a document without modifying its contents:
+
+` + "``` yaml" + `
+hash:
+  algorithm: hmac_sha256
+  key: ${SIGNING_KEY}
+  target_metadata: signature
 ` + "```" + ``,
 	}
 }
@@ -64,15 +93,21 @@ process_field:
 
 // HashConfig contains configuration fields for the Hash processor.
 type HashConfig struct {
-	Parts     []int  `json:"parts" yaml:"parts"`
-	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	Parts          []int  `json:"parts" yaml:"parts"`
+	Algorithm      string `json:"algorithm" yaml:"algorithm"`
+	Key            string `json:"key" yaml:"key"`
+	TargetMetadata string `json:"target_metadata" yaml:"target_metadata"`
+	TargetPath     string `json:"target_path" yaml:"target_path"`
 }
 
 // NewHashConfig returns a HashConfig with default values.
 func NewHashConfig() HashConfig {
 	return HashConfig{
-		Parts:     []int{},
-		Algorithm: "sha256",
+		Parts:          []int{},
+		Algorithm:      "sha256",
+		Key:            "",
+		TargetMetadata: "",
+		TargetPath:     "",
 	}
 }
 
@@ -104,18 +139,55 @@ func xxhash64Hash(b []byte) ([]byte, error) {
 	return []byte(strconv.FormatUint(h.Sum64(), 10)), nil
 }
 
-func strToHashr(str string) (hashFunc, error) {
+func murmur3Hash(b []byte) ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(murmur3.Sum32(b)), 10)), nil
+}
+
+func hmacSha256Hash(key []byte) hashFunc {
+	return func(b []byte) ([]byte, error) {
+		hasher := hmac.New(sha256.New, key)
+		hasher.Write(b)
+		return hasher.Sum(nil), nil
+	}
+}
+
+func hmacSha512Hash(key []byte) hashFunc {
+	return func(b []byte) ([]byte, error) {
+		hasher := hmac.New(sha512.New, key)
+		hasher.Write(b)
+		return hasher.Sum(nil), nil
+	}
+}
+
+// strToHashr returns the hash function of an algorithm, and whether the hash
+// it produces is binary rather than a printable string.
+func strToHashr(str string, key []byte) (hashFunc, bool, error) {
+	switch str {
+	case "hmac_sha256", "hmac_sha512":
+		if len(key) == 0 {
+			return nil, false, fmt.Errorf("a key is required for hash algorithm: %v", str)
+		}
+		if str == "hmac_sha256" {
+			return hmacSha256Hash(key), true, nil
+		}
+		return hmacSha512Hash(key), true, nil
+	}
+	if len(key) > 0 {
+		return nil, false, fmt.Errorf("hash algorithm does not support a key: %v", str)
+	}
 	switch str {
 	case "sha1":
-		return sha1Hash, nil
+		return sha1Hash, true, nil
 	case "sha256":
-		return sha256Hash, nil
+		return sha256Hash, true, nil
 	case "sha512":
-		return sha512Hash, nil
+		return sha512Hash, true, nil
 	case "xxhash64":
-		return xxhash64Hash, nil
+		return xxhash64Hash, false, nil
+	case "murmur3_32":
+		return murmur3Hash, false, nil
 	}
-	return nil, fmt.Errorf("hash algorithm not recognised: %v", str)
+	return nil, false, fmt.Errorf("hash algorithm not recognised: %v", str)
 }
 
 //------------------------------------------------------------------------------
@@ -123,8 +195,10 @@ func strToHashr(str string) (hashFunc, error) {
 // Hash is a processor that can selectively hash parts of a message following a
 // chosen algorithm.
 type Hash struct {
-	conf HashConfig
-	fn   hashFunc
+	conf       HashConfig
+	fn         hashFunc
+	binary     bool
+	targetPath []string
 
 	log   log.Modular
 	stats metrics.Type
@@ -139,15 +213,24 @@ type Hash struct {
 func NewHash(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	cor, err := strToHashr(conf.Hash.Algorithm)
+	cor, binary, err := strToHashr(conf.Hash.Algorithm, []byte(conf.Hash.Key))
 	if err != nil {
 		return nil, err
 	}
+	if len(conf.Hash.TargetMetadata) > 0 && len(conf.Hash.TargetPath) > 0 {
+		return nil, errors.New("cannot set both target_metadata and target_path")
+	}
+	var targetPath []string
+	if len(conf.Hash.TargetPath) > 0 {
+		targetPath = strings.Split(conf.Hash.TargetPath, ".")
+	}
 	return &Hash{
-		conf:  conf.Hash,
-		fn:    cor,
-		log:   log,
-		stats: stats,
+		conf:       conf.Hash,
+		fn:         cor,
+		binary:     binary,
+		targetPath: targetPath,
+		log:        log,
+		stats:      stats,
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
@@ -158,6 +241,34 @@ func NewHash(
 
 //------------------------------------------------------------------------------
 
+// setTarget writes a hash to the configured metadata key or JSON path of a
+// part, leaving its contents otherwise unchanged.
+func (c *Hash) setTarget(part types.Part, hash []byte) error {
+	hashStr := string(hash)
+	if c.binary {
+		hashStr = hex.EncodeToString(hash)
+	}
+	if len(c.conf.TargetMetadata) > 0 {
+		part.Metadata().Set(c.conf.TargetMetadata, hashStr)
+		return nil
+	}
+
+	jsonPart, err := part.JSON()
+	if err == nil {
+		jsonPart, err = message.CopyJSON(jsonPart)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse part into json: %v", err)
+	}
+	gPart, _ := gabs.Consume(jsonPart)
+	if _, err = gPart.Set(hashStr, c.targetPath...); err != nil {
+		return fmt.Errorf("failed to set target path: %v", err)
+	}
+	return part.SetJSON(gPart.Data())
+}
+
+//------------------------------------------------------------------------------
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (c *Hash) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -168,8 +279,13 @@ func (c *Hash) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 	proc := func(index int, span opentracing.Span, part types.Part) error {
 		newPart, err := c.fn(part.Get())
 		if err == nil {
-			newMsg.Get(index).Set(newPart)
-		} else {
+			if len(c.conf.TargetMetadata) > 0 || len(c.targetPath) > 0 {
+				err = c.setTarget(part, newPart)
+			} else {
+				newMsg.Get(index).Set(newPart)
+			}
+		}
+		if err != nil {
 			c.log.Debugf("Failed to hash message part: %v\n", err)
 			c.mErr.Incr(1)
 		}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -83,6 +84,7 @@ func NewHashSampleConfig() HashSampleConfig {
 // hashing its contents.
 type HashSample struct {
 	conf  Config
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 
@@ -100,6 +102,7 @@ func NewHashSample(
 ) (Type, error) {
 	return &HashSample{
 		conf:  conf,
+		mgr:   mgr,
 		log:   log,
 		stats: stats,
 
@@ -133,6 +136,7 @@ func (s *HashSample) ProcessMessage(msg types.Message) ([]types.Message, types.R
 			s.mDropOOB.Incr(1)
 			s.mDropped.Incr(1)
 			s.log.Debugf("Cannot sample message part %v for parts count: %v\n", index, lParts)
+			drop.Report(s.mgr, TypeHashSample, msg)
 			return nil, response.NewAck()
 		}
 
@@ -140,6 +144,7 @@ func (s *HashSample) ProcessMessage(msg types.Message) ([]types.Message, types.R
 		if _, err := hash.Write(msg.Get(index).Get()); nil != err {
 			s.mErr.Incr(1)
 			s.log.Debugf("Cannot hash message part for sampling: %v\n", err)
+			drop.Report(s.mgr, TypeHashSample, msg)
 			return nil, response.NewAck()
 		}
 	}
@@ -153,6 +158,7 @@ func (s *HashSample) ProcessMessage(msg types.Message) ([]types.Message, types.R
 	}
 
 	s.mDropped.Incr(int64(msg.Len()))
+	drop.Report(s.mgr, TypeHashSample, msg)
 	return nil, response.NewAck()
 }
 
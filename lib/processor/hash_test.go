@@ -24,6 +24,7 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/hex"
 	"os"
 	"reflect"
 	"strconv"
@@ -33,6 +34,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/OneOfOne/xxhash"
+	"github.com/spaolacci/murmur3"
 )
 
 func TestHashBadAlgo(t *testing.T) {
@@ -205,6 +207,139 @@ func TestHashXXHash64(t *testing.T) {
 	}
 }
 
+func TestHashHMAC(t *testing.T) {
+	tests := map[string]string{
+		"hmac_sha256": "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		"hmac_sha512": "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea250554" +
+			"9758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+	}
+
+	for algo, exp := range tests {
+		conf := NewConfig()
+		conf.Hash.Algorithm = algo
+		conf.Hash.Key = "Jefe"
+
+		proc, err := NewHash(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New([][]byte{
+			[]byte("what do ya want for nothing?"),
+		}))
+		if len(msgs) != 1 {
+			t.Fatalf("%v: Hash failed: %v", algo, res)
+		}
+		if act := hex.EncodeToString(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("%v: Unexpected output: %v != %v", algo, act, exp)
+		}
+	}
+}
+
+func TestHashBadKeys(t *testing.T) {
+	conf := NewConfig()
+	conf.Hash.Algorithm = "hmac_sha256"
+	if _, err := NewHash(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from hmac without key")
+	}
+
+	conf.Hash.Algorithm = "sha256"
+	conf.Hash.Key = "foo"
+	if _, err := NewHash(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from sha256 with key")
+	}
+}
+
+func TestHashMurmur3(t *testing.T) {
+	conf := NewConfig()
+	conf.Hash.Algorithm = "murmur3_32"
+
+	input := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+	}
+
+	exp := [][]byte{}
+	for i := range input {
+		exp = append(exp, []byte(strconv.FormatUint(uint64(murmur3.Sum32(input[i])), 10)))
+	}
+
+	proc, err := NewHash(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if len(msgs) != 1 {
+		t.Fatalf("Hash failed: %v", res)
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+}
+
+func TestHashTargetMetadata(t *testing.T) {
+	conf := NewConfig()
+	conf.Hash.Algorithm = "sha256"
+	conf.Hash.TargetMetadata = "hash"
+
+	proc, err := NewHash(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("hello world")}))
+	if len(msgs) != 1 {
+		t.Fatalf("Hash failed: %v", res)
+	}
+	if exp, act := "hello world", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Contents were modified: %v != %v", act, exp)
+	}
+	sum := sha256.Sum256([]byte("hello world"))
+	if exp, act := hex.EncodeToString(sum[:]), msgs[0].Get(0).Metadata().Get("hash"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+func TestHashTargetPath(t *testing.T) {
+	conf := NewConfig()
+	conf.Hash.Algorithm = "xxhash64"
+	conf.Hash.TargetPath = "meta.hash"
+
+	proc, err := NewHash(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte(`{"foo":"bar"}`)
+	msgs, res := proc.ProcessMessage(message.New([][]byte{input, []byte("not json")}))
+	if len(msgs) != 1 {
+		t.Fatalf("Hash failed: %v", res)
+	}
+
+	h := xxhash.New64()
+	h.Write(input)
+	exp := `{"foo":"bar","meta":{"hash":"` + strconv.FormatUint(h.Sum64(), 10) + `"}}`
+	if act := string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected valid document to pass")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected invalid document to fail")
+	}
+	if exp, act := "not json", string(msgs[0].Get(1).Get()); exp != act {
+		t.Errorf("Failed part was modified: %v != %v", act, exp)
+	}
+
+	conf.Hash.TargetMetadata = "foo"
+	if _, err = NewHash(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from both targets")
+	}
+}
+
 func TestHashIndexBounds(t *testing.T) {
 	conf := NewConfig()
 
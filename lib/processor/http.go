@@ -22,6 +22,7 @@ package processor
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -53,13 +54,21 @@ parallel requests with ` + "`max_parallel`" + `. Alternatively, you can use the
 ` + "[`archive`](#archive)" + ` processor to create a single message
 from the batch.
 
-The ` + "`rate_limit`" + ` field can be used to specify a rate limit
+The ` + "`request.rate_limit`" + ` field can be used to specify a rate limit
 [resource](../rate_limits/README.md) to cap the rate of requests across all
-parallel components service wide.
+parallel components service wide. When combined with ` + "`parallel`" + ` each
+individual request of a batch waits for access from the rate limit, and any
+requests still waiting when the processor is shut down are abandoned and flagged
+as failed.
 
 The URL and header values of this type can be dynamically set using function
 interpolations described [here](../config_interpolation.md#functions).
 
+The field ` + "`request.compression`" + ` can be set to ` + "`gzip`" + `,
+` + "`deflate` or `zstd`" + ` in order to compress request bodies, which also sets the
+` + "`Content-Encoding`" + ` header of requests accordingly. Compressed responses
+are decompressed automatically when the server supports gzip.
+
 In order to map or encode the payload to a specific request body, and map the
 response back into the original payload instead of replacing it entirely, you
 can use the ` + "[`process_map`](#process_map)" + ` or
@@ -111,6 +120,9 @@ type HTTP struct {
 	mErr       metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
+
+	closeChan chan struct{}
+	closed    int32
 }
 
 // NewHTTP returns a HTTP processor.
@@ -130,6 +142,8 @@ func NewHTTP(
 		mErr:       stats.GetCounter("error"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
+
+		closeChan: make(chan struct{}),
 	}
 	var err error
 	if g.client, err = client.New(
@@ -137,6 +151,7 @@ func NewHTTP(
 		client.OptSetLogger(g.log),
 		client.OptSetStats(metrics.Namespaced(g.stats, "client")),
 		client.OptSetManager(mgr),
+		client.OptSetCloseChan(g.closeChan),
 	); err != nil {
 		return nil, err
 	}
@@ -160,7 +175,7 @@ func (h *HTTP) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 			h.log.Errorf("HTTP parallel request to '%v' failed: %v\n", h.conf.HTTP.Client.URL, err)
 			responseMsg = msg.Copy()
 			responseMsg.Iter(func(i int, p types.Part) error {
-				FlagFail(p)
+				FlagErr(p, err)
 				return nil
 			})
 		} else {
@@ -201,7 +216,7 @@ func (h *HTTP) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 					if err == nil {
 						results[index].Set(result.Get(0).Get())
 					} else {
-						FlagFail(results[index])
+						FlagErr(results[index], err)
 					}
 					resChan <- err
 				}
@@ -240,6 +255,9 @@ func (h *HTTP) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (h *HTTP) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&h.closed, 0, 1) {
+		close(h.closeChan)
+	}
 }
 
 // WaitForClose blocks until the processor has closed down.
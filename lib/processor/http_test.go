@@ -32,8 +32,33 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
+type fakeRateLimit struct {
+	accesses int64
+	period   time.Duration
+}
+
+func (f *fakeRateLimit) Access() (time.Duration, error) {
+	atomic.AddInt64(&f.accesses, 1)
+	return f.period, nil
+}
+func (f *fakeRateLimit) CloseAsync()                              {}
+func (f *fakeRateLimit) WaitForClose(timeout time.Duration) error { return nil }
+
+type fakeRateLimitMgr struct {
+	types.DudMgr
+	ratelimits map[string]types.RateLimit
+}
+
+func (f *fakeRateLimitMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	if r, exists := f.ratelimits[name]; exists {
+		return r, nil
+	}
+	return nil, types.ErrRateLimitNotFound
+}
+
 func TestHTTPClientRetries(t *testing.T) {
 	var reqCount uint32
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -270,3 +295,99 @@ func TestHTTPClientParallelCapped(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", act, exp)
 	}
 }
+
+func TestHTTPClientParallelRateLimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("foobar"))
+	}))
+	defer ts.Close()
+
+	rl := &fakeRateLimit{}
+	mgr := &fakeRateLimitMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": rl,
+		},
+	}
+
+	conf := NewConfig()
+	conf.HTTP.Client.URL = ts.URL + "/testpost"
+	conf.HTTP.Client.RateLimit = "foo"
+	conf.HTTP.Parallel = true
+	conf.HTTP.MaxParallel = 2
+
+	h, err := NewHTTP(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := h.ProcessMessage(message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+		[]byte("qux"),
+		[]byte("quz"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if expC, actC := 5, msgs[0].Len(); actC != expC {
+		t.Fatalf("Wrong result count: %v != %v", actC, expC)
+	}
+	for i := 0; i < 5; i++ {
+		if exp, act := "foobar", string(msgs[0].Get(i).Get()); act != exp {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+	}
+	if exp, act := int64(5), atomic.LoadInt64(&rl.accesses); act != exp {
+		t.Errorf("Wrong count of rate limit accesses: %v != %v", act, exp)
+	}
+}
+
+func TestHTTPClientParallelRateLimitClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Did not expect a request")
+		w.Write([]byte("foobar"))
+	}))
+	defer ts.Close()
+
+	mgr := &fakeRateLimitMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{period: time.Hour},
+		},
+	}
+
+	conf := NewConfig()
+	conf.HTTP.Client.URL = ts.URL + "/testpost"
+	conf.HTTP.Client.RateLimit = "foo"
+	conf.HTTP.Parallel = true
+
+	h, err := NewHTTP(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		h.CloseAsync()
+	}()
+
+	msgs, res := h.ProcessMessage(message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if expC, actC := 3, msgs[0].Len(); actC != expC {
+		t.Fatalf("Wrong result count: %v != %v", actC, expC)
+	}
+	for i := 0; i < 3; i++ {
+		if !HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected failed flag on part %v", i)
+		}
+	}
+	if err := h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -21,6 +21,8 @@
 package processor
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -28,6 +30,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
 )
 
 //------------------------------------------------------------------------------
@@ -49,7 +52,29 @@ The new message will have metadata copied from the first pre-existing message of
 the batch.
 
 This processor will interpolate functions within the 'content' field, you can
-find a list of functions [here](../config_interpolation.md#functions).`,
+find a list of functions [here](../config_interpolation.md#functions). The
+function ` + "`json_field`" + ` can be given the index of a message, which
+allows the content to be generated from the JSON contents of another message of
+the batch.
+
+### Merge Mode
+
+When the field ` + "`mode`" + ` is set to ` + "`merge`" + `, rather than
+inserting a new message the content is parsed as a JSON document and merged into
+the JSON document of the existing message at the index, where an index of -1
+targets the last message of the batch. Fields that exist in both documents are
+combined into an array. For example, the following config merges the object
+` + "`meta`" + ` of the first message of a batch into the last message:
+
+` + "``` yaml" + `
+insert_part:
+  index: -1
+  mode: merge
+  content: ${!json_field:meta,0}
+` + "```" + `
+
+If the content or the target message cannot be parsed as JSON then the target
+message is left unchanged and flagged as having failed.`,
 	}
 }
 
@@ -59,6 +84,7 @@ find a list of functions [here](../config_interpolation.md#functions).`,
 type InsertPartConfig struct {
 	Index   int    `json:"index" yaml:"index"`
 	Content string `json:"content" yaml:"content"`
+	Mode    string `json:"mode" yaml:"mode"`
 }
 
 // NewInsertPartConfig returns a InsertPartConfig with default values.
@@ -66,6 +92,7 @@ func NewInsertPartConfig() InsertPartConfig {
 	return InsertPartConfig{
 		Index:   -1,
 		Content: "",
+		Mode:    "insert",
 	}
 }
 
@@ -76,12 +103,14 @@ func NewInsertPartConfig() InsertPartConfig {
 type InsertPart struct {
 	interpolate bool
 	part        []byte
+	merge       bool
 
 	conf  Config
 	log   log.Modular
 	stats metrics.Type
 
 	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
 }
@@ -92,7 +121,7 @@ func NewInsertPart(
 ) (Type, error) {
 	part := []byte(conf.InsertPart.Content)
 	interpolate := text.ContainsFunctionVariables(part)
-	return &InsertPart{
+	p := &InsertPart{
 		part:        part,
 		interpolate: interpolate,
 		conf:        conf,
@@ -100,9 +129,73 @@ func NewInsertPart(
 		stats:       stats,
 
 		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
-	}, nil
+	}
+	switch conf.InsertPart.Mode {
+	case "insert":
+	case "merge":
+		p.merge = true
+	default:
+		return nil, fmt.Errorf("insert_part mode not recognised: %v", conf.InsertPart.Mode)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// mergeJSON merges a JSON document into the JSON document of a message part.
+func (p *InsertPart) mergeJSON(part types.Part, content []byte) error {
+	var contentJSON interface{}
+	if err := json.Unmarshal(content, &contentJSON); err != nil {
+		return fmt.Errorf("failed to parse content as JSON: %v", err)
+	}
+	if _, isObj := contentJSON.(map[string]interface{}); !isObj {
+		return fmt.Errorf("expected content to be a JSON object, found %T", contentJSON)
+	}
+	jsonPart, err := part.JSON()
+	if err == nil {
+		jsonPart, err = message.CopyJSON(jsonPart)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	gPart, err := gabs.Consume(jsonPart)
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	gContent, err := gabs.Consume(contentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to parse content as JSON: %v", err)
+	}
+	if err = gPart.Merge(gContent); err != nil {
+		return fmt.Errorf("failed to merge content: %v", err)
+	}
+	return part.SetJSON(gPart.Data())
+}
+
+func (p *InsertPart) processMerge(msg types.Message, content []byte) ([]types.Message, types.Response) {
+	newMsg := msg.Copy()
+
+	index := p.conf.InsertPart.Index
+	if index < 0 {
+		index = newMsg.Len() + index
+	}
+	if index < 0 || index >= newMsg.Len() {
+		p.mErr.Incr(1)
+		p.log.Debugf("Merge index %v out of bounds for batch of size %v\n", p.conf.InsertPart.Index, newMsg.Len())
+	} else if err := p.mergeJSON(newMsg.Get(index), content); err != nil {
+		p.mErr.Incr(1)
+		p.log.Debugf("Failed to merge content: %v\n", err)
+		FlagErr(newMsg.Get(index), err)
+	}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
 }
 
 //------------------------------------------------------------------------------
@@ -119,6 +212,10 @@ func (p *InsertPart) ProcessMessage(msg types.Message) ([]types.Message, types.R
 		newPartBytes = p.part
 	}
 
+	if p.merge {
+		return p.processMerge(msg, newPartBytes)
+	}
+
 	index := p.conf.InsertPart.Index
 	msgLen := msg.Len()
 	if index < 0 {
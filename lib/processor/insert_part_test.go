@@ -252,3 +252,96 @@ func TestInsertPartInterpolation(t *testing.T) {
 		}
 	}
 }
+
+func TestInsertPartBadMode(t *testing.T) {
+	conf := NewConfig()
+	conf.InsertPart.Mode = "nope"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	if _, err := NewInsertPart(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+}
+
+func TestInsertPartMerge(t *testing.T) {
+	conf := NewConfig()
+	conf.InsertPart.Mode = "merge"
+	conf.InsertPart.Index = 0
+	conf.InsertPart.Content = `{"bar":${!json_field:bar,1}}`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewInsertPart(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		in     [][]byte
+		out    [][]byte
+		failed []bool
+	}
+
+	tests := []test{
+		{
+			in: [][]byte{
+				[]byte(`{"foo":"a"}`),
+				[]byte(`{"bar":{"baz":"b"}}`),
+			},
+			out: [][]byte{
+				[]byte(`{"bar":{"baz":"b"},"foo":"a"}`),
+				[]byte(`{"bar":{"baz":"b"}}`),
+			},
+			failed: []bool{false, false},
+		},
+		{
+			in: [][]byte{
+				[]byte(`{"bar":1}`),
+				[]byte(`{"bar":2}`),
+			},
+			out: [][]byte{
+				[]byte(`{"bar":[1,2]}`),
+				[]byte(`{"bar":2}`),
+			},
+			failed: []bool{false, false},
+		},
+		{
+			in: [][]byte{
+				[]byte(`not json`),
+				[]byte(`{"bar":"b"}`),
+			},
+			out: [][]byte{
+				[]byte(`not json`),
+				[]byte(`{"bar":"b"}`),
+			},
+			failed: []bool{true, false},
+		},
+		{
+			in: [][]byte{
+				[]byte(`{"foo":"a"}`),
+				[]byte(`{"baz":"b"}`),
+			},
+			out: [][]byte{
+				[]byte(`{"bar":null,"foo":"a"}`),
+				[]byte(`{"baz":"b"}`),
+			},
+			failed: []bool{false, false},
+		},
+	}
+
+	for _, test := range tests {
+		msgs, res := proc.ProcessMessage(message.New(test.in))
+		if len(msgs) != 1 {
+			t.Fatalf("Insert Part failed on: %s", test.in)
+		} else if res != nil {
+			t.Errorf("Expected nil response: %v", res)
+		}
+		if exp, act := test.out, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Unexpected output for %s: %s != %s", test.in, act, exp)
+		}
+		for i, exp := range test.failed {
+			if act := HasFailed(msgs[0].Get(i)); exp != act {
+				t.Errorf("Unexpected failed flag for %s at index %v: %v != %v", test.in, i, act, exp)
+			}
+		}
+	}
+}
@@ -24,6 +24,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -45,7 +48,10 @@ func init() {
 Parses messages as a JSON document, performs a mutation on the data, and then
 overwrites the previous contents with the new value.
 
-If the path is empty or "." the root of the data will be targeted.
+If the path is empty or "." the root of the data will be targeted. The
+` + "`pointer_get`, `pointer_set` and `pointer_delete`" + ` operators instead
+expect a [JSON Pointer](https://tools.ietf.org/html/rfc6901) path such as
+` + "`/foo/0/bar`" + `, where an empty path targets the root.
 
 This processor will interpolate functions within the 'value' field, you can find
 a list of functions [here](../config_interpolation.md#functions).
@@ -86,6 +92,20 @@ collision).
 Removes a key identified by the dot path. If the path does not exist this is a
 no-op.
 
+#### ` + "`flatten`" + `
+
+Replaces the object at a target dot path with a single level object where the
+keys of nested objects are joined with dots. E.g. the object
+` + "`{\"foo\":{\"bar\":5}}`" + ` becomes ` + "`{\"foo.bar\":5}`" + `. Arrays
+and empty objects are not flattened.
+
+#### ` + "`merge_patch`" + `
+
+Applies the value as a JSON merge patch, as described in
+[RFC 7386](https://tools.ietf.org/html/rfc7386), to the value at a target dot
+path. Fields of the patch with a ` + "`null`" + ` value are removed from the
+target.
+
 #### ` + "`move`" + `
 
 Moves the value of a target dot path (if it exists) to a new location. The
@@ -93,6 +113,46 @@ destination path is specified in the ` + "`value`" + ` field. If the destination
 path does not exist all objects in the path are created (unless there is a
 collision).
 
+#### ` + "`patch`" + `
+
+Applies the value as a JSON patch document, as described in
+[RFC 6902](https://tools.ietf.org/html/rfc6902), to the value at a target dot
+path. The patch operations are applied in order and if any of them fail,
+including a ` + "`test`" + ` operation, the message is flagged as failed and left
+unchanged.
+
+` + "``` yaml" + `
+json:
+  operator: patch
+  path: document
+  value:
+  - op: test
+    path: /type
+    value: user
+  - op: move
+    from: /name
+    path: /names/-
+` + "```" + `
+
+#### ` + "`pointer_delete`" + `
+
+Removes the value identified by a
+[JSON Pointer](https://tools.ietf.org/html/rfc6901) path. If the pointer does
+not exist this is a no-op.
+
+#### ` + "`pointer_get`" + `
+
+Reads the value identified by a JSON Pointer path and replaces the original
+contents entirely by the new value. If the pointer does not exist the message is
+flagged as failed.
+
+#### ` + "`pointer_set`" + `
+
+Sets the value identified by a JSON Pointer path. The parent of the target must
+already exist. When the parent is an array the final token of the pointer must
+either be an existing index, which is replaced, or ` + "`-`" + `, which appends
+the value to the array.
+
 #### ` + "`select`" + `
 
 Reads the value found at a dot path and replaced the original contents entirely
@@ -118,7 +178,14 @@ json:
 ` + "```" + `
 
 The value will be converted into '{"foo":{"bar":5}}'. If the YAML object
-contains keys that aren't strings those fields will be ignored.`,
+contains keys that aren't strings those fields will be ignored.
+
+#### ` + "`unflatten`" + `
+
+The inverse of ` + "`flatten`" + `, replaces the object at a target dot path with
+an object where keys containing dots are expanded into nested objects. If the
+expanded keys collide, e.g. ` + "`foo`" + ` and ` + "`foo.bar`" + ` both exist,
+the message is flagged as failed.`,
 	}
 }
 
@@ -444,7 +511,422 @@ func newAppendOperator(path []string) jsonOperator {
 	}
 }
 
-func getOperator(opStr string, path []string, value json.RawMessage) (jsonOperator, error) {
+func newMergePatchOperator(path []string) jsonOperator {
+	var mergePatch func(target, patch interface{}) interface{}
+	mergePatch = func(target, patch interface{}) interface{} {
+		patchObj, isObj := patch.(map[string]interface{})
+		if !isObj {
+			return patch
+		}
+		targetObj, isObj := target.(map[string]interface{})
+		if !isObj {
+			targetObj = map[string]interface{}{}
+		}
+		for k, v := range patchObj {
+			if v == nil {
+				delete(targetObj, k)
+			} else {
+				targetObj[k] = mergePatch(targetObj[k], v)
+			}
+		}
+		return targetObj
+	}
+
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		var patch interface{}
+		if err := json.Unmarshal([]byte(value), &patch); err != nil {
+			return nil, fmt.Errorf("failed to parse value: %v", err)
+		}
+		if len(path) == 0 {
+			return mergePatch(body, patch), nil
+		}
+
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message body: %v", err)
+		}
+		gPart.Set(mergePatch(gPart.S(path...).Data(), patch), path...)
+		return gPart.Data(), nil
+	}
+}
+
+func newPatchOperator(path []string) jsonOperator {
+	type patchOp struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		From  string      `json:"from"`
+		Value interface{} `json:"value"`
+	}
+
+	applyOp := func(target interface{}, op patchOp) (interface{}, error) {
+		tokens, err := parseJSONPointer(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return jsonPointerSet(target, tokens, op.Value, jsonPointerAdd)
+		case "remove":
+			return jsonPointerDelete(target, tokens)
+		case "replace":
+			return jsonPointerSet(target, tokens, op.Value, jsonPointerReplace)
+		case "move", "copy":
+			fromTokens, err := parseJSONPointer(op.From)
+			if err != nil {
+				return nil, err
+			}
+			v, err := jsonPointerGet(target, fromTokens)
+			if err != nil {
+				return nil, err
+			}
+			if op.Op == "move" {
+				if target, err = jsonPointerDelete(target, fromTokens); err != nil {
+					return nil, err
+				}
+			} else if v, err = message.CopyJSON(v); err != nil {
+				return nil, err
+			}
+			return jsonPointerSet(target, tokens, v, jsonPointerAdd)
+		case "test":
+			v, err := jsonPointerGet(target, tokens)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(v, op.Value) {
+				return nil, fmt.Errorf("test failed for path '%v'", op.Path)
+			}
+			return target, nil
+		}
+		return nil, fmt.Errorf("patch operation not recognised: %v", op.Op)
+	}
+
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		var ops []patchOp
+		if err := json.Unmarshal([]byte(value), &ops); err != nil {
+			return nil, fmt.Errorf("failed to parse patch: %v", err)
+		}
+
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message body: %v", err)
+		}
+
+		target := gPart.S(path...).Data()
+		for i, op := range ops {
+			if target, err = applyOp(target, op); err != nil {
+				return nil, fmt.Errorf("patch operation %v (%v) failed: %v", i, op.Op, err)
+			}
+		}
+
+		if len(path) == 0 {
+			return target, nil
+		}
+		gPart.Set(target, path...)
+		return gPart.Data(), nil
+	}
+}
+
+func newPointerGetOperator(pointer []string) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		v, err := jsonPointerGet(body, pointer)
+		if err != nil {
+			return nil, err
+		}
+		if str, isStr := v.(string); isStr {
+			return rawJSONValue(str), nil
+		}
+		return v, nil
+	}
+}
+
+func newPointerSetOperator(pointer []string) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(value), &data); err != nil {
+			return nil, fmt.Errorf("failed to parse value: %v", err)
+		}
+		return jsonPointerSet(body, pointer, data, jsonPointerUpsert)
+	}
+}
+
+func newPointerDeleteOperator(pointer []string) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		res, err := jsonPointerDelete(body, pointer)
+		if err == errJSONPointerNotFound {
+			return body, nil
+		}
+		return res, err
+	}
+}
+
+func newFlattenOperator(path []string) jsonOperator {
+	var flatten func(prefix string, obj map[string]interface{}, result map[string]interface{})
+	flatten = func(prefix string, obj map[string]interface{}, result map[string]interface{}) {
+		for k, v := range obj {
+			if child, isObj := v.(map[string]interface{}); isObj && len(child) > 0 {
+				flatten(prefix+k+".", child, result)
+				continue
+			}
+			result[prefix+k] = v
+		}
+	}
+
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message body: %v", err)
+		}
+
+		obj, isObj := gPart.S(path...).Data().(map[string]interface{})
+		if !isObj {
+			return nil, fmt.Errorf("object not found at path '%v'", strings.Join(path, "."))
+		}
+		result := map[string]interface{}{}
+		flatten("", obj, result)
+
+		if len(path) == 0 {
+			return result, nil
+		}
+		gPart.Set(result, path...)
+		return gPart.Data(), nil
+	}
+}
+
+func newUnflattenOperator(path []string) jsonOperator {
+	return func(body interface{}, value json.RawMessage) (interface{}, error) {
+		gPart, err := gabs.Consume(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message body: %v", err)
+		}
+
+		obj, isObj := gPart.S(path...).Data().(map[string]interface{})
+		if !isObj {
+			return nil, fmt.Errorf("object not found at path '%v'", strings.Join(path, "."))
+		}
+
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		result := map[string]interface{}{}
+		for _, k := range keys {
+			target := result
+			segments := strings.Split(k, ".")
+			for _, seg := range segments[:len(segments)-1] {
+				next, exists := target[seg]
+				if !exists {
+					next = map[string]interface{}{}
+					target[seg] = next
+				}
+				nextObj, isObj := next.(map[string]interface{})
+				if !isObj {
+					return nil, fmt.Errorf("key '%v' collides with a non-object value", k)
+				}
+				target = nextObj
+			}
+			leaf := segments[len(segments)-1]
+			if _, exists := target[leaf]; exists {
+				return nil, fmt.Errorf("key '%v' collides with an existing value", k)
+			}
+			target[leaf] = obj[k]
+		}
+
+		if len(path) == 0 {
+			return result, nil
+		}
+		gPart.Set(result, path...)
+		return gPart.Data(), nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var errJSONPointerNotFound = errors.New("value not found at pointer")
+
+type jsonPointerSetMode int
+
+const (
+	// Replaces an object key or array element, which must already exist.
+	jsonPointerReplace jsonPointerSetMode = iota
+
+	// Sets an object key or inserts an array element, as per the add operation
+	// of RFC 6902.
+	jsonPointerAdd
+
+	// Sets an object key or replaces an array element, where the array index
+	// "-" appends a new element.
+	jsonPointerUpsert
+)
+
+// parseJSONPointer parses an RFC 6901 JSON Pointer into reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if len(pointer) == 0 {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("JSON pointer '%v' must begin with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func jsonPointerIndex(token string, length int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index '%v'", token)
+	}
+	if i >= length {
+		return 0, errJSONPointerNotFound
+	}
+	return i, nil
+}
+
+func jsonPointerGet(root interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch t := root.(type) {
+		case map[string]interface{}:
+			var exists bool
+			if root, exists = t[token]; !exists {
+				return nil, errJSONPointerNotFound
+			}
+		case []interface{}:
+			i, err := jsonPointerIndex(token, len(t))
+			if err != nil {
+				return nil, err
+			}
+			root = t[i]
+		default:
+			return nil, errJSONPointerNotFound
+		}
+	}
+	return root, nil
+}
+
+// jsonPointerSet sets a value at a pointer and returns the (possibly new) root
+// of the document. Parent containers of the target must already exist.
+func jsonPointerSet(root interface{}, tokens []string, value interface{}, mode jsonPointerSetMode) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+	switch t := root.(type) {
+	case map[string]interface{}:
+		child, exists := t[token]
+		if last {
+			if !exists && mode == jsonPointerReplace {
+				return nil, errJSONPointerNotFound
+			}
+			t[token] = value
+			return t, nil
+		}
+		if !exists {
+			return nil, errJSONPointerNotFound
+		}
+		newChild, err := jsonPointerSet(child, tokens[1:], value, mode)
+		if err != nil {
+			return nil, err
+		}
+		t[token] = newChild
+		return t, nil
+	case []interface{}:
+		if last && token == "-" && mode != jsonPointerReplace {
+			return append(t, value), nil
+		}
+		length := len(t)
+		if last && mode == jsonPointerAdd {
+			length++
+		}
+		i, err := jsonPointerIndex(token, length)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			if mode == jsonPointerAdd {
+				t = append(t, nil)
+				copy(t[i+1:], t[i:])
+			}
+			t[i] = value
+			return t, nil
+		}
+		newChild, err := jsonPointerSet(t[i], tokens[1:], value, mode)
+		if err != nil {
+			return nil, err
+		}
+		t[i] = newChild
+		return t, nil
+	}
+	return nil, errJSONPointerNotFound
+}
+
+// jsonPointerDelete removes the value at a pointer and returns the (possibly
+// new) root of the document.
+func jsonPointerDelete(root interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+	switch t := root.(type) {
+	case map[string]interface{}:
+		child, exists := t[token]
+		if !exists {
+			return nil, errJSONPointerNotFound
+		}
+		if last {
+			delete(t, token)
+			return t, nil
+		}
+		newChild, err := jsonPointerDelete(child, tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		t[token] = newChild
+		return t, nil
+	case []interface{}:
+		i, err := jsonPointerIndex(token, len(t))
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return append(t[:i], t[i+1:]...), nil
+		}
+		newChild, err := jsonPointerDelete(t[i], tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		t[i] = newChild
+		return t, nil
+	}
+	return nil, errJSONPointerNotFound
+}
+
+//------------------------------------------------------------------------------
+
+func getOperator(opStr string, pathStr string, value json.RawMessage) (jsonOperator, error) {
+	switch opStr {
+	case "pointer_get", "pointer_set", "pointer_delete":
+		pointer, err := parseJSONPointer(pathStr)
+		if err != nil {
+			return nil, err
+		}
+		switch opStr {
+		case "pointer_get":
+			return newPointerGetOperator(pointer), nil
+		case "pointer_set":
+			return newPointerSetOperator(pointer), nil
+		}
+		return newPointerDeleteOperator(pointer), nil
+	}
+
+	path := strings.Split(pathStr, ".")
+	if len(pathStr) == 0 || pathStr == "." {
+		path = []string{}
+	}
+
 	var destPath []string
 	if opStr == "move" || opStr == "copy" {
 		var destDotPath string
@@ -470,6 +952,14 @@ func getOperator(opStr string, path []string, value json.RawMessage) (jsonOperat
 		return newAppendOperator(path), nil
 	case "clean":
 		return newCleanOperator(path), nil
+	case "merge_patch":
+		return newMergePatchOperator(path), nil
+	case "patch":
+		return newPatchOperator(path), nil
+	case "flatten":
+		return newFlattenOperator(path), nil
+	case "unflatten":
+		return newUnflattenOperator(path), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
@@ -517,13 +1007,8 @@ func NewJSON(
 
 	j.interpolate = text.ContainsFunctionVariables(j.valueBytes)
 
-	splitPath := strings.Split(conf.JSON.Path, ".")
-	if len(conf.JSON.Path) == 0 || conf.JSON.Path == "." {
-		splitPath = []string{}
-	}
-
 	var err error
-	if j.operator, err = getOperator(conf.JSON.Operator, splitPath, json.RawMessage(j.valueBytes)); err != nil {
+	if j.operator, err = getOperator(conf.JSON.Operator, conf.JSON.Path, json.RawMessage(j.valueBytes)); err != nil {
 		return nil, err
 	}
 	return j, nil
@@ -975,3 +975,160 @@ func TestJSONDelete(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONNewOperators(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	type jTest struct {
+		name     string
+		operator string
+		path     string
+		value    string
+		input    string
+		output   string
+		failed   bool
+	}
+
+	tests := []jTest{
+		{
+			name:     "merge patch root",
+			operator: "merge_patch",
+			value:    `{"a":"z","c":{"f":null},"d":[1]}`,
+			input:    `{"a":"b","c":{"d":"e","f":"g"}}`,
+			output:   `{"a":"z","c":{"d":"e"},"d":[1]}`,
+		},
+		{
+			name:     "merge patch path",
+			operator: "merge_patch",
+			path:     "foo",
+			value:    `{"bar":null,"baz":2}`,
+			input:    `{"foo":{"bar":1},"qux":3}`,
+			output:   `{"foo":{"baz":2},"qux":3}`,
+		},
+		{
+			name:     "patch ops",
+			operator: "patch",
+			value:    `[{"op":"add","path":"/arr/1","value":"x"},{"op":"remove","path":"/a"},{"op":"replace","path":"/b","value":5},{"op":"copy","from":"/b","path":"/c"},{"op":"move","from":"/arr/0","path":"/d"}]`,
+			input:    `{"a":1,"b":2,"arr":["y","z"]}`,
+			output:   `{"arr":["x","z"],"b":5,"c":5,"d":"y"}`,
+		},
+		{
+			name:     "patch path",
+			operator: "patch",
+			path:     "doc",
+			value:    `[{"op":"test","path":"/type","value":"user"},{"op":"add","path":"/names/-","value":"bar"}]`,
+			input:    `{"doc":{"type":"user","names":["foo"]}}`,
+			output:   `{"doc":{"names":["foo","bar"],"type":"user"}}`,
+		},
+		{
+			name:     "patch test fails",
+			operator: "patch",
+			value:    `[{"op":"remove","path":"/a"},{"op":"test","path":"/b","value":3}]`,
+			input:    `{"a":1,"b":2}`,
+			output:   `{"a":1,"b":2}`,
+			failed:   true,
+		},
+		{
+			name:     "pointer get",
+			operator: "pointer_get",
+			path:     "/foo/1/a~1b",
+			input:    `{"foo":[0,{"a/b":"bar"}]}`,
+			output:   `bar`,
+		},
+		{
+			name:     "pointer get missing",
+			operator: "pointer_get",
+			path:     "/foo/2",
+			input:    `{"foo":[0,1]}`,
+			output:   `{"foo":[0,1]}`,
+			failed:   true,
+		},
+		{
+			name:     "pointer set",
+			operator: "pointer_set",
+			path:     "/foo/0",
+			value:    `{"bar":true}`,
+			input:    `{"foo":[0,1]}`,
+			output:   `{"foo":[{"bar":true},1]}`,
+		},
+		{
+			name:     "pointer set append",
+			operator: "pointer_set",
+			path:     "/foo/-",
+			value:    `2`,
+			input:    `{"foo":[0,1]}`,
+			output:   `{"foo":[0,1,2]}`,
+		},
+		{
+			name:     "pointer delete",
+			operator: "pointer_delete",
+			path:     "/foo/0",
+			input:    `{"foo":[0,1]}`,
+			output:   `{"foo":[1]}`,
+		},
+		{
+			name:     "pointer delete missing",
+			operator: "pointer_delete",
+			path:     "/bar",
+			input:    `{"foo":[0,1]}`,
+			output:   `{"foo":[0,1]}`,
+		},
+		{
+			name:     "flatten",
+			operator: "flatten",
+			path:     "doc",
+			input:    `{"doc":{"a":{"b":{"c":1},"d":[{"e":2}]},"f":{}}}`,
+			output:   `{"doc":{"a.b.c":1,"a.d":[{"e":2}],"f":{}}}`,
+		},
+		{
+			name:     "unflatten",
+			operator: "unflatten",
+			input:    `{"a.b.c":1,"a.d":[{"e":2}],"f":{}}`,
+			output:   `{"a":{"b":{"c":1},"d":[{"e":2}]},"f":{}}`,
+		},
+		{
+			name:     "unflatten collision",
+			operator: "unflatten",
+			input:    `{"a":1,"a.b":2}`,
+			output:   `{"a":1,"a.b":2}`,
+			failed:   true,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.JSON.Operator = test.operator
+		conf.JSON.Path = test.path
+		if len(test.value) > 0 {
+			conf.JSON.Value = rawJSONValue(test.value)
+		}
+
+		jProc, err := NewJSON(conf, nil, tLog, tStats)
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		msgs, _ := jProc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+
+		if exp, act := test.output, string(message.GetAllBytes(msgs[0])[0]); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+		if exp, act := test.failed, HasFailed(msgs[0].Get(0)); exp != act {
+			t.Errorf("Wrong failed flag '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestJSONBadPointer(t *testing.T) {
+	conf := NewConfig()
+	conf.JSON.Operator = "pointer_get"
+	conf.JSON.Path = "foo.bar"
+
+	if _, err := NewJSON(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad pointer")
+	}
+}
@@ -21,7 +21,9 @@
 package processor
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,6 +33,7 @@ import (
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/aws/lambda/client"
+	"github.com/Jeffail/gabs"
 )
 
 //------------------------------------------------------------------------------
@@ -44,13 +47,20 @@ message part is the payload of the request, and the result of the invocation
 will become the new contents of the message.
 
 It is possible to perform requests per message of a batch in parallel by setting
-the ` + "`parallel`" + ` flag to ` + "`true`" + `. The ` + "`rate_limit`" + `
-field can be used to specify a rate limit [resource](../rate_limits/README.md)
-to cap the rate of requests across parallel components service wide.
+the ` + "`parallel`" + ` flag to ` + "`true`" + `, and the number of concurrent
+invocations can be capped with ` + "`max_parallel`" + `, where zero means no
+cap. The ` + "`rate_limit`" + ` field can be used to specify a rate limit
+[resource](../rate_limits/README.md) to cap the rate of requests across parallel
+components service wide.
+
+If ` + "`result_path`" + ` is set the message is parsed as a JSON document and
+the result of the invocation is set at the path, rather than replacing the
+contents of the message. Results that are valid JSON are inserted as structured
+values, otherwise they are inserted as a string.
 
 In order to map or encode the payload to a specific request body, and map the
-response back into the original payload instead of replacing it entirely, you
-can use the ` + "[`process_map`](#process_map)" + ` or
+response back into the original payload in more complex ways, you can use the
+` + "[`process_map`](#process_map)" + ` or
  ` + "[`process_field`](#process_field)" + ` processors.
 
 ### Error Handling
@@ -58,7 +68,14 @@ can use the ` + "[`process_map`](#process_map)" + ` or
 When all retry attempts for a message are exhausted the processor cancels the
 attempt. These failed messages will continue through the pipeline unchanged, but
 can be dropped or placed in a dead letter queue according to your config, you
-can read about these patterns [here](../error_handling.md).`,
+can read about these patterns [here](../error_handling.md).
+
+Errors returned by the function itself are not retried. Messages that result in
+a function error are also left unchanged and flagged as failed, but in addition
+the metadata field ` + "`lambda_function_error`" + ` is set to the
+` + "`errorType`" + ` reported by the function (or ` + "`Unhandled`" + ` when
+there isn't one), allowing them to be routed separately from invocations that
+failed to reach the function.`,
 	}
 }
 
@@ -67,14 +84,18 @@ can read about these patterns [here](../error_handling.md).`,
 // LambdaConfig contains configuration fields for the Lambda processor.
 type LambdaConfig struct {
 	client.Config `json:",inline" yaml:",inline"`
-	Parallel      bool `json:"parallel" yaml:"parallel"`
+	Parallel      bool   `json:"parallel" yaml:"parallel"`
+	MaxParallel   int    `json:"max_parallel" yaml:"max_parallel"`
+	ResultPath    string `json:"result_path" yaml:"result_path"`
 }
 
 // NewLambdaConfig returns a LambdaConfig with default values.
 func NewLambdaConfig() LambdaConfig {
 	return LambdaConfig{
-		Config:   client.NewConfig(),
-		Parallel: false,
+		Config:      client.NewConfig(),
+		Parallel:    false,
+		MaxParallel: 0,
+		ResultPath:  "",
 	}
 }
 
@@ -85,7 +106,9 @@ func NewLambdaConfig() LambdaConfig {
 type Lambda struct {
 	client *client.Type
 
-	parallel bool
+	parallel   bool
+	max        int
+	resultPath []string
 
 	conf  Config
 	log   log.Modular
@@ -93,6 +116,9 @@ type Lambda struct {
 
 	mCount     metrics.StatCounter
 	mErrLambda metrics.StatCounter
+	mErrFunc   metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
 	mErr       metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
@@ -108,13 +134,20 @@ func NewLambda(
 		stats: stats,
 
 		parallel: conf.Lambda.Parallel,
+		max:      conf.Lambda.MaxParallel,
 
 		mCount:     stats.GetCounter("count"),
 		mErrLambda: stats.GetCounter("error.lambda"),
+		mErrFunc:   stats.GetCounter("error.function"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErrJSONS:  stats.GetCounter("error.json_set"),
 		mErr:       stats.GetCounter("error"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
+	if len(conf.Lambda.ResultPath) > 0 {
+		l.resultPath = strings.Split(conf.Lambda.ResultPath, ".")
+	}
 	var err error
 	if l.client, err = client.New(
 		conf.Lambda.Config,
@@ -129,67 +162,118 @@ func NewLambda(
 
 //------------------------------------------------------------------------------
 
+// setResult writes the result of an invocation to a message part, either
+// replacing its contents or setting it at the configured result path.
+func (l *Lambda) setResult(part types.Part, result []byte) error {
+	if len(l.resultPath) == 0 {
+		part.Set(result)
+		return nil
+	}
+
+	jsonPart, err := part.JSON()
+	if err == nil {
+		jsonPart, err = message.CopyJSON(jsonPart)
+	}
+	if err != nil {
+		l.mErrJSONP.Incr(1)
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	var res interface{}
+	if err = json.Unmarshal(result, &res); err != nil {
+		res = string(result)
+	}
+
+	gPart, _ := gabs.Consume(jsonPart)
+	if _, err = gPart.Set(res, l.resultPath...); err == nil {
+		err = part.SetJSON(gPart.Data())
+	}
+	if err != nil {
+		l.mErrJSONS.Incr(1)
+		return fmt.Errorf("failed to set result: %v", err)
+	}
+	return nil
+}
+
+// invoke performs an invocation for a single message part of a batch and
+// writes the result into the corresponding part of the response.
+func (l *Lambda) invoke(index int, msg types.Message, part types.Part) {
+	result, err := l.client.Invoke(message.Lock(msg, index))
+	if err == nil && result.Len() != 1 {
+		err = fmt.Errorf("unexpected response size: %v", result.Len())
+	}
+	if err == nil {
+		err = l.setResult(part, result.Get(0).Get())
+	}
+	if err == nil {
+		return
+	}
+
+	l.mErr.Incr(1)
+	if fErr, isFuncErr := err.(client.ErrFunction); isFuncErr {
+		l.mErrFunc.Incr(1)
+		errType := fErr.Type
+		if len(errType) == 0 {
+			errType = fErr.Kind
+		}
+		part.Metadata().Set("lambda_function_error", errType)
+	} else {
+		l.mErrLambda.Incr(1)
+	}
+	l.log.Errorf("Lambda function '%v' failed: %v\n", l.conf.Lambda.Config.Function, err)
+	FlagErr(part, err)
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (l *Lambda) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	l.mCount.Incr(1)
-	var responseMsg types.Message
+
+	if msg.Len() < 1 {
+		l.mErr.Incr(1)
+		l.log.Errorf("Lambda response from '%v' was empty", l.conf.Lambda.Config.Function)
+		return nil, response.NewError(fmt.Errorf(
+			"lambda response from '%v' was empty", l.conf.Lambda.Config.Function,
+		))
+	}
+
+	parts := make([]types.Part, msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		parts[i] = p.Copy()
+		return nil
+	})
 
 	if !l.parallel || msg.Len() == 1 {
-		// Easy, just do a single request.
-		var err error
-		if responseMsg, err = l.client.Invoke(msg); err != nil {
-			l.mErr.Incr(1)
-			l.mErrLambda.Incr(1)
-			l.log.Errorf("Lambda function '%v' failed: %v\n", l.conf.Lambda.Config.Function, err)
-			responseMsg = msg
-			responseMsg.Iter(func(i int, p types.Part) error {
-				FlagFail(p)
-				return nil
-			})
+		for i, p := range parts {
+			l.invoke(i, msg, p)
 		}
 	} else {
-		parts := make([]types.Part, msg.Len())
-		msg.Iter(func(i int, p types.Part) error {
-			parts[i] = p.Copy()
-			return nil
-		})
+		max := l.max
+		if max <= 0 || msg.Len() < max {
+			max = msg.Len()
+		}
 
+		reqChan := make(chan int)
 		wg := sync.WaitGroup{}
-		wg.Add(msg.Len())
+		wg.Add(max)
 
-		for i := 0; i < msg.Len(); i++ {
-			go func(index int) {
-				result, err := l.client.Invoke(message.Lock(msg, index))
-				if err == nil && result.Len() != 1 {
-					err = fmt.Errorf("unexpected response size: %v", result.Len())
-				}
-				if err != nil {
-					l.mErr.Incr(1)
-					l.mErrLambda.Incr(1)
-					l.log.Errorf("Lambda parallel request to '%v' failed: %v\n", l.conf.Lambda.Config.Function, err)
-					FlagFail(parts[index])
-				} else {
-					parts[index] = result.Get(0)
+		for i := 0; i < max; i++ {
+			go func() {
+				for index := range reqChan {
+					l.invoke(index, msg, parts[index])
 				}
-
 				wg.Done()
-			}(i)
+			}()
 		}
-
+		for i := range parts {
+			reqChan <- i
+		}
+		close(reqChan)
 		wg.Wait()
-		responseMsg = message.New(nil)
-		responseMsg.SetAll(parts)
-	}
-
-	if responseMsg.Len() < 1 {
-		l.mErr.Incr(1)
-		l.log.Errorf("Lambda response from '%v' was empty", l.conf.Lambda.Config.Function)
-		return nil, response.NewError(fmt.Errorf(
-			"lambda response from '%v' was empty", l.conf.Lambda.Config.Function,
-		))
 	}
 
+	responseMsg := message.New(nil)
+	responseMsg.SetAll(parts)
 	msgs := [1]types.Message{responseMsg}
 
 	l.mBatchSent.Incr(1)
@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestLambdaSetResult(t *testing.T) {
+	type testCase struct {
+		name   string
+		path   string
+		input  string
+		result string
+		output string
+		errs   bool
+	}
+
+	tests := []testCase{
+		{
+			name:   "replace",
+			input:  `{"foo":"bar"}`,
+			result: `not json`,
+			output: `not json`,
+		},
+		{
+			name:   "json result",
+			path:   "foo.result",
+			input:  `{"foo":{"bar":"baz"}}`,
+			result: `{"count":10}`,
+			output: `{"foo":{"bar":"baz","result":{"count":10}}}`,
+		},
+		{
+			name:   "string result",
+			path:   "result",
+			input:  `{"foo":"bar"}`,
+			result: `not json`,
+			output: `{"foo":"bar","result":"not json"}`,
+		},
+		{
+			name:   "not json input",
+			path:   "result",
+			input:  `not json`,
+			result: `{"count":10}`,
+			output: `not json`,
+			errs:   true,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Lambda.ResultPath = test.path
+
+		l := &Lambda{
+			conf:      conf,
+			log:       log.Noop(),
+			stats:     metrics.Noop(),
+			mErrJSONP: metrics.Noop().GetCounter("foo"),
+			mErrJSONS: metrics.Noop().GetCounter("foo"),
+		}
+		if len(test.path) > 0 {
+			l.resultPath = strings.Split(test.path, ".")
+		}
+
+		part := message.New([][]byte{[]byte(test.input)}).Get(0)
+		err := l.setResult(part, []byte(test.result))
+		if test.errs && err == nil {
+			t.Errorf("%v: expected error", test.name)
+		} else if !test.errs && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		if exp, act := test.output, string(part.Get()); exp != act {
+			t.Errorf("%v: wrong result: %v != %v", test.name, act, exp)
+		}
+	}
+}
@@ -22,6 +22,7 @@ package processor
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -57,7 +58,8 @@ for_each:
 ` + "```" + `
 
 The ` + "`level`" + ` field determines the log level of the printed events and
-can be any of the following values: TRACE, DEBUG, INFO, WARN, ERROR.
+can be any of the following values: TRACE, DEBUG, INFO, WARN, ERROR. Levels are
+case insensitive.
 
 ### Structured Fields
 
@@ -104,6 +106,10 @@ type Log struct {
 	message *text.InterpolatedString
 	fields  map[string]*text.InterpolatedString
 	printFn func(logger log.Modular, msg string)
+
+	mCount     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
 }
 
 // NewLog returns a Log processor.
@@ -115,6 +121,10 @@ func NewLog(
 		level:   conf.Log.Level,
 		fields:  map[string]*text.InterpolatedString{},
 		message: text.NewInterpolatedString(conf.Log.Message),
+
+		mCount:     stats.GetCounter("count"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
 	}
 	if len(conf.Log.Fields) > 0 {
 		staticFields := map[string]string{}
@@ -139,7 +149,7 @@ func NewLog(
 //------------------------------------------------------------------------------
 
 func (l *Log) levelToLogFn(level string) (func(logger log.Modular, msg string), error) {
-	switch level {
+	switch strings.ToUpper(level) {
 	case "TRACE":
 		return func(logger log.Modular, msg string) {
 			logger.Traceln(msg)
@@ -168,6 +178,8 @@ func (l *Log) levelToLogFn(level string) (func(logger log.Modular, msg string),
 
 // ProcessMessage logs an event and returns the message unchanged.
 func (l *Log) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	l.mCount.Incr(1)
+
 	targetLog := l.log
 	if len(l.fields) > 0 {
 		interpFields := make(map[string]string, len(l.fields))
@@ -178,6 +190,9 @@ func (l *Log) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 	}
 	msgs := [1]types.Message{msg}
 	l.printFn(targetLog, l.message.Get(msg))
+
+	l.mBatchSent.Incr(1)
+	l.mSent.Incr(int64(msg.Len()))
 	return msgs[:], nil
 }
 
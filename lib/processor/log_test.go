@@ -127,6 +127,27 @@ func TestLogLevelTrace(t *testing.T) {
 	}
 }
 
+func TestLogLevelCaseInsensitive(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeLog
+	conf.Log.Message = "${!json_field:foo}"
+	conf.Log.Level = "warn"
+
+	logMock := &mockLog{}
+	l, err := New(conf, nil, logMock, metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{[]byte(`{"foo":"bar"}`)})
+	if _, res := l.ProcessMessage(input); res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []string{"bar"}, logMock.warns; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong log for warn: %v != %v", act, exp)
+	}
+}
+
 func TestLogWithFields(t *testing.T) {
 	conf := NewConfig()
 	conf.Type = TypeLog
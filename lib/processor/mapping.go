@@ -0,0 +1,216 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/mapping"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMapping] = TypeSpec{
+		constructor: NewMapping,
+		description: `
+Executes a mapping against each message part, restructuring its JSON contents
+and metadata in a single step. A mapping can rename, move and delete fields,
+perform arithmetic and string manipulation, branch on conditions and read or
+write metadata, which would otherwise require a long chain of ` + "`json`" + `,
+` + "`text`" + ` and ` + "`metadata`" + ` processors.
+
+For example, with the following config:
+
+` + "``` yaml" + `
+mapping:
+  mapping: |
+    root = this
+    root.user.name = this.first_name + " " + this.last_name
+    root.first_name = deleted()
+    root.last_name = deleted()
+    root.tier = if this.spend > 100 { "gold" } else { "standard" }
+    meta user_id = this.id
+` + "```" + `
+
+If the initial contents of a message were:
+
+` + "``` json" + `
+{"id":"u1","first_name":"Jane","last_name":"Doe","spend":150}
+` + "```" + `
+
+Then the resulting contents would be:
+
+` + "``` json" + `
+{"id":"u1","spend":150,"tier":"gold","user":{"name":"Jane Doe"}}
+` + "```" + `
+
+And the metadata key ` + "`user_id`" + ` would be set to ` + "`u1`" + `.
+
+### Assignments
+
+A mapping is a list of assignments, one per line, where lines beginning with
+` + "`#`" + ` are comments. The left hand side of an assignment is either
+` + "`root`" + `, a path within root such as ` + "`root.foo.bar`" + `, or a
+metadata key such as ` + "`meta foo`" + `. Field names containing whitespace or
+symbols can be quoted: ` + "`root.\"foo bar\"`" + `.
+
+The new document starts empty, and the contents of a part are only replaced if
+the mapping assigns to ` + "`root`" + `. Use ` + "`root = this`" + ` to begin
+with a copy of the original document. Assigning ` + "`deleted()`" + ` removes a
+field or metadata key, and an ` + "`if`" + ` expression where no branch matches
+skips the assignment entirely.
+
+### Expressions
+
+The right hand side of an assignment is an expression, which can be a literal
+(` + "`\"string\"`" + `, ` + "`5`" + `, ` + "`true`" + `, ` + "`null`" + `),
+` + "`this`" + ` (the original document) followed by any number of field
+accessors such as ` + "`this.foo.0.bar`" + `, or a function call. Accessing a
+field that does not exist results in ` + "`null`" + `.
+
+Expressions can be combined with the operators ` + "`+ - * / %`" + ` (where
+` + "`+`" + ` also concatenates strings), ` + "`== != > >= < <=`" + `,
+` + "`&& || !`" + ` and parentheses, and can be branched with
+` + "`if cond { a } else if cond { b } else { c }`" + `.
+
+The functions available are:
+
+- ` + "`content()`" + ` returns the raw contents of the part as a string.
+- ` + "`deleted()`" + ` removes the target of the assignment.
+- ` + "`meta(\"key\")`" + ` returns a metadata value or ` + "`null`" + `.
+- ` + "`timestamp_unix()`" + ` returns the current unix timestamp in seconds.
+
+And the methods that can be called on any expression are:
+
+- ` + "`contains(v)`" + `: whether a string contains a substring or an array contains a value.
+- ` + "`join(delim)`" + `: joins an array into a string.
+- ` + "`length()`" + `: the length of a string, array or object.
+- ` + "`lowercase()`" + `, ` + "`uppercase()`" + `, ` + "`trim()`" + `: string manipulation.
+- ` + "`number()`" + `: parses a string into a number.
+- ` + "`or(v)`" + `: returns ` + "`v`" + ` if the expression is ` + "`null`" + ` or fails.
+- ` + "`parse_json()`" + `: parses a string as a JSON document.
+- ` + "`replace(old, new)`" + `: replaces all occurrences of a substring.
+- ` + "`round()`" + `: rounds a number to the nearest integer.
+- ` + "`split(delim)`" + `: splits a string into an array.
+- ` + "`string()`" + `: converts a value into a string, serialising objects and arrays as JSON.
+
+If any assignment of a mapping fails the part is left unchanged and flagged as
+having failed, and can be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MappingConfig contains configuration fields for the Mapping processor.
+type MappingConfig struct {
+	Parts   []int  `json:"parts" yaml:"parts"`
+	Mapping string `json:"mapping" yaml:"mapping"`
+}
+
+// NewMappingConfig returns a MappingConfig with default values.
+func NewMappingConfig() MappingConfig {
+	return MappingConfig{
+		Parts:   []int{},
+		Mapping: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Mapping is a processor that executes a mapping against message parts,
+// replacing their contents and metadata with the result.
+type Mapping struct {
+	parts   []int
+	mapping *mapping.Mapping
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewMapping returns a Mapping processor.
+func NewMapping(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	m, err := mapping.New(conf.Mapping.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping: %v", err)
+	}
+	return &Mapping{
+		parts:   conf.Mapping.Parts,
+		mapping: m,
+		conf:    conf,
+		log:     log,
+		stats:   stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Mapping) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := p.mapping.Exec(part); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to execute mapping: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeMapping, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Mapping) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Mapping) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,90 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestMappingProcessor(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root = this
+root.full_name = this.first + " " + this.last
+root.first = deleted()
+root.last = deleted()
+meta id = this.id`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewMapping(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"id":"a","first":"foo","last":"bar"}`),
+		[]byte(`not json`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	if exp, act := `{"full_name":"foo bar","id":"a"}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := "a", msgs[0].Get(0).Metadata().Get("id"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected first part not to fail")
+	}
+
+	if exp, act := `not json`, string(msgs[0].Get(1).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected second part to fail")
+	}
+
+	if exp, act := `{"id":"a","first":"foo","last":"bar"}`, string(msgIn.Get(0).Get()); exp != act {
+		t.Errorf("Input message was modified: %v != %v", act, exp)
+	}
+}
+
+func TestMappingProcessorBadMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root.foo = this.bar.nope()`
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	if _, err := NewMapping(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad mapping")
+	}
+}
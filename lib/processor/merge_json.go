@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -41,7 +42,22 @@ Parses selected messages of a batch as JSON documents, attempts to merge them
 into one single JSON document and then writes it to a new message at the end of
 the batch. Merged parts are removed unless ` + "`retain_parts`" + ` is set to
 true. The new merged message will contain the metadata of the first part to be
-merged.`,
+merged.
+
+### Modes
+
+When ` + "`mode`" + ` is ` + "`merge`" + ` the JSON objects of the parts are
+deep merged into a single object, and any parts that are not JSON objects are
+ignored. When ` + "`mode`" + ` is ` + "`array`" + ` the JSON documents of the
+parts are instead placed in a single array in the order of the batch.
+
+### Collisions
+
+When merging, the field ` + "`collision`" + ` determines what happens when a
+key exists within more than one object and the values are not both objects
+(objects are always merged recursively). The strategy ` + "`array`" + `
+combines the values into an array, ` + "`first`" + ` keeps the value of the
+first part merged and ` + "`last`" + ` keeps the value of the last part merged.`,
 	}
 }
 
@@ -49,8 +65,10 @@ merged.`,
 
 // MergeJSONConfig contains configuration fields for the MergeJSON processor.
 type MergeJSONConfig struct {
-	Parts       []int `json:"parts" yaml:"parts"`
-	RetainParts bool  `json:"retain_parts" yaml:"retain_parts"`
+	Parts       []int  `json:"parts" yaml:"parts"`
+	RetainParts bool   `json:"retain_parts" yaml:"retain_parts"`
+	Mode        string `json:"mode" yaml:"mode"`
+	Collision   string `json:"collision" yaml:"collision"`
 }
 
 // NewMergeJSONConfig returns a MergeJSONConfig with default values.
@@ -58,6 +76,8 @@ func NewMergeJSONConfig() MergeJSONConfig {
 	return MergeJSONConfig{
 		Parts:       []int{},
 		RetainParts: false,
+		Mode:        "merge",
+		Collision:   "array",
 	}
 }
 
@@ -66,8 +86,10 @@ func NewMergeJSONConfig() MergeJSONConfig {
 // MergeJSON is a processor that merges JSON parsed message parts into a single
 // value.
 type MergeJSON struct {
-	parts  []int
-	retain bool
+	parts     []int
+	retain    bool
+	array     bool
+	collision string
 
 	log   log.Modular
 	stats metrics.Type
@@ -85,10 +107,11 @@ func NewMergeJSON(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
 	j := &MergeJSON{
-		parts:  conf.MergeJSON.Parts,
-		retain: conf.MergeJSON.RetainParts,
-		log:    log,
-		stats:  stats,
+		parts:     conf.MergeJSON.Parts,
+		retain:    conf.MergeJSON.RetainParts,
+		collision: conf.MergeJSON.Collision,
+		log:       log,
+		stats:     stats,
 
 		mCount:     stats.GetCounter("count"),
 		mErrJSONP:  stats.GetCounter("error.json_parse"),
@@ -97,11 +120,58 @@ func NewMergeJSON(
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
+	switch conf.MergeJSON.Mode {
+	case "merge":
+	case "array":
+		j.array = true
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", conf.MergeJSON.Mode)
+	}
+	switch conf.MergeJSON.Collision {
+	case "array", "first", "last":
+	default:
+		return nil, fmt.Errorf("collision strategy not recognised: %v", conf.MergeJSON.Collision)
+	}
 	return j, nil
 }
 
 //------------------------------------------------------------------------------
 
+// mergeObjects recursively merges the fields of src into dst, where colliding
+// fields that are not both objects are resolved by keeping either the first or
+// last value.
+func mergeObjects(dst, src map[string]interface{}, keepFirst bool) {
+	for k, v := range src {
+		existing, exists := dst[k]
+		if !exists {
+			dst[k] = v
+			continue
+		}
+		dstObj, dstIsObj := existing.(map[string]interface{})
+		srcObj, srcIsObj := v.(map[string]interface{})
+		if dstIsObj && srcIsObj {
+			mergeObjects(dstObj, srcObj, keepFirst)
+		} else if !keepFirst {
+			dst[k] = v
+		}
+	}
+}
+
+// merge combines a JSON document into the merged object of the processor.
+func (p *MergeJSON) merge(result *gabs.Container, doc interface{}) error {
+	if p.collision == "array" {
+		gDoc, err := gabs.Consume(doc)
+		if err != nil {
+			return err
+		}
+		return result.Merge(gDoc)
+	}
+	if obj, isObj := doc.(map[string]interface{}); isObj {
+		mergeObjects(result.Data().(map[string]interface{}), obj, p.collision == "first")
+	}
+	return nil
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -115,6 +185,7 @@ func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 	}()
 
 	newPart := gabs.New()
+	var newArray []interface{}
 	mergeFunc := func(index int) {
 		jsonPart, err := msg.Get(index).JSON()
 		if err == nil {
@@ -127,15 +198,15 @@ func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			return
 		}
 
-		var gPart *gabs.Container
-		if gPart, err = gabs.Consume(jsonPart); err != nil {
+		if p.array {
+			newArray = append(newArray, jsonPart)
+			return
+		}
+		if err = p.merge(newPart, jsonPart); err != nil {
 			p.mErrJSONP.Incr(1)
 			p.mErr.Incr(1)
 			p.log.Debugf("Failed to parse part into json: %v\n", err)
-			return
 		}
-
-		newPart.Merge(gPart)
 	}
 
 	var newMsg types.Message
@@ -173,12 +244,20 @@ func (p *MergeJSON) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 		firstPartCopy = msg.Get(p.parts[0]).Copy()
 	}
 
+	result := newPart.Data()
+	if p.array {
+		if newArray == nil {
+			newArray = []interface{}{}
+		}
+		result = newArray
+	}
+
 	i := newMsg.Append(firstPartCopy)
-	if err := newMsg.Get(i).SetJSON(newPart.Data()); err != nil {
+	if err := newMsg.Get(i).SetJSON(result); err != nil {
 		p.mErrJSONS.Incr(1)
 		p.mErr.Incr(1)
 		p.log.Debugf("Failed to marshal merged part into json: %v\n", err)
-		FlagFail(newMsg.Get(i))
+		FlagErr(newMsg.Get(i), err)
 	}
 
 	msgs := [1]types.Message{newMsg}
@@ -230,3 +230,114 @@ func TestMergeJSONNoRetention(t *testing.T) {
 		t.Errorf("Wrong metadata: %v != %v", act, exp)
 	}
 }
+
+func TestMergeJSONBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.MergeJSON.Mode = "nope"
+	if _, err := NewMergeJSON(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+
+	conf = NewConfig()
+	conf.MergeJSON.Collision = "nope"
+	if _, err := NewMergeJSON(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad collision strategy")
+	}
+}
+
+func TestMergeJSONCollisions(t *testing.T) {
+	type jTest struct {
+		name      string
+		collision string
+		input     []string
+		output    string
+	}
+
+	tests := []jTest{
+		{
+			name:      "first",
+			collision: "first",
+			input: []string{
+				`{"baz":{"foo":1,"bar":[1]},"qux":"a"}`,
+				`{"baz":{"foo":2,"quz":3},"qux":{"a":"b"}}`,
+				`{"baz":{"foo":3,"bar":[2]}}`,
+			},
+			output: `{"baz":{"bar":[1],"foo":1,"quz":3},"qux":"a"}`,
+		},
+		{
+			name:      "last",
+			collision: "last",
+			input: []string{
+				`{"baz":{"foo":1,"bar":[1]},"qux":"a"}`,
+				`{"baz":{"foo":2,"quz":3},"qux":{"a":"b"}}`,
+				`{"baz":{"foo":3,"bar":[2]}}`,
+			},
+			output: `{"baz":{"bar":[2],"foo":3,"quz":3},"qux":{"a":"b"}}`,
+		},
+		{
+			name:      "not objects",
+			collision: "last",
+			input: []string{
+				`{"foo":1}`,
+				`[1,2]`,
+				`"bar"`,
+			},
+			output: `{"foo":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.MergeJSON.Collision = test.collision
+
+		jMrg, err := NewMergeJSON(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		var input [][]byte
+		for _, in := range test.input {
+			input = append(input, []byte(in))
+		}
+		msgs, _ := jMrg.ProcessMessage(message.New(input))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+		if exp, act := 1, msgs[0].Len(); exp != act {
+			t.Fatalf("Wrong count of parts '%v': %v != %v", test.name, act, exp)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestMergeJSONArray(t *testing.T) {
+	conf := NewConfig()
+	conf.MergeJSON.Mode = "array"
+	conf.MergeJSON.Parts = []int{0, 2}
+
+	jMrg, err := NewMergeJSON(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := jMrg.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":1}`),
+		[]byte(`{"bar":2}`),
+		[]byte(`[3]`),
+		[]byte(`not json`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Processor did not succeed")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"bar":2}`),
+		[]byte(`not json`),
+		[]byte(`[{"foo":1},[3]]`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
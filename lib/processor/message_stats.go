@@ -0,0 +1,433 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/sketch"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMessageStats] = TypeSpec{
+		constructor: NewMessageStats,
+		description: `
+Samples a percentage of message parts (0 to 100) and tracks the distribution of
+their sizes in bytes and, when a ` + "`key`" + ` is configured, the approximate
+number of distinct key values and the most frequent of them. Messages are not
+modified.
+
+This is useful for diagnosing skewed partitions and producers of oversized
+messages directly from within a pipeline, for example:
+
+` + "``` yaml" + `
+message_stats:
+  key: ${!metadata:kafka_key}
+  sample: 10
+  top_n: 10
+` + "```" + `
+
+The key supports [interpolation functions](../config_interpolation.md#functions)
+resolved per message part. Key cardinality is estimated with a HyperLogLog and
+key frequencies with a count-min sketch, and therefore both are approximate but
+require a fixed amount of memory regardless of the number of distinct keys.
+
+The collected stats of all open ` + "`message_stats`" + ` processors are
+returned as a JSON object from the HTTP endpoint ` + "`/debug/messages`" + `,
+keyed by the path of the processor, and are discarded once the processors at a
+path are closed. Sizes are also exposed as metrics under the counters
+` + "`size.le_<bytes>`" + ` and the gauge ` + "`size.max`" + `, and key
+cardinality under the gauge ` + "`key.cardinality`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MessageStatsConfig contains configuration fields for the MessageStats
+// processor.
+type MessageStatsConfig struct {
+	Key        string  `json:"key" yaml:"key"`
+	Sample     float64 `json:"sample" yaml:"sample"`
+	TopN       int     `json:"top_n" yaml:"top_n"`
+	RandomSeed int64   `json:"seed" yaml:"seed"`
+}
+
+// NewMessageStatsConfig returns a MessageStatsConfig with default values.
+func NewMessageStatsConfig() MessageStatsConfig {
+	return MessageStatsConfig{
+		Key:        "",
+		Sample:     100.0,
+		TopN:       10,
+		RandomSeed: 0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// messageSizeBuckets are the inclusive upper bounds of the size histogram, in
+// bytes, with a final implicit bucket for all larger sizes.
+var messageSizeBuckets = []int64{
+	64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304,
+}
+
+// MessageSizeBucket is the number of sampled message parts with a size less
+// than or equal to a number of bytes and greater than the previous bucket. The
+// final bucket has an empty limit and counts all larger sizes.
+type MessageSizeBucket struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// MessageSizeSummary describes the distribution of sampled message part sizes.
+type MessageSizeSummary struct {
+	Min     int64               `json:"min"`
+	Max     int64               `json:"max"`
+	Mean    float64             `json:"mean"`
+	Buckets []MessageSizeBucket `json:"buckets"`
+}
+
+// MessageKeySummary describes the approximate cardinality and most frequent
+// values of the keys of sampled message parts.
+type MessageKeySummary struct {
+	Cardinality uint64            `json:"cardinality"`
+	Top         []sketch.TopEntry `json:"top"`
+}
+
+// MessageStatsSummary describes the sampled message parts of a message_stats
+// processor.
+type MessageStatsSummary struct {
+	Sampled int64              `json:"sampled"`
+	Size    MessageSizeSummary `json:"size"`
+	Key     *MessageKeySummary `json:"key,omitempty"`
+}
+
+// messageStats accumulates the sizes and keys of message parts for all
+// message_stats processors sharing a path.
+type messageStats struct {
+	sync.Mutex
+
+	sampled   int64
+	sizeTotal int64
+	sizeMin   int64
+	sizeMax   int64
+	buckets   []int64
+
+	keys *sketch.HyperLogLog
+	top  *sketch.TopK
+}
+
+func newMessageStats(topN int) *messageStats {
+	return &messageStats{
+		buckets: make([]int64, len(messageSizeBuckets)+1),
+		keys:    sketch.NewHyperLogLog(),
+		top:     sketch.NewTopK(topN),
+	}
+}
+
+// record adds a message part size and key and returns the index of its size
+// bucket, the largest size so far and the estimated key cardinality.
+func (m *messageStats) record(size int64, key []byte, keyed bool) (bucket int, max int64, cardinality uint64) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.sampled == 0 || size < m.sizeMin {
+		m.sizeMin = size
+	}
+	if size > m.sizeMax {
+		m.sizeMax = size
+	}
+	m.sampled++
+	m.sizeTotal += size
+
+	for bucket = 0; bucket < len(messageSizeBuckets); bucket++ {
+		if size <= messageSizeBuckets[bucket] {
+			break
+		}
+	}
+	m.buckets[bucket]++
+
+	if keyed {
+		m.keys.Add(sketch.Hash(key))
+		m.top.Add(key)
+		cardinality = m.keys.Count()
+	}
+	return bucket, m.sizeMax, cardinality
+}
+
+// growTop raises the number of most frequent keys tracked to at least topN.
+func (m *messageStats) growTop(topN int) {
+	m.Lock()
+	m.top.Grow(topN)
+	m.Unlock()
+}
+
+func (m *messageStats) summary(keyed bool, topN int) MessageStatsSummary {
+	m.Lock()
+	defer m.Unlock()
+
+	s := MessageStatsSummary{
+		Sampled: m.sampled,
+		Size: MessageSizeSummary{
+			Min:     m.sizeMin,
+			Max:     m.sizeMax,
+			Buckets: make([]MessageSizeBucket, len(m.buckets)),
+		},
+	}
+	if m.sampled > 0 {
+		s.Size.Mean = float64(m.sizeTotal) / float64(m.sampled)
+	}
+	for i, c := range m.buckets {
+		s.Size.Buckets[i].Count = c
+		if i < len(messageSizeBuckets) {
+			s.Size.Buckets[i].LE = fmt.Sprintf("%v", messageSizeBuckets[i])
+		}
+	}
+	if keyed {
+		s.Key = &MessageKeySummary{
+			Cardinality: m.keys.Count(),
+			Top:         m.top.Top(),
+		}
+		if len(s.Key.Top) > topN {
+			s.Key.Top = s.Key.Top[:topN]
+		}
+	}
+	return s
+}
+
+// messageStatsEntry is the set of open message_stats processors at a path,
+// which share their collected stats. Processors only share a path when they
+// are copies of the same config.
+type messageStatsEntry struct {
+	stats *messageStats
+	procs map[*MessageStats]struct{}
+}
+
+// summaryOpts returns whether any processor of the entry has a key and the
+// largest top_n of them.
+func (e *messageStatsEntry) summaryOpts() (keyed bool, topN int) {
+	for p := range e.procs {
+		keyed = keyed || p.key != nil
+		if p.conf.MessageStats.TopN > topN {
+			topN = p.conf.MessageStats.TopN
+		}
+	}
+	return
+}
+
+var messageStatsRegistry = struct {
+	sync.RWMutex
+	m map[string]*messageStatsEntry
+}{
+	m: map[string]*messageStatsEntry{},
+}
+
+// registerMessageStats adds a processor to the entry of its path and returns
+// the stats it shares with the other processors of that path.
+func registerMessageStats(path string, proc *MessageStats) *messageStats {
+	messageStatsRegistry.Lock()
+	defer messageStatsRegistry.Unlock()
+
+	e, exists := messageStatsRegistry.m[path]
+	if !exists {
+		e = &messageStatsEntry{
+			stats: newMessageStats(proc.conf.MessageStats.TopN),
+			procs: map[*MessageStats]struct{}{},
+		}
+		messageStatsRegistry.m[path] = e
+	}
+	e.procs[proc] = struct{}{}
+	e.stats.growTop(proc.conf.MessageStats.TopN)
+	return e.stats
+}
+
+// deregisterMessageStats removes a processor from the entry of its path, and
+// removes the entry once it has no processors left.
+func deregisterMessageStats(path string, proc *MessageStats) {
+	messageStatsRegistry.Lock()
+	defer messageStatsRegistry.Unlock()
+
+	e, exists := messageStatsRegistry.m[path]
+	if !exists {
+		return
+	}
+	delete(e.procs, proc)
+	if len(e.procs) == 0 {
+		delete(messageStatsRegistry.m, path)
+	}
+}
+
+// MessageStatsSummaries returns a summary of the sampled message parts of all
+// open message_stats processors, keyed by their paths.
+func MessageStatsSummaries() map[string]MessageStatsSummary {
+	type summaryEntry struct {
+		stats *messageStats
+		keyed bool
+		topN  int
+	}
+
+	messageStatsRegistry.RLock()
+	entries := make(map[string]summaryEntry, len(messageStatsRegistry.m))
+	for k, v := range messageStatsRegistry.m {
+		keyed, topN := v.summaryOpts()
+		entries[k] = summaryEntry{stats: v.stats, keyed: keyed, topN: topN}
+	}
+	messageStatsRegistry.RUnlock()
+
+	summaries := make(map[string]MessageStatsSummary, len(entries))
+	for k, v := range entries {
+		summaries[k] = v.stats.summary(v.keyed, v.topN)
+	}
+	return summaries
+}
+
+// MessageStatsHandler returns an HTTP handler that responds with the summaries
+// of all message_stats processors as a JSON object.
+func MessageStatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resBytes, err := json.Marshal(MessageStatsSummaries())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MessageStats is a processor that tracks the sizes and key frequencies of a
+// sample of message parts.
+type MessageStats struct {
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	path   string
+	key    *text.InterpolatedBytes
+	sample float64
+	gen    *rand.Rand
+	mut    sync.Mutex
+
+	collected *messageStats
+
+	mCount       metrics.StatCounter
+	mSampled     metrics.StatCounter
+	mSizeBuckets []metrics.StatCounter
+	mSizeMax     metrics.StatGauge
+	mCardinality metrics.StatGauge
+}
+
+// NewMessageStats returns a MessageStats processor.
+func NewMessageStats(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.MessageStats.TopN <= 0 {
+		return nil, fmt.Errorf("top_n must be greater than zero, got %v", conf.MessageStats.TopN)
+	}
+
+	path := metrics.Namespace(stats)
+	if len(path) == 0 {
+		path = TypeMessageStats
+	}
+
+	m := &MessageStats{
+		conf:   conf,
+		log:    log,
+		stats:  stats,
+		path:   path,
+		sample: conf.MessageStats.Sample / 100.0,
+		gen:    rand.New(rand.NewSource(conf.MessageStats.RandomSeed)),
+
+		mCount:       stats.GetCounter("count"),
+		mSampled:     stats.GetCounter("sampled"),
+		mSizeMax:     stats.GetGauge("size.max"),
+		mCardinality: stats.GetGauge("key.cardinality"),
+	}
+	if len(conf.MessageStats.Key) > 0 {
+		m.key = text.NewInterpolatedBytes([]byte(conf.MessageStats.Key))
+	}
+	m.collected = registerMessageStats(path, m)
+
+	for _, b := range messageSizeBuckets {
+		m.mSizeBuckets = append(m.mSizeBuckets, stats.GetCounter(fmt.Sprintf("size.le_%v", b)))
+	}
+	m.mSizeBuckets = append(m.mSizeBuckets, stats.GetCounter("size.le_inf"))
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (m *MessageStats) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	m.mCount.Incr(1)
+
+	msg.Iter(func(i int, p types.Part) error {
+		m.mut.Lock()
+		skip := m.gen.Float64() >= m.sample
+		m.mut.Unlock()
+		if skip {
+			return nil
+		}
+
+		var key []byte
+		if m.key != nil {
+			key = m.key.Get(message.Lock(msg, i))
+		}
+		bucket, max, cardinality := m.collected.record(int64(len(p.Get())), key, m.key != nil)
+
+		m.mSampled.Incr(1)
+		m.mSizeBuckets[bucket].Incr(1)
+		m.mSizeMax.Set(max)
+		if m.key != nil {
+			m.mCardinality.Set(int64(cardinality))
+		}
+		return nil
+	})
+
+	msgs := [1]types.Message{msg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (m *MessageStats) CloseAsync() {
+	deregisterMessageStats(m.path, m)
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (m *MessageStats) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,246 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestMessageStatsBadTopN(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMessageStats
+	conf.MessageStats.TopN = 0
+
+	if _, err := NewMessageStats(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero top_n")
+	}
+}
+
+func TestMessageStatsSizes(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMessageStats
+
+	stats := metrics.Namespaced(metrics.Noop(), "message_stats_sizes")
+	proc, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte("foo"),
+		[]byte(strings.Repeat("a", 100)),
+		[]byte(strings.Repeat("b", 5000000)),
+	})
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 || msgs[0].Len() != 3 {
+		t.Fatal("Expected message to pass through unchanged")
+	}
+
+	summary, exists := MessageStatsSummaries()["message_stats_sizes"]
+	if !exists {
+		t.Fatal("Message stats summary not found")
+	}
+	if exp, act := int64(3), summary.Sampled; exp != act {
+		t.Errorf("Wrong count of sampled parts: %v != %v", act, exp)
+	}
+	if exp, act := int64(3), summary.Size.Min; exp != act {
+		t.Errorf("Wrong min size: %v != %v", act, exp)
+	}
+	if exp, act := int64(5000000), summary.Size.Max; exp != act {
+		t.Errorf("Wrong max size: %v != %v", act, exp)
+	}
+	if summary.Key != nil {
+		t.Error("Expected no key summary without a key")
+	}
+
+	buckets := summary.Size.Buckets
+	if exp, act := len(messageSizeBuckets)+1, len(buckets); exp != act {
+		t.Fatalf("Wrong count of buckets: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), buckets[0].Count; exp != act {
+		t.Errorf("Wrong count of first bucket: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), buckets[1].Count; exp != act {
+		t.Errorf("Wrong count of second bucket: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), buckets[len(buckets)-1].Count; exp != act {
+		t.Errorf("Wrong count of last bucket: %v != %v", act, exp)
+	}
+}
+
+func TestMessageStatsKeys(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMessageStats
+	conf.MessageStats.Key = "${!json_field:user}"
+	conf.MessageStats.TopN = 2
+
+	stats := metrics.Namespaced(metrics.Noop(), "message_stats_keys")
+	proc, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parts [][]byte
+	for i := 0; i < 10; i++ {
+		parts = append(parts, []byte(`{"user":"foo"}`))
+	}
+	for i := 0; i < 5; i++ {
+		parts = append(parts, []byte(`{"user":"bar"}`))
+	}
+	parts = append(parts, []byte(`{"user":"baz"}`))
+	if _, res := proc.ProcessMessage(message.New(parts)); res != nil {
+		t.Fatal(res.Error())
+	}
+
+	req := httptest.NewRequest("GET", "/debug/messages", nil)
+	rec := httptest.NewRecorder()
+	MessageStatsHandler()(rec, req)
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Fatalf("Wrong response code: %v != %v", act, exp)
+	}
+
+	var summaries map[string]MessageStatsSummary
+	if err = json.Unmarshal(rec.Body.Bytes(), &summaries); err != nil {
+		t.Fatal(err)
+	}
+	summary, exists := summaries["message_stats_keys"]
+	if !exists {
+		t.Fatal("Message stats summary not found")
+	}
+	if summary.Key == nil {
+		t.Fatal("Expected key summary")
+	}
+	if exp, act := uint64(3), summary.Key.Cardinality; exp != act {
+		t.Errorf("Wrong key cardinality: %v != %v", act, exp)
+	}
+	if exp, act := 2, len(summary.Key.Top); exp != act {
+		t.Fatalf("Wrong count of top keys: %v != %v", act, exp)
+	}
+	if exp, act := "foo", summary.Key.Top[0].Value; exp != act {
+		t.Errorf("Wrong top key: %v != %v", act, exp)
+	}
+	if exp, act := uint64(10), summary.Key.Top[0].Count; exp != act {
+		t.Errorf("Wrong top key count: %v != %v", act, exp)
+	}
+	if exp, act := "bar", summary.Key.Top[1].Value; exp != act {
+		t.Errorf("Wrong second key: %v != %v", act, exp)
+	}
+}
+
+func TestMessageStatsSample(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMessageStats
+	conf.MessageStats.Sample = 0
+
+	stats := metrics.Namespaced(metrics.Noop(), "message_stats_sample")
+	proc, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")})); res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := int64(0), MessageStatsSummaries()["message_stats_sample"].Sampled; exp != act {
+		t.Errorf("Wrong count of sampled parts: %v != %v", act, exp)
+	}
+}
+
+func TestMessageStatsClose(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMessageStats
+
+	stats := metrics.Namespaced(metrics.Noop(), "message_stats_close")
+	procOne, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	procTwo, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	procOne.CloseAsync()
+	if _, exists := MessageStatsSummaries()["message_stats_close"]; !exists {
+		t.Error("Expected summary while a processor at the path is open")
+	}
+
+	procTwo.CloseAsync()
+	if _, exists := MessageStatsSummaries()["message_stats_close"]; exists {
+		t.Error("Expected summary to be removed once all processors are closed")
+	}
+}
+
+func TestMessageStatsSharedTopN(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeMessageStats
+	conf.MessageStats.Key = "${!content}"
+	conf.MessageStats.TopN = 1
+
+	stats := metrics.Namespaced(metrics.Noop(), "message_stats_shared_top_n")
+	procOne, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer procOne.CloseAsync()
+
+	conf.MessageStats.TopN = 3
+	procTwo, err := NewMessageStats(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte("foo"), []byte("foo"), []byte("foo"),
+		[]byte("bar"), []byte("bar"),
+		[]byte("baz"),
+	})
+	if _, res := procTwo.ProcessMessage(input); res != nil {
+		t.Fatal(res.Error())
+	}
+
+	summary := MessageStatsSummaries()["message_stats_shared_top_n"]
+	if summary.Key == nil {
+		t.Fatal("Expected key summary")
+	}
+	if exp, act := 3, len(summary.Key.Top); exp != act {
+		t.Errorf("Wrong count of top keys: %v != %v", act, exp)
+	}
+
+	procTwo.CloseAsync()
+	summary = MessageStatsSummaries()["message_stats_shared_top_n"]
+	if summary.Key == nil {
+		t.Fatal("Expected key summary")
+	}
+	if exp, act := 1, len(summary.Key.Top); exp != act {
+		t.Errorf("Wrong count of top keys: %v != %v", act, exp)
+	}
+}
@@ -21,14 +21,19 @@
 package processor
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
 	"github.com/opentracing/opentracing-go"
 )
 
@@ -74,7 +79,41 @@ Removes all metadata values from the message.
 #### ` + "`delete_prefix`" + `
 
 Removes all metadata values from the message where the key is prefixed with the
-value provided.`,
+value provided.
+
+#### ` + "`extract_regex`" + `
+
+Applies the regular expression provided by ` + "`value`" + ` to the contents of
+the message and sets a metadata value for each capture group of the first
+match. Named capture groups are set with their name as the metadata key, and
+unnamed groups are set with their index. In both cases the key is prefixed with
+the value of ` + "`key`" + `. If the expression does not match the payload then
+the message is flagged as having failed.
+
+Function interpolations are not resolved within ` + "`value`" + ` for this
+operator.
+
+` + "``` yaml" + `
+metadata:
+  operator: extract_regex
+  key: log_
+  value: '^(?P<level>[A-Z]+) (?P<source>\S+)'
+` + "```" + `
+
+#### ` + "`to_json`" + `
+
+Sets a JSON object containing all metadata key/value pairs of the message at
+the dot path specified by ` + "`key`" + ` within the payload, which must be a
+JSON document. An empty ` + "`key`" + ` replaces the entire payload with the
+object.
+
+#### ` + "`from_json`" + `
+
+The inverse of ` + "`to_json`" + `, sets a metadata value for each field of the
+JSON object found at the dot path specified by ` + "`key`" + ` within the
+payload. An empty ` + "`key`" + ` targets the root of the payload. String
+values are set as they are, and all other values are set as their JSON
+representation.`,
 	}
 }
 
@@ -100,17 +139,18 @@ func NewMetadataConfig() MetadataConfig {
 
 //------------------------------------------------------------------------------
 
-type metadataOperator func(m types.Metadata, value []byte) error
+type metadataOperator func(part types.Part, value []byte) error
 
 func newMetadataSetOperator(key string) metadataOperator {
-	return func(m types.Metadata, value []byte) error {
-		m.Set(key, string(value))
+	return func(part types.Part, value []byte) error {
+		part.Metadata().Set(key, string(value))
 		return nil
 	}
 }
 
 func newMetadataDeleteAllOperator(key string) metadataOperator {
-	return func(m types.Metadata, value []byte) error {
+	return func(part types.Part, value []byte) error {
+		m := part.Metadata()
 		m.Iter(func(k, _ string) error {
 			m.Delete(k)
 			return nil
@@ -120,7 +160,8 @@ func newMetadataDeleteAllOperator(key string) metadataOperator {
 }
 
 func newMetadataDeletePrefixOperator(key string) metadataOperator {
-	return func(m types.Metadata, value []byte) error {
+	return func(part types.Part, value []byte) error {
+		m := part.Metadata()
 		prefix := string(value)
 		m.Iter(func(k, _ string) error {
 			if strings.HasPrefix(k, prefix) {
@@ -132,7 +173,95 @@ func newMetadataDeletePrefixOperator(key string) metadataOperator {
 	}
 }
 
-func getMetadataOperator(opStr string, key string) (metadataOperator, error) {
+func newMetadataExtractRegexOperator(key, pattern string) (metadataOperator, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile regular expression: %v", err)
+	}
+	names := re.SubexpNames()
+	return func(part types.Part, value []byte) error {
+		matches := re.FindSubmatch(part.Get())
+		if matches == nil {
+			return fmt.Errorf("expression '%v' did not match payload", pattern)
+		}
+		m := part.Metadata()
+		for i := 1; i < len(matches); i++ {
+			name := names[i]
+			if len(name) == 0 {
+				name = strconv.Itoa(i)
+			}
+			m.Set(key+name, string(matches[i]))
+		}
+		return nil
+	}, nil
+}
+
+func splitMetadataPath(key string) []string {
+	if len(key) == 0 {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
+
+func newMetadataToJSONOperator(key string) metadataOperator {
+	path := splitMetadataPath(key)
+	return func(part types.Part, value []byte) error {
+		obj := map[string]interface{}{}
+		part.Metadata().Iter(func(k, v string) error {
+			obj[k] = v
+			return nil
+		})
+		if len(path) == 0 {
+			return part.SetJSON(obj)
+		}
+
+		jsonPart, err := part.JSON()
+		if err == nil {
+			jsonPart, err = message.CopyJSON(jsonPart)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse message body: %v", err)
+		}
+
+		gPart, _ := gabs.Consume(jsonPart)
+		if _, err = gPart.Set(obj, path...); err != nil {
+			return fmt.Errorf("failed to set path '%v': %v", key, err)
+		}
+		return part.SetJSON(gPart.Data())
+	}
+}
+
+func newMetadataFromJSONOperator(key string) metadataOperator {
+	path := splitMetadataPath(key)
+	return func(part types.Part, value []byte) error {
+		jsonPart, err := part.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to parse message body: %v", err)
+		}
+
+		gPart, _ := gabs.Consume(jsonPart)
+		obj, ok := gPart.S(path...).Data().(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("object not found at path '%v'", key)
+		}
+
+		m := part.Metadata()
+		for k, v := range obj {
+			if str, isStr := v.(string); isStr {
+				m.Set(k, str)
+				continue
+			}
+			vBytes, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to marshal field '%v': %v", k, err)
+			}
+			m.Set(k, string(vBytes))
+		}
+		return nil
+	}
+}
+
+func getMetadataOperator(opStr, key, value string) (metadataOperator, error) {
 	switch opStr {
 	case "set":
 		return newMetadataSetOperator(key), nil
@@ -140,6 +269,12 @@ func getMetadataOperator(opStr string, key string) (metadataOperator, error) {
 		return newMetadataDeleteAllOperator(key), nil
 	case "delete_prefix":
 		return newMetadataDeletePrefixOperator(key), nil
+	case "extract_regex":
+		return newMetadataExtractRegexOperator(key, value)
+	case "to_json":
+		return newMetadataToJSONOperator(key), nil
+	case "from_json":
+		return newMetadataFromJSONOperator(key), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
@@ -187,7 +322,7 @@ func NewMetadata(
 	m.interpolate = text.ContainsFunctionVariables(m.valueBytes)
 
 	var err error
-	if m.operator, err = getMetadataOperator(conf.Metadata.Operator, conf.Metadata.Key); err != nil {
+	if m.operator, err = getMetadataOperator(conf.Metadata.Operator, conf.Metadata.Key, conf.Metadata.Value); err != nil {
 		return nil, err
 	}
 	return m, nil
@@ -207,7 +342,7 @@ func (p *Metadata) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 	}
 
 	proc := func(index int, span opentracing.Span, part types.Part) error {
-		if err := p.operator(part.Metadata(), valueBytes); err != nil {
+		if err := p.operator(part, valueBytes); err != nil {
 			p.mErr.Incr(1)
 			p.log.Debugf("Failed to apply operator: %v\n", err)
 			return err
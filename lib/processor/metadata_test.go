@@ -22,6 +22,7 @@ package processor
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -198,3 +199,134 @@ func TestMetadataDeletePrefix(t *testing.T) {
 		t.Errorf("Lost metadata: %v", expMap)
 	}
 }
+
+func TestMetadataExtractRegex(t *testing.T) {
+	conf := NewConfig()
+	conf.Metadata.Operator = "extract_regex"
+	conf.Metadata.Key = "log_"
+	conf.Metadata.Value = `^(?P<level>[A-Z]+) (\S+)`
+
+	proc, err := NewMetadata(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte("ERROR db.go failed to connect"),
+		[]byte("nope"),
+	}))
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	meta := msgs[0].Get(0).Metadata()
+	if exp, act := "ERROR", meta.Get("log_level"); exp != act {
+		t.Errorf("Wrong metadata value: %v != %v", act, exp)
+	}
+	if exp, act := "db.go", meta.Get("log_2"); exp != act {
+		t.Errorf("Wrong metadata value: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Unexpected fail flag")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected fail flag for non-matching payload")
+	}
+}
+
+func TestMetadataBadRegex(t *testing.T) {
+	conf := NewConfig()
+	conf.Metadata.Operator = "extract_regex"
+	conf.Metadata.Value = `(`
+
+	if _, err := NewMetadata(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad expression")
+	}
+}
+
+func TestMetadataToJSON(t *testing.T) {
+	type testCase struct {
+		name  string
+		key   string
+		input string
+		exp   string
+	}
+
+	tests := []testCase{
+		{
+			name:  "root",
+			key:   "",
+			input: "not json",
+			exp:   `{"bar":"2","foo":"1"}`,
+		},
+		{
+			name:  "nested path",
+			key:   "doc.meta",
+			input: `{"doc":{"id":"a"}}`,
+			exp:   `{"doc":{"id":"a","meta":{"bar":"2","foo":"1"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			conf := NewConfig()
+			conf.Metadata.Operator = "to_json"
+			conf.Metadata.Key = test.key
+
+			proc, err := NewMetadata(conf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				tt.Fatal(err)
+			}
+
+			inMsg := message.New([][]byte{[]byte(test.input)})
+			inMsg.Get(0).Metadata().Set("foo", "1").Set("bar", "2")
+
+			msgs, _ := proc.ProcessMessage(inMsg)
+			if len(msgs) != 1 {
+				tt.Fatalf("Wrong count of messages: %v", len(msgs))
+			}
+			if act := string(msgs[0].Get(0).Get()); act != test.exp {
+				tt.Errorf("Wrong result: %v != %v", act, test.exp)
+			}
+			if act := string(inMsg.Get(0).Get()); act != test.input {
+				tt.Errorf("Input message was modified: %v", act)
+			}
+		})
+	}
+}
+
+func TestMetadataFromJSON(t *testing.T) {
+	conf := NewConfig()
+	conf.Metadata.Operator = "from_json"
+	conf.Metadata.Key = "doc.meta"
+
+	proc, err := NewMetadata(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"doc":{"meta":{"foo":"bar","count":5,"tags":["a","b"]}}}`),
+		[]byte(`{"doc":{}}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	expMap := map[string]string{
+		"foo":   "bar",
+		"count": "5",
+		"tags":  `["a","b"]`,
+	}
+	actMap := map[string]string{}
+	msgs[0].Get(0).Metadata().Iter(func(k, v string) error {
+		actMap[k] = v
+		return nil
+	})
+	if !reflect.DeepEqual(expMap, actMap) {
+		t.Errorf("Wrong metadata: %v != %v", actMap, expMap)
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected fail flag for missing object")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -54,7 +55,18 @@ for_each:
 
 The ` + "`path`" + ` field should be a dot separated path of the metric to be
 set and will automatically be converted into the correct format of the
-configured metric aggregator.
+configured metric aggregator. The path can also be set using function
+interpolations, in which case a metric is created for each unique resolved
+path:
+
+` + "``` yaml" + `
+metric:
+  type: counter
+  path: count.by_topic.${!metadata:kafka_topic}
+` + "```" + `
+
+Since each unique path results in a new metric, paths should only be
+interpolated from values with a low cardinality.
 
 The ` + "`value`" + ` field can be set using function interpolations described
 [here](../config_interpolation.md#functions) and is used according to the
@@ -144,13 +156,24 @@ type Metric struct {
 	stats metrics.Type
 
 	interpolateValue bool
+	pathStr          *text.InterpolatedString
+	getVec           func(path string) metricVec
+
+	labels labels
+	vec    metricVec
 
-	labels   labels
-	mCounter metrics.StatCounterVec
-	mGauge   metrics.StatGaugeVec
-	mTimer   metrics.StatTimerVec
+	vecsMut sync.Mutex
+	vecs    map[string]metricVec
 
-	handler func(string, types.Message) error
+	handler func(metricVec, string, types.Message) error
+}
+
+// metricVec contains the metric vector of a path, where only the vector
+// matching the metric type is set.
+type metricVec struct {
+	counter metrics.StatCounterVec
+	gauge   metrics.StatGaugeVec
+	timer   metrics.StatTimerVec
 }
 
 type labels []label
@@ -213,43 +236,58 @@ func NewMetric(
 		})
 	}
 
+	labelNames = m.labels.names()
+	getCounter := func(path string) metricVec {
+		return metricVec{counter: stats.GetCounterVec(path, labelNames)}
+	}
+
 	switch strings.ToLower(conf.Metric.Type) {
 	case "counter":
-		m.mCounter = stats.GetCounterVec(conf.Metric.Path, m.labels.names())
+		m.getVec = getCounter
 		m.handler = m.handleCounter
 	case "counter_parts":
-		m.mCounter = stats.GetCounterVec(conf.Metric.Path, m.labels.names())
+		m.getVec = getCounter
 		m.handler = m.handleCounterParts
 	case "counter_by":
-		m.mCounter = stats.GetCounterVec(conf.Metric.Path, m.labels.names())
+		m.getVec = getCounter
 		m.handler = m.handleCounterBy
 	case "gauge":
-		m.mGauge = stats.GetGaugeVec(conf.Metric.Path, m.labels.names())
+		m.getVec = func(path string) metricVec {
+			return metricVec{gauge: stats.GetGaugeVec(path, labelNames)}
+		}
 		m.handler = m.handleGauge
 	case "timing":
-		m.mTimer = stats.GetTimerVec(conf.Metric.Path, m.labels.names())
+		m.getVec = func(path string) metricVec {
+			return metricVec{timer: stats.GetTimerVec(path, labelNames)}
+		}
 		m.handler = m.handleTimer
 	default:
 		return nil, fmt.Errorf("metric type unrecognised: %v", conf.Metric.Type)
 	}
 
+	if text.ContainsFunctionVariables([]byte(conf.Metric.Path)) {
+		m.pathStr = text.NewInterpolatedString(conf.Metric.Path)
+		m.vecs = map[string]metricVec{}
+	} else {
+		m.vec = m.getVec(conf.Metric.Path)
+	}
 	return m, nil
 }
 
-func (m *Metric) handleCounter(val string, msg types.Message) error {
-	m.mCounter.With(m.labels.values(msg)...).Incr(1)
+func (m *Metric) handleCounter(vec metricVec, val string, msg types.Message) error {
+	vec.counter.With(m.labels.values(msg)...).Incr(1)
 	return nil
 }
 
-func (m *Metric) handleCounterParts(val string, msg types.Message) error {
+func (m *Metric) handleCounterParts(vec metricVec, val string, msg types.Message) error {
 	if msg.Len() == 0 {
 		return nil
 	}
-	m.mCounter.With(m.labels.values(msg)...).Incr(int64(msg.Len()))
+	vec.counter.With(m.labels.values(msg)...).Incr(int64(msg.Len()))
 	return nil
 }
 
-func (m *Metric) handleCounterBy(val string, msg types.Message) error {
+func (m *Metric) handleCounterBy(vec metricVec, val string, msg types.Message) error {
 	i, err := strconv.ParseInt(val, 10, 64)
 	if err != nil {
 		return err
@@ -257,11 +295,11 @@ func (m *Metric) handleCounterBy(val string, msg types.Message) error {
 	if i < 0 {
 		return errors.New("value is negative")
 	}
-	m.mCounter.With(m.labels.values(msg)...).Incr(i)
+	vec.counter.With(m.labels.values(msg)...).Incr(i)
 	return nil
 }
 
-func (m *Metric) handleGauge(val string, msg types.Message) error {
+func (m *Metric) handleGauge(vec metricVec, val string, msg types.Message) error {
 	i, err := strconv.ParseInt(val, 10, 64)
 	if err != nil {
 		return err
@@ -269,11 +307,11 @@ func (m *Metric) handleGauge(val string, msg types.Message) error {
 	if i < 0 {
 		return errors.New("value is negative")
 	}
-	m.mGauge.With(m.labels.values(msg)...).Set(i)
+	vec.gauge.With(m.labels.values(msg)...).Set(i)
 	return nil
 }
 
-func (m *Metric) handleTimer(val string, msg types.Message) error {
+func (m *Metric) handleTimer(vec metricVec, val string, msg types.Message) error {
 	i, err := strconv.ParseInt(val, 10, 64)
 	if err != nil {
 		return err
@@ -281,7 +319,7 @@ func (m *Metric) handleTimer(val string, msg types.Message) error {
 	if i < 0 {
 		return errors.New("value is negative")
 	}
-	m.mTimer.With(m.labels.values(msg)...).Timing(i)
+	vec.timer.With(m.labels.values(msg)...).Timing(i)
 	return nil
 }
 
@@ -292,7 +330,24 @@ func (m *Metric) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 		value = string(text.ReplaceFunctionVariables(msg, []byte(m.conf.Metric.Value)))
 	}
 
-	if err := m.handler(value, msg); err != nil {
+	vec := m.vec
+	if m.pathStr != nil {
+		path := m.pathStr.Get(msg)
+		if len(path) == 0 {
+			m.log.Errorf("Metric path resolved to an empty string\n")
+			return []types.Message{msg}, nil
+		}
+
+		m.vecsMut.Lock()
+		var exists bool
+		if vec, exists = m.vecs[path]; !exists {
+			vec = m.getVec(path)
+			m.vecs[path] = vec
+		}
+		m.vecsMut.Unlock()
+	}
+
+	if err := m.handler(vec, value, msg); err != nil {
 		m.log.Errorf("Handler error: %v\n", err)
 	}
 
@@ -22,6 +22,7 @@ package processor
 
 import (
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -356,3 +357,82 @@ func TestMetricTiming(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestMetricCounterInterpolatedPath(t *testing.T) {
+	mockStats := &mockMetric{
+		values: map[string]int64{},
+	}
+
+	conf := NewConfig()
+	conf.Type = "metric"
+	conf.Metric.Type = "counter_by"
+	conf.Metric.Path = "foo.${!json_field:topic}"
+	conf.Metric.Value = "${!json_field:count}"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.WrapFlat(mockStats))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := [][][]byte{
+		{[]byte(`{"topic":"a","count":2}`)},
+		{[]byte(`{"topic":"b","count":3}`)},
+		{[]byte(`{"topic":"a","count":4}`)},
+		{[]byte(`{"count":5}`)},
+	}
+
+	expMetrics := map[string]int64{
+		"foo.a":    6,
+		"foo.b":    3,
+		"foo.null": 5,
+	}
+
+	for _, i := range inputs {
+		msg, res := proc.ProcessMessage(message.New(i))
+		if exp, act := 1, len(msg); exp != act {
+			t.Errorf("Wrong count of resulting messages: %v != %v", act, exp)
+		}
+		if res != nil {
+			t.Error(res.Error())
+		}
+	}
+
+	if !reflect.DeepEqual(expMetrics, mockStats.values) {
+		t.Errorf("Wrong result: %v != %v", mockStats.values, expMetrics)
+	}
+}
+
+func TestMetricCounterInterpolatedPathParallel(t *testing.T) {
+	stats := metrics.NewLocal()
+
+	conf := NewConfig()
+	conf.Type = "metric"
+	conf.Metric.Type = "counter"
+	conf.Metric.Path = "foo.${!json_field:topic}"
+
+	proc, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				proc.ProcessMessage(message.New([][]byte{[]byte(`{"topic":"a"}`)}))
+				proc.ProcessMessage(message.New([][]byte{[]byte(`{"topic":"b"}`)}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	expMetrics := map[string]int64{
+		"foo.a": 400,
+		"foo.b": 400,
+	}
+	if act := stats.GetCounters(); !reflect.DeepEqual(expMetrics, act) {
+		t.Errorf("Wrong result: %v != %v", act, expMetrics)
+	}
+}
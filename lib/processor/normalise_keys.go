@@ -0,0 +1,300 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeNormaliseKeys] = TypeSpec{
+		constructor: NewNormaliseKeys,
+		description: `
+Parses messages as a JSON document and rewrites the keys of all objects within
+it, including objects nested within arrays.
+
+Keys are modified in three steps. First, each regular expression of the field
+` + "`rename`" + ` that matches the key is replaced with the respective value,
+where the expressions are applied in lexicographical order and the replacement
+may reference capture groups (` + "`$1`" + `). Second, the case of the key is
+converted according to the field ` + "`case`" + `, which can be one of
+` + "`none`, `lower`, `upper`, `camel` or `snake`" + `. The ` + "`camel`" + `
+and ` + "`snake`" + ` conversions treat any character that isn't a letter or
+digit as a word separator. Finally, any characters found in the field
+` + "`strip`" + ` are removed from the key.
+
+For example, the following config renames keys prefixed with ` + "`@`" + `,
+converts all keys to lower case and removes dots, which are interpreted as
+object paths by Elasticsearch:
+
+` + "``` yaml" + `
+normalise_keys:
+  rename:
+    ^@(.*)$: meta_$1
+  case: lower
+  strip: .
+` + "```" + `
+
+If two keys of an object are normalised to the same key then the value of the
+key that is last in lexicographical order is kept. Messages that cannot be
+parsed as JSON are left unchanged and flagged as having failed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NormaliseKeysConfig contains configuration fields for the NormaliseKeys
+// processor.
+type NormaliseKeysConfig struct {
+	Parts  []int             `json:"parts" yaml:"parts"`
+	Rename map[string]string `json:"rename" yaml:"rename"`
+	Case   string            `json:"case" yaml:"case"`
+	Strip  string            `json:"strip" yaml:"strip"`
+}
+
+// NewNormaliseKeysConfig returns a NormaliseKeysConfig with default values.
+func NewNormaliseKeysConfig() NormaliseKeysConfig {
+	return NormaliseKeysConfig{
+		Parts:  []int{},
+		Rename: map[string]string{},
+		Case:   "none",
+		Strip:  "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type keyRename struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// NormaliseKeys is a processor that rewrites the keys of JSON documents.
+type NormaliseKeys struct {
+	conf     NormaliseKeysConfig
+	renames  []keyRename
+	caseFunc func(string) string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewNormaliseKeys returns a NormaliseKeys processor.
+func NewNormaliseKeys(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	n := &NormaliseKeys{
+		conf:  conf.NormaliseKeys,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	patterns := make([]string, 0, len(conf.NormaliseKeys.Rename))
+	for k := range conf.NormaliseKeys.Rename {
+		patterns = append(patterns, k)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile rename pattern '%v': %v", pattern, err)
+		}
+		n.renames = append(n.renames, keyRename{
+			re:          re,
+			replacement: conf.NormaliseKeys.Rename[pattern],
+		})
+	}
+
+	switch conf.NormaliseKeys.Case {
+	case "none", "":
+	case "lower":
+		n.caseFunc = strings.ToLower
+	case "upper":
+		n.caseFunc = strings.ToUpper
+	case "camel":
+		n.caseFunc = toCamelCase
+	case "snake":
+		n.caseFunc = toSnakeCase
+	default:
+		return nil, fmt.Errorf("case not recognised: %v", conf.NormaliseKeys.Case)
+	}
+	return n, nil
+}
+
+//------------------------------------------------------------------------------
+
+// splitKeyWords breaks a key into words, separated either by characters that
+// aren't letters or digits or by a lower case letter followed by an upper case
+// letter.
+func splitKeyWords(key string) []string {
+	var words []string
+	var word []rune
+	var prevLower bool
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			prevLower = false
+			continue
+		}
+		if prevLower && unicode.IsUpper(r) {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+		prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+func toCamelCase(key string) string {
+	words := splitKeyWords(key)
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		words[i] = w
+	}
+	return strings.Join(words, "")
+}
+
+func toSnakeCase(key string) string {
+	words := splitKeyWords(key)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, "_")
+}
+
+func (n *NormaliseKeys) normaliseKey(key string) string {
+	for _, r := range n.renames {
+		key = r.re.ReplaceAllString(key, r.replacement)
+	}
+	if n.caseFunc != nil {
+		key = n.caseFunc(key)
+	}
+	if len(n.conf.Strip) > 0 {
+		key = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(n.conf.Strip, r) {
+				return -1
+			}
+			return r
+		}, key)
+	}
+	return key
+}
+
+func (n *NormaliseKeys) normalise(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		newObj := make(map[string]interface{}, len(t))
+		for _, k := range keys {
+			newObj[n.normaliseKey(k)] = n.normalise(t[k])
+		}
+		return newObj
+	case []interface{}:
+		newArray := make([]interface{}, len(t))
+		for i, ele := range t {
+			newArray[i] = n.normalise(ele)
+		}
+		return newArray
+	}
+	return v
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (n *NormaliseKeys) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	n.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(i int, span opentracing.Span, part types.Part) error {
+		jsonPart, err := part.JSON()
+		if err != nil {
+			n.log.Debugf("Failed to parse message part as JSON: %v\n", err)
+			n.mErr.Incr(1)
+			return err
+		}
+		if err = part.SetJSON(n.normalise(jsonPart)); err != nil {
+			n.log.Debugf("Failed to set message part JSON: %v\n", err)
+			n.mErr.Incr(1)
+			return err
+		}
+		return nil
+	}
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	IteratePartsWithSpan(TypeNormaliseKeys, n.conf.Parts, newMsg, proc)
+
+	n.mBatchSent.Incr(1)
+	n.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (n *NormaliseKeys) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (n *NormaliseKeys) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
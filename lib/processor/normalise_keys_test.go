@@ -0,0 +1,204 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestNormaliseKeysBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.NormaliseKeys.Case = "nope"
+	if _, err := NewNormaliseKeys(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad case")
+	}
+
+	conf = NewConfig()
+	conf.NormaliseKeys.Rename = map[string]string{"(": "foo"}
+	if _, err := NewNormaliseKeys(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad rename pattern")
+	}
+}
+
+func TestNormaliseKeys(t *testing.T) {
+	type test struct {
+		name   string
+		rename map[string]string
+		kcase  string
+		strip  string
+		input  string
+		output string
+	}
+
+	tests := []test{
+		{
+			name:   "no changes",
+			kcase:  "none",
+			input:  `{"Foo.Bar":{"baz_Qux":[{"A":1}]}}`,
+			output: `{"Foo.Bar":{"baz_Qux":[{"A":1}]}}`,
+		},
+		{
+			name:   "lower case",
+			kcase:  "lower",
+			input:  `{"Foo.Bar":{"baz_Qux":[{"A":1}]}}`,
+			output: `{"foo.bar":{"baz_qux":[{"a":1}]}}`,
+		},
+		{
+			name:   "upper case",
+			kcase:  "upper",
+			input:  `{"Foo.Bar":{"baz_Qux":[{"A":1}]}}`,
+			output: `{"FOO.BAR":{"BAZ_QUX":[{"A":1}]}}`,
+		},
+		{
+			name:   "camel case",
+			kcase:  "camel",
+			input:  `{"foo_bar":{"BazQux":[{"first-name":"Foo_Bar"}]},"user_ID":2}`,
+			output: `{"fooBar":{"bazQux":[{"firstName":"Foo_Bar"}]},"userId":2}`,
+		},
+		{
+			name:   "snake case",
+			kcase:  "snake",
+			input:  `{"fooBar":{"Baz Qux":[{"first-name":1}]},"foo2Bar":2}`,
+			output: `{"foo2_bar":2,"foo_bar":{"baz_qux":[{"first_name":1}]}}`,
+		},
+		{
+			name:   "strip dots",
+			kcase:  "none",
+			strip:  ".",
+			input:  `{"foo.bar":{"b.a.z":"q.u.x"}}`,
+			output: `{"foobar":{"baz":"q.u.x"}}`,
+		},
+		{
+			name: "rename",
+			rename: map[string]string{
+				"^@(.*)$": "meta_$1",
+				"^meta_":  "m_",
+			},
+			kcase:  "none",
+			input:  `{"@timestamp":1,"@version":{"@id":2},"foo":3}`,
+			output: `{"foo":3,"m_timestamp":1,"m_version":{"m_id":2}}`,
+		},
+		{
+			name: "rename case and strip",
+			rename: map[string]string{
+				"^@": "",
+			},
+			kcase:  "snake",
+			strip:  "_",
+			input:  `{"@fooBar":{"baz.qux":1}}`,
+			output: `{"foobar":{"bazqux":1}}`,
+		},
+		{
+			name:   "collision",
+			kcase:  "lower",
+			input:  `{"FOO":1,"Foo":2,"foo":3}`,
+			output: `{"foo":3}`,
+		},
+		{
+			name:   "not an object",
+			kcase:  "lower",
+			input:  `["FOO",{"BAR":1}]`,
+			output: `["FOO",{"bar":1}]`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.NormaliseKeys.Case = test.kcase
+		conf.NormaliseKeys.Strip = test.strip
+		if test.rename != nil {
+			conf.NormaliseKeys.Rename = test.rename
+		}
+
+		proc, err := NewNormaliseKeys(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if res != nil {
+			t.Fatalf("%v: Unexpected response: %v", test.name, res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("%v: Wrong count of messages: %v", test.name, len(msgs))
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("%v: Wrong result: %v != %v", test.name, act, exp)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("%v: Unexpected failed flag", test.name)
+		}
+	}
+}
+
+func TestNormaliseKeysParts(t *testing.T) {
+	conf := NewConfig()
+	conf.NormaliseKeys.Case = "lower"
+	conf.NormaliseKeys.Parts = []int{1}
+
+	proc, err := NewNormaliseKeys(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"FOO":1}`),
+		[]byte(`{"FOO":2}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	exp := [][]byte{
+		[]byte(`{"FOO":1}`),
+		[]byte(`{"foo":2}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestNormaliseKeysNotJSON(t *testing.T) {
+	conf := NewConfig()
+	conf.NormaliseKeys.Case = "lower"
+
+	proc, err := NewNormaliseKeys(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`not json`)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := "not json", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected failed flag")
+	}
+}
@@ -22,14 +22,19 @@ package processor
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
 	"github.com/opentracing/opentracing-go"
 )
 
@@ -40,8 +45,18 @@ func init() {
 		constructor: NewNumber,
 		description: `
 Parses message contents into a 64-bit floating point number and performs an
-operator on it. In order to execute this processor on a sub field of a document
-use it with the ` + "[`process_field`](#process_field)" + ` processor.
+operator on it. If ` + "`path`" + ` is set then the message is parsed as a JSON
+document and the operator is instead applied to the number found at the dot
+path, where the result replaces the original value.
+
+The operand of the operator is taken from ` + "`value`" + `, or, if
+` + "`value_path`" + ` is set, from the number found at that dot path within the
+same JSON document, in which case ` + "`value`" + ` is ignored. Messages where either number cannot be found or parsed are
+left unchanged and flagged as having failed.
+
+The ` + "`result_type`" + ` field determines how results are written, where
+` + "`float`" + ` writes the result as it is and ` + "`int`" + ` truncates it to
+an integer.
 
 The value field can either be a number or a string type. If it is a string type
 then this processor will interpolate functions within it, you can find a list of
@@ -71,6 +86,20 @@ for each message of the batch place it within a
 
 Adds a value.
 
+#### ` + "`divide`" + `
+
+Divides by a value. Dividing by zero results in the message being flagged as
+failed.
+
+#### ` + "`multiply`" + `
+
+Multiplies by a value.
+
+#### ` + "`round`" + `
+
+Rounds the number to a number of decimal places specified by the value, where a
+value of zero rounds to the nearest integer.
+
 #### ` + "`subtract`" + `
 
 Subtracts a value.`,
@@ -81,33 +110,64 @@ Subtracts a value.`,
 
 // NumberConfig contains configuration fields for the Number processor.
 type NumberConfig struct {
-	Parts    []int       `json:"parts" yaml:"parts"`
-	Operator string      `json:"operator" yaml:"operator"`
-	Value    interface{} `json:"value" yaml:"value"`
+	Parts      []int       `json:"parts" yaml:"parts"`
+	Operator   string      `json:"operator" yaml:"operator"`
+	Path       string      `json:"path" yaml:"path"`
+	ResultType string      `json:"result_type" yaml:"result_type"`
+	Value      interface{} `json:"value" yaml:"value"`
+	ValuePath  string      `json:"value_path" yaml:"value_path"`
 }
 
 // NewNumberConfig returns a NumberConfig with default values.
 func NewNumberConfig() NumberConfig {
 	return NumberConfig{
-		Parts:    []int{},
-		Operator: "add",
-		Value:    0,
+		Parts:      []int{},
+		Operator:   "add",
+		Path:       "",
+		ResultType: "float",
+		Value:      0,
+		ValuePath:  "",
 	}
 }
 
 //------------------------------------------------------------------------------
 
-type numberOperator func(content, value float64) float64
+type numberOperator func(content, value float64) (float64, error)
 
 func newNumberAddOperator() numberOperator {
-	return func(content, value float64) float64 {
-		return content + value
+	return func(content, value float64) (float64, error) {
+		return content + value, nil
+	}
+}
+
+func newNumberDivideOperator() numberOperator {
+	return func(content, value float64) (float64, error) {
+		if value == 0 {
+			return 0, errors.New("attempted to divide by zero")
+		}
+		return content / value, nil
+	}
+}
+
+func newNumberMultiplyOperator() numberOperator {
+	return func(content, value float64) (float64, error) {
+		return content * value, nil
+	}
+}
+
+func newNumberRoundOperator() numberOperator {
+	return func(content, value float64) (float64, error) {
+		if value < 0 {
+			return 0, fmt.Errorf("decimal places must not be negative: %v", value)
+		}
+		shift := math.Pow(10, math.Floor(value))
+		return math.Round(content*shift) / shift, nil
 	}
 }
 
 func newNumberSubtractOperator() numberOperator {
-	return func(content, value float64) float64 {
-		return content - value
+	return func(content, value float64) (float64, error) {
+		return content - value, nil
 	}
 }
 
@@ -115,6 +175,12 @@ func getNumberOperator(opStr string) (numberOperator, error) {
 	switch opStr {
 	case "add":
 		return newNumberAddOperator(), nil
+	case "divide":
+		return newNumberDivideOperator(), nil
+	case "multiply":
+		return newNumberMultiplyOperator(), nil
+	case "round":
+		return newNumberRoundOperator(), nil
 	case "subtract":
 		return newNumberSubtractOperator(), nil
 	}
@@ -123,9 +189,28 @@ func getNumberOperator(opStr string) (numberOperator, error) {
 
 //------------------------------------------------------------------------------
 
+func numberFromJSON(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case json.Number:
+		return t.Float64()
+	case string:
+		return strconv.ParseFloat(t, 64)
+	case nil:
+		return 0, errors.New("value not found")
+	}
+	return 0, fmt.Errorf("value type '%T' is not a number", v)
+}
+
+//------------------------------------------------------------------------------
+
 // Number is a processor that performs number based operations on payloads.
 type Number struct {
-	parts []int
+	parts     []int
+	path      []string
+	valuePath []string
+	asInt     bool
 
 	interpolatedValue *text.InterpolatedString
 	value             float64
@@ -158,6 +243,10 @@ func NewNumber(
 	}
 
 	var err error
+	if len(conf.Number.ValuePath) > 0 {
+		n.valuePath = strings.Split(conf.Number.ValuePath, ".")
+	}
+
 	switch t := conf.Number.Value.(type) {
 	case string:
 		if text.ContainsFunctionVariables([]byte(t)) {
@@ -172,7 +261,9 @@ func NewNumber(
 	case json.Number:
 		n.value, err = t.Float64()
 	default:
-		err = fmt.Errorf("value type '%T' not allowed", t)
+		if len(n.valuePath) == 0 {
+			err = fmt.Errorf("value type '%T' not allowed", t)
+		}
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse value: %v", err)
@@ -181,6 +272,17 @@ func NewNumber(
 	if n.operator, err = getNumberOperator(conf.Number.Operator); err != nil {
 		return nil, err
 	}
+
+	switch conf.Number.ResultType {
+	case "float":
+	case "int":
+		n.asInt = true
+	default:
+		return nil, fmt.Errorf("result type not recognised: %v", conf.Number.ResultType)
+	}
+	if len(conf.Number.Path) > 0 {
+		n.path = strings.Split(conf.Number.Path, ".")
+	}
 	return n, nil
 }
 
@@ -210,14 +312,62 @@ func (n *Number) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 	}
 
 	proc := func(index int, span opentracing.Span, part types.Part) error {
-		data, err := strconv.ParseFloat(string(part.Get()), 64)
+		var gPart *gabs.Container
+		if len(n.path) > 0 || len(n.valuePath) > 0 {
+			jsonPart, err := part.JSON()
+			if err == nil {
+				jsonPart, err = message.CopyJSON(jsonPart)
+			}
+			if err != nil {
+				n.mErr.Incr(1)
+				n.log.Debugf("Failed to parse part into json: %v\n", err)
+				return err
+			}
+			gPart, _ = gabs.Consume(jsonPart)
+		}
+
+		operand := value
+		if len(n.valuePath) > 0 {
+			var err error
+			if operand, err = numberFromJSON(gPart.S(n.valuePath...).Data()); err != nil {
+				n.mErr.Incr(1)
+				n.log.Debugf("Failed to parse value path into float: %v\n", err)
+				return fmt.Errorf("value path: %v", err)
+			}
+		}
+
+		var data float64
+		var err error
+		if len(n.path) > 0 {
+			data, err = numberFromJSON(gPart.S(n.path...).Data())
+		} else {
+			data, err = strconv.ParseFloat(string(part.Get()), 64)
+		}
 		if err != nil {
 			n.mErr.Incr(1)
 			n.log.Debugf("Failed to parse content into float: %v\n", err)
 			return err
 		}
-		data = n.operator(data, value)
-		part.Set([]byte(strconv.FormatFloat(data, 'f', -1, 64)))
+
+		if data, err = n.operator(data, operand); err != nil {
+			n.mErr.Incr(1)
+			n.log.Debugf("Failed to apply operator: %v\n", err)
+			return err
+		}
+
+		if len(n.path) > 0 {
+			var result interface{} = data
+			if n.asInt {
+				result = int64(data)
+			}
+			gPart.Set(result, n.path...)
+			return part.SetJSON(gPart.Data())
+		}
+		if n.asInt {
+			part.Set([]byte(strconv.FormatInt(int64(data), 10)))
+		} else {
+			part.Set([]byte(strconv.FormatFloat(data, 'f', -1, 64)))
+		}
 		return nil
 	}
 
@@ -286,3 +286,154 @@ func TestNumberBadInterpolatedValue(t *testing.T) {
 		return nil
 	})
 }
+
+func TestNumberOperators(t *testing.T) {
+	type testCase struct {
+		name       string
+		operator   string
+		value      interface{}
+		path       string
+		valuePath  string
+		resultType string
+		input      []string
+		output     []string
+		failed     []bool
+	}
+
+	tests := []testCase{
+		{
+			name:     "multiply",
+			operator: "multiply",
+			value:    2.5,
+			input:    []string{"4", "-1.5"},
+			output:   []string{"10", "-3.75"},
+			failed:   []bool{false, false},
+		},
+		{
+			name:     "divide",
+			operator: "divide",
+			value:    4,
+			input:    []string{"10", "nope"},
+			output:   []string{"2.5", "nope"},
+			failed:   []bool{false, true},
+		},
+		{
+			name:     "divide by zero",
+			operator: "divide",
+			value:    0,
+			input:    []string{"10"},
+			output:   []string{"10"},
+			failed:   []bool{true},
+		},
+		{
+			name:     "round",
+			operator: "round",
+			value:    0,
+			input:    []string{"10.5", "-2.4"},
+			output:   []string{"11", "-2"},
+			failed:   []bool{false, false},
+		},
+		{
+			name:     "round places",
+			operator: "round",
+			value:    2,
+			input:    []string{"3.14159"},
+			output:   []string{"3.14"},
+			failed:   []bool{false},
+		},
+		{
+			name:       "int result",
+			operator:   "divide",
+			value:      3,
+			resultType: "int",
+			input:      []string{"10"},
+			output:     []string{"3"},
+			failed:     []bool{false},
+		},
+		{
+			name:     "path",
+			operator: "multiply",
+			value:    100,
+			path:     "doc.ratio",
+			input:    []string{`{"doc":{"ratio":0.25,"id":"a"}}`},
+			output:   []string{`{"doc":{"id":"a","ratio":25}}`},
+			failed:   []bool{false},
+		},
+		{
+			name:       "path and value path",
+			operator:   "subtract",
+			path:       "end",
+			valuePath:  "start",
+			resultType: "int",
+			input:      []string{`{"end":"15.5","start":3}`, `{"end":10}`},
+			output:     []string{`{"end":12,"start":3}`, `{"end":10}`},
+			failed:     []bool{false, true},
+		},
+		{
+			name:     "path not found",
+			operator: "add",
+			value:    1,
+			path:     "nope",
+			input:    []string{`{"foo":1}`},
+			output:   []string{`{"foo":1}`},
+			failed:   []bool{true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeNumber
+			conf.Number.Value = test.value
+			conf.Number.Operator = test.operator
+			conf.Number.Path = test.path
+			conf.Number.ValuePath = test.valuePath
+			if len(test.resultType) > 0 {
+				conf.Number.ResultType = test.resultType
+			}
+
+			proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+			if err != nil {
+				tt.Fatal(err)
+			}
+
+			input := message.New(nil)
+			for _, p := range test.input {
+				input.Append(message.NewPart([]byte(p)))
+			}
+
+			exp := make([][]byte, len(test.output))
+			for i, p := range test.output {
+				exp[i] = []byte(p)
+			}
+
+			msgs, res := proc.ProcessMessage(input)
+			if res != nil {
+				tt.Fatal(res.Error())
+			}
+
+			if len(msgs) != 1 {
+				tt.Fatalf("Expected one message, received: %v", len(msgs))
+			}
+			if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(act, exp) {
+				tt.Errorf("Unexpected output: %s != %s", exp, act)
+			}
+			msgs[0].Iter(func(i int, p types.Part) error {
+				if exp, act := test.failed[i], HasFailed(p); exp != act {
+					tt.Errorf("Unexpected fail flag for part %v: %v != %v", i, act, exp)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestNumberBadResultType(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNumber
+	conf.Number.ResultType = "nope"
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad result type")
+	}
+}
@@ -22,6 +22,7 @@ package processor
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -45,7 +46,12 @@ though they were each a batch of one message (similar to the
 processed in parallel.
 
 The field ` + "`cap`" + `, if greater than zero, caps the maximum number of
-parallel processing threads.`,
+parallel processing threads. When set to -1 the cap matches GOMAXPROCS, which
+defaults to the number of logical CPUs available.
+
+Messages that fail within a child processor are flagged and continue through
+the pipeline, but if a child returns an unrecoverable error, such as when it is
+closed during processing, the entire batch is rejected with that error.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			var err error
 			procConfs := make([]interface{}, len(conf.Parallel.Processors))
@@ -108,9 +114,14 @@ func NewParallel(
 		}
 		children = append(children, proc)
 	}
+	threadCap := conf.Parallel.Cap
+	if threadCap < 0 {
+		threadCap = runtime.GOMAXPROCS(0)
+	}
+	stats.GetGauge("cap").Set(int64(threadCap))
 	return &Parallel{
 		children: children,
-		cap:      conf.Parallel.Cap,
+		cap:      threadCap,
 		log:      log,
 
 		mCount:     stats.GetCounter("count"),
@@ -145,10 +156,17 @@ func (p *Parallel) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 	wg.Add(max)
 
 	var unAcks int32
+	var errRes types.Response
+	var errMut sync.Mutex
 	for i := 0; i < max; i++ {
 		go func() {
 			for index := range reqChan {
 				resMsgs, res := ExecuteAll(p.children, resultMsgs[index])
+				if res != nil && res.Error() != nil {
+					errMut.Lock()
+					errRes = res
+					errMut.Unlock()
+				}
 				if res != nil && res.SkipAck() {
 					atomic.AddInt32(&unAcks, 1)
 				}
@@ -170,10 +188,19 @@ func (p *Parallel) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 	close(reqChan)
 	wg.Wait()
 
+	if errRes != nil {
+		p.mErr.Incr(1)
+		p.log.Errorf("Child processor returned error: %v\n", errRes.Error())
+		return nil, errRes
+	}
+
 	resMsg := message.New(nil)
 	for _, m := range resultMsgs {
-		m.Iter(func(i int, p types.Part) error {
-			resMsg.Append(p)
+		m.Iter(func(i int, part types.Part) error {
+			if HasFailed(part) {
+				p.mErr.Incr(1)
+			}
+			resMsg.Append(part)
 			return nil
 		})
 	}
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestParallelBasic(t *testing.T) {
@@ -131,6 +132,48 @@ func TestParallelError(t *testing.T) {
 	}
 }
 
+func TestParallelChildClosed(t *testing.T) {
+	mgr := &fakeRateLimitMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{period: time.Hour},
+		},
+	}
+
+	throttleConf := NewConfig()
+	throttleConf.Type = TypeThrottle
+	throttleConf.Throttle.RateLimit = "foo"
+
+	conf := NewConfig()
+	conf.Parallel.Processors = []Config{throttleConf}
+
+	h, err := NewParallel(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		h.CloseAsync()
+	}()
+
+	msgs, res := h.ProcessMessage(message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+	}))
+	if len(msgs) != 0 {
+		t.Error("Expected empty msgs response")
+	}
+	if res == nil {
+		t.Fatal("Expected non-nil response")
+	}
+	if exp, act := types.ErrTypeClosed, res.Error(); exp != act {
+		t.Errorf("Wrong error returned: %v != %v", act, exp)
+	}
+	if err = h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestParallelUnack(t *testing.T) {
 	batchConf := NewConfig()
 	batchConf.Type = TypeBatch
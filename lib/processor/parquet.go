@@ -0,0 +1,456 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParquet] = TypeSpec{
+		constructor: NewParquet,
+		description: `
+Converts batches of JSON messages into a single Parquet file, or Parquet files
+back into a batch of JSON messages, according to the operator:
+
+` + "`from_json`" + ` encodes all messages of a batch as rows of a single Parquet
+file, which becomes the contents of a single resulting message. The resulting
+message adopts the metadata of the _first_ message of the batch. A schema is
+required for this operator, and messages with fields not within the schema
+have those fields ignored.
+
+` + "`to_json`" + ` decodes each message as a Parquet file and expands it into
+a message for each row, where each new message adopts the metadata of the
+original file. If a schema is not specified then it is read from the file.
+
+A schema is defined either inline with the field ` + "`schema`" + ` or from a
+file with ` + "`schema_file`" + `, and uses the
+[parquet-go JSON schema format](https://github.com/xitongsys/parquet-go#json)
+where the field ` + "`name`" + ` is the key of the field within the JSON
+documents:
+
+` + "``` json" + `
+{
+  "Tag": "name=root, repetitiontype=REQUIRED",
+  "Fields": [
+    {"Tag": "name=id, inname=Id, type=UTF8, repetitiontype=REQUIRED"},
+    {"Tag": "name=count, inname=Count, type=INT64, repetitiontype=OPTIONAL"}
+  ]
+}
+` + "```" + `
+
+The field ` + "`compression`" + ` sets the codec used for column chunks when
+encoding, and can be one of ` + "`uncompressed`, `snappy`, `gzip`, `lz4` or `zstd`" + `.
+The field ` + "`row_group_size`" + ` sets the target size in bytes of each
+row group within the file.
+
+In order to write analytics friendly objects to S3 this processor can be added
+to an ` + "`s3`" + ` output with a [batching policy](../outputs/README.md#batching):
+
+` + "``` yaml" + `
+output:
+  s3:
+    bucket: example-bucket
+    path: ${!count:files}-${!timestamp_unix_nano}.parquet
+    content_type: application/octet-stream
+  batching:
+    count: 10000
+  processors:
+  - parquet:
+      operator: from_json
+      schema_file: ./schema.json
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParquetConfig contains configuration fields for the Parquet processor.
+type ParquetConfig struct {
+	Operator     string `json:"operator" yaml:"operator"`
+	Compression  string `json:"compression" yaml:"compression"`
+	Schema       string `json:"schema" yaml:"schema"`
+	SchemaFile   string `json:"schema_file" yaml:"schema_file"`
+	RowGroupSize int64  `json:"row_group_size" yaml:"row_group_size"`
+}
+
+// NewParquetConfig returns a ParquetConfig with default values.
+func NewParquetConfig() ParquetConfig {
+	return ParquetConfig{
+		Operator:     "from_json",
+		Compression:  "snappy",
+		Schema:       "",
+		SchemaFile:   "",
+		RowGroupSize: 128 * 1024 * 1024,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var parquetCompressionCodecs = map[string]parquet.CompressionCodec{
+	"uncompressed": parquet.CompressionCodec_UNCOMPRESSED,
+	"snappy":       parquet.CompressionCodec_SNAPPY,
+	"gzip":         parquet.CompressionCodec_GZIP,
+	"lz4":          parquet.CompressionCodec_LZ4,
+	"zstd":         parquet.CompressionCodec_ZSTD,
+}
+
+//------------------------------------------------------------------------------
+
+// Parquet is a processor that converts batches of JSON documents into Parquet
+// files and vice versa.
+type Parquet struct {
+	conf        ParquetConfig
+	schema      string
+	compression parquet.CompressionCodec
+	encode      bool
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewParquet returns a Parquet processor.
+func NewParquet(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	p := &Parquet{
+		conf:   conf.Parquet,
+		schema: conf.Parquet.Schema,
+		log:    log,
+		stats:  stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSucc:      stats.GetCounter("success"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	switch conf.Parquet.Operator {
+	case "from_json":
+		p.encode = true
+	case "to_json":
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.Parquet.Operator)
+	}
+
+	var exists bool
+	if p.compression, exists = parquetCompressionCodecs[conf.Parquet.Compression]; !exists {
+		return nil, fmt.Errorf("compression codec not recognised: %v", conf.Parquet.Compression)
+	}
+	if conf.Parquet.RowGroupSize <= 0 {
+		return nil, errors.New("row_group_size must be greater than zero")
+	}
+
+	if len(conf.Parquet.SchemaFile) > 0 {
+		if len(p.schema) > 0 {
+			return nil, errors.New("cannot specify both schema and schema_file")
+		}
+		schemaBytes, err := ioutil.ReadFile(conf.Parquet.SchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema_file: %v", err)
+		}
+		p.schema = string(schemaBytes)
+	}
+	if len(p.schema) > 0 {
+		if _, err := schema.NewSchemaHandlerFromJSON(p.schema); err != nil {
+			return nil, fmt.Errorf("failed to parse schema: %v", err)
+		}
+	} else if p.encode {
+		return nil, errors.New("a schema is required for the from_json operator")
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *Parquet) encodeBatch(msg types.Message) (types.Part, error) {
+	buf := &parquetBuffer{}
+	pw, err := writer.NewJSONWriter(p.schema, buf, 1)
+	if err != nil {
+		return nil, err
+	}
+	pw.CompressionType = p.compression
+	pw.RowGroupSize = p.conf.RowGroupSize
+
+	if err = msg.Iter(func(i int, part types.Part) error {
+		if werr := pw.Write(string(part.Get())); werr != nil {
+			return fmt.Errorf("failed to write message %v: %v", i, werr)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err = pw.WriteStop(); err != nil {
+		return nil, err
+	}
+
+	newPart := msg.Get(0).Copy()
+	newPart.Set(buf.buf)
+	return newPart, nil
+}
+
+func (p *Parquet) decodePart(part types.Part) ([]types.Part, error) {
+	var schemaObj interface{}
+	if len(p.schema) > 0 {
+		schemaObj = p.schema
+	}
+
+	pr, err := reader.NewParquetReader(&parquetBuffer{buf: part.Get()}, schemaObj, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer pr.ReadStop()
+
+	rows, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	if err != nil {
+		return nil, err
+	}
+
+	newParts := make([]types.Part, 0, len(rows))
+	for _, row := range rows {
+		rowBytes, err := json.Marshal(parquetRowToJSON(
+			pr.SchemaHandler, []string{pr.SchemaHandler.GetRootInName()}, reflect.ValueOf(row),
+		))
+		if err != nil {
+			return nil, err
+		}
+		newPart := part.Copy()
+		newPart.Set(rowBytes)
+		newParts = append(newParts, newPart)
+	}
+	return newParts, nil
+}
+
+// parquetRowToJSON converts a value read from a Parquet file into a structure
+// that marshals to JSON using the external field names of the schema, as the
+// types created by the reader only carry the internal names.
+func parquetRowToJSON(sh *schema.SchemaHandler, inPath []string, v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return parquetRowToJSON(sh, inPath, v.Elem())
+	case reflect.Struct:
+		obj := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			fieldPath := append(inPath[:len(inPath):len(inPath)], v.Type().Field(i).Name)
+			key := fieldPath[len(fieldPath)-1]
+			if exPath, exists := sh.InPathToExPath[common.PathToStr(fieldPath)]; exists {
+				exNames := common.StrToPath(exPath)
+				key = exNames[len(exNames)-1]
+			}
+			obj[key] = parquetRowToJSON(sh, fieldPath, v.Field(i))
+		}
+		return obj
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		elemPath := append(inPath[:len(inPath):len(inPath)], "List", "Element")
+		if _, exists := sh.InPathToExPath[common.PathToStr(elemPath)]; !exists {
+			elemPath = inPath
+		}
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = parquetRowToJSON(sh, elemPath, v.Index(i))
+		}
+		return arr
+	case reflect.Map:
+		valuePath := append(inPath[:len(inPath):len(inPath)], "Key_value", "Value")
+		obj := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			obj[fmt.Sprintf("%v", k.Interface())] = parquetRowToJSON(sh, valuePath, v.MapIndex(k))
+		}
+		return obj
+	}
+	return v.Interface()
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Parquet) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	if msg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	if p.encode {
+		newMsg := msg.Copy()
+		spans := tracing.CreateChildSpans(TypeParquet, newMsg)
+
+		newPart, err := p.encodeBatch(msg)
+		if err != nil {
+			newMsg.Iter(func(i int, part types.Part) error {
+				FlagErr(part, err)
+				spans[i].LogFields(
+					olog.String("event", "error"),
+					olog.String("type", err.Error()),
+				)
+				return nil
+			})
+			p.log.Errorf("Failed to encode parquet file: %v\n", err)
+			p.mErr.Incr(1)
+		} else {
+			p.mSucc.Incr(1)
+			newMsg.SetAll([]types.Part{newPart})
+		}
+		for _, s := range spans {
+			s.Finish()
+		}
+
+		p.mSent.Incr(int64(newMsg.Len()))
+		p.mBatchSent.Incr(1)
+		return []types.Message{newMsg}, nil
+	}
+
+	newMsg := message.New(nil)
+	msg.Iter(func(i int, part types.Part) error {
+		span := tracing.CreateChildSpan(TypeParquet, part)
+		defer span.Finish()
+
+		newParts, err := p.decodePart(part)
+		if err == nil {
+			p.mSucc.Incr(1)
+			newMsg.Append(newParts...)
+			return nil
+		}
+
+		p.mErr.Incr(1)
+		p.log.Errorf("Failed to decode parquet file: %v\n", err)
+		newMsg.Append(part.Copy())
+		FlagErr(newMsg.Get(-1), err)
+		span.LogFields(
+			olog.String("event", "error"),
+			olog.String("type", err.Error()),
+		)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	p.mSent.Incr(int64(newMsg.Len()))
+	p.mBatchSent.Incr(1)
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Parquet) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Parquet) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// parquetBuffer is an in memory implementation of source.ParquetFile. Readers
+// open a separate handle for each column, which share the underlying bytes.
+type parquetBuffer struct {
+	buf []byte
+	loc int
+}
+
+func (b *parquetBuffer) Open(name string) (source.ParquetFile, error) {
+	return &parquetBuffer{buf: b.buf}, nil
+}
+
+func (b *parquetBuffer) Create(name string) (source.ParquetFile, error) {
+	return &parquetBuffer{}, nil
+}
+
+func (b *parquetBuffer) Read(p []byte) (int, error) {
+	if b.loc >= len(b.buf) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.buf[b.loc:])
+	b.loc += n
+	return n, nil
+}
+
+func (b *parquetBuffer) Write(p []byte) (int, error) {
+	if end := b.loc + len(p); end > len(b.buf) {
+		b.buf = append(b.buf, make([]byte, end-len(b.buf))...)
+	}
+	n := copy(b.buf[b.loc:], p)
+	b.loc += n
+	return n, nil
+}
+
+func (b *parquetBuffer) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(b.loc) + offset
+	case io.SeekEnd:
+		abs = int64(len(b.buf)) + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	b.loc = int(abs)
+	return abs, nil
+}
+
+func (b *parquetBuffer) Close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,164 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+const testParquetSchema = `{
+  "Tag": "name=root, repetitiontype=REQUIRED",
+  "Fields": [
+    {"Tag": "name=id, inname=Id, type=UTF8, repetitiontype=REQUIRED"},
+    {"Tag": "name=count, inname=Count, type=INT64, repetitiontype=REQUIRED"}
+  ]
+}`
+
+func TestParquetRoundTrip(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	encConf := NewConfig()
+	encConf.Parquet.Operator = "from_json"
+	encConf.Parquet.Schema = testParquetSchema
+
+	enc, err := NewParquet(encConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decConf := NewConfig()
+	decConf.Parquet.Operator = "to_json"
+	decConf.Parquet.Schema = testParquetSchema
+
+	dec, err := NewParquet(decConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := []string{
+		`{"count":1,"id":"foo"}`,
+		`{"count":2,"id":"bar"}`,
+		`{"count":3,"id":"baz"}`,
+	}
+	var inputBytes [][]byte
+	for _, in := range input {
+		inputBytes = append(inputBytes, []byte(in))
+	}
+	msgIn := message.New(inputBytes)
+	msgIn.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := enc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 || msgs[0].Len() != 1 {
+		t.Fatalf("Wrong count of encoded messages: %v", len(msgs))
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Fatal("Encoding failed")
+	}
+	if exp, act := "bar", msgs[0].Get(0).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	if msgs, res = dec.ProcessMessage(msgs[0]); res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of decoded messages: %v", len(msgs))
+	}
+	if exp, act := len(input), msgs[0].Len(); exp != act {
+		t.Fatalf("Wrong count of decoded parts: %v != %v", act, exp)
+	}
+	for i, exp := range input {
+		if act := string(msgs[0].Get(i).Get()); exp != act {
+			t.Errorf("Wrong decoded part %v: %v != %v", i, act, exp)
+		}
+		if exp, act := "bar", msgs[0].Get(i).Metadata().Get("foo"); exp != act {
+			t.Errorf("Wrong metadata: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestParquetDecodeBadInput(t *testing.T) {
+	conf := NewConfig()
+	conf.Parquet.Operator = "to_json"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewParquet(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("not parquet")}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 || msgs[0].Len() != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if exp, act := "not parquet", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected part to be flagged as failed")
+	}
+}
+
+func TestParquetBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	tests := map[string]func(c *ParquetConfig){
+		"bad operator": func(c *ParquetConfig) {
+			c.Operator = "nope"
+		},
+		"bad compression": func(c *ParquetConfig) {
+			c.Schema = testParquetSchema
+			c.Compression = "nope"
+		},
+		"missing schema": func(c *ParquetConfig) {},
+		"bad schema": func(c *ParquetConfig) {
+			c.Schema = "not a schema"
+		},
+		"both schemas": func(c *ParquetConfig) {
+			c.Schema = testParquetSchema
+			c.SchemaFile = "./schema.json"
+		},
+		"bad row group size": func(c *ParquetConfig) {
+			c.Schema = testParquetSchema
+			c.RowGroupSize = 0
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		fn(&conf.Parquet)
+		if _, err := NewParquet(conf, nil, testLog, metrics.DudType{}); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParseCSV] = TypeSpec{
+		constructor: NewParseCSV,
+		description: `
+Parses messages as delimited lines of CSV and converts each record into a JSON
+object, where each new object replaces the original message within the batch.
+
+Field names are taken from ` + "`columns`" + ` when specified, otherwise the
+first line of each message is treated as a header row. When consuming a CSV file
+line by line (where each message is a single record) the columns should be
+configured explicitly.
+
+For example, with the following config:
+
+` + "``` yaml" + `
+parse_csv:
+  columns: [ id, name, score ]
+` + "```" + `
+
+A message ` + "`1,foo,3.5`" + ` becomes:
+
+` + "``` json" + `
+{"id":"1","name":"foo","score":"3.5"}
+` + "```" + `
+
+All values are parsed as strings. The field ` + "`delimiter`" + ` sets the
+character separating fields, and ` + "`lazy_quotes`" + ` allows quotes to
+appear within unquoted fields and non-doubled quotes within quoted fields.
+
+Messages that fail to parse, or contain records with a different number of
+fields to the columns, remain unchanged in the batch and are flagged as having
+failed. Messages containing only a header row are removed from the batch.
+
+The ` + "[`format_csv`](#format_csv)" + ` processor performs the inverse of
+this processor.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParseCSVConfig contains configuration fields for the ParseCSV processor.
+type ParseCSVConfig struct {
+	Parts      []int    `json:"parts" yaml:"parts"`
+	Columns    []string `json:"columns" yaml:"columns"`
+	Delimiter  string   `json:"delimiter" yaml:"delimiter"`
+	LazyQuotes bool     `json:"lazy_quotes" yaml:"lazy_quotes"`
+}
+
+// NewParseCSVConfig returns a ParseCSVConfig with default values.
+func NewParseCSVConfig() ParseCSVConfig {
+	return ParseCSVConfig{
+		Parts:      []int{},
+		Columns:    []string{},
+		Delimiter:  ",",
+		LazyQuotes: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func csvDelimiter(delim string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(delim)
+	if size == 0 || size != len(delim) {
+		return 0, fmt.Errorf("delimiter must be a single character, got '%v'", delim)
+	}
+	if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter: '%v'", delim)
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ParseCSV is a processor that converts CSV records into JSON objects.
+type ParseCSV struct {
+	conf      ParseCSVConfig
+	delimiter rune
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSucc      metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewParseCSV returns a ParseCSV processor.
+func NewParseCSV(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	delim, err := csvDelimiter(conf.ParseCSV.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+	return &ParseCSV{
+		conf:      conf.ParseCSV,
+		delimiter: delim,
+		log:       log,
+		stats:     stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSucc:      stats.GetCounter("success"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *ParseCSV) parse(part types.Part) ([]types.Part, error) {
+	r := csv.NewReader(bytes.NewReader(part.Get()))
+	r.Comma = p.delimiter
+	r.LazyQuotes = p.conf.LazyQuotes
+	r.FieldsPerRecord = -1
+
+	columns := p.conf.Columns
+	if len(columns) == 0 {
+		header, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.New("message does not contain a header row")
+			}
+			return nil, err
+		}
+		columns = header
+	}
+
+	var newParts []types.Part
+	for n := 1; ; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(columns) {
+			return nil, fmt.Errorf("record %v has %v fields, expected %v", n, len(record), len(columns))
+		}
+
+		obj := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			obj[col] = record[i]
+		}
+		newPart := part.Copy()
+		if err = newPart.SetJSON(obj); err != nil {
+			return nil, err
+		}
+		newParts = append(newParts, newPart)
+	}
+	return newParts, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *ParseCSV) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	lParts := msg.Len()
+
+	noParts := len(p.conf.Parts) == 0
+	msg.Iter(func(i int, part types.Part) error {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, t := range p.conf.Parts {
+				if t == nI || t == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part.Copy())
+			return nil
+		}
+
+		span := tracing.CreateChildSpan(TypeParseCSV, part)
+		defer span.Finish()
+
+		newParts, err := p.parse(part)
+		if err == nil {
+			p.mSucc.Incr(1)
+			newMsg.Append(newParts...)
+			return nil
+		}
+
+		p.mErr.Incr(1)
+		p.log.Debugf("Failed to parse message as CSV: %v\n", err)
+		newMsg.Append(part.Copy())
+		FlagErr(newMsg.Get(-1), err)
+		span.LogFields(
+			olog.String("event", "error"),
+			olog.String("type", err.Error()),
+		)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *ParseCSV) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *ParseCSV) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,145 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestParseCSVHeader(t *testing.T) {
+	conf := NewConfig()
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewParseCSV(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte("id,name\n1,foo\n2,\"bar, baz\""),
+		[]byte("id,name\n3,qux"),
+		[]byte("id,name"),
+		[]byte("id,name\n4"),
+	})
+	msgIn.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":"1","name":"foo"}`),
+		[]byte(`{"id":"2","name":"bar, baz"}`),
+		[]byte(`{"id":"3","name":"qux"}`),
+		[]byte("id,name\n4"),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "bar", msgs[0].Get(1).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	for i := 0; i < 3; i++ {
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected part %v not to fail", i)
+		}
+	}
+	if !HasFailed(msgs[0].Get(3)) {
+		t.Error("Expected mismatched record to fail")
+	}
+}
+
+func TestParseCSVColumns(t *testing.T) {
+	conf := NewConfig()
+	conf.ParseCSV.Columns = []string{"id", "name"}
+	conf.ParseCSV.Delimiter = "|"
+	conf.ParseCSV.LazyQuotes = true
+	conf.ParseCSV.Parts = []int{0, 1}
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewParseCSV(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`1|fo"o`),
+		[]byte("2|bar\n3|baz"),
+		[]byte("4|not selected"),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":"1","name":"fo\"o"}`),
+		[]byte(`{"id":"2","name":"bar"}`),
+		[]byte(`{"id":"3","name":"baz"}`),
+		[]byte("4|not selected"),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestParseCSVEmpty(t *testing.T) {
+	conf := NewConfig()
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewParseCSV(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("id,name")}))
+	if len(msgs) != 0 {
+		t.Error("Expected no messages")
+	}
+	if res == nil || res.Error() != nil {
+		t.Error("Expected ack response")
+	}
+}
+
+func TestParseCSVBadDelimiter(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	for _, delim := range []string{"", "ab", "\"", "\n"} {
+		conf := NewConfig()
+		conf.ParseCSV.Delimiter = delim
+		if _, err := NewParseCSV(conf, nil, testLog, metrics.DudType{}); err == nil {
+			t.Errorf("Expected error from delimiter: %q", delim)
+		}
+	}
+}
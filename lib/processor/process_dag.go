@@ -281,7 +281,7 @@ func (p *ProcessDAG) ProcessMessage(msg types.Message) ([]types.Message, types.R
 			if failed, err := p.children[id].OverlayResult(result, results[i]); err != nil {
 				p.log.Errorf("Failed to overlay child '%v': %v\n", id, err)
 				result.Iter(func(i int, p types.Part) error {
-					FlagFail(p)
+					FlagErr(p, err)
 					return nil
 				})
 				continue
@@ -330,6 +330,14 @@ func (p *ProcessMap) TargetsProvided() []string {
 // result can be overlayed onto the original message in order to complete the
 // map.
 func (p *ProcessMap) CreateResult(msg types.Message) error {
+	_, _, err := p.createResult(msg)
+	return err
+}
+
+// createResult is the implementation of CreateResult, which also returns the
+// indexes of message parts that were skipped (either empty or excluded by
+// conditions) and those where the premap failed.
+func (p *ProcessMap) createResult(msg types.Message) (skipped, failed []int, err error) {
 	p.mCount.Incr(1)
 
 	if len(p.parts) > 0 {
@@ -349,21 +357,21 @@ func (p *ProcessMap) CreateResult(msg types.Message) error {
 
 	originalLen := msg.Len()
 
-	skipped, failed := p.mapper.MapRequests(msg)
+	skipped, failed = p.mapper.MapRequests(msg)
 	if msg.Len() == 0 {
 		msg.SetAll(make([]types.Part, originalLen))
 		for _, i := range failed {
 			FlagFail(msg.Get(i))
 		}
-		return nil
+		return skipped, failed, nil
 	}
 
-	procResults, err := processMap(msg, p.children)
-	if err != nil {
+	var procResults []types.Message
+	if procResults, err = processMap(msg, p.children); err != nil {
 		p.mErrProc.Incr(1)
 		p.mErr.Incr(1)
 		p.log.Errorf("Processors failed: %v\n", err)
-		return err
+		return skipped, failed, err
 	}
 
 	var alignedResult types.Message
@@ -371,7 +379,7 @@ func (p *ProcessMap) CreateResult(msg types.Message) error {
 		p.mErrPost.Incr(1)
 		p.mErr.Incr(1)
 		p.log.Errorf("Postmap failed: %v\n", err)
-		return err
+		return skipped, failed, err
 	}
 
 	for _, i := range failed {
@@ -383,7 +391,7 @@ func (p *ProcessMap) CreateResult(msg types.Message) error {
 		alignedParts[i] = alignedResult.Get(i)
 	}
 	msg.SetAll(alignedParts)
-	return nil
+	return skipped, failed, nil
 }
 
 // OverlayResult attempts to merge the result of a process_map with the original
@@ -0,0 +1,210 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// profileSamples is the number of most recent execution times retained per
+// processor for calculating percentiles.
+const profileSamples = 1024
+
+// profileStats accumulates execution times and error counts for all processors
+// sharing a path.
+type profileStats struct {
+	sync.Mutex
+
+	samples [profileSamples]time.Duration
+	next    int
+	filled  bool
+
+	batches int64
+	parts   int64
+	errors  int64
+}
+
+func (p *profileStats) record(d time.Duration, parts, errors int) {
+	p.Lock()
+	p.samples[p.next] = d
+	if p.next++; p.next == profileSamples {
+		p.next = 0
+		p.filled = true
+	}
+	p.batches++
+	p.parts += int64(parts)
+	p.errors += int64(errors)
+	p.Unlock()
+}
+
+// ProfileSummary describes the recent execution times and the error rate of a
+// processor.
+type ProfileSummary struct {
+	Batches   int64   `json:"batches"`
+	Parts     int64   `json:"parts"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50       string  `json:"p50"`
+	P99       string  `json:"p99"`
+}
+
+func (p *profileStats) summary() ProfileSummary {
+	p.Lock()
+	n := p.next
+	if p.filled {
+		n = profileSamples
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, p.samples[:n])
+	s := ProfileSummary{
+		Batches: p.batches,
+		Parts:   p.parts,
+		Errors:  p.errors,
+	}
+	p.Unlock()
+
+	if s.Parts > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Parts)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	percentile := func(q float64) string {
+		if len(sorted) == 0 {
+			return time.Duration(0).String()
+		}
+		return sorted[int(q*float64(len(sorted)-1))].String()
+	}
+	s.P50 = percentile(0.5)
+	s.P99 = percentile(0.99)
+	return s
+}
+
+var profiles = struct {
+	sync.RWMutex
+	m map[string]*profileStats
+}{
+	m: map[string]*profileStats{},
+}
+
+func getProfileStats(path string) *profileStats {
+	profiles.RLock()
+	p, exists := profiles.m[path]
+	profiles.RUnlock()
+	if exists {
+		return p
+	}
+
+	profiles.Lock()
+	if p, exists = profiles.m[path]; !exists {
+		p = &profileStats{}
+		profiles.m[path] = p
+	}
+	profiles.Unlock()
+	return p
+}
+
+// ProfileSummaries returns a summary of the recent execution times and error
+// rates of all processors, keyed by their paths.
+func ProfileSummaries() map[string]ProfileSummary {
+	profiles.RLock()
+	stats := make(map[string]*profileStats, len(profiles.m))
+	for k, v := range profiles.m {
+		stats[k] = v
+	}
+	profiles.RUnlock()
+
+	summaries := make(map[string]ProfileSummary, len(stats))
+	for k, v := range stats {
+		summaries[k] = v.summary()
+	}
+	return summaries
+}
+
+// ProfileHandler returns an HTTP handler that responds with the profile
+// summaries of all processors as a JSON object.
+func ProfileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resBytes, err := json.Marshal(ProfileSummaries())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// profiled wraps a processor in order to record its execution times and the
+// number of message parts it has flagged as failed.
+type profiled struct {
+	Type
+	stats *profileStats
+}
+
+func newProfiled(proc Type, path string) Type {
+	return &profiled{
+		Type:  proc,
+		stats: getProfileStats(path),
+	}
+}
+
+func countFailed(msgs ...types.Message) (failed int) {
+	for _, m := range msgs {
+		m.Iter(func(i int, p types.Part) error {
+			if HasFailed(p) {
+				failed++
+			}
+			return nil
+		})
+	}
+	return
+}
+
+// ProcessMessage executes the wrapped processor and records the outcome.
+func (p *profiled) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	parts, failedBefore := msg.Len(), countFailed(msg)
+
+	started := time.Now()
+	msgs, res := p.Type.ProcessMessage(msg)
+	elapsed := time.Since(started)
+
+	errors := 0
+	if res != nil && res.Error() != nil {
+		errors = parts
+	} else if failedAfter := countFailed(msgs...); failedAfter > failedBefore {
+		errors = failedAfter - failedBefore
+	}
+	p.stats.record(elapsed, parts, errors)
+	return msgs, res
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestProfileNoNamespace(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeNoop
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := proc.(*profiled); ok {
+		t.Error("Expected processor without a namespace to not be profiled")
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeJMESPath
+	conf.JMESPath.Query = "foo"
+
+	stats := metrics.Namespaced(metrics.Namespaced(metrics.Noop(), "profile_test"), "processor.0")
+	proc, err := New(conf, nil, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`not json`),
+	})
+	for i := 0; i < 2; i++ {
+		if _, res := proc.ProcessMessage(msg.Copy()); res != nil {
+			t.Fatal(res.Error())
+		}
+	}
+
+	summary, exists := ProfileSummaries()["profile_test.processor.0"]
+	if !exists {
+		t.Fatal("Profile summary not found")
+	}
+	if exp, act := int64(2), summary.Batches; exp != act {
+		t.Errorf("Wrong count of batches: %v != %v", act, exp)
+	}
+	if exp, act := int64(4), summary.Parts; exp != act {
+		t.Errorf("Wrong count of parts: %v != %v", act, exp)
+	}
+	if exp, act := int64(2), summary.Errors; exp != act {
+		t.Errorf("Wrong count of errors: %v != %v", act, exp)
+	}
+	if exp, act := 0.5, summary.ErrorRate; exp != act {
+		t.Errorf("Wrong error rate: %v != %v", act, exp)
+	}
+	if len(summary.P50) == 0 || len(summary.P99) == 0 {
+		t.Errorf("Missing percentiles: %+v", summary)
+	}
+}
+
+func TestProfilePercentiles(t *testing.T) {
+	p := &profileStats{}
+	for i := 1; i <= profileSamples+100; i++ {
+		p.record(1, 1, 0)
+	}
+	p.record(100, 1, 0)
+
+	s := p.summary()
+	if exp, act := "1ns", s.P50; exp != act {
+		t.Errorf("Wrong p50: %v != %v", act, exp)
+	}
+	if exp, act := "1ns", s.P99; exp != act {
+		t.Errorf("Wrong p99: %v != %v", act, exp)
+	}
+	if exp, act := int64(profileSamples+101), s.Batches; exp != act {
+		t.Errorf("Wrong count of batches: %v != %v", act, exp)
+	}
+
+	for i := 0; i < 20; i++ {
+		p.record(100, 1, 0)
+	}
+	if exp, act := "100ns", p.summary().P99; exp != act {
+		t.Errorf("Wrong p99: %v != %v", act, exp)
+	}
+}
+
+func TestProfileHandler(t *testing.T) {
+	getProfileStats("profile_handler_test").record(5, 2, 1)
+
+	rec := httptest.NewRecorder()
+	ProfileHandler()(rec, httptest.NewRequest("GET", "/debug/pipeline", nil))
+	if exp, act := http.StatusOK, rec.Code; exp != act {
+		t.Fatalf("Wrong status code: %v != %v", act, exp)
+	}
+
+	var res map[string]ProfileSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := (ProfileSummary{
+		Batches:   1,
+		Parts:     2,
+		Errors:    1,
+		ErrorRate: 0.5,
+		P50:       "5ns",
+		P99:       "5ns",
+	}), res["profile_handler_test"]; exp != act {
+		t.Errorf("Wrong summary: %+v != %+v", act, exp)
+	}
+}
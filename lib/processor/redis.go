@@ -0,0 +1,321 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+	"github.com/go-redis/redis"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedis] = TypeSpec{
+		constructor: NewRedis,
+		description: `
+Executes a Redis command for each message of a batch and places the result into
+the message, allowing you to enrich messages with data stored in Redis or to
+maintain counters and sets without a dedicated cache resource.
+
+The ` + "`command`" + ` can be any Redis command such as ` + "`get`" + `,
+` + "`sadd`" + `, ` + "`incrby`" + ` or ` + "`sismember`" + `, and is called
+with the ` + "`key`" + ` followed by each of the ` + "`args`" + `. Functions
+within the key and args are interpolated individually for each message of the
+batch, and you can find a list of functions
+[here](../config_interpolation.md#functions). If the key is empty it is omitted
+from the command.
+
+If ` + "`result_path`" + ` is empty the result replaces the contents of the
+message, where string results are written as they are and other results are
+written as JSON. Otherwise the message is parsed as a JSON document and the
+result is set at the path, where integers remain numbers, arrays become JSON
+arrays and a nil reply (such as ` + "`get`" + ` of a missing key) becomes
+` + "`null`" + `.
+
+For example, in order to count occurrences of a user ID and add the running
+total to each document:
+
+` + "``` yaml" + `
+redis:
+  url: tcp://localhost:6379
+  command: incrby
+  key: "user_counts:${!json_field:user.id}"
+  args: [ "1" ]
+  result_path: user.count
+` + "```" + `
+
+Failed commands are not retried by default, since many commands such as
+` + "`incrby`" + ` are not idempotent and a failed response does not guarantee
+that the command was not applied. Retries can be enabled with
+` + "`retries`" + ` for commands that are safe to repeat. Commands that fail
+after the configured number of retries leave the message unchanged and flag it
+as having failed, allowing you to use
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedisConfig contains configuration fields for the Redis processor.
+type RedisConfig struct {
+	URL         string   `json:"url" yaml:"url"`
+	Parts       []int    `json:"parts" yaml:"parts"`
+	Command     string   `json:"command" yaml:"command"`
+	Key         string   `json:"key" yaml:"key"`
+	Args        []string `json:"args" yaml:"args"`
+	ResultPath  string   `json:"result_path" yaml:"result_path"`
+	Retries     int      `json:"retries" yaml:"retries"`
+	RetryPeriod string   `json:"retry_period" yaml:"retry_period"`
+}
+
+// NewRedisConfig returns a RedisConfig with default values.
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		URL:         "tcp://localhost:6379",
+		Parts:       []int{},
+		Command:     "",
+		Key:         "",
+		Args:        []string{},
+		ResultPath:  "",
+		Retries:     0,
+		RetryPeriod: "500ms",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Redis is a processor that executes Redis commands for each message of a
+// batch and places the results into the messages.
+type Redis struct {
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	parts       []int
+	command     string
+	key         *text.InterpolatedString
+	args        []*text.InterpolatedString
+	resultPath  []string
+	retries     int
+	retryPeriod time.Duration
+
+	client *redis.Client
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mRetry     metrics.StatCounter
+	mLatency   metrics.StatTimer
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewRedis returns a Redis processor.
+func NewRedis(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if len(conf.Redis.Command) == 0 {
+		return nil, errors.New("a command must be specified")
+	}
+
+	var retryPeriod time.Duration
+	if tout := conf.Redis.RetryPeriod; len(tout) > 0 {
+		var err error
+		if retryPeriod, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse retry period string: %v", err)
+		}
+	}
+
+	url, err := url.Parse(conf.Redis.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var pass string
+	if url.User != nil {
+		pass, _ = url.User.Password()
+	}
+
+	r := &Redis{
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		parts:       conf.Redis.Parts,
+		command:     conf.Redis.Command,
+		key:         text.NewInterpolatedString(conf.Redis.Key),
+		retries:     conf.Redis.Retries,
+		retryPeriod: retryPeriod,
+
+		client: redis.NewClient(&redis.Options{
+			Addr:     url.Host,
+			Network:  url.Scheme,
+			Password: pass,
+		}),
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErrJSONS:  stats.GetCounter("error.json_set"),
+		mRetry:     stats.GetCounter("retry"),
+		mLatency:   stats.GetTimer("latency"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	for _, arg := range conf.Redis.Args {
+		r.args = append(r.args, text.NewInterpolatedString(arg))
+	}
+	if len(conf.Redis.ResultPath) > 0 {
+		r.resultPath = strings.Split(conf.Redis.ResultPath, ".")
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// redisResultToJSON converts a Redis reply into a value that can be marshalled
+// into JSON.
+func redisResultToJSON(res interface{}) interface{} {
+	switch t := res.(type) {
+	case []byte:
+		return string(t)
+	case []interface{}:
+		arr := make([]interface{}, len(t))
+		for i, v := range t {
+			arr[i] = redisResultToJSON(v)
+		}
+		return arr
+	}
+	return res
+}
+
+func (r *Redis) exec(args []interface{}) (interface{}, error) {
+	tStarted := time.Now()
+	defer func() {
+		r.mLatency.Timing(time.Since(tStarted).Nanoseconds())
+	}()
+
+	res, err := r.client.Do(args...).Result()
+	for i := 0; i < r.retries && err != nil && err != redis.Nil; i++ {
+		r.log.Errorf("Command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mRetry.Incr(1)
+		res, err = r.client.Do(args...).Result()
+	}
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return res, err
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *Redis) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		lMsg := message.Lock(newMsg, index)
+
+		args := make([]interface{}, 0, len(r.args)+2)
+		args = append(args, r.command)
+		if key := r.key.Get(lMsg); len(key) > 0 {
+			args = append(args, key)
+		}
+		for _, arg := range r.args {
+			args = append(args, arg.Get(lMsg))
+		}
+
+		res, err := r.exec(args)
+		if err != nil {
+			r.mErr.Incr(1)
+			r.log.Debugf("Command '%v' failed: %v\n", r.command, err)
+			return err
+		}
+		res = redisResultToJSON(res)
+
+		if len(r.resultPath) == 0 {
+			if str, isStr := res.(string); isStr {
+				part.Set([]byte(str))
+				return nil
+			}
+			if err = part.SetJSON(res); err != nil {
+				r.mErrJSONS.Incr(1)
+				r.mErr.Incr(1)
+				r.log.Debugf("Failed to convert result into part: %v\n", err)
+			}
+			return err
+		}
+
+		jsonPart, err := part.JSON()
+		if err == nil {
+			jsonPart, err = message.CopyJSON(jsonPart)
+		}
+		if err != nil {
+			r.mErrJSONP.Incr(1)
+			r.mErr.Incr(1)
+			r.log.Debugf("Failed to parse part into json: %v\n", err)
+			return err
+		}
+
+		gPart, _ := gabs.Consume(jsonPart)
+		if _, err = gPart.Set(res, r.resultPath...); err == nil {
+			err = part.SetJSON(gPart.Data())
+		}
+		if err != nil {
+			r.mErrJSONS.Incr(1)
+			r.mErr.Incr(1)
+			r.log.Debugf("Failed to set result: %v\n", err)
+		}
+		return err
+	}
+
+	IteratePartsWithSpan(TypeRedis, r.parts, newMsg, proc)
+
+	r.mBatchSent.Incr(1)
+	r.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (r *Redis) CloseAsync() {
+	r.client.Close()
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (r *Redis) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,196 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/ory/dockertest"
+)
+
+func TestRedisBadConfig(t *testing.T) {
+	conf := NewConfig()
+	if _, err := NewRedis(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing command")
+	}
+
+	conf.Redis.Command = "get"
+	conf.Redis.RetryPeriod = "nope"
+	if _, err := NewRedis(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad retry period")
+	}
+}
+
+func TestRedisResultToJSON(t *testing.T) {
+	input := []interface{}{"foo", int64(5), []byte("bar"), nil, []interface{}{"baz"}}
+	exp := []interface{}{"foo", int64(5), "bar", nil, []interface{}{"baz"}}
+	if act := redisResultToJSON(input); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestRedisIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+
+	resource, err := pool.Run("redis", "latest", nil)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+
+	url := fmt.Sprintf("tcp://localhost:%v", resource.GetPort("6379/tcp"))
+
+	if err = pool.Retry(func() error {
+		conf := NewConfig()
+		conf.Redis.URL = url
+		conf.Redis.Command = "ping"
+		conf.Redis.Retries = 0
+
+		r, cErr := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+		if cErr != nil {
+			return cErr
+		}
+		defer r.CloseAsync()
+		_, cErr = r.(*Redis).exec([]interface{}{"ping"})
+		return cErr
+	}); err != nil {
+		t.Fatalf("Could not connect to docker resource: %s", err)
+	}
+
+	defer func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+	}()
+
+	t.Run("testRedisIncrBy", func(te *testing.T) {
+		testRedisIncrBy(url, te)
+	})
+	t.Run("testRedisSets", func(te *testing.T) {
+		testRedisSets(url, te)
+	})
+	t.Run("testRedisGetMissing", func(te *testing.T) {
+		testRedisGetMissing(url, te)
+	})
+}
+
+func testRedisIncrBy(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+	conf.Redis.Command = "incrby"
+	conf.Redis.Key = "benthos_test_counter_${!json_field:id}"
+	conf.Redis.Args = []string{"${!json_field:n}"}
+	conf.Redis.ResultPath = "result.count"
+
+	r, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.CloseAsync()
+
+	msgs, res := r.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"a","n":2}`),
+		[]byte(`{"id":"b","n":1}`),
+		[]byte(`{"id":"a","n":3}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"id":"a","n":2,"result":{"count":2}}`),
+		[]byte(`{"id":"b","n":1,"result":{"count":1}}`),
+		[]byte(`{"id":"a","n":3,"result":{"count":5}}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func testRedisSets(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+	conf.Redis.Command = "sadd"
+	conf.Redis.Key = "benthos_test_set"
+	conf.Redis.Args = []string{"${!content}"}
+
+	sadd, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sadd.CloseAsync()
+
+	msgs, _ := sadd.ProcessMessage(message.New([][]byte{
+		[]byte(`foo`), []byte(`bar`), []byte(`foo`),
+	}))
+	exp := [][]byte{[]byte(`1`), []byte(`1`), []byte(`0`)}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	conf.Redis.Command = "sismember"
+	sismember, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sismember.CloseAsync()
+
+	msgs, _ = sismember.ProcessMessage(message.New([][]byte{
+		[]byte(`foo`), []byte(`baz`),
+	}))
+	exp = [][]byte{[]byte(`1`), []byte(`0`)}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func testRedisGetMissing(url string, t *testing.T) {
+	conf := NewConfig()
+	conf.Redis.URL = url
+	conf.Redis.Command = "get"
+	conf.Redis.Key = "benthos_test_missing_key"
+	conf.Redis.ResultPath = "value"
+
+	r, err := NewRedis(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.CloseAsync()
+
+	msgs, _ := r.ProcessMessage(message.New([][]byte{[]byte(`{"id":"foo"}`)}))
+	if exp, act := `{"id":"foo","value":null}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected missing key not to fail")
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRetry] = TypeSpec{
+		constructor: NewRetry,
+		description: `
+Executes a list of child processors on each message of a batch individually, and
+if the result of the processors is flagged as having failed the processors are
+executed again on the original message until they succeed or the retries are
+exhausted.
+
+Messages that fail are retried together in rounds, where the wait between
+rounds grows exponentially according to the ` + "`backoff`" + ` fields. The
+field ` + "`max_retries`" + ` caps the number of rounds after the first attempt,
+where zero means messages are retried until they succeed.
+
+Any failure flags from previous processors are cleared before each attempt, and
+the results of a successful attempt replace the original message, which means
+it's possible for a child processor to expand a message into multiple messages
+or to filter it entirely.
+
+If the retries for a message are exhausted then by default the result of the
+final attempt continues through the pipeline flagged as failed, allowing you to
+use [error handling patterns](../error_handling.md). Alternatively, setting
+` + "`drop_exhausted`" + ` to ` + "`true`" + ` drops these messages instead.
+
+For example, in order to retry an HTTP enrichment up to five times:
+
+` + "``` yaml" + `
+retry:
+  max_retries: 5
+  backoff:
+    initial_interval: 1s
+    max_interval: 10s
+  processors:
+  - http:
+      request:
+        url: http://example.com/enrich
+        retries: 0
+` + "```" + ``,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			procConfs := make([]interface{}, len(conf.Retry.Processors))
+			for i, pConf := range conf.Retry.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"max_retries":    conf.Retry.MaxRetries,
+				"backoff":        conf.Retry.Backoff,
+				"drop_exhausted": conf.Retry.DropExhausted,
+				"processors":     procConfs,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RetryConfig is a config struct containing fields for the Retry processor.
+type RetryConfig struct {
+	retries.Config `json:",inline" yaml:",inline"`
+	DropExhausted  bool     `json:"drop_exhausted" yaml:"drop_exhausted"`
+	Processors     []Config `json:"processors" yaml:"processors"`
+}
+
+// NewRetryConfig returns a default RetryConfig.
+func NewRetryConfig() RetryConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "500ms"
+	rConf.Backoff.MaxInterval = "10s"
+	rConf.Backoff.MaxElapsedTime = "0s"
+
+	return RetryConfig{
+		Config:        rConf,
+		DropExhausted: false,
+		Processors:    []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Retry is a processor that executes child processors on each message of a
+// batch, and retries those that fail with an exponential backoff.
+type Retry struct {
+	children      []types.Processor
+	backoffCtor   func() backoff.BackOff
+	dropExhausted bool
+
+	log log.Modular
+
+	closed    int32
+	closeChan chan struct{}
+
+	mCount     metrics.StatCounter
+	mRetry     metrics.StatCounter
+	mExhausted metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewRetry returns a Retry processor.
+func NewRetry(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	backoffCtor, err := conf.Retry.GetCtor()
+	if err != nil {
+		return nil, err
+	}
+
+	var children []types.Processor
+	for i, pconf := range conf.Retry.Processors {
+		prefix := fmt.Sprintf("processor.%v", i)
+		var proc Type
+		if proc, err = New(pconf, mgr, log.NewModule("."+prefix), metrics.Namespaced(stats, prefix)); err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+
+	return &Retry{
+		children:      children,
+		backoffCtor:   backoffCtor,
+		dropExhausted: conf.Retry.DropExhausted,
+
+		log: log,
+
+		closeChan: make(chan struct{}),
+
+		mCount:     stats.GetCounter("count"),
+		mRetry:     stats.GetCounter("retry"),
+		mExhausted: stats.GetCounter("exhausted"),
+		mDropped:   stats.GetCounter("dropped"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// attempt executes the child processors on a single message of a batch and
+// returns the resulting parts along with whether the attempt succeeded.
+func (r *Retry) attempt(msg types.Message, index int) ([]types.Part, bool) {
+	part := msg.Get(index).Copy()
+	ClearFail(part)
+
+	reqMsg := message.New(nil)
+	reqMsg.Append(part)
+
+	resultMsgs, res := ExecuteAll(r.children, reqMsg)
+	if res != nil && res.Error() != nil {
+		FlagErr(part, res.Error())
+		return []types.Part{part}, false
+	}
+
+	succeeded := true
+	var parts []types.Part
+	for _, m := range resultMsgs {
+		m.Iter(func(i int, p types.Part) error {
+			if HasFailed(p) {
+				succeeded = false
+			}
+			parts = append(parts, p)
+			return nil
+		})
+	}
+	return parts, succeeded
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *Retry) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+
+	spans := tracing.CreateChildSpans(TypeRetry, msg)
+	defer func() {
+		for _, s := range spans {
+			s.Finish()
+		}
+	}()
+
+	results := make([][]types.Part, msg.Len())
+	pending := make([]int, msg.Len())
+	for i := range pending {
+		pending[i] = i
+	}
+
+	boff := r.backoffCtor()
+	for len(pending) > 0 {
+		var failed []int
+		for _, i := range pending {
+			var succeeded bool
+			if results[i], succeeded = r.attempt(msg, i); !succeeded {
+				failed = append(failed, i)
+			}
+		}
+		if len(failed) == 0 {
+			break
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			r.mExhausted.Incr(int64(len(failed)))
+			r.log.Debugf("Retries exhausted for %v messages\n", len(failed))
+			if r.dropExhausted {
+				r.mDropped.Incr(int64(len(failed)))
+				for _, i := range failed {
+					results[i] = nil
+				}
+			}
+			break
+		}
+
+		r.mRetry.Incr(int64(len(failed)))
+		for _, s := range spans {
+			s.LogEvent("retry")
+		}
+		select {
+		case <-time.After(wait):
+		case <-r.closeChan:
+			return nil, response.NewError(types.ErrTypeClosed)
+		}
+		pending = failed
+	}
+
+	newMsg := message.New(nil)
+	for _, parts := range results {
+		newMsg.Append(parts...)
+	}
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	r.mBatchSent.Incr(1)
+	r.mSent.Incr(int64(newMsg.Len()))
+
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (r *Retry) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.closed, 0, 1) {
+		close(r.closeChan)
+	}
+	for _, c := range r.children {
+		c.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (r *Retry) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, c := range r.children {
+		if err := c.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,207 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestRetryExhausted(t *testing.T) {
+	procConf := NewConfig()
+	procConf.Type = TypeNumber
+	procConf.Number.Operator = "add"
+	procConf.Number.Value = 1
+
+	conf := NewConfig()
+	conf.Type = TypeRetry
+	conf.Retry.MaxRetries = 2
+	conf.Retry.Backoff.InitialInterval = "1ms"
+	conf.Retry.Backoff.MaxInterval = "1ms"
+	conf.Retry.Processors = []Config{procConf}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`5`),
+		[]byte(`nope`),
+		[]byte(`10`),
+	})
+	FlagFail(input.Get(0))
+
+	msgs, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte(`6`),
+		[]byte(`nope`),
+		[]byte(`11`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, exp := range []bool{false, true, false} {
+		if act := HasFailed(msgs[0].Get(i)); act != exp {
+			t.Errorf("Wrong failed flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestRetryExhaustedDrop(t *testing.T) {
+	procConf := NewConfig()
+	procConf.Type = TypeNumber
+	procConf.Number.Operator = "add"
+	procConf.Number.Value = 1
+
+	conf := NewConfig()
+	conf.Type = TypeRetry
+	conf.Retry.MaxRetries = 1
+	conf.Retry.Backoff.InitialInterval = "1ms"
+	conf.Retry.Backoff.MaxInterval = "1ms"
+	conf.Retry.DropExhausted = true
+	conf.Retry.Processors = []Config{procConf}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`nope`),
+		[]byte(`10`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`11`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`nope`),
+	}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages, received: %v", len(msgs))
+	}
+	if res == nil {
+		t.Fatal("Expected response from dropped batch")
+	}
+	if err = res.Error(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryHTTPSucceeds(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) < 3 {
+			http.Error(w, "test error", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("foobar"))
+	}))
+	defer ts.Close()
+
+	procConf := NewConfig()
+	procConf.Type = TypeHTTP
+	procConf.HTTP.Client.URL = ts.URL + "/testpost"
+	procConf.HTTP.Client.NumRetries = 0
+
+	conf := NewConfig()
+	conf.Type = TypeRetry
+	conf.Retry.MaxRetries = 5
+	conf.Retry.Backoff.InitialInterval = "1ms"
+	conf.Retry.Backoff.MaxInterval = "1ms"
+	conf.Retry.Processors = []Config{procConf}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`test`)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := "foobar", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Did not expect failed flag")
+	}
+	if exp, act := uint32(3), atomic.LoadUint32(&reqCount); exp != act {
+		t.Errorf("Wrong count of requests: %v != %v", act, exp)
+	}
+}
+
+func TestRetryClose(t *testing.T) {
+	procConf := NewConfig()
+	procConf.Type = TypeNumber
+
+	conf := NewConfig()
+	conf.Type = TypeRetry
+	conf.Retry.MaxRetries = 0
+	conf.Retry.Backoff.InitialInterval = "1h"
+	conf.Retry.Backoff.MaxInterval = "1h"
+	conf.Retry.Processors = []Config{procConf}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		proc.CloseAsync()
+	}()
+
+	_, res := proc.ProcessMessage(message.New([][]byte{[]byte(`nope`)}))
+	if res == nil {
+		t.Fatal("Expected error response")
+	}
+	if exp, act := types.ErrTypeClosed, res.Error(); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+	if err = proc.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -26,9 +26,12 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/drop"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/OneOfOne/xxhash"
 )
 
 //------------------------------------------------------------------------------
@@ -39,7 +42,23 @@ func init() {
 		description: `
 Retains a randomly sampled percentage of message batches (0 to 100) and drops
 all others. The random seed is static in order to sample deterministically, but
-can be set in config to allow parallel samples that are unique.`,
+can be set in config to allow parallel samples that are unique.
+
+If a ` + "`key`" + ` is set then batches are instead sampled by hashing the
+resolved key, so that batches sharing a key are either all retained or all
+dropped. This is useful for shipping a fraction of traffic to expensive sinks
+whilst keeping all messages of, for example, a trace or user together. The key
+supports [function interpolations](../config_interpolation.md#functions),
+which are resolved against the first message of a batch:
+
+` + "``` yaml" + `
+sample:
+  retain: 5
+  key: ${!json_field:trace_id}
+` + "```" + `
+
+In order to sample individual messages of a batch use this processor with the
+` + "[`for_each`](#for_each)" + ` processor.`,
 	}
 }
 
@@ -49,6 +68,7 @@ can be set in config to allow parallel samples that are unique.`,
 type SampleConfig struct {
 	Retain     float64 `json:"retain" yaml:"retain"`
 	RandomSeed int64   `json:"seed" yaml:"seed"`
+	Key        string  `json:"key" yaml:"key"`
 }
 
 // NewSampleConfig returns a SampleConfig with default values.
@@ -56,6 +76,7 @@ func NewSampleConfig() SampleConfig {
 	return SampleConfig{
 		Retain:     10.0, // 10%
 		RandomSeed: 0,
+		Key:        "",
 	}
 }
 
@@ -64,10 +85,12 @@ func NewSampleConfig() SampleConfig {
 // Sample is a processor that drops messages based on a random sample.
 type Sample struct {
 	conf  Config
+	mgr   types.Manager
 	log   log.Modular
 	stats metrics.Type
 
 	retain float64
+	key    *text.InterpolatedString
 	gen    *rand.Rand
 	mut    sync.Mutex
 
@@ -82,11 +105,17 @@ func NewSample(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
 	gen := rand.New(rand.NewSource(conf.Sample.RandomSeed))
+	var key *text.InterpolatedString
+	if len(conf.Sample.Key) > 0 {
+		key = text.NewInterpolatedString(conf.Sample.Key)
+	}
 	return &Sample{
 		conf:   conf,
+		mgr:    mgr,
 		log:    log,
 		stats:  stats,
 		retain: conf.Sample.Retain / 100.0,
+		key:    key,
 		gen:    gen,
 
 		mCount:     stats.GetCounter("count"),
@@ -102,10 +131,18 @@ func NewSample(
 // resulting messages or a response to be sent back to the message source.
 func (s *Sample) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	s.mCount.Incr(1)
-	s.mut.Lock()
-	defer s.mut.Unlock()
-	if s.gen.Float64() > s.retain {
+
+	var rate float64
+	if s.key != nil {
+		rate = scaleNum(xxhash.Checksum64([]byte(s.key.Get(msg)))) / 100.0
+	} else {
+		s.mut.Lock()
+		rate = s.gen.Float64()
+		s.mut.Unlock()
+	}
+	if rate > s.retain {
 		s.mDropped.Incr(1)
+		drop.Report(s.mgr, TypeSample, msg)
 		return nil, response.NewAck()
 	}
 	s.mBatchSent.Incr(1)
@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"testing"
@@ -103,3 +104,48 @@ func TestSample24Percent(t *testing.T) {
 		t.Errorf("Sample error greater than margin: %v != %v", act, exp)
 	}
 }
+
+func TestSampleKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Sample.Retain = 20.0
+	conf.Sample.Key = "${!json_field:id}"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewSample(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := 100000
+	totalSampled := 0
+	margin := 0.02
+	for i := 0; i < total; i++ {
+		msgIn := message.New([][]byte{
+			[]byte(fmt.Sprintf(`{"id":"%v","attempt":1}`, i)),
+		})
+		msgs, _ := proc.ProcessMessage(msgIn)
+		sampled := len(msgs) == 1
+		if sampled {
+			totalSampled++
+		}
+
+		// Messages sharing a key should always share the same fate.
+		msgIn = message.New([][]byte{
+			[]byte(fmt.Sprintf(`{"id":"%v","attempt":2}`, i)),
+		})
+		if msgs, _ = proc.ProcessMessage(msgIn); sampled != (len(msgs) == 1) {
+			t.Fatalf("Key %v sampled inconsistently", i)
+		}
+	}
+
+	act, exp := (float64(totalSampled)/float64(total))*100.0, conf.Sample.Retain
+	var sampleError float64
+	if exp > act {
+		sampleError = (exp - act) / exp
+	} else {
+		sampleError = (act - exp) / exp
+	}
+	if sampleError > margin {
+		t.Errorf("Sample error greater than margin: %v != %v", act, exp)
+	}
+}
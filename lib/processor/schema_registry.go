@@ -0,0 +1,494 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/linkedin/goavro/v2"
+	"github.com/opentracing/opentracing-go"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSchemaRegistry] = TypeSpec{
+		constructor: NewSchemaRegistry,
+		description: `
+EXPERIMENTAL: This processor is considered experimental and is therefore subject
+to change outside of major version releases.
+
+Converts messages between JSON and the wire format of a
+[Confluent Schema Registry](https://docs.confluent.io/current/schema-registry/index.html),
+where a payload is prefixed with a magic byte and the ID of the schema it was
+encoded with. Avro, JSON Schema and Protobuf schemas are supported.
+
+### Operators
+
+#### ` + "`to_json`" + `
+
+Decodes a message using the schema identified by its wire format prefix, which
+is fetched from the registry and cached, and replaces the message with the JSON
+representation of the document.
+
+#### ` + "`from_json`" + `
+
+Encodes a JSON message using the latest schema registered under a subject, and
+prefixes the result with the ID of that schema. Messages encoded with a JSON
+Schema are validated against it before being sent.
+
+### Subject Name Strategies
+
+When encoding, the subject of the schema is determined by the field
+` + "`subject_name_strategy`" + `, which can be one of the following:
+
+- ` + "`topic`" + `: The subject is ` + "`<topic>-value`" + `.
+- ` + "`record`" + `: The subject is the value of ` + "`record_name`" + `.
+- ` + "`topic_record`" + `: The subject is ` + "`<topic>-<record_name>`" + `.
+
+The field ` + "`topic`" + ` supports
+[function interpolations](../config_interpolation.md#functions) and by default
+resolves to the topic of messages consumed from Kafka.
+
+### Protobuf
+
+The field ` + "`record_name`" + ` is also used to select the fully qualified
+name of the message type to encode when a Protobuf schema defines more than one
+message, otherwise the first message type of the schema is used. Protobuf
+schemas that import other registered schemas are not currently supported.
+
+Fields of the type ` + "`google.protobuf.Any`" + ` are resolved against the
+message types declared within the schema, and are represented in JSON as the
+fields of the unpacked message along with an ` + "`@type`" + ` field containing
+its type URL.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryConfig contains configuration fields for the SchemaRegistry
+// processor.
+type SchemaRegistryConfig struct {
+	Parts               []int       `json:"parts" yaml:"parts"`
+	Operator            string      `json:"operator" yaml:"operator"`
+	URL                 string      `json:"url" yaml:"url"`
+	SubjectNameStrategy string      `json:"subject_name_strategy" yaml:"subject_name_strategy"`
+	Topic               string      `json:"topic" yaml:"topic"`
+	RecordName          string      `json:"record_name" yaml:"record_name"`
+	CacheDuration       string      `json:"cache_duration" yaml:"cache_duration"`
+	Timeout             string      `json:"timeout" yaml:"timeout"`
+	TLS                 btls.Config `json:"tls" yaml:"tls"`
+	auth.Config         `json:",inline" yaml:",inline"`
+}
+
+// NewSchemaRegistryConfig returns a SchemaRegistryConfig with default values.
+func NewSchemaRegistryConfig() SchemaRegistryConfig {
+	return SchemaRegistryConfig{
+		Parts:               []int{},
+		Operator:            "to_json",
+		URL:                 "http://localhost:8081",
+		SubjectNameStrategy: "topic",
+		Topic:               "${!metadata:kafka_topic}",
+		RecordName:          "",
+		CacheDuration:       "10m",
+		Timeout:             "5s",
+		TLS:                 btls.NewConfig(),
+		Config:              auth.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// schemaRegistryCodec converts between JSON documents and the payload of a
+// message following the wire format header.
+type schemaRegistryCodec interface {
+	ToJSON(payload []byte) (interface{}, error)
+	FromJSON(jObj interface{}) ([]byte, error)
+}
+
+type avroRegistryCodec struct {
+	codec *goavro.Codec
+}
+
+func (a *avroRegistryCodec) ToJSON(payload []byte) (interface{}, error) {
+	jObj, _, err := a.codec.NativeFromBinary(payload)
+	return jObj, err
+}
+
+func (a *avroRegistryCodec) FromJSON(jObj interface{}) ([]byte, error) {
+	return a.codec.BinaryFromNative(nil, jObj)
+}
+
+type jsonRegistryCodec struct {
+	schema *gojsonschema.Schema
+}
+
+func (j *jsonRegistryCodec) validate(jObj interface{}) error {
+	result, err := j.schema.Validate(gojsonschema.NewGoLoader(jObj))
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		var errs []string
+		for _, desc := range result.Errors() {
+			errs = append(errs, desc.String())
+		}
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (j *jsonRegistryCodec) ToJSON(payload []byte) (interface{}, error) {
+	var jObj interface{}
+	if err := json.Unmarshal(payload, &jObj); err != nil {
+		return nil, err
+	}
+	return jObj, nil
+}
+
+func (j *jsonRegistryCodec) FromJSON(jObj interface{}) ([]byte, error) {
+	if err := j.validate(jObj); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jObj)
+}
+
+func newSchemaRegistryCodec(schemaType, schema, recordName string) (schemaRegistryCodec, error) {
+	switch schemaType {
+	case "", "AVRO":
+		codec, err := goavro.NewCodec(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Avro schema: %v", err)
+		}
+		return &avroRegistryCodec{codec: codec}, nil
+	case "JSON":
+		s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON schema: %v", err)
+		}
+		return &jsonRegistryCodec{schema: s}, nil
+	case "PROTOBUF":
+		return newProtobufRegistryCodec(schema, recordName)
+	}
+	return nil, fmt.Errorf("schema type not supported: %v", schemaType)
+}
+
+//------------------------------------------------------------------------------
+
+type schemaRegistrySubject struct {
+	id      int
+	codec   schemaRegistryCodec
+	fetched time.Time
+}
+
+// SchemaRegistry is a processor that converts messages between JSON and the
+// wire format of a schema registry.
+type SchemaRegistry struct {
+	parts      []int
+	conf       SchemaRegistryConfig
+	baseURL    string
+	client     http.Client
+	cacheFor   time.Duration
+	topic      *text.InterpolatedString
+	subjectFor func(topic string) string
+	operator   func(part types.Part, index int, msg types.Message) error
+
+	cacheMut  sync.Mutex
+	idCache   map[int]schemaRegistryCodec
+	subjCache map[string]schemaRegistrySubject
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrFetch  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaRegistry returns a SchemaRegistry processor.
+func NewSchemaRegistry(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	s := &SchemaRegistry{
+		parts:     conf.SchemaRegistry.Parts,
+		conf:      conf.SchemaRegistry,
+		baseURL:   strings.TrimSuffix(conf.SchemaRegistry.URL, "/"),
+		topic:     text.NewInterpolatedString(conf.SchemaRegistry.Topic),
+		idCache:   map[int]schemaRegistryCodec{},
+		subjCache: map[string]schemaRegistrySubject{},
+		log:       log,
+		stats:     stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrFetch:  stats.GetCounter("error.fetch"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	if len(s.baseURL) == 0 {
+		return nil, errors.New("a url must be specified")
+	}
+
+	var err error
+	if tout := conf.SchemaRegistry.Timeout; len(tout) > 0 {
+		if s.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if cDur := conf.SchemaRegistry.CacheDuration; len(cDur) > 0 {
+		if s.cacheFor, err = time.ParseDuration(cDur); err != nil {
+			return nil, fmt.Errorf("failed to parse cache_duration string: %v", err)
+		}
+	}
+	if conf.SchemaRegistry.TLS.Enabled {
+		tlsConf, err := conf.SchemaRegistry.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		s.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	recordName := conf.SchemaRegistry.RecordName
+	switch conf.SchemaRegistry.SubjectNameStrategy {
+	case "topic":
+		s.subjectFor = func(topic string) string {
+			return topic + "-value"
+		}
+	case "record":
+		if len(recordName) == 0 {
+			return nil, errors.New("a record_name must be specified for the record subject name strategy")
+		}
+		s.subjectFor = func(topic string) string {
+			return recordName
+		}
+	case "topic_record":
+		if len(recordName) == 0 {
+			return nil, errors.New("a record_name must be specified for the topic_record subject name strategy")
+		}
+		s.subjectFor = func(topic string) string {
+			return topic + "-" + recordName
+		}
+	default:
+		return nil, fmt.Errorf("subject name strategy not recognised: %v", conf.SchemaRegistry.SubjectNameStrategy)
+	}
+
+	switch conf.SchemaRegistry.Operator {
+	case "to_json":
+		s.operator = s.toJSON
+	case "from_json":
+		s.operator = s.fromJSON
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.SchemaRegistry.Operator)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+type schemaRegistryResponse struct {
+	ID         int    `json:"id"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+func (s *SchemaRegistry) fetch(path string) (*schemaRegistryResponse, error) {
+	req, err := http.NewRequest("GET", s.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if err = s.conf.Config.Sign(req); err != nil {
+		return nil, err
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %v returned status %v: %s", path, res.StatusCode, resBytes)
+	}
+
+	var sRes schemaRegistryResponse
+	if err = json.Unmarshal(resBytes, &sRes); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return &sRes, nil
+}
+
+func (s *SchemaRegistry) codecByID(id int) (schemaRegistryCodec, error) {
+	s.cacheMut.Lock()
+	defer s.cacheMut.Unlock()
+
+	if codec, exists := s.idCache[id]; exists {
+		return codec, nil
+	}
+
+	sRes, err := s.fetch(fmt.Sprintf("/schemas/ids/%v", id))
+	if err != nil {
+		s.mErrFetch.Incr(1)
+		return nil, fmt.Errorf("failed to fetch schema %v: %v", id, err)
+	}
+	codec, err := newSchemaRegistryCodec(sRes.SchemaType, sRes.Schema, s.conf.RecordName)
+	if err != nil {
+		return nil, err
+	}
+	s.idCache[id] = codec
+	return codec, nil
+}
+
+func (s *SchemaRegistry) codecBySubject(subject string) (int, schemaRegistryCodec, error) {
+	s.cacheMut.Lock()
+	defer s.cacheMut.Unlock()
+
+	if cached, exists := s.subjCache[subject]; exists && time.Since(cached.fetched) < s.cacheFor {
+		return cached.id, cached.codec, nil
+	}
+
+	sRes, err := s.fetch(fmt.Sprintf("/subjects/%v/versions/latest", url.PathEscape(subject)))
+	if err != nil {
+		s.mErrFetch.Incr(1)
+		return 0, nil, fmt.Errorf("failed to fetch schema for subject '%v': %v", subject, err)
+	}
+
+	codec, exists := s.idCache[sRes.ID]
+	if !exists {
+		if codec, err = newSchemaRegistryCodec(sRes.SchemaType, sRes.Schema, s.conf.RecordName); err != nil {
+			return 0, nil, err
+		}
+		s.idCache[sRes.ID] = codec
+	}
+	s.subjCache[subject] = schemaRegistrySubject{
+		id:      sRes.ID,
+		codec:   codec,
+		fetched: time.Now(),
+	}
+	return sRes.ID, codec, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *SchemaRegistry) toJSON(part types.Part, index int, msg types.Message) error {
+	payload := part.Get()
+	if len(payload) < 5 || payload[0] != 0 {
+		return errors.New("message does not match the schema registry wire format")
+	}
+
+	id := int(binary.BigEndian.Uint32(payload[1:5]))
+	codec, err := s.codecByID(id)
+	if err != nil {
+		return err
+	}
+
+	jObj, err := codec.ToJSON(payload[5:])
+	if err != nil {
+		return fmt.Errorf("failed to decode message with schema %v: %v", id, err)
+	}
+	if err = part.SetJSON(jObj); err != nil {
+		return fmt.Errorf("failed to set JSON: %v", err)
+	}
+	return nil
+}
+
+func (s *SchemaRegistry) fromJSON(part types.Part, index int, msg types.Message) error {
+	jObj, err := part.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	subject := s.subjectFor(s.topic.Get(message.Lock(msg, index)))
+	id, codec, err := s.codecBySubject(subject)
+	if err != nil {
+		return err
+	}
+
+	payload, err := codec.FromJSON(jObj)
+	if err != nil {
+		return fmt.Errorf("failed to encode message with schema %v: %v", id, err)
+	}
+
+	encoded := make([]byte, 5, len(payload)+5)
+	binary.BigEndian.PutUint32(encoded[1:], uint32(id))
+	part.Set(append(encoded, payload...))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *SchemaRegistry) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if err := s.operator(part, index, newMsg); err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Operator failed: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeSchemaRegistry, s.parts, newMsg, proc)
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *SchemaRegistry) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *SchemaRegistry) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,183 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+//------------------------------------------------------------------------------
+
+// protobufRegistryCodec converts between JSON and Protobuf payloads, where
+// each payload is prefixed with the indexes of its message type within the
+// schema.
+type protobufRegistryCodec struct {
+	file *desc.FileDescriptor
+
+	// Resolves the message types of google.protobuf.Any fields against those
+	// declared within the schema.
+	anyResolver jsonpb.AnyResolver
+
+	// The message type used for encoding, and its indexes within the schema.
+	encodeType    *desc.MessageDescriptor
+	encodeIndexes []int
+}
+
+func newProtobufRegistryCodec(schema, recordName string) (schemaRegistryCodec, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"schema.proto": schema,
+		}),
+	}
+	files, err := parser.ParseFiles("schema.proto")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Protobuf schema: %v", err)
+	}
+
+	p := &protobufRegistryCodec{
+		file:        files[0],
+		anyResolver: dynamic.AnyResolver(nil, files[0]),
+	}
+	if len(p.file.GetMessageTypes()) == 0 {
+		return nil, errors.New("protobuf schema does not contain any message types")
+	}
+
+	if len(recordName) == 0 {
+		p.encodeType = p.file.GetMessageTypes()[0]
+		p.encodeIndexes = []int{0}
+		return p, nil
+	}
+	if p.encodeIndexes = protobufMessageIndexes(p.file.GetMessageTypes(), recordName); p.encodeIndexes == nil {
+		return nil, fmt.Errorf("message type '%v' not found in Protobuf schema", recordName)
+	}
+	p.encodeType, _ = p.messageType(p.encodeIndexes)
+	return p, nil
+}
+
+// protobufMessageIndexes returns the path of indexes to a message type by its
+// fully qualified name, or nil if it does not exist.
+func protobufMessageIndexes(types []*desc.MessageDescriptor, name string) []int {
+	for i, t := range types {
+		if t.GetFullyQualifiedName() == name {
+			return []int{i}
+		}
+		if nested := protobufMessageIndexes(t.GetNestedMessageTypes(), name); nested != nil {
+			return append([]int{i}, nested...)
+		}
+	}
+	return nil
+}
+
+func (p *protobufRegistryCodec) messageType(indexes []int) (*desc.MessageDescriptor, error) {
+	types := p.file.GetMessageTypes()
+	var mType *desc.MessageDescriptor
+	for _, i := range indexes {
+		if i < 0 || i >= len(types) {
+			return nil, fmt.Errorf("message index %v out of bounds", i)
+		}
+		mType = types[i]
+		types = mType.GetNestedMessageTypes()
+	}
+	return mType, nil
+}
+
+func (p *protobufRegistryCodec) ToJSON(payload []byte) (interface{}, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 {
+		return nil, errors.New("failed to read message indexes")
+	}
+	payload = payload[n:]
+
+	indexes := []int{0}
+	if count > 0 {
+		indexes = make([]int, count)
+		for i := range indexes {
+			index, n := binary.Varint(payload)
+			if n <= 0 {
+				return nil, errors.New("failed to read message indexes")
+			}
+			indexes[i] = int(index)
+			payload = payload[n:]
+		}
+	}
+
+	mType, err := p.messageType(indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamic.NewMessage(mType)
+	if err = msg.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+
+	jBytes, err := msg.MarshalJSONPB(&jsonpb.Marshaler{
+		AnyResolver: p.anyResolver,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var jObj interface{}
+	if err = json.Unmarshal(jBytes, &jObj); err != nil {
+		return nil, err
+	}
+	return jObj, nil
+}
+
+func (p *protobufRegistryCodec) FromJSON(jObj interface{}) ([]byte, error) {
+	jBytes, err := json.Marshal(jObj)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamic.NewMessage(p.encodeType)
+	if err = msg.UnmarshalJSONPB(&jsonpb.Unmarshaler{
+		AnyResolver: p.anyResolver,
+	}, jBytes); err != nil {
+		return nil, err
+	}
+	payload, err := msg.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// The common case of the first message type is encoded as a single zero.
+	var prefix []byte
+	buf := make([]byte, binary.MaxVarintLen64)
+	if len(p.encodeIndexes) == 1 && p.encodeIndexes[0] == 0 {
+		prefix = append(prefix, 0)
+	} else {
+		prefix = append(prefix, buf[:binary.PutVarint(buf, int64(len(p.encodeIndexes)))]...)
+		for _, i := range p.encodeIndexes {
+			prefix = append(prefix, buf[:binary.PutVarint(buf, int64(i))]...)
+		}
+	}
+	return append(prefix, payload...), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,344 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+type fakeSchema struct {
+	id         int
+	subject    string
+	schema     string
+	schemaType string
+}
+
+func newFakeSchemaRegistry(t *testing.T, schemas []fakeSchema) (*httptest.Server, *int64) {
+	var reqs int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&reqs, 1)
+		for _, s := range schemas {
+			if r.URL.Path == "/subjects/"+s.subject+"/versions/latest" ||
+				r.URL.Path == "/schemas/ids/"+strconv.Itoa(s.id) {
+				resBytes, err := json.Marshal(map[string]interface{}{
+					"id":         s.id,
+					"subject":    s.subject,
+					"version":    1,
+					"schema":     s.schema,
+					"schemaType": s.schemaType,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write(resBytes)
+				return
+			}
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	return ts, &reqs
+}
+
+func TestSchemaRegistryAvro(t *testing.T) {
+	ts, reqs := newFakeSchemaRegistry(t, []fakeSchema{
+		{
+			id:      3,
+			subject: "foo-value",
+			schema: `{
+	"type": "record",
+	"name": "identity",
+	"fields": [
+		{ "name": "Name", "type": "string" },
+		{ "name": "Age", "type": "int" }
+	]
+}`,
+		},
+	})
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.SchemaRegistry.URL = ts.URL
+	conf.SchemaRegistry.Operator = "from_json"
+
+	encoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SchemaRegistry.Operator = "to_json"
+	decoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"Age":10,"Name":"foo"}`),
+		[]byte(`{"Age":20,"Name":"bar"}`),
+	})
+	input.Get(0).Metadata().Set("kafka_topic", "foo")
+	input.Get(1).Metadata().Set("kafka_topic", "foo")
+
+	msgs, res := encoder.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	// Avro binary of "foo" (len 3 zigzag) and 10 (zigzag)
+	if exp, act := []byte{0, 0, 0, 0, 3, 6, 'f', 'o', 'o', 20}, msgs[0].Get(0).Get(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong encoded result: %v != %v", act, exp)
+	}
+
+	msgs, res = decoder.ProcessMessage(msgs[0])
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := message.GetAllBytes(input), message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong decoded result: %s != %s", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Unexpected fail flag on part %v", i)
+		}
+	}
+
+	if exp, act := int64(2), atomic.LoadInt64(reqs); exp != act {
+		t.Errorf("Wrong count of registry requests: %v != %v", act, exp)
+	}
+}
+
+func TestSchemaRegistryJSONSchema(t *testing.T) {
+	ts, _ := newFakeSchemaRegistry(t, []fakeSchema{
+		{
+			id:         5,
+			subject:    "foo-identity",
+			schemaType: "JSON",
+			schema: `{
+	"type": "object",
+	"properties": {
+		"name": { "type": "string" }
+	},
+	"required": ["name"]
+}`,
+		},
+	})
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.SchemaRegistry.URL = ts.URL
+	conf.SchemaRegistry.Operator = "from_json"
+	conf.SchemaRegistry.SubjectNameStrategy = "topic_record"
+	conf.SchemaRegistry.Topic = "foo"
+	conf.SchemaRegistry.RecordName = "identity"
+
+	encoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SchemaRegistry.Operator = "to_json"
+	decoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := encoder.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo"}`),
+		[]byte(`{"age":10}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []byte("\x00\x00\x00\x00\x05{\"name\":\"foo\"}"), msgs[0].Get(0).Get(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong encoded result: %q != %q", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Unexpected fail flag on valid part")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected fail flag on invalid part")
+	}
+
+	msgs, res = decoder.ProcessMessage(message.New([][]byte{
+		msgs[0].Get(0).Get(),
+		[]byte(`not wire format`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := `{"name":"foo"}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong decoded result: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected fail flag on invalid part")
+	}
+}
+
+func TestSchemaRegistryProtobuf(t *testing.T) {
+	ts, _ := newFakeSchemaRegistry(t, []fakeSchema{
+		{
+			id:         7,
+			subject:    "foo.Outer.Inner",
+			schemaType: "PROTOBUF",
+			schema: `
+syntax = "proto3";
+package foo;
+
+message First {
+  string id = 1;
+}
+
+message Outer {
+  message Inner {
+    string name = 1;
+    int32 age = 2;
+  }
+}`,
+		},
+	})
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.SchemaRegistry.URL = ts.URL
+	conf.SchemaRegistry.Operator = "from_json"
+	conf.SchemaRegistry.SubjectNameStrategy = "record"
+	conf.SchemaRegistry.RecordName = "foo.Outer.Inner"
+
+	encoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SchemaRegistry.Operator = "to_json"
+	decoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := encoder.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo","age":10}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	// Header, message indexes [1, 0], then fields name and age.
+	exp := []byte{0, 0, 0, 0, 7, 4, 2, 0, 0x0a, 3, 'f', 'o', 'o', 0x10, 10}
+	if act := msgs[0].Get(0).Get(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong encoded result: %v != %v", act, exp)
+	}
+
+	msgs, res = decoder.ProcessMessage(msgs[0])
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := `{"age":10,"name":"foo"}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong decoded result: %v != %v", act, exp)
+	}
+}
+
+func TestSchemaRegistryProtobufAny(t *testing.T) {
+	ts, _ := newFakeSchemaRegistry(t, []fakeSchema{
+		{
+			id:         8,
+			subject:    "foo-value",
+			schemaType: "PROTOBUF",
+			schema: `
+syntax = "proto3";
+package foo;
+
+import "google/protobuf/any.proto";
+
+message Wrapper {
+  google.protobuf.Any payload = 1;
+}
+
+message Inner {
+  string name = 1;
+}`,
+		},
+	})
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.SchemaRegistry.URL = ts.URL
+	conf.SchemaRegistry.Operator = "from_json"
+	conf.SchemaRegistry.Topic = "foo"
+
+	encoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SchemaRegistry.Operator = "to_json"
+	decoder, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"payload":{"@type":"type.googleapis.com/foo.Inner","name":"foo"}}`
+	msgs, res := encoder.ProcessMessage(message.New([][]byte{[]byte(input)}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Fatalf("Failed to encode message: %v", msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	}
+
+	msgs, res = decoder.ProcessMessage(msgs[0])
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := input, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong decoded result: %v != %v", act, exp)
+	}
+}
+
+func TestSchemaRegistryBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.SchemaRegistry.SubjectNameStrategy = "nope"
+	if _, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad subject name strategy")
+	}
+
+	conf = NewConfig()
+	conf.SchemaRegistry.SubjectNameStrategy = "record"
+	if _, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing record name")
+	}
+
+	conf = NewConfig()
+	conf.SchemaRegistry.Operator = "nope"
+	if _, err := NewSchemaRegistry(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+}
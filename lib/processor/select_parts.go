@@ -21,6 +21,9 @@
 package processor
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -28,6 +31,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
 )
 
 //------------------------------------------------------------------------------
@@ -49,7 +53,29 @@ output message) the batch is dropped entirely.
 Message indexes can be negative, and if so the part will be selected from the
 end counting backwards starting from -1. E.g. if index = -1 then the selected
 part will be the last part of the message, if index = -2 then the part before
-the last element with be selected, and so on.`,
+the last element with be selected, and so on.
+
+### Expressions
+
+Alternatively, the field ` + "`expression`" + ` can be set to a comma separated
+list of indexes and ranges, in which case ` + "`parts`" + ` is ignored. A range
+has the form ` + "`start:end`" + `, where the end is exclusive, either side can
+be omitted in order to select from the first or up to the last part, and both
+sides can be negative. E.g. the expression ` + "`-1, 0:2`" + ` with the message
+parts [ '0', '1', '2', '3' ] results in [ '3', '0', '1' ].
+
+Expressions support
+[function interpolations](../config_interpolation.md#functions), which are
+resolved once per batch, allowing the selection to be derived from the
+contents or metadata of the batch:
+
+` + "``` yaml" + `
+select_parts:
+  expression: "0:${!metadata:keep_count}"
+` + "```" + `
+
+If an interpolated expression cannot be parsed then the batch is left
+unchanged and each message is flagged as having failed.`,
 	}
 }
 
@@ -58,26 +84,117 @@ the last element with be selected, and so on.`,
 // SelectPartsConfig contains configuration fields for the SelectParts
 // processor.
 type SelectPartsConfig struct {
-	Parts []int `json:"parts" yaml:"parts"`
+	Parts      []int  `json:"parts" yaml:"parts"`
+	Expression string `json:"expression" yaml:"expression"`
 }
 
 // NewSelectPartsConfig returns a SelectPartsConfig with default values.
 func NewSelectPartsConfig() SelectPartsConfig {
 	return SelectPartsConfig{
-		Parts: []int{0},
+		Parts:      []int{0},
+		Expression: "",
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// partSelection is either a single index or a range of indexes of a batch.
+type partSelection struct {
+	start, end int
+	isRange    bool
+	hasStart   bool
+	hasEnd     bool
+}
+
+func parsePartIndex(str string) (int, error) {
+	index, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse index '%v': %v", str, err)
+	}
+	return index, nil
+}
+
+// parsePartSelections parses a comma separated list of indexes and ranges.
+func parsePartSelections(expr string) ([]partSelection, error) {
+	var selections []partSelection
+	for _, term := range strings.Split(expr, ",") {
+		if len(strings.TrimSpace(term)) == 0 {
+			continue
+		}
+		var sel partSelection
+		var err error
+		if i := strings.Index(term, ":"); i >= 0 {
+			sel.isRange = true
+			if startStr := strings.TrimSpace(term[:i]); len(startStr) > 0 {
+				if sel.start, err = parsePartIndex(startStr); err != nil {
+					return nil, err
+				}
+				sel.hasStart = true
+			}
+			if endStr := strings.TrimSpace(term[i+1:]); len(endStr) > 0 {
+				if sel.end, err = parsePartIndex(endStr); err != nil {
+					return nil, err
+				}
+				sel.hasEnd = true
+			}
+		} else if sel.start, err = parsePartIndex(term); err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	return selections, nil
+}
+
+// indexes returns the indexes selected from a batch of a given size, and
+// whether a single index was out of bounds.
+func (s partSelection) indexes(lParts int) ([]int, bool) {
+	resolve := func(i int) int {
+		if i < 0 {
+			// Negative indexes count backwards from the end.
+			return lParts + i
+		}
+		return i
+	}
+	if !s.isRange {
+		index := resolve(s.start)
+		if index < 0 || index >= lParts {
+			return nil, true
+		}
+		return []int{index}, false
+	}
+
+	start, end := 0, lParts
+	if s.hasStart {
+		if start = resolve(s.start); start < 0 {
+			start = 0
+		}
+	}
+	if s.hasEnd {
+		if end = resolve(s.end); end > lParts {
+			end = lParts
+		}
+	}
+	var indexes []int
+	for i := start; i < end; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes, false
+}
+
+//------------------------------------------------------------------------------
+
 // SelectParts is a processor that selects parts from a message to append to a
 // new message.
 type SelectParts struct {
-	conf  Config
+	conf       Config
+	selections []partSelection
+	expression *text.InterpolatedString
+
 	log   log.Modular
 	stats metrics.Type
 
 	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
 	mSkipped   metrics.StatCounter
 	mSelected  metrics.StatCounter
 	mDropped   metrics.StatCounter
@@ -89,18 +206,34 @@ type SelectParts struct {
 func NewSelectParts(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	return &SelectParts{
+	s := &SelectParts{
 		conf:  conf,
 		log:   log,
 		stats: stats,
 
 		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
 		mSkipped:   stats.GetCounter("skipped"),
 		mSelected:  stats.GetCounter("selected"),
 		mDropped:   stats.GetCounter("dropped"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
-	}, nil
+	}
+
+	expr := conf.SelectParts.Expression
+	if text.ContainsFunctionVariables([]byte(expr)) {
+		s.expression = text.NewInterpolatedString(expr)
+	} else if len(strings.TrimSpace(expr)) > 0 {
+		var err error
+		if s.selections, err = parsePartSelections(expr); err != nil {
+			return nil, fmt.Errorf("failed to parse expression: %v", err)
+		}
+	} else {
+		for _, index := range conf.SelectParts.Parts {
+			s.selections = append(s.selections, partSelection{start: index})
+		}
+	}
+	return s, nil
 }
 
 //------------------------------------------------------------------------------
@@ -110,19 +243,32 @@ func NewSelectParts(
 func (m *SelectParts) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	m.mCount.Incr(1)
 
+	selections := m.selections
+	if m.expression != nil {
+		var err error
+		if selections, err = parsePartSelections(m.expression.Get(msg)); err != nil {
+			m.mErr.Incr(1)
+			m.log.Errorf("Failed to parse expression: %v\n", err)
+			newMsg := msg.Copy()
+			newMsg.Iter(func(i int, p types.Part) error {
+				FlagErr(p, err)
+				return nil
+			})
+			m.mBatchSent.Incr(1)
+			m.mSent.Incr(int64(newMsg.Len()))
+			return []types.Message{newMsg}, nil
+		}
+	}
+
 	newMsg := message.New(nil)
 
 	lParts := msg.Len()
-	for _, index := range m.conf.SelectParts.Parts {
-		if index < 0 {
-			// Negative indexes count backwards from the end.
-			index = lParts + index
-		}
-
-		// Check boundary of part index.
-		if index < 0 || index >= lParts {
+	for _, sel := range selections {
+		indexes, skipped := sel.indexes(lParts)
+		if skipped {
 			m.mSkipped.Incr(1)
-		} else {
+		}
+		for _, index := range indexes {
 			m.mSelected.Incr(1)
 			newMsg.Append(msg.Get(index).Copy())
 		}
@@ -160,3 +160,118 @@ func TestSelectPartsEmpty(t *testing.T) {
 		t.Error("Expected failure with zero parts selected")
 	}
 }
+
+func TestSelectPartsExpression(t *testing.T) {
+	in := [][]byte{
+		[]byte("0"),
+		[]byte("1"),
+		[]byte("2"),
+		[]byte("3"),
+	}
+
+	tests := []struct {
+		expr string
+		out  [][]byte
+	}{
+		{
+			expr: "-1, 0:2",
+			out: [][]byte{
+				[]byte("3"),
+				[]byte("0"),
+				[]byte("1"),
+			},
+		},
+		{
+			expr: "2:",
+			out: [][]byte{
+				[]byte("2"),
+				[]byte("3"),
+			},
+		},
+		{
+			expr: ":-3",
+			out: [][]byte{
+				[]byte("0"),
+			},
+		},
+		{
+			expr: "-2:10, 7",
+			out: [][]byte{
+				[]byte("2"),
+				[]byte("3"),
+			},
+		},
+		{
+			expr: ":",
+			out:  in,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.SelectParts.Expression = test.expr
+
+		proc, err := NewSelectParts(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, res := proc.ProcessMessage(message.New(in))
+		if len(msgs) != 1 {
+			t.Fatalf("Select Parts failed on: %v", test.expr)
+		} else if res != nil {
+			t.Errorf("Expected nil response: %v", res)
+		}
+		if exp, act := test.out, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Unexpected output for %v: %s != %s", test.expr, act, exp)
+		}
+	}
+}
+
+func TestSelectPartsExpressionInterpolated(t *testing.T) {
+	conf := NewConfig()
+	conf.SelectParts.Expression = "0:${!metadata:count}"
+
+	proc, err := NewSelectParts(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte("0"),
+		[]byte("1"),
+		[]byte("2"),
+	})
+	msg.Get(0).Metadata().Set("count", "2")
+
+	msgs, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := [][]byte{[]byte("0"), []byte("1")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+
+	msg.Get(0).Metadata().Set("count", "nope")
+	msgs, res = proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := message.GetAllBytes(msg), message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if !HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected part %v to be flagged as failed", i)
+		}
+	}
+}
+
+func TestSelectPartsBadExpression(t *testing.T) {
+	conf := NewConfig()
+	conf.SelectParts.Expression = "0:foo"
+
+	if _, err := NewSelectParts(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad expression")
+	}
+}
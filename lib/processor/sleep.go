@@ -22,6 +22,8 @@ package processor
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -50,7 +52,15 @@ each message of a batch place it within a
 for_each:
 - sleep:
     duration: ${!metadata:sleep_for}
-` + "```" + ``,
+` + "```" + `
+
+An interpolated duration that is a plain number, such as the value of a
+` + "`Retry-After`" + ` header, is interpreted as a number of seconds. Durations
+that fail to parse result in no sleep.
+
+The ` + "`max_duration`" + ` field caps the period slept, which is useful when
+the duration is taken from message contents that can't be trusted. An empty
+` + "`max_duration`" + ` disables the cap.`,
 	}
 }
 
@@ -58,13 +68,15 @@ for_each:
 
 // SleepConfig contains configuration fields for the Sleep processor.
 type SleepConfig struct {
-	Duration string `json:"duration" yaml:"duration"`
+	Duration    string `json:"duration" yaml:"duration"`
+	MaxDuration string `json:"max_duration" yaml:"max_duration"`
 }
 
 // NewSleepConfig returns a SleepConfig with default values.
 func NewSleepConfig() SleepConfig {
 	return SleepConfig{
-		Duration: "100us",
+		Duration:    "100us",
+		MaxDuration: "",
 	}
 }
 
@@ -81,11 +93,13 @@ type Sleep struct {
 	stats metrics.Type
 
 	duration       time.Duration
+	maxDuration    time.Duration
 	isInterpolated bool
 	durationStr    *text.InterpolatedString
 
 	mCount     metrics.StatCounter
 	mErr       metrics.StatCounter
+	mCapped    metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
 }
@@ -105,12 +119,18 @@ func NewSleep(
 
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
+		mCapped:    stats.GetCounter("capped"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
 
+	var err error
+	if len(conf.Sleep.MaxDuration) > 0 {
+		if t.maxDuration, err = time.ParseDuration(conf.Sleep.MaxDuration); err != nil {
+			return nil, fmt.Errorf("failed to parse max_duration: %v", err)
+		}
+	}
 	if !t.isInterpolated {
-		var err error
 		if t.duration, err = time.ParseDuration(conf.Sleep.Duration); err != nil {
 			return nil, fmt.Errorf("failed to parse duration: %v", err)
 		}
@@ -118,6 +138,16 @@ func NewSleep(
 	return t, nil
 }
 
+// parseSleepDuration parses a duration string, where a plain number is
+// interpreted as seconds.
+func parseSleepDuration(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	if secs, err := strconv.ParseFloat(str, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(str)
+}
+
 //------------------------------------------------------------------------------
 
 // ProcessMessage applies the processor to a message, either creating >0
@@ -135,11 +165,15 @@ func (s *Sleep) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 	period := s.duration
 	if s.isInterpolated {
 		var err error
-		if period, err = time.ParseDuration(s.durationStr.Get(msg)); err != nil {
+		if period, err = parseSleepDuration(s.durationStr.Get(msg)); err != nil {
 			s.log.Errorf("Failed to parse duration: %v\n", err)
 			s.mErr.Incr(1)
 		}
 	}
+	if s.maxDuration > 0 && period > s.maxDuration {
+		s.mCapped.Incr(1)
+		period = s.maxDuration
+	}
 	select {
 	case <-time.After(period):
 	case <-s.closeChan:
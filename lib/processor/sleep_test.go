@@ -125,3 +125,62 @@ func TestSleepBadDuration(t *testing.T) {
 		t.Error("Expected error from bad duration")
 	}
 }
+
+func TestSleepInterpolatedSeconds(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSleep
+	conf.Sleep.Duration = "${!metadata:retry_after}"
+
+	slp, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`foo`)})
+	msg.Get(0).Metadata().Set("retry_after", "0.2")
+
+	tBefore := time.Now()
+	slp.ProcessMessage(msg)
+	tAfter := time.Now()
+
+	if dur := tAfter.Sub(tBefore); dur < (time.Millisecond * 200) {
+		t.Errorf("Message didn't take long enough")
+	}
+}
+
+func TestSleepMaxDuration(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSleep
+	conf.Sleep.Duration = "${!json_field:foo}"
+	conf.Sleep.MaxDuration = "100ms"
+
+	slp, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tBefore := time.Now()
+	slp.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"1h"}`),
+	}))
+	tAfter := time.Now()
+
+	dur := tAfter.Sub(tBefore)
+	if dur < (time.Millisecond * 100) {
+		t.Errorf("Message didn't take long enough")
+	}
+	if dur > time.Second {
+		t.Errorf("Message took too long: %v", dur)
+	}
+}
+
+func TestSleepBadMaxDuration(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSleep
+	conf.Sleep.MaxDuration = "nope"
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err == nil {
+		t.Error("Expected error from bad max duration")
+	}
+}
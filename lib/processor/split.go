@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -44,7 +45,32 @@ bytes (which ever limit is reached first).
 If there is a remainder of messages after splitting a batch the remainder is
 also sent as a single batch. For example, if your target size was 10, and the
 processor received a batch of 95 message parts, the result would be 9 batches of
-10 messages followed by a batch of 5 messages.`,
+10 messages followed by a batch of 5 messages.
+
+### Chunking
+
+Individual message parts can also be broken down into chunks before they are
+batched. When the field ` + "`chunk_delimiter`" + ` is set each message part is
+split on every occurrence of the delimiter, which is removed from the output
+along with any empty segments.
+When ` + "`chunk_size`" + ` is also non-zero neighbouring segments are joined
+back together (with the delimiter) for as long as the resulting chunk remains
+within the size limit in bytes.
+
+Setting only ` + "`chunk_size`" + ` splits message parts into chunks of exactly
+that many bytes (with the exception of the final chunk), and any segment larger
+than the chunk size is split this way when a delimiter is set. Chunks inherit
+the metadata of the message part they were taken from.
+
+For example, the following config breaks large newline delimited documents into
+chunks of up to 1MB each, with batches of ten chunks:
+
+` + "``` yaml" + `
+split:
+  size: 10
+  chunk_delimiter: "\n"
+  chunk_size: 1048576
+` + "```" + ``,
 	}
 }
 
@@ -53,15 +79,19 @@ processor received a batch of 95 message parts, the result would be 9 batches of
 // SplitConfig is a configuration struct containing fields for the Split
 // processor, which breaks message batches down into batches of a smaller size.
 type SplitConfig struct {
-	Size     int `json:"size" yaml:"size"`
-	ByteSize int `json:"byte_size" yaml:"byte_size"`
+	Size           int    `json:"size" yaml:"size"`
+	ByteSize       int    `json:"byte_size" yaml:"byte_size"`
+	ChunkDelimiter string `json:"chunk_delimiter" yaml:"chunk_delimiter"`
+	ChunkSize      int    `json:"chunk_size" yaml:"chunk_size"`
 }
 
 // NewSplitConfig returns a SplitConfig with default values.
 func NewSplitConfig() SplitConfig {
 	return SplitConfig{
-		Size:     1,
-		ByteSize: 0,
+		Size:           1,
+		ByteSize:       0,
+		ChunkDelimiter: "",
+		ChunkSize:      0,
 	}
 }
 
@@ -72,10 +102,13 @@ type Split struct {
 	log   log.Modular
 	stats metrics.Type
 
-	size     int
-	byteSize int
+	size      int
+	byteSize  int
+	delim     []byte
+	chunkSize int
 
 	mCount     metrics.StatCounter
+	mChunked   metrics.StatCounter
 	mDropped   metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
@@ -89,10 +122,13 @@ func NewSplit(
 		log:   log,
 		stats: stats,
 
-		size:     conf.Split.Size,
-		byteSize: conf.Split.ByteSize,
+		size:      conf.Split.Size,
+		byteSize:  conf.Split.ByteSize,
+		delim:     []byte(conf.Split.ChunkDelimiter),
+		chunkSize: conf.Split.ChunkSize,
 
 		mCount:     stats.GetCounter("count"),
+		mChunked:   stats.GetCounter("chunked"),
 		mDropped:   stats.GetCounter("dropped"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
@@ -101,6 +137,52 @@ func NewSplit(
 
 //------------------------------------------------------------------------------
 
+// chunk breaks the contents of a message part down into chunks according to
+// the configured delimiter and chunk size.
+func (s *Split) chunk(b []byte) [][]byte {
+	var segments [][]byte
+	if len(s.delim) > 0 {
+		for _, seg := range bytes.Split(b, s.delim) {
+			if len(seg) > 0 {
+				segments = append(segments, seg)
+			}
+		}
+	} else if len(b) > 0 {
+		segments = [][]byte{b}
+	}
+	if s.chunkSize <= 0 {
+		return segments
+	}
+
+	var chunks [][]byte
+	var current []byte
+	for _, seg := range segments {
+		for len(seg) > s.chunkSize {
+			if current != nil {
+				chunks = append(chunks, current)
+				current = nil
+			}
+			chunks = append(chunks, seg[:s.chunkSize])
+			seg = seg[s.chunkSize:]
+		}
+		if current != nil {
+			if len(current)+len(s.delim)+len(seg) <= s.chunkSize {
+				joined := make([]byte, 0, len(current)+len(s.delim)+len(seg))
+				joined = append(joined, current...)
+				joined = append(joined, s.delim...)
+				current = append(joined, seg...)
+				continue
+			}
+			chunks = append(chunks, current)
+		}
+		current = seg
+	}
+	if current != nil {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (s *Split) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -111,6 +193,25 @@ func (s *Split) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 		return nil, response.NewAck()
 	}
 
+	if len(s.delim) > 0 || s.chunkSize > 0 {
+		chunked := message.New(nil)
+		msg.Iter(func(i int, p types.Part) error {
+			chunks := s.chunk(p.Get())
+			if len(chunks) == 0 {
+				chunked.Append(p)
+				return nil
+			}
+			if len(chunks) > 1 {
+				s.mChunked.Incr(1)
+			}
+			for _, c := range chunks {
+				chunked.Append(p.Copy().Set(c))
+			}
+			return nil
+		})
+		msg = chunked
+	}
+
 	msgs := []types.Message{}
 
 	nextMsg := message.New(nil)
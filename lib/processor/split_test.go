@@ -22,6 +22,7 @@ package processor
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -191,3 +192,125 @@ func TestSplitByBytesTooLarge(t *testing.T) {
 		t.Errorf("Wrong contents: %v != %v", act, exp)
 	}
 }
+
+func TestSplitChunkDelimiter(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSplit
+	conf.Split.Size = 0
+	conf.Split.ChunkDelimiter = "\n"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inMsg := message.New([][]byte{
+		[]byte("foo\nbar\n\nbaz\n"),
+		[]byte("qux"),
+	})
+	inMsg.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, _ := proc.ProcessMessage(inMsg)
+	if exp, act := 1, len(msgs); exp != act {
+		t.Fatalf("Wrong batch count: %v != %v", act, exp)
+	}
+	if exp, act := [][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+		[]byte("qux"),
+	}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong contents: %s != %s", act, exp)
+	}
+	for i := 0; i < 3; i++ {
+		if exp, act := "bar", msgs[0].Get(i).Metadata().Get("foo"); exp != act {
+			t.Errorf("Wrong metadata of chunk %v: %v != %v", i, act, exp)
+		}
+	}
+	if exp, act := "foo\nbar\n\nbaz\n", string(inMsg.Get(0).Get()); exp != act {
+		t.Errorf("Original message was modified: %v != %v", act, exp)
+	}
+}
+
+func TestSplitChunkSize(t *testing.T) {
+	type test struct {
+		delim  string
+		size   int
+		input  string
+		output []string
+	}
+	tests := []test{
+		{
+			size:   3,
+			input:  "foobarbazq",
+			output: []string{"foo", "bar", "baz", "q"},
+		},
+		{
+			delim:  ",",
+			size:   7,
+			input:  "foo,bar,baz,qux",
+			output: []string{"foo,bar", "baz,qux"},
+		},
+		{
+			delim:  ",",
+			size:   8,
+			input:  "a,b,c,toolongvalue,d,e",
+			output: []string{"a,b,c", "toolongv", "alue,d,e"},
+		},
+		{
+			delim:  ",",
+			size:   100,
+			input:  "foo,bar",
+			output: []string{"foo,bar"},
+		},
+	}
+
+	for i, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeSplit
+		conf.Split.Size = 0
+		conf.Split.ChunkDelimiter = test.delim
+		conf.Split.ChunkSize = test.size
+
+		proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if exp, act := 1, len(msgs); exp != act {
+			t.Fatalf("Wrong batch count for test %v: %v != %v", i, act, exp)
+		}
+		var act []string
+		for _, b := range message.GetAllBytes(msgs[0]) {
+			act = append(act, string(b))
+		}
+		if !reflect.DeepEqual(test.output, act) {
+			t.Errorf("Wrong chunks for test %v: %q != %q", i, act, test.output)
+		}
+	}
+}
+
+func TestSplitChunksByBytes(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSplit
+	conf.Split.Size = 0
+	conf.Split.ByteSize = 6
+	conf.Split.ChunkSize = 3
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foobarbaz")}))
+	if exp, act := 2, len(msgs); exp != act {
+		t.Fatalf("Wrong batch count: %v != %v", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong contents: %s != %s", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("baz")}, message.GetAllBytes(msgs[1]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong contents: %s != %s", act, exp)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bsql "github.com/Jeffail/benthos/lib/util/sql"
 	"github.com/Jeffail/benthos/lib/util/text"
 	olog "github.com/opentracing/opentracing-go/log"
 
@@ -91,7 +92,9 @@ The following is a list of supported drivers and their respective DSN formats:
 - ` + "`postgres`: `postgresql://[user[:password]@][netloc][:port][/dbname][?param1=value1&...]`" + `
 
 Please note that the ` + "`postgres`" + ` driver enforces SSL by default, you
-can override this with the parameter ` + "`sslmode=disable`" + ` if required.`,
+can override this with the parameter ` + "`sslmode=disable`" + ` if required.
+
+` + bsql.InitDocumentation,
 	}
 }
 
@@ -99,11 +102,12 @@ can override this with the parameter ` + "`sslmode=disable`" + ` if required.`,
 
 // SQLConfig contains configuration fields for the SQL processor.
 type SQLConfig struct {
-	Driver      string   `json:"driver" yaml:"driver"`
-	DSN         string   `json:"dsn" yaml:"dsn"`
-	Query       string   `json:"query" yaml:"query"`
-	Args        []string `json:"args" yaml:"args"`
-	ResultCodec string   `json:"result_codec" yaml:"result_codec"`
+	Driver      string          `json:"driver" yaml:"driver"`
+	DSN         string          `json:"dsn" yaml:"dsn"`
+	Query       string          `json:"query" yaml:"query"`
+	Args        []string        `json:"args" yaml:"args"`
+	ResultCodec string          `json:"result_codec" yaml:"result_codec"`
+	Init        bsql.InitConfig `json:"init" yaml:"init"`
 }
 
 // NewSQLConfig returns a SQLConfig with default values.
@@ -114,6 +118,7 @@ func NewSQLConfig() SQLConfig {
 		Query:       "",
 		Args:        []string{},
 		ResultCodec: "none",
+		Init:        bsql.NewInitConfig(),
 	}
 }
 
@@ -174,6 +179,10 @@ func NewSQL(
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
+	if err = bsql.RunInit(conf.SQL.Init, db, log); err != nil {
+		db.Close()
+		return nil, err
+	}
 	if s.query, err = db.Prepare(conf.SQL.Query); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to prepare query: %v", err)
@@ -301,7 +310,7 @@ func (s *SQL) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 	}
 	if err != nil {
 		result.Iter(func(i int, p types.Part) error {
-			FlagFail(p)
+			FlagErr(p, err)
 			spans[i].LogFields(
 				olog.String("event", "error"),
 				olog.String("type", err.Error()),
@@ -85,6 +85,50 @@ func TestSQLPostgresIntegration(t *testing.T) {
 	t.Run("testSQLPostgres", func(t *testing.T) {
 		testSQLPostgres(t, dsn)
 	})
+	t.Run("testSQLPostgresInit", func(t *testing.T) {
+		testSQLPostgresInit(t, dsn)
+	})
+}
+
+func testSQLPostgresInit(t *testing.T, dsn string) {
+	conf := NewConfig()
+	conf.Type = TypeSQL
+	conf.SQL.Driver = "postgres"
+	conf.SQL.DSN = dsn
+	conf.SQL.Init.Statements = []string{
+		`CREATE TABLE IF NOT EXISTS inittable (foo varchar(50) not null, primary key (foo));`,
+		`CREATE INDEX IF NOT EXISTS inittable_foo_idx ON inittable (foo);`,
+	}
+	conf.SQL.Query = "INSERT INTO inittable (foo) VALUES ($1);"
+	conf.SQL.Args = []string{
+		"${!json_field:foo}",
+	}
+
+	s, err := NewSQL(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := [][]byte{
+		[]byte(`{"foo":"foo1"}`),
+	}
+
+	resMsgs, response := s.ProcessMessage(message.New(parts))
+	if response != nil {
+		t.Fatal("Expected nil response")
+	}
+	if len(resMsgs) != 1 {
+		t.Fatalf("Wrong resulting msgs: %v != %v", len(resMsgs), 1)
+	}
+	if HasFailed(resMsgs[0].Get(0)) {
+		t.Error("Expected message not to be flagged as failed")
+	}
+
+	// Init statements are idempotent and can therefore run again.
+	if s, err = NewSQL(conf, nil, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	s.CloseAsync()
 }
 
 func testSQLPostgres(t *testing.T, dsn string) {
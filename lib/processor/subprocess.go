@@ -24,8 +24,11 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os/exec"
 	"sync"
 	"sync/atomic"
@@ -36,6 +39,8 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/cenkalti/backoff"
 	olog "github.com/opentracing/opentracing-go/log"
 )
 
@@ -46,27 +51,57 @@ func init() {
 		constructor: NewSubprocess,
 		description: `
 Subprocess is a processor that runs a process in the background and, for each
-message, will pipe its contents to the stdin stream of the process followed by a
-newline.
+message, will pipe its contents to the stdin stream of the process encoded
+according to the selected ` + "`codec`" + `.
 
-The subprocess must then either return a line over stdout or stderr. If a
-response is returned over stdout then its contents will replace the message. If
-a response is instead returned from stderr will be logged and the message will
-continue unchanged and will be marked as failed.
+The subprocess must then either return a response over stdout or a line over
+stderr. If a response is returned over stdout then its contents will replace the
+message. If a response is instead returned from stderr will be logged and the
+message will continue unchanged and will be marked as failed.
+
+#### Codecs
+
+The default codec ` + "`lines`" + ` writes messages to stdin followed by a
+newline, and reads each line of stdout as a response.
+
+The codec ` + "`length_prefixed_uint32_be`" + ` writes each message prefixed
+with its length in bytes as a four byte big endian unsigned integer, and expects
+responses over stdout to be encoded the same way. This codec allows messages to
+contain arbitrary binary data, including line breaks.
+
+Responses larger than ` + "`max_response_size`" + ` bytes are discarded and
+the corresponding messages are marked as failed.
 
 #### Subprocess requirements
 
 It is required that subprocesses flush their stdout and stderr pipes for each
-line.
+response.
+
+#### Timeouts
+
+When ` + "`timeout`" + ` is set to a non-zero duration a subprocess that fails
+to respond to a message within that period is considered hung. The message is
+marked as failed and the subprocess is killed, and then restarted according to
+the restart behaviour below, so that a single stuck request cannot stall the
+pipeline indefinitely.
+
+#### Restarts
 
-Benthos will attempt to keep the process alive for as long as the pipeline is
-running. If the process exits early it will be restarted.
+If the process exits early and ` + "`restart_on_exit`" + ` is true it will be
+restarted, with a delay between attempts governed by the ` + "`backoff`" + `
+fields. The backoff is reset once a restarted process successfully responds to a
+message. If ` + "`max_retries`" + ` is non-zero then the processor will give up
+after that many consecutive failed attempts, at which point (or immediately when
+restarts are disabled) messages will be rejected with an error.
+
+Messages processed whilst the subprocess is being restarted are marked as
+failed.
 
 #### Messages containing line breaks
 
-If a message contains line breaks each line of the message is piped to the
-subprocess and flushed, and a response is expected from the subprocess before
-another line is fed in.`,
+When using the ` + "`lines`" + ` codec, if a message contains line breaks each
+line of the message is piped to the subprocess and flushed, and a response is
+expected from the subprocess before another line is fed in.`,
 	}
 }
 
@@ -74,18 +109,140 @@ another line is fed in.`,
 
 // SubprocessConfig contains configuration fields for the Subprocess processor.
 type SubprocessConfig struct {
-	Parts []int    `json:"parts" yaml:"parts"`
-	Name  string   `json:"name" yaml:"name"`
-	Args  []string `json:"args" yaml:"args"`
+	Parts           []int    `json:"parts" yaml:"parts"`
+	Name            string   `json:"name" yaml:"name"`
+	Args            []string `json:"args" yaml:"args"`
+	Codec           string   `json:"codec" yaml:"codec"`
+	MaxResponseSize int      `json:"max_response_size" yaml:"max_response_size"`
+	Timeout         string   `json:"timeout" yaml:"timeout"`
+	RestartOnExit   bool     `json:"restart_on_exit" yaml:"restart_on_exit"`
+	retries.Config  `json:",inline" yaml:",inline"`
 }
 
 // NewSubprocessConfig returns a SubprocessConfig with default values.
 func NewSubprocessConfig() SubprocessConfig {
+	rConf := retries.NewConfig()
+	rConf.Backoff.InitialInterval = "100ms"
+	rConf.Backoff.MaxInterval = "10s"
+	rConf.Backoff.MaxElapsedTime = "0s"
+
 	return SubprocessConfig{
-		Parts: []int{},
-		Name:  "cat",
-		Args:  []string{},
+		Parts:           []int{},
+		Name:            "cat",
+		Args:            []string{},
+		Codec:           "lines",
+		MaxResponseSize: bufio.MaxScanTokenSize,
+		Timeout:         "",
+		RestartOnExit:   true,
+		Config:          rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	errSubprocTimeout        = errors.New("timed out waiting for subprocess response")
+	errSubprocNotRunning     = errors.New("subprocess is not running")
+	errSubprocResponseTooBig = errors.New("subprocess response exceeded max_response_size")
+)
+
+// subprocCodec determines how messages are written to the stdin of a
+// subprocess and how responses are read from its stdout.
+type subprocCodec struct {
+	encode     func(payload []byte) []byte
+	readStream func(r io.Reader, maxSize int, fn func([]byte, error)) error
+
+	// splitLines indicates that messages containing line breaks must be sent
+	// one line at a time.
+	splitLines bool
+}
+
+func encodeLine(payload []byte) []byte {
+	b := make([]byte, 0, len(payload)+1)
+	b = append(b, payload...)
+	return append(b, '\n')
+}
+
+// readLines calls fn with each line read from r, with any line that exceeds
+// maxSize being discarded and reported as an error.
+func readLines(r io.Reader, maxSize int, fn func([]byte, error)) error {
+	br := bufio.NewReader(r)
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(bytes.TrimRight(chunk, "\r\n")) > maxSize {
+				tooLong = true
+				line = nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if tooLong {
+			fn(nil, errSubprocResponseTooBig)
+		} else {
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			fn(bytes.TrimSuffix(line, []byte("\r")), nil)
+		}
+		line = nil
+		tooLong = false
+	}
+}
+
+func encodeLengthPrefixed(payload []byte) []byte {
+	b := make([]byte, 4, len(payload)+4)
+	binary.BigEndian.PutUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+// readLengthPrefixed calls fn with each length prefixed message read from r,
+// with any message that exceeds maxSize being discarded and reported as an
+// error.
+func readLengthPrefixed(r io.Reader, maxSize int, fn func([]byte, error)) error {
+	br := bufio.NewReader(r)
+	var lenBuf [4]byte
+	for {
+		if _, err := io.ReadFull(br, lenBuf[:]); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(lenBuf[:]))
+		if size > int64(maxSize) {
+			if _, err := io.CopyN(ioutil.Discard, br, size); err != nil {
+				return err
+			}
+			fn(nil, errSubprocResponseTooBig)
+			continue
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return err
+		}
+		fn(payload, nil)
+	}
+}
+
+func strToSubprocCodec(str string) (subprocCodec, error) {
+	switch str {
+	case "lines":
+		return subprocCodec{
+			encode:     encodeLine,
+			readStream: readLines,
+			splitLines: true,
+		}, nil
+	case "length_prefixed_uint32_be":
+		return subprocCodec{
+			encode:     encodeLengthPrefixed,
+			readStream: readLengthPrefixed,
+		}, nil
 	}
+	return subprocCodec{}, fmt.Errorf("codec not recognised: %v", str)
 }
 
 //------------------------------------------------------------------------------
@@ -121,8 +278,34 @@ func NewSubprocess(
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
-	var err error
-	if e.subproc, err = newSubprocWrapper(conf.Subprocess.Name, conf.Subprocess.Args, log); err != nil {
+
+	codec, err := strToSubprocCodec(conf.Subprocess.Codec)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Subprocess.MaxResponseSize <= 0 {
+		return nil, errors.New("max_response_size must be greater than zero")
+	}
+	var timeout time.Duration
+	if len(conf.Subprocess.Timeout) > 0 {
+		if timeout, err = time.ParseDuration(conf.Subprocess.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	boffCtor, err := conf.Subprocess.Config.GetCtor()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.subproc, err = newSubprocWrapper(subprocWrapperConfig{
+		name:          conf.Subprocess.Name,
+		args:          conf.Subprocess.Args,
+		codec:         codec,
+		maxSize:       conf.Subprocess.MaxResponseSize,
+		timeout:       timeout,
+		restartOnExit: conf.Subprocess.RestartOnExit,
+		backoffCtor:   boffCtor,
+	}, log, stats); err != nil {
 		return nil, err
 	}
 	return e, nil
@@ -130,65 +313,137 @@ func NewSubprocess(
 
 //------------------------------------------------------------------------------
 
+type subprocResponse struct {
+	payload []byte
+	err     error
+}
+
+type subprocWrapperConfig struct {
+	name          string
+	args          []string
+	codec         subprocCodec
+	maxSize       int
+	timeout       time.Duration
+	restartOnExit bool
+	backoffCtor   func() backoff.BackOff
+}
+
 type subprocWrapper struct {
-	name string
-	args []string
+	conf subprocWrapperConfig
+	log  log.Modular
+
+	// respondedSinceStart is set once the current process has successfully
+	// responded, and is used to reset the restart backoff.
+	respondedSinceStart int32
 
 	cmdMut      sync.Mutex
 	cmdExitChan chan struct{}
-	stdoutChan  chan []byte
+	stdoutChan  chan subprocResponse
 	stderrChan  chan []byte
+	exhausted   bool
 
 	cmd         *exec.Cmd
 	cmdStdin    io.WriteCloser
 	cmdCancelFn func()
 
+	mRestart    metrics.StatCounter
+	mRestartErr metrics.StatCounter
+	mTimeout    metrics.StatCounter
+
 	closeChan  chan struct{}
 	closedChan chan struct{}
 }
 
-func newSubprocWrapper(name string, args []string, log log.Modular) (*subprocWrapper, error) {
+func newSubprocWrapper(conf subprocWrapperConfig, log log.Modular, stats metrics.Type) (*subprocWrapper, error) {
 	s := &subprocWrapper{
-		name:       name,
-		args:       args,
-		closeChan:  make(chan struct{}),
-		closedChan: make(chan struct{}),
+		conf:        conf,
+		log:         log,
+		cmdCancelFn: func() {},
+		mRestart:    stats.GetCounter("restart"),
+		mRestartErr: stats.GetCounter("restart.error"),
+		mTimeout:    stats.GetCounter("timeout"),
+		closeChan:   make(chan struct{}),
+		closedChan:  make(chan struct{}),
 	}
 	if err := s.start(); err != nil {
 		return nil, err
 	}
-	go func() {
-		defer func() {
-			s.stop()
-			close(s.closedChan)
-		}()
-		for {
-			select {
-			case <-s.cmdExitChan:
-				log.Warnln("Subprocess exited")
-
-				// Flush channels
-				var msgBytes []byte
-				for stdoutMsg := range s.stdoutChan {
-					msgBytes = append(msgBytes, stdoutMsg...)
-				}
-				if len(msgBytes) > 0 {
-					log.Infoln(string(msgBytes))
-				}
-				msgBytes = nil
-				for stderrMsg := range s.stderrChan {
-					msgBytes = append(msgBytes, stderrMsg...)
-				}
-				if len(msgBytes) > 0 {
-					log.Errorln(string(msgBytes))
-				}
-				s.restart()
-			case <-s.closeChan:
+	go s.loop()
+	return s, nil
+}
+
+func (s *subprocWrapper) loop() {
+	defer func() {
+		s.stop()
+		close(s.closedChan)
+	}()
+
+	boff := s.conf.backoffCtor()
+	for {
+		select {
+		case <-s.cmdExitChan:
+			s.log.Warnln("Subprocess exited")
+			s.flush()
+
+			if atomic.SwapInt32(&s.respondedSinceStart, 0) == 1 {
+				boff.Reset()
+			}
+			if !s.conf.restartOnExit || !s.restartWithBackoff(boff) {
+				s.stop()
+				s.cmdMut.Lock()
+				s.exhausted = true
+				s.cmdMut.Unlock()
+				<-s.closeChan
 				return
 			}
+		case <-s.closeChan:
+			return
 		}
-	}()
-	return s, nil
+	}
+}
+
+// flush drains and logs any remaining output of an exited process.
+func (s *subprocWrapper) flush() {
+	var msgBytes []byte
+	for stdoutMsg := range s.stdoutChan {
+		msgBytes = append(msgBytes, stdoutMsg.payload...)
+	}
+	if len(msgBytes) > 0 {
+		s.log.Infoln(string(msgBytes))
+	}
+	msgBytes = nil
+	for stderrMsg := range s.stderrChan {
+		msgBytes = append(msgBytes, stderrMsg...)
+	}
+	if len(msgBytes) > 0 {
+		s.log.Errorln(string(msgBytes))
+	}
+}
+
+// restartWithBackoff attempts to restart the process until it succeeds, the
+// backoff is exhausted or the wrapper is closed. Returns false if the process
+// was not restarted.
+func (s *subprocWrapper) restartWithBackoff(boff backoff.BackOff) bool {
+	s.stop()
+	for {
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			s.log.Errorln("Subprocess restart attempts exhausted")
+			return false
+		}
+		select {
+		case <-time.After(wait):
+		case <-s.closeChan:
+			return false
+		}
+		if err := s.start(); err != nil {
+			s.mRestartErr.Incr(1)
+			s.log.Errorf("Failed to restart subprocess: %v\n", err)
+			continue
+		}
+		s.mRestart.Incr(1)
+		return true
+	}
 }
 
 func (s *subprocWrapper) start() error {
@@ -203,7 +458,7 @@ func (s *subprocWrapper) start() error {
 		}
 	}()
 
-	cmd := exec.CommandContext(cmdCtx, s.name, s.args...)
+	cmd := exec.CommandContext(cmdCtx, s.conf.name, s.conf.args...)
 	var cmdStdin io.WriteCloser
 	if cmdStdin, err = cmd.StdinPipe(); err != nil {
 		return err
@@ -224,7 +479,7 @@ func (s *subprocWrapper) start() error {
 	s.cmdCancelFn = cmdCancelFn
 
 	cmdExitChan := make(chan struct{})
-	stdoutChan := make(chan []byte)
+	stdoutChan := make(chan subprocResponse)
 	stderrChan := make(chan []byte)
 
 	go func() {
@@ -238,10 +493,9 @@ func (s *subprocWrapper) start() error {
 			s.cmdMut.Unlock()
 		}()
 
-		scanner := bufio.NewScanner(cmdStdout)
-		for scanner.Scan() {
-			stdoutChan <- scanner.Bytes()
-		}
+		s.conf.codec.readStream(cmdStdout, s.conf.maxSize, func(payload []byte, err error) {
+			stdoutChan <- subprocResponse{payload: payload, err: err}
+		})
 	}()
 	go func() {
 		defer func() {
@@ -254,10 +508,12 @@ func (s *subprocWrapper) start() error {
 			s.cmdMut.Unlock()
 		}()
 
-		scanner := bufio.NewScanner(cmdStderr)
-		for scanner.Scan() {
-			stderrChan <- scanner.Bytes()
-		}
+		readLines(cmdStderr, s.conf.maxSize, func(line []byte, err error) {
+			if err != nil {
+				line = []byte(err.Error())
+			}
+			stderrChan <- line
+		})
 	}()
 
 	s.cmdExitChan = cmdExitChan
@@ -266,11 +522,6 @@ func (s *subprocWrapper) start() error {
 	return nil
 }
 
-func (s *subprocWrapper) restart() error {
-	s.stop()
-	return s.start()
-}
-
 func (s *subprocWrapper) stop() error {
 	s.cmdMut.Lock()
 	var err error
@@ -285,27 +536,37 @@ func (s *subprocWrapper) stop() error {
 	return err
 }
 
-func (s *subprocWrapper) Send(line []byte) ([]byte, error) {
+func (s *subprocWrapper) Send(payload []byte) ([]byte, error) {
 	s.cmdMut.Lock()
 	stdin := s.cmdStdin
 	outChan := s.stdoutChan
 	errChan := s.stderrChan
+	exhausted := s.exhausted
+	cancelFn := s.cmdCancelFn
 	s.cmdMut.Unlock()
 
-	if stdin == nil {
+	if exhausted {
 		return nil, types.ErrTypeClosed
 	}
-	if _, err := stdin.Write(line); err != nil {
-		return nil, err
+	if stdin == nil {
+		return nil, errSubprocNotRunning
 	}
-	if _, err := stdin.Write([]byte("\n")); err != nil {
+	if _, err := stdin.Write(s.conf.codec.encode(payload)); err != nil {
 		return nil, err
 	}
 
-	var outBytes, errBytes []byte
+	var timeoutChan <-chan time.Time
+	if s.conf.timeout > 0 {
+		timer := time.NewTimer(s.conf.timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	var res subprocResponse
+	var errBytes []byte
 	var open bool
 	select {
-	case outBytes, open = <-outChan:
+	case res, open = <-outChan:
 	case errBytes, open = <-errChan:
 		tout := time.After(time.Second)
 		var errBuf bytes.Buffer
@@ -320,15 +581,31 @@ func (s *subprocWrapper) Send(line []byte) ([]byte, error) {
 			}
 		}
 		errBytes = errBuf.Bytes()
+	case <-timeoutChan:
+		// The process can no longer be trusted to respond in order, and
+		// therefore it is killed and left to be restarted. Following messages
+		// must not be written to it whilst it is shutting down.
+		s.mTimeout.Incr(1)
+		s.cmdMut.Lock()
+		if s.cmdStdin == stdin {
+			s.cmdStdin = nil
+		}
+		s.cmdMut.Unlock()
+		cancelFn()
+		return nil, errSubprocTimeout
 	}
 
 	if !open {
-		return nil, types.ErrTypeClosed
+		return nil, errSubprocNotRunning
 	}
 	if len(errBytes) > 0 {
 		return nil, errors.New(string(errBytes))
 	}
-	return outBytes, nil
+	if res.err != nil {
+		return nil, res.err
+	}
+	atomic.StoreInt32(&s.respondedSinceStart, 1)
+	return res.payload, nil
 }
 
 //------------------------------------------------------------------------------
@@ -345,8 +622,15 @@ func (e *Subprocess) ProcessMessage(msg types.Message) ([]types.Message, types.R
 		span := tracing.CreateChildSpan(TypeSubprocess, result.Get(i))
 		defer span.Finish()
 
+		var splitMsg [][]byte
+		if e.subproc.conf.codec.splitLines {
+			splitMsg = bytes.Split(result.Get(i).Get(), []byte("\n"))
+		} else {
+			splitMsg = [][]byte{result.Get(i).Get()}
+		}
+
 		results := [][]byte{}
-		splitMsg := bytes.Split(result.Get(i).Get(), []byte("\n"))
+		failed := false
 		for j, p := range splitMsg {
 			if len(p) == 0 && len(splitMsg) > 1 && j == (len(splitMsg)-1) {
 				results = append(results, []byte(""))
@@ -364,11 +648,15 @@ func (e *Subprocess) ProcessMessage(msg types.Message) ([]types.Message, types.R
 					olog.String("type", err.Error()),
 				)
 				results = append(results, p)
+				failed = true
 			} else {
 				results = append(results, res)
 			}
 		}
 		result.Get(i).Set(bytes.Join(results, []byte("\n")))
+		if failed {
+			FlagFail(result.Get(i))
+		}
 		return nil
 	}
 
@@ -21,6 +21,7 @@
 package processor
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 	"time"
@@ -155,3 +156,211 @@ func TestSubprocessLineBreaks(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSubprocessReadLines(t *testing.T) {
+	input := "foo\nbar\r\nthis line is too long\nbaz\n\nqux"
+
+	type result struct {
+		payload string
+		err     error
+	}
+	var results []result
+	err := readLines(bytes.NewReader([]byte(input)), 5, func(p []byte, err error) {
+		results = append(results, result{payload: string(p), err: err})
+	})
+	if err == nil {
+		t.Error("Expected EOF error")
+	}
+
+	exp := []result{
+		{payload: "foo"},
+		{payload: "bar"},
+		{err: errSubprocResponseTooBig},
+		{payload: "baz"},
+		{payload: ""},
+	}
+	if !reflect.DeepEqual(exp, results) {
+		t.Errorf("Wrong results: %v != %v", results, exp)
+	}
+}
+
+func TestSubprocessReadLengthPrefixed(t *testing.T) {
+	var input []byte
+	input = append(input, encodeLengthPrefixed([]byte("foo\nbar"))...)
+	input = append(input, encodeLengthPrefixed([]byte("this is too long"))...)
+	input = append(input, encodeLengthPrefixed(nil)...)
+	input = append(input, encodeLengthPrefixed([]byte("baz"))...)
+	input = append(input, 0, 0)
+
+	type result struct {
+		payload string
+		err     error
+	}
+	var results []result
+	err := readLengthPrefixed(bytes.NewReader(input), 10, func(p []byte, err error) {
+		results = append(results, result{payload: string(p), err: err})
+	})
+	if err == nil {
+		t.Error("Expected EOF error")
+	}
+
+	exp := []result{
+		{payload: "foo\nbar"},
+		{err: errSubprocResponseTooBig},
+		{payload: ""},
+		{payload: "baz"},
+	}
+	if !reflect.DeepEqual(exp, results) {
+		t.Errorf("Wrong results: %v != %v", results, exp)
+	}
+}
+
+func TestSubprocessBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Codec = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad codec")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Timeout = "nope"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad timeout")
+	}
+
+	conf = NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.MaxResponseSize = 0
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad max response size")
+	}
+}
+
+func TestSubprocessLengthPrefixedWithCat(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Name = "cat"
+	conf.Subprocess.Codec = "length_prefixed_uint32_be"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Skipf("Not sure if this is due to missing executable: %v", err)
+	}
+	defer func() {
+		proc.CloseAsync()
+		if err := proc.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	exp := [][]byte{
+		[]byte("hello\nworld"),
+		{0, 1, 2, '\n', 3},
+		[]byte(""),
+	}
+	msgs, res := proc.ProcessMessage(message.New(exp))
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", res)
+	}
+	if exp, act := len(exp), msgs[0].Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if act := msgs[0].Get(i).Get(); !bytes.Equal(exp[i], act) {
+			t.Errorf("Wrong result of part %v: %q != %q", i, act, exp[i])
+		}
+		if HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Part %v failed", i)
+		}
+	}
+}
+
+func TestSubprocessTimeoutRestart(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Name = "sh"
+	conf.Subprocess.Args = []string{"-c", `while read line; do
+  if [ "$line" = "hang" ]; then read never; fi
+  echo "$line"
+done`}
+	conf.Subprocess.Timeout = "100ms"
+	conf.Subprocess.Backoff.InitialInterval = "10ms"
+	conf.Subprocess.Backoff.MaxInterval = "10ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Skipf("Not sure if this is due to missing executable: %v", err)
+	}
+	defer func() {
+		proc.CloseAsync()
+		if err := proc.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if exp, act := "foo", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{[]byte("hang")}))
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected hung message to fail")
+	}
+	if exp, act := "hang", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		msgs, _ = proc.ProcessMessage(message.New([][]byte{[]byte("bar")}))
+		if !HasFailed(msgs[0].Get(0)) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Subprocess was not restarted")
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	if exp, act := "bar", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestSubprocessNoRestart(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Name = "sh"
+	conf.Subprocess.Args = []string{"-c", `read line; echo "$line"`}
+	conf.Subprocess.RestartOnExit = false
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Skipf("Not sure if this is due to missing executable: %v", err)
+	}
+	defer func() {
+		proc.CloseAsync()
+		if err := proc.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if exp, act := "foo", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		_, res := proc.ProcessMessage(message.New([][]byte{[]byte("bar")}))
+		if res != nil && res.Error() != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected messages to be rejected after exit")
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+}
@@ -29,7 +29,10 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/microcosm-cc/bluemonday"
@@ -76,6 +79,13 @@ Unescapes text that has been url escaped.
 
 Extract the matching section of the argument regular expression in a message.
 
+#### ` + "`find_all_regexp`" + `
+
+Extracts all matching sections of the argument regular expression in a message,
+where each match becomes a new message of the batch with the metadata of the
+original message. Messages where the expression does not match are removed from
+the batch.
+
 #### ` + "`prepend`" + `
 
 Prepends text to the beginning of the payload.
@@ -85,6 +95,30 @@ Prepends text to the beginning of the payload.
 Returns a doubled-quoted string, using escape sequences (\t, \n, \xFF, \u0100)
 for control characters and other non-printable characters.
 
+#### ` + "`regexp_expand`" + `
+
+Replaces the contents of a message with the value expanded as a template for
+each match of the argument regular expression. Inside the value $ signs are
+interpreted as submatch expansions, e.g. $1 represents the text of the first
+submatch and $name represents the text of a named submatch. Messages where the
+expression does not match are emptied.
+
+For example, with the following config:
+
+` + "``` yaml" + `
+text:
+  operator: regexp_expand
+  arg: '(?P<key>\w+)=(?P<value>\w+)'
+  value: "$key: $value\n"
+` + "```" + `
+
+A message ` + "`foo=bar baz=qux`" + ` would become:
+
+` + "```" + `
+foo: bar
+baz: qux
+` + "```" + `
+
 #### ` + "`replace`" + `
 
 Replaces all occurrences of the argument in a message with a value.
@@ -246,6 +280,20 @@ func newTextFindRegexpOperator(arg string) (textOperator, error) {
 	}, nil
 }
 
+func newTextRegexpExpandOperator(arg string) (textOperator, error) {
+	rp, err := regexp.Compile(arg)
+	if err != nil {
+		return nil, err
+	}
+	return func(body []byte, value []byte) ([]byte, error) {
+		var result []byte
+		for _, submatches := range rp.FindAllSubmatchIndex(body, -1) {
+			result = rp.Expand(result, value, body, submatches)
+		}
+		return result, nil
+	}, nil
+}
+
 func newTextStripHTMLOperator(arg string) textOperator {
 	p := bluemonday.NewPolicy()
 	return func(body []byte, value []byte) ([]byte, error) {
@@ -277,6 +325,8 @@ func getTextOperator(opStr string, arg string) (textOperator, error) {
 		return newTextPrependOperator(), nil
 	case "quote":
 		return newTextQuoteOperator(), nil
+	case "regexp_expand":
+		return newTextRegexpExpandOperator(arg)
 	case "replace":
 		return newTextReplaceOperator(arg), nil
 	case "replace_regexp":
@@ -307,6 +357,7 @@ type Text struct {
 	interpolate bool
 	valueBytes  []byte
 	operator    textOperator
+	findAll     *regexp.Regexp
 
 	conf  Config
 	log   log.Modular
@@ -339,6 +390,12 @@ func NewText(
 	t.interpolate = text.ContainsFunctionVariables(t.valueBytes)
 
 	var err error
+	if conf.Text.Operator == "find_all_regexp" {
+		if t.findAll, err = regexp.Compile(conf.Text.Arg); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
 	if t.operator, err = getTextOperator(conf.Text.Operator, conf.Text.Arg); err != nil {
 		return nil, err
 	}
@@ -351,6 +408,9 @@ func NewText(
 // resulting messages or a response to be sent back to the message source.
 func (t *Text) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	t.mCount.Incr(1)
+	if t.findAll != nil {
+		return t.processFindAll(msg)
+	}
 	newMsg := msg.Copy()
 
 	valueBytes := t.valueBytes
@@ -379,6 +439,48 @@ func (t *Text) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 	return msgs[:], nil
 }
 
+// processFindAll replaces each targeted message part with a part for each
+// match of the find_all_regexp expression.
+func (t *Text) processFindAll(msg types.Message) ([]types.Message, types.Response) {
+	newMsg := message.New(nil)
+	lParts := msg.Len()
+
+	noParts := len(t.parts) == 0
+	msg.Iter(func(i int, part types.Part) error {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, p := range t.parts {
+				if p == nI || p == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part.Copy())
+			return nil
+		}
+
+		span := tracing.CreateChildSpan(TypeText, part)
+		for _, match := range t.findAll.FindAll(part.Get(), -1) {
+			newPart := part.Copy()
+			newPart.Set(match)
+			newMsg.Append(newPart)
+		}
+		span.Finish()
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	t.mBatchSent.Incr(1)
+	t.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
 // CloseAsync shuts down the processor and stops processing requests.
 func (t *Text) CloseAsync() {
 }
@@ -22,6 +22,7 @@ package processor
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -955,3 +956,112 @@ func TestTextUnquote(t *testing.T) {
 		}
 	}
 }
+
+func TestTextRegexpExpand(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	type jTest struct {
+		name   string
+		arg    string
+		value  string
+		input  string
+		output string
+	}
+
+	tests := []jTest{
+		{
+			name:   "expand named",
+			arg:    `(?P<key>\w+)=(?P<value>\w+)`,
+			value:  "$key: $value\n",
+			input:  `foo=bar baz=qux`,
+			output: "foo: bar\nbaz: qux\n",
+		},
+		{
+			name:   "expand indexed",
+			arg:    `(\w+)@(\w+)\.com`,
+			value:  "$2/$1",
+			input:  `contact: foo@bar.com`,
+			output: `bar/foo`,
+		},
+		{
+			name:   "expand no match",
+			arg:    `(\w+)@(\w+)\.com`,
+			value:  "$2/$1",
+			input:  `nothing here`,
+			output: ``,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Text.Operator = "regexp_expand"
+		conf.Text.Arg = test.arg
+		conf.Text.Value = test.value
+
+		tp, err := NewText(conf, nil, tLog, tStats)
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		msgs, _ := tp.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+
+		if exp, act := test.output, string(message.GetAllBytes(msgs[0])[0]); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestTextFindAllRegexp(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	conf := NewConfig()
+	conf.Text.Operator = "find_all_regexp"
+	conf.Text.Arg = `\d+`
+	conf.Text.Parts = []int{0, 1}
+
+	tp, err := NewText(conf, nil, tLog, tStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inMsg := message.New([][]byte{
+		[]byte(`a 1 b 22 c 333`),
+		[]byte(`no numbers`),
+		[]byte(`not 4 target`),
+	})
+	inMsg.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := tp.ProcessMessage(inMsg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte(`1`),
+		[]byte(`22`),
+		[]byte(`333`),
+		[]byte(`not 4 target`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "bar", msgs[0].Get(2).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	msgs, res = tp.ProcessMessage(message.New([][]byte{[]byte(`none`)}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages, received: %v", len(msgs))
+	}
+	if res == nil {
+		t.Error("Expected response for empty result")
+	}
+}
@@ -23,11 +23,13 @@ package processor
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -42,7 +44,26 @@ period. This throttle is per processing pipeline, and therefore four threads
 each with a throttle would result in four times the rate specified.
 
 The period should be specified as a time duration string. For example, '1s'
-would be 1 second, '10ms' would be 10 milliseconds, etc.`,
+would be 1 second, '10ms' would be 10 milliseconds, etc.
+
+Alternatively, a ` + "`rate_limit`" + ` can be specified as the name of a
+[rate limit resource](../rate_limits/README.md), in which case the period is
+ignored and each message batch blocks until the rate limit grants access. Since
+rate limit resources are shared, this allows multiple processing threads and
+pipelines to share a single global rate budget:
+
+` + "``` yaml" + `
+pipeline:
+  processors:
+  - throttle:
+      rate_limit: foo
+resources:
+  rate_limits:
+    foo:
+      local:
+        count: 500
+        interval: 1s
+` + "```" + ``,
 	}
 }
 
@@ -50,20 +71,22 @@ would be 1 second, '10ms' would be 10 milliseconds, etc.`,
 
 // ThrottleConfig contains configuration fields for the Throttle processor.
 type ThrottleConfig struct {
-	Period string `json:"period" yaml:"period"`
+	Period    string `json:"period" yaml:"period"`
+	RateLimit string `json:"rate_limit" yaml:"rate_limit"`
 }
 
 // NewThrottleConfig returns a ThrottleConfig with default values.
 func NewThrottleConfig() ThrottleConfig {
 	return ThrottleConfig{
-		Period: "100us",
+		Period:    "100us",
+		RateLimit: "",
 	}
 }
 
 //------------------------------------------------------------------------------
 
 // Throttle is a processor that limits the stream of a pipeline to one message
-// batch per period specified.
+// batch per period specified, or to the rate granted by a rate limit resource.
 type Throttle struct {
 	conf  Config
 	log   log.Modular
@@ -71,10 +94,15 @@ type Throttle struct {
 
 	duration  time.Duration
 	lastBatch time.Time
+	rateLimit types.RateLimit
 
-	mut sync.Mutex
+	mut       sync.Mutex
+	closed    int32
+	closeChan chan struct{}
 
 	mCount     metrics.StatCounter
+	mLimited   metrics.StatCounter
+	mLimitErr  metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
 }
@@ -88,12 +116,22 @@ func NewThrottle(
 		log:   log,
 		stats: stats,
 
+		closeChan: make(chan struct{}),
+
 		mCount:     stats.GetCounter("count"),
+		mLimited:   stats.GetCounter("rate_limit.limited"),
+		mLimitErr:  stats.GetCounter("rate_limit.error"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
 
 	var err error
+	if len(conf.Throttle.RateLimit) > 0 {
+		if t.rateLimit, err = mgr.GetRateLimit(conf.Throttle.RateLimit); err != nil {
+			return nil, fmt.Errorf("unable to locate rate_limit resource '%v': %v", conf.Throttle.RateLimit, err)
+		}
+		return t, nil
+	}
 	if t.duration, err = time.ParseDuration(conf.Throttle.Period); err != nil {
 		return nil, fmt.Errorf("failed to parse period: %v", err)
 	}
@@ -107,15 +145,26 @@ func NewThrottle(
 // resulting messages or a response to be sent back to the message source.
 func (m *Throttle) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	m.mCount.Incr(1)
-	m.mut.Lock()
-	defer m.mut.Unlock()
 
 	spans := tracing.CreateChildSpans(TypeThrottle, msg)
 
 	var throttleFor time.Duration
-	if since := time.Since(m.lastBatch); m.duration > since {
-		throttleFor = m.duration - since
-		time.Sleep(throttleFor)
+	if m.rateLimit != nil {
+		var ok bool
+		if throttleFor, ok = m.waitForAccess(); !ok {
+			for _, s := range spans {
+				s.Finish()
+			}
+			return nil, response.NewError(types.ErrTypeClosed)
+		}
+	} else {
+		m.mut.Lock()
+		if since := time.Since(m.lastBatch); m.duration > since {
+			throttleFor = m.duration - since
+			time.Sleep(throttleFor)
+		}
+		m.lastBatch = time.Now()
+		m.mut.Unlock()
 	}
 
 	for _, s := range spans {
@@ -123,16 +172,42 @@ func (m *Throttle) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 		s.Finish()
 	}
 
-	m.lastBatch = time.Now()
-
 	m.mBatchSent.Incr(1)
 	m.mSent.Incr(int64(msg.Len()))
 	msgs := [1]types.Message{msg}
 	return msgs[:], nil
 }
 
+// waitForAccess blocks until the rate limit grants access, returning the total
+// time spent waiting, or false if the processor was closed whilst waiting.
+func (m *Throttle) waitForAccess() (time.Duration, bool) {
+	var waited time.Duration
+	for {
+		period, err := m.rateLimit.Access()
+		if err != nil {
+			m.log.Errorf("Rate limit error: %v\n", err)
+			m.mLimitErr.Incr(1)
+			period = time.Second
+		} else if period > 0 {
+			m.mLimited.Incr(1)
+		}
+		if period <= 0 {
+			return waited, true
+		}
+		select {
+		case <-time.After(period):
+			waited += period
+		case <-m.closeChan:
+			return waited, false
+		}
+	}
+}
+
 // CloseAsync shuts down the processor and stops processing requests.
 func (m *Throttle) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&m.closed, 0, 1) {
+		close(m.closeChan)
+	}
 }
 
 // WaitForClose blocks until the processor has closed down.
@@ -21,12 +21,14 @@
 package processor
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestThrottle(t *testing.T) {
@@ -84,3 +86,80 @@ func TestThrottleBadPeriod(t *testing.T) {
 		t.Error("Expected error from bad duration")
 	}
 }
+
+func TestThrottleRateLimit(t *testing.T) {
+	rl := &fakeRateLimit{}
+	mgr := &fakeRateLimitMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": rl,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeThrottle
+	conf.Throttle.Period = "1h"
+	conf.Throttle.RateLimit = "foo"
+
+	throt, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tBefore := time.Now()
+	for i := 0; i < 3; i++ {
+		msgIn := message.New(nil)
+		msgsOut, res := throt.ProcessMessage(msgIn)
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if exp, act := msgIn, msgsOut[0]; exp != act {
+			t.Errorf("Wrong message returned: %v != %v", act, exp)
+		}
+	}
+	if dur := time.Since(tBefore); dur > (time.Millisecond * 100) {
+		t.Errorf("Period should be ignored when a rate limit is set: %v", dur)
+	}
+	if exp, act := int64(3), atomic.LoadInt64(&rl.accesses); exp != act {
+		t.Errorf("Wrong count of rate limit accesses: %v != %v", act, exp)
+	}
+}
+
+func TestThrottleRateLimitClose(t *testing.T) {
+	mgr := &fakeRateLimitMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": &fakeRateLimit{period: time.Hour},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeThrottle
+	conf.Throttle.RateLimit = "foo"
+
+	throt, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		throt.CloseAsync()
+	}()
+
+	msgsOut, res := throt.ProcessMessage(message.New(nil))
+	if len(msgsOut) != 0 {
+		t.Errorf("Expected no messages, received: %v", len(msgsOut))
+	}
+	if res == nil || res.Error() != types.ErrTypeClosed {
+		t.Errorf("Expected closed error response, received: %v", res)
+	}
+}
+
+func TestThrottleRateLimitNotFound(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeThrottle
+	conf.Throttle.RateLimit = "foo"
+
+	if _, err := New(conf, &fakeMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing rate limit")
+	}
+}
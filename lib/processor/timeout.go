@@ -0,0 +1,245 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrProcessingTimeout is the error used to flag message parts that were not
+// processed by the children of a timeout processor within the time limit.
+var ErrProcessingTimeout = errors.New("processing timed out")
+
+func init() {
+	Constructors[TypeTimeout] = TypeSpec{
+		constructor: NewTimeout,
+		description: `
+Executes a list of child processors on a message batch, and if they fail to
+complete within the period specified by ` + "`duration`" + ` then the original
+batch continues through the pipeline with each message flagged as having failed
+with the error ` + "`processing timed out`" + `.
+
+This can be used in order to protect a pipeline from processors that might
+hang indefinitely or take an unreasonable amount of time for certain messages,
+such as pathological regular expressions, slow scripts or unresponsive
+subprocesses:
+
+` + "``` yaml" + `
+- timeout:
+    duration: 500ms
+    processors:
+    - subprocess:
+        name: ./analyse.py
+- catch:
+  - log:
+      message: "Processing failed: ${!metadata:benthos_processing_failed}"
+` + "```" + `
+
+Child processors cannot be interrupted, and therefore continue executing in the
+background once timed out. Subsequent batches wait for the previous execution
+to finish within their own time limit, and are flagged as timed out if it does
+not.
+
+Timed out messages can be handled with the ` + "[`catch`](#catch)" + `
+processor, and more information about error handing can be found
+[here](../error_handling.md).`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			procConfs := make([]interface{}, len(conf.Timeout.Processors))
+			for i, pConf := range conf.Timeout.Processors {
+				if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"duration":   conf.Timeout.Duration,
+				"processors": procConfs,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TimeoutConfig is a config struct containing fields for the Timeout
+// processor.
+type TimeoutConfig struct {
+	Duration   string   `json:"duration" yaml:"duration"`
+	Processors []Config `json:"processors" yaml:"processors"`
+}
+
+// NewTimeoutConfig returns a default TimeoutConfig.
+func NewTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{
+		Duration:   "5s",
+		Processors: []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type timeoutResult struct {
+	msgs []types.Message
+	res  types.Response
+}
+
+// Timeout is a processor that bounds the execution time of a list of child
+// processors, flagging message batches that exceed it as failed.
+type Timeout struct {
+	duration time.Duration
+	children []types.Processor
+
+	// busy is held for as long as an execution of the children is in flight,
+	// including executions that have already timed out.
+	busy chan struct{}
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mTimeout   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewTimeout returns a Timeout processor.
+func NewTimeout(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	duration, err := time.ParseDuration(conf.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse duration: %v", err)
+	}
+	if duration <= 0 {
+		return nil, errors.New("duration must be greater than zero")
+	}
+
+	var children []types.Processor
+	for i, pconf := range conf.Timeout.Processors {
+		prefix := fmt.Sprintf("%v", i)
+		var proc Type
+		if proc, err = New(pconf, mgr, log.NewModule("."+prefix), metrics.Namespaced(stats, prefix)); err != nil {
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+
+	return &Timeout{
+		duration: duration,
+		children: children,
+		busy:     make(chan struct{}, 1),
+
+		log: log,
+
+		mCount:     stats.GetCounter("count"),
+		mTimeout:   stats.GetCounter("timeout"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (t *Timeout) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	t.mCount.Incr(1)
+
+	timer := time.NewTimer(t.duration)
+	defer timer.Stop()
+
+	select {
+	case t.busy <- struct{}{}:
+	case <-timer.C:
+		return t.timedOut(msg)
+	}
+
+	// The children are given a deep copy of the batch so that the original can
+	// be safely returned should they exceed the timeout.
+	resultChan := make(chan timeoutResult, 1)
+	go func(execMsg types.Message) {
+		msgs, res := ExecuteAll(t.children, execMsg)
+		resultChan <- timeoutResult{msgs: msgs, res: res}
+		<-t.busy
+	}(msg.DeepCopy())
+
+	var result timeoutResult
+	select {
+	case result = <-resultChan:
+	case <-timer.C:
+		return t.timedOut(msg)
+	}
+	if len(result.msgs) == 0 {
+		return nil, result.res
+	}
+
+	t.mBatchSent.Incr(int64(len(result.msgs)))
+	for _, m := range result.msgs {
+		t.mSent.Incr(int64(m.Len()))
+	}
+	return result.msgs, nil
+}
+
+func (t *Timeout) timedOut(msg types.Message) ([]types.Message, types.Response) {
+	t.mTimeout.Incr(1)
+	t.log.Debugf("Child processors exceeded timeout of %v\n", t.duration)
+	newMsg := msg.Copy()
+	newMsg.Iter(func(i int, p types.Part) error {
+		FlagErr(p, ErrProcessingTimeout)
+		return nil
+	})
+	t.mBatchSent.Incr(1)
+	t.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (t *Timeout) CloseAsync() {
+	for _, c := range t.children {
+		c.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (t *Timeout) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	select {
+	case t.busy <- struct{}{}:
+		<-t.busy
+	case <-time.After(time.Until(stopBy)):
+		return types.ErrTimeout
+	}
+	for _, c := range t.children {
+		if err := c.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
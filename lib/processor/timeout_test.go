@@ -0,0 +1,145 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestTimeoutWithinLimit(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeTimeout
+	conf.Timeout.Duration = "1s"
+
+	procConf := NewConfig()
+	procConf.Type = TypeText
+	procConf.Text.Operator = "to_upper"
+	conf.Timeout.Processors = append(conf.Timeout.Processors, procConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo"), []byte("bar"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of result msgs: %v", len(msgs))
+	}
+
+	exp := [][]byte{[]byte("FOO"), []byte("BAR")}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	msgs[0].Iter(func(i int, p types.Part) error {
+		if HasFailed(p) {
+			t.Errorf("Unexpected failure flag on part %v", i)
+		}
+		return nil
+	})
+
+	proc.CloseAsync()
+	if err = proc.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTimeoutExceeded(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeTimeout
+	conf.Timeout.Duration = "10ms"
+
+	procConf := NewConfig()
+	procConf.Type = TypeSleep
+	procConf.Sleep.Duration = "200ms"
+	conf.Timeout.Processors = append(conf.Timeout.Processors, procConf)
+
+	procConf = NewConfig()
+	procConf.Type = TypeText
+	procConf.Text.Operator = "to_upper"
+	conf.Timeout.Processors = append(conf.Timeout.Processors, procConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j := 0; j < 2; j++ {
+		input := message.New([][]byte{
+			[]byte("foo"), []byte("bar"),
+		})
+		msgs, res := proc.ProcessMessage(input)
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Wrong count of result msgs: %v", len(msgs))
+		}
+
+		exp := [][]byte{[]byte("foo"), []byte("bar")}
+		if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result: %s != %s", act, exp)
+		}
+		msgs[0].Iter(func(i int, p types.Part) error {
+			if act, exp := p.Metadata().Get(FailFlagKey), ErrProcessingTimeout.Error(); act != exp {
+				t.Errorf("Wrong failure flag on part %v: %v != %v", i, act, exp)
+			}
+			return nil
+		})
+		input.Iter(func(i int, p types.Part) error {
+			if HasFailed(p) {
+				t.Errorf("Input part %v was flagged", i)
+			}
+			return nil
+		})
+	}
+
+	proc.CloseAsync()
+	if err = proc.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTimeoutBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeTimeout
+
+	conf.Timeout.Duration = "not a duration"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad duration")
+	}
+
+	conf.Timeout.Duration = "0s"
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero duration")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeTokenize] = TypeSpec{
+		constructor: NewTokenize,
+		description: `
+Splits the contents of messages into tokens separated by a delimiter, where each
+token becomes a new message that replaces the original within the batch.
+Metadata is copied from the original message into each token.
+
+The ` + "`delimiter`" + ` field can be any sequence of bytes. Alternatively,
+the field ` + "`regexp`" + ` can be set to a regular expression, in which case
+the ` + "`delimiter`" + ` is ignored and tokens are separated by each match of
+the expression.
+
+When ` + "`include_delimiter`" + ` is set to ` + "`true`" + ` each token
+retains the delimiter that ended it. Tokens that are empty are removed.
+
+The field ` + "`max_parts`" + `, if greater than zero, caps the number of tokens
+produced from a single message, where the last token contains the remaining
+contents of the message.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TokenizeConfig contains configuration fields for the Tokenize processor.
+type TokenizeConfig struct {
+	Parts            []int  `json:"parts" yaml:"parts"`
+	Delimiter        string `json:"delimiter" yaml:"delimiter"`
+	Regexp           string `json:"regexp" yaml:"regexp"`
+	IncludeDelimiter bool   `json:"include_delimiter" yaml:"include_delimiter"`
+	MaxParts         int    `json:"max_parts" yaml:"max_parts"`
+}
+
+// NewTokenizeConfig returns a TokenizeConfig with default values.
+func NewTokenizeConfig() TokenizeConfig {
+	return TokenizeConfig{
+		Parts:            []int{},
+		Delimiter:        "\n",
+		Regexp:           "",
+		IncludeDelimiter: false,
+		MaxParts:         0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Tokenize is a processor that splits messages into tokens.
+type Tokenize struct {
+	conf      TokenizeConfig
+	delimiter []byte
+	re        *regexp.Regexp
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewTokenize returns a Tokenize processor.
+func NewTokenize(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	t := &Tokenize{
+		conf:      conf.Tokenize,
+		delimiter: []byte(conf.Tokenize.Delimiter),
+		log:       log,
+		stats:     stats,
+
+		mCount:     stats.GetCounter("count"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if len(conf.Tokenize.Regexp) > 0 {
+		var err error
+		if t.re, err = regexp.Compile(conf.Tokenize.Regexp); err != nil {
+			return nil, fmt.Errorf("failed to compile regexp: %v", err)
+		}
+	} else if len(t.delimiter) == 0 {
+		return nil, errors.New("either a delimiter or regexp must be specified")
+	}
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+// delimiterIndexes returns the start and end indexes of up to n delimiters
+// within a payload, where n < 0 returns all of them.
+func (t *Tokenize) delimiterIndexes(payload []byte, n int) [][]int {
+	if t.re != nil {
+		return t.re.FindAllIndex(payload, n)
+	}
+	var indexes [][]int
+	for offset := 0; n < 0 || len(indexes) < n; {
+		i := bytes.Index(payload[offset:], t.delimiter)
+		if i < 0 {
+			break
+		}
+		start := offset + i
+		offset = start + len(t.delimiter)
+		indexes = append(indexes, []int{start, offset})
+	}
+	return indexes
+}
+
+func (t *Tokenize) tokenize(part types.Part) []types.Part {
+	payload := part.Get()
+
+	n := -1
+	if t.conf.MaxParts > 0 {
+		n = t.conf.MaxParts - 1
+	}
+
+	var tokens [][]byte
+	var last int
+	for _, index := range t.delimiterIndexes(payload, n) {
+		end := index[0]
+		if t.conf.IncludeDelimiter {
+			end = index[1]
+		}
+		tokens = append(tokens, payload[last:end])
+		last = index[1]
+	}
+	tokens = append(tokens, payload[last:])
+
+	parts := make([]types.Part, 0, len(tokens))
+	for _, token := range tokens {
+		if len(token) == 0 {
+			continue
+		}
+		newPart := part.Copy()
+		newPart.Set(token)
+		parts = append(parts, newPart)
+	}
+	return parts
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (t *Tokenize) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	t.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	lParts := msg.Len()
+
+	noParts := len(t.conf.Parts) == 0
+	msg.Iter(func(i int, part types.Part) error {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, p := range t.conf.Parts {
+				if p == nI || p == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part.Copy())
+			return nil
+		}
+
+		span := tracing.CreateChildSpan(TypeTokenize, part)
+		newMsg.Append(t.tokenize(part)...)
+		span.Finish()
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	t.mBatchSent.Incr(1)
+	t.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (t *Tokenize) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (t *Tokenize) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
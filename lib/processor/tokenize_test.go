@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestTokenize(t *testing.T) {
+	type testCase struct {
+		name   string
+		conf   TokenizeConfig
+		input  [][]byte
+		output [][]byte
+	}
+
+	tests := []testCase{
+		{
+			name: "lines",
+			conf: NewTokenizeConfig(),
+			input: [][]byte{
+				[]byte("foo\nbar\nbaz\n"),
+			},
+			output: [][]byte{
+				[]byte("foo"),
+				[]byte("bar"),
+				[]byte("baz"),
+			},
+		},
+		{
+			name: "multi byte delimiter",
+			conf: TokenizeConfig{
+				Delimiter: "\r\n\r\n",
+			},
+			input: [][]byte{
+				[]byte("foo\r\nbar\r\n\r\nbaz"),
+			},
+			output: [][]byte{
+				[]byte("foo\r\nbar"),
+				[]byte("baz"),
+			},
+		},
+		{
+			name: "include delimiter",
+			conf: TokenizeConfig{
+				Delimiter:        ";",
+				IncludeDelimiter: true,
+			},
+			input: [][]byte{
+				[]byte("foo;bar;baz"),
+			},
+			output: [][]byte{
+				[]byte("foo;"),
+				[]byte("bar;"),
+				[]byte("baz"),
+			},
+		},
+		{
+			name: "regexp",
+			conf: TokenizeConfig{
+				Regexp: `\s*[,;]\s*`,
+			},
+			input: [][]byte{
+				[]byte("foo , bar;baz  ;  qux"),
+			},
+			output: [][]byte{
+				[]byte("foo"),
+				[]byte("bar"),
+				[]byte("baz"),
+				[]byte("qux"),
+			},
+		},
+		{
+			name: "regexp include delimiter",
+			conf: TokenizeConfig{
+				Regexp:           `[.!?]`,
+				IncludeDelimiter: true,
+			},
+			input: [][]byte{
+				[]byte("Hi. Who? Me!"),
+			},
+			output: [][]byte{
+				[]byte("Hi."),
+				[]byte(" Who?"),
+				[]byte(" Me!"),
+			},
+		},
+		{
+			name: "max parts",
+			conf: TokenizeConfig{
+				Delimiter: ",",
+				MaxParts:  2,
+			},
+			input: [][]byte{
+				[]byte("foo,bar,baz"),
+			},
+			output: [][]byte{
+				[]byte("foo"),
+				[]byte("bar,baz"),
+			},
+		},
+		{
+			name: "regexp max parts",
+			conf: TokenizeConfig{
+				Regexp:   `,`,
+				MaxParts: 1,
+			},
+			input: [][]byte{
+				[]byte("foo,bar,baz"),
+			},
+			output: [][]byte{
+				[]byte("foo,bar,baz"),
+			},
+		},
+		{
+			name: "select parts",
+			conf: TokenizeConfig{
+				Parts:     []int{1},
+				Delimiter: ",",
+			},
+			input: [][]byte{
+				[]byte("foo,bar"),
+				[]byte("baz,qux"),
+			},
+			output: [][]byte{
+				[]byte("foo,bar"),
+				[]byte("baz"),
+				[]byte("qux"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.Tokenize = test.conf
+
+		proc, err := NewTokenize(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		input := message.New(test.input)
+		input.Get(0).Metadata().Set("foo", "bar")
+
+		msgs, res := proc.ProcessMessage(input)
+		if res != nil {
+			t.Fatalf("%v: %v", test.name, res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("%v: wrong count of messages: %v", test.name, len(msgs))
+		}
+		if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(test.output, act) {
+			t.Errorf("%v: wrong result: %q != %q", test.name, act, test.output)
+		}
+		if exp, act := "bar", msgs[0].Get(0).Metadata().Get("foo"); exp != act {
+			t.Errorf("%v: wrong metadata: %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestTokenizeEmpty(t *testing.T) {
+	conf := NewConfig()
+	conf.Tokenize.Delimiter = ","
+
+	proc, err := NewTokenize(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(",,,"),
+	}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages: %s", message.GetAllBytes(msgs[0]))
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestTokenizeBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Tokenize.Delimiter = ""
+	if _, err := NewTokenize(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty delimiter")
+	}
+
+	conf = NewConfig()
+	conf.Tokenize.Regexp = "(foo"
+	if _, err := NewTokenize(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad regexp")
+	}
+}
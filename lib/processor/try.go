@@ -51,7 +51,10 @@ For example, with the following config:
 ` + "```" + `
 
 If the processor ` + "`foo`" + ` fails for a particular message, that message
-will skip the processors ` + "`bar` and `baz`" + `.
+will skip the processors ` + "`bar` and `baz`" + `. Messages that have already
+been flagged as failed by a processor prior to the try skip all of the child
+processors, and messages that fail within the try remain flagged as failed once
+they leave it.
 
 This processor is useful for when child processors depend on the successful
 output of previous processors. This processor can be followed with a
@@ -144,8 +147,11 @@ func (p *Try) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 
 	resMsg := message.New(nil)
 	for _, m := range resultMsgs {
-		m.Iter(func(i int, p types.Part) error {
-			resMsg.Append(p)
+		m.Iter(func(i int, part types.Part) error {
+			if HasFailed(part) {
+				p.mErr.Incr(1)
+			}
+			resMsg.Append(part)
 			return nil
 		})
 	}
@@ -269,3 +269,44 @@ func TestTryFilterAll(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestTryAlreadyFailed(t *testing.T) {
+	encodeConf := NewConfig()
+	encodeConf.Type = "encode"
+
+	conf := NewConfig()
+	conf.Type = TypeTry
+	conf.Try = append(conf.Try, encodeConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte("foo bar baz"),
+		[]byte("1 2 3 4"),
+		[]byte("hello foo world"),
+	})
+	FlagFail(msg.Get(1))
+
+	exp := [][]byte{
+		[]byte("Zm9vIGJhciBiYXo="),
+		[]byte("1 2 3 4"),
+		[]byte("aGVsbG8gZm9vIHdvcmxk"),
+	}
+	msgs, res := proc.ProcessMessage(msg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of result msgs: %v", len(msgs))
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %s != %s", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected fail flag to remain")
+	}
+}
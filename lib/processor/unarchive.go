@@ -24,9 +24,11 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -34,6 +36,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message/tracing"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/klauspost/compress/zstd"
 	olog "github.com/opentracing/opentracing-go/log"
 )
 
@@ -45,7 +48,10 @@ func init() {
 		description: `
 Unarchives messages according to the selected archive format into multiple
 messages within a batch. Supported archive formats are:
-` + "`tar`, `zip`, `binary`, `lines`, `json_documents` and `json_array`." + `
+` + "`tar`, `tar.gz`, `tar.zst`, `zip`, `binary`, `lines`, `json_documents`, `json_array` and `json_map`." + `
+
+The formats ` + "`tar.gz`" + ` and ` + "`tar.zst`" + ` decompress the message
+with gzip and zstd respectively before extracting it as a tar archive.
 
 When a message is unarchived the new messages replaces the original message in
 the batch. Messages that are selected but fail to unarchive (invalid format)
@@ -58,9 +64,40 @@ message.
 The ` + "`json_array`" + ` format attempts to parse the message as a JSON array
 and for each element of the array expands its contents into a new message.
 
-For the unarchive formats that contain file information (tar, zip), a metadata
-field is added to each message called ` + "`archive_filename`" + ` with the
-extracted filename.`,
+The ` + "`json_map`" + ` format attempts to parse the message as a JSON object
+and for each value of the object expands its contents into a new message, in
+order of their keys. A metadata field is added to each message called
+` + "`archive_key`" + ` with the key of the value it was extracted from.
+
+For the unarchive formats that contain file information (tar, tar.gz, tar.zst,
+zip), a metadata field is added to each message called
+` + "`archive_filename`" + ` with the extracted filename, and directory entries
+are skipped. The filename can be used in path interpolations downstream, for
+example the following config writes each extracted file to a path that mirrors
+its location within the archive:
+
+` + "``` yaml" + `
+pipeline:
+  processors:
+  - unarchive:
+      format: tar.gz
+output:
+  file:
+    path: /tmp/extracted/${!metadata:archive_filename}
+` + "```" + `
+
+### Large Archives
+
+This processor does not stream archives. Inputs read each message in full, and
+therefore both the archive and all of its extracted entries must fit in memory
+at the same time.
+
+When ` + "`batch_size`" + ` is greater than zero the resulting messages are
+split into multiple batches of at most that size, which are each sent through
+the remaining pipeline and acknowledged independently. This allows an archive
+with a very large number of entries to be written downstream in manageable
+chunks, but a message is only acknowledged at its input once every batch it was
+split into has been delivered.`,
 	}
 }
 
@@ -68,15 +105,17 @@ extracted filename.`,
 
 // UnarchiveConfig contains configuration fields for the Unarchive processor.
 type UnarchiveConfig struct {
-	Format string `json:"format" yaml:"format"`
-	Parts  []int  `json:"parts" yaml:"parts"`
+	Format    string `json:"format" yaml:"format"`
+	Parts     []int  `json:"parts" yaml:"parts"`
+	BatchSize int    `json:"batch_size" yaml:"batch_size"`
 }
 
 // NewUnarchiveConfig returns a UnarchiveConfig with default values.
 func NewUnarchiveConfig() UnarchiveConfig {
 	return UnarchiveConfig{
-		Format: "binary",
-		Parts:  []int{},
+		Format:    "binary",
+		Parts:     []int{},
+		BatchSize: 0,
 	}
 }
 
@@ -84,9 +123,27 @@ func NewUnarchiveConfig() UnarchiveConfig {
 
 type unarchiveFunc func(part types.Part) ([]types.Part, error)
 
-func tarUnarchive(part types.Part) ([]types.Part, error) {
-	buf := bytes.NewBuffer(part.Get())
-	tr := tar.NewReader(buf)
+// maxEntryPrealloc caps the size of buffers allocated up front for archive
+// entries, as the size declared by an entry header cannot be trusted.
+const maxEntryPrealloc = 64 * 1024 * 1024
+
+// readEntry reads an archive entry into a buffer allocated from its declared
+// size, avoiding the repeated reallocation and copying of a growing buffer.
+func readEntry(r io.Reader, size int64) ([]byte, error) {
+	if size > maxEntryPrealloc {
+		size = maxEntryPrealloc
+	}
+	buf := bytes.Buffer{}
+	if size > 0 {
+		// Extra space avoids a final reallocation when reading the EOF.
+		buf.Grow(int(size) + bytes.MinRead)
+	}
+	_, err := buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
+
+func readTar(r io.Reader, part types.Part) ([]types.Part, error) {
+	tr := tar.NewReader(r)
 
 	var newParts []types.Part
 
@@ -100,14 +157,17 @@ func tarUnarchive(part types.Part) ([]types.Part, error) {
 		if err != nil {
 			return nil, err
 		}
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
 
-		newPartBuf := bytes.Buffer{}
-		if _, err = newPartBuf.ReadFrom(tr); err != nil {
+		var entry []byte
+		if entry, err = readEntry(tr, h.Size); err != nil {
 			return nil, err
 		}
 
 		newPart := part.Copy()
-		newPart.Set(newPartBuf.Bytes())
+		newPart.Set(entry)
 		newPart.Metadata().Set("archive_filename", h.Name)
 		newParts = append(newParts, newPart)
 	}
@@ -115,6 +175,28 @@ func tarUnarchive(part types.Part) ([]types.Part, error) {
 	return newParts, nil
 }
 
+func tarUnarchive(part types.Part) ([]types.Part, error) {
+	return readTar(bytes.NewReader(part.Get()), part)
+}
+
+func tarGzipUnarchive(part types.Part) ([]types.Part, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(part.Get()))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	return readTar(gr, part)
+}
+
+func tarZstdUnarchive(part types.Part) ([]types.Part, error) {
+	zr, err := zstd.NewReader(bytes.NewReader(part.Get()))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return readTar(zr, part)
+}
+
 func zipUnarchive(part types.Part) ([]types.Part, error) {
 	buf := bytes.NewReader(part.Get())
 	zr, err := zip.NewReader(buf, int64(buf.Len()))
@@ -126,18 +208,22 @@ func zipUnarchive(part types.Part) ([]types.Part, error) {
 
 	// Iterate through the files in the archive.
 	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
 		fr, err := f.Open()
 		if err != nil {
 			return nil, err
 		}
 
-		newPartBuf := bytes.Buffer{}
-		if _, err = newPartBuf.ReadFrom(fr); err != nil {
+		entry, err := readEntry(fr, int64(f.UncompressedSize64))
+		fr.Close()
+		if err != nil {
 			return nil, err
 		}
 
 		newPart := part.Copy()
-		newPart.Set(newPartBuf.Bytes())
+		newPart.Set(entry)
 		newPart.Metadata().Set("archive_filename", f.Name)
 		newParts = append(newParts, newPart)
 	}
@@ -213,10 +299,43 @@ func jsonArrayUnarchive(part types.Part) ([]types.Part, error) {
 	return parts, nil
 }
 
+func jsonMapUnarchive(part types.Part) ([]types.Part, error) {
+	jDoc, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message into JSON map: %v", err)
+	}
+
+	jMap, ok := jDoc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse message into JSON map: invalid type '%T'", jDoc)
+	}
+
+	keys := make([]string, 0, len(jMap))
+	for k := range jMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]types.Part, len(keys))
+	for i, key := range keys {
+		newPart := part.Copy()
+		if err = newPart.SetJSON(jMap[key]); err != nil {
+			return nil, fmt.Errorf("failed to marshal element into new message: %v", err)
+		}
+		newPart.Metadata().Set("archive_key", key)
+		parts[i] = newPart
+	}
+	return parts, nil
+}
+
 func strToUnarchiver(str string) (unarchiveFunc, error) {
 	switch str {
 	case "tar":
 		return tarUnarchive, nil
+	case "tar.gz":
+		return tarGzipUnarchive, nil
+	case "tar.zst":
+		return tarZstdUnarchive, nil
 	case "zip":
 		return zipUnarchive, nil
 	case "binary":
@@ -227,6 +346,8 @@ func strToUnarchiver(str string) (unarchiveFunc, error) {
 		return jsonDocumentsUnarchive, nil
 	case "json_array":
 		return jsonArrayUnarchive, nil
+	case "json_map":
+		return jsonMapUnarchive, nil
 	}
 	return nil, fmt.Errorf("archive format not recognised: %v", str)
 }
@@ -310,7 +431,7 @@ func (d *Unarchive) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			d.mErr.Incr(1)
 			d.log.Errorf("Failed to unarchive message part: %v\n", err)
 			newMsg.Append(part)
-			FlagFail(newMsg.Get(-1))
+			FlagErr(newMsg.Get(-1), err)
 			span.LogFields(
 				olog.String("event", "error"),
 				olog.String("type", err.Error()),
@@ -319,10 +440,25 @@ func (d *Unarchive) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 		return nil
 	})
 
-	d.mBatchSent.Incr(1)
 	d.mSent.Incr(int64(newMsg.Len()))
-	msgs := [1]types.Message{newMsg}
-	return msgs[:], nil
+	if d.conf.BatchSize <= 0 || newMsg.Len() <= d.conf.BatchSize {
+		d.mBatchSent.Incr(1)
+		msgs := [1]types.Message{newMsg}
+		return msgs[:], nil
+	}
+
+	var msgs []types.Message
+	var batch types.Message
+	newMsg.Iter(func(i int, p types.Part) error {
+		if i%d.conf.BatchSize == 0 {
+			batch = message.New(nil)
+			msgs = append(msgs, batch)
+		}
+		batch.Append(p)
+		return nil
+	})
+	d.mBatchSent.Incr(int64(len(msgs)))
+	return msgs, nil
 }
 
 // CloseAsync shuts down the processor and stops processing requests.
@@ -24,6 +24,7 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"reflect"
@@ -32,6 +33,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestUnarchiveBadAlgo(t *testing.T) {
@@ -114,6 +116,73 @@ func TestUnarchiveTar(t *testing.T) {
 	}
 }
 
+func TestUnarchiveTarGzip(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "tar.gz"
+
+	exp := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+	}
+	expNames := []string{"foo/first.txt", "foo/second.txt"}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "foo/",
+		Mode:     0755,
+		Typeflag: tar.TypeDir,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range exp {
+		if err := tw.WriteHeader(&tar.Header{
+			Name: expNames[i],
+			Mode: 0600,
+			Size: int64(len(exp[i])),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(exp[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	proc, err := NewUnarchive(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{buf.Bytes()}))
+	if len(msgs) != 1 {
+		t.Fatalf("Unarchive failed: %v", res)
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	for i := 0; i < msgs[0].Len(); i++ {
+		if name := msgs[0].Get(i).Metadata().Get("archive_filename"); name != expNames[i] {
+			t.Errorf("Unexpected name %d: %s != %s", i, name, expNames[i])
+		}
+	}
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{[]byte("not gzipped")}))
+	if len(msgs) != 1 || msgs[0].Len() != 1 {
+		t.Fatal("Expected original message to be kept")
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected message to be flagged as failed")
+	}
+}
+
 func TestUnarchiveZip(t *testing.T) {
 	conf := NewConfig()
 	conf.Unarchive.Format = "zip"
@@ -215,6 +284,40 @@ fourth
 	}
 }
 
+func TestUnarchiveBatchSize(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "lines"
+	conf.Unarchive.BatchSize = 2
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	exp := [][][]byte{
+		{[]byte("first"), []byte("second")},
+		{[]byte("third"), []byte("fourth")},
+		{[]byte("5")},
+	}
+
+	proc, err := NewUnarchive(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("first\nsecond\nthird\nfourth\n5"),
+	}))
+	if res != nil {
+		t.Errorf("Expected nil response: %v", res)
+	}
+	if len(msgs) != len(exp) {
+		t.Fatalf("Wrong count of batches: %v != %v", len(msgs), len(exp))
+	}
+	for i, m := range msgs {
+		if act := message.GetAllBytes(m); !reflect.DeepEqual(exp[i], act) {
+			t.Errorf("Unexpected output of batch %v: %s != %s", i, act, exp[i])
+		}
+	}
+}
+
 func TestUnarchiveJSONDocuments(t *testing.T) {
 	conf := NewConfig()
 	conf.Unarchive.Format = "json_documents"
@@ -276,6 +379,54 @@ func TestUnarchiveJSONArray(t *testing.T) {
 	}
 }
 
+func TestUnarchiveJSONMap(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "json_map"
+
+	exp := [][]byte{
+		[]byte(`"testing 123"`),
+		[]byte(`["nested","array"]`),
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`5`),
+	}
+	expKeys := []string{"a", "b", "c", "d"}
+
+	proc, err := NewUnarchive(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"c":{"foo":"bar"},"d":5,"a":"testing 123","b":["nested","array"]}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Unarchive failed")
+	} else if res != nil {
+		t.Errorf("Expected nil response: %v", res)
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Unexpected output: %s != %s", act, exp)
+	}
+	actKeys := []string{}
+	msgs[0].Iter(func(i int, p types.Part) error {
+		actKeys = append(actKeys, p.Metadata().Get("archive_key"))
+		return nil
+	})
+	if !reflect.DeepEqual(expKeys, actKeys) {
+		t.Errorf("Unexpected keys: %v != %v", actKeys, expKeys)
+	}
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{
+		[]byte(`["not","a","map"]`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Unarchive failed")
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected failed flag")
+	}
+}
+
 func TestUnarchiveBinary(t *testing.T) {
 	conf := NewConfig()
 	conf.Unarchive.Format = "binary"
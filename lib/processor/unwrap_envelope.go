@@ -0,0 +1,332 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/gabs"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeUnwrapEnvelope] = TypeSpec{
+		constructor: NewUnwrapEnvelope,
+		description: `
+Unwraps the contents of AWS notification envelopes, such as those received from
+an SQS queue subscribed to an SNS topic or to S3 bucket events. The envelopes to
+unwrap are listed in the field ` + "`envelopes`" + `, where the supported types
+are ` + "`sns`" + ` and ` + "`s3`" + `. Messages that are not a recognised
+envelope are left unchanged.
+
+### ` + "`sns`" + `
+
+An SNS notification is replaced with the contents of its ` + "`Message`" + `
+field, and the following metadata fields are added:
+
+` + "``` text" + `
+- sns_type
+- sns_message_id
+- sns_topic_arn
+- sns_subject
+- sns_timestamp
+- All message attributes
+` + "```" + `
+
+### ` + "`s3`" + `
+
+An S3 event notification is expanded into a message for each of its records,
+where each new message contains the JSON record and the following metadata
+fields:
+
+` + "``` text" + `
+- s3_bucket
+- s3_key
+- s3_event_name
+- s3_event_time
+- s3_region
+` + "```" + `
+
+The object key of an S3 event is URL encoded, the ` + "`s3_key`" + ` metadata
+field contains the decoded key.
+
+When both types are enabled an SNS notification is unwrapped first, and the
+contents are then unwrapped as an S3 event notification, which is the case when
+S3 events are published to SNS before reaching an SQS queue.
+
+Messages that cannot be parsed as JSON are left unchanged and flagged as having
+failed.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// UnwrapEnvelopeConfig contains configuration fields for the UnwrapEnvelope
+// processor.
+type UnwrapEnvelopeConfig struct {
+	Parts     []int    `json:"parts" yaml:"parts"`
+	Envelopes []string `json:"envelopes" yaml:"envelopes"`
+}
+
+// NewUnwrapEnvelopeConfig returns a UnwrapEnvelopeConfig with default values.
+func NewUnwrapEnvelopeConfig() UnwrapEnvelopeConfig {
+	return UnwrapEnvelopeConfig{
+		Parts:     []int{},
+		Envelopes: []string{"sns", "s3"},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// UnwrapEnvelope is a processor that unwraps AWS notification envelopes.
+type UnwrapEnvelope struct {
+	conf UnwrapEnvelopeConfig
+	sns  bool
+	s3   bool
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSNS       metrics.StatCounter
+	mS3        metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewUnwrapEnvelope returns a UnwrapEnvelope processor.
+func NewUnwrapEnvelope(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	u := &UnwrapEnvelope{
+		conf:  conf.UnwrapEnvelope,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSNS:       stats.GetCounter("unwrapped.sns"),
+		mS3:        stats.GetCounter("unwrapped.s3"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	for _, e := range conf.UnwrapEnvelope.Envelopes {
+		switch e {
+		case "sns":
+			u.sns = true
+		case "s3":
+			u.s3 = true
+		default:
+			return nil, fmt.Errorf("envelope type not recognised: %v", e)
+		}
+	}
+	if !u.sns && !u.s3 {
+		return nil, errors.New("at least one envelope type must be specified")
+	}
+	return u, nil
+}
+
+//------------------------------------------------------------------------------
+
+func gabsStr(g *gabs.Container, path ...string) string {
+	s, _ := g.S(path...).Data().(string)
+	return s
+}
+
+// unwrapSNS returns a copy of a message part containing the message of an SNS
+// notification, or nil if the JSON document is not an SNS notification.
+func unwrapSNS(part types.Part, jObj interface{}) types.Part {
+	gObj, err := gabs.Consume(jObj)
+	if err != nil || gabsStr(gObj, "Type") != "Notification" {
+		return nil
+	}
+	content, isStr := gObj.S("Message").Data().(string)
+	if !isStr {
+		return nil
+	}
+
+	newPart := part.Copy()
+	newPart.Set([]byte(content))
+
+	meta := newPart.Metadata()
+	if attrs, ok := gObj.S("MessageAttributes").Data().(map[string]interface{}); ok {
+		for k := range attrs {
+			meta.Set(k, gabsStr(gObj, "MessageAttributes", k, "Value"))
+		}
+	}
+	meta.Set("sns_type", gabsStr(gObj, "Type"))
+	meta.Set("sns_message_id", gabsStr(gObj, "MessageId"))
+	meta.Set("sns_topic_arn", gabsStr(gObj, "TopicArn"))
+	meta.Set("sns_subject", gabsStr(gObj, "Subject"))
+	meta.Set("sns_timestamp", gabsStr(gObj, "Timestamp"))
+	return newPart
+}
+
+// unwrapS3 returns a message part for each S3 record of an event notification,
+// or nil if the JSON document is not an S3 event notification.
+func unwrapS3(part types.Part, jObj interface{}) ([]types.Part, error) {
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return nil, nil
+	}
+	records, _ := gObj.S("Records").Data().([]interface{})
+
+	var parts []types.Part
+	for _, r := range records {
+		gRecord, err := gabs.Consume(r)
+		if err != nil || gabsStr(gRecord, "eventSource") != "aws:s3" {
+			continue
+		}
+		record, err := message.CopyJSON(r)
+		if err != nil {
+			return nil, err
+		}
+
+		newPart := part.Copy()
+		if err = newPart.SetJSON(record); err != nil {
+			return nil, err
+		}
+
+		key := gabsStr(gRecord, "s3", "object", "key")
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+
+		newPart.Metadata().
+			Set("s3_bucket", gabsStr(gRecord, "s3", "bucket", "name")).
+			Set("s3_key", key).
+			Set("s3_event_name", gabsStr(gRecord, "eventName")).
+			Set("s3_event_time", gabsStr(gRecord, "eventTime")).
+			Set("s3_region", gabsStr(gRecord, "awsRegion"))
+		parts = append(parts, newPart)
+	}
+	return parts, nil
+}
+
+func (u *UnwrapEnvelope) unwrap(part types.Part) ([]types.Part, error) {
+	jObj, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	if u.sns {
+		if newPart := unwrapSNS(part, jObj); newPart != nil {
+			u.mSNS.Incr(1)
+			part = newPart
+			if !u.s3 {
+				return []types.Part{part}, nil
+			}
+			if jObj, err = part.JSON(); err != nil {
+				// The SNS message is not JSON and therefore can't be an S3
+				// event notification.
+				return []types.Part{part}, nil
+			}
+		}
+	}
+
+	if u.s3 {
+		parts, err := unwrapS3(part, jObj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap S3 event: %v", err)
+		}
+		if len(parts) > 0 {
+			u.mS3.Incr(1)
+			return parts, nil
+		}
+	}
+	return []types.Part{part.Copy()}, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (u *UnwrapEnvelope) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	u.mCount.Incr(1)
+
+	newMsg := message.New(nil)
+	lParts := msg.Len()
+
+	noParts := len(u.conf.Parts) == 0
+	msg.Iter(func(i int, part types.Part) error {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, p := range u.conf.Parts {
+				if p == nI || p == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part.Copy())
+			return nil
+		}
+
+		span := tracing.CreateChildSpan(TypeUnwrapEnvelope, part)
+		defer span.Finish()
+
+		parts, err := u.unwrap(part)
+		if err != nil {
+			u.mErr.Incr(1)
+			u.log.Debugf("Failed to unwrap message part: %v\n", err)
+			newPart := part.Copy()
+			FlagErr(newPart, err)
+			span.LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+			newMsg.Append(newPart)
+			return nil
+		}
+		newMsg.Append(parts...)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	u.mBatchSent.Incr(1)
+	u.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (u *UnwrapEnvelope) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (u *UnwrapEnvelope) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
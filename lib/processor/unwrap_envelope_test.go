@@ -0,0 +1,227 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+var testSNSEnvelope = `{
+  "Type": "Notification",
+  "MessageId": "foo-id",
+  "TopicArn": "arn:aws:sns:us-east-1:123456789012:foo",
+  "Subject": "foo subject",
+  "Message": %v,
+  "Timestamp": "2019-07-01T12:00:00.000Z",
+  "MessageAttributes": {
+    "foo": {"Type": "String", "Value": "bar"}
+  }
+}`
+
+var testS3Event = `{
+  "Records": [
+    {
+      "eventSource": "aws:s3",
+      "awsRegion": "us-east-1",
+      "eventTime": "2019-07-01T12:00:00.000Z",
+      "eventName": "ObjectCreated:Put",
+      "s3": {
+        "bucket": {"name": "foo-bucket"},
+        "object": {"key": "foo/bar+baz%3D.txt", "size": 10}
+      }
+    },
+    {
+      "eventSource": "aws:sqs"
+    },
+    {
+      "eventSource": "aws:s3",
+      "awsRegion": "us-east-1",
+      "eventTime": "2019-07-01T12:00:01.000Z",
+      "eventName": "ObjectRemoved:Delete",
+      "s3": {
+        "bucket": {"name": "foo-bucket"},
+        "object": {"key": "qux.txt"}
+      }
+    }
+  ]
+}`
+
+func TestUnwrapEnvelopeBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.UnwrapEnvelope.Envelopes = []string{"nope"}
+	if _, err := NewUnwrapEnvelope(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad envelope type")
+	}
+
+	conf.UnwrapEnvelope.Envelopes = []string{}
+	if _, err := NewUnwrapEnvelope(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty envelope types")
+	}
+}
+
+func TestUnwrapEnvelopeSNS(t *testing.T) {
+	conf := NewConfig()
+	conf.UnwrapEnvelope.Envelopes = []string{"sns"}
+
+	proc, err := NewUnwrapEnvelope(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := fmt.Sprintf(testSNSEnvelope, strconv.Quote("hello world"))
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(input),
+		[]byte(`{"foo":"not an envelope"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+
+	exp := [][]byte{
+		[]byte("hello world"),
+		[]byte(`{"foo":"not an envelope"}`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	expMeta := map[string]string{
+		"foo":            "bar",
+		"sns_type":       "Notification",
+		"sns_message_id": "foo-id",
+		"sns_topic_arn":  "arn:aws:sns:us-east-1:123456789012:foo",
+		"sns_subject":    "foo subject",
+		"sns_timestamp":  "2019-07-01T12:00:00.000Z",
+	}
+	for k, v := range expMeta {
+		if act := msgs[0].Get(0).Metadata().Get(k); act != v {
+			t.Errorf("Wrong metadata value for %v: %v != %v", k, act, v)
+		}
+	}
+	if act := msgs[0].Get(1).Metadata().Get("sns_type"); act != "" {
+		t.Errorf("Unexpected metadata: %v", act)
+	}
+}
+
+func TestUnwrapEnvelopeS3(t *testing.T) {
+	conf := NewConfig()
+
+	proc, err := NewUnwrapEnvelope(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := map[string]string{
+		"direct":   testS3Event,
+		"with sns": fmt.Sprintf(testSNSEnvelope, strconv.Quote(testS3Event)),
+	}
+
+	for name, input := range inputs {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if res != nil {
+			t.Fatalf("%v: %v", name, res.Error())
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("%v: Wrong count of messages: %v", name, len(msgs))
+		}
+		if exp, act := 2, msgs[0].Len(); exp != act {
+			t.Fatalf("%v: Wrong count of parts: %v != %v", name, act, exp)
+		}
+
+		expMeta := []map[string]string{
+			{
+				"s3_bucket":     "foo-bucket",
+				"s3_key":        "foo/bar baz=.txt",
+				"s3_event_name": "ObjectCreated:Put",
+				"s3_event_time": "2019-07-01T12:00:00.000Z",
+				"s3_region":     "us-east-1",
+			},
+			{
+				"s3_bucket":     "foo-bucket",
+				"s3_key":        "qux.txt",
+				"s3_event_name": "ObjectRemoved:Delete",
+				"s3_event_time": "2019-07-01T12:00:01.000Z",
+				"s3_region":     "us-east-1",
+			},
+		}
+		for i, meta := range expMeta {
+			part := msgs[0].Get(i)
+			for k, v := range meta {
+				if act := part.Metadata().Get(k); act != v {
+					t.Errorf("%v: Wrong metadata value for %v at part %v: %v != %v", name, k, i, act, v)
+				}
+			}
+			jObj, err := part.JSON()
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			if act, _ := jObj.(map[string]interface{})["eventName"]; act != meta["s3_event_name"] {
+				t.Errorf("%v: Wrong record at part %v: %v", name, i, act)
+			}
+		}
+
+		if name == "with sns" {
+			if exp, act := "foo-id", msgs[0].Get(1).Metadata().Get("sns_message_id"); exp != act {
+				t.Errorf("Wrong sns metadata: %v != %v", act, exp)
+			}
+		}
+	}
+}
+
+func TestUnwrapEnvelopeNotJSON(t *testing.T) {
+	conf := NewConfig()
+
+	proc, err := NewUnwrapEnvelope(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`not json`),
+		[]byte(fmt.Sprintf(testSNSEnvelope, strconv.Quote("not json either"))),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`not json`),
+		[]byte(`not json either`),
+	}
+	if act := message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected failed flag")
+	}
+	if HasFailed(msgs[0].Get(1)) {
+		t.Error("Unexpected failed flag")
+	}
+}
@@ -47,7 +47,10 @@ The field ` + "`at_least_once`" + `, if true, ensures that the child processors
 are always executed at least one time (like a do .. while loop.)
 
 The field ` + "`max_loops`" + `, if greater than zero, caps the number of loops
-for a message batch to this value.
+for a message batch to this value. Since a condition that never resolves to
+false would otherwise block the pipeline indefinitely it is recommended to
+always set a cap when looping on data that comes from outside of the pipeline,
+such as when paginating through the results of an API.
 
 If following a loop execution the number of messages in a batch is reduced to
 zero the loop is exited regardless of the condition result. If following a loop
@@ -112,6 +115,7 @@ type While struct {
 
 	mCount      metrics.StatCounter
 	mLoop       metrics.StatCounter
+	mMaxLoops   metrics.StatCounter
 	mCondFailed metrics.StatCounter
 	mSent       metrics.StatCounter
 	mBatchSent  metrics.StatCounter
@@ -149,6 +153,7 @@ func NewWhile(
 
 		mCount:      stats.GetCounter("count"),
 		mLoop:       stats.GetCounter("loop"),
+		mMaxLoops:   stats.GetCounter("max_loops_reached"),
 		mCondFailed: stats.GetCounter("failed"),
 		mSent:       stats.GetCounter("sent"),
 		mBatchSent:  stats.GetCounter("batch.sent"),
@@ -167,11 +172,20 @@ func (w *While) ProcessMessage(msg types.Message) (msgs []types.Message, res typ
 
 	loops := 0
 	condResult := w.atLeastOnce || w.cond.Check(msg)
+	defer func() {
+		for _, s := range spans {
+			s.SetTag("result", condResult)
+			s.SetTag("loops", loops)
+			s.Finish()
+		}
+	}()
+
 	for condResult {
 		if atomic.LoadInt32(&w.running) != 1 {
 			return nil, response.NewError(types.ErrTypeClosed)
 		}
 		if w.maxLoops > 0 && loops >= w.maxLoops {
+			w.mMaxLoops.Incr(1)
 			w.log.Traceln("Reached max loops count")
 			break
 		}
@@ -190,11 +204,6 @@ func (w *While) ProcessMessage(msg types.Message) (msgs []types.Message, res typ
 		loops++
 	}
 
-	for _, s := range spans {
-		s.SetTag("result", condResult)
-		s.Finish()
-	}
-
 	w.mBatchSent.Incr(int64(len(msgs)))
 	totalParts := 0
 	for _, msg := range msgs {
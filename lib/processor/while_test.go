@@ -205,3 +205,32 @@ func TestWhileWithStaticTrue(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestWhileExitsOnEmptyBatch(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "while"
+	conf.While.Condition.Type = "static"
+	conf.While.Condition.Static = true
+
+	procConf := NewConfig()
+	procConf.Type = "select_parts"
+	procConf.SelectParts.Parts = []int{5}
+
+	conf.While.Processors = append(conf.While.Processors, procConf)
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := c.ProcessMessage(message.New([][]byte{[]byte("bar")}))
+	if len(msgs) != 0 {
+		t.Errorf("Expected no messages, received: %v", len(msgs))
+	}
+	if res == nil {
+		t.Fatal("Expected response from dropped batch")
+	}
+	if err = res.Error(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message/tracing"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWorkflow] = TypeSpec{
+		constructor: NewWorkflow,
+		description: `
+Executes a map of named enrichment branches as a Directed Acyclic Graph (DAG),
+where each branch is a ` + "[`process_map`](#process_map)" + ` with an optional
+list of explicit ` + "`dependencies`" + `. The order of execution is resolved
+automatically by matching the premap targets of each branch against the postmap
+targets of the others, and branches without dependencies between them are
+executed in parallel.
+
+The outcome of each branch is recorded for every message part as a metadata
+field named after the branch with the prefix ` + "`meta_prefix`" + `, with the
+value ` + "`succeeded`" + `, ` + "`skipped`" + ` or ` + "`failed`" + `:
+
+- A branch is ` + "`skipped`" + ` for a message part when its conditions do
+  not pass, or its premap fails because the targets are not found. Branches
+  that depend on the result of a skipped branch will typically be skipped as
+  well since their premap targets will also be missing.
+- A branch has ` + "`failed`" + ` for a message part when its processors return
+  an error or flag the part as failed, or when its postmap fails. The result of
+  a failed branch is not mapped back into the message part, and the part is
+  flagged as having failed so that it can be handled with
+  [error handling patterns](../error_handling.md).
+
+For example, if we had three target HTTP services that we wished to enrich each
+document with - foo, bar and baz - where baz relies on the result of both foo
+and bar, we might express that relationship here like so:
+
+` + "``` yaml" + `
+workflow:
+  meta_prefix: workflow_
+  branches:
+    foo:
+      premap:
+        .: .
+      processors:
+      - http:
+          request:
+            url: http://foo/enrich
+      postmap:
+        foo_result: .
+    bar:
+      premap:
+        .: msg.sub.path
+      processors:
+      - http:
+          request:
+            url: http://bar/enrich
+      postmap:
+        bar_result: .
+    baz:
+      premap:
+        foo_obj: foo_result
+        bar_obj: bar_result
+      processors:
+      - http:
+          request:
+            url: http://baz/enrich
+      postmap:
+        baz_obj: .
+` + "```" + `
+
+With this config foo and bar are executed in parallel, and once they are both
+finished baz is executed. A document without the field ` + "`msg.sub.path`" + `
+would result in the metadata ` + "`workflow_bar`" + ` and ` + "`workflow_baz`" + `
+both being set to ` + "`skipped`" + `.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			sanitBranches := map[string]interface{}{}
+			for k, v := range conf.Workflow.Branches {
+				sanit, err := v.Sanitise()
+				if err != nil {
+					return nil, err
+				}
+				sanit["dependencies"] = v.Dependencies
+				sanitBranches[k] = sanit
+			}
+			return map[string]interface{}{
+				"meta_prefix": conf.Workflow.MetaPrefix,
+				"branches":    sanitBranches,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WorkflowConfig is a config struct containing fields for the Workflow
+// processor.
+type WorkflowConfig struct {
+	MetaPrefix string                         `json:"meta_prefix" yaml:"meta_prefix"`
+	Branches   map[string]DepProcessMapConfig `json:"branches" yaml:"branches"`
+}
+
+// NewWorkflowConfig returns a default WorkflowConfig.
+func NewWorkflowConfig() WorkflowConfig {
+	return WorkflowConfig{
+		MetaPrefix: "workflow_",
+		Branches:   map[string]DepProcessMapConfig{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	workflowSucceeded = "succeeded"
+	workflowSkipped   = "skipped"
+	workflowFailed    = "failed"
+)
+
+// Workflow is a processor that applies a DAG of process_map branches to
+// messages and records the outcome of each branch within the metadata of each
+// message part.
+type Workflow struct {
+	children   map[string]*ProcessMap
+	dag        [][]string
+	metaPrefix string
+
+	log log.Modular
+
+	mCount      metrics.StatCounter
+	mErr        metrics.StatCounter
+	mSkipped    metrics.StatCounter
+	mSucceeded  metrics.StatCounter
+	mSent       metrics.StatCounter
+	mBatchSent  metrics.StatCounter
+	mBranchFail metrics.StatCounter
+}
+
+// NewWorkflow returns a Workflow processor.
+func NewWorkflow(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	children := map[string]*ProcessMap{}
+	explicitDeps := map[string][]string{}
+
+	for k, v := range conf.Workflow.Branches {
+		nsLog := log.NewModule(fmt.Sprintf(".%v", k))
+		nsStats := metrics.Namespaced(stats, k)
+
+		child, err := NewProcessMap(v.ProcessMapConfig, mgr, nsLog, nsStats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create branch '%v': %v", k, err)
+		}
+
+		children[k] = child
+		explicitDeps[k] = v.Dependencies
+	}
+
+	dag, err := resolveDAG(explicitDeps, children)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Workflow{
+		children:   children,
+		dag:        dag,
+		metaPrefix: conf.Workflow.MetaPrefix,
+
+		log: log,
+
+		mCount:      stats.GetCounter("count"),
+		mErr:        stats.GetCounter("error"),
+		mSkipped:    stats.GetCounter("branch.skipped"),
+		mSucceeded:  stats.GetCounter("branch.succeeded"),
+		mBranchFail: stats.GetCounter("branch.failed"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+	}
+
+	w.log.Infof("Resolved workflow DAG: %v\n", w.dag)
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// overlayBranch maps the result of a branch back onto the payload, and records
+// the outcome of the branch for each message part.
+func (w *Workflow) overlayBranch(
+	id string, payload, branchResult types.Message, skipped []int, err error,
+) {
+	statuses := make([]string, payload.Len())
+	for _, i := range skipped {
+		if i < len(statuses) {
+			statuses[i] = workflowSkipped
+		}
+	}
+
+	if err == nil {
+		// Parts flagged as failed by the branch processors are emptied so that
+		// they aren't mapped back onto the payload.
+		parts := make([]types.Part, branchResult.Len())
+		branchResult.Iter(func(i int, p types.Part) error {
+			parts[i] = p
+			if i < len(statuses) && len(statuses[i]) == 0 && HasFailed(p) {
+				statuses[i] = workflowFailed
+				parts[i] = nil
+			}
+			return nil
+		})
+		branchResult.SetAll(parts)
+
+		var failed []int
+		if failed, err = w.children[id].OverlayResult(payload, branchResult); err == nil {
+			for _, i := range failed {
+				statuses[i] = workflowFailed
+			}
+		}
+	}
+	if err != nil {
+		w.log.Errorf("Failed to perform branch '%v': %v\n", id, err)
+		for i, s := range statuses {
+			if len(s) == 0 {
+				statuses[i] = workflowFailed
+			}
+		}
+	}
+
+	key := w.metaPrefix + id
+	payload.Iter(func(i int, p types.Part) error {
+		status := statuses[i]
+		switch status {
+		case workflowSkipped:
+			w.mSkipped.Incr(1)
+		case workflowFailed:
+			w.mBranchFail.Incr(1)
+			w.mErr.Incr(1)
+			FlagErr(p, fmt.Errorf("workflow branch '%v' failed", id))
+		default:
+			status = workflowSucceeded
+			w.mSucceeded.Incr(1)
+		}
+		p.Metadata().Set(key, status)
+		return nil
+	})
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	result := msg.DeepCopy()
+	result.Iter(func(i int, p types.Part) error {
+		_ = p.Get()
+		_, _ = p.JSON()
+		_ = p.Metadata()
+		return nil
+	})
+
+	propMsg, propSpans := tracing.WithChildSpans(TypeWorkflow, result)
+
+	for _, layer := range w.dag {
+		results := make([]types.Message, len(layer))
+		skipped := make([][]int, len(layer))
+		errors := make([]error, len(layer))
+
+		wg := sync.WaitGroup{}
+		wg.Add(len(layer))
+		for i, eid := range layer {
+			go func(id string, index int) {
+				var resSpans []opentracing.Span
+				branchMsg := propMsg.Copy()
+				// Parts might already be flagged as failed, either before
+				// the workflow or by a branch of a previous layer, which
+				// must not be mistaken for a failure of this branch.
+				branchMsg.Iter(func(i int, p types.Part) error {
+					ClearFail(p)
+					return nil
+				})
+				results[index], resSpans = tracing.WithChildSpans(id, branchMsg)
+
+				var premapFailed []int
+				skipped[index], premapFailed, errors[index] = w.children[id].createResult(results[index])
+				skipped[index] = append(skipped[index], premapFailed...)
+
+				for _, s := range resSpans {
+					s.Finish()
+				}
+				wg.Done()
+			}(eid, i)
+		}
+		wg.Wait()
+
+		for i, id := range layer {
+			w.overlayBranch(id, result, results[i], skipped[i], errors[i])
+		}
+	}
+
+	for _, s := range propSpans {
+		s.Finish()
+	}
+
+	w.mBatchSent.Incr(1)
+	w.mSent.Incr(int64(result.Len()))
+
+	msgs := [1]types.Message{result}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (w *Workflow) CloseAsync() {
+	for _, c := range w.children {
+		c.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (w *Workflow) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, c := range w.children {
+		if err := c.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,202 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestWorkflowCircular(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWorkflow
+	conf.Workflow.Branches["foo"] = createProcMapConf("tmp.baz", "tmp.foo")
+	conf.Workflow.Branches["bar"] = createProcMapConf("tmp.foo", "tmp.bar")
+	conf.Workflow.Branches["baz"] = createProcMapConf("tmp.bar", "tmp.baz")
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err == nil {
+		t.Error("expected error from circular deps")
+	}
+}
+
+func TestWorkflowSimple(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWorkflow
+	conf.Workflow.Branches["foo"] = createProcMapConf("root", "tmp.foo")
+	conf.Workflow.Branches["bar"] = createProcMapConf("tmp.foo", "tmp.bar")
+	conf.Workflow.Branches["baz"] = createProcMapConf("tmp.bar", "tmp.baz")
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]byte{
+		[]byte(`{"oops":"no root"}`),
+		[]byte(`{"root":"foobarbaz","tmp":{"bar":"foobarbaz","baz":"foobarbaz","foo":"foobarbaz"}}`),
+		[]byte(`{"root":"foobarbaz","tmp":{"also":"here","bar":"foobarbaz","baz":"foobarbaz","foo":"foobarbaz"}}`),
+	}
+	expMeta := []map[string]string{
+		{
+			"workflow_foo": "skipped",
+			"workflow_bar": "skipped",
+			"workflow_baz": "skipped",
+		},
+		{
+			"workflow_foo": "succeeded",
+			"workflow_bar": "succeeded",
+			"workflow_baz": "succeeded",
+		},
+		{
+			"workflow_foo": "succeeded",
+			"workflow_bar": "succeeded",
+			"workflow_baz": "succeeded",
+		},
+	}
+
+	msg, res := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"oops":"no root"}`),
+		[]byte(`{"root":"foobarbaz"}`),
+		[]byte(`{"root":"foobarbaz","tmp":{"also":"here"}}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, m := range expMeta {
+		part := msg[0].Get(i)
+		for k, v := range m {
+			if act := part.Metadata().Get(k); act != v {
+				t.Errorf("Wrong metadata value for part %v key %v: %v != %v", i, k, act, v)
+			}
+		}
+		if HasFailed(part) {
+			t.Errorf("Did not expect part %v to be flagged as failed", i)
+		}
+	}
+}
+
+func TestWorkflowFailedBranch(t *testing.T) {
+	numConf := NewConfig()
+	numConf.Type = TypeNumber
+	numConf.Number.Operator = "add"
+	numConf.Number.Value = 1
+
+	countConf := NewDepProcessMapConfig()
+	countConf.Premap["."] = "value"
+	countConf.Postmap["result"] = "."
+	countConf.Processors = []Config{numConf}
+
+	conf := NewConfig()
+	conf.Type = TypeWorkflow
+	conf.Workflow.MetaPrefix = "wf_"
+	conf.Workflow.Branches["count"] = countConf
+	conf.Workflow.Branches["next"] = createProcMapConf("result", "tmp.next")
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{
+		[]byte(`{"result":6,"tmp":{"next":6},"value":5}`),
+		[]byte(`{"value":"nope"}`),
+		[]byte(`{"other":1}`),
+	}
+	expMeta := []map[string]string{
+		{
+			"wf_count": "succeeded",
+			"wf_next":  "succeeded",
+		},
+		{
+			"wf_count": "failed",
+			"wf_next":  "skipped",
+		},
+		{
+			"wf_count": "skipped",
+			"wf_next":  "skipped",
+		},
+	}
+	expFailed := []bool{false, true, false}
+
+	msg, res := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"value":5}`),
+		[]byte(`{"value":"nope"}`),
+		[]byte(`{"other":1}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for i, m := range expMeta {
+		part := msg[0].Get(i)
+		for k, v := range m {
+			if act := part.Metadata().Get(k); act != v {
+				t.Errorf("Wrong metadata value for part %v key %v: %v != %v", i, k, act, v)
+			}
+		}
+		if exp, act := expFailed[i], HasFailed(part); exp != act {
+			t.Errorf("Wrong failed flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestWorkflowAlreadyFailed(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWorkflow
+	conf.Workflow.Branches["foo"] = createProcMapConf("root", "tmp.foo")
+	conf.Workflow.Branches["bar"] = createProcMapConf("tmp.foo", "tmp.bar")
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inMsg := message.New([][]byte{
+		[]byte(`{"root":"foobar"}`),
+	})
+	FlagFail(inMsg.Get(0))
+
+	msg, res := c.ProcessMessage(inMsg)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	exp := [][]byte{
+		[]byte(`{"root":"foobar","tmp":{"bar":"foobar","foo":"foobar"}}`),
+	}
+	if act := message.GetAllBytes(msg[0]); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	for _, k := range []string{"workflow_foo", "workflow_bar"} {
+		if exp, act := "succeeded", msg[0].Get(0).Metadata().Get(k); exp != act {
+			t.Errorf("Wrong metadata value for key %v: %v != %v", k, act, exp)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build XSD
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/opentracing/opentracing-go"
+	xsdvalidate "github.com/terminalstatic/go-xsd-validate"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeXSD] = TypeSpec{
+		constructor: NewXSD,
+		description: `
+Validates XML messages against an [XML Schema](https://www.w3.org/TR/xmlschema-1/)
+document. The schema is loaded from ` + "`schema_path`" + `, which can either be
+a path to a local file or an HTTP URL, and any schemas it imports or includes
+are resolved relative to it.
+
+Messages that are not well formed or that fail validation are left unchanged
+and flagged as having failed, with the validation errors as the failure reason,
+allowing you to use [error handling patterns](../error_handling.md) in order to
+route them elsewhere before they reach an XML parser. Valid messages are left
+unchanged.
+
+This processor requires libxml2 and is only included when Benthos is built with
+the ` + "`XSD`" + ` build tag.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// XSDConfig contains configuration fields for the XSD processor.
+type XSDConfig struct {
+	Parts      []int  `json:"parts" yaml:"parts"`
+	SchemaPath string `json:"schema_path" yaml:"schema_path"`
+}
+
+// NewXSDConfig returns a XSDConfig with default values.
+func NewXSDConfig() *XSDConfig {
+	return &XSDConfig{
+		Parts:      []int{},
+		SchemaPath: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var xsdInitOnce sync.Once
+var xsdInitErr error
+
+// XSD is a processor that validates XML messages against an XML Schema.
+type XSD struct {
+	parts      []int
+	handler    *xsdvalidate.XsdHandler
+	handlerMut sync.RWMutex
+
+	conf  Config
+	log   log.Modular
+	stats metrics.Type
+
+	closeOnce sync.Once
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mInvalid   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewXSD returns a XSD processor.
+func NewXSD(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.XSD == nil || len(conf.XSD.SchemaPath) == 0 {
+		return nil, errors.New("a schema_path must be specified")
+	}
+
+	xsdInitOnce.Do(func() {
+		xsdInitErr = xsdvalidate.Init()
+	})
+	if xsdInitErr != nil {
+		return nil, fmt.Errorf("failed to initialise libxml2: %v", xsdInitErr)
+	}
+
+	handler, err := xsdvalidate.NewXsdHandlerUrl(conf.XSD.SchemaPath, xsdvalidate.ParsErrDefault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %v", err)
+	}
+
+	return &XSD{
+		parts:   conf.XSD.Parts,
+		handler: handler,
+
+		conf:  conf,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mInvalid:   stats.GetCounter("error.invalid"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *XSD) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	// The handler is freed when the processor closes, which can happen while
+	// messages are still being processed.
+	p.handlerMut.RLock()
+	defer p.handlerMut.RUnlock()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		if p.handler == nil {
+			p.mErr.Incr(1)
+			return types.ErrTypeClosed
+		}
+		if err := p.handler.ValidateMem(part.Get(), xsdvalidate.ValidErrDefault); err != nil {
+			p.mErr.Incr(1)
+			p.mInvalid.Incr(1)
+			err = fmt.Errorf("xml failed validation: %v", strings.TrimSpace(err.Error()))
+			p.log.Debugf("Message failed schema validation: %v\n", err)
+			return err
+		}
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeXSD, p.parts, newMsg, proc)
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *XSD) CloseAsync() {
+	p.closeOnce.Do(func() {
+		p.handlerMut.Lock()
+		p.handler.Free()
+		p.handler = nil
+		p.handlerMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *XSD) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
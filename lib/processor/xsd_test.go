@@ -0,0 +1,127 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// +build XSD
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+const xsdTestSchema = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="doc">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="id" type="xs:integer"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+func newXSDTestProc(t *testing.T) Type {
+	t.Helper()
+
+	tmpDir, err := ioutil.TempDir("", "benthos_xsd_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	schemaPath := filepath.Join(tmpDir, "schema.xsd")
+	if err = ioutil.WriteFile(schemaPath, []byte(xsdTestSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeXSD
+	conf.XSD.SchemaPath = schemaPath
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proc
+}
+
+func TestXSDValidation(t *testing.T) {
+	proc := newXSDTestProc(t)
+	defer proc.CloseAsync()
+
+	input := [][]byte{
+		[]byte(`<doc><id>10</id></doc>`),
+		[]byte(`<doc><id>nope</id></doc>`),
+		[]byte(`<doc><id>10</id>`),
+	}
+	expFailed := []bool{false, true, true}
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("Wrong count of messages: %v", len(msgs))
+	}
+	for i, exp := range expFailed {
+		part := msgs[0].Get(i)
+		if act := HasFailed(part); exp != act {
+			t.Errorf("Wrong failed flag for part %v: %v != %v", i, act, exp)
+		}
+		if exp, act := string(input[i]), string(part.Get()); exp != act {
+			t.Errorf("Wrong contents for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestXSDCloseWhileProcessing(t *testing.T) {
+	proc := newXSDTestProc(t)
+
+	wg := sync.WaitGroup{}
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				proc.ProcessMessage(message.New([][]byte{
+					[]byte(`<doc><id>10</id></doc>`),
+				}))
+			}
+		}()
+	}
+
+	proc.CloseAsync()
+	wg.Wait()
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`<doc><id>10</id></doc>`),
+	}))
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected message to fail after close")
+	}
+}
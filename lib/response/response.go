@@ -20,7 +20,11 @@
 
 package response
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
 
 //------------------------------------------------------------------------------
 
@@ -120,3 +124,28 @@ func NewUnack() Unack {
 }
 
 //------------------------------------------------------------------------------
+
+// Prepared is a response type that indicates the message has been staged by a
+// transactional output and can be either committed or aborted. A Prepared
+// response is considered successful, but the message has not yet been made
+// visible within the sink.
+type Prepared struct {
+	types.PreparedWrite
+}
+
+// Error returns the underlying error.
+func (p Prepared) Error() error { return nil }
+
+// SkipAck indicates whether a successful message should be acknowledged.
+func (p Prepared) SkipAck() bool {
+	return false
+}
+
+// NewPrepared returns a Prepared response type wrapping a staged write.
+func NewPrepared(w types.PreparedWrite) Prepared {
+	return Prepared{
+		PreparedWrite: w,
+	}
+}
+
+//------------------------------------------------------------------------------
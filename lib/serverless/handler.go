@@ -141,7 +141,10 @@ func (h *Handler) Handle(ctx context.Context, obj interface{}) (interface{}, err
 // NewHandler returns a Handler by creating a Benthos pipeline.
 func NewHandler(conf config.Type) (*Handler, error) {
 	// Logging and stats aggregation.
-	logger := log.New(os.Stdout, conf.Logger)
+	logger, err := log.NewFromConfig(os.Stdout, conf.Logger)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create our metrics type.
 	stats, err := metrics.New(conf.Metrics, metrics.OptSetLogger(logger))
@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
@@ -34,6 +35,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/api"
+	"github.com/Jeffail/benthos/lib/audit"
 	"github.com/Jeffail/benthos/lib/buffer"
 	"github.com/Jeffail/benthos/lib/cache"
 	"github.com/Jeffail/benthos/lib/condition"
@@ -72,6 +74,12 @@ var (
 	showConfigYAML = flag.Bool(
 		"print-yaml", false, "Print loaded configuration as YAML, then exit",
 	)
+	resolveConfig = flag.Bool(
+		"resolve", false,
+		`
+Used with --print-yaml, masks the values of secret fields and annotates fields
+resolved from environment variables with a comment describing their source.`[1:],
+	)
 	showAll = flag.Bool(
 		"all", false,
 		`
@@ -89,6 +97,13 @@ Set whether all fields should be shown when printing configuration via
 		`
 Parse config files in strict mode, where any linting errors will cause Benthos
 to fail`[1:],
+	)
+	strictDelivery = flag.Bool(
+		"strict-delivery", false,
+		`
+Refuse to start when the config contains a combination of components that can
+lose messages without returning an error to the input, such as a memory buffer.
+When combined with --lint these problems are also reported as lint errors.`[1:],
 	)
 	examples = flag.String(
 		"example", "",
@@ -151,6 +166,14 @@ When running Benthos in streams mode any files in this directory with a .json or
 .yaml extension will be parsed as a stream configuration (input, buffer,
 pipeline, output), where the filename less the extension will be the id of the
 stream.`[1:],
+	)
+	streamsSnapshotDir = flag.String(
+		"streams-snapshot-dir", "",
+		`
+When running Benthos in streams mode the configs of streams created or updated
+via the REST API are saved to this directory, and are removed when the stream is
+deleted via the API. Any streams saved in this directory are restored on
+startup, replacing streams of the same id from the --streams-dir directory.`[1:],
 	)
 	// Plugin Flags
 	printInputPlugins     bool
@@ -159,6 +182,9 @@ stream.`[1:],
 	printConditionPlugins bool
 	printCachePlugins     bool
 	printRateLimitPlugins bool
+	printMetricsPlugins   bool
+	printTracerPlugins    bool
+	printLoggerPlugins    bool
 )
 
 func registerPluginFlags() {
@@ -198,6 +224,24 @@ func registerPluginFlags() {
 			"Print a list of available ratelimit plugins, then exit",
 		)
 	}
+	if metrics.PluginCount() > 0 {
+		flag.BoolVar(
+			&printMetricsPlugins, "list-metrics-plugins", false,
+			"Print a list of available metrics plugins, then exit",
+		)
+	}
+	if tracer.PluginCount() > 0 {
+		flag.BoolVar(
+			&printTracerPlugins, "list-tracer-plugins", false,
+			"Print a list of available tracer plugins, then exit",
+		)
+	}
+	if log.PluginCount() > 0 {
+		flag.BoolVar(
+			&printLoggerPlugins, "list-logger-plugins", false,
+			"Print a list of available logger plugins, then exit",
+		)
+	}
 }
 
 //------------------------------------------------------------------------------
@@ -230,7 +274,9 @@ func bootstrap() (config.Type, []string) {
 	}
 
 	var lints []string
+	var readPath string
 	if len(*configPath) > 0 {
+		readPath = *configPath
 		var err error
 		if lints, err = config.Read(*configPath, true, &conf); err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
@@ -246,10 +292,22 @@ func bootstrap() (config.Type, []string) {
 					fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
 					os.Exit(1)
 				}
+				readPath = path
 				break
 			}
 		}
 	}
+	if *strictDelivery {
+		deliveryLints := config.LintDelivery(conf)
+		if !*lintConfig && len(deliveryLints) > 0 {
+			fmt.Fprintln(os.Stderr, "Config rejected by --strict-delivery:")
+			for _, l := range deliveryLints {
+				fmt.Fprintln(os.Stderr, l)
+			}
+			os.Exit(1)
+		}
+		lints = append(lints, deliveryLints...)
+	}
 	if *lintConfig {
 		if len(lints) > 0 {
 			for _, l := range lints {
@@ -291,6 +349,20 @@ func bootstrap() (config.Type, []string) {
 				fmt.Fprintln(os.Stderr, fmt.Sprintf("Configuration marshal error: %v", err))
 			}
 			os.Exit(0)
+		} else if *resolveConfig {
+			var rawConfig []byte
+			if len(readPath) > 0 {
+				if rawConfig, err = ioutil.ReadFile(readPath); err != nil {
+					fmt.Fprintln(os.Stderr, fmt.Sprintf("Configuration file read error: %v", err))
+					os.Exit(1)
+				}
+			}
+			if configYAML, err := config.MarshalResolvedYAML(outConf, rawConfig); err == nil {
+				fmt.Println(string(configYAML))
+			} else {
+				fmt.Fprintln(os.Stderr, fmt.Sprintf("Configuration marshal error: %v", err))
+			}
+			os.Exit(0)
 		} else {
 			if configYAML, err := uconfig.MarshalYAML(outConf); err == nil {
 				fmt.Println(string(configYAML))
@@ -306,7 +378,8 @@ func bootstrap() (config.Type, []string) {
 		*printConditions || *printCaches || *printRateLimits ||
 		*printMetrics || *printTracers || printInputPlugins ||
 		printOutputPlugins || printProcessorPlugins || printConditionPlugins ||
-		printCachePlugins || printRateLimitPlugins {
+		printCachePlugins || printRateLimitPlugins || printMetricsPlugins ||
+		printTracerPlugins || printLoggerPlugins {
 		if *printInputs {
 			fmt.Println(input.Descriptions())
 		}
@@ -352,12 +425,34 @@ func bootstrap() (config.Type, []string) {
 		if printCachePlugins {
 			fmt.Println(cache.PluginDescriptions())
 		}
+		if printMetricsPlugins {
+			fmt.Println(metrics.PluginDescriptions())
+		}
+		if printTracerPlugins {
+			fmt.Println(tracer.PluginDescriptions())
+		}
+		if printLoggerPlugins {
+			fmt.Println(log.PluginDescriptions())
+		}
 		os.Exit(0)
 	}
 
 	return conf, lints
 }
 
+// lintStreamDelivery logs the delivery lints of each stream config and returns
+// true if any were found.
+func lintStreamDelivery(logger log.Modular, confs map[string]stream.Config) bool {
+	rejected := false
+	for id, conf := range confs {
+		for _, lint := range config.LintStreamDelivery(conf) {
+			logger.Errorf("Stream (%v) config rejected by --strict-delivery: %v\n", id, lint)
+			rejected = true
+		}
+	}
+	return rejected
+}
+
 type stoppableStreams interface {
 	Stop(timeout time.Duration) error
 }
@@ -372,13 +467,15 @@ func Run() {
 	config, lints := bootstrap()
 
 	// Logging and stats aggregation.
-	var logger log.Modular
-
 	// Note: Only log to Stderr if one of our outputs is stdout.
-	if config.Output.Type == "stdout" {
-		logger = log.New(os.Stderr, config.Logger)
-	} else {
-		logger = log.New(os.Stdout, config.Logger)
+	logStream := os.Stdout
+	if config.Output.Type == "stdout" && config.Output.STDOUT.Target != "stderr" {
+		logStream = os.Stderr
+	}
+	logger, err := log.NewFromConfig(logStream, config.Logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
 	}
 
 	if len(lints) > 0 {
@@ -398,7 +495,6 @@ func Run() {
 
 	// Create our metrics type.
 	var stats metrics.Type
-	var err error
 	stats, err = metrics.New(config.Metrics, metrics.OptSetLogger(logger))
 	for err != nil {
 		logger.Errorf("Failed to connect to metrics aggregator: %v\n", err)
@@ -429,6 +525,23 @@ func Run() {
 		logger.Errorf("Failed to initialise API: %v\n", err)
 		os.Exit(1)
 	}
+	httpServer.RegisterEndpoint(
+		"/debug/pipeline", "Returns the recent execution times and error"+
+			" rates of each processor.",
+		processor.ProfileHandler(),
+	)
+	httpServer.RegisterEndpoint(
+		"/debug/messages", "Returns the sampled message size distributions"+
+			" and key frequencies of each message_stats processor.",
+		processor.MessageStatsHandler(),
+	)
+	if config.HTTP.DebugEndpoints {
+		httpServer.RegisterEndpoint(
+			"/debug/config/diff", "DEBUG: Returns the loaded config, and when a"+
+				" candidate config is posted the changes required to reach it.",
+			config.DiffHandler(),
+		)
+	}
 
 	// Create resource manager.
 	manager, err := manager.New(config.Manager, httpServer, logger, stats)
@@ -437,6 +550,19 @@ func Run() {
 		os.Exit(1)
 	}
 
+	// Create drop audit sink.
+	var dropAudit *audit.Type
+	if config.DropAudit.Enabled {
+		if dropAudit, err = audit.New(
+			config.DropAudit, manager,
+			logger.NewModule(".drop_audit"),
+			metrics.Namespaced(stats, "drop_audit"),
+		); err != nil {
+			logger.Errorf("Failed to create drop audit: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	var dataStream stoppableStreams
 	dataStreamClosedChan := make(chan struct{})
 
@@ -447,13 +573,63 @@ func Run() {
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
+			strmmgr.OptSetSnapshotDirectory(*streamsSnapshotDir),
+			strmmgr.OptSetStrictDelivery(*strictDelivery),
 		)
 		var streamConfs map[string]stream.Config
+		var rawConfs map[string][]byte
 		if len(*streamsDir) > 0 {
 			if streamConfs, err = strmmgr.LoadStreamConfigsFromDirectory(true, *streamsDir); err != nil {
 				logger.Errorf("Failed to load stream configs: %v\n", err)
 				os.Exit(1)
 			}
+			if rawConfs, err = strmmgr.LoadRawStreamConfigsFromDirectory(*streamsDir); err != nil {
+				logger.Errorf("Failed to load stream configs: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if streamConfs == nil {
+			streamConfs = map[string]stream.Config{}
+		}
+		// Streams from the directory that are replaced by snapshots or that
+		// were deleted via the API are not counted.
+		lStreams := len(streamConfs)
+		var snapshotConfs map[string]stream.Config
+		if len(*streamsSnapshotDir) > 0 {
+			var deleted []string
+			if deleted, err = strmmgr.LoadDeletedStreamsFromDirectory(*streamsSnapshotDir); err != nil {
+				logger.Errorf("Failed to load stream snapshots: %v\n", err)
+				os.Exit(1)
+			}
+			for _, id := range deleted {
+				if _, exists := streamConfs[id]; exists {
+					logger.Infof("Skipping stream (%v) as it was deleted via the API\n", id)
+					delete(streamConfs, id)
+					lStreams--
+				}
+			}
+			var snapshotRawConfs map[string][]byte
+			if snapshotConfs, err = strmmgr.LoadStreamConfigsFromDirectory(true, *streamsSnapshotDir); err != nil {
+				logger.Errorf("Failed to load stream snapshots: %v\n", err)
+				os.Exit(1)
+			}
+			if snapshotRawConfs, err = strmmgr.LoadRawStreamConfigsFromDirectory(*streamsSnapshotDir); err != nil {
+				logger.Errorf("Failed to load stream snapshots: %v\n", err)
+				os.Exit(1)
+			}
+			if rawConfs == nil {
+				rawConfs = map[string][]byte{}
+			}
+			for id, conf := range snapshotConfs {
+				if _, exists := streamConfs[id]; exists {
+					lStreams--
+				}
+				streamConfs[id] = conf
+				rawConfs[id] = snapshotRawConfs[id]
+			}
+		}
+		if *strictDelivery && lintStreamDelivery(logger, streamConfs) {
+			os.Exit(1)
 		}
 		dataStream = streamMgr
 		for id, conf := range streamConfs {
@@ -461,11 +637,15 @@ func Run() {
 				logger.Errorf("Failed to create stream (%v): %v\n", id, err)
 				os.Exit(1)
 			}
+			streamMgr.SetRawConfig(id, rawConfs[id])
 		}
 		logger.Infoln("Launching benthos in streams mode, use CTRL+C to close.")
-		if lStreams := len(streamConfs); lStreams > 0 {
+		if lStreams > 0 {
 			logger.Infof("Created %v streams from directory: %v\n", lStreams, *streamsDir)
 		}
+		if lSnapshots := len(snapshotConfs); lSnapshots > 0 {
+			logger.Infof("Restored %v streams from snapshot directory: %v\n", lSnapshots, *streamsSnapshotDir)
+		}
 	} else {
 		if dataStream, err = stream.New(
 			config.Config,
@@ -530,6 +710,12 @@ func Run() {
 		if err := dataStream.Stop(exitTimeout); err != nil {
 			os.Exit(1)
 		}
+		if dropAudit != nil {
+			dropAudit.CloseAsync()
+			if err := dropAudit.WaitForClose(time.Until(timesOut)); err != nil {
+				logger.Warnf("Failed to close drop audit cleanly: %v\n", err)
+			}
+		}
 		manager.CloseAsync()
 		if err := manager.WaitForClose(time.Until(timesOut)); err != nil {
 			logger.Warnf(
@@ -44,6 +44,18 @@ import (
 
 //------------------------------------------------------------------------------
 
+// lintDelivery returns an error listing the combinations of components within
+// a stream config that can lose messages when strict delivery is enabled.
+func (m *Type) lintDelivery(id string, conf stream.Config) error {
+	if !m.strictDelivery {
+		return nil
+	}
+	if lints := config.LintStreamDelivery(conf); len(lints) > 0 {
+		return fmt.Errorf("stream '%v' config rejected by strict delivery: %v", id, strings.Join(lints, "; "))
+	}
+	return nil
+}
+
 func (m *Type) registerEndpoints() {
 	m.manager.RegisterEndpoint(
 		"/streams",
@@ -124,6 +136,11 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	if requestErr = yaml.Unmarshal(setBytes, &newSet); requestErr != nil {
 		return
 	}
+	for id, conf := range newSet {
+		if requestErr = m.lintDelivery(id, conf); requestErr != nil {
+			return
+		}
+	}
 
 	toDelete := []string{}
 	toUpdate := map[string]stream.Config{}
@@ -158,7 +175,9 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 
 	for i, id := range toDelete {
 		go func(sid string, j int) {
-			errDelete[j] = m.Delete(sid, time.Until(deadline))
+			if errDelete[j] = m.Delete(sid, time.Until(deadline)); errDelete[j] == nil {
+				m.removeSnapshot(sid)
+			}
 			wg.Done()
 		}(id, i)
 	}
@@ -166,7 +185,10 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	for id, conf := range toUpdate {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
-			errUpdate[j] = m.Update(sid, *sconf, time.Until(deadline))
+			if errUpdate[j] = m.Update(sid, *sconf, time.Until(deadline)); errUpdate[j] == nil {
+				m.SetRawConfig(sid, nil)
+				m.saveSnapshot(sid, *sconf)
+			}
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -175,7 +197,10 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	for id, conf := range toCreate {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
-			errCreate[j] = m.Create(sid, *sconf)
+			if errCreate[j] = m.Create(sid, *sconf); errCreate[j] == nil {
+				m.SetRawConfig(sid, nil)
+				m.saveSnapshot(sid, *sconf)
+			}
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -229,11 +254,13 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var rawConfig []byte
 	readConfig := func() (confOut stream.Config, err error) {
 		var confBytes []byte
 		if confBytes, err = ioutil.ReadAll(r.Body); err != nil {
 			return
 		}
+		rawConfig = confBytes
 
 		confOut = stream.NewConfig()
 		err = yaml.Unmarshal(text.ReplaceEnvVariables(confBytes), &confOut)
@@ -293,6 +320,9 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
+		if requestErr = m.lintDelivery(id, conf); requestErr != nil {
+			return
+		}
 		serverErr = m.Create(id, conf)
 	case "GET":
 		var info *StreamStatus
@@ -320,6 +350,9 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
+		if requestErr = m.lintDelivery(id, conf); requestErr != nil {
+			return
+		}
 		serverErr = m.Update(id, conf, time.Until(deadline))
 	case "DELETE":
 		serverErr = m.Delete(id, time.Until(deadline))
@@ -329,12 +362,27 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			if conf, requestErr = patchConfig(info.Config()); requestErr != nil {
 				return
 			}
+			if requestErr = m.lintDelivery(id, conf); requestErr != nil {
+				return
+			}
 			serverErr = m.Update(id, conf, time.Until(deadline))
 		}
 	default:
 		requestErr = fmt.Errorf("verb not supported: %v", r.Method)
 	}
 
+	if serverErr == nil && requestErr == nil {
+		switch r.Method {
+		case "POST", "PUT":
+			m.SetRawConfig(id, rawConfig)
+			m.saveSnapshot(id, conf)
+		case "PATCH":
+			m.saveSnapshot(id, conf)
+		case "DELETE":
+			m.removeSnapshot(id)
+		}
+	}
+
 	if serverErr == ErrStreamDoesNotExist {
 		serverErr = nil
 		http.Error(w, "Stream not found", http.StatusNotFound)
@@ -24,9 +24,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -598,6 +600,54 @@ func TestTypeAPIDefaultConf(t *testing.T) {
 	}
 }
 
+func TestTypeAPIStrictDelivery(t *testing.T) {
+	mgr := New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetAPITimeout(time.Millisecond*100),
+		OptSetStrictDelivery(true),
+	)
+
+	r := router(mgr)
+
+	body := []byte(`{
+	"input": {
+		"type": "nanomsg"
+	},
+	"buffer": {
+		"type": "memory"
+	},
+	"output": {
+		"type": "nanomsg"
+	}
+}`)
+
+	request, err := http.NewRequest("POST", "/streams/foo", bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	request, err = http.NewRequest("POST", "/streams", bytes.NewReader([]byte(`{"foo":`+string(body)+`}`)))
+	if err != nil {
+		panic(err)
+	}
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusBadRequest, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	if _, err = mgr.Read("foo"); err != ErrStreamDoesNotExist {
+		t.Errorf("Unexpected error: %v != %v", err, ErrStreamDoesNotExist)
+	}
+}
+
 func TestTypeAPIGetStats(t *testing.T) {
 	mgr := New(
 		OptSetLogger(log.Noop()),
@@ -645,3 +695,145 @@ func TestTypeAPIGetStats(t *testing.T) {
 		t.Logf("Metrics: %v", stats)
 	}
 }
+
+func TestTypeAPISnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_stream_snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mgr := New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetAPITimeout(time.Millisecond*100),
+		OptSetSnapshotDirectory(dir),
+	)
+
+	r := router(mgr)
+	conf := harmlessConf()
+
+	request := genRequest("POST", "/streams/foo", conf)
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	confs, err := LoadStreamConfigsFromDirectory(false, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := confs["foo"]; !exists || len(confs) != 1 {
+		t.Errorf("Unexpected snapshots: %v", confs)
+	}
+
+	conf.Output.Type = "stdout"
+	request = genRequest("PUT", "/streams/foo", conf)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	if confs, err = LoadStreamConfigsFromDirectory(false, dir); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "stdout", confs["foo"].Output.Type; exp != act {
+		t.Errorf("Unexpected snapshot output type: %v != %v", act, exp)
+	}
+
+	request = genRequest("DELETE", "/streams/foo", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "foo.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected snapshot to be removed: %v", err)
+	}
+
+	deleted, err := LoadDeletedStreamsFromDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"foo"}, deleted; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deleted streams: %v != %v", act, exp)
+	}
+
+	request = genRequest("POST", "/streams/foo", conf)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+	if deleted, err = LoadDeletedStreamsFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) > 0 {
+		t.Errorf("Expected tombstone to be removed: %v", deleted)
+	}
+
+	if err = mgr.Stop(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTypeAPISnapshotEnvVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_stream_snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("BENTHOS_TEST_SNAPSHOT_PATH", "/secret")
+	defer os.Unsetenv("BENTHOS_TEST_SNAPSHOT_PATH")
+
+	mgr := New(
+		OptSetLogger(log.New(os.Stdout, log.Config{LogLevel: "NONE"})),
+		OptSetStats(metrics.DudType{}),
+		OptSetManager(types.DudMgr{}),
+		OptSetAPITimeout(time.Millisecond*100),
+		OptSetSnapshotDirectory(dir),
+	)
+
+	r := router(mgr)
+
+	request, err := http.NewRequest("POST", "/streams/foo", bytes.NewReader([]byte(`
+input:
+  type: http_server
+  http_server:
+    path: ${BENTHOS_TEST_SNAPSHOT_PATH}
+output:
+  type: http_server
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	confs, err := LoadStreamConfigsFromDirectory(false, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "${BENTHOS_TEST_SNAPSHOT_PATH}", confs["foo"].Input.HTTPServer.Path; exp != act {
+		t.Errorf("Wrong snapshot path: %v != %v", act, exp)
+	}
+
+	if confs, err = LoadStreamConfigsFromDirectory(true, dir); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "/secret", confs["foo"].Input.HTTPServer.Path; exp != act {
+		t.Errorf("Wrong restored path: %v != %v", act, exp)
+	}
+
+	if err = mgr.Stop(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -32,23 +32,22 @@ import (
 
 //------------------------------------------------------------------------------
 
-// LoadStreamConfigsFromDirectory reads a map of stream ids to configurations
-// by walking a directory of .json and .yaml files.
-func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string]stream.Config, error) {
-	streamMap := map[string]stream.Config{}
-
+// walkStreamConfigs calls fn with the id and path of each .json and .yaml file
+// within a directory, where the id of a stream is the path of the file relative
+// to the directory without its extension.
+func walkStreamConfigs(dir string, fn func(id, path string) error) error {
 	dir = filepath.Clean(dir)
 
 	if info, err := os.Stat(dir); err != nil {
 		if os.IsNotExist(err) {
-			return streamMap, nil
+			return nil
 		}
-		return nil, err
+		return err
 	} else if !info.IsDir() {
-		return streamMap, nil
+		return nil
 	}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, werr error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, werr error) error {
 		if werr != nil {
 			return werr
 		}
@@ -70,13 +69,21 @@ func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string
 		} else {
 			id = strings.TrimSuffix(id, ".json")
 		}
+		return fn(id, path)
+	})
+}
 
+// LoadStreamConfigsFromDirectory reads a map of stream ids to configurations
+// by walking a directory of .json and .yaml files.
+func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string]stream.Config, error) {
+	streamMap := map[string]stream.Config{}
+	err := walkStreamConfigs(dir, func(id, path string) error {
 		if _, exists := streamMap[id]; exists {
 			return fmt.Errorf("stream id (%v) collision from file: %v", id, path)
 		}
 
 		conf := config.New()
-		if _, readerr := config.Read(path, true, &conf); readerr != nil {
+		if _, readerr := config.Read(path, replaceEnvVars, &conf); readerr != nil {
 			// TODO: Read and report linting errors.
 			return readerr
 		}
@@ -84,8 +91,26 @@ func LoadStreamConfigsFromDirectory(replaceEnvVars bool, dir string) (map[string
 		streamMap[id] = conf.Config
 		return nil
 	})
-
 	return streamMap, err
 }
 
+// LoadRawStreamConfigsFromDirectory reads a map of stream ids to the contents
+// of their configuration files, prior to the replacement of environment
+// variables, by walking a directory of .json and .yaml files.
+func LoadRawStreamConfigsFromDirectory(dir string) (map[string][]byte, error) {
+	rawMap := map[string][]byte{}
+	err := walkStreamConfigs(dir, func(id, path string) error {
+		if _, exists := rawMap[id]; exists {
+			return fmt.Errorf("stream id (%v) collision from file: %v", id, path)
+		}
+		rawBytes, readerr := config.ReadRaw(path)
+		if readerr != nil {
+			return readerr
+		}
+		rawMap[id] = rawBytes
+		return nil
+	})
+	return rawMap, err
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,180 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jeffail/benthos/lib/config"
+	"github.com/Jeffail/benthos/lib/stream"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// tombstoneSuffix is the extension of files within a snapshot directory that
+// mark a stream as deleted.
+const tombstoneSuffix = ".deleted"
+
+// snapshotPath returns the path of the snapshot file of a stream.
+func (m *Type) snapshotPath(id string) (string, error) {
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("stream id '%v' cannot be used as a snapshot file name", id)
+	}
+	return filepath.Join(m.snapshotDir, id+".yaml"), nil
+}
+
+// tombstonePath returns the path of the file marking a stream as deleted.
+func (m *Type) tombstonePath(id string) (string, error) {
+	path, err := m.snapshotPath(id)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".yaml") + tombstoneSuffix, nil
+}
+
+// SetRawConfig sets the config of a stream prior to the replacement of
+// environment variables, which is used when saving a snapshot of the stream in
+// order to write environment variable references rather than their values.
+func (m *Type) SetRawConfig(id string, rawConfig []byte) {
+	m.snapshotLock.Lock()
+	if len(rawConfig) > 0 {
+		m.rawConfigs[id] = rawConfig
+	} else {
+		delete(m.rawConfigs, id)
+	}
+	m.snapshotLock.Unlock()
+}
+
+// saveSnapshot writes the config of a stream to the snapshot directory, if one
+// is configured, so that it can be restored after a restart. The file is
+// written to a temporary path and then renamed in order to avoid leaving a
+// partial config behind.
+func (m *Type) saveSnapshot(id string, conf stream.Config) {
+	if len(m.snapshotDir) == 0 {
+		return
+	}
+	if err := m.writeSnapshot(id, conf); err != nil {
+		m.logger.Errorf("Failed to save snapshot of stream '%v': %v\n", id, err)
+	}
+}
+
+func (m *Type) writeSnapshot(id string, conf stream.Config) error {
+	path, err := m.snapshotPath(id)
+	if err != nil {
+		return err
+	}
+	tombstone, err := m.tombstonePath(id)
+	if err != nil {
+		return err
+	}
+
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return err
+	}
+
+	m.snapshotLock.Lock()
+	defer m.snapshotLock.Unlock()
+
+	var confBytes []byte
+	if rawConfig, exists := m.rawConfigs[id]; exists {
+		confBytes, err = config.MarshalUnresolvedYAML(sanit, rawConfig)
+	} else {
+		confBytes, err = yaml.Marshal(sanit)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(m.snapshotDir, 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, confBytes, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if err = os.Remove(tombstone); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeSnapshot deletes the snapshot file of a stream, if one exists, and
+// writes a tombstone in its place so that a stream of the same id loaded from
+// a streams directory is not created again after a restart.
+func (m *Type) removeSnapshot(id string) {
+	m.SetRawConfig(id, nil)
+	if len(m.snapshotDir) == 0 {
+		return
+	}
+	path, err := m.snapshotPath(id)
+	if err != nil {
+		return
+	}
+	tombstone, err := m.tombstonePath(id)
+	if err != nil {
+		return
+	}
+
+	m.snapshotLock.Lock()
+	defer m.snapshotLock.Unlock()
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		m.logger.Errorf("Failed to remove snapshot of stream '%v': %v\n", id, err)
+	}
+	if err = os.MkdirAll(m.snapshotDir, 0755); err == nil {
+		err = ioutil.WriteFile(tombstone, nil, 0600)
+	}
+	if err != nil {
+		m.logger.Errorf("Failed to mark stream '%v' as deleted: %v\n", id, err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// LoadDeletedStreamsFromDirectory returns the ids of streams that were deleted
+// via the HTTP API according to the tombstones within a snapshot directory.
+func LoadDeletedStreamsFromDirectory(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), tombstoneSuffix) {
+			ids = append(ids, strings.TrimSuffix(info.Name(), tombstoneSuffix))
+		}
+	}
+	return ids, nil
+}
+
+//------------------------------------------------------------------------------
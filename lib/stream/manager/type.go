@@ -117,6 +117,11 @@ type Type struct {
 	apiTimeout time.Duration
 
 	pipelineProcCtors []StreamProcConstructorFunc
+	strictDelivery    bool
+
+	snapshotDir  string
+	snapshotLock sync.Mutex
+	rawConfigs   map[string][]byte
 
 	lock sync.Mutex
 }
@@ -125,6 +130,7 @@ type Type struct {
 func New(opts ...func(*Type)) *Type {
 	t := &Type{
 		streams:    map[string]*StreamStatus{},
+		rawConfigs: map[string][]byte{},
 		manager:    types.DudMgr{},
 		stats:      metrics.DudType{},
 		apiTimeout: time.Second * 5,
@@ -170,6 +176,26 @@ func OptSetAPITimeout(tout time.Duration) func(*Type) {
 	}
 }
 
+// OptSetSnapshotDirectory sets a directory where the configs of streams that
+// are created or updated via the HTTP API are saved, and from which they are
+// removed when the stream is deleted via the API, leaving a tombstone that is
+// read by LoadDeletedStreamsFromDirectory. The configs are saved in the same
+// format read by LoadStreamConfigsFromDirectory.
+func OptSetSnapshotDirectory(dir string) func(*Type) {
+	return func(t *Type) {
+		t.snapshotDir = dir
+	}
+}
+
+// OptSetStrictDelivery rejects stream configs sent to the HTTP API that contain
+// combinations of components that can lose messages, as reported by
+// config.LintStreamDelivery.
+func OptSetStrictDelivery(strict bool) func(*Type) {
+	return func(t *Type) {
+		t.strictDelivery = strict
+	}
+}
+
 // OptAddProcessors adds processor constructors that will be called for every
 // new stream and attached to the processor pipelines. The constructor is given
 // the name of the stream as an argument.
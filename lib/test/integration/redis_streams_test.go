@@ -57,7 +57,7 @@ func TestRedisStreamsIntegration(t *testing.T) {
 		conf := writer.NewRedisStreamsConfig()
 		conf.URL = url
 
-		r, cErr := writer.NewRedisStreams(conf, log.Noop(), metrics.Noop())
+		r, cErr := writer.NewRedisStreams(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
 		if cErr != nil {
 			return cErr
 		}
@@ -84,12 +84,15 @@ func TestRedisStreamsIntegration(t *testing.T) {
 	t.Run("TestRedisStreamsDisconnect", func(te *testing.T) {
 		testRedisStreamsDisconnect(url, te)
 	})
+	t.Run("TestRedisStreamsFields", func(te *testing.T) {
+		testRedisStreamsFields(url, te)
+	})
 }
 
 func createRedisStreamsInputOutput(
 	inConf reader.RedisStreamsConfig, outConf writer.RedisStreamsConfig,
 ) (mInput reader.Type, mOutput writer.Type, err error) {
-	if mOutput, err = writer.NewRedisStreams(outConf, log.Noop(), metrics.Noop()); err != nil {
+	if mOutput, err = writer.NewRedisStreams(outConf, types.NoopMgr(), log.Noop(), metrics.Noop()); err != nil {
 		return
 	}
 	if err = mOutput.Connect(); err != nil {
@@ -180,6 +183,76 @@ func testRedisStreamsSinglePart(url string, t *testing.T) {
 	wg.Wait()
 }
 
+func testRedisStreamsFields(url string, t *testing.T) {
+	inConf := reader.NewRedisStreamsConfig()
+	inConf.URL = url
+	inConf.Streams = []string{"benthos_test_streams_fields"}
+	inConf.StartFromOldest = false
+
+	outConf := writer.NewRedisStreamsConfig()
+	outConf.URL = url
+	outConf.Stream = "benthos_test_streams_${!metadata:suffix}"
+	outConf.Fields = map[string]string{
+		"user":    "user.id",
+		"count":   "count",
+		"missing": "does.not.exist",
+	}
+
+	mInput, err := reader.NewRedisStreams(inConf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mInput.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	mOutput, err := writer.NewRedisStreams(outConf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mOutput.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		mInput.CloseAsync()
+		if cErr := mInput.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+		mOutput.CloseAsync()
+		if cErr := mOutput.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	body := `{"user":{"id":"foo"},"count":5}`
+	msg := message.New([][]byte{[]byte(body)})
+	msg.Get(0).Metadata().Set("suffix", "fields")
+	if err = mOutput.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	actM, err := mInput.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := actM.Get(0)
+	if exp, act := body, string(part.Get()); exp != act {
+		t.Errorf("Wrong body: %v != %v", act, exp)
+	}
+	if exp, act := "foo", part.Metadata().Get("user"); exp != act {
+		t.Errorf("Wrong user field: %v != %v", act, exp)
+	}
+	if exp, act := "5", part.Metadata().Get("count"); exp != act {
+		t.Errorf("Wrong count field: %v != %v", act, exp)
+	}
+	if act := part.Metadata().Get("missing"); len(act) > 0 {
+		t.Errorf("Unexpected missing field: %v", act)
+	}
+	if err = mInput.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func testRedisStreamsMultiplePart(url string, t *testing.T) {
 	inConf := reader.NewRedisStreamsConfig()
 	inConf.URL = url
@@ -74,6 +74,7 @@ type Config struct {
 	Type   string       `json:"type" yaml:"type"`
 	Jaeger JaegerConfig `json:"jaeger" yaml:"jaeger"`
 	None   struct{}     `json:"none" yaml:"none"`
+	Plugin interface{}  `json:"plugin,omitempty" yaml:"plugin,omitempty"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -82,6 +83,7 @@ func NewConfig() Config {
 		Type:   TypeNone,
 		Jaeger: NewJaegerConfig(),
 		None:   struct{}{},
+		Plugin: nil,
 	}
 }
 
@@ -106,9 +108,20 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 		if outputMap[t], err = sfunc(conf); err != nil {
 			return nil, err
 		}
-	} else {
+	} else if _, exists := hashMap[t]; exists {
 		outputMap[t] = hashMap[t]
 	}
+	if spec, exists := pluginSpecs[t]; exists {
+		var plugSanit interface{}
+		if spec.confSanitiser != nil {
+			plugSanit = spec.confSanitiser(conf.Plugin)
+		} else {
+			plugSanit = hashMap["plugin"]
+		}
+		if plugSanit != nil {
+			outputMap["plugin"] = plugSanit
+		}
+	}
 
 	return outputMap, nil
 }
@@ -145,6 +158,21 @@ func (c *Config) UnmarshalYAML(value *yaml.Node) error {
 		aliased.Type = inferredType
 	}
 
+	if spec, exists := pluginSpecs[aliased.Type]; exists && spec.confConstructor != nil {
+		confBytes, err := yaml.Marshal(aliased.Plugin)
+		if err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+
+		conf := spec.confConstructor()
+		if err = yaml.Unmarshal(confBytes, conf); err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+		aliased.Plugin = conf
+	} else {
+		aliased.Plugin = nil
+	}
+
 	*c = Config(aliased)
 	return nil
 }
@@ -227,6 +255,16 @@ func New(conf Config, opts ...func(Type)) (Type, error) {
 	if c, ok := Constructors[conf.Type]; ok {
 		return c.constructor(conf, opts...)
 	}
+	if c, ok := pluginSpecs[conf.Type]; ok {
+		t, err := c.constructor(conf.Plugin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tracer plugin '%v': %v", conf.Type, err)
+		}
+		for _, opt := range opts {
+			opt(t)
+		}
+		return t, nil
+	}
 	return nil, ErrInvalidTracerType
 }
 
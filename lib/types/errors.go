@@ -67,6 +67,7 @@ var (
 	ErrPluginNotFound    = errors.New("plugin not found")
 	ErrKeyAlreadyExists  = errors.New("key already exists")
 	ErrKeyNotFound       = errors.New("key does not exist")
+	ErrValueMismatch     = errors.New("value does not match")
 	ErrPipeNotFound      = errors.New("pipe was not found")
 )
 
@@ -92,3 +93,18 @@ func (e ErrUnexpectedHTTPRes) Error() string {
 }
 
 //------------------------------------------------------------------------------
+
+// ErrRejected is returned as a response to an input when a message has been
+// deemed invalid and should be rejected by the source rather than retried.
+// Inputs that are able to communicate a rejection back to the source will do
+// so, e.g. with an HTTP 400 response.
+type ErrRejected struct {
+	Err error
+}
+
+// Error returns the Error string.
+func (e ErrRejected) Error() string {
+	return fmt.Sprintf("message rejected: %v", e.Err)
+}
+
+//------------------------------------------------------------------------------
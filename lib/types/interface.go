@@ -53,6 +53,38 @@ type Cache interface {
 	Closable
 }
 
+// CacheWithTTL is an optional interface implemented by caches that support
+// overriding the expiration of individual keys.
+type CacheWithTTL interface {
+	// SetWithTTL attempts to set the value of a key with an expiration that
+	// overrides the default of the cache.
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+
+	// AddWithTTL attempts to set the value of a key only if the key does not
+	// already exist, with an expiration that overrides the default of the
+	// cache.
+	AddWithTTL(key string, value []byte, ttl time.Duration) error
+}
+
+// CacheCounter is an optional interface implemented by caches that support
+// atomically incrementing integer values.
+type CacheCounter interface {
+	// Incr atomically adds delta to the integer value of a key and returns the
+	// result. A key that does not exist is treated as having the value zero.
+	// Returns an error if the existing value is not an integer.
+	Incr(key string, delta int64) (int64, error)
+}
+
+// CacheCompareAndSwapper is an optional interface implemented by caches that
+// support atomically replacing a value only when it has not changed.
+type CacheCompareAndSwapper interface {
+	// CompareAndSwap sets the value of a key only if its current value is equal
+	// to old. Returns ErrKeyNotFound if the key does not exist and
+	// ErrValueMismatch if the current value is not equal to old. A ttl of
+	// zero uses the default expiration of the cache.
+	CompareAndSwap(key string, old, value []byte, ttl time.Duration) error
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
@@ -219,4 +251,24 @@ type Response interface {
 	SkipAck() bool
 }
 
+// PreparedWrite is a write that has been staged by an output as part of a
+// transaction and must be either committed or aborted. Responses that
+// implement PreparedWrite are returned by outputs in reply to a transaction
+// with Prepare set to true.
+type PreparedWrite interface {
+	// Commit makes the staged write visible within the sink.
+	Commit() error
+
+	// Abort discards the staged write.
+	Abort() error
+}
+
+// Stager is an optional interface implemented by outputs that might be able to
+// stage writes in reply to transactions with Prepare set to true.
+type Stager interface {
+	// CanStage returns true if the output responds to transactions with
+	// Prepare set to true with a PreparedWrite instead of writing the payload.
+	CanStage() bool
+}
+
 //------------------------------------------------------------------------------
@@ -33,6 +33,11 @@ type Transaction struct {
 	// the message is no longer owned by the receiver.) The response itself
 	// indicates whether the message has been propagated successfully.
 	ResponseChan chan<- Response
+
+	// Prepare indicates that the receiver should stage the payload without
+	// committing it, if supported, and respond with a PreparedWrite that
+	// determines the outcome of the transaction.
+	Prepare bool
 }
 
 //------------------------------------------------------------------------------
@@ -46,4 +51,15 @@ func NewTransaction(payload Message, resChan chan<- Response) Transaction {
 	}
 }
 
+// NewPrepareTransaction creates a new transaction object from a message payload
+// and a response channel, where the receiver is asked to stage the message
+// rather than commit it.
+func NewPrepareTransaction(payload Message, resChan chan<- Response) Transaction {
+	return Transaction{
+		Payload:      payload,
+		ResponseChan: resChan,
+		Prepare:      true,
+	}
+}
+
 //------------------------------------------------------------------------------
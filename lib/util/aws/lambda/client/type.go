@@ -22,6 +22,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -61,6 +62,49 @@ func NewConfig() Config {
 
 //------------------------------------------------------------------------------
 
+// ErrFunction is returned when a lambda invocation reached the function but
+// the function itself reported an error. Unlike transport errors these are not
+// retried.
+type ErrFunction struct {
+	// Kind is the function error reported by AWS, either Handled or
+	// Unhandled.
+	Kind string
+
+	// Type and Message are extracted from the error payload of the function
+	// when present.
+	Type    string
+	Message string
+
+	// Payload is the raw response payload of the invocation.
+	Payload []byte
+}
+
+// Error returns the Error string.
+func (e ErrFunction) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("lambda function returned %v error: %v", e.Kind, e.Message)
+	}
+	return fmt.Sprintf("lambda function returned %v error", e.Kind)
+}
+
+func newErrFunction(kind string, payload []byte) ErrFunction {
+	e := ErrFunction{
+		Kind:    kind,
+		Payload: payload,
+	}
+	var body struct {
+		Type    string `json:"errorType"`
+		Message string `json:"errorMessage"`
+	}
+	if err := json.Unmarshal(payload, &body); err == nil {
+		e.Type = body.Type
+		e.Message = body.Message
+	}
+	return e
+}
+
+//------------------------------------------------------------------------------
+
 // Type is a client that performs lambda invocations.
 type Type struct {
 	lambda *lambda.Lambda
@@ -75,6 +119,7 @@ type Type struct {
 
 	mCount    metrics.StatCounter
 	mErr      metrics.StatCounter
+	mErrFunc  metrics.StatCounter
 	mSucc     metrics.StatCounter
 	mLimited  metrics.StatCounter
 	mLimitFor metrics.StatCounter
@@ -109,6 +154,7 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	l.mCount = l.stats.GetCounter("count")
 	l.mSucc = l.stats.GetCounter("success")
 	l.mErr = l.stats.GetCounter("error")
+	l.mErrFunc = l.stats.GetCounter("error.function")
 	l.mLimited = l.stats.GetCounter("rate_limit.count")
 	l.mLimitFor = l.stats.GetCounter("rate_limit.total_ms")
 	l.mLimitErr = l.stats.GetCounter("rate_limit.error")
@@ -178,7 +224,10 @@ func (l *Type) waitForAccess() bool {
 	}
 }
 
-// Invoke attempts to invoke lambda function with a message as its payload.
+// Invoke attempts to invoke lambda function with each part of a message as its
+// payload. Transport errors are retried according to the configured number of
+// retries, whereas errors reported by the function are returned immediately as
+// an ErrFunction.
 func (l *Type) Invoke(msg types.Message) (types.Message, error) {
 	l.mCount.Incr(1)
 	response := msg.Copy()
@@ -198,6 +247,15 @@ func (l *Type) Invoke(msg types.Message) (types.Message, error) {
 			})
 			done()
 
+			if err == nil && result.FunctionError != nil {
+				l.mErrFunc.Incr(1)
+				fErr := newErrFunction(*result.FunctionError, result.Payload)
+				s.LogFields(
+					olog.String("event", "error"),
+					olog.String("type", fErr.Error()),
+				)
+				return fErr
+			}
 			if err == nil {
 				l.mSucc.Incr(1)
 				response.Get(i).Set(result.Payload)
@@ -0,0 +1,135 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+//------------------------------------------------------------------------------
+
+// Change describes a single difference between two configs at a dot separated
+// path, where an op of "added" or "removed" indicates that the path only exists
+// in one of the configs.
+type Change struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// normalise converts a config structure into its generic JSON representation
+// so that structures of differing types can be compared.
+func normalise(v interface{}) (interface{}, error) {
+	jBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var gen interface{}
+	if err = json.Unmarshal(jBytes, &gen); err != nil {
+		return nil, err
+	}
+	return gen, nil
+}
+
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func diffWalk(path string, from, to interface{}, changes []Change) []Change {
+	switch fromT := from.(type) {
+	case map[string]interface{}:
+		toT, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]struct{}{}
+		for k := range fromT {
+			keys[k] = struct{}{}
+		}
+		for k := range toT {
+			keys[k] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+		for _, k := range sortedKeys {
+			fromV, fromExists := fromT[k]
+			toV, toExists := toT[k]
+			switch {
+			case !toExists:
+				changes = append(changes, Change{Path: joinPath(path, k), Op: "removed", From: fromV})
+			case !fromExists:
+				changes = append(changes, Change{Path: joinPath(path, k), Op: "added", To: toV})
+			default:
+				changes = diffWalk(joinPath(path, k), fromV, toV, changes)
+			}
+		}
+		return changes
+	case []interface{}:
+		toT, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(fromT) || i < len(toT); i++ {
+			iPath := joinPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(toT):
+				changes = append(changes, Change{Path: iPath, Op: "removed", From: fromT[i]})
+			case i >= len(fromT):
+				changes = append(changes, Change{Path: iPath, Op: "added", To: toT[i]})
+			default:
+				changes = diffWalk(iPath, fromT[i], toT[i], changes)
+			}
+		}
+		return changes
+	}
+	if !reflect.DeepEqual(from, to) {
+		changes = append(changes, Change{Path: path, Op: "changed", From: from, To: to})
+	}
+	return changes
+}
+
+// Diff returns the list of changes required to turn one config structure into
+// another. Both structures are compared using their JSON representations, and
+// are therefore usually sanitised configs.
+func Diff(from, to interface{}) ([]Change, error) {
+	genFrom, err := normalise(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise config: %v", err)
+	}
+	genTo, err := normalise(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalise candidate config: %v", err)
+	}
+	return diffWalk("", genFrom, genTo, []Change{}), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{
+		"input": Sanitised{
+			"type": "kafka",
+			"kafka": map[string]interface{}{
+				"addresses": []string{"foo:9092", "bar:9092"},
+				"topic":     "foo",
+			},
+		},
+		"removed": "value",
+		"same":    10,
+	}
+	to := map[string]interface{}{
+		"input": Sanitised{
+			"type": "kafka",
+			"kafka": map[string]interface{}{
+				"addresses": []string{"foo:9092"},
+				"topic":     "bar",
+			},
+		},
+		"added": []string{"a"},
+		"same":  10,
+	}
+
+	changes, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []Change{
+		{Path: "added", Op: "added", To: []interface{}{"a"}},
+		{Path: "input.kafka.addresses.1", Op: "removed", From: "bar:9092"},
+		{Path: "input.kafka.topic", Op: "changed", From: "foo", To: "bar"},
+		{Path: "removed", Op: "removed", From: "value"},
+	}
+	if !reflect.DeepEqual(exp, changes) {
+		t.Errorf("Wrong changes: %v != %v", changes, exp)
+	}
+}
+
+func TestDiffTypeChange(t *testing.T) {
+	changes, err := Diff(
+		map[string]interface{}{"foo": map[string]interface{}{"bar": 1}},
+		map[string]interface{}{"foo": "bar"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Change{
+		{Path: "foo", Op: "changed", From: map[string]interface{}{"bar": float64(1)}, To: "bar"},
+	}
+	if !reflect.DeepEqual(exp, changes) {
+		t.Errorf("Wrong changes: %v != %v", changes, exp)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	changes, err := Diff(Sanitised{"type": "foo"}, Sanitised{"type": "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Unexpected changes: %v", changes)
+	}
+}
@@ -22,6 +22,8 @@ package client
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
 	"mime"
@@ -40,6 +42,7 @@ import (
 	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/Jeffail/benthos/lib/util/throttle"
 	"github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/klauspost/compress/zstd"
 	"github.com/opentracing/opentracing-go"
 	olog "github.com/opentracing/opentracing-go/log"
 )
@@ -48,18 +51,21 @@ import (
 
 // Config is a configuration struct for an HTTP client.
 type Config struct {
-	URL         string            `json:"url" yaml:"url"`
-	Verb        string            `json:"verb" yaml:"verb"`
-	Headers     map[string]string `json:"headers" yaml:"headers"`
-	RateLimit   string            `json:"rate_limit" yaml:"rate_limit"`
-	Timeout     string            `json:"timeout" yaml:"timeout"`
-	Retry       string            `json:"retry_period" yaml:"retry_period"`
-	MaxBackoff  string            `json:"max_retry_backoff" yaml:"max_retry_backoff"`
-	NumRetries  int               `json:"retries" yaml:"retries"`
-	BackoffOn   []int             `json:"backoff_on" yaml:"backoff_on"`
-	DropOn      []int             `json:"drop_on" yaml:"drop_on"`
-	TLS         tls.Config        `json:"tls" yaml:"tls"`
-	auth.Config `json:",inline" yaml:",inline"`
+	URL          string            `json:"url" yaml:"url"`
+	Verb         string            `json:"verb" yaml:"verb"`
+	Headers      map[string]string `json:"headers" yaml:"headers"`
+	RateLimit    string            `json:"rate_limit" yaml:"rate_limit"`
+	Timeout      string            `json:"timeout" yaml:"timeout"`
+	Retry        string            `json:"retry_period" yaml:"retry_period"`
+	MaxBackoff   string            `json:"max_retry_backoff" yaml:"max_retry_backoff"`
+	NumRetries   int               `json:"retries" yaml:"retries"`
+	BackoffOn    []int             `json:"backoff_on" yaml:"backoff_on"`
+	DropOn       []int             `json:"drop_on" yaml:"drop_on"`
+	RetryOn      []int             `json:"retry_on" yaml:"retry_on"`
+	SuccessfulOn []int             `json:"successful_on" yaml:"successful_on"`
+	Compression  string            `json:"compression" yaml:"compression"`
+	TLS          tls.Config        `json:"tls" yaml:"tls"`
+	auth.Config  `json:",inline" yaml:",inline"`
 }
 
 // NewConfig creates a new Config with default values.
@@ -70,15 +76,18 @@ func NewConfig() Config {
 		Headers: map[string]string{
 			"Content-Type": "application/octet-stream",
 		},
-		RateLimit:  "",
-		Timeout:    "5s",
-		Retry:      "1s",
-		MaxBackoff: "300s",
-		NumRetries: 3,
-		BackoffOn:  []int{429},
-		DropOn:     []int{},
-		TLS:        tls.NewConfig(),
-		Config:     auth.NewConfig(),
+		RateLimit:    "",
+		Timeout:      "5s",
+		Retry:        "1s",
+		MaxBackoff:   "300s",
+		NumRetries:   3,
+		BackoffOn:    []int{429},
+		DropOn:       []int{},
+		RetryOn:      []int{},
+		SuccessfulOn: []int{},
+		Compression:  "none",
+		TLS:          tls.NewConfig(),
+		Config:       auth.NewConfig(),
 	}
 }
 
@@ -88,12 +97,17 @@ func NewConfig() Config {
 type Type struct {
 	client http.Client
 
-	backoffOn map[int]struct{}
-	dropOn    map[int]struct{}
+	backoffOn    map[int]struct{}
+	dropOn       map[int]struct{}
+	retryOn      map[int]struct{}
+	successfulOn map[int]struct{}
 
 	url     *text.InterpolatedString
 	headers map[string]*text.InterpolatedString
 	host    *text.InterpolatedString
+	encoder encodeFunc
+
+	multipart []multipartPart
 
 	conf          Config
 	retryThrottle *throttle.Type
@@ -122,15 +136,17 @@ type Type struct {
 // New creates a new Type.
 func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	h := Type{
-		url:       text.NewInterpolatedString(conf.URL),
-		conf:      conf,
-		log:       log.Noop(),
-		stats:     metrics.Noop(),
-		mgr:       types.NoopMgr(),
-		backoffOn: map[int]struct{}{},
-		dropOn:    map[int]struct{}{},
-		headers:   map[string]*text.InterpolatedString{},
-		host:      nil,
+		url:          text.NewInterpolatedString(conf.URL),
+		conf:         conf,
+		log:          log.Noop(),
+		stats:        metrics.Noop(),
+		mgr:          types.NoopMgr(),
+		backoffOn:    map[int]struct{}{},
+		dropOn:       map[int]struct{}{},
+		retryOn:      map[int]struct{}{},
+		successfulOn: map[int]struct{}{},
+		headers:      map[string]*text.InterpolatedString{},
+		host:         nil,
 	}
 
 	if tout := conf.Timeout; len(tout) > 0 {
@@ -150,12 +166,30 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		}
 	}
 
+	switch conf.Compression {
+	case "none", "":
+	case "gzip":
+		h.encoder = gzipEncode
+	case "deflate":
+		h.encoder = deflateEncode
+	case "zstd":
+		h.encoder = zstdEncode
+	default:
+		return nil, fmt.Errorf("compression type not recognised: %v", conf.Compression)
+	}
+
 	for _, c := range conf.BackoffOn {
 		h.backoffOn[c] = struct{}{}
 	}
 	for _, c := range conf.DropOn {
 		h.dropOn[c] = struct{}{}
 	}
+	for _, c := range conf.RetryOn {
+		h.retryOn[c] = struct{}{}
+	}
+	for _, c := range conf.SuccessfulOn {
+		h.successfulOn[c] = struct{}{}
+	}
 
 	for k, v := range conf.Headers {
 		if strings.ToLower(k) == "host" {
@@ -242,6 +276,35 @@ func OptSetManager(mgr types.Manager) func(*Type) {
 	}
 }
 
+// MultipartExpression describes a part of a multipart request body, where each
+// field is an interpolated string resolved against the message being sent.
+type MultipartExpression struct {
+	ContentDisposition string `json:"content_disposition" yaml:"content_disposition"`
+	ContentType        string `json:"content_type" yaml:"content_type"`
+	Body               string `json:"body" yaml:"body"`
+}
+
+type multipartPart struct {
+	contentDisposition *text.InterpolatedString
+	contentType        *text.InterpolatedString
+	body               *text.InterpolatedString
+}
+
+// OptSetMultipart sets a list of expressions used to construct a multipart
+// request body for each message sent, instead of a part per message part.
+func OptSetMultipart(parts []MultipartExpression) func(*Type) {
+	return func(t *Type) {
+		t.multipart = make([]multipartPart, len(parts))
+		for i, p := range parts {
+			t.multipart[i] = multipartPart{
+				contentDisposition: text.NewInterpolatedString(p.ContentDisposition),
+				contentType:        text.NewInterpolatedString(p.ContentType),
+				body:               text.NewInterpolatedString(p.Body),
+			}
+		}
+	}
+}
+
 // OptSetHTTPTransport sets the HTTP Transport to use. NOTE: This setting will
 // override any configured TLS options.
 func OptSetHTTPTransport(transport *http.Transport) func(*Type) {
@@ -297,6 +360,58 @@ func (h *Type) waitForAccess() bool {
 	}
 }
 
+type encodeFunc func(b []byte) ([]byte, error)
+
+func gzipEncode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deflateEncode produces zlib wrapped data, which is what the deflate content
+// coding refers to as per RFC 7230.
+func deflateEncode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var zstdEncoder *zstd.Encoder
+var zstdEncoderErr error
+var zstdEncoderOnce sync.Once
+
+func zstdEncode(b []byte) ([]byte, error) {
+	// An encoder is safe to share for EncodeAll calls.
+	zstdEncoderOnce.Do(func() {
+		zstdEncoder, zstdEncoderErr = zstd.NewWriter(nil)
+	})
+	if zstdEncoderErr != nil {
+		return nil, zstdEncoderErr
+	}
+	return zstdEncoder.EncodeAll(b, nil), nil
+}
+
+// encodeBody compresses a request body when a compression algorithm is
+// configured.
+func (h *Type) encodeBody(b []byte) ([]byte, error) {
+	if h.encoder == nil {
+		return b, nil
+	}
+	return h.encoder(b)
+}
+
 // CreateRequest creates an HTTP request out of a single message.
 func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 	url := h.url.Get(msg)
@@ -310,9 +425,12 @@ func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 				req.Host = h.host.Get(msg)
 			}
 		}
-	} else if msg.Len() == 1 {
+	} else if msg.Len() == 1 && len(h.multipart) == 0 {
 		var body io.Reader
 		if msgBytes := msg.Get(0).Get(); len(msgBytes) > 0 {
+			if msgBytes, err = h.encodeBody(msgBytes); err != nil {
+				return
+			}
 			body = bytes.NewBuffer(msgBytes)
 		}
 		if req, err = http.NewRequest(h.conf.Verb, url, body); err == nil {
@@ -322,12 +440,28 @@ func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 			if h.host != nil {
 				req.Host = h.host.Get(msg)
 			}
+			if body != nil && h.encoder != nil {
+				req.Header.Set("Content-Encoding", h.conf.Compression)
+			}
 		}
 	} else {
 		body := &bytes.Buffer{}
 		writer := multipart.NewWriter(body)
 
-		for i := 0; i < msg.Len() && err == nil; i++ {
+		for i := 0; i < len(h.multipart) && err == nil; i++ {
+			header := textproto.MIMEHeader{}
+			if contentType := h.multipart[i].contentType.Get(msg); len(contentType) > 0 {
+				header.Set("Content-Type", contentType)
+			}
+			if disposition := h.multipart[i].contentDisposition.Get(msg); len(disposition) > 0 {
+				header.Set("Content-Disposition", disposition)
+			}
+			var part io.Writer
+			if part, err = writer.CreatePart(header); err == nil {
+				_, err = io.WriteString(part, h.multipart[i].body.Get(msg))
+			}
+		}
+		for i := 0; len(h.multipart) == 0 && i < msg.Len() && err == nil; i++ {
 			contentType := "application/octet-stream"
 			if v, exists := h.headers["Content-Type"]; exists {
 				contentType = v.Get(msg)
@@ -341,8 +475,12 @@ func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 		}
 
 		writer.Close()
+		var bodyBytes []byte
 		if err == nil {
-			if req, err = http.NewRequest(h.conf.Verb, url, body); err == nil {
+			bodyBytes, err = h.encodeBody(body.Bytes())
+		}
+		if err == nil {
+			if req, err = http.NewRequest(h.conf.Verb, url, bytes.NewReader(bodyBytes)); err == nil {
 				for k, v := range h.headers {
 					req.Header.Add(k, v.Get(msg))
 				}
@@ -351,6 +489,9 @@ func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 				}
 				req.Header.Del("Content-Type")
 				req.Header.Add("Content-Type", writer.FormDataContentType())
+				if h.encoder != nil {
+					req.Header.Set("Content-Encoding", h.conf.Compression)
+				}
 			}
 		}
 	}
@@ -436,6 +577,9 @@ const (
 // determining whether the send succeeded, and if not what the retry strategy
 // should be.
 func (h *Type) checkStatus(code int) (succeeded bool, retStrat retryStrategy) {
+	if _, exists := h.successfulOn[code]; exists {
+		return true, noRetry
+	}
 	if _, exists := h.dropOn[code]; exists {
 		return false, noRetry
 	}
@@ -443,6 +587,11 @@ func (h *Type) checkStatus(code int) (succeeded bool, retStrat retryStrategy) {
 		return false, retryBackoff
 	}
 	if code < 200 || code > 299 {
+		if len(h.retryOn) > 0 {
+			if _, exists := h.retryOn[code]; !exists {
+				return false, noRetry
+			}
+		}
 		return false, retryLinear
 	}
 	return true, noRetry
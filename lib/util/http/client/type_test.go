@@ -22,6 +22,8 @@ package client
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -39,6 +41,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/klauspost/compress/zstd"
 )
 
 //------------------------------------------------------------------------------
@@ -71,6 +74,68 @@ func TestHTTPClientRetries(t *testing.T) {
 	}
 }
 
+func TestHTTPClientRetryOn(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) == 1 {
+			http.Error(w, "test error", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "test error", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.NumRetries = 3
+	conf.RetryOn = []int{503}
+
+	h, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Send(message.New([][]byte{[]byte("test")})); err == nil {
+		t.Error("Expected error from unlisted status code")
+	}
+
+	if exp, act := uint32(2), atomic.LoadUint32(&reqCount); exp != act {
+		t.Errorf("Wrong count of HTTP attempts: %v != %v", exp, act)
+	}
+}
+
+func TestHTTPClientSuccessfulOn(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("already exists"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.SuccessfulOn = []int{409}
+
+	h, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resMsg, err := h.Send(message.New([][]byte{[]byte("test")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "already exists", string(resMsg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong response: %v != %v", act, exp)
+	}
+	if exp, act := uint32(1), atomic.LoadUint32(&reqCount); exp != act {
+		t.Errorf("Wrong count of HTTP attempts: %v != %v", exp, act)
+	}
+}
+
 func TestHTTPClientBadRequest(t *testing.T) {
 	conf := NewConfig()
 	conf.URL = "htp://notvalid:1111"
@@ -139,6 +204,74 @@ func TestHTTPClientSendBasic(t *testing.T) {
 	}
 }
 
+func TestHTTPClientSendCompressed(t *testing.T) {
+	tests := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		"deflate": func(r io.Reader) (io.Reader, error) {
+			return zlib.NewReader(r)
+		},
+		"zstd": func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		},
+	}
+
+	for algo, decoder := range tests {
+		t.Run(algo, func(t *testing.T) {
+			resultChan := make(chan string, 1)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if exp, act := algo, r.Header.Get("Content-Encoding"); exp != act {
+					t.Errorf("Wrong content encoding: %v != %v", act, exp)
+				}
+				dr, err := decoder(r.Body)
+				if err != nil {
+					t.Error(err)
+					resultChan <- ""
+					return
+				}
+				b, err := ioutil.ReadAll(dr)
+				if err != nil {
+					t.Error(err)
+				}
+				resultChan <- string(b)
+			}))
+			defer ts.Close()
+
+			conf := NewConfig()
+			conf.URL = ts.URL + "/testpost"
+			conf.Compression = algo
+
+			h, err := New(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = h.Send(message.New([][]byte{[]byte("hello world")})); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case res := <-resultChan:
+				if exp, act := "hello world", res; exp != act {
+					t.Errorf("Wrong result: %v != %v", act, exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Action timed out")
+			}
+		})
+	}
+}
+
+func TestHTTPClientBadCompression(t *testing.T) {
+	conf := NewConfig()
+	conf.Compression = "nope"
+
+	if _, err := New(conf); err == nil {
+		t.Error("Expected error from bad compression type")
+	}
+}
+
 func TestHTTPClientDropOn(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
@@ -322,6 +455,95 @@ func TestHTTPClientSendMultipart(t *testing.T) {
 	}
 }
 
+func TestHTTPClientSendMultipartExpressions(t *testing.T) {
+	type part struct {
+		header textproto.MIMEHeader
+		body   string
+	}
+	resultChan := make(chan []part, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var parts []part
+		defer func() {
+			resultChan <- parts
+		}()
+
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Errorf("Bad media type: %v -> %v", r.Header.Get("Content-Type"), err)
+			return
+		}
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			t.Errorf("Expected multipart media type, received: %v", mediaType)
+			return
+		}
+
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, err := ioutil.ReadAll(p)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			parts = append(parts, part{header: p.Header, body: string(b)})
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+
+	h, err := New(conf, OptSetMultipart([]MultipartExpression{
+		{
+			ContentType:        "application/json",
+			ContentDisposition: `form-data; name="meta"`,
+			Body:               `{"name":"${!metadata:name}"}`,
+		},
+		{
+			ContentType:        "text/plain",
+			ContentDisposition: `form-data; name="file"; filename="${!metadata:name}"`,
+			Body:               "${!content}",
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{[]byte("hello world")})
+	testMsg.Get(0).Metadata().Set("name", "foo.txt")
+	if _, err := h.Send(testMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case parts := <-resultChan:
+		if exp, act := 2, len(parts); exp != act {
+			t.Fatalf("Wrong # parts: %v != %v", act, exp)
+		}
+		if exp, act := `{"name":"foo.txt"}`, parts[0].body; exp != act {
+			t.Errorf("Wrong first part body: %v != %v", act, exp)
+		}
+		if exp, act := "application/json", parts[0].header.Get("Content-Type"); exp != act {
+			t.Errorf("Wrong first part content type: %v != %v", act, exp)
+		}
+		if exp, act := "hello world", parts[1].body; exp != act {
+			t.Errorf("Wrong second part body: %v != %v", act, exp)
+		}
+		if exp, act := `form-data; name="file"; filename="foo.txt"`, parts[1].header.Get("Content-Disposition"); exp != act {
+			t.Errorf("Wrong second part content disposition: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Action timed out")
+	}
+}
+
 func TestHTTPClientReceiveMultipart(t *testing.T) {
 	nTestLoops := 1000
 
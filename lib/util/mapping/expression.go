@@ -0,0 +1,324 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// deleteValue is returned by the deleted() function and removes the target of
+// an assignment.
+type deleteValue struct{}
+
+// nothingValue is returned by an if expression where no branch matched and
+// results in the assignment being skipped.
+type nothingValue struct{}
+
+// execContext provides the expressions of a mapping access to the message part
+// being mapped.
+type execContext struct {
+	part types.Part
+}
+
+type expr interface {
+	exec(ctx *execContext) (interface{}, error)
+}
+
+//------------------------------------------------------------------------------
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (l *literalExpr) exec(ctx *execContext) (interface{}, error) {
+	return l.value, nil
+}
+
+type thisExpr struct{}
+
+func (thisExpr) exec(ctx *execContext) (interface{}, error) {
+	v, err := ctx.part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+	return v, nil
+}
+
+type fieldExpr struct {
+	target expr
+	field  string
+}
+
+func (f *fieldExpr) exec(ctx *execContext) (interface{}, error) {
+	v, err := f.target.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return t[f.field], nil
+	case []interface{}:
+		i, err := strconv.Atoi(f.field)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access field '%v' of an array", f.field)
+		}
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+		return t[i], nil
+	}
+	return nil, fmt.Errorf("cannot access field '%v' of %v", f.field, typeName(v))
+}
+
+//------------------------------------------------------------------------------
+
+type unaryExpr struct {
+	op      string
+	operand expr
+}
+
+func (u *unaryExpr) exec(ctx *execContext) (interface{}, error) {
+	v, err := u.operand.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if u.op == "!" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %v", typeName(v))
+		}
+		return !b, nil
+	}
+	n, err := toNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return -n, nil
+}
+
+type binaryExpr struct {
+	op       string
+	lhs, rhs expr
+}
+
+func (b *binaryExpr) exec(ctx *execContext) (interface{}, error) {
+	l, err := b.lhs.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.op == "&&" || b.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v' expected boolean, got %v", b.op, typeName(l))
+		}
+		if (b.op == "&&" && !lb) || (b.op == "||" && lb) {
+			return lb, nil
+		}
+		r, err := b.rhs.exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v' expected boolean, got %v", b.op, typeName(r))
+		}
+		return rb, nil
+	}
+
+	r, err := b.rhs.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case "==":
+		return reflect.DeepEqual(l, r), nil
+	case "!=":
+		return !reflect.DeepEqual(l, r), nil
+	}
+
+	if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator '%v' cannot be applied to string and %v", b.op, typeName(r))
+		}
+		switch b.op {
+		case "+":
+			return ls + rs, nil
+		case ">":
+			return ls > rs, nil
+		case ">=":
+			return ls >= rs, nil
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		}
+		return nil, fmt.Errorf("operator '%v' cannot be applied to strings", b.op)
+	}
+
+	ln, err := toNumber(l)
+	if err != nil {
+		return nil, fmt.Errorf("operator '%v': %v", b.op, err)
+	}
+	rn, err := toNumber(r)
+	if err != nil {
+		return nil, fmt.Errorf("operator '%v': %v", b.op, err)
+	}
+	switch b.op {
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/":
+		if rn == 0 {
+			return nil, errors.New("attempted to divide by zero")
+		}
+		return ln / rn, nil
+	case "%":
+		if rn == 0 {
+			return nil, errors.New("attempted to divide by zero")
+		}
+		return math.Mod(ln, rn), nil
+	case ">":
+		return ln > rn, nil
+	case ">=":
+		return ln >= rn, nil
+	case "<":
+		return ln < rn, nil
+	case "<=":
+		return ln <= rn, nil
+	}
+	return nil, fmt.Errorf("unknown operator '%v'", b.op)
+}
+
+//------------------------------------------------------------------------------
+
+type ifExpr struct {
+	conds     []expr
+	values    []expr
+	otherwise expr
+}
+
+func (i *ifExpr) exec(ctx *execContext) (interface{}, error) {
+	for j, cond := range i.conds {
+		v, err := cond.exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("if condition expected boolean, got %v", typeName(v))
+		}
+		if b {
+			return i.values[j].exec(ctx)
+		}
+	}
+	if i.otherwise != nil {
+		return i.otherwise.exec(ctx)
+	}
+	return nothingValue{}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func toNumber(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	}
+	return 0, fmt.Errorf("expected number, got %v", typeName(v))
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, int, int64, json.Number:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case deleteValue:
+		return "deleted"
+	case nothingValue:
+		return "nothing"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// clone deep copies objects and arrays so that assignments to the new document
+// never modify the structure of the original.
+func clone(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			c[k] = clone(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, e := range t {
+			c[i] = clone(e)
+		}
+		return c
+	}
+	return v
+}
+
+//------------------------------------------------------------------------------
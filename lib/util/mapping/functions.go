@@ -0,0 +1,294 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+type function struct {
+	arity int
+	fn    func(ctx *execContext, args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"content": {0, func(ctx *execContext, args []interface{}) (interface{}, error) {
+		return string(ctx.part.Get()), nil
+	}},
+	"deleted": {0, func(ctx *execContext, args []interface{}) (interface{}, error) {
+		return deleteValue{}, nil
+	}},
+	"meta": {1, func(ctx *execContext, args []interface{}) (interface{}, error) {
+		key, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("meta expected string key, got %v", typeName(args[0]))
+		}
+		var value interface{}
+		ctx.part.Metadata().Iter(func(k, v string) error {
+			if k == key {
+				value = v
+			}
+			return nil
+		})
+		return value, nil
+	}},
+	"timestamp_unix": {0, func(ctx *execContext, args []interface{}) (interface{}, error) {
+		return float64(time.Now().Unix()), nil
+	}},
+}
+
+type functionExpr struct {
+	name string
+	fn   function
+	args []expr
+}
+
+func newFunction(t token, args []expr) (expr, error) {
+	fn, exists := functions[t.val]
+	if !exists {
+		return nil, fmt.Errorf("line %v: unrecognised function '%v'", t.line, t.val)
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("line %v: function '%v' expects %v arguments, got %v", t.line, t.val, fn.arity, len(args))
+	}
+	return &functionExpr{name: t.val, fn: fn, args: args}, nil
+}
+
+func (f *functionExpr) exec(ctx *execContext) (interface{}, error) {
+	args, err := execArgs(ctx, f.args)
+	if err != nil {
+		return nil, err
+	}
+	return f.fn.fn(ctx, args)
+}
+
+//------------------------------------------------------------------------------
+
+type method struct {
+	arity int
+	fn    func(v interface{}, args []interface{}) (interface{}, error)
+}
+
+func stringMethod(fn func(s string) interface{}) method {
+	return method{0, func(v interface{}, args []interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %v", typeName(v))
+		}
+		return fn(s), nil
+	}}
+}
+
+func stringArgs(args []interface{}) ([]string, error) {
+	strs := make([]string, len(args))
+	for i, a := range args {
+		s, ok := a.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string argument, got %v", typeName(a))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+var methods = map[string]method{
+	"contains": {1, func(v interface{}, args []interface{}) (interface{}, error) {
+		switch t := v.(type) {
+		case string:
+			sub, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string argument, got %v", typeName(args[0]))
+			}
+			return strings.Contains(t, sub), nil
+		case []interface{}:
+			for _, e := range t {
+				if reflect.DeepEqual(e, args[0]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return nil, fmt.Errorf("expected string or array, got %v", typeName(v))
+	}},
+	"join": {1, func(v interface{}, args []interface{}) (interface{}, error) {
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array, got %v", typeName(v))
+		}
+		delim, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		strs := make([]string, len(arr))
+		for i, e := range arr {
+			strs[i] = toString(e)
+		}
+		return strings.Join(strs, delim[0]), nil
+	}},
+	"length": {0, func(v interface{}, args []interface{}) (interface{}, error) {
+		switch t := v.(type) {
+		case string:
+			return float64(len(t)), nil
+		case []interface{}:
+			return float64(len(t)), nil
+		case map[string]interface{}:
+			return float64(len(t)), nil
+		}
+		return nil, fmt.Errorf("expected string, array or object, got %v", typeName(v))
+	}},
+	"lowercase": stringMethod(func(s string) interface{} { return strings.ToLower(s) }),
+	"number": {0, func(v interface{}, args []interface{}) (interface{}, error) {
+		if s, ok := v.(string); ok {
+			return strconv.ParseFloat(strings.TrimSpace(s), 64)
+		}
+		return toNumber(v)
+	}},
+	"parse_json": {0, func(v interface{}, args []interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %v", typeName(v))
+		}
+		var result interface{}
+		if err := json.Unmarshal([]byte(s), &result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}},
+	"replace": {2, func(v interface{}, args []interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %v", typeName(v))
+		}
+		strs, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Replace(s, strs[0], strs[1], -1), nil
+	}},
+	"round": {0, func(v interface{}, args []interface{}) (interface{}, error) {
+		n, err := toNumber(v)
+		if err != nil {
+			return nil, err
+		}
+		return math.Round(n), nil
+	}},
+	"split": {1, func(v interface{}, args []interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %v", typeName(v))
+		}
+		delim, err := stringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(s, delim[0])
+		arr := make([]interface{}, len(parts))
+		for i, p := range parts {
+			arr[i] = p
+		}
+		return arr, nil
+	}},
+	"string": {0, func(v interface{}, args []interface{}) (interface{}, error) {
+		return toString(v), nil
+	}},
+	"trim":      stringMethod(func(s string) interface{} { return strings.TrimSpace(s) }),
+	"uppercase": stringMethod(func(s string) interface{} { return strings.ToUpper(s) }),
+}
+
+type methodExpr struct {
+	name   string
+	method method
+	target expr
+	args   []expr
+}
+
+func newMethod(t token, target expr, args []expr) (expr, error) {
+	if t.val == "or" {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("line %v: method 'or' expects 1 argument, got %v", t.line, len(args))
+		}
+		return &orExpr{target: target, fallback: args[0]}, nil
+	}
+	m, exists := methods[t.val]
+	if !exists {
+		return nil, fmt.Errorf("line %v: unrecognised method '%v'", t.line, t.val)
+	}
+	if len(args) != m.arity {
+		return nil, fmt.Errorf("line %v: method '%v' expects %v arguments, got %v", t.line, t.val, m.arity, len(args))
+	}
+	return &methodExpr{name: t.val, method: m, target: target, args: args}, nil
+}
+
+func (m *methodExpr) exec(ctx *execContext) (interface{}, error) {
+	v, err := m.target.exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args, err := execArgs(ctx, m.args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.method.fn(v, args)
+	if err != nil {
+		return nil, fmt.Errorf("method '%v': %v", m.name, err)
+	}
+	return res, nil
+}
+
+// orExpr returns the result of its target unless it is null or fails, in which
+// case the fallback is returned instead.
+type orExpr struct {
+	target   expr
+	fallback expr
+}
+
+func (o *orExpr) exec(ctx *execContext) (interface{}, error) {
+	if v, err := o.target.exec(ctx); err == nil && v != nil {
+		return v, nil
+	}
+	return o.fallback.exec(ctx)
+}
+
+func execArgs(ctx *execContext, exprs []expr) ([]interface{}, error) {
+	args := make([]interface{}, len(exprs))
+	for i, e := range exprs {
+		v, err := e.exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, isDelete := v.(deleteValue); isDelete {
+			return nil, errors.New("deleted() cannot be used as an argument")
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mapping implements a small language for restructuring JSON documents
+// and metadata of message parts.
+package mapping
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrNoStatements is returned when attempting to parse an empty mapping.
+var ErrNoStatements = errors.New("mapping contains no statements")
+
+type statement struct {
+	line    int
+	path    []string
+	meta    bool
+	metaKey string
+	value   expr
+}
+
+// Mapping is a parsed list of assignments that can be executed against message
+// parts.
+type Mapping struct {
+	statements []statement
+}
+
+// New parses a mapping and returns it or an error if the mapping is invalid.
+func New(mapping string) (*Mapping, error) {
+	toks, err := lex(mapping)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{toks: toks}
+	stmts, err := p.parseStatements()
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) == 0 {
+		return nil, ErrNoStatements
+	}
+	return &Mapping{statements: stmts}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Exec executes the mapping against a message part, replacing its contents and
+// metadata with the result. If any statement fails the part is left unchanged
+// and an error is returned.
+//
+// The contents of the part are only replaced when the mapping assigns to root,
+// therefore a mapping consisting only of metadata assignments leaves the
+// payload untouched.
+func (m *Mapping) Exec(part types.Part) error {
+	ctx := &execContext{part: part}
+	meta := part.Metadata().Copy()
+
+	var root interface{} = nothingValue{}
+	rootSet := false
+
+	for _, stmt := range m.statements {
+		v, err := stmt.value.exec(ctx)
+		if err != nil {
+			return fmt.Errorf("line %v: %v", stmt.line, err)
+		}
+		if _, skip := v.(nothingValue); skip {
+			continue
+		}
+		_, isDelete := v.(deleteValue)
+
+		if stmt.meta {
+			if isDelete {
+				meta.Delete(stmt.metaKey)
+			} else {
+				meta.Set(stmt.metaKey, toString(v))
+			}
+			continue
+		}
+
+		if len(stmt.path) == 0 {
+			if isDelete {
+				return fmt.Errorf("line %v: root cannot be deleted", stmt.line)
+			}
+			root, rootSet = clone(v), true
+			continue
+		}
+
+		if _, isObj := root.(map[string]interface{}); !isObj {
+			if rootSet {
+				return fmt.Errorf("line %v: cannot assign field of root %v", stmt.line, typeName(root))
+			}
+			root = map[string]interface{}{}
+		}
+		rootSet = true
+		if err = setPath(root.(map[string]interface{}), stmt.path, clone(v), isDelete); err != nil {
+			return fmt.Errorf("line %v: %v", stmt.line, err)
+		}
+	}
+
+	if rootSet {
+		switch t := root.(type) {
+		case string:
+			part.Set([]byte(t))
+		default:
+			if err := part.SetJSON(t); err != nil {
+				return err
+			}
+		}
+	}
+	part.SetMetadata(meta)
+	return nil
+}
+
+func setPath(obj map[string]interface{}, path []string, value interface{}, del bool) error {
+	for i, key := range path[:len(path)-1] {
+		next, exists := obj[key]
+		if !exists || next == nil {
+			if del {
+				return nil
+			}
+			next = map[string]interface{}{}
+			obj[key] = next
+		}
+		nextObj, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot assign field of %v at path %v", typeName(next), pathStr(path[:i+1]))
+		}
+		obj = nextObj
+	}
+	key := path[len(path)-1]
+	if del {
+		delete(obj, key)
+	} else {
+		obj[key] = value
+	}
+	return nil
+}
+
+func pathStr(path []string) string {
+	if len(path) == 0 {
+		return "root"
+	}
+	s := "root"
+	for _, p := range path {
+		s += "." + p
+	}
+	return s
+}
+
+//------------------------------------------------------------------------------
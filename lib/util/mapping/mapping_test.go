@@ -0,0 +1,281 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+func TestMappingExec(t *testing.T) {
+	type test struct {
+		mapping string
+		input   string
+		meta    map[string]string
+		output  string
+	}
+
+	tests := map[string]test{
+		"copy and rename": {
+			mapping: `root = this
+root.new_name = this.old_name
+root.old_name = deleted()`,
+			input:  `{"old_name":"foo","other":1}`,
+			output: `{"new_name":"foo","other":1}`,
+		},
+		"new document": {
+			mapping: `root.user.id = this.id
+root.user.name = this.first + " " + this.last`,
+			input:  `{"id":5,"first":"foo","last":"bar"}`,
+			output: `{"user":{"id":5,"name":"foo bar"}}`,
+		},
+		"arithmetic": {
+			mapping: `root.a = this.n * 2 + 1
+root.b = (this.n + 1) % 4
+root.c = -this.n / 2`,
+			input:  `{"n":5}`,
+			output: `{"a":11,"b":2,"c":-2.5}`,
+		},
+		"conditionals": {
+			mapping: `root.size = if this.n > 10 { "big" } else if this.n > 3 { "medium" } else { "small" }
+root.flag = if this.n == 5 && !this.skip { true }
+root.never = if this.n < 0 { "negative" }`,
+			input:  `{"n":5,"skip":false}`,
+			output: `{"flag":true,"size":"medium"}`,
+		},
+		"multi line conditional": {
+			mapping: `root.size = if this.n > 10 {
+  "big"
+} else {
+  "small"
+}`,
+			input:  `{"n":50}`,
+			output: `{"size":"big"}`,
+		},
+		"string methods": {
+			mapping: `root.a = this.s.uppercase()
+root.b = this.s.trim().replace(" ", "_").lowercase()
+root.c = this.csv.split(",").join("|")
+root.d = this.s.contains("Bar")
+root.e = this.csv.split(",").length()`,
+			input:  `{"s":" Foo Bar ","csv":"a,b,c"}`,
+			output: `{"a":" FOO BAR ","b":"foo_bar","c":"a|b|c","d":true,"e":3}`,
+		},
+		"type coercion": {
+			mapping: `root.a = this.num_str.number() + 1
+root.b = this.num.string()
+root.c = this.obj.string()
+root.d = this.nested.parse_json().foo
+root.e = this.frac.round()`,
+			input:  `{"num_str":"10","num":5,"obj":{"a":1},"nested":"{\"foo\":\"bar\"}","frac":2.6}`,
+			output: `{"a":11,"b":"5","c":"{\"a\":1}","d":"bar","e":3}`,
+		},
+		"defaults": {
+			mapping: `root.a = this.missing.or("default")
+root.b = this.present.or("default")
+root.c = this.missing.deeper.or(this.present)
+root.d = this.present.number().or(0)`,
+			input:  `{"present":"yes"}`,
+			output: `{"a":"default","b":"yes","c":"yes","d":0}`,
+		},
+		"array index": {
+			mapping: `root.first = this.items.0.name
+root.missing = this.items.5`,
+			input:  `{"items":[{"name":"foo"},{"name":"bar"}]}`,
+			output: `{"first":"foo","missing":null}`,
+		},
+		"metadata": {
+			mapping: `root.topic = meta("topic")
+root.missing = meta("nope")
+meta out = this.id
+meta topic = deleted()`,
+			input:  `{"id":"foo"}`,
+			meta:   map[string]string{"topic": "bar"},
+			output: `{"missing":null,"topic":"bar"}`,
+		},
+		"raw content": {
+			mapping: `root = content().uppercase()`,
+			input:   `hello world`,
+			output:  `HELLO WORLD`,
+		},
+		"metadata only": {
+			mapping: `meta foo = "bar" # comments are ignored`,
+			input:   `not json`,
+			output:  `not json`,
+		},
+		"quoted fields": {
+			mapping: `root."foo bar" = this."baz buz"`,
+			input:   `{"baz buz":"qux"}`,
+			output:  `{"foo bar":"qux"}`,
+		},
+	}
+
+	for name, test := range tests {
+		m, err := New(test.mapping)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		part := message.NewPart([]byte(test.input))
+		for k, v := range test.meta {
+			part.Metadata().Set(k, v)
+		}
+		if err = m.Exec(part); err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if exp, act := test.output, string(part.Get()); exp != act {
+			t.Errorf("%v: wrong result: %v != %v", name, act, exp)
+		}
+	}
+}
+
+func TestMappingMetadata(t *testing.T) {
+	m, err := New(`meta foo = "bar"
+meta count = this.n + 1
+meta removed = deleted()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := message.NewPart([]byte(`{"n":1}`))
+	part.Metadata().Set("removed", "baz")
+	if err = m.Exec(part); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "bar", part.Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "2", part.Metadata().Get("count"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "", part.Metadata().Get("removed"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := `{"n":1}`, string(part.Get()); exp != act {
+		t.Errorf("Wrong content: %v != %v", act, exp)
+	}
+}
+
+func TestMappingDoesNotModifyInput(t *testing.T) {
+	m, err := New(`root = this
+root.foo.bar = "changed"
+root.baz = this.foo.bar`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := message.NewPart([]byte(`{"foo":{"bar":"original"}}`))
+	if err = m.Exec(part); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"baz":"original","foo":{"bar":"changed"}}`, string(part.Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestMappingExecErrors(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		input   string
+		err     string
+	}{
+		"not json": {
+			mapping: `root.foo = this.bar`,
+			input:   `not json`,
+			err:     "line 1: failed to parse message as JSON: invalid character 'o' in literal null (expecting 'u')",
+		},
+		"bad arithmetic": {
+			mapping: "root.foo = \"bar\"\nroot.bar = this.s * 2",
+			input:   `{"s":"foo"}`,
+			err:     "line 2: operator '*' cannot be applied to string and number",
+		},
+		"divide by zero": {
+			mapping: `root.foo = this.n / 0`,
+			input:   `{"n":1}`,
+			err:     "line 1: attempted to divide by zero",
+		},
+		"bad method target": {
+			mapping: `root.foo = this.n.uppercase()`,
+			input:   `{"n":1}`,
+			err:     "line 1: method 'uppercase': expected string, got number",
+		},
+		"non-boolean condition": {
+			mapping: `root.foo = if this.n { "yes" }`,
+			input:   `{"n":1}`,
+			err:     "line 1: if condition expected boolean, got number",
+		},
+		"field of scalar": {
+			mapping: `root = "foo"
+root.bar = "baz"`,
+			input: `{}`,
+			err:   "line 2: cannot assign field of root string",
+		},
+	}
+
+	for name, test := range tests {
+		m, err := New(test.mapping)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		part := message.NewPart([]byte(test.input))
+		err = m.Exec(part)
+		if err == nil {
+			t.Errorf("%v: expected error", name)
+			continue
+		}
+		if exp, act := test.err, err.Error(); exp != act {
+			t.Errorf("%v: wrong error: %v != %v", name, act, exp)
+		}
+		if exp, act := test.input, string(part.Get()); exp != act {
+			t.Errorf("%v: part was modified: %v != %v", name, act, exp)
+		}
+	}
+}
+
+func TestMappingParseErrors(t *testing.T) {
+	tests := map[string]string{
+		``:                              "mapping contains no statements",
+		`foo = "bar"`:                   "line 1: expected assignment to root or meta, got 'foo'",
+		`root.foo "bar"`:                `line 1: expected '=', got "bar"`,
+		`root.foo = "bar`:               "line 1: unterminated string literal",
+		`root.foo = this.bar.nope()`:    "line 1: unrecognised method 'nope'",
+		`root.foo = nope()`:             "line 1: unrecognised function 'nope'",
+		`root.foo = meta()`:             "line 1: function 'meta' expects 1 arguments, got 0",
+		"root.foo = 5\nroot.bar = ~":    "line 2: unexpected character '~'",
+		"root.foo = 5 root.bar = 6":     "line 1: expected end of statement, got 'root'",
+		`root.foo = if true { "a" } el`: "line 1: expected end of statement, got 'el'",
+	}
+
+	for mapping, exp := range tests {
+		_, err := New(mapping)
+		if err == nil {
+			t.Errorf("Expected error from mapping: %v", mapping)
+			continue
+		}
+		if act := err.Error(); act != exp {
+			t.Errorf("Wrong error from mapping %v: %v != %v", mapping, act, exp)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//------------------------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokNewline:
+		return "end of line"
+	case tokString:
+		return strconv.Quote(t.val)
+	}
+	return fmt.Sprintf("'%v'", t.val)
+}
+
+var punctuation = []string{
+	"==", "!=", ">=", "<=", "&&", "||",
+	".", ",", "(", ")", "{", "}", "=", ">", "<", "+", "-", "*", "/", "%", "!",
+}
+
+// lex breaks a mapping into tokens. Newlines are significant as statement
+// terminators, but are dropped whilst within brackets or braces so that
+// expressions can span multiple lines.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line, depth := 1, 0
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			if depth == 0 {
+				toks = append(toks, token{kind: tokNewline, line: line})
+			}
+			line++
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			toks = append(toks, token{kind: tokIdent, val: string(runes[start:i]), line: line})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			if i+1 < len(runes) && runes[i] == '.' && unicode.IsDigit(runes[i+1]) {
+				i++
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			toks = append(toks, token{kind: tokNumber, val: string(runes[start:i]), line: line})
+		case r == '"':
+			start := i
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+				if i < len(runes) && runes[i] == '\n' {
+					return nil, fmt.Errorf("line %v: unterminated string literal", line)
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %v: unterminated string literal", line)
+			}
+			i++
+			str, err := strconv.Unquote(string(runes[start:i]))
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid string literal: %v", line, err)
+			}
+			toks = append(toks, token{kind: tokString, val: str, line: line})
+		default:
+			rest := string(runes[i:])
+			matched := ""
+			for _, p := range punctuation {
+				if strings.HasPrefix(rest, p) {
+					matched = p
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("line %v: unexpected character '%v'", line, string(r))
+			}
+			switch matched {
+			case "(", "{":
+				depth++
+			case ")", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+			toks = append(toks, token{kind: tokPunct, val: matched, line: line})
+			i += len(matched)
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, line: line})
+	return toks, nil
+}
+
+//------------------------------------------------------------------------------
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(v string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.val == v
+}
+
+func (p *parser) isIdent(v string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.val == v
+}
+
+func (p *parser) expectPunct(v string) error {
+	if t := p.next(); t.kind != tokPunct || t.val != v {
+		return fmt.Errorf("line %v: expected '%v', got %v", t.line, v, t)
+	}
+	return nil
+}
+
+func (p *parser) parseStatements() ([]statement, error) {
+	var stmts []statement
+	for {
+		for p.peek().kind == tokNewline {
+			p.next()
+		}
+		if p.peek().kind == tokEOF {
+			return stmts, nil
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+		if t := p.next(); t.kind != tokNewline && t.kind != tokEOF {
+			return nil, fmt.Errorf("line %v: expected end of statement, got %v", t.line, t)
+		}
+	}
+}
+
+func (p *parser) parseStatement() (statement, error) {
+	start := p.next()
+	stmt := statement{line: start.line}
+
+	switch {
+	case start.kind == tokIdent && start.val == "root":
+		for p.isPunct(".") {
+			p.next()
+			seg := p.next()
+			if seg.kind != tokIdent && seg.kind != tokString && seg.kind != tokNumber {
+				return stmt, fmt.Errorf("line %v: expected field name, got %v", seg.line, seg)
+			}
+			stmt.path = append(stmt.path, seg.val)
+		}
+	case start.kind == tokIdent && start.val == "meta":
+		key := p.next()
+		if key.kind != tokIdent && key.kind != tokString {
+			return stmt, fmt.Errorf("line %v: expected metadata key, got %v", key.line, key)
+		}
+		stmt.meta, stmt.metaKey = true, key.val
+	default:
+		return stmt, fmt.Errorf("line %v: expected assignment to root or meta, got %v", start.line, start)
+	}
+
+	if err := p.expectPunct("="); err != nil {
+		return stmt, err
+	}
+	var err error
+	stmt.value, err = p.parseExpr()
+	return stmt, err
+}
+
+//------------------------------------------------------------------------------
+
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", ">", ">=", "<", "<="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseExpr() (expr, error) {
+	return p.parseBinary(0)
+}
+
+func (p *parser) parseBinary(level int) (expr, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	lhs, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokPunct || !containsStr(binaryPrecedence[level], t.val) {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &binaryExpr{op: t.val, lhs: lhs, rhs: rhs}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isPunct("!") || p.isPunct("-") {
+		op := p.next().val
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct(".") {
+		p.next()
+		seg := p.next()
+		if seg.kind != tokIdent && seg.kind != tokString && seg.kind != tokNumber {
+			return nil, fmt.Errorf("line %v: expected field or method name, got %v", seg.line, seg)
+		}
+		if seg.kind != tokIdent || !p.isPunct("(") {
+			e = &fieldExpr{target: e, field: seg.val}
+			continue
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		if e, err = newMethod(seg, e, args); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (p *parser) parseArgs() ([]expr, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var args []expr
+	for !p.isPunct(")") {
+		if len(args) > 0 {
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literalExpr{value: t.val}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid number: %v", t.line, err)
+		}
+		return &literalExpr{value: f}, nil
+	case tokPunct:
+		if t.val == "(" {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case tokIdent:
+		switch t.val {
+		case "true", "false":
+			return &literalExpr{value: t.val == "true"}, nil
+		case "null":
+			return &literalExpr{value: nil}, nil
+		case "this":
+			return thisExpr{}, nil
+		case "if":
+			return p.parseIf()
+		}
+		if p.isPunct("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return newFunction(t, args)
+		}
+	}
+	return nil, fmt.Errorf("line %v: unexpected %v", t.line, t)
+}
+
+func (p *parser) parseBlock() (expr, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct("}"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *parser) parseIf() (expr, error) {
+	e := &ifExpr{}
+	for {
+		cond, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		val, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		e.conds = append(e.conds, cond)
+		e.values = append(e.values, val)
+
+		if !p.isIdent("else") {
+			return e, nil
+		}
+		p.next()
+		if !p.isIdent("if") {
+			if e.otherwise, err = p.parseBlock(); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+		p.next()
+	}
+}
+
+func containsStr(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
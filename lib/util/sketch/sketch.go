@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sketch
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+//------------------------------------------------------------------------------
+
+// Hash returns a well distributed 64-bit hash of a value, suitable for use with
+// the sketches of this package.
+func Hash(v []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(v)
+
+	// FNV does not distribute the high bits of short values well, and so the
+	// result is finalised with the mixer from SplitMix64.
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+//------------------------------------------------------------------------------
+
+// hllPrecision is the number of bits of a hash used to select a register,
+// resulting in 4096 registers and a standard error of roughly 1.6%.
+const hllPrecision = 12
+
+// HyperLogLog estimates the number of distinct values added to it.
+type HyperLogLog struct {
+	registers [1 << hllPrecision]uint8
+}
+
+// NewHyperLogLog creates an empty HyperLogLog.
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{}
+}
+
+// Add records a value by its hash.
+func (h *HyperLogLog) Add(hash uint64) {
+	index := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Count returns the estimated number of distinct values added.
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+
+	// Linear counting is more accurate for small cardinalities.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+//------------------------------------------------------------------------------
+
+// CountMin estimates the frequencies of values added to it, where estimates
+// can exceed but never fall short of the true frequency.
+type CountMin struct {
+	width uint64
+	rows  [][]uint64
+}
+
+// NewCountMin creates an empty CountMin sketch with a number of rows (depth)
+// and counters per row (width).
+func NewCountMin(depth, width int) *CountMin {
+	rows := make([][]uint64, depth)
+	for i := range rows {
+		rows[i] = make([]uint64, width)
+	}
+	return &CountMin{
+		width: uint64(width),
+		rows:  rows,
+	}
+}
+
+// Add increments the frequency of a value by its hash and returns the new
+// estimated frequency of the value.
+func (c *CountMin) Add(hash uint64) uint64 {
+	h1, h2 := hash&0xffffffff, hash>>32
+	min := uint64(math.MaxUint64)
+	for i, row := range c.rows {
+		j := (h1 + uint64(i)*h2) % c.width
+		row[j]++
+		if row[j] < min {
+			min = row[j]
+		}
+	}
+	return min
+}
+
+// Estimate returns the estimated frequency of a value by its hash.
+func (c *CountMin) Estimate(hash uint64) uint64 {
+	h1, h2 := hash&0xffffffff, hash>>32
+	min := uint64(math.MaxUint64)
+	for i, row := range c.rows {
+		if v := row[(h1+uint64(i)*h2)%c.width]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+//------------------------------------------------------------------------------
+
+// TopEntry is a value and its estimated frequency.
+type TopEntry struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+// TopK tracks the approximate K most frequent values added to it, using a
+// CountMin sketch to estimate frequencies.
+type TopK struct {
+	k      int
+	counts *CountMin
+	top    map[string]uint64
+}
+
+// NewTopK creates an empty TopK that tracks k values.
+func NewTopK(k int) *TopK {
+	return &TopK{
+		k:      k,
+		counts: NewCountMin(4, 2048),
+		top:    make(map[string]uint64, k),
+	}
+}
+
+// Grow raises the number of values tracked to k, values added from then on can
+// fill the extra space. Smaller values of k are ignored.
+func (t *TopK) Grow(k int) {
+	if k > t.k {
+		t.k = k
+	}
+}
+
+// Add records an occurrence of a value.
+func (t *TopK) Add(value []byte) {
+	count := t.counts.Add(Hash(value))
+	if _, exists := t.top[string(value)]; exists || len(t.top) < t.k {
+		t.top[string(value)] = count
+		return
+	}
+
+	var minValue string
+	minCount := uint64(math.MaxUint64)
+	for v, c := range t.top {
+		if c < minCount {
+			minValue, minCount = v, c
+		}
+	}
+	if count > minCount {
+		delete(t.top, minValue)
+		t.top[string(value)] = count
+	}
+}
+
+// Top returns the tracked values sorted by their estimated frequencies in
+// descending order.
+func (t *TopK) Top() []TopEntry {
+	entries := make([]TopEntry, 0, len(t.top))
+	for v, c := range t.top {
+		entries = append(entries, TopEntry{Value: v, Count: c})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count == entries[j].Count {
+			return entries[i].Value < entries[j].Value
+		}
+		return entries[i].Count > entries[j].Count
+	})
+	return entries
+}
+
+//------------------------------------------------------------------------------
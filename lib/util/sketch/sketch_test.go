@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 100000} {
+		h := NewHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.Add(Hash([]byte(fmt.Sprintf("value-%v", i))))
+			// Duplicates must not affect the estimate.
+			h.Add(Hash([]byte(fmt.Sprintf("value-%v", i))))
+		}
+		act := float64(h.Count())
+		if diff := math.Abs(act - float64(n)); diff > float64(n)*0.05 {
+			t.Errorf("Wrong estimate for %v values: %v", n, act)
+		}
+	}
+}
+
+func TestCountMin(t *testing.T) {
+	c := NewCountMin(4, 2048)
+	for i := 0; i < 1000; i++ {
+		for j := 0; j <= i%10; j++ {
+			c.Add(Hash([]byte(fmt.Sprintf("value-%v", i))))
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		exp := uint64(i%10 + 1)
+		if act := c.Estimate(Hash([]byte(fmt.Sprintf("value-%v", i)))); act < exp {
+			t.Errorf("Estimate below true frequency for value-%v: %v < %v", i, act, exp)
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	k := NewTopK(3)
+	for i := 0; i < 1000; i++ {
+		k.Add([]byte(fmt.Sprintf("noise-%v", i)))
+		if i%2 == 0 {
+			k.Add([]byte("foo"))
+		}
+		if i%4 == 0 {
+			k.Add([]byte("bar"))
+		}
+		if i%10 == 0 {
+			k.Add([]byte("baz"))
+		}
+	}
+
+	top := k.Top()
+	if exp, act := 3, len(top); exp != act {
+		t.Fatalf("Wrong count of top entries: %v != %v", act, exp)
+	}
+	for i, exp := range []string{"foo", "bar", "baz"} {
+		if act := top[i].Value; exp != act {
+			t.Errorf("Wrong top entry %v: %v != %v", i, act, exp)
+		}
+	}
+	if exp, act := uint64(500), top[0].Count; act < exp {
+		t.Errorf("Estimate below true frequency: %v < %v", act, exp)
+	}
+}
+
+func TestTopKGrow(t *testing.T) {
+	k := NewTopK(1)
+	k.Add([]byte("foo"))
+	k.Grow(2)
+	k.Grow(1)
+	k.Add([]byte("bar"))
+	k.Add([]byte("baz"))
+
+	if exp, act := 2, len(k.Top()); exp != act {
+		t.Errorf("Wrong count of top entries: %v != %v", act, exp)
+	}
+}
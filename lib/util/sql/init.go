@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/cenkalti/backoff"
+)
+
+//------------------------------------------------------------------------------
+
+// InitDocumentation is a markdown description of how and why to use init
+// statements.
+const InitDocumentation = `### Init Statements
+
+A list of statements can be specified in the field ` + "`init.statements`" + `,
+which are executed in order against the database before the component begins
+processing messages. This allows pipelines to bootstrap the tables and indexes
+they depend upon:
+
+` + "``` yaml" + `
+init:
+  statements:
+  - CREATE TABLE IF NOT EXISTS footable (foo varchar(50), bar varchar(50));
+  - CREATE INDEX IF NOT EXISTS foo_idx ON footable (foo);
+` + "```" + `
+
+Statements should therefore be idempotent. If a statement fails it is retried
+according to the ` + "`init.max_retries` and `init.backoff`" + ` fields, and if
+retries are exhausted the component fails to start.`
+
+//------------------------------------------------------------------------------
+
+// InitConfig contains configuration fields for statements that are executed
+// against a database when a component is first created.
+type InitConfig struct {
+	Statements     []string `json:"statements" yaml:"statements"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+// NewInitConfig creates a new InitConfig with default values.
+func NewInitConfig() InitConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
+	return InitConfig{
+		Statements: []string{},
+		Config:     rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RunInit executes each init statement of a config in order against a
+// database, retrying failed statements according to the backoff policy of the
+// config. An error is returned if any statement ultimately fails.
+func RunInit(conf InitConfig, db *sql.DB, log log.Modular) error {
+	if len(conf.Statements) == 0 {
+		return nil
+	}
+	boffCtor, err := conf.GetCtor()
+	if err != nil {
+		return err
+	}
+	for i, stmt := range conf.Statements {
+		boff := boffCtor()
+		for {
+			if _, err = db.Exec(stmt); err == nil {
+				break
+			}
+			wait := boff.NextBackOff()
+			if wait == backoff.Stop {
+				return fmt.Errorf("failed to execute init statement %v: %v", i, err)
+			}
+			log.Warnf("Failed to execute init statement %v: %v\n", i, err)
+			<-time.After(wait)
+		}
+		log.Debugf("Executed init statement %v\n", i)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,170 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeDriver struct {
+	sync.Mutex
+	failures int
+	executed []string
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{d: c.d, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+	if s.d.failures > 0 {
+		s.d.failures--
+		return nil, errors.New("simulated failure")
+	}
+	s.d.executed = append(s.d.executed, s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+var fakeDrivers = map[string]*fakeDriver{
+	"fake_init_ok":    {},
+	"fake_init_retry": {failures: 2},
+	"fake_init_fail":  {failures: 100},
+}
+
+func init() {
+	for k, v := range fakeDrivers {
+		sql.Register(k, v)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestRunInitBasic(t *testing.T) {
+	db, err := sql.Open("fake_init_ok", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	conf := NewInitConfig()
+	conf.Statements = []string{
+		"CREATE TABLE IF NOT EXISTS foo (bar varchar(50));",
+		"CREATE INDEX IF NOT EXISTS bar_idx ON foo (bar);",
+	}
+	if err = RunInit(conf, db, log.Noop()); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := conf.Statements
+	if act := fakeDrivers["fake_init_ok"].executed; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong statements executed: %v != %v", act, exp)
+	}
+}
+
+func TestRunInitRetries(t *testing.T) {
+	db, err := sql.Open("fake_init_retry", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	conf := NewInitConfig()
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	conf.Statements = []string{
+		"CREATE TABLE IF NOT EXISTS foo (bar varchar(50));",
+	}
+	if err = RunInit(conf, db, log.Noop()); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := conf.Statements
+	if act := fakeDrivers["fake_init_retry"].executed; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong statements executed: %v != %v", act, exp)
+	}
+}
+
+func TestRunInitFails(t *testing.T) {
+	db, err := sql.Open("fake_init_fail", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	conf := NewInitConfig()
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	conf.Statements = []string{
+		"CREATE TABLE IF NOT EXISTS foo (bar varchar(50));",
+	}
+	if err = RunInit(conf, db, log.Noop()); err == nil {
+		t.Error("Expected error")
+	}
+	if act := fakeDrivers["fake_init_fail"].executed; len(act) > 0 {
+		t.Errorf("Unexpected statements executed: %v", act)
+	}
+}
+
+//------------------------------------------------------------------------------
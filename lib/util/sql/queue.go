@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// QueueDocumentation is a markdown description of the table used by SQLite
+// queues, which is shared by the input and output.
+const QueueDocumentation = `### Queue Table
+
+Messages are stored within the table named by the field ` + "`table`" + `,
+which is created when it does not already exist with the following schema:
+
+` + "``` sql" + `
+CREATE TABLE IF NOT EXISTS benthos_queue (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  content BLOB NOT NULL,
+  metadata TEXT NOT NULL
+);
+` + "```" + `
+
+Each message of a batch is stored as its own row, where ` + "`metadata`" + ` is
+a JSON object of the metadata of the message.
+
+The database is opened in write-ahead logging mode, and therefore an input and
+output can share a file, even across processes.
+
+SQLite is supported but depends on C bindings, and therefore it is not compiled
+by default. Build it into your project with CGO enabled and the tag:
+'go install -tags "SQLITE" github.com/Jeffail/benthos/cmd/...'`
+
+var queueTableRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// OpenQueue opens a SQLite database file, creating it if it does not already
+// exist, and ensures that a table suitable for use as a queue exists within it.
+func OpenQueue(path, table string) (*sql.DB, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("a path must be specified")
+	}
+	if !queueTableRegex.MatchString(table) {
+		return nil, fmt.Errorf("table name '%v' is invalid, it must contain only alphanumeric characters and underscores", table)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  content BLOB NOT NULL,
+  metadata TEXT NOT NULL
+)`, table)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// EncodeQueueMetadata serialises the metadata of a message part for storage
+// within a queue table.
+func EncodeQueueMetadata(p types.Part) (string, error) {
+	meta := map[string]string{}
+	p.Metadata().Iter(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecodeQueuePart creates a message part from the content and serialised
+// metadata of a row within a queue table.
+func DecodeQueuePart(content []byte, metadata string) (types.Part, error) {
+	var meta map[string]string
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %v", err)
+	}
+	p := message.NewPart(content)
+	for k, v := range meta {
+		p.Metadata().Set(k, v)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
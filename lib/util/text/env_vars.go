@@ -78,4 +78,32 @@ func ReplaceEnvVariables(inBytes []byte) []byte {
 	return replaced
 }
 
+// EnvVariableSources returns a description of how each environment variable
+// pattern within a blob of data is resolved by ReplaceEnvVariables, in the
+// order that they appear. Each description names the variable and states
+// whether its value was taken from the environment, from the default value or
+// left empty.
+func EnvVariableSources(inBytes []byte) []string {
+	var sources []string
+	for _, content := range envRegex.FindAll(inBytes, -1) {
+		if len(content) <= 3 {
+			continue
+		}
+		targetVar, hasDefault := string(content[2:len(content)-1]), false
+		if colonIndex := bytes.IndexByte(content, ':'); colonIndex != -1 {
+			targetVar = string(content[2:colonIndex])
+			hasDefault = true
+		}
+		switch {
+		case len(os.Getenv(targetVar)) > 0:
+			sources = append(sources, targetVar+" from env")
+		case hasDefault:
+			sources = append(sources, targetVar+" unset, using default")
+		default:
+			sources = append(sources, targetVar+" unset, left empty")
+		}
+	}
+	return sources
+}
+
 //------------------------------------------------------------------------------
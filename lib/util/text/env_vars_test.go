@@ -22,6 +22,7 @@ package text
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -78,3 +79,28 @@ func TestEnvSwapping(t *testing.T) {
 		}
 	}
 }
+
+func TestEnvSources(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_FOO", "foo")
+	os.Setenv("BENTHOS_TEST_BAR", "")
+	defer os.Unsetenv("BENTHOS_TEST_FOO")
+
+	tests := map[string][]string{
+		"foo ${BENTHOS_TEST_FOO:bar} baz": {"BENTHOS_TEST_FOO from env"},
+		"foo ${BENTHOS_TEST_BAR:bar} baz": {"BENTHOS_TEST_BAR unset, using default"},
+		"foo ${BENTHOS_TEST_BAR} baz":     {"BENTHOS_TEST_BAR unset, left empty"},
+		"${BENTHOS_TEST_FOO} ${BENTHOS_TEST_BAR:bar}": {
+			"BENTHOS_TEST_FOO from env",
+			"BENTHOS_TEST_BAR unset, using default",
+		},
+		"foo ${{BENTHOS_TEST_FOO:bar}} baz": nil,
+		"nothing $ here boss {}":            nil,
+	}
+
+	for in, exp := range tests {
+		act := EnvVariableSources([]byte(in))
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result for '%v': %v != %v", in, act, exp)
+		}
+	}
+}
@@ -22,7 +22,10 @@ package text
 
 import (
 	"bytes"
+	crand "crypto/rand"
 	"encoding/json"
+	"math"
+	"math/rand"
 	"os"
 	"regexp"
 	"strconv"
@@ -108,6 +111,93 @@ func metadataMapFunction(msg Message, arg string) []byte {
 	return result
 }
 
+func errorFunction(msg Message, arg string) []byte {
+	part := 0
+	if len(arg) > 0 {
+		partB, err := strconv.ParseInt(arg, 10, 64)
+		if err == nil {
+			part = int(partB)
+		}
+	}
+	return []byte(msg.Get(part).Metadata().Get("benthos_processing_failed"))
+}
+
+const nanoidAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+func nanoidFunction(_ Message, arg string) []byte {
+	size := 21
+	if len(arg) > 0 {
+		if s, err := strconv.Atoi(arg); err == nil && s > 0 {
+			size = s
+		}
+	}
+	b := make([]byte, size)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = nanoidAlphabet[b[i]&63]
+	}
+	return b
+}
+
+var randomIntMux = &sync.Mutex{}
+var randomIntSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func randomIntFunction(_ Message, arg string) []byte {
+	var min, max int64 = 0, math.MaxInt64 - 1
+	if len(arg) > 0 {
+		args := strings.Split(arg, ",")
+		if v, err := strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64); err == nil {
+			min = v
+		}
+		if len(args) > 1 {
+			if v, err := strconv.ParseInt(strings.TrimSpace(args[1]), 10, 64); err == nil {
+				max = v
+			}
+		}
+	}
+	if max < min {
+		min, max = max, min
+	}
+
+	// The span is calculated with unsigned arithmetic as the difference of two
+	// int64 values can exceed math.MaxInt64.
+	span := uint64(max) - uint64(min)
+
+	randomIntMux.Lock()
+	var offset uint64
+	if span == math.MaxUint64 {
+		offset = randomIntSource.Uint64()
+	} else {
+		offset = randomUint64n(randomIntSource, span+1)
+	}
+	randomIntMux.Unlock()
+	return strconv.AppendInt(nil, int64(uint64(min)+offset), 10)
+}
+
+// randomUint64n returns a uniformly distributed random number within [0,n),
+// rejecting values that would bias the result towards lower numbers.
+func randomUint64n(r *rand.Rand, n uint64) uint64 {
+	threshold := -n % n
+	for {
+		if v := r.Uint64(); v >= threshold {
+			return v % n
+		}
+	}
+}
+
+func envFunction(_ Message, arg string) []byte {
+	args := strings.SplitN(arg, ",", 2)
+	if v := os.Getenv(args[0]); len(v) > 0 {
+		return []byte(v)
+	}
+	if len(args) > 1 {
+		return []byte(args[1])
+	}
+	return []byte("")
+}
+
 func contentFunction(msg Message, arg string) []byte {
 	part := 0
 	if len(arg) > 0 {
@@ -141,6 +231,9 @@ var functionVars = map[string]func(msg Message, arg string) []byte{
 	"timestamp_unix_nano": func(_ Message, arg string) []byte {
 		return []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
 	},
+	"timestamp_ms": func(_ Message, arg string) []byte {
+		return []byte(strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10))
+	},
 	"timestamp_unix": func(_ Message, arg string) []byte {
 		tNow := time.Now()
 		precision, _ := strconv.ParseInt(arg, 10, 64)
@@ -170,6 +263,9 @@ var functionVars = map[string]func(msg Message, arg string) []byte{
 		hn, _ := os.Hostname()
 		return []byte(hn)
 	},
+	"env":        envFunction,
+	"nanoid":     nanoidFunction,
+	"random_int": randomIntFunction,
 	"echo": func(_ Message, arg string) []byte {
 		return []byte(arg)
 	},
@@ -190,6 +286,7 @@ var functionVars = map[string]func(msg Message, arg string) []byte{
 		return []byte(strconv.FormatUint(count, 10))
 	},
 	"content":              contentFunction,
+	"error":                errorFunction,
 	"json_field":           jsonFieldFunction,
 	"metadata":             metadataFunction,
 	"metadata_json_object": metadataMapFunction,
@@ -22,6 +22,7 @@ package text
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -194,6 +195,28 @@ func TestContentFunctionIndex(t *testing.T) {
 	}
 }
 
+func TestErrorFunction(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+	})
+	msg.Get(1).Metadata().Set("benthos_processing_failed", "it broke")
+
+	act := string(ReplaceFunctionVariables(
+		msg, []byte(`foo ${!error} baz`),
+	))
+	if exp := "foo  baz"; act != exp {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	act = string(ReplaceFunctionVariables(
+		msg, []byte(`foo ${!error:1} baz`),
+	))
+	if exp := "foo it broke baz"; act != exp {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
 func TestBatchSizeFunction(t *testing.T) {
 	act := string(ReplaceFunctionVariables(
 		message.New(make([][]byte, 0)), []byte(`${!batch_size} bar baz`),
@@ -451,3 +474,101 @@ func TestUUIDV4Function(t *testing.T) {
 		results[result] = struct{}{}
 	}
 }
+
+func TestNanoidFunction(t *testing.T) {
+	results := map[string]struct{}{}
+
+	for i := 0; i < 100; i++ {
+		result := string(ReplaceFunctionVariables(nil, []byte(`${!nanoid}`)))
+		if exp, act := 21, len(result); exp != act {
+			t.Errorf("Wrong ID length: %v != %v", act, exp)
+		}
+		if _, exists := results[result]; exists {
+			t.Errorf("Duplicate ID generated: %v", result)
+		}
+		results[result] = struct{}{}
+	}
+
+	if exp, act := 8, len(ReplaceFunctionVariables(nil, []byte(`${!nanoid:8}`))); exp != act {
+		t.Errorf("Wrong ID length: %v != %v", act, exp)
+	}
+}
+
+func TestRandomIntFunction(t *testing.T) {
+	seen := map[int64]struct{}{}
+	for i := 0; i < 1000; i++ {
+		result := string(ReplaceFunctionVariables(nil, []byte(`${!random_int:1,6}`)))
+		v, err := strconv.ParseInt(result, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < 1 || v > 6 {
+			t.Errorf("Random int out of range: %v", v)
+		}
+		seen[v] = struct{}{}
+	}
+	if exp, act := 6, len(seen); exp != act {
+		t.Errorf("Wrong count of distinct values: %v != %v", act, exp)
+	}
+
+	result := string(ReplaceFunctionVariables(nil, []byte(`${!random_int}`)))
+	if v, err := strconv.ParseInt(result, 10, 64); err != nil || v < 0 {
+		t.Errorf("Unexpected random int: %v", result)
+	}
+
+	ranges := [][2]int64{
+		{-10, math.MaxInt64},
+		{math.MinInt64, math.MaxInt64},
+		{math.MinInt64, 10},
+		{math.MaxInt64 - 1, math.MaxInt64},
+	}
+	for _, r := range ranges {
+		fn := fmt.Sprintf("${!random_int:%v,%v}", r[0], r[1])
+		for i := 0; i < 100; i++ {
+			result := string(ReplaceFunctionVariables(nil, []byte(fn)))
+			v, err := strconv.ParseInt(result, 10, 64)
+			if err != nil {
+				t.Fatalf("Unexpected random int from %v: %v", fn, result)
+			}
+			if v < r[0] || v > r[1] {
+				t.Errorf("Random int from %v out of range: %v", fn, v)
+			}
+		}
+	}
+}
+
+func TestTimestampMsFunction(t *testing.T) {
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	result := string(ReplaceFunctionVariables(nil, []byte(`${!timestamp_ms}`)))
+	after := time.Now().UnixNano() / int64(time.Millisecond)
+
+	v, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v < before || v > after {
+		t.Errorf("Timestamp out of range: %v not within %v and %v", v, before, after)
+	}
+}
+
+func TestEnvFunction(t *testing.T) {
+	os.Setenv("BENTHOS_TEST_ENV_FUNCTION", "foo")
+	defer os.Unsetenv("BENTHOS_TEST_ENV_FUNCTION")
+
+	tests := [][2]string{
+		{"${!env:BENTHOS_TEST_ENV_FUNCTION}", "foo"},
+		{"${!env:BENTHOS_TEST_ENV_FUNCTION,bar}", "foo"},
+		{"${!env:BENTHOS_TEST_ENV_FUNCTION_UNSET}", ""},
+		{"${!env:BENTHOS_TEST_ENV_FUNCTION_UNSET,bar}", "bar"},
+		{"${!env:BENTHOS_TEST_ENV_FUNCTION_UNSET,bar,baz}", "bar,baz"},
+	}
+
+	for _, test := range tests {
+		input := test[0]
+		exp := test[1]
+		act := string(ReplaceFunctionVariables(nil, []byte(input)))
+		if exp != act {
+			t.Errorf("Wrong results for input (%v): %v != %v", input, act, exp)
+		}
+	}
+}